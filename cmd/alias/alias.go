@@ -0,0 +1,175 @@
+package alias
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/neilberkman/shannon/internal/config"
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/search"
+	"github.com/spf13/cobra"
+)
+
+var (
+	showAll bool
+	format  string
+)
+
+type conversationNames struct {
+	ID      int64    `json:"id"`
+	Title   string   `json:"title"`
+	Slug    string   `json:"slug"`
+	Aliases []string `json:"aliases"`
+}
+
+// NewCmd creates the alias command
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "alias",
+		Short: "Name conversations with slugs and aliases",
+		Long: `Every conversation has a slug made from its title and the month it started,
+such as python-pandas-cleanup-2024-05, shown by 'shannon view'. Slugs and
+aliases you set work anywhere a conversation ID does, including view, export,
+edit and artifacts.
+
+Examples:
+  shannon alias set 123 pandas
+  shannon view pandas
+  shannon alias list --all
+  shannon alias rm pandas`,
+	}
+
+	cmd.AddCommand(newSetCmd())
+	cmd.AddCommand(newRemoveCmd())
+	cmd.AddCommand(newListCmd())
+
+	return cmd
+}
+
+// newSetCmd creates the set subcommand
+func newSetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "set [conversation] [alias]",
+		Short: "Give a conversation an alias",
+		Long: `Give a conversation an alias, in addition to its ID and slug. Aliases may
+contain letters, digits, '-', '.' and '_', but not only digits.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			database, err := getDatabase()
+			if err != nil {
+				return err
+			}
+			defer closeDatabase(database)
+
+			engine := search.NewEngine(database)
+			convID, err := engine.ResolveConversation(args[0])
+			if err != nil {
+				return err
+			}
+			if err := engine.SetAlias(convID, args[1]); err != nil {
+				return err
+			}
+			fmt.Printf("Conversation %d is now also %s\n", convID, args[1])
+			return nil
+		},
+	}
+}
+
+// newRemoveCmd creates the rm subcommand
+func newRemoveCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "rm [alias...]",
+		Aliases: []string{"remove"},
+		Short:   "Remove aliases",
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			database, err := getDatabase()
+			if err != nil {
+				return err
+			}
+			defer closeDatabase(database)
+
+			engine := search.NewEngine(database)
+			for _, alias := range args {
+				if err := engine.RemoveAlias(alias); err != nil {
+					return err
+				}
+				fmt.Printf("Removed alias %s\n", alias)
+			}
+			return nil
+		},
+	}
+}
+
+// newListCmd creates the list subcommand
+func newListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List conversations with aliases and their slugs",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "table" && format != "json" {
+				return fmt.Errorf("unknown format %q (use table or json)", format)
+			}
+
+			database, err := getDatabase()
+			if err != nil {
+				return err
+			}
+			defer closeDatabase(database)
+
+			names, err := search.NewEngine(database).GetConversationNames(!showAll)
+			if err != nil {
+				return err
+			}
+
+			if format == "json" {
+				out := make([]conversationNames, len(names))
+				for i, n := range names {
+					out[i] = conversationNames{ID: n.ID, Title: n.Title, Slug: n.Slug, Aliases: n.Aliases}
+					if out[i].Aliases == nil {
+						out[i].Aliases = []string{}
+					}
+				}
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(out)
+			}
+
+			if len(names) == 0 {
+				fmt.Println("No aliases set. Use --all to list every conversation's slug.")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			_, _ = fmt.Fprintln(w, "ID\tALIASES\tSLUG\tTITLE")
+			for _, n := range names {
+				_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", n.ID, strings.Join(n.Aliases, ", "), n.Slug, n.Title)
+			}
+			return w.Flush()
+		},
+	}
+
+	cmd.Flags().BoolVarP(&showAll, "all", "a", false, "list every conversation, not only those with aliases")
+	cmd.Flags().StringVarP(&format, "format", "f", "table", "output format (table/json)")
+
+	return cmd
+}
+
+func getDatabase() (*db.DB, error) {
+	cfg := config.Get()
+	database, err := db.New(cfg.Database.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	return database, nil
+}
+
+func closeDatabase(database *db.DB) {
+	if err := database.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+	}
+}
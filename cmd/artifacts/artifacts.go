@@ -10,6 +10,7 @@ import (
 	"github.com/neilberkman/shannon/internal/artifacts"
 	"github.com/neilberkman/shannon/internal/config"
 	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/rendering"
 	"github.com/neilberkman/shannon/internal/search"
 	"github.com/spf13/cobra"
 )
@@ -20,6 +21,9 @@ var (
 	artifactType string
 	language     string
 	limit        int
+	codeBlocks   bool
+	minLines     int
+	source       string
 )
 
 // NewCmd creates the artifacts command
@@ -30,14 +34,24 @@ func NewCmd() *cobra.Command {
 		Long: `Extract and manage artifacts (code, documents, etc.) from Claude conversations.
 
 Artifacts are special content blocks that Claude generates, such as code files,
-markdown documents, SVG images, and more.`,
+markdown documents, SVG images, and more.
+
+Much useful code is in ordinary fenced code blocks instead. With --code-blocks,
+blocks of at least --min-lines lines are listed, searched, extracted and
+exported as artifacts too, in the language of the fence or, for untagged
+blocks, the one their code looks like. --source artifact or --source codeblock
+keeps only one kind.`,
 	}
 
+	cmd.PersistentFlags().BoolVar(&codeBlocks, "code-blocks", false, "also treat large fenced code blocks as artifacts")
+	cmd.PersistentFlags().IntVar(&minLines, "min-lines", artifacts.DefaultCodeBlockLines, "smallest code block, in lines, taken for an artifact with --code-blocks")
+
 	// Add subcommands
 	cmd.AddCommand(newListCmd())
 	cmd.AddCommand(newSearchCmd())
 	cmd.AddCommand(newExtractCmd())
 	cmd.AddCommand(newViewCmd())
+	cmd.AddCommand(newExportAllCmd())
 
 	return cmd
 }
@@ -45,15 +59,10 @@ markdown documents, SVG images, and more.`,
 // newListCmd creates the list subcommand
 func newListCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "list [conversation-id]",
+		Use:   "list [conversation]",
 		Short: "List artifacts in a conversation",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			conversationID, err := strconv.ParseInt(args[0], 10, 64)
-			if err != nil {
-				return fmt.Errorf("invalid conversation ID: %w", err)
-			}
-
 			// Get database
 			database, err := getDatabase()
 			if err != nil {
@@ -65,14 +74,21 @@ func newListCmd() *cobra.Command {
 				}
 			}()
 
-			engine := search.NewEngine(database)
+			engine, err := newEngine(database)
+			if err != nil {
+				return err
+			}
+			conversationID, err := engine.ResolveConversation(args[0])
+			if err != nil {
+				return err
+			}
 			artifactsList, err := engine.GetConversationArtifacts(conversationID)
 			if err != nil {
 				return fmt.Errorf("failed to get artifacts: %w", err)
 			}
 
-			// Filter by type or language if specified
-			filtered := filterArtifacts(artifactsList, artifactType, language)
+			// Filter by type, language or source if specified
+			filtered := filterArtifacts(artifactsList, artifactType, language, source)
 
 			// Render the list
 			renderer := getRenderer(format)
@@ -84,6 +100,7 @@ func newListCmd() *cobra.Command {
 
 	cmd.Flags().StringVar(&artifactType, "type", "", "filter by artifact type (code, markdown, html, svg, react, mermaid)")
 	cmd.Flags().StringVar(&language, "language", "", "filter by programming language (for code artifacts)")
+	cmd.Flags().StringVar(&source, "source", "", "only artifacts from antArtifact tags (artifact) or code blocks (codeblock)")
 	cmd.Flags().StringVarP(&format, "format", "f", "terminal", "output format (terminal, markdown)")
 
 	return cmd
@@ -109,7 +126,10 @@ func newSearchCmd() *cobra.Command {
 				}
 			}()
 
-			engine := search.NewEngine(database)
+			engine, err := newEngine(database)
+			if err != nil {
+				return err
+			}
 			results, err := engine.SearchArtifacts(search.SearchOptions{
 				Query: query,
 				Limit: limit,
@@ -146,15 +166,10 @@ func newSearchCmd() *cobra.Command {
 // newExtractCmd creates the extract subcommand
 func newExtractCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "extract [conversation-id]",
+		Use:   "extract [conversation]",
 		Short: "Extract artifacts from a conversation to files",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			conversationID, err := strconv.ParseInt(args[0], 10, 64)
-			if err != nil {
-				return fmt.Errorf("invalid conversation ID: %w", err)
-			}
-
 			// Get database
 			database, err := getDatabase()
 			if err != nil {
@@ -166,7 +181,14 @@ func newExtractCmd() *cobra.Command {
 				}
 			}()
 
-			engine := search.NewEngine(database)
+			engine, err := newEngine(database)
+			if err != nil {
+				return err
+			}
+			conversationID, err := engine.ResolveConversation(args[0])
+			if err != nil {
+				return err
+			}
 
 			// Get conversation details
 			conv, _, err := engine.GetConversation(conversationID)
@@ -198,22 +220,34 @@ func newExtractCmd() *cobra.Command {
 			// Extract each artifact
 			fmt.Printf("Extracting %d artifacts to %s/\n", len(artifactsList), outputDir)
 
+			var report *syntaxReport
+			if validate {
+				report = newSyntaxReport()
+			}
 			for i, artifact := range artifactsList {
-				filename := generateFilename(artifact, i)
+				filename := generateFilename(artifact, i, artifact.Title)
 				path := filepath.Join(outputDir, filename)
 
 				if err := os.WriteFile(path, []byte(artifact.Content), 0644); err != nil {
 					return fmt.Errorf("failed to write %s: %w", filename, err)
 				}
 
-				fmt.Printf("  ✓ %s\n", filename)
+				if report != nil && !report.check(filename, artifact, conv.ID, conv.Name) {
+					fmt.Printf("  %s%s (doesn't parse)\n", rendering.Symbol("✗ ", ""), filename)
+					continue
+				}
+				fmt.Printf("  %s%s\n", rendering.Symbol("✓ ", ""), filename)
 			}
 
+			if report != nil {
+				report.print()
+			}
 			return nil
 		},
 	}
 
 	cmd.Flags().StringVarP(&outputDir, "output-dir", "o", "", "output directory (defaults to conversation name)")
+	cmd.Flags().BoolVar(&validate, "validate", false, "check that code artifacts parse, with the installed toolchains, and report those that don't")
 
 	return cmd
 }
@@ -221,15 +255,10 @@ func newExtractCmd() *cobra.Command {
 // newViewCmd creates the view subcommand
 func newViewCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "view [conversation-id] [artifact-index]",
+		Use:   "view [conversation] [artifact-index]",
 		Short: "View a specific artifact",
 		Args:  cobra.ExactArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			conversationID, err := strconv.ParseInt(args[0], 10, 64)
-			if err != nil {
-				return fmt.Errorf("invalid conversation ID: %w", err)
-			}
-
 			index, err := strconv.Atoi(args[1])
 			if err != nil {
 				return fmt.Errorf("invalid artifact index: %w", err)
@@ -246,7 +275,14 @@ func newViewCmd() *cobra.Command {
 				}
 			}()
 
-			engine := search.NewEngine(database)
+			engine, err := newEngine(database)
+			if err != nil {
+				return err
+			}
+			conversationID, err := engine.ResolveConversation(args[0])
+			if err != nil {
+				return err
+			}
 			artifactsList, err := engine.GetConversationArtifacts(conversationID)
 			if err != nil {
 				return fmt.Errorf("failed to get artifacts: %w", err)
@@ -280,8 +316,8 @@ func getRenderer(format string) artifacts.Renderer {
 	}
 }
 
-func filterArtifacts(list []*artifacts.Artifact, artifactType, language string) []*artifacts.Artifact {
-	if artifactType == "" && language == "" {
+func filterArtifacts(list []*artifacts.Artifact, artifactType, language, source string) []*artifacts.Artifact {
+	if artifactType == "" && language == "" && source == "" {
 		return list
 	}
 
@@ -293,6 +329,9 @@ func filterArtifacts(list []*artifacts.Artifact, artifactType, language string)
 		if language != "" && !strings.EqualFold(a.Language, language) {
 			continue
 		}
+		if source != "" && a.Source != source {
+			continue
+		}
 		filtered = append(filtered, a)
 	}
 	return filtered
@@ -315,9 +354,9 @@ func sanitizeFilename(name string) string {
 	return replacer.Replace(name)
 }
 
-func generateFilename(artifact *artifacts.Artifact, index int) string {
-	// Use title if available, otherwise use index
-	base := artifact.Title
+// generateFilename names the file of an artifact after base, or after its
+// index if base is empty
+func generateFilename(artifact *artifacts.Artifact, index int, base string) string {
 	if base == "" {
 		base = fmt.Sprintf("artifact_%d", index+1)
 	}
@@ -336,6 +375,22 @@ func generateFilename(artifact *artifacts.Artifact, index int) string {
 	return base
 }
 
+// newEngine creates a search engine that takes code blocks for artifacts
+// with --code-blocks
+func newEngine(database *db.DB) (*search.Engine, error) {
+	if source != "" && source != artifacts.KindArtifact && source != artifacts.KindCodeBlock {
+		return nil, fmt.Errorf("invalid --source %q (use %s or %s)", source, artifacts.KindArtifact, artifacts.KindCodeBlock)
+	}
+	engine := search.NewEngine(database)
+	if codeBlocks {
+		if minLines < 1 {
+			return nil, fmt.Errorf("invalid --min-lines %d", minLines)
+		}
+		engine.SetCodeBlockArtifacts(minLines)
+	}
+	return engine, nil
+}
+
 // getDatabase returns a database connection
 func getDatabase() (*db.DB, error) {
 	cfg := config.Get()
@@ -0,0 +1,208 @@
+package artifacts
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/dates"
+	"github.com/neilberkman/shannon/internal/search"
+	"github.com/spf13/cobra"
+)
+
+var (
+	after  string
+	before string
+)
+
+// manifestName is the file export-all lists the files it wrote in
+const manifestName = "manifest.csv"
+
+// manifestHeader names the columns of the manifest
+var manifestHeader = []string{
+	"file", "conversation_id", "conversation_uuid", "conversation_name", "message_id", "message_uuid",
+	"created_at", "type", "language", "title", "identifier", "source",
+}
+
+// newExportAllCmd creates the export-all subcommand
+func newExportAllCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export-all",
+		Short: "Write the artifacts of every conversation to files",
+		Long: `Write the artifacts of every conversation to a directory, optionally only those
+of a type or language or from messages sent in a date range.
+
+Files are named after the artifact's title. Names already used, by this export
+or by a file in the directory, get a numeric suffix, so nothing is
+overwritten. A manifest.csv lists each file with the conversation and message
+it came from.
+
+--validate checks that each code artifact parses and lists those that don't,
+with the conversation that produced them: Go and JSON are parsed directly,
+Python, JavaScript, shell, Ruby and PHP with python3 -m py_compile,
+node --check, bash -n, ruby -c and php -l when they are installed.
+
+--after and --before take a date (2024-06-01), @2024, @2024-06, today,
+yesterday or an age such as 30d.
+
+Examples:
+  shannon artifacts export-all --type svg --dir svgs/
+  shannon artifacts export-all --type code --language python --dir scripts/
+  shannon artifacts export-all --type react --after @2024 --dir components/
+  shannon artifacts export-all --code-blocks --language go --dir snippets/
+  shannon artifacts export-all --type code --validate --dir code/`,
+		Args: cobra.NoArgs,
+		RunE: runExportAll,
+	}
+
+	cmd.Flags().StringVarP(&outputDir, "dir", "d", "", "directory to write the artifacts to (required)")
+	cmd.Flags().StringVar(&artifactType, "type", "", "only artifacts of this type (code, markdown, html, svg, react, mermaid)")
+	cmd.Flags().StringVar(&language, "language", "", "only code artifacts in this language")
+	cmd.Flags().StringVar(&source, "source", "", "only artifacts from antArtifact tags (artifact) or code blocks (codeblock)")
+	cmd.Flags().StringVar(&after, "after", "", "only artifacts from messages sent from this date or age on")
+	cmd.Flags().StringVar(&before, "before", "", "only artifacts from messages sent before this date or age")
+	cmd.Flags().BoolVar(&validate, "validate", false, "check that code artifacts parse, with the installed toolchains, and report those that don't")
+	if err := cmd.MarkFlagRequired("dir"); err != nil {
+		panic(fmt.Sprintf("failed to mark flag required: %v", err))
+	}
+
+	return cmd
+}
+
+func runExportAll(cmd *cobra.Command, args []string) error {
+	filter := search.ArtifactFilter{Type: artifactType, Language: language, Source: source}
+	if codeBlocks {
+		filter.CodeBlockLines = minLines
+	}
+	var err error
+	if after != "" {
+		if filter.After, err = dates.Parse(after, time.Now()); err != nil {
+			return fmt.Errorf("invalid --after: %w", err)
+		}
+	}
+	if before != "" {
+		if filter.Before, err = dates.Parse(before, time.Now()); err != nil {
+			return fmt.Errorf("invalid --before: %w", err)
+		}
+	}
+
+	database, err := getDatabase()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := database.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+		}
+	}()
+
+	engine, err := newEngine(database)
+	if err != nil {
+		return err
+	}
+	if _, err := engine.EnsureCodeIndex(); err != nil {
+		return fmt.Errorf("failed to build code block index: %w", err)
+	}
+	found, err := engine.AllArtifacts(filter)
+	if err != nil {
+		return err
+	}
+	if len(found) == 0 {
+		fmt.Println("No artifacts match.")
+		return nil
+	}
+
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	var report *syntaxReport
+	if validate {
+		report = newSyntaxReport()
+	}
+	manifest, err := exportArtifacts(outputDir, found, report)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %d artifacts to %s, listed in %s\n", len(found), outputDir, manifest)
+	if report != nil {
+		report.print()
+	}
+	return nil
+}
+
+// exportArtifacts writes each artifact to a file of its own in dir and lists
+// them in a manifest, returning the manifest's name. Code artifacts are
+// checked for syntax errors into report, unless it is nil.
+func exportArtifacts(dir string, found []*search.ArchivedArtifact, report *syntaxReport) (string, error) {
+	// Files already in the directory are never overwritten
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read output directory: %w", err)
+	}
+	taken := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		taken[strings.ToLower(entry.Name())] = true
+	}
+	manifestFile := uniqueName(manifestName, taken)
+
+	rows := [][]string{manifestHeader}
+	for i, a := range found {
+		base := a.Title
+		if base == "" {
+			base = a.ID
+		}
+		name := uniqueName(generateFilename(a.Artifact, i, base), taken)
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(a.Content), 0644); err != nil {
+			return "", fmt.Errorf("failed to write %s: %w", name, err)
+		}
+		if report != nil {
+			report.check(name, a.Artifact, a.ConversationID, a.ConversationName)
+		}
+		rows = append(rows, []string{
+			name,
+			strconv.FormatInt(a.ConversationID, 10),
+			a.ConversationUUID,
+			a.ConversationName,
+			strconv.FormatInt(a.MessageID, 10),
+			a.MessageUUID,
+			a.CreatedAt.UTC().Format(time.RFC3339),
+			a.Type,
+			a.Language,
+			a.Title,
+			a.ID,
+			a.Source,
+		})
+	}
+
+	file, err := os.Create(filepath.Join(dir, manifestFile))
+	if err != nil {
+		return "", fmt.Errorf("failed to create manifest: %w", err)
+	}
+	w := csv.NewWriter(file)
+	if err := w.WriteAll(rows); err != nil {
+		_ = file.Close()
+		return "", fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return "", fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return manifestFile, nil
+}
+
+// uniqueName returns name, or name with a numeric suffix before its
+// extension if that is taken, and marks it taken. Names are compared
+// ignoring case, since some file systems do.
+func uniqueName(name string, taken map[string]bool) string {
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	candidate := name
+	for n := 2; taken[strings.ToLower(candidate)]; n++ {
+		candidate = fmt.Sprintf("%s-%d%s", stem, n, ext)
+	}
+	taken[strings.ToLower(candidate)] = true
+	return candidate
+}
@@ -0,0 +1,70 @@
+package artifacts
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/artifacts"
+	"github.com/neilberkman/shannon/internal/search"
+)
+
+func TestExportArtifacts(t *testing.T) {
+	dir := t.TempDir()
+	// A file from an earlier export is left alone
+	if err := os.WriteFile(filepath.Join(dir, "Logo.svg"), []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	svg := func(id, title string, messageID int64) *search.ArchivedArtifact {
+		return &search.ArchivedArtifact{
+			Artifact: &artifacts.Artifact{
+				ID: id, Type: artifacts.TypeSVG, Title: title, Content: "<svg>" + id + "</svg>",
+				MessageID: messageID, ConversationID: 7,
+			},
+			ConversationUUID: "conv-7",
+			ConversationName: "Icons, round 2",
+			MessageUUID:      "msg-1",
+			CreatedAt:        time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		}
+	}
+	found := []*search.ArchivedArtifact{svg("logo", "Logo", 1), svg("logo-v2", "logo", 2), svg("arrow", "", 3)}
+
+	manifest, err := exportArtifacts(dir, found, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest != "manifest.csv" {
+		t.Errorf("expected manifest.csv, got %s", manifest)
+	}
+
+	file, err := os.Open(filepath.Join(dir, manifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = file.Close() }()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 4 || rows[0][0] != "file" {
+		t.Fatalf("expected a header and 3 rows, got %v", rows)
+	}
+
+	expected := []string{"Logo-2.svg", "logo-3.svg", "arrow.svg"}
+	for i, name := range expected {
+		row := rows[i+1]
+		if row[0] != name || row[2] != "conv-7" || row[3] != "Icons, round 2" || row[10] != found[i].ID {
+			t.Errorf("row %d: expected %s from conv-7, got %v", i, name, row)
+		}
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil || string(content) != found[i].Content {
+			t.Errorf("expected %s to hold %q, got %q (%v)", name, found[i].Content, content, err)
+		}
+	}
+	if content, _ := os.ReadFile(filepath.Join(dir, "Logo.svg")); string(content) != "old" {
+		t.Errorf("expected the existing file to be kept, got %q", content)
+	}
+}
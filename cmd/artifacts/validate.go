@@ -0,0 +1,88 @@
+package artifacts
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/neilberkman/shannon/internal/artifacts"
+	"github.com/neilberkman/shannon/internal/rendering"
+)
+
+// validate turns on syntax checks of the code artifacts written
+var validate bool
+
+// brokenArtifact is a written code artifact that doesn't parse
+type brokenArtifact struct {
+	file             string
+	conversationID   int64
+	conversationName string
+	err              *artifacts.SyntaxError
+}
+
+// syntaxReport collects the results of checking written artifacts
+type syntaxReport struct {
+	checked   int
+	unchecked map[string]int // code artifacts without a syntax check, by language
+	broken    []brokenArtifact
+}
+
+func newSyntaxReport() *syntaxReport {
+	return &syntaxReport{unchecked: make(map[string]int)}
+}
+
+// check validates an artifact written to file and reports whether it parses.
+// Artifacts that can't be checked count as parsing.
+func (r *syntaxReport) check(file string, a *artifacts.Artifact, conversationID int64, conversationName string) bool {
+	err := artifacts.Validate(a)
+	var syntaxErr *artifacts.SyntaxError
+	switch {
+	case err == nil:
+		r.checked++
+	case errors.As(err, &syntaxErr):
+		r.checked++
+		r.broken = append(r.broken, brokenArtifact{file, conversationID, conversationName, syntaxErr})
+		return false
+	case a.Type == artifacts.TypeCode:
+		language := a.Language
+		if language == "" {
+			language = "unknown"
+		}
+		if err != artifacts.ErrNoSyntaxCheck {
+			// Say why, such as the toolchain not being installed
+			language += " (" + strings.TrimPrefix(err.Error(), artifacts.ErrNoSyntaxCheck.Error()+": ") + ")"
+		}
+		r.unchecked[language]++
+	}
+	return true
+}
+
+// print lists the artifacts that don't parse, by the conversation that
+// produced them
+func (r *syntaxReport) print() {
+	fmt.Println()
+	if r.checked == 0 {
+		fmt.Println("No code artifacts could be checked")
+	} else if len(r.broken) == 0 {
+		fmt.Printf("%sAll %d checked code artifacts parse\n", rendering.Symbol("✓ ", ""), r.checked)
+	} else {
+		fmt.Printf("%s%d of %d checked code artifacts don't parse:\n", rendering.Symbol("✗ ", ""), len(r.broken), r.checked)
+		for _, b := range r.broken {
+			fmt.Printf("\n  %s, from conversation %d (%s)\n", b.file, b.conversationID, b.conversationName)
+			fmt.Printf("    %s:\n", b.err.Checker)
+			for _, line := range strings.Split(b.err.Message, "\n") {
+				fmt.Printf("      %s\n", line)
+			}
+		}
+	}
+
+	if len(r.unchecked) > 0 {
+		var skipped []string
+		for language, n := range r.unchecked {
+			skipped = append(skipped, fmt.Sprintf("%d %s", n, language))
+		}
+		sort.Strings(skipped)
+		fmt.Printf("Not checked: %s\n", strings.Join(skipped, ", "))
+	}
+}
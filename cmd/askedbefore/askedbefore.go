@@ -0,0 +1,163 @@
+package askedbefore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/neilberkman/shannon/cmd/tui"
+	"github.com/neilberkman/shannon/internal/config"
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/i18n"
+	"github.com/neilberkman/shannon/internal/rendering"
+	"github.com/neilberkman/shannon/internal/search"
+	"github.com/spf13/cobra"
+)
+
+// snippetLength is how many characters of a question and its answer are shown
+const snippetLength = 200
+
+var (
+	limit  int
+	useTUI bool
+	format string
+)
+
+type priorQuestion struct {
+	ConversationID   int64     `json:"conversation_id"`
+	ConversationName string    `json:"conversation_name"`
+	MessageID        int64     `json:"message_id"`
+	AskedAt          time.Time `json:"asked_at"`
+	Similarity       float64   `json:"similarity"`
+	Question         string    `json:"question"`
+	Answer           string    `json:"answer"`
+}
+
+// AskedBeforeCmd represents the asked-before command
+var AskedBeforeCmd = &cobra.Command{
+	Use:   "asked-before <question>",
+	Short: "Find conversations where you asked a similar question",
+	Long: `Before asking Claude something new, find the earlier conversations where you
+asked much the same, most similar first, with the start of the answer you got.
+
+Only your own messages are compared with the question, by the words that say
+what it's about: "how do I", "can you" and the like are ignored, and word
+endings don't matter, so "profiling goroutines" finds "profile a goroutine".
+Each conversation is listed once, with its closest question.
+
+Examples:
+  shannon asked-before "how do I profile goroutines"
+
+  # More matches, or the IDs for scripting
+  shannon asked-before -n 20 "retry with exponential backoff"
+  shannon asked-before -f id "postgres connection pooling" | xargs shannon export -d answers/
+
+  # Flip through the conversations in the TUI; ] next, [ previous
+  shannon asked-before --tui "rust lifetimes in structs"`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runAskedBefore,
+}
+
+func init() {
+	AskedBeforeCmd.Flags().IntVarP(&limit, "limit", "n", 5, "maximum number of conversations to show")
+	AskedBeforeCmd.Flags().BoolVar(&useTUI, "tui", false, "flip through the conversations in the TUI")
+	AskedBeforeCmd.Flags().StringVarP(&format, "format", "f", "text", "output format (text/id/json)")
+}
+
+func runAskedBefore(cmd *cobra.Command, args []string) error {
+	switch format {
+	case "text", "id", "json":
+	default:
+		return fmt.Errorf("unknown format %q (use text, id or json)", format)
+	}
+	question := strings.Join(args, " ")
+
+	// Get configuration
+	cfg := config.Get()
+
+	// Open database
+	database, err := db.New(cfg.Database.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer func() {
+		if err := database.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+		}
+	}()
+
+	engine := search.NewEngine(database)
+	engine.SetDictionary(search.NewDictionary(cfg.Search.Stopwords, cfg.Search.Synonyms))
+
+	found, err := engine.AskedBefore(context.Background(), question, limit)
+	if err != nil {
+		return err
+	}
+
+	if len(found) == 0 {
+		if format == "json" {
+			fmt.Println("[]")
+		} else if format != "id" {
+			fmt.Println(i18n.T("You haven't asked anything like that before."))
+		}
+		return nil
+	}
+
+	if useTUI {
+		ids := make([]int64, len(found))
+		for i, q := range found {
+			ids[i] = q.ConversationID
+		}
+		return tui.RunCarousel(engine, i18n.Tf("Asked before: %s", question), ids)
+	}
+
+	switch format {
+	case "id":
+		for _, q := range found {
+			fmt.Println(q.ConversationID)
+		}
+	case "json":
+		questions := make([]priorQuestion, len(found))
+		for i, q := range found {
+			questions[i] = priorQuestion{
+				ConversationID:   q.ConversationID,
+				ConversationName: q.ConversationName,
+				MessageID:        q.MessageID,
+				AskedAt:          q.AskedAt,
+				Similarity:       q.Similarity,
+				Question:         q.Question,
+				Answer:           q.Answer,
+			}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(questions)
+	default:
+		fmt.Println(i18n.T("Asked before, most similar first:"))
+		for _, q := range found {
+			id := fmt.Sprintf("%d", q.ConversationID)
+			if rendering.IsHyperlinksSupported() {
+				id = rendering.MakeHyperlinkWithID(id, fmt.Sprintf("shannon://view/%d", q.ConversationID), fmt.Sprintf("conv-%d", q.ConversationID))
+			}
+			fmt.Printf("\n%3.0f%%  %s  %s  %s\n", q.Similarity*100, q.AskedAt.Format("2006-01-02"), id, q.ConversationName)
+			fmt.Printf("      %s: %s\n", rendering.FormatSender("human"), snippet(q.Question))
+			if q.Answer != "" {
+				fmt.Printf("      %s: %s\n", rendering.FormatSender("assistant"), snippet(q.Answer))
+			}
+		}
+		fmt.Println("\n" + i18n.T("Read one with `shannon view <id>`"))
+	}
+	return nil
+}
+
+// snippet puts the start of a message on one line
+func snippet(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > snippetLength {
+		return string(runes[:snippetLength-3]) + "..."
+	}
+	return text
+}
@@ -0,0 +1,500 @@
+package cleanup
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dustin/go-humanize"
+	"github.com/neilberkman/shannon/internal/cleanup"
+	"github.com/neilberkman/shannon/internal/config"
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/search"
+	"github.com/spf13/cobra"
+)
+
+var (
+	conversationID int64
+	limit          int
+	format         string
+	assumeYes      bool
+	keepLines      int
+	keepChars      int
+)
+
+// NewCmd creates the cleanup command
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cleanup",
+		Short: "Delete or truncate individual messages",
+		Long: `Remove messages you no longer need, such as huge pasted logs, from the local
+database. Conversation counts, the search index and the code index are updated
+to match.
+
+Every change can be undone for 7 days; after that the original text is purged.
+Deleted messages are not restored when the conversation is imported again.
+
+Whole conversations can be deleted too. They go to the trash, where 'shannon
+trash' lists, restores and empties them, and stay deleted across imports until
+restored or imported with 'shannon import --restore-deleted'.
+
+Examples:
+  shannon cleanup large
+  shannon cleanup truncate 1234 --keep-lines 20
+  shannon cleanup delete 1234
+  shannon cleanup list
+  shannon cleanup undo 5
+  shannon cleanup conversation 42
+  shannon cleanup deleted`,
+	}
+
+	cmd.AddCommand(newLargeCmd())
+	cmd.AddCommand(newDeleteCmd())
+	cmd.AddCommand(newTruncateCmd())
+	cmd.AddCommand(newListCmd())
+	cmd.AddCommand(newUndoCmd())
+	cmd.AddCommand(newConversationCmd())
+	cmd.AddCommand(newDeletedCmd())
+
+	return cmd
+}
+
+// newLargeCmd creates the large subcommand
+func newLargeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "large",
+		Short: "List the largest messages",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			database, err := getDatabase()
+			if err != nil {
+				return err
+			}
+			defer closeDatabase(database)
+
+			messages, err := cleanup.LargestMessages(database, conversationID, limit)
+			if err != nil {
+				return err
+			}
+
+			if format == "json" {
+				return writeJSON(map[string]interface{}{
+					"messages": messages,
+					"count":    len(messages),
+				})
+			}
+
+			if len(messages) == 0 {
+				fmt.Println("No messages found.")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			if _, err := fmt.Fprintln(w, "Message\tConv\tSender\tSize\tLines\tPreview"); err != nil {
+				return fmt.Errorf("failed to write header: %w", err)
+			}
+			if _, err := fmt.Fprintln(w, "-------\t----\t------\t----\t-----\t-------"); err != nil {
+				return fmt.Errorf("failed to write separator: %w", err)
+			}
+			for _, m := range messages {
+				if _, err := fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%d\t%s\n",
+					m.MessageID, m.ConversationID, m.Sender, humanize.Bytes(uint64(m.Size)), m.Lines, m.Preview); err != nil {
+					return fmt.Errorf("failed to write row: %w", err)
+				}
+			}
+			return w.Flush()
+		},
+	}
+
+	cmd.Flags().Int64VarP(&conversationID, "conversation", "c", 0, "only consider messages in this conversation")
+	cmd.Flags().IntVarP(&limit, "limit", "l", 20, "maximum number of messages to show")
+	cmd.Flags().StringVarP(&format, "format", "f", "table", "output format (table/json)")
+
+	return cmd
+}
+
+// newDeleteCmd creates the delete subcommand
+func newDeleteCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "delete [message-id...]",
+		Short: "Delete messages",
+		Long: `Delete one or more messages. Replies to a deleted message are attached to
+its parent so the rest of the conversation stays in order.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ids, err := parseIDs(args, "message")
+			if err != nil {
+				return err
+			}
+
+			database, err := getDatabase()
+			if err != nil {
+				return err
+			}
+			defer closeDatabase(database)
+
+			if !confirm(database, ids, fmt.Sprintf("Delete %s?", pluralize(len(ids), "this message", "these messages"))) {
+				fmt.Println("Aborted.")
+				return nil
+			}
+
+			for _, id := range ids {
+				edit, err := cleanup.DeleteMessage(database, id)
+				if err != nil {
+					return fmt.Errorf("failed to delete message %d: %w", id, err)
+				}
+				fmt.Printf("Deleted message %d (%s); undo with: shannon cleanup undo %d\n",
+					id, humanize.Bytes(uint64(edit.OriginalSize)), edit.ID)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "don't ask for confirmation")
+
+	return cmd
+}
+
+// newTruncateCmd creates the truncate subcommand
+func newTruncateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "truncate [message-id...]",
+		Short: "Truncate messages, keeping only their beginning",
+		Long: `Shorten one or more messages to their first lines or characters. A note at
+the end of each message records how much was removed.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ids, err := parseIDs(args, "message")
+			if err != nil {
+				return err
+			}
+			opts := cleanup.TruncateOptions{KeepLines: keepLines, KeepChars: keepChars}
+			if opts.KeepLines <= 0 && opts.KeepChars <= 0 {
+				return fmt.Errorf("--keep-lines or --keep-chars must be greater than 0")
+			}
+
+			database, err := getDatabase()
+			if err != nil {
+				return err
+			}
+			defer closeDatabase(database)
+
+			if !confirm(database, ids, fmt.Sprintf("Truncate %s?", pluralize(len(ids), "this message", "these messages"))) {
+				fmt.Println("Aborted.")
+				return nil
+			}
+
+			for _, id := range ids {
+				edit, err := cleanup.TruncateMessage(database, id, opts)
+				if errors.Is(err, cleanup.ErrNothingToTruncate) {
+					fmt.Printf("Message %d is already short enough; skipped\n", id)
+					continue
+				} else if err != nil {
+					return fmt.Errorf("failed to truncate message %d: %w", id, err)
+				}
+				fmt.Printf("Truncated message %d (was %s); undo with: shannon cleanup undo %d\n",
+					id, humanize.Bytes(uint64(edit.OriginalSize)), edit.ID)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&keepLines, "keep-lines", 20, "number of lines to keep (0 for no line limit)")
+	cmd.Flags().IntVar(&keepChars, "keep-chars", 0, "number of characters to keep (0 for no character limit)")
+	cmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "don't ask for confirmation")
+
+	return cmd
+}
+
+// newListCmd creates the list subcommand
+func newListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List changes that can still be undone",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			database, err := getDatabase()
+			if err != nil {
+				return err
+			}
+			defer closeDatabase(database)
+
+			edits, err := cleanup.ListEdits(database, conversationID)
+			if err != nil {
+				return err
+			}
+
+			if format == "json" {
+				return writeJSON(map[string]interface{}{
+					"edits": edits,
+					"count": len(edits),
+				})
+			}
+
+			if len(edits) == 0 {
+				fmt.Println("No changes to undo.")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			if _, err := fmt.Fprintln(w, "ID\tAction\tMessage\tConv\tSender\tOriginal Size\tEdited\tExpires"); err != nil {
+				return fmt.Errorf("failed to write header: %w", err)
+			}
+			if _, err := fmt.Fprintln(w, "--\t------\t-------\t----\t------\t-------------\t------\t-------"); err != nil {
+				return fmt.Errorf("failed to write separator: %w", err)
+			}
+			for _, e := range edits {
+				if _, err := fmt.Fprintf(w, "%d\t%s\t%d\t%d\t%s\t%s\t%s\t%s\n",
+					e.ID, e.Action, e.MessageID, e.ConversationID, e.Sender,
+					humanize.Bytes(uint64(e.OriginalSize)), humanize.Time(e.EditedAt),
+					humanize.Time(e.EditedAt.Add(cleanup.UndoWindow))); err != nil {
+					return fmt.Errorf("failed to write row: %w", err)
+				}
+			}
+			return w.Flush()
+		},
+	}
+
+	cmd.Flags().Int64VarP(&conversationID, "conversation", "c", 0, "only show changes to this conversation")
+	cmd.Flags().StringVarP(&format, "format", "f", "table", "output format (table/json)")
+
+	return cmd
+}
+
+// newUndoCmd creates the undo subcommand
+func newUndoCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "undo [edit-id]",
+		Short: "Restore a deleted or truncated message",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			editID, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid edit ID: %w", err)
+			}
+
+			database, err := getDatabase()
+			if err != nil {
+				return err
+			}
+			defer closeDatabase(database)
+
+			edit, err := cleanup.Undo(database, editID)
+			if err != nil {
+				return fmt.Errorf("undo failed: %w", err)
+			}
+
+			verb := "Restored deleted"
+			if edit.Action == cleanup.ActionTruncate {
+				verb = "Restored full text of"
+			}
+			fmt.Printf("%s message %d in conversation %d\n", verb, edit.MessageID, edit.ConversationID)
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// newConversationCmd creates the conversation subcommand
+func newConversationCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "conversation [conversation...]",
+		Short: "Move whole conversations to the trash",
+		Long: `Move one or more conversations to the trash. They drop out of search, lists
+and stats right away, and are deleted for good once they have been in the
+trash for trash.retention_days (30 by default) or when the trash is emptied.
+Until then 'shannon trash restore' brings them back.
+
+A tombstone is kept as well, so the conversation isn't imported again from an
+export that still contains it. Pass --restore-deleted to 'shannon import' or
+'shannon sync import' to bring it back after the trash has been emptied.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			database, err := getDatabase()
+			if err != nil {
+				return err
+			}
+			defer closeDatabase(database)
+
+			// Conversations can be named by slug or alias too
+			engine := search.NewEngine(database)
+			ids := make([]int64, 0, len(args))
+			for _, arg := range args {
+				id, err := engine.ResolveConversation(arg)
+				if err != nil {
+					return err
+				}
+				ids = append(ids, id)
+			}
+
+			if !confirmConversations(database, ids) {
+				fmt.Println("Aborted.")
+				return nil
+			}
+
+			for _, id := range ids {
+				trashed, err := cleanup.TrashConversation(database, id)
+				if err != nil {
+					return fmt.Errorf("failed to delete conversation %d: %w", id, err)
+				}
+				fmt.Printf("Moved conversation %d %q (%d messages) to the trash\n", id, trashed.Name, trashed.MessageCount)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "don't ask for confirmation")
+
+	return cmd
+}
+
+// newDeletedCmd creates the deleted subcommand
+func newDeletedCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "deleted",
+		Short: "List deleted conversations kept out of imports",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			database, err := getDatabase()
+			if err != nil {
+				return err
+			}
+			defer closeDatabase(database)
+
+			deleted, err := cleanup.DeletedConversations(database)
+			if err != nil {
+				return err
+			}
+
+			if format == "json" {
+				return writeJSON(map[string]interface{}{
+					"conversations": deleted,
+					"count":         len(deleted),
+				})
+			}
+
+			if len(deleted) == 0 {
+				fmt.Println("No deleted conversations.")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			if _, err := fmt.Fprintln(w, "UUID\tName\tMessages\tDeleted"); err != nil {
+				return fmt.Errorf("failed to write header: %w", err)
+			}
+			if _, err := fmt.Fprintln(w, "----\t----\t--------\t-------"); err != nil {
+				return fmt.Errorf("failed to write separator: %w", err)
+			}
+			for _, d := range deleted {
+				if _, err := fmt.Fprintf(w, "%s\t%s\t%d\t%s\n",
+					d.UUID, d.Name, d.MessageCount, humanize.Time(d.DeletedAt)); err != nil {
+					return fmt.Errorf("failed to write row: %w", err)
+				}
+			}
+			return w.Flush()
+		},
+	}
+
+	cmd.Flags().StringVarP(&format, "format", "f", "table", "output format (table/json)")
+
+	return cmd
+}
+
+// confirmConversations shows the conversations about to be deleted and asks
+// before continuing
+func confirmConversations(database *db.DB, ids []int64) bool {
+	if assumeYes {
+		return true
+	}
+
+	for _, id := range ids {
+		var name string
+		var messages int
+		if err := database.QueryRow("SELECT name, message_count FROM conversations WHERE id = ?", id).Scan(&name, &messages); err != nil {
+			fmt.Printf("  %d: not found\n", id)
+			continue
+		}
+		fmt.Printf("  %d: %s, %d messages\n", id, name, messages)
+	}
+
+	question := pluralize(len(ids), "Move this conversation to the trash?", "Move these conversations to the trash?")
+	fmt.Printf("\n%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// confirm shows the messages about to change and asks before continuing
+func confirm(database *db.DB, ids []int64, question string) bool {
+	if assumeYes {
+		return true
+	}
+
+	for _, id := range ids {
+		var sender string
+		var text string
+		if err := database.QueryRow("SELECT sender, message_text(text) FROM messages WHERE id = ?", id).Scan(&sender, &text); err != nil {
+			fmt.Printf("  %d: not found\n", id)
+			continue
+		}
+		fmt.Printf("  %d: %s, %s, %d lines\n", id, sender, humanize.Bytes(uint64(len(text))), strings.Count(text, "\n")+1)
+	}
+
+	fmt.Printf("\n%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+func parseIDs(args []string, kind string) ([]int64, error) {
+	ids := make([]int64, 0, len(args))
+	for _, arg := range args {
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s ID %q: %w", kind, arg, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func pluralize(n int, singular, plural string) string {
+	if n == 1 {
+		return singular
+	}
+	return plural
+}
+
+func writeJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// getDatabase returns a database connection, first making changes older than
+// the undo window permanent
+func getDatabase() (*db.DB, error) {
+	cfg := config.Get()
+	database, err := db.New(cfg.Database.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	if _, err := cleanup.PurgeExpired(database, cleanup.UndoWindow); err != nil {
+		closeDatabase(database)
+		return nil, err
+	}
+	return database, nil
+}
+
+func closeDatabase(database *db.DB) {
+	if err := database.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+	}
+}
@@ -0,0 +1,163 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/neilberkman/shannon/cmd/tui"
+	"github.com/neilberkman/shannon/internal/cluster"
+	"github.com/neilberkman/shannon/internal/config"
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/models"
+	"github.com/neilberkman/shannon/internal/search"
+	"github.com/spf13/cobra"
+)
+
+var (
+	clusters int
+	terms    int
+	show     int
+	seed     int64
+	useTUI   bool
+	format   string
+)
+
+// ClusterCmd represents the cluster command
+var ClusterCmd = &cobra.Command{
+	Use:   "cluster",
+	Short: "Group conversations by topic",
+	Long: `Group all conversations into topical clusters, a map of everything you've
+discussed. Each conversation is described by its title and your messages,
+weighted by how distinctive each word is (TF-IDF), and grouped with k-means.
+Clusters are labelled with their most distinctive terms and listed largest
+first, with the conversations closest to the topic first.
+
+Words from search.stopwords in the config file are ignored along with common
+English ones. Clustering is repeatable: the same --seed gives the same
+clusters for the same conversations.
+
+Examples:
+  # Twenty topics with their five most typical conversations
+  shannon cluster --k 20
+
+  # Browse the topics and their conversations in the TUI
+  shannon cluster --tui
+
+  # Every conversation of every topic, for scripting
+  shannon cluster --show 0 --format json`,
+	Args: cobra.NoArgs,
+	RunE: runCluster,
+}
+
+func init() {
+	ClusterCmd.Flags().IntVarP(&clusters, "k", "k", 20, "number of clusters")
+	ClusterCmd.Flags().IntVar(&terms, "terms", 4, "number of terms in each cluster's label")
+	ClusterCmd.Flags().IntVar(&show, "show", 5, "conversations listed per cluster in table output (0 for all)")
+	ClusterCmd.Flags().Int64Var(&seed, "seed", 1, "seed for picking the starting clusters")
+	ClusterCmd.Flags().BoolVar(&useTUI, "tui", false, "browse the clusters in the TUI")
+	ClusterCmd.Flags().StringVarP(&format, "format", "f", "table", "output format (table/json)")
+}
+
+type clusterOutput struct {
+	Terms         []string             `json:"terms"`
+	Size          int                  `json:"size"`
+	Conversations []conversationOutput `json:"conversations"`
+}
+
+type conversationOutput struct {
+	ID         int64   `json:"id"`
+	Name       string  `json:"name"`
+	Similarity float64 `json:"similarity"`
+}
+
+func runCluster(cmd *cobra.Command, args []string) error {
+	if clusters < 1 {
+		return fmt.Errorf("invalid --k %d: need at least one cluster", clusters)
+	}
+	if format != "table" && format != "json" {
+		return fmt.Errorf("unknown format %q (use table or json)", format)
+	}
+
+	// Get configuration
+	cfg := config.Get()
+
+	// Open database
+	database, err := db.New(cfg.Database.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer func() {
+		if err := database.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+		}
+	}()
+
+	engine := search.NewEngine(database)
+
+	builder := cluster.NewBuilder(cfg.Search.Stopwords...)
+	conversations := make(map[int64]*models.Conversation)
+	err = engine.EachConversationText(func(conv *models.Conversation, text string) error {
+		conversations[conv.ID] = conv
+		builder.Add(conv.ID, conv.Name, text)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(conversations) == 0 {
+		return fmt.Errorf("no conversations to cluster; import an export first")
+	}
+
+	result := builder.Cluster(clusters, terms, seed)
+
+	if useTUI {
+		groups := make([]tui.ClusterGroup, len(result.Clusters))
+		for i, c := range result.Clusters {
+			groups[i].Label = strings.Join(c.Terms, ", ")
+			for _, member := range c.Members {
+				groups[i].Conversations = append(groups[i].Conversations, conversations[member.ID])
+			}
+		}
+		return tui.RunClusters(engine, groups)
+	}
+
+	if format == "json" {
+		output := make([]clusterOutput, len(result.Clusters))
+		for i, c := range result.Clusters {
+			output[i] = clusterOutput{Terms: c.Terms, Size: len(c.Members), Conversations: []conversationOutput{}}
+			for _, member := range c.Members {
+				output[i].Conversations = append(output[i].Conversations, conversationOutput{
+					ID:         member.ID,
+					Name:       conversations[member.ID].Name,
+					Similarity: member.Similarity,
+				})
+			}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(output)
+	}
+
+	for i, c := range result.Clusters {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("%d. %s (%d conversations)\n", i+1, strings.Join(c.Terms, ", "), len(c.Members))
+		members := c.Members
+		if show > 0 && len(members) > show {
+			members = members[:show]
+		}
+		for _, member := range members {
+			fmt.Printf("   %6d  %s\n", member.ID, conversations[member.ID].Name)
+		}
+		if len(members) < len(c.Members) {
+			fmt.Printf("           ... and %d more\n", len(c.Members)-len(members))
+		}
+	}
+	if len(result.Unclustered) > 0 {
+		fmt.Printf("\n%d conversations had too little text to cluster\n", len(result.Unclustered))
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package context
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/neilberkman/shannon/internal/clipboard"
+	"github.com/neilberkman/shannon/internal/config"
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/export"
+	"github.com/neilberkman/shannon/internal/search"
+	"github.com/spf13/cobra"
+)
+
+var (
+	maxTokens   int
+	strategy    string
+	instruction string
+	outputFile  string
+	copyBlock   bool
+)
+
+// ContextCmd represents the context command
+var ContextCmd = &cobra.Command{
+	Use:   "context [conversation]",
+	Short: "Build a context block from a conversation that fits a token budget",
+	Long: `Pick the messages of a conversation that fit within a token budget and
+print them as a block to paste into a new Claude chat, to carry on the work
+with the context that matters most.
+
+Strategies:
+  recent            the latest messages, cutting the start of the earliest
+                    one kept to fill the budget (default)
+  relevant:QUERY    the messages matching QUERY, best matches first, each with
+                    the question it answers or the answer it got
+
+Messages keep the numbers 'shannon view' gives them, and the block notes
+how many were left out between them. Tokens are estimated at about four
+characters each, as in the conversation metrics, so leave some headroom
+below the model's real limit.
+
+Examples:
+  shannon context 123 --max-tokens 8000
+  shannon context 123 --strategy relevant:"connection pooling" --copy
+  shannon context python-pandas-cleanup-2024-05 -i "Now port it to polars" -o context.md`,
+	Args: cobra.ExactArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if maxTokens <= 0 {
+			return fmt.Errorf("--max-tokens must be positive")
+		}
+		name, query, _ := strings.Cut(strategy, ":")
+		switch name {
+		case export.ContextRecent:
+			if query != "" {
+				return fmt.Errorf("the recent strategy takes no query")
+			}
+		case export.ContextRelevant:
+			if strings.TrimSpace(query) == "" {
+				return fmt.Errorf(`the relevant strategy needs a query, as in relevant:"query"`)
+			}
+		default:
+			return fmt.Errorf("unknown strategy %q (want recent or relevant:QUERY)", strategy)
+		}
+		return nil
+	},
+	RunE: runContext,
+}
+
+func init() {
+	ContextCmd.Flags().IntVarP(&maxTokens, "max-tokens", "t", 8000, "most tokens the block may take, estimated")
+	ContextCmd.Flags().StringVarP(&strategy, "strategy", "s", export.ContextRecent, "how to pick messages: recent, or relevant:QUERY")
+	ContextCmd.Flags().StringVarP(&instruction, "instruction", "i", "", "what to ask for after the context")
+	ContextCmd.Flags().StringVarP(&outputFile, "output", "o", "", "write the block to this file")
+	ContextCmd.Flags().BoolVarP(&copyBlock, "copy", "c", false, "copy the block to the clipboard")
+}
+
+func runContext(cmd *cobra.Command, args []string) error {
+	cfg := config.Get()
+	database, err := db.New(cfg.Database.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer func() {
+		if err := database.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+		}
+	}()
+
+	engine := search.NewEngine(database)
+	convID, err := engine.ResolveConversation(args[0])
+	if err != nil {
+		return err
+	}
+	conv, messages, err := engine.GetConversation(convID)
+	if err != nil {
+		return fmt.Errorf("failed to get conversation: %w", err)
+	}
+
+	var window *export.ContextWindow
+	if name, query, _ := strings.Cut(strategy, ":"); name == export.ContextRelevant {
+		query = strings.TrimSpace(query)
+		results, err := engine.Search(search.SearchOptions{
+			Query:          query,
+			ConversationID: &convID,
+			Limit:          len(messages) + 1,
+			IncludePrivate: true,
+		})
+		if err != nil {
+			return err
+		}
+		ranked := make([]int64, len(results))
+		for i, r := range results {
+			ranked[i] = r.MessageID
+		}
+		window, err = export.RelevantContext(conv, messages, query, ranked, maxTokens, instruction)
+		if err != nil {
+			return err
+		}
+	} else {
+		window, err = export.RecentContext(conv, messages, maxTokens, instruction)
+		if err != nil {
+			return err
+		}
+	}
+
+	block := window.Block()
+	summary := fmt.Sprintf("%d of %d message(s), about %s tokens", len(window.Messages()), window.Total, humanize.Comma(int64(window.Tokens())))
+	if window.Truncated() {
+		summary += ", the earliest cut to fit"
+	}
+
+	if outputFile == "" && !copyBlock {
+		fmt.Print(block)
+		fmt.Fprintf(os.Stderr, "Context: %s\n", summary)
+		return nil
+	}
+	if outputFile != "" {
+		if err := os.WriteFile(outputFile, []byte(block), 0644); err != nil {
+			return fmt.Errorf("failed to write context: %w", err)
+		}
+		fmt.Printf("Wrote %s to %s\n", summary, outputFile)
+	}
+	if copyBlock {
+		if err := clipboard.Init(); err != nil {
+			return fmt.Errorf("clipboard unavailable: %w", err)
+		}
+		if err := clipboard.Write(block); err != nil {
+			return fmt.Errorf("failed to copy the context: %w", err)
+		}
+		fmt.Printf("Copied %s to the clipboard\n", summary)
+	}
+	return nil
+}
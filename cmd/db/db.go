@@ -0,0 +1,415 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/neilberkman/shannon/internal/config"
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/search"
+	"github.com/neilberkman/shannon/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var (
+	noBackup bool
+	fixOrder bool
+	noVacuum bool
+)
+
+// NewCmd creates the db command
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "db",
+		Short: "Manage the database schema",
+		Long: `Upgrade the database to the schema of this version of shannon, check what
+to do with a database written by a newer version, check the order of the
+messages in each conversation, rebuild the code block and artifact index,
+compress the text of the messages, or copy them to a shared store.
+
+Other commands refuse to open a database whose schema version doesn't match
+this version of shannon.
+
+Examples:
+  shannon db upgrade
+  shannon db downgrade-check
+  shannon db check-order --fix
+  shannon db reindex
+  shannon db compress
+  shannon db push`,
+	}
+
+	cmd.AddCommand(newUpgradeCmd())
+	cmd.AddCommand(newDowngradeCheckCmd())
+	cmd.AddCommand(newCheckOrderCmd())
+	cmd.AddCommand(newReindexCmd())
+	cmd.AddCommand(newCompressCmd(true))
+	cmd.AddCommand(newCompressCmd(false))
+	cmd.AddCommand(newPushCmd())
+
+	return cmd
+}
+
+// newUpgradeCmd creates the upgrade subcommand
+func newUpgradeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "Migrate the database to this version's schema",
+		Long: `Migrate a database created by an older version of shannon to the schema of
+this version. A copy of the database is saved next to it first, named after
+its old schema version, unless --no-backup is given.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := databasePath()
+			if err != nil {
+				return err
+			}
+
+			result, err := db.Upgrade(path, noBackup)
+			if err != nil {
+				return err
+			}
+			if result.From == result.To {
+				fmt.Printf("Database is already at schema version %d\n", result.To)
+				return nil
+			}
+
+			if result.Backup != "" {
+				fmt.Printf("Backed up the database to %s\n", result.Backup)
+			}
+			fmt.Printf("Upgraded the database from schema version %d to %d\n", result.From, result.To)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&noBackup, "no-backup", false, "don't back up the database first")
+
+	return cmd
+}
+
+// newDowngradeCheckCmd creates the downgrade-check subcommand
+func newDowngradeCheckCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "downgrade-check",
+		Short: "Check a database written by a newer version",
+		Long: `Compare a database written by a newer version of shannon with the schema of
+this version, listing what this version doesn't know about and anything it
+needs that is missing, and the ways to use the data with this version.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := databasePath()
+			if err != nil {
+				return err
+			}
+
+			c, err := db.CheckDowngrade(path)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Database: %s\n", path)
+			fmt.Printf("Schema version: %d (this version of shannon uses %d)\n", c.Version, db.SchemaVersion)
+			switch {
+			case c.Version < db.SchemaVersion:
+				fmt.Println("\nThe database is older than this version. Run `shannon db upgrade` to migrate it.")
+				return nil
+			case c.Version == db.SchemaVersion:
+				fmt.Println("\nThe database matches this version; nothing to do.")
+				return nil
+			}
+
+			if len(c.Extra) > 0 {
+				fmt.Println("\nAdded by a newer version, unknown to this one:")
+				for _, object := range c.Extra {
+					fmt.Printf("  %s\n", object)
+				}
+			}
+			if len(c.Missing) > 0 {
+				fmt.Println("\nUsed by this version but missing from the database:")
+				for _, object := range c.Missing {
+					fmt.Printf("  %s\n", object)
+				}
+			}
+
+			fmt.Println()
+			if c.Compatible() {
+				fmt.Println("The database has everything this version reads, but this version could break")
+				fmt.Println("data it doesn't know about by writing to it, so it won't open it.")
+			} else {
+				fmt.Println("This version can't read the database.")
+			}
+
+			fmt.Println("\nTo use your conversations with this version, either:")
+			fmt.Println("  - upgrade shannon to a version that supports schema version", c.Version)
+			for _, backup := range c.Backups {
+				fmt.Printf("  - restore the backup made before the upgrade, losing changes since: cp %q %q\n", backup, path)
+			}
+			fmt.Println("  - move the database aside and import your exports again with this version")
+			return nil
+		},
+	}
+}
+
+// newReindexCmd creates the reindex subcommand
+func newReindexCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "reindex",
+		Short: "Rebuild the code block and artifact index",
+		Long: `Extract the code blocks and artifacts of every message again and rebuild the
+index that code search and the TUI's artifact view read from. Imports index
+the messages they add, and the index is rebuilt on its own after an upgrade
+that changes what's extracted, so this is only needed if the index is damaged.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			database, err := db.New(config.Get().Database.Path)
+			if err != nil {
+				return fmt.Errorf("failed to open database: %w", err)
+			}
+			defer func() {
+				if err := database.Close(); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+				}
+			}()
+
+			start := time.Now()
+			count, err := search.NewEngine(database).RebuildCodeIndex()
+			if err != nil {
+				return fmt.Errorf("failed to rebuild code index: %w", err)
+			}
+			fmt.Printf("Indexed %d code blocks and artifacts in %s\n", count, time.Since(start).Round(time.Millisecond))
+			return nil
+		},
+	}
+}
+
+// newCheckOrderCmd creates the check-order subcommand
+func newCheckOrderCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "check-order",
+		Short: "Find conversations whose messages are out of order",
+		Long: `List the conversations whose messages are numbered in a different order from
+the one they were written in, which comes from each message's parent and the
+time it was sent. Messages are numbered by their place in the export they
+were imported from, so importing a later export that adds messages to a
+conversation can leave it out of order; imports repair the conversations
+they add messages to.
+
+With --fix, the listed conversations are renumbered.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			database, err := db.New(config.Get().Database.Path)
+			if err != nil {
+				return fmt.Errorf("failed to open database: %w", err)
+			}
+			defer func() {
+				if err := database.Close(); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+				}
+			}()
+
+			problems, err := database.CheckOrder()
+			if err != nil {
+				return fmt.Errorf("failed to check message order: %w", err)
+			}
+			if len(problems) == 0 {
+				fmt.Println("All conversations are in order")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tMESSAGES\tMISPLACED\tDUPLICATES\tNAME")
+			for _, p := range problems {
+				fmt.Fprintf(w, "%d\t%d\t%d\t%d\t%s\n", p.ConversationID, p.Messages, p.Misplaced, p.Duplicates, p.Name)
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+
+			if !fixOrder {
+				fmt.Printf("\n%d conversation(s) out of order; run with --fix to renumber them\n", len(problems))
+				return nil
+			}
+
+			tx, err := database.Begin()
+			if err != nil {
+				return fmt.Errorf("failed to begin transaction: %w", err)
+			}
+			defer func() { _ = tx.Rollback() }()
+			moved := 0
+			for _, p := range problems {
+				n, err := db.RepairOrder(tx, p.ConversationID)
+				if err != nil {
+					return fmt.Errorf("failed to repair conversation %d: %w", p.ConversationID, err)
+				}
+				moved += n
+			}
+			if err := tx.Commit(); err != nil {
+				return fmt.Errorf("failed to commit: %w", err)
+			}
+			fmt.Printf("\nRenumbered %d message(s) in %d conversation(s)\n", moved, len(problems))
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&fixOrder, "fix", false, "renumber the conversations that are out of order")
+
+	return cmd
+}
+
+// newCompressCmd creates the compress subcommand, or decompress to undo it
+func newCompressCmd(compress bool) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "compress",
+		Short: "Store the text of the messages compressed",
+		Long: `Compress the text of every message that gets smaller for it, and store the
+text of messages imported later compressed too. Reading, searching and
+exporting work as before: text is decompressed as it's read, and the
+full-text indexes hold the plain text, so they aren't rebuilt.
+
+Text is compressed with DEFLATE. Messages under 256 bytes are left as they
+are. Afterwards the database is vacuumed so the file shrinks, which needs
+free disk space about the size of the database; --no-vacuum skips it.
+
+shannon db decompress stores the text plainly again.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := databasePath()
+			if err != nil {
+				return err
+			}
+			database, err := db.New(path)
+			if err != nil {
+				return fmt.Errorf("failed to open database: %w", err)
+			}
+			defer func() {
+				if err := database.Close(); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+				}
+			}()
+
+			var result *db.CompressionResult
+			verb, action := "Compressed", "compress"
+			if compress {
+				result, err = database.CompressMessages()
+			} else {
+				verb, action = "Decompressed", "decompress"
+				result, err = database.DecompressMessages()
+			}
+			if err != nil {
+				return err
+			}
+			if result.Messages == 0 {
+				fmt.Printf("No messages to %s\n", action)
+			} else {
+				fmt.Printf("%s %d message(s): %s of text now takes %s\n", verb, result.Messages,
+					humanize.Bytes(uint64(result.Before)), humanize.Bytes(uint64(result.After)))
+			}
+			if result.After < result.Before {
+				fmt.Printf("Saved %s (%.0f%%)\n", humanize.Bytes(uint64(result.Before-result.After)),
+					100*float64(result.Before-result.After)/float64(result.Before))
+			}
+
+			total, compressed, err := database.TextSize()
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Message text: %s, of which %s compressed\n", humanize.Bytes(uint64(total)), humanize.Bytes(uint64(compressed)))
+
+			if noVacuum || result.Messages == 0 {
+				return nil
+			}
+			before, err := os.Stat(path)
+			if err != nil {
+				return err
+			}
+			start := time.Now()
+			if err := database.Vacuum(); err != nil {
+				return err
+			}
+			after, err := os.Stat(path)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Vacuumed the database in %s: %s, was %s\n", time.Since(start).Round(time.Millisecond),
+				humanize.Bytes(uint64(after.Size())), humanize.Bytes(uint64(before.Size())))
+			return nil
+		},
+	}
+	if !compress {
+		cmd.Use = "decompress"
+		cmd.Short = "Store the text of the messages uncompressed again"
+		cmd.Long = `Undo shannon db compress: store the text of every message uncompressed, and
+the text of messages imported later too. The database is vacuumed afterwards
+unless --no-vacuum is given.`
+	}
+
+	cmd.Flags().BoolVar(&noVacuum, "no-vacuum", false, "don't vacuum the database afterwards")
+
+	return cmd
+}
+
+// newPushCmd creates the push subcommand
+func newPushCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "push",
+		Short: "Copy the local database to the shared store",
+		Long: `Copy the conversations of the local database at database.path to the shared
+store database.driver names, adding those it doesn't have and the messages
+they're missing, with all their branches. Pushing again copies what changed
+since; nothing is removed from the shared store.
+
+Ratings, aliases, slugs, notes and the other things shannon keeps about
+conversations stay in the local database.`,
+		Annotations: map[string]string{store.Annotation: "true"},
+		Args:        cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := config.Get()
+			if !store.Shared(cfg) {
+				return fmt.Errorf("database.driver is %s; set it to %s and database.url to push to a shared store", cfg.Database.Driver, store.DriverPostgres)
+			}
+			path, err := databasePath()
+			if err != nil {
+				return err
+			}
+
+			local, err := store.OpenSQLite(path, cfg.Import.BatchSize)
+			if err != nil {
+				return fmt.Errorf("failed to open database: %w", err)
+			}
+			defer func() {
+				if err := local.Close(); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+				}
+			}()
+			shared, err := store.Open(cfg)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if err := shared.Close(); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: failed to close %s store: %v\n", cfg.Database.Driver, err)
+				}
+			}()
+
+			stats, err := store.Copy(local, shared, path)
+			if err != nil {
+				return fmt.Errorf("push failed: %w", err)
+			}
+			fmt.Printf("Pushed %d new conversation(s) and %d message(s) to the %s store in %s\n",
+				stats.ConversationsImported, stats.MessagesImported, cfg.Database.Driver, stats.Duration.Round(time.Millisecond))
+			return nil
+		},
+	}
+}
+
+// databasePath returns the configured database, which must already exist
+func databasePath() (string, error) {
+	path := config.Get().Database.Path
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("no database at %s: %w", path, err)
+	}
+	return path, nil
+}
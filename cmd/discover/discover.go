@@ -10,6 +10,8 @@ import (
 
 	imports "github.com/neilberkman/shannon/cmd/import"
 	"github.com/neilberkman/shannon/internal/discovery"
+	"github.com/neilberkman/shannon/internal/rendering"
+	"github.com/neilberkman/shannon/internal/store"
 	"github.com/spf13/cobra"
 )
 
@@ -38,7 +40,8 @@ Examples:
   shannon discover --include ~/Documents             # Also search Documents folder
   shannon discover --auto-import                     # Import any new valid exports found
   shannon discover --show-invalid                    # Show files that look like exports but are invalid`,
-	RunE: runDiscover,
+	Annotations: map[string]string{store.Annotation: "true"},
+	RunE:        runDiscover,
 }
 
 func init() {
@@ -131,7 +134,7 @@ func runDiscover(cmd *cobra.Command, args []string) error {
 		for path, export := range uniqueExports {
 			// Skip zip files for now (would need to extract first)
 			if strings.Contains(export.Path, "!") {
-				fmt.Printf("  ⚠️  Skipping zip file: %s (extraction not yet supported)\n", filepath.Base(path))
+				fmt.Printf("  %sSkipping zip file: %s (extraction not yet supported)\n", rendering.Symbol("⚠️  ", ""), filepath.Base(path))
 				continue
 			}
 
@@ -149,7 +152,7 @@ func runDiscover(cmd *cobra.Command, args []string) error {
 				}
 			} else {
 				successCount++
-				fmt.Printf("✓ imported\n")
+				fmt.Printf("%simported\n", rendering.Symbol("✓ ", ""))
 			}
 		}
 
@@ -184,9 +187,9 @@ func displayExportTable(exports []*discovery.ExportFile) error {
 	}
 
 	for _, export := range exports {
-		status := "✓"
+		status := rendering.Symbol("✓", "valid")
 		if !export.IsValid {
-			status = "✗"
+			status = rendering.Symbol("✗", "invalid")
 		}
 
 		filename := filepath.Base(export.Path)
@@ -231,7 +234,7 @@ func displayExportTable(exports []*discovery.ExportFile) error {
 	// Show invalid files with errors
 	for _, export := range exports {
 		if !export.IsValid {
-			fmt.Printf("⚠️  %s: %s\n", filepath.Base(export.Path), export.ErrorMessage)
+			fmt.Printf("%s%s: %s\n", rendering.Symbol("⚠️  ", ""), filepath.Base(export.Path), export.ErrorMessage)
 		}
 	}
 
@@ -0,0 +1,111 @@
+package doctor
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/dustin/go-humanize"
+	"github.com/neilberkman/shannon/internal/config"
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/discovery"
+	"github.com/neilberkman/shannon/internal/rendering"
+	"github.com/neilberkman/shannon/internal/store"
+	"github.com/spf13/cobra"
+)
+
+// DoctorCmd represents the doctor command
+var DoctorCmd = &cobra.Command{
+	Use:     "doctor",
+	Aliases: []string{"paths"},
+	Short:   "Show where shannon keeps its files and check the database",
+	Long: `Show the config file, the database with its size and schema version, the
+directories searched for exports and what the terminal supports, then check
+the database: SQLite's integrity check, whether the full-text indexes match
+the messages, and rows left behind by deleted conversations or messages.
+
+Nothing is changed. The command fails if a check does, so it can be used in
+scripts. Compare its output across machines when an installation misbehaves.
+
+Examples:
+  shannon doctor
+  shannon paths`,
+	Args:        cobra.NoArgs,
+	Annotations: map[string]string{store.Annotation: "true"},
+	RunE:        runDoctor,
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	cfg := config.Get()
+	dirs := config.GetDirs()
+
+	fmt.Println("Files:")
+	if file := config.File(); file != "" {
+		fmt.Printf("  Config file:  %s\n", file)
+	} else {
+		fmt.Printf("  Config file:  none, using defaults (create %s)\n", filepath.Join(dirs.Config, "config.yaml"))
+	}
+	fmt.Printf("  Data dir:     %s\n", dirs.Data)
+	fmt.Printf("  Database:     %s\n", cfg.Database.Path)
+	if store.Shared(cfg) {
+		fmt.Printf("  Shared store: %s at database.url; search, list, view, export, stats and import use it, the TUI doesn't\n", cfg.Database.Driver)
+	}
+
+	inspection, err := db.Inspect(cfg.Database.Path)
+	if err != nil {
+		fmt.Printf("                %v\n", err)
+	} else {
+		fmt.Printf("  Size:         %s\n", humanize.Bytes(uint64(inspection.Size)))
+		switch {
+		case inspection.Version == 0:
+			fmt.Printf("  Schema:       not created yet; import an export first\n")
+		case inspection.Version != db.SchemaVersion:
+			fmt.Printf("  Schema:       version %d, but this version of shannon uses %d\n", inspection.Version, db.SchemaVersion)
+			fmt.Printf("                %v\n", &db.VersionError{Path: inspection.Path, Version: inspection.Version})
+		default:
+			fmt.Printf("  Schema:       version %d\n", inspection.Version)
+			fmt.Printf("  Contents:     %d conversations, %d messages\n", inspection.Conversations, inspection.Messages)
+		}
+	}
+
+	fmt.Println("\nExport search paths (shannon discover):")
+	for _, path := range discovery.NewScanner().GetSearchPaths() {
+		fmt.Printf("  %s\n", path)
+	}
+
+	caps := rendering.DetectTerminalCapabilities()
+	fmt.Println("\nTerminal (shannon terminal):")
+	fmt.Printf("  Type:         %s\n", caps.TerminalType)
+	fmt.Printf("  Hyperlinks:   %s\n", yesNo(caps.SupportsHyperlinks))
+	fmt.Printf("  Graphics:     %s\n", yesNo(caps.SupportsGraphics))
+	input := "basic"
+	if caps.SupportsAdvancedInput {
+		input = "advanced"
+	}
+	fmt.Printf("  Input:        %s\n", input)
+
+	if inspection == nil || len(inspection.Checks) == 0 {
+		return nil
+	}
+
+	fmt.Println("\nHealth checks:")
+	failed := 0
+	for _, check := range inspection.Checks {
+		if check.OK {
+			fmt.Printf("  %s %s\n", rendering.Symbol("✓", "ok"), check.Name)
+			continue
+		}
+		failed++
+		fmt.Printf("  %s %s: %s\n", rendering.Symbol("✗", "FAIL"), check.Name, check.Detail)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d health checks failed", failed, len(inspection.Checks))
+	}
+	return nil
+}
+
+func yesNo(ok bool) string {
+	if ok {
+		return "yes"
+	}
+	return "no"
+}
@@ -6,11 +6,11 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"strconv"
 
 	"github.com/neilberkman/shannon/internal/config"
 	"github.com/neilberkman/shannon/internal/db"
 	"github.com/neilberkman/shannon/internal/models"
+	"github.com/neilberkman/shannon/internal/rendering"
 	"github.com/neilberkman/shannon/internal/search"
 	"github.com/spf13/cobra"
 )
@@ -22,7 +22,7 @@ var (
 
 // EditCmd represents the edit command
 var EditCmd = &cobra.Command{
-	Use:   "edit [conversation-id]",
+	Use:   "edit [conversation]",
 	Short: "Open a conversation in your editor",
 	Long: `Open a conversation in your editor for viewing or editing.
 
@@ -49,12 +49,6 @@ func init() {
 }
 
 func runEdit(cmd *cobra.Command, args []string) error {
-	// Parse conversation ID
-	convID, err := strconv.ParseInt(args[0], 10, 64)
-	if err != nil {
-		return fmt.Errorf("invalid conversation ID: %w", err)
-	}
-
 	// Get configuration
 	cfg := config.Get()
 
@@ -72,6 +66,12 @@ func runEdit(cmd *cobra.Command, args []string) error {
 	// Create search engine
 	engine := search.NewEngine(database)
 
+	// Accept a slug or alias in place of the ID
+	convID, err := engine.ResolveConversation(args[0])
+	if err != nil {
+		return err
+	}
+
 	// Get conversation and messages
 	conv, messages, err := engine.GetConversation(convID)
 	if err != nil {
@@ -161,11 +161,7 @@ func formatMarkdown(conv *models.Conversation, messages []*models.Message) strin
 	for i, msg := range messages {
 		timestamp := msg.CreatedAt.Format("2006-01-02 15:04:05")
 
-		if msg.Sender == "human" {
-			content += fmt.Sprintf("## Human (%s)\n\n", timestamp)
-		} else {
-			content += fmt.Sprintf("## Assistant (%s)\n\n", timestamp)
-		}
+		content += fmt.Sprintf("## %s (%s)\n\n", rendering.FormatSender(msg.Sender), timestamp)
 
 		content += msg.Text + "\n\n"
 
@@ -188,14 +184,7 @@ func formatText(conv *models.Conversation, messages []*models.Message) string {
 
 	for _, msg := range messages {
 		timestamp := msg.CreatedAt.Format("2006-01-02 15:04:05")
-		sender := msg.Sender
-		if sender == "human" {
-			sender = "HUMAN"
-		} else {
-			sender = "ASSISTANT"
-		}
-
-		content += fmt.Sprintf("[%s] %s\n", timestamp, sender)
+		content += fmt.Sprintf("[%s] %s\n", timestamp, rendering.FormatSender(msg.Sender))
 		content += "----------------------------------------\n"
 		content += msg.Text + "\n\n"
 	}
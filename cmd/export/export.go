@@ -2,19 +2,26 @@ package export
 
 import (
 	"bufio"
-	"encoding/json"
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/neilberkman/shannon/internal/config"
+	"github.com/neilberkman/shannon/internal/dates"
 	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/export"
+	"github.com/neilberkman/shannon/internal/filter"
 	"github.com/neilberkman/shannon/internal/models"
-	"github.com/neilberkman/shannon/internal/rendering"
+	"github.com/neilberkman/shannon/internal/repeats"
+	"github.com/neilberkman/shannon/internal/schema"
 	"github.com/neilberkman/shannon/internal/search"
+	"github.com/neilberkman/shannon/internal/store"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
 var (
@@ -23,13 +30,39 @@ var (
 	outputDir    string
 	stdout       bool
 	quiet        bool
+	query        string
+	matchingOnly bool
+	onlySender   string
+	includes     []string
+	excludes     []string
+	collapse     bool
+	maxResults   int
+	after        string
+	before       string
+	printSchema  bool
+	chain        bool
+
+	// messageFilter picks the messages exported, from --only and the patterns
+	messageFilter *filter.Filter
+
+	// Wiki publishing
+	wikiToken  string
+	wikiParent string
+	wikiURL    string
+	wikiUser   string
+	wikiSpace  string
+
+	// Gists
+	gist   bool
+	public bool
 )
 
 // ExportCmd represents the export command
 var ExportCmd = &cobra.Command{
-	Use:   "export [conversation-id...]",
-	Short: "Export conversations to files",
-	Long: `Export one or more conversations to files in various formats.
+	Use:   "export [conversation...]",
+	Short: "Export conversations to files, wikis or gists",
+	Long: `Export one or more conversations to files in various formats, publish
+them as pages in Notion or Confluence, or share them as GitHub gists.
 
 Examples:
   # Export single conversation (stdout by default)
@@ -49,20 +82,157 @@ Examples:
   claudesearch export 123 --format json | jq '.messages[].text'
   
   # Read IDs from stdin with -
-  claudesearch search "bug" --format json | jq -r '.results[].conversation_id' | claudesearch export -`,
-	Args: cobra.MinimumNArgs(1),
-	RunE: runExport,
+  claudesearch search "bug" --format json | jq -r '.results[].ConversationID' | claudesearch export -
+
+  # Export every conversation matching a search
+  claudesearch export --query "kubernetes" -d exports/
+
+  # Only include the messages that matched
+  claudesearch export --query "kubernetes" -d exports/ --matching-only
+
+  # Just Claude's answers, or just your prompts, e.g. for a prompt library
+  claudesearch export 123 --only assistant
+  claudesearch export --query "regex" -d prompts/ --only human
+
+  # Leave out messages that just say "continue", or keep only those
+  # mentioning a word
+  claudesearch export 123 --exclude-pattern '(?i)^\s*continue\W*$'
+  claudesearch export 123 --include-pattern '(?i)kubernetes|k8s'
+
+  # Collapse content quoted back from earlier messages, like pasted logs
+  claudesearch export 123 --collapse-quotes
+
+  # Only matches from the last month, or from 2024
+  claudesearch export --query "kubernetes" -d exports/ --after 30d
+  claudesearch export --query "kubernetes" -d exports/ --after @2024 --before @2025
+
+  # Write conversations back out in Claude's own export format, all in one
+  # conversations.json, with every branch
+  claudesearch export 123 456 --format claude-json -o conversations.json
+  claudesearch export --query "kubernetes" --format claude-json -d subset/
+
+  # An e-book for an e-reader, with a chapter per conversation
+  claudesearch export 123 456 --format epub -o reading.epub
+  claudesearch export --query "kubernetes" --format epub -d books/
+
+  # Turn a conversation into a slide deck, one question and answer per slide
+  # and a code slide per artifact, for Marp or as a reveal.js page
+  claudesearch export 123 --format marp -o walkthrough.md
+  claudesearch export 123 --format reveal -o walkthrough.html
+
+  # Slack mrkdwn or Discord Markdown, cut into chunks that each fit in a
+  # message, to paste into a channel
+  claudesearch export 123 --format slack
+  claudesearch export 123 --format discord
+
+  # Publish to Notion under a page shared with your integration
+  claudesearch export 123 --format notion --token secret_xxx --parent <page-id>
+
+  # Publish to Confluence Cloud
+  claudesearch export 123 --format confluence --url https://example.atlassian.net/wiki \
+    --user me@example.com --token <api-token> --space ENG
+
+  # A conversation and the ones it continues and is continued in (see
+  # 'shannon link') as one document, or one e-book
+  claudesearch export 456 --chain -o project.md
+  claudesearch export 456 --chain --format epub -o project.epub
+
+  # Share as a secret GitHub gist, with each artifact as a file of its own
+  claudesearch export 123 --gist --token <github-token>
+  claudesearch export 123 --gist --public
+
+Credentials can also be set in the config file under export.notion,
+export.confluence and export.gist; gists also use GITHUB_TOKEN.
+
+JSON output follows a published schema (see --schema) and carries a
+schema_version field that changes only on breaking changes.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if printSchema {
+			return nil
+		}
+		if onlySender != "" && onlySender != "human" && onlySender != "assistant" {
+			return fmt.Errorf("--only must be human or assistant, not %q", onlySender)
+		}
+		if collapse && outputFormat == "claude-json" {
+			return fmt.Errorf("--collapse-quotes can't be used with --format claude-json, which keeps messages as they are")
+		}
+		if query != "" {
+			if len(args) > 0 {
+				return fmt.Errorf("cannot combine --query with conversation IDs")
+			}
+			if chain {
+				return fmt.Errorf("--chain cannot be combined with --query")
+			}
+			return nil
+		}
+		if chain {
+			if outputDir != "" {
+				return fmt.Errorf("--chain exports one document; use -o instead of -d")
+			}
+			return cobra.ExactArgs(1)(cmd, args)
+		}
+		if matchingOnly {
+			return fmt.Errorf("--matching-only requires --query")
+		}
+		if after != "" || before != "" {
+			return fmt.Errorf("--after and --before require --query")
+		}
+		return cobra.MinimumNArgs(1)(cmd, args)
+	},
+	RunE:        runExport,
+	Annotations: map[string]string{store.Annotation: "true"},
 }
 
 func init() {
-	ExportCmd.Flags().StringVarP(&outputFormat, "format", "f", "markdown", "output format: markdown, text, or json")
+	ExportCmd.Flags().StringVarP(&outputFormat, "format", "f", "markdown", "output format: markdown, text, json, html, marp, reveal, slack, discord, claude-json, epub, notion, confluence or gist")
 	ExportCmd.Flags().StringVarP(&outputFile, "output", "o", "", "output to file instead of stdout")
 	ExportCmd.Flags().StringVarP(&outputDir, "dir", "d", "", "output directory (required for multiple conversations)")
 	ExportCmd.Flags().BoolVar(&stdout, "stdout", false, "force output to stdout (deprecated, now default)")
 	ExportCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "suppress status messages")
+	ExportCmd.Flags().StringVar(&query, "query", "", "export all conversations matching this search query")
+	ExportCmd.Flags().BoolVar(&matchingOnly, "matching-only", false, "with --query, only include messages that matched")
+	ExportCmd.Flags().StringVar(&onlySender, "only", "", "only include the messages of one sender: assistant or human")
+	ExportCmd.Flags().StringArrayVar(&includes, "include-pattern", nil, "only include messages matching this regular expression (repeatable: any of them)")
+	ExportCmd.Flags().StringArrayVar(&excludes, "exclude-pattern", nil, "leave out messages matching this regular expression (repeatable)")
+	ExportCmd.Flags().BoolVar(&collapse, "collapse-quotes", false, "collapse content repeated from earlier messages, like quoted pastes, into a placeholder")
+	ExportCmd.Flags().IntVar(&maxResults, "max-results", 1000, "with --query, maximum number of matching messages to consider")
+	ExportCmd.Flags().StringVar(&after, "after", "", "with --query, only matches from this date or age on (2024-06-01, 30d, @2024)")
+	ExportCmd.Flags().StringVar(&before, "before", "", "with --query, only matches before this date or age")
+	ExportCmd.Flags().StringVar(&wikiToken, "token", "", "Notion integration token, Confluence API token or GitHub token")
+	ExportCmd.Flags().StringVar(&wikiParent, "parent", "", "ID of the Notion or Confluence page to publish under")
+	ExportCmd.Flags().StringVar(&wikiURL, "url", "", "Confluence base URL, e.g. https://example.atlassian.net/wiki")
+	ExportCmd.Flags().StringVar(&wikiUser, "user", "", "Confluence user email (omit to use the token as a personal access token)")
+	ExportCmd.Flags().StringVar(&wikiSpace, "space", "", "Confluence space key")
+	ExportCmd.Flags().BoolVar(&gist, "gist", false, "share as a GitHub gist, printing its URL (same as --format gist)")
+	ExportCmd.Flags().BoolVar(&public, "public", false, "with --gist, make the gist public instead of secret")
+	ExportCmd.Flags().BoolVar(&chain, "chain", false, "export the chain of linked conversations the conversation is part of as one document")
+	ExportCmd.Flags().BoolVar(&printSchema, "schema", false, "print the JSON Schema of --format json output and exit")
 }
 
 func runExport(cmd *cobra.Command, args []string) error {
+	if printSchema {
+		return schema.Write(os.Stdout, "export")
+	}
+	var err error
+	if messageFilter, err = filter.New(onlySender, includes, excludes); err != nil {
+		return err
+	}
+	if gist {
+		if cmd.Flags().Changed("format") && outputFormat != "gist" {
+			return fmt.Errorf("--gist cannot be combined with --format %s", outputFormat)
+		}
+		outputFormat = "gist"
+	}
+	if public && outputFormat != "gist" {
+		return fmt.Errorf("--public requires --gist")
+	}
+	if export.IsPublisher(outputFormat) && (outputFile != "" || outputDir != "") {
+		return fmt.Errorf("--format %s publishes online and cannot be combined with -o or -d", outputFormat)
+	}
+	if query != "" {
+		return runQueryExport()
+	}
+
 	// Handle stdin input with "-"
 	if len(args) == 1 && args[0] == "-" {
 		// Read IDs from stdin
@@ -82,16 +252,20 @@ func runExport(cmd *cobra.Command, args []string) error {
 		}
 	}
 	// Validate arguments
-	if len(args) > 1 && outputFile != "" {
+	combined := combinedFormat()
+	if len(args) > 1 && outputFile != "" && !combined {
 		return fmt.Errorf("cannot use -o with multiple conversations, use -d instead")
 	}
 
-	if len(args) > 1 && outputDir == "" {
+	if len(args) > 1 && outputDir == "" && !export.IsPublisher(outputFormat) && !combined {
 		return fmt.Errorf("multiple conversations require -d flag to specify output directory")
 	}
 
 	// Get configuration
 	cfg := config.Get()
+	if store.Shared(cfg) {
+		return exportShared(cfg, args)
+	}
 
 	// Open database
 	database, err := db.New(cfg.Database.Path)
@@ -107,39 +281,242 @@ func runExport(cmd *cobra.Command, args []string) error {
 	// Create search engine
 	engine := search.NewEngine(database)
 
-	// Export each conversation
+	if chain {
+		convID, err := engine.ResolveConversation(args[0])
+		if err != nil {
+			return err
+		}
+		return exportChain(engine, convID)
+	}
+
+	var convIDs []int64
 	for _, idStr := range args {
-		convID, err := strconv.ParseInt(idStr, 10, 64)
+		convID, err := engine.ResolveConversation(idStr)
+		if err != nil {
+			return err
+		}
+		convIDs = append(convIDs, convID)
+	}
+	return exportConversations(engine, convIDs)
+}
+
+// exportShared exports conversations from a shared store, which takes
+// conversation IDs only and has no links between conversations to chain
+func exportShared(cfg *config.Config, args []string) error {
+	if chain {
+		return fmt.Errorf("--chain isn't supported with the %s store", cfg.Database.Driver)
+	}
+	var convIDs []int64
+	for _, ref := range args {
+		convID, err := strconv.ParseInt(ref, 10, 64)
 		if err != nil {
-			return fmt.Errorf("invalid conversation ID %s: %w", idStr, err)
+			return fmt.Errorf("conversation %q not found; the %s store takes conversation IDs", ref, cfg.Database.Driver)
+		}
+		convIDs = append(convIDs, convID)
+	}
+
+	s, err := store.Open(cfg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+		}
+	}()
+	return exportConversations(s, convIDs)
+}
+
+// exportConversations exports the conversations given on the command line,
+// together in a combined format or each on its own
+func exportConversations(a archive, convIDs []int64) error {
+	if combinedFormat() {
+		if err := exportCombined(a, convIDs, nil, ""); err != nil {
+			return err
 		}
+		logExports(a, convIDs...)
+		return nil
+	}
 
-		if err := exportConversation(engine, convID, len(args) > 1, quiet); err != nil {
+	for _, convID := range convIDs {
+		if err := exportConversation(a, convID, len(convIDs) > 1, quiet, nil); err != nil {
 			return fmt.Errorf("failed to export conversation %d: %w", convID, err)
 		}
+		logExports(a, convID)
 	}
 
 	return nil
 }
 
-func exportConversation(engine *search.Engine, convID int64, multiple bool, quiet bool) error {
+// runQueryExport searches for the query and exports every conversation with a match
+func runQueryExport() error {
+	if outputFile != "" && !combinedFormat() {
+		return fmt.Errorf("cannot use -o with --query, use -d instead")
+	}
+
+	cfg := config.Get()
+	if store.Shared(cfg) {
+		s, err := store.Open(cfg)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := s.Close(); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+			}
+		}()
+		return exportQuery(s)
+	}
+
+	database, err := db.New(cfg.Database.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer func() {
+		if err := database.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+		}
+	}()
+
+	engine := search.NewEngine(database)
+	engine.SetDictionary(search.NewDictionary(cfg.Search.Stopwords, cfg.Search.Synonyms))
+	return exportQuery(engine)
+}
+
+// archive is what conversations are exported from: the local search engine
+// or a shared store
+type archive interface {
+	Search(opts search.SearchOptions) ([]*models.SearchResult, error)
+	GetConversation(conversationID int64) (*models.Conversation, []*models.Message, error)
+	GetAllMessages(conversationID int64) ([]*models.Message, error)
+}
+
+// exportQuery exports the conversations matching --query, most relevant
+// first
+func exportQuery(engine archive) error {
+	combined := combinedFormat()
+	opts := search.SearchOptions{
+		Query:     query,
+		Limit:     maxResults,
+		SortBy:    "relevance",
+		SortOrder: "desc",
+	}
+	if after != "" {
+		t, err := dates.Parse(after, time.Now())
+		if err != nil {
+			return fmt.Errorf("invalid --after: %w", err)
+		}
+		opts.StartDate = &t
+	}
+	if before != "" {
+		t, err := dates.Parse(before, time.Now())
+		if err != nil {
+			return fmt.Errorf("invalid --before: %w", err)
+		}
+		opts.EndDate = &t
+	}
+
+	results, err := engine.Search(opts)
+	if err != nil {
+		return fmt.Errorf("search failed: %w", err)
+	}
+
+	// Group matches by conversation, most relevant conversation first
+	var convIDs []int64
+	matches := make(map[int64]map[int64]bool)
+	for _, r := range results {
+		if _, ok := matches[r.ConversationID]; !ok {
+			matches[r.ConversationID] = make(map[int64]bool)
+			convIDs = append(convIDs, r.ConversationID)
+		}
+		matches[r.ConversationID][r.MessageID] = true
+	}
+
+	if len(convIDs) == 0 {
+		if !quiet {
+			fmt.Fprintf(os.Stderr, "No conversations match %q\n", query)
+		}
+		return nil
+	}
+
+	if combined {
+		var only map[int64]map[int64]bool
+		if matchingOnly {
+			only = matches
+		}
+		if err := exportCombined(engine, convIDs, only, fmt.Sprintf("Claude conversations about %s", query)); err != nil {
+			return err
+		}
+		logExports(engine, convIDs...)
+		return nil
+	}
+
+	if len(convIDs) > 1 && outputDir == "" && !export.IsPublisher(outputFormat) {
+		return fmt.Errorf("%d conversations match %q; use -d to specify an output directory", len(convIDs), query)
+	}
+
+	for _, convID := range convIDs {
+		var only map[int64]bool
+		if matchingOnly {
+			only = matches[convID]
+		}
+		if err := exportConversation(engine, convID, len(convIDs) > 1, quiet, only); err != nil {
+			return fmt.Errorf("failed to export conversation %d: %w", convID, err)
+		}
+		logExports(engine, convID)
+	}
+
+	if !quiet && outputDir != "" {
+		fmt.Printf("Exported %d conversations matching %q to %s\n", len(convIDs), query, outputDir)
+	}
+
+	return nil
+}
+
+// logExports records the exports in the access log for `shannon stats
+// --usage`. A shared store keeps no access log.
+func logExports(a archive, convIDs ...int64) {
+	engine, ok := a.(*search.Engine)
+	if !ok {
+		return
+	}
+	for _, convID := range convIDs {
+		if err := engine.LogAccess(convID, search.AccessExport); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			return
+		}
+	}
+}
+
+// exportConversation writes a single conversation. If only is non-nil, just
+// the messages with those IDs are included.
+func exportConversation(engine archive, convID int64, multiple bool, quiet bool, only map[int64]bool) error {
 	// Get conversation and messages
-	conv, messages, err := engine.GetConversation(convID)
+	conv, messages, err := exportMessages(engine, convID, only != nil)
 	if err != nil {
 		return err
 	}
 
-	// Generate content based on format
-	var content string
-	switch outputFormat {
-	case "json":
-		content, err = formatJSON(conv, messages)
-	case "text":
-		content = formatText(conv, messages)
-	default: // markdown
-		content = formatMarkdown(conv, messages)
+	// Collapse repeats across the whole conversation, before any messages
+	// are left out, so the first occurrence is the one that's kept
+	if collapse {
+		messages, _ = repeats.Collapse(messages, repeats.DefaultMinLines)
+	}
+	messages = keepMessages(messages, only)
+
+	if export.IsPublisher(outputFormat) {
+		url, err := newPublisher(outputFormat).Publish(context.Background(), conv, messages)
+		if err != nil {
+			return err
+		}
+		if !quiet {
+			fmt.Printf("Published conversation %d to %s\n", conv.ID, url)
+		}
+		return nil
 	}
 
+	// Generate content based on format
+	content, err := export.Render(outputFormat, conv, messages)
 	if err != nil {
 		return err
 	}
@@ -164,15 +541,7 @@ func exportConversation(engine *search.Engine, convID int64, multiple bool, quie
 			safeName = safeName[:100]
 		}
 
-		ext := ".md"
-		switch outputFormat {
-		case "json":
-			ext = ".json"
-		case "text":
-			ext = ".txt"
-		}
-
-		filename = fmt.Sprintf("%d-%s%s", conv.ID, safeName, ext)
+		filename = fmt.Sprintf("%d-%s%s", conv.ID, safeName, export.Extension(outputFormat))
 
 		if outputDir != "" {
 			filename = filepath.Join(outputDir, filename)
@@ -198,79 +567,247 @@ func exportConversation(engine *search.Engine, convID int64, multiple bool, quie
 	return nil
 }
 
-func formatMarkdown(conv *models.Conversation, messages []*models.Message) string {
-	var sb strings.Builder
+// exportChain writes the chain of conversations a conversation is part of,
+// from the first to the last, as one document: the -o file or stdout
+func exportChain(engine *search.Engine, convID int64) error {
+	convIDs, err := engine.Chain(convID)
+	if err != nil {
+		return err
+	}
+
+	if combinedFormat() {
+		first, _, err := engine.GetConversation(convIDs[0])
+		if err != nil {
+			return fmt.Errorf("failed to export conversation %d: %w", convIDs[0], err)
+		}
+		if err := exportCombined(engine, convIDs, nil, first.Name); err != nil {
+			return err
+		}
+		logExports(engine, convIDs...)
+		return nil
+	}
 
-	// Header
-	sb.WriteString(fmt.Sprintf("# %s\n\n", conv.Name))
-	sb.WriteString(fmt.Sprintf("**ID:** %d  \n", conv.ID))
-	sb.WriteString(fmt.Sprintf("**Created:** %s  \n", conv.CreatedAt.Format("2006-01-02 15:04:05")))
-	sb.WriteString(fmt.Sprintf("**Updated:** %s  \n", conv.UpdatedAt.Format("2006-01-02 15:04:05")))
-	sb.WriteString(fmt.Sprintf("**Messages:** %d  \n\n", len(messages)))
-	sb.WriteString("---\n\n")
+	parts := make([]export.ChainPart, len(convIDs))
+	for i, id := range convIDs {
+		conv, messages, err := engine.GetConversation(id)
+		if err != nil {
+			return fmt.Errorf("failed to export conversation %d: %w", id, err)
+		}
+		if collapse {
+			messages, _ = repeats.Collapse(messages, repeats.DefaultMinLines)
+		}
+		parts[i] = export.ChainPart{Conversation: conv, Messages: keepMessages(messages, nil)}
+	}
 
-	// Messages
-	for i, msg := range messages {
-		timestamp := msg.CreatedAt.Format("2006-01-02 15:04:05")
+	content, err := export.RenderChain(outputFormat, parts)
+	if err != nil {
+		return err
+	}
 
-		displaySender := rendering.FormatSender(msg.Sender)
-		sb.WriteString(fmt.Sprintf("## %s (%s)\n\n", displaySender, timestamp))
+	if outputFile == "" {
+		fmt.Print(content)
+	} else {
+		if dir := filepath.Dir(outputFile); dir != "." && dir != "" {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return fmt.Errorf("failed to create directory: %w", err)
+			}
+		}
+		if err := os.WriteFile(outputFile, []byte(content), 0644); err != nil {
+			return fmt.Errorf("failed to write file: %w", err)
+		}
+		if !quiet {
+			fmt.Printf("Exported %d linked conversations to %s\n", len(parts), outputFile)
+		}
+	}
+	logExports(engine, convIDs...)
+	return nil
+}
 
-		// Handle code blocks in message text
-		text := strings.ReplaceAll(msg.Text, "```", "````")
-		sb.WriteString(text)
-		sb.WriteString("\n\n")
+// exportMessages returns a conversation with its main branch, or with the
+// messages of every branch when only matches are exported, so a match on
+// another branch isn't lost
+func exportMessages(engine archive, convID int64, matchesOnly bool) (*models.Conversation, []*models.Message, error) {
+	conv, messages, err := engine.GetConversation(convID)
+	if err != nil || !matchesOnly {
+		return conv, messages, err
+	}
+	messages, err = engine.GetAllMessages(convID)
+	return conv, messages, err
+}
 
-		// Add separator between messages (except last)
-		if i < len(messages)-1 {
-			sb.WriteString("---\n\n")
+// keepMessages returns the messages passing --only and the patterns. If ids
+// is non-nil, just the messages with those IDs are kept.
+func keepMessages(messages []*models.Message, ids map[int64]bool) []*models.Message {
+	if ids != nil {
+		var kept []*models.Message
+		for _, msg := range messages {
+			if ids[msg.ID] {
+				kept = append(kept, msg)
+			}
 		}
+		messages = kept
 	}
+	return messageFilter.Apply(messages)
+}
 
-	return sb.String()
+// combinedFormat reports whether --format puts every conversation in one
+// file, so -o and stdout can take several
+func combinedFormat() bool {
+	return outputFormat == export.FormatClaudeJSON || outputFormat == export.FormatEPUB
 }
 
-func formatText(conv *models.Conversation, messages []*models.Message) string {
-	var sb strings.Builder
+// exportCombined writes the conversations to a single file in a combined
+// format. The title names an e-book; empty names it after its conversations.
+func exportCombined(engine archive, convIDs []int64, only map[int64]map[int64]bool, title string) error {
+	if outputFormat == export.FormatEPUB {
+		return exportEPUB(engine, convIDs, only, title)
+	}
+	return exportClaudeJSON(engine, convIDs, only)
+}
 
-	// Header
-	sb.WriteString(fmt.Sprintf("CONVERSATION: %s\n", conv.Name))
-	sb.WriteString(fmt.Sprintf("ID: %d\n", conv.ID))
-	sb.WriteString(fmt.Sprintf("Created: %s\n", conv.CreatedAt.Format("2006-01-02 15:04:05")))
-	sb.WriteString(fmt.Sprintf("Updated: %s\n", conv.UpdatedAt.Format("2006-01-02 15:04:05")))
-	sb.WriteString(fmt.Sprintf("Messages: %d\n", len(messages)))
-	sb.WriteString(strings.Repeat("=", 80) + "\n\n")
+// exportClaudeJSON writes the conversations, with all their branches, to a
+// single file in Claude's export format: the -o file, conversations.json in
+// the -d directory, or stdout. If only is non-nil, just the listed messages
+// of each conversation are included.
+func exportClaudeJSON(engine archive, convIDs []int64, only map[int64]map[int64]bool) error {
+	var claude export.ClaudeExport
+	for _, convID := range convIDs {
+		conv, _, err := engine.GetConversation(convID)
+		if err != nil {
+			return fmt.Errorf("failed to export conversation %d: %w", convID, err)
+		}
+		messages, err := engine.GetAllMessages(convID)
+		if err != nil {
+			return fmt.Errorf("failed to export conversation %d: %w", convID, err)
+		}
+
+		var ids map[int64]bool
+		if only != nil {
+			ids = only[convID]
+			if ids == nil {
+				ids = map[int64]bool{}
+			}
+		}
+		claude.Add(conv, keepMessages(messages, ids))
+	}
 
-	// Messages
-	for _, msg := range messages {
-		timestamp := msg.CreatedAt.Format("2006-01-02 15:04:05")
-		sender := strings.ToUpper(msg.Sender)
+	content, err := claude.JSON()
+	if err != nil {
+		return err
+	}
 
-		sb.WriteString(fmt.Sprintf("[%s] %s\n", timestamp, sender))
-		sb.WriteString(strings.Repeat("-", 40) + "\n")
-		sb.WriteString(msg.Text)
-		sb.WriteString("\n\n")
+	filename := outputFile
+	if filename == "" && outputDir != "" {
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+		filename = filepath.Join(outputDir, "conversations.json")
+	}
+	if filename == "" {
+		fmt.Print(content)
+		return nil
 	}
 
-	return sb.String()
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if !quiet {
+		fmt.Printf("Exported %d conversations to %s\n", claude.Len(), filename)
+	}
+	return nil
 }
 
-func formatJSON(conv *models.Conversation, messages []*models.Message) (string, error) {
-	data := map[string]interface{}{
-		"conversation": map[string]interface{}{
-			"id":         conv.ID,
-			"uuid":       conv.UUID,
-			"name":       conv.Name,
-			"created_at": conv.CreatedAt,
-			"updated_at": conv.UpdatedAt,
-		},
-		"messages": messages,
+// exportEPUB writes the conversations as the chapters of an e-book: the -o
+// file, conversations.epub in the -d directory, or stdout if it isn't a
+// terminal. If only is non-nil, just the listed messages of each
+// conversation are included.
+func exportEPUB(engine archive, convIDs []int64, only map[int64]map[int64]bool, title string) error {
+	filename := outputFile
+	if filename == "" && outputDir != "" {
+		filename = filepath.Join(outputDir, "conversations.epub")
+	}
+	if filename == "" && term.IsTerminal(int(os.Stdout.Fd())) {
+		return fmt.Errorf("an EPUB is a binary file; use -o or -d, or redirect stdout")
 	}
 
-	jsonBytes, err := json.MarshalIndent(data, "", "  ")
+	book := &export.EPUB{Title: title}
+	if len(convIDs) == 1 {
+		book.Title = ""
+	}
+	for _, convID := range convIDs {
+		conv, messages, err := exportMessages(engine, convID, only != nil)
+		if err != nil {
+			return fmt.Errorf("failed to export conversation %d: %w", convID, err)
+		}
+		if collapse {
+			messages, _ = repeats.Collapse(messages, repeats.DefaultMinLines)
+		}
+		var ids map[int64]bool
+		if only != nil {
+			ids = only[convID]
+			if ids == nil {
+				ids = map[int64]bool{}
+			}
+		}
+		book.Add(conv, keepMessages(messages, ids))
+	}
+
+	if filename == "" {
+		return book.Write(os.Stdout)
+	}
+	if dir := filepath.Dir(filename); dir != "." && dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+	}
+	f, err := os.Create(filename)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal JSON: %w", err)
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := book.Write(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
 	}
+	if !quiet {
+		fmt.Printf("Exported %d conversations to %s\n", book.Len(), filename)
+	}
+	return nil
+}
 
-	return string(jsonBytes), nil
+// newPublisher creates the publisher for a wiki format from the flags,
+// falling back to the config file
+func newPublisher(format string) export.Publisher {
+	cfg := config.Get()
+	switch format {
+	case "gist":
+		return &export.GistPublisher{
+			Token:  firstNonEmpty(wikiToken, cfg.Export.Gist.Token, os.Getenv("GITHUB_TOKEN")),
+			Public: public,
+		}
+	case "notion":
+		return &export.NotionPublisher{
+			Token:    firstNonEmpty(wikiToken, cfg.Export.Notion.Token),
+			ParentID: firstNonEmpty(wikiParent, cfg.Export.Notion.Parent),
+		}
+	}
+	return &export.ConfluencePublisher{
+		BaseURL:  firstNonEmpty(wikiURL, cfg.Export.Confluence.URL),
+		User:     firstNonEmpty(wikiUser, cfg.Export.Confluence.User),
+		Token:    firstNonEmpty(wikiToken, cfg.Export.Confluence.Token),
+		Space:    firstNonEmpty(wikiSpace, cfg.Export.Confluence.Space),
+		ParentID: firstNonEmpty(wikiParent, cfg.Export.Confluence.Parent),
+	}
+}
+
+// firstNonEmpty returns the first of values that isn't empty
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
 }
@@ -0,0 +1,140 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/filter"
+	"github.com/neilberkman/shannon/internal/models"
+	"github.com/neilberkman/shannon/internal/search"
+)
+
+func TestKeepMessagesOnly(t *testing.T) {
+	messages := []*models.Message{
+		{ID: 1, Sender: "human", Text: "How do I parse JSON in Go?"},
+		{ID: 2, Sender: "assistant", Text: "Use encoding/json."},
+		{ID: 3, Sender: "human", Text: "And YAML?"},
+		{ID: 4, Sender: "assistant", Text: "Use gopkg.in/yaml.v3."},
+	}
+
+	tests := []struct {
+		name   string
+		sender string
+		ids    map[int64]bool
+		want   []int64
+	}{
+		{"everyone", "", nil, []int64{1, 2, 3, 4}},
+		{"human", "human", nil, []int64{1, 3}},
+		{"assistant", "assistant", nil, []int64{2, 4}},
+		{"human matches", "human", map[int64]bool{1: true, 2: true}, []int64{1}},
+		{"assistant matches", "assistant", map[int64]bool{1: true, 2: true}, []int64{2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			if messageFilter, err = filter.New(tt.sender, nil, nil); err != nil {
+				t.Fatal(err)
+			}
+			defer func() { messageFilter = nil }()
+
+			kept := keepMessages(messages, tt.ids)
+			if len(kept) != len(tt.want) {
+				t.Fatalf("expected messages %v, got %d messages", tt.want, len(kept))
+			}
+			for i, msg := range kept {
+				if msg.ID != tt.want[i] {
+					t.Errorf("expected messages %v, got message %d at %d", tt.want, msg.ID, i)
+				}
+			}
+		})
+	}
+}
+
+func TestOnlyRejectsOtherSenders(t *testing.T) {
+	defer func() { onlySender = "" }()
+
+	for _, sender := range []string{"human", "assistant"} {
+		onlySender = sender
+		if err := ExportCmd.Args(ExportCmd, []string{"1"}); err != nil {
+			t.Errorf("expected --only %s to be accepted, got %v", sender, err)
+		}
+	}
+
+	for _, sender := range []string{"user", "Human", "both"} {
+		onlySender = sender
+		if err := ExportCmd.Args(ExportCmd, []string{"1"}); err == nil {
+			t.Errorf("expected --only %s to be rejected", sender)
+		}
+	}
+}
+
+func TestQueryExportMatchingOnly(t *testing.T) {
+	database, err := db.New(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create in-memory db: %v", err)
+	}
+	defer func() { _ = database.Close() }()
+	engine := search.NewEngine(database)
+
+	// The question was asked again on a branch, and only that branch
+	// mentions kubernetes
+	start := time.Date(2025, 6, 25, 9, 0, 0, 0, time.UTC)
+	for _, stmt := range []string{
+		`INSERT INTO conversations (id, uuid, name, created_at, updated_at, message_count) VALUES (1, 'conv-1', 'Deploying', '2025-06-25 09:00:00', '2025-06-25 09:10:00', 4)`,
+		`INSERT INTO branches (id, conversation_id, name) VALUES (1, 1, 'main'), (2, 1, 'branch-1')`,
+	} {
+		if _, err := database.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, msg := range []struct {
+		parent any
+		branch int
+		sender string
+		text   string
+	}{
+		{nil, 1, "human", "How do I deploy this service?"},
+		{1, 1, "assistant", "Copy the binary over and run it."},
+		{1, 2, "assistant", "Run it on kubernetes with a deployment."},
+		{3, 2, "human", "How do I roll back a kubernetes deployment?"},
+	} {
+		if _, err := database.Exec(
+			`INSERT INTO messages (id, uuid, conversation_id, sender, text, created_at, parent_id, branch_id, sequence) VALUES (?, ?, 1, ?, ?, ?, ?, ?, ?)`,
+			i+1, "msg-"+string(rune('a'+i)), msg.sender, msg.text, start.Add(time.Duration(i)*time.Minute), msg.parent, msg.branch, i,
+		); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dir := t.TempDir()
+	query, matchingOnly, outputDir, outputFormat, quiet = "kubernetes", true, dir, "markdown", true
+	defer func() { query, matchingOnly, outputDir, outputFormat, quiet = "", false, "", "markdown", false }()
+	if err := exportQuery(engine); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.md"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected one export, got %v (%v)", files, err)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := string(data)
+	for _, want := range []string{"Run it on kubernetes", "roll back a kubernetes deployment"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("expected the match %q in the export:\n%s", want, content)
+		}
+	}
+	for _, unwanted := range []string{"deploy this service", "Copy the binary"} {
+		if strings.Contains(content, unwanted) {
+			t.Errorf("expected %q to be left out:\n%s", unwanted, content)
+		}
+	}
+}
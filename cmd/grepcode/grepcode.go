@@ -0,0 +1,140 @@
+package grepcode
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/neilberkman/shannon/internal/artifacts"
+	"github.com/neilberkman/shannon/internal/config"
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/search"
+	"github.com/spf13/cobra"
+)
+
+var (
+	languages  []string
+	in         []string
+	ignoreCase bool
+	limit      int
+	format     string
+	reindex    bool
+)
+
+// GrepCodeCmd represents the grep-code command
+var GrepCodeCmd = &cobra.Command{
+	Use:   "grep-code [pattern]",
+	Short: "Search only inside code blocks and artifacts",
+	Long: `Search for a literal string inside fenced code blocks and artifacts,
+ignoring the surrounding prose.
+
+Each match is printed as conversation-id:message-id:line: text, where line is
+the line number within the message. Matching is a plain substring match on
+each line, case-sensitive unless --ignore-case is given.
+
+Examples:
+  shannon grep-code "http.Client" --lang go
+  shannon grep-code "useEffect" --in artifacts
+  shannon grep-code -i "select \* from" --lang sql --format json
+
+The code block index is built automatically for existing databases the first
+time this command runs; use --reindex to rebuild it.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runGrepCode,
+}
+
+func init() {
+	GrepCodeCmd.Flags().StringSliceVar(&languages, "lang", nil, "only search code in these languages (e.g. go,python)")
+	GrepCodeCmd.Flags().StringSliceVar(&in, "in", []string{"artifacts", "codeblocks"}, "where to search (artifacts,codeblocks)")
+	GrepCodeCmd.Flags().BoolVarP(&ignoreCase, "ignore-case", "i", false, "case-insensitive matching")
+	GrepCodeCmd.Flags().IntVarP(&limit, "limit", "l", 0, "maximum number of matching lines (0 for no limit)")
+	GrepCodeCmd.Flags().StringVarP(&format, "format", "f", "text", "output format (text/json)")
+	GrepCodeCmd.Flags().BoolVar(&reindex, "reindex", false, "rebuild the code block index before searching")
+}
+
+func runGrepCode(cmd *cobra.Command, args []string) error {
+	pattern := strings.Join(args, " ")
+
+	kinds, err := parseKinds(in)
+	if err != nil {
+		return err
+	}
+
+	var langs []string
+	for _, lang := range languages {
+		langs = append(langs, artifacts.NormalizeLanguage(lang))
+	}
+
+	cfg := config.Get()
+
+	database, err := db.New(cfg.Database.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer func() {
+		if err := database.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+		}
+	}()
+
+	engine := search.NewEngine(database)
+
+	if reindex {
+		count, err := engine.RebuildCodeIndex()
+		if err != nil {
+			return fmt.Errorf("failed to rebuild code index: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Indexed %d code blocks\n", count)
+	} else {
+		rebuilt, err := engine.EnsureCodeIndex()
+		if err != nil {
+			return fmt.Errorf("failed to build code index: %w", err)
+		}
+		if rebuilt {
+			fmt.Fprintln(os.Stderr, "Built code block index")
+		}
+	}
+
+	matches, err := engine.SearchCode(search.CodeSearchOptions{
+		Query:      pattern,
+		Languages:  langs,
+		Kinds:      kinds,
+		IgnoreCase: ignoreCase,
+		Limit:      limit,
+	})
+	if err != nil {
+		return fmt.Errorf("code search failed: %w", err)
+	}
+
+	if format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(map[string]interface{}{
+			"results": matches,
+			"count":   len(matches),
+		})
+	}
+
+	for _, m := range matches {
+		fmt.Printf("%d:%d:%d: %s\n", m.ConversationID, m.MessageID, m.Line, m.Text)
+	}
+
+	return nil
+}
+
+// parseKinds maps --in values to code block kinds
+func parseKinds(values []string) ([]string, error) {
+	var kinds []string
+	for _, v := range values {
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "artifacts", "artifact":
+			kinds = append(kinds, artifacts.KindArtifact)
+		case "codeblocks", "codeblock", "blocks":
+			kinds = append(kinds, artifacts.KindCodeBlock)
+		default:
+			return nil, fmt.Errorf("invalid --in value %q (use artifacts or codeblocks)", v)
+		}
+	}
+	return kinds, nil
+}
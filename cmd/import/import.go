@@ -1,40 +1,102 @@
 package imports
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/dustin/go-humanize"
 	"github.com/neilberkman/shannon/internal/config"
+	"github.com/neilberkman/shannon/internal/dates"
 	"github.com/neilberkman/shannon/internal/db"
 	"github.com/neilberkman/shannon/internal/imports"
+	"github.com/neilberkman/shannon/internal/models"
+	"github.com/neilberkman/shannon/internal/search"
+	"github.com/neilberkman/shannon/internal/store"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var (
-	batchSize int
-	force     bool
+	batchSize      int
+	force          bool
+	restoreDeleted bool
+	strict         bool
+	allowUnknown   bool
+	recoverExport  bool
+	treeDir        string
+	treePattern    string
+	onlyMatching   string
+	after          string
+	before         string
+	minMessages    int
 )
 
 // importCmd represents the import command
 var ImportCmd = &cobra.Command{
-	Use:   "import [file]",
+	Use:   "import [file | --dir path]",
 	Short: "Import a Claude export file",
 	Long: `Import conversations from a Claude export JSON file into the local database.
 
 The import process will:
-- Parse the JSON export file
+- Parse the JSON export file, decompressing gzip, bzip2 and zstd files
 - Detect conversation branches
 - Create full-text search indexes
-- Skip files that have already been imported (unless --force is used)`,
+- Skip files that have already been imported (unless --force is used)
+- Skip conversations deleted with 'shannon cleanup conversation' (unless
+  --restore-deleted is used)
+- Skip malformed conversations and report them at the end, importing the
+  rest (unless --strict is used, which stops at the first one)
+- Adapt older and newer variants of the export format it recognizes, such
+  as renamed fields, and fail on fields it doesn't know rather than drop
+  their data (unless --allow-unknown-fields is used)
+- Fail on an export whose JSON breaks off, like a truncated download (unless
+  --recover is used, which imports the complete conversations before the
+  break, reports what was skipped and records the import as partial)
 
-	Args: cobra.ExactArgs(1),
-	RunE: runImport,
+With --dir, the files under a directory matching --pattern are imported
+instead, as written by tools that save each conversation to its own JSON
+file. Each file may hold a single conversation or a whole export; files that
+are neither are skipped and reported. They're all imported in one
+transaction, recorded as a single import with a combined summary.
+
+--only-matching, --after, --before and --min-messages import only the
+conversations that meet all of them and leave out the rest: those whose
+title or messages contain the text (ignoring case), last updated in the date
+range, with at least that many messages. The file is still recorded as
+imported, so importing the rest of it later takes --force.`,
+
+	Example: `  shannon import conversations.json
+  shannon import --dir ~/claude-backup
+  shannon import --dir ./exported --pattern 'chat-*.json'
+  shannon import conversations.json --only-matching kubernetes --after 2024-01-01 --min-messages 5`,
+
+	Args: func(cmd *cobra.Command, args []string) error {
+		if treeDir != "" {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
+	Annotations: map[string]string{store.Annotation: "true"},
+	RunE:        runImport,
 }
 
 func init() {
 	ImportCmd.Flags().IntVar(&batchSize, "batch-size", 1000, "number of messages to import at once")
 	ImportCmd.Flags().BoolVar(&force, "force", false, "force re-import of already imported files")
+	ImportCmd.Flags().BoolVar(&restoreDeleted, "restore-deleted", false, "import conversations that were deleted locally")
+	ImportCmd.Flags().BoolVar(&strict, "strict", false, "fail the whole import on the first malformed conversation")
+	ImportCmd.Flags().BoolVar(&allowUnknown, "allow-unknown-fields", false, "import exports with fields shannon doesn't know, dropping their data")
+	ImportCmd.Flags().BoolVar(&recoverExport, "recover", false, "import the complete conversations of an export whose JSON breaks off, like a truncated download")
+	ImportCmd.Flags().StringVar(&treeDir, "dir", "", "import the files of a directory tree, one conversation or export each")
+	ImportCmd.Flags().StringVar(&treePattern, "pattern", imports.DefaultTreePattern, "file names to import with --dir")
+	ImportCmd.Flags().StringVar(&onlyMatching, "only-matching", "", "import only conversations whose title or messages contain this text")
+	ImportCmd.Flags().StringVar(&after, "after", "", "import only conversations last updated from this date or age on (2024-06-01, 30d, @2024)")
+	ImportCmd.Flags().StringVar(&before, "before", "", "import only conversations last updated before this date or age")
+	ImportCmd.Flags().IntVar(&minMessages, "min-messages", 0, "import only conversations with at least this many messages")
 
 	if err := viper.BindPFlag("import.batch_size", ImportCmd.Flags().Lookup("batch-size")); err != nil {
 		panic(fmt.Sprintf("failed to bind flag: %v", err))
@@ -42,6 +104,12 @@ func init() {
 }
 
 func runImport(cmd *cobra.Command, args []string) error {
+	if treeDir != "" {
+		return importTree(treeDir, treePattern)
+	}
+	if cmd.Flags().Changed("pattern") {
+		return fmt.Errorf("--pattern only applies with --dir")
+	}
 	filePath := args[0]
 	return ImportFile(filePath, force)
 }
@@ -53,11 +121,50 @@ func ImportFile(filePath string, forceImport bool) error {
 
 // ImportFileQuiet imports a single Claude export file with optional quiet mode
 func ImportFileQuiet(filePath string, forceImport bool, quiet bool) error {
+	if recoverExport && strict {
+		return fmt.Errorf("--recover cannot be combined with --strict")
+	}
+
 	// Check if file exists
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
 		return fmt.Errorf("file not found: %s", filePath)
 	}
 
+	if !quiet {
+		fmt.Printf("Importing %s...\n", filePath)
+	}
+	if cfg := config.Get(); store.Shared(cfg) {
+		return importShared(cfg, filePath, quiet)
+	}
+	return runImporter(quiet, func(importer *imports.Importer) (*models.ImportStats, error) {
+		return importer.Import(filePath)
+	})
+}
+
+// importTree imports the files of a directory tree matching pattern
+func importTree(root, pattern string) error {
+	if recoverExport && strict {
+		return fmt.Errorf("--recover cannot be combined with --strict")
+	}
+	if info, err := os.Stat(root); err != nil {
+		return fmt.Errorf("directory not found: %s", root)
+	} else if !info.IsDir() {
+		return fmt.Errorf("not a directory: %s", root)
+	}
+
+	if cfg := config.Get(); store.Shared(cfg) {
+		return fmt.Errorf("--dir isn't supported with the %s store; import into the local database and run 'shannon db push'", cfg.Database.Driver)
+	}
+
+	fmt.Printf("Importing %s from %s...\n", pattern, root)
+	return runImporter(false, func(importer *imports.Importer) (*models.ImportStats, error) {
+		return importer.ImportTree(root, pattern)
+	})
+}
+
+// runImporter runs an import with an importer set up from the flags and
+// configuration, and summarizes it
+func runImporter(quiet bool, importFn func(importer *imports.Importer) (*models.ImportStats, error)) error {
 	// Get configuration
 	cfg := config.Get()
 
@@ -76,32 +183,281 @@ func ImportFileQuiet(filePath string, forceImport bool, quiet bool) error {
 
 	// Create importer
 	importer := imports.NewImporter(database, cfg.Import.BatchSize, cfg.Import.Verbose || viper.GetBool("verbose"))
-
-	// Import file
-	if !quiet {
-		fmt.Printf("Importing %s...\n", filePath)
+	importer.SetRestoreDeleted(restoreDeleted)
+	importer.SetStrict(strict)
+	importer.SetAllowUnknownFields(allowUnknown)
+	importer.SetRecover(recoverExport)
+	filter, err := importFilter()
+	if err != nil {
+		return err
 	}
-	stats, err := importer.Import(filePath)
+	importer.SetFilter(filter)
+
+	stats, err := importFn(importer)
 	if err != nil {
+		var corruption *imports.CorruptionError
+		if !recoverExport && errors.As(err, &corruption) {
+			return fmt.Errorf("import failed: %w (use --recover to import the %d conversations before it)", err, corruption.Index)
+		}
 		return fmt.Errorf("import failed: %w", err)
 	}
 
+	// New messages are indexed as they're imported, but a database indexed by
+	// an older version needs all of them extracted again
+	rebuilt, err := search.NewEngine(database).EnsureCodeIndex()
+	if err != nil && !quiet {
+		fmt.Fprintf(os.Stderr, "Warning: failed to build code block index: %v\n", err)
+	}
+
 	// Print statistics only if not quiet
 	if !quiet {
 		fmt.Printf("\nImport completed in %s:\n", stats.Duration)
+		if stats.Files > 0 {
+			fmt.Printf("  Files read: %d", stats.Files)
+			if stats.FilesSkipped > 0 {
+				fmt.Printf(" (%d skipped)", stats.FilesSkipped)
+			}
+			fmt.Println()
+		}
 		fmt.Printf("  Conversations imported: %d\n", stats.ConversationsImported)
 		fmt.Printf("  Messages imported: %d\n", stats.MessagesImported)
 		fmt.Printf("  Branches detected: %d\n", stats.BranchesDetected)
+		if stats.ConversationsReordered > 0 {
+			fmt.Printf("  Conversations reordered: %d\n", stats.ConversationsReordered)
+		}
+		if rebuilt {
+			fmt.Println("  Code block index rebuilt")
+		}
+		if len(stats.ExportVariants) > 0 {
+			fmt.Printf("  Export format adapted: %s\n", strings.Join(stats.ExportVariants, ", "))
+		}
+		printDeleted(stats)
+		if stats.ConversationsFiltered > 0 {
+			fmt.Printf("  Conversations left out by filters: %d\n", stats.ConversationsFiltered)
+		}
+		printProfile(stats)
+		fmt.Printf("\nRecorded as import %d (see 'shannon imports show %d')\n", stats.ImportID, stats.ImportID)
+
+		printErrors(stats, viper.GetBool("verbose"))
+		printCorruption(stats)
+	}
+
+	return nil
+}
+
+// importShared imports an export into a shared store. The export is read
+// here and the conversations it keeps sent to the store, so the filters and
+// --strict work as they do locally.
+func importShared(cfg *config.Config, filePath string, quiet bool) error {
+	if restoreDeleted || recoverExport {
+		return fmt.Errorf("--restore-deleted and --recover aren't supported with the %s store", cfg.Database.Driver)
+	}
+	filter, err := importFilter()
+	if err != nil {
+		return err
+	}
+
+	hash, err := imports.FileHash(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to hash file: %w", err)
+	}
+	parser, err := imports.NewParser(filePath)
+	if err != nil {
+		return err
+	}
+	parser.SetStrict(strict)
+	defer func() {
+		if err := parser.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close parser: %v\n", err)
+		}
+	}()
+	export, err := parser.Parse()
+	if err != nil {
+		return fmt.Errorf("import failed: %w", err)
+	}
+	if !allowUnknown {
+		if err := parser.UnknownFields(); err != nil {
+			return fmt.Errorf("import failed: %w", err)
+		}
+	}
+	if strict {
+		if err := imports.ValidateExport(export); err != nil {
+			return fmt.Errorf("import failed: invalid export: %w", err)
+		}
+	}
 
-		if len(stats.Errors) > 0 {
-			fmt.Printf("\nErrors encountered: %d\n", len(stats.Errors))
-			if viper.GetBool("verbose") {
-				for _, err := range stats.Errors {
-					fmt.Printf("  - %v\n", err)
-				}
+	conversations := export.Conversations
+	filtered := 0
+	if filter != nil {
+		conversations = nil
+		for _, conv := range export.Conversations {
+			if filter.Match(&conv) {
+				conversations = append(conversations, conv)
+			} else {
+				filtered++
 			}
 		}
 	}
 
+	s, err := store.Open(cfg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := s.Close(); err != nil && !quiet {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+		}
+	}()
+
+	stats, err := s.ImportConversations(filePath, hash, conversations)
+	if err != nil {
+		return fmt.Errorf("import failed: %w", err)
+	}
+	for _, convErr := range parser.Errors() {
+		stats.Errors = append(stats.Errors, convErr)
+	}
+	stats.ConversationsFiltered = filtered
+
+	if !quiet {
+		fmt.Printf("\nImport completed in %s:\n", stats.Duration)
+		fmt.Printf("  Conversations imported: %d\n", stats.ConversationsImported)
+		fmt.Printf("  Messages imported: %d\n", stats.MessagesImported)
+		if stats.ConversationsFiltered > 0 {
+			fmt.Printf("  Conversations left out by filters: %d\n", stats.ConversationsFiltered)
+		}
+		printProfile(stats)
+		fmt.Printf("\nRecorded as import %d in the %s store\n", stats.ImportID, cfg.Database.Driver)
+		printErrors(stats, viper.GetBool("verbose"))
+	}
 	return nil
 }
+
+// importFilter builds the filter of conversations to import from the flags,
+// or nil when none are set
+func importFilter() (*imports.Filter, error) {
+	if onlyMatching == "" && after == "" && before == "" && minMessages <= 0 {
+		return nil, nil
+	}
+	filter := &imports.Filter{Matching: onlyMatching, MinMessages: minMessages}
+	var err error
+	if after != "" {
+		if filter.After, err = dates.Parse(after, time.Now()); err != nil {
+			return nil, fmt.Errorf("invalid --after: %w", err)
+		}
+	}
+	if before != "" {
+		if filter.Before, err = dates.Parse(before, time.Now()); err != nil {
+			return nil, fmt.Errorf("invalid --before: %w", err)
+		}
+	}
+	return filter, nil
+}
+
+// maxReportedErrors is how many conversation errors are listed after an
+// import unless running verbosely
+const maxReportedErrors = 5
+
+// printErrors summarizes the conversations that were skipped because of errors
+func printErrors(stats *models.ImportStats, verbose bool) {
+	var convErrors []error
+	var corruption *imports.CorruptionError
+	for _, err := range stats.Errors {
+		if !errors.As(err, &corruption) {
+			convErrors = append(convErrors, err)
+		}
+	}
+	if len(convErrors) == 0 {
+		return
+	}
+
+	if stats.Files > 0 {
+		fmt.Printf("\nSkipped %d conversation(s) or file(s) with errors:\n", len(convErrors))
+	} else {
+		fmt.Printf("\nSkipped %d conversation(s) with errors:\n", len(convErrors))
+	}
+	for n, err := range convErrors {
+		if n == maxReportedErrors && !verbose {
+			fmt.Printf("  ... and %d more (see 'shannon imports show %d')\n", len(convErrors)-n, stats.ImportID)
+			break
+		}
+		fmt.Printf("  - %v\n", err)
+	}
+	fmt.Println("Use --strict to fail the import instead.")
+}
+
+// printCorruption reports where a recovered export broke off and what was
+// skipped after it
+func printCorruption(stats *models.ImportStats) {
+	for _, err := range stats.Errors {
+		var corruption *imports.CorruptionError
+		if !errors.As(err, &corruption) {
+			continue
+		}
+		fmt.Printf("\nRecovered a corrupted export: %v\n", corruption)
+		fmt.Printf("  Conversations read before the break: %d\n", corruption.Index)
+		skipped := fmt.Sprintf("%s, about %d conversation(s)", humanize.Bytes(uint64(corruption.Skipped)), corruption.Conversations)
+		if corruption.UUID != "" {
+			skipped += ", starting with " + corruption.UUID
+		}
+		fmt.Printf("  Skipped: %s\n", skipped)
+		fmt.Printf("Recorded as a partial import; import a complete export later to fill in the rest.\n")
+	}
+}
+
+// printDeleted reports conversations deleted locally that the import skipped
+// or brought back
+func printDeleted(stats *models.ImportStats) {
+	if stats.ConversationsRestored > 0 {
+		fmt.Printf("  Deleted conversations restored: %d\n", stats.ConversationsRestored)
+	}
+	if stats.ConversationsSkipped > 0 {
+		fmt.Printf("  Deleted conversations skipped: %d (use --restore-deleted to import them)\n", stats.ConversationsSkipped)
+	}
+}
+
+// maxReportedLanguages is how many languages the import summary names
+const maxReportedLanguages = 8
+
+// printProfile describes the code and artifacts the import brought in and the
+// conversations it added the most messages to
+func printProfile(stats *models.ImportStats) {
+	if stats.ArtifactsFound > 0 || stats.CodeBlocksFound > 0 {
+		fmt.Printf("  Artifacts found: %d\n", stats.ArtifactsFound)
+		fmt.Printf("  Code blocks found: %d\n", stats.CodeBlocksFound)
+	}
+
+	if len(stats.Languages) > 0 {
+		languages := make([]string, 0, len(stats.Languages))
+		for language := range stats.Languages {
+			languages = append(languages, language)
+		}
+		sort.Slice(languages, func(a, b int) bool {
+			na, nb := stats.Languages[languages[a]], stats.Languages[languages[b]]
+			if na != nb {
+				return na > nb
+			}
+			return languages[a] < languages[b]
+		})
+
+		var parts []string
+		for n, language := range languages {
+			if n == maxReportedLanguages {
+				parts = append(parts, fmt.Sprintf("%d more", len(languages)-n))
+				break
+			}
+			name := language
+			if name == "" {
+				name = "unlabeled"
+			}
+			parts = append(parts, fmt.Sprintf("%s %d", name, stats.Languages[language]))
+		}
+		fmt.Printf("  Languages: %s\n", strings.Join(parts, ", "))
+	}
+
+	if len(stats.LargestConversations) > 1 {
+		fmt.Println("  Largest conversations:")
+		for _, conv := range stats.LargestConversations {
+			fmt.Printf("    %5d messages  %s\n", conv.Messages, conv.Name)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package imports
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dustin/go-humanize"
+	"github.com/neilberkman/shannon/internal/config"
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/imports"
+	"github.com/neilberkman/shannon/internal/models"
+	"github.com/spf13/cobra"
+)
+
+var (
+	limit      int
+	format     string
+	assumeYes  bool
+	showErrors int
+)
+
+// NewCmd creates the imports command
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "imports",
+		Short: "Show and manage import history",
+		Long: `Show the history of imported export files and undo individual imports.
+
+Examples:
+  shannon imports list
+  shannon imports show 3
+  shannon imports undo 3`,
+	}
+
+	cmd.AddCommand(newListCmd())
+	cmd.AddCommand(newShowCmd())
+	cmd.AddCommand(newUndoCmd())
+
+	return cmd
+}
+
+// newListCmd creates the list subcommand
+func newListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List previous imports",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			database, err := getDatabase()
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if err := database.Close(); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+				}
+			}()
+
+			records, err := imports.ListImports(database, limit)
+			if err != nil {
+				return err
+			}
+
+			if format == "json" {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(map[string]interface{}{
+					"imports": records,
+					"count":   len(records),
+				})
+			}
+
+			if len(records) == 0 {
+				fmt.Println("No imports recorded.")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			if _, err := fmt.Fprintln(w, "ID\tImported\tStatus\tConvs\tMsgs\tErrors\tDuration\tFile"); err != nil {
+				return fmt.Errorf("failed to write header: %w", err)
+			}
+			if _, err := fmt.Fprintln(w, "--\t--------\t------\t-----\t----\t------\t--------\t----"); err != nil {
+				return fmt.Errorf("failed to write separator: %w", err)
+			}
+			for _, r := range records {
+				if _, err := fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%d\t%d\t%s\t%s\n",
+					r.ID, humanize.Time(r.ImportedAt), r.Status, r.ConversationsCount,
+					r.MessagesCount, r.ErrorsCount, r.Duration, r.FilePath); err != nil {
+					return fmt.Errorf("failed to write row: %w", err)
+				}
+			}
+			return w.Flush()
+		},
+	}
+
+	cmd.Flags().IntVarP(&limit, "limit", "l", 20, "maximum number of imports to show")
+	cmd.Flags().StringVarP(&format, "format", "f", "table", "output format (table/json)")
+
+	return cmd
+}
+
+// newShowCmd creates the show subcommand
+func newShowCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "show [import-id]",
+		Short: "Show details and errors for an import",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			importID, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid import ID: %w", err)
+			}
+
+			database, err := getDatabase()
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if err := database.Close(); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+				}
+			}()
+
+			record, importErrors, err := imports.GetImport(database, importID)
+			if err != nil {
+				return err
+			}
+
+			if format == "json" {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(map[string]interface{}{
+					"import": record,
+					"errors": importErrors,
+				})
+			}
+
+			printImport(record)
+
+			if len(importErrors) > 0 {
+				fmt.Printf("\nConversation errors (%d):\n", len(importErrors))
+				for i, ie := range importErrors {
+					if showErrors > 0 && i >= showErrors {
+						fmt.Printf("  ... and %d more (use --errors 0 to show all)\n", len(importErrors)-i)
+						break
+					}
+					fmt.Printf("  - %s: %s\n", ie.ConversationUUID, ie.Message)
+				}
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&format, "format", "f", "table", "output format (table/json)")
+	cmd.Flags().IntVar(&showErrors, "errors", 20, "maximum number of conversation errors to show (0 for all)")
+
+	return cmd
+}
+
+// newUndoCmd creates the undo subcommand
+func newUndoCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "undo [import-id]",
+		Short: "Remove conversations and messages introduced by an import",
+		Long: `Remove the conversations and messages that were introduced by a specific import.
+
+Conversations that later imports added messages to are kept; only the messages
+from the undone import are removed from them. The import is also removed from
+the history, so the same file can be imported again afterwards.
+
+Imports made before shannon tracked import provenance cannot be undone.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			importID, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid import ID: %w", err)
+			}
+
+			database, err := getDatabase()
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if err := database.Close(); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+				}
+			}()
+
+			record, _, err := imports.GetImport(database, importID)
+			if err != nil {
+				return err
+			}
+
+			if !assumeYes {
+				printImport(record)
+				fmt.Print("\nRemove everything introduced by this import? [y/N] ")
+				answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+				answer = strings.ToLower(strings.TrimSpace(answer))
+				if answer != "y" && answer != "yes" {
+					fmt.Println("Aborted.")
+					return nil
+				}
+			}
+
+			stats, err := imports.UndoImport(database, importID)
+			if err != nil {
+				return fmt.Errorf("undo failed: %w", err)
+			}
+
+			fmt.Printf("Undid import %d:\n", importID)
+			fmt.Printf("  Conversations removed: %d\n", stats.ConversationsRemoved)
+			fmt.Printf("  Conversations updated: %d\n", stats.ConversationsUpdated)
+			fmt.Printf("  Messages removed: %d\n", stats.MessagesRemoved)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "don't ask for confirmation")
+
+	return cmd
+}
+
+func printImport(r *models.ImportRecord) {
+	fmt.Printf("Import %d\n", r.ID)
+	fmt.Printf("  File:          %s\n", r.FilePath)
+	fmt.Printf("  Hash:          %s\n", r.FileHash)
+	fmt.Printf("  Imported:      %s (%s)\n", r.ImportedAt.Format("2006-01-02 15:04:05"), humanize.Time(r.ImportedAt))
+	fmt.Printf("  Status:        %s\n", r.Status)
+	fmt.Printf("  Duration:      %s\n", r.Duration)
+	fmt.Printf("  Conversations: %d\n", r.ConversationsCount)
+	fmt.Printf("  Messages:      %d\n", r.MessagesCount)
+	fmt.Printf("  Branches:      %d\n", r.BranchesDetected)
+	fmt.Printf("  Errors:        %d\n", r.ErrorsCount)
+	if r.ErrorMessage != "" {
+		fmt.Printf("  Failure:       %s\n", r.ErrorMessage)
+	}
+}
+
+// getDatabase returns a database connection
+func getDatabase() (*db.DB, error) {
+	cfg := config.Get()
+	database, err := db.New(cfg.Database.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	return database, nil
+}
@@ -0,0 +1,219 @@
+package index
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/neilberkman/shannon/internal/config"
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/export"
+	"github.com/neilberkman/shannon/internal/search"
+	"github.com/spf13/cobra"
+)
+
+var (
+	spotlight bool
+	recoll    bool
+	outputDir string
+	quiet     bool
+)
+
+// indexFileRegex matches the files this command writes, so pruning never
+// touches anything else in the directory
+var indexFileRegex = regexp.MustCompile(`^\d+-[a-z0-9-]*\.html$`)
+
+// IndexCmd represents the index command
+var IndexCmd = &cobra.Command{
+	Use:   "index",
+	Short: "Write conversation files for desktop search tools",
+	Long: `Write one HTML file per conversation so desktop search tools can find
+conversations by title and content outside the terminal.
+
+Each file carries the conversation title, dates and claude.ai link as metadata.
+Running the command again only rewrites conversations that changed and removes
+files for conversations that no longer exist.
+
+  --spotlight  (macOS) write to the shannon data directory and ask Spotlight
+               to index it right away
+  --recoll     write to the shannon data directory and generate a Recoll
+               configuration that indexes it
+  --dir        write to any directory, for other indexers
+
+Examples:
+  shannon index --spotlight
+  shannon index --recoll && recollindex -c ~/.local/share/shannon/recoll
+  shannon index --dir ~/Documents/claude`,
+	Args: cobra.NoArgs,
+	RunE: runIndex,
+}
+
+func init() {
+	IndexCmd.Flags().BoolVar(&spotlight, "spotlight", false, "write files for macOS Spotlight and import them")
+	IndexCmd.Flags().BoolVar(&recoll, "recoll", false, "write files and a Recoll configuration")
+	IndexCmd.Flags().StringVarP(&outputDir, "dir", "d", "", "output directory (defaults to the shannon data directory)")
+	IndexCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "suppress progress output")
+}
+
+// indexStats counts what an index run changed
+type indexStats struct {
+	written   int
+	unchanged int
+	removed   int
+}
+
+func runIndex(cmd *cobra.Command, args []string) error {
+	if !spotlight && !recoll && outputDir == "" {
+		return fmt.Errorf("choose --spotlight, --recoll or --dir")
+	}
+	if spotlight && runtime.GOOS != "darwin" {
+		return fmt.Errorf("--spotlight is only available on macOS; use --recoll or --dir instead")
+	}
+
+	dataDir := config.GetDirs().Data
+	dir := outputDir
+	if dir == "" {
+		if spotlight {
+			dir = filepath.Join(dataDir, "spotlight")
+		} else {
+			dir = filepath.Join(dataDir, "index")
+		}
+	}
+
+	cfg := config.Get()
+
+	database, err := db.New(cfg.Database.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer func() {
+		if err := database.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+		}
+	}()
+
+	stats, err := writeIndex(search.NewEngine(database), dir)
+	if err != nil {
+		return err
+	}
+
+	if !quiet {
+		fmt.Fprintf(os.Stderr, "Indexed %d conversations in %s (%d updated, %d unchanged, %d removed)\n",
+			stats.written+stats.unchanged, dir, stats.written, stats.unchanged, stats.removed)
+	}
+
+	if spotlight {
+		// mdimport queues the files for Spotlight instead of waiting for it to notice them
+		if err := exec.Command("mdimport", dir).Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: mdimport failed, Spotlight will pick the files up on its own: %v\n", err)
+		}
+	}
+
+	if recoll {
+		confDir := filepath.Join(dataDir, "recoll")
+		if err := writeRecollConfig(confDir, dir); err != nil {
+			return err
+		}
+		if !quiet {
+			fmt.Fprintf(os.Stderr, "Recoll configuration written to %s\n", confDir)
+			fmt.Fprintf(os.Stderr, "Update the Recoll index with: recollindex -c %q\n", confDir)
+			fmt.Fprintf(os.Stderr, "Search it with: recoll -c %q\n", confDir)
+		}
+	}
+
+	return nil
+}
+
+// writeIndex writes a file for every conversation, skipping files whose
+// content is unchanged, and removes files of deleted conversations
+func writeIndex(engine *search.Engine, dir string) (*indexStats, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	conversations, err := engine.GetAllConversations(-1, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &indexStats{}
+	keep := make(map[string]bool)
+	for _, c := range conversations {
+		conv, messages, err := engine.GetConversation(c.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load conversation %d: %w", c.ID, err)
+		}
+
+		name := fmt.Sprintf("%d-%s.html", conv.ID, slugify(conv.Name))
+		keep[name] = true
+		path := filepath.Join(dir, name)
+
+		content := export.ConversationToHTML(conv, messages)
+		if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, content) {
+			stats.unchanged++
+			continue
+		}
+
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		// Desktop search shows the modification date, so match the conversation's
+		if err := os.Chtimes(path, conv.UpdatedAt, conv.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to set modification time of %s: %w", path, err)
+		}
+		stats.written++
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read output directory: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || keep[entry.Name()] || !indexFileRegex.MatchString(entry.Name()) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+			return nil, fmt.Errorf("failed to remove %s: %w", entry.Name(), err)
+		}
+		stats.removed++
+	}
+
+	return stats, nil
+}
+
+// writeRecollConfig creates a Recoll configuration directory that indexes only dir
+func writeRecollConfig(confDir, dir string) error {
+	if err := os.MkdirAll(confDir, 0755); err != nil {
+		return fmt.Errorf("failed to create Recoll configuration directory: %w", err)
+	}
+
+	conf := fmt.Sprintf("# Generated by shannon index --recoll\ntopdirs = \"%s\"\n", dir)
+	if err := os.WriteFile(filepath.Join(confDir, "recoll.conf"), []byte(conf), 0644); err != nil {
+		return fmt.Errorf("failed to write Recoll configuration: %w", err)
+	}
+	return nil
+}
+
+// slugify turns a conversation name into a short lowercase filename part
+func slugify(name string) string {
+	var sb strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			sb.WriteRune(r)
+			dash = false
+		} else if !dash && sb.Len() > 0 {
+			sb.WriteByte('-')
+			dash = true
+		}
+		if sb.Len() >= 60 {
+			break
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "-")
+}
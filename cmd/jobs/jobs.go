@@ -0,0 +1,360 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dustin/go-humanize"
+	"github.com/neilberkman/shannon/internal/config"
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/jobs"
+	"github.com/neilberkman/shannon/internal/search"
+	"github.com/spf13/cobra"
+)
+
+var (
+	format   string
+	queueAll bool
+)
+
+// NewCmd creates the jobs command
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "jobs",
+		Short: "Queue enrichments for the background worker and follow their progress",
+		Long: `Enrichments that take a while to compute for the whole archive are queued as
+jobs, one per conversation, and run in the background by 'shannon worker', so
+they never hold up searching and browsing. Jobs that fail are retried a few
+times before they're left failed.
+
+Kinds of job:
+` + kindList() + `
+Examples:
+  shannon jobs add secrets
+  shannon worker --once
+  shannon jobs list
+  shannon jobs results secrets`,
+	}
+
+	cmd.AddCommand(newListCmd())
+	cmd.AddCommand(newAddCmd())
+	cmd.AddCommand(newRetryCmd())
+	cmd.AddCommand(newResultsCmd())
+
+	return cmd
+}
+
+// kindList describes the kinds of job, one per line
+func kindList() string {
+	var b strings.Builder
+	for _, e := range jobs.Enrichers {
+		fmt.Fprintf(&b, "  %-10s %s\n", e.Kind, e.Description)
+	}
+	return b.String()
+}
+
+// newListCmd creates the list subcommand
+func newListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "Show how far the queued jobs have got, and why any failed",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			database, err := getDatabase()
+			if err != nil {
+				return err
+			}
+			defer closeDatabase(database)
+
+			progress, err := jobs.ListProgress(database)
+			if err != nil {
+				return err
+			}
+			failed, err := jobs.ListJobs(database, jobs.StatusFailed)
+			if err != nil {
+				return err
+			}
+
+			if format == "json" {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(map[string]interface{}{
+					"progress": progress,
+					"failed":   failed,
+				})
+			}
+
+			if len(progress) == 0 {
+				fmt.Println("No jobs. Queue some with 'shannon jobs add KIND'.")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			if _, err := fmt.Fprintln(w, "Kind\tPending\tRunning\tDone\tFailed\tProgress"); err != nil {
+				return fmt.Errorf("failed to write header: %w", err)
+			}
+			if _, err := fmt.Fprintln(w, "----\t-------\t-------\t----\t------\t--------"); err != nil {
+				return fmt.Errorf("failed to write separator: %w", err)
+			}
+			for _, p := range progress {
+				if _, err := fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d%%\n",
+					p.Kind, p.Pending, p.Running, p.Done, p.Failed, (p.Done+p.Failed)*100/p.Total()); err != nil {
+					return fmt.Errorf("failed to write row: %w", err)
+				}
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+
+			if len(failed) > 0 {
+				fmt.Println("\nFailed (retry with 'shannon jobs retry'):")
+				for _, job := range failed {
+					fmt.Printf("  %s of conversation %d, %s: %s\n",
+						job.Kind, job.ConversationID, humanize.Time(*job.FinishedAt), job.Error)
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&format, "format", "f", "table", "output format (table/json)")
+
+	return cmd
+}
+
+// newAddCmd creates the add subcommand
+func newAddCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "add KIND [conversation...]",
+		Short: "Queue jobs for conversations",
+		Long: `Queue a kind of job for the conversations given, or else for every
+conversation that hasn't been done yet or has gained or lost messages since.
+--all queues every conversation again.
+
+Kinds of job:
+` + kindList(),
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kind := args[0]
+			if _, err := jobs.Lookup(kind); err != nil {
+				return err
+			}
+
+			database, err := getDatabase()
+			if err != nil {
+				return err
+			}
+			defer closeDatabase(database)
+
+			var ids []int64
+			switch {
+			case len(args) > 1:
+				// Conversations can be named by slug or alias too
+				engine := search.NewEngine(database)
+				for _, arg := range args[1:] {
+					id, err := engine.ResolveConversation(arg)
+					if err != nil {
+						return err
+					}
+					ids = append(ids, id)
+				}
+			case queueAll:
+				if ids, err = allConversations(database); err != nil {
+					return err
+				}
+			default:
+				if ids, err = jobs.Stale(database, kind); err != nil {
+					return err
+				}
+			}
+
+			queued, err := jobs.Enqueue(database, kind, ids)
+			if err != nil {
+				return err
+			}
+			if queued == 0 {
+				fmt.Println("Nothing to queue.")
+				return nil
+			}
+			fmt.Printf("Queued %s; run 'shannon worker' to process them.\n", pluralize(queued, kind+" job"))
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&queueAll, "all", false, "queue every conversation, even those already done")
+
+	return cmd
+}
+
+// newRetryCmd creates the retry subcommand
+func newRetryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "retry [KIND]",
+		Short: "Queue failed jobs again",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kind := ""
+			if len(args) == 1 {
+				kind = args[0]
+				if _, err := jobs.Lookup(kind); err != nil {
+					return err
+				}
+			}
+
+			database, err := getDatabase()
+			if err != nil {
+				return err
+			}
+			defer closeDatabase(database)
+
+			retried, err := jobs.Retry(database, kind)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Queued %s again.\n", pluralize(int(retried), "failed job"))
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// newResultsCmd creates the results subcommand
+func newResultsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "results KIND",
+		Short: "Show what finished jobs found",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kind := args[0]
+			if _, err := jobs.Lookup(kind); err != nil {
+				return err
+			}
+
+			database, err := getDatabase()
+			if err != nil {
+				return err
+			}
+			defer closeDatabase(database)
+
+			results, err := jobs.Results(database, kind)
+			if err != nil {
+				return err
+			}
+
+			if format == "json" {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(map[string]interface{}{
+					"kind":    kind,
+					"results": results,
+					"count":   len(results),
+				})
+			}
+
+			if len(results) == 0 {
+				fmt.Printf("No %s results yet. Queue jobs with 'shannon jobs add %s' and run 'shannon worker'.\n", kind, kind)
+				return nil
+			}
+			switch kind {
+			case "secrets":
+				return printSecrets(results)
+			default:
+				for _, r := range results {
+					fmt.Printf("%d %s: %s\n", r.ConversationID, r.ConversationName, r.Result)
+				}
+				return nil
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&format, "format", "f", "table", "output format (table/json)")
+
+	return cmd
+}
+
+// printSecrets lists the likely secrets found in each conversation, by
+// message UUID so 'shannon view --message' can show them
+func printSecrets(results []*jobs.Result) error {
+	total, conversations := 0, 0
+	for _, r := range results {
+		var findings []jobs.SecretFinding
+		if err := json.Unmarshal(r.Result, &findings); err != nil {
+			return fmt.Errorf("failed to read the secrets found in conversation %d: %w", r.ConversationID, err)
+		}
+		if len(findings) == 0 {
+			continue
+		}
+		if conversations > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("%d %s\n", r.ConversationID, r.ConversationName)
+		for _, f := range findings {
+			fmt.Printf("  message %s line %d: %s %s\n", shortUUID(f.MessageUUID), f.Line, f.Rule, f.Masked)
+		}
+		total += len(findings)
+		conversations++
+	}
+
+	if conversations > 0 {
+		fmt.Println()
+	}
+	fmt.Printf("%s in %s, of %s scanned\n", pluralize(total, "likely secret"),
+		pluralize(conversations, "conversation"), pluralize(len(results), "conversation"))
+	return nil
+}
+
+func shortUUID(uuid string) string {
+	if len(uuid) > 8 {
+		return uuid[:8]
+	}
+	return uuid
+}
+
+// allConversations returns the IDs of the conversations outside the trash
+func allConversations(database *db.DB) ([]int64, error) {
+	rows, err := database.Query("SELECT id FROM conversations WHERE deleted_at IS NULL ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list conversations: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close rows: %v\n", err)
+		}
+	}()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func pluralize(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+func getDatabase() (*db.DB, error) {
+	cfg := config.Get()
+	database, err := db.New(cfg.Database.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	return database, nil
+}
+
+func closeDatabase(database *db.DB) {
+	if err := database.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+	}
+}
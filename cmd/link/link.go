@@ -0,0 +1,129 @@
+package link
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/neilberkman/shannon/internal/config"
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/i18n"
+	"github.com/neilberkman/shannon/internal/search"
+	"github.com/spf13/cobra"
+)
+
+var (
+	continues string
+	unlink    bool
+	limit     int
+)
+
+// LinkCmd represents the link command
+var LinkCmd = &cobra.Command{
+	Use:   "link [conversation]",
+	Short: "Link a conversation to the earlier one it continues",
+	Long: `Record that a conversation carries on from an earlier one, such as a new chat
+started when the old one got too long. 'shannon view' and the TUI show the
+conversations a conversation continues and is continued in, and
+'shannon export --chain' exports the whole chain as one document.
+
+Without --continues, the links the conversation has are shown along with
+earlier conversations it may continue, most likely first: those with a
+similar title or with messages about what its first message asks.
+
+Each conversation continues at most one other and is continued in at most
+one.
+
+Examples:
+  shannon link 456
+  shannon link 456 --continues 123
+  shannon export 456 --chain -o project.md
+  shannon link 456 --unlink`,
+	Args: cobra.ExactArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if unlink && continues != "" {
+			return fmt.Errorf("--unlink can't be combined with --continues")
+		}
+		return nil
+	},
+	RunE: runLink,
+}
+
+func init() {
+	LinkCmd.Flags().StringVar(&continues, "continues", "", "the earlier conversation this one carries on from")
+	LinkCmd.Flags().BoolVar(&unlink, "unlink", false, "remove the link to the conversation this one continues")
+	LinkCmd.Flags().IntVarP(&limit, "limit", "n", 5, "maximum number of conversations to suggest")
+}
+
+func runLink(cmd *cobra.Command, args []string) error {
+	cfg := config.Get()
+	database, err := db.New(cfg.Database.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer func() {
+		if err := database.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+		}
+	}()
+
+	engine := search.NewEngine(database)
+	engine.SetDictionary(search.NewDictionary(cfg.Search.Stopwords, cfg.Search.Synonyms))
+	convID, err := engine.ResolveConversation(args[0])
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case unlink:
+		previousID, err := engine.Unlink(convID)
+		if err != nil {
+			return err
+		}
+		if previousID == 0 {
+			fmt.Printf("Conversation %d doesn't continue another\n", convID)
+		} else {
+			fmt.Printf("Conversation %d no longer continues %d\n", convID, previousID)
+		}
+		return nil
+	case continues != "":
+		previousID, err := engine.ResolveConversation(continues)
+		if err != nil {
+			return err
+		}
+		if err := engine.Continue(convID, previousID); err != nil {
+			return err
+		}
+		fmt.Printf("Conversation %d now continues %d\n", convID, previousID)
+		return nil
+	}
+
+	lineage, err := engine.GetLineage(convID)
+	if err != nil {
+		return err
+	}
+	if lineage.Continues != nil {
+		fmt.Printf("%s %d %s\n", i18n.T("Continues:"), lineage.Continues.ID, lineage.Continues.Name)
+	}
+	if lineage.ContinuedIn != nil {
+		fmt.Printf("%s %d %s\n", i18n.T("Continued in:"), lineage.ContinuedIn.ID, lineage.ContinuedIn.Name)
+	}
+	if lineage.Continues != nil {
+		return nil
+	}
+
+	candidates, err := engine.SuggestContinued(context.Background(), convID, limit)
+	if err != nil {
+		return err
+	}
+	if len(candidates) == 0 {
+		fmt.Println(i18n.T("No earlier conversations look like this one continues them."))
+		return nil
+	}
+	fmt.Println(i18n.T("It may continue, most likely first:"))
+	for _, c := range candidates {
+		fmt.Printf("%3.0f%%  %s  %d  %s\n", c.Similarity*100, c.UpdatedAt.Format("2006-01-02"), c.ConversationID, c.ConversationName)
+	}
+	fmt.Println("\n" + i18n.Tf("Link one with `shannon link %d --continues <id>`", convID))
+	return nil
+}
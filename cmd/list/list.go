@@ -5,30 +5,63 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 	"text/tabwriter"
 	"time"
 
 	"github.com/neilberkman/shannon/internal/config"
+	"github.com/neilberkman/shannon/internal/dates"
 	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/i18n"
+	"github.com/neilberkman/shannon/internal/query"
 	"github.com/neilberkman/shannon/internal/rendering"
+	"github.com/neilberkman/shannon/internal/schema"
+	"github.com/neilberkman/shannon/internal/search"
+	"github.com/neilberkman/shannon/internal/store"
 	"github.com/spf13/cobra"
 )
 
 var (
-	limit      int
-	sortBy     string
-	searchTerm string
-	quiet      bool
-	format     string
+	limit       int
+	sortBy      string
+	searchTerm  string
+	quiet       bool
+	format      string
+	printSchema bool
+	after       string
+	before      string
+	starred     bool
+	duration    string
+	activeDays  string
 )
 
 type conversation struct {
-	ID           int64
-	UUID         string
-	Name         string
-	CreatedAt    string
-	UpdatedAt    string
-	MessageCount int
+	ID                int64
+	UUID              string
+	Name              string
+	CreatedAt         string
+	UpdatedAt         string
+	MessageCount      int
+	TokenCount        int
+	ArtifactCount     int
+	HumanMessageCount int
+	DurationSeconds   int64
+	ActiveDays        int
+}
+
+// metricSorts are the sort options backed by derived conversation metrics,
+// shown as an extra table column when selected unless the table always has
+// one
+var metricSorts = map[string]struct {
+	column string
+	header string
+}{
+	"tokens":         {"token_count", "Tokens"},
+	"artifacts":      {"artifact_count", "Artifacts"},
+	"human-messages": {"human_message_count", "Human"},
+	"duration":       {"duration_seconds", ""},
+	"active-days":    {"active_days", "Days"},
 }
 
 // ListCmd represents the list command
@@ -41,21 +74,65 @@ Examples:
   claudesearch list
   claudesearch list --limit 20
   claudesearch list --search "python"
-  claudesearch list --sort date`,
-	RunE: runList,
+  claudesearch list --sort date
+  claudesearch list --sort tokens --limit 10
+  claudesearch list --after 30d
+  claudesearch list --after @2024 --before @2025
+  claudesearch list --starred
+  claudesearch list --duration ">2h" --sort duration
+  claudesearch list --days ">7"
+
+Sorting by tokens, artifacts, human-messages, duration or active-days uses
+metrics computed at import time; token counts are estimates.
+
+The Duration column is how long a conversation ran, from its first message
+to its last. --duration and --days keep the conversations that ran that long
+or have messages on that many days, compared with >, >=, <, <= or =; a bare
+value means at least. Durations take units such as 45m, 2h, 3d or 2w. Both
+work in search queries too, as duration:>2h and days:>7.
+
+--after and --before filter by when a conversation was last updated and take
+a date (2024-06-01, 01.06.2024, 1 Jun 2024), @2024, @2024-06, today,
+yesterday or an age such as 36h, 30d, 2w, 3m or 1y.
+
+--starred lists the conversations starred in claude.ai and those with starred
+messages, for exports that record stars.
+
+JSON output follows a published schema (see --schema) and carries a
+schema_version field that changes only on breaking changes.`,
+	Annotations: map[string]string{store.Annotation: "true"},
+	RunE:        runList,
 }
 
 func init() {
 	ListCmd.Flags().IntVarP(&limit, "limit", "l", 50, "maximum number of conversations to show")
-	ListCmd.Flags().StringVarP(&sortBy, "sort", "s", "date", "sort by: date, name, or messages")
+	ListCmd.Flags().StringVarP(&sortBy, "sort", "s", "date", "sort by: date, name, messages, tokens, artifacts, human-messages, duration, or active-days")
 	ListCmd.Flags().StringVar(&searchTerm, "search", "", "filter conversations by name")
+	ListCmd.Flags().StringVar(&after, "after", "", "only conversations updated from this date or age on")
+	ListCmd.Flags().StringVar(&before, "before", "", "only conversations updated before this date or age")
+	ListCmd.Flags().BoolVar(&starred, "starred", false, "only conversations starred in claude.ai or with starred messages")
+	ListCmd.Flags().StringVar(&duration, "duration", "", "only conversations that ran this long from first message to last, e.g. \">2h\"")
+	ListCmd.Flags().StringVar(&activeDays, "days", "", "only conversations with messages on this many days, e.g. \">7\"")
 	ListCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "suppress extra output (pipe-friendly)")
 	ListCmd.Flags().StringVarP(&format, "format", "f", "table", "output format (table/json/csv)")
+	ListCmd.Flags().BoolVar(&printSchema, "schema", false, "print the JSON Schema of --format json output and exit")
 }
 
 func runList(cmd *cobra.Command, args []string) error {
+	if printSchema {
+		return schema.Write(os.Stdout, "list")
+	}
+
+	durationBound, daysBound, err := parseBounds()
+	if err != nil {
+		return err
+	}
+
 	// Get configuration
 	cfg := config.Get()
+	if store.Shared(cfg) {
+		return listShared(cfg, durationBound, daysBound)
+	}
 
 	// Open database
 	database, err := db.New(cfg.Database.Path)
@@ -70,26 +147,69 @@ func runList(cmd *cobra.Command, args []string) error {
 
 	// Build query
 	query := `
-		SELECT id, uuid, name, created_at, updated_at, message_count 
+		SELECT id, uuid, name, created_at, updated_at, message_count,
+		       token_count, artifact_count, human_message_count, duration_seconds, active_days
 		FROM conversations
 	`
 
+	// Conversations in the trash are listed by 'shannon trash list'
+	conditions := []string{"deleted_at IS NULL"}
 	var queryArgs []interface{}
 
 	// Add search filter if provided
 	if searchTerm != "" {
-		query += " WHERE name LIKE ?"
+		conditions = append(conditions, "name LIKE ?")
 		queryArgs = append(queryArgs, "%"+searchTerm+"%")
 	}
 
+	if starred {
+		conditions = append(conditions, "(starred OR id IN (SELECT conversation_id FROM messages WHERE starred))")
+	}
+
+	// Add date filters if provided
+	if after != "" {
+		t, err := dates.Parse(after, time.Now())
+		if err != nil {
+			return fmt.Errorf("invalid --after: %w", err)
+		}
+		conditions = append(conditions, "updated_at >= ?")
+		queryArgs = append(queryArgs, t.UTC().Format("2006-01-02 15:04:05"))
+	}
+	if before != "" {
+		t, err := dates.Parse(before, time.Now())
+		if err != nil {
+			return fmt.Errorf("invalid --before: %w", err)
+		}
+		conditions = append(conditions, "updated_at < ?")
+		queryArgs = append(queryArgs, t.UTC().Format("2006-01-02 15:04:05"))
+	}
+
+	if durationBound != nil {
+		conditions = append(conditions, "duration_seconds "+durationBound.Op+" ?")
+		queryArgs = append(queryArgs, durationBound.Value)
+	}
+	if daysBound != nil {
+		conditions = append(conditions, "active_days "+daysBound.Op+" ?")
+		queryArgs = append(queryArgs, daysBound.Value)
+	}
+
+	where := " WHERE " + strings.Join(conditions, " AND ")
+	query += where
+
 	// Add sorting
 	switch sortBy {
 	case "name":
 		query += " ORDER BY name ASC"
 	case "messages":
 		query += " ORDER BY message_count DESC"
-	default: // date
+	case "date", "":
 		query += " ORDER BY updated_at DESC"
+	default:
+		metric, ok := metricSorts[sortBy]
+		if !ok {
+			return fmt.Errorf("invalid sort %q (use date, name, messages, tokens, artifacts, human-messages, duration, or active-days)", sortBy)
+		}
+		query += " ORDER BY " + metric.column + " DESC, updated_at DESC"
 	}
 
 	// Add limit
@@ -110,7 +230,8 @@ func runList(cmd *cobra.Command, args []string) error {
 	var conversations []conversation
 	for rows.Next() {
 		var c conversation
-		err := rows.Scan(&c.ID, &c.UUID, &c.Name, &c.CreatedAt, &c.UpdatedAt, &c.MessageCount)
+		err := rows.Scan(&c.ID, &c.UUID, &c.Name, &c.CreatedAt, &c.UpdatedAt, &c.MessageCount,
+			&c.TokenCount, &c.ArtifactCount, &c.HumanMessageCount, &c.DurationSeconds, &c.ActiveDays)
 		if err != nil {
 			return fmt.Errorf("failed to scan conversation: %w", err)
 		}
@@ -124,32 +245,130 @@ func runList(cmd *cobra.Command, args []string) error {
 	// Display results
 	if len(conversations) == 0 {
 		if !quiet {
-			fmt.Println("No conversations found.")
+			fmt.Println(i18n.T("No conversations found."))
 		}
 		return nil
 	}
 
 	switch format {
 	case "json":
-		return outputJSON(conversations, getTotalCount(database, searchTerm))
+		return outputJSON(conversations, getTotalCount(database, where, queryArgs))
 	case "csv":
 		return outputCSV(conversations)
 	default:
-		return outputTable(conversations, getTotalCount(database, searchTerm), searchTerm, quiet)
+		return outputTable(conversations, getTotalCount(database, where, queryArgs), searchTerm, quiet)
 	}
 }
 
-func getTotalCount(database *db.DB, searchTerm string) int {
-	query := "SELECT COUNT(*) FROM conversations"
-	var args []interface{}
+// listShared lists the conversations of a shared store, filtering and
+// sorting them here; the metric sorts need what only the local database
+// keeps
+func listShared(cfg *config.Config, durationBound, daysBound *search.Bound) error {
+	if _, ok := metricSorts[sortBy]; ok && sortBy != "duration" {
+		return fmt.Errorf("--sort %s isn't supported with the %s store", sortBy, cfg.Database.Driver)
+	}
+	if sortBy != "date" && sortBy != "" && sortBy != "name" && sortBy != "messages" && sortBy != "duration" {
+		return fmt.Errorf("invalid sort %q (use date, name, messages, or duration)", sortBy)
+	}
+	var afterTime, beforeTime time.Time
+	var err error
+	if after != "" {
+		if afterTime, err = dates.Parse(after, time.Now()); err != nil {
+			return fmt.Errorf("invalid --after: %w", err)
+		}
+	}
+	if before != "" {
+		if beforeTime, err = dates.Parse(before, time.Now()); err != nil {
+			return fmt.Errorf("invalid --before: %w", err)
+		}
+	}
 
-	if searchTerm != "" {
-		query += " WHERE name LIKE ?"
-		args = append(args, "%"+searchTerm+"%")
+	s, err := store.Open(cfg)
+	if err != nil {
+		return err
 	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+		}
+	}()
 
+	conversations, total, err := sharedConversations(s, afterTime, beforeTime, durationBound, daysBound)
+	if err != nil {
+		return err
+	}
+	if len(conversations) == 0 {
+		if !quiet {
+			fmt.Println(i18n.T("No conversations found."))
+		}
+		return nil
+	}
+
+	switch format {
+	case "json":
+		return outputJSON(conversations, total)
+	case "csv":
+		return outputCSV(conversations)
+	default:
+		return outputTable(conversations, total, searchTerm, quiet)
+	}
+}
+
+// sharedConversations filters, sorts and limits the conversations of a
+// store as the list query does for the local database, returning them with
+// how many matched before the limit. A negative limit keeps them all, as
+// SQLite's LIMIT -1 does.
+func sharedConversations(s store.Store, afterTime, beforeTime time.Time, durationBound, daysBound *search.Bound) ([]conversation, int, error) {
+	all, err := s.ListConversations(0)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var conversations []conversation
+	for _, c := range all {
+		switch {
+		case searchTerm != "" && !strings.Contains(strings.ToLower(c.Name), strings.ToLower(searchTerm)),
+			starred && !c.Starred,
+			after != "" && c.UpdatedAt.Before(afterTime),
+			before != "" && !c.UpdatedAt.Before(beforeTime),
+			durationBound != nil && !durationBound.Matches(c.DurationSeconds),
+			daysBound != nil && !daysBound.Matches(int64(c.ActiveDays)):
+			continue
+		}
+		conversations = append(conversations, conversation{
+			ID:                c.ID,
+			UUID:              c.UUID,
+			Name:              c.Name,
+			CreatedAt:         c.CreatedAt.UTC().Format("2006-01-02 15:04:05"),
+			UpdatedAt:         c.UpdatedAt.UTC().Format("2006-01-02 15:04:05"),
+			MessageCount:      c.MessageCount,
+			HumanMessageCount: c.HumanMessageCount,
+			DurationSeconds:   c.DurationSeconds,
+			ActiveDays:        c.ActiveDays,
+		})
+	}
+
+	// The store lists the most recently updated first
+	switch sortBy {
+	case "name":
+		sort.SliceStable(conversations, func(i, j int) bool { return conversations[i].Name < conversations[j].Name })
+	case "messages":
+		sort.SliceStable(conversations, func(i, j int) bool { return conversations[i].MessageCount > conversations[j].MessageCount })
+	case "duration":
+		sort.SliceStable(conversations, func(i, j int) bool { return conversations[i].DurationSeconds > conversations[j].DurationSeconds })
+	}
+
+	total := len(conversations)
+	if limit >= 0 && limit < total {
+		conversations = conversations[:limit]
+	}
+	return conversations, total, nil
+}
+
+// getTotalCount counts the conversations matching the list's filters
+func getTotalCount(database *db.DB, where string, args []interface{}) int {
 	var count int
-	if err := database.QueryRow(query, args...).Scan(&count); err != nil {
+	if err := database.QueryRow("SELECT COUNT(*) FROM conversations"+where, args...).Scan(&count); err != nil {
 		// Log the error but return 0 to continue operation
 		fmt.Fprintf(os.Stderr, "Warning: failed to get total count: %v\n", err)
 		return 0
@@ -157,6 +376,21 @@ func getTotalCount(database *db.DB, searchTerm string) int {
 	return count
 }
 
+// parseBounds reads --duration and --days, returning nil for those not given
+func parseBounds() (durationBound, daysBound *search.Bound, err error) {
+	if duration != "" {
+		if durationBound, err = query.DurationBound(duration); err != nil {
+			return nil, nil, fmt.Errorf("invalid --duration: %w", err)
+		}
+	}
+	if activeDays != "" {
+		if daysBound, err = query.DaysBound(activeDays); err != nil {
+			return nil, nil, fmt.Errorf("invalid --days: %w", err)
+		}
+	}
+	return durationBound, daysBound, nil
+}
+
 func truncate(s string, maxLen int) string {
 	if len(s) <= maxLen {
 		return s
@@ -166,10 +400,19 @@ func truncate(s string, maxLen int) string {
 
 func outputTable(conversations []conversation, total int, searchTerm string, quiet bool) error {
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	if _, err := fmt.Fprintln(w, "ID\tMessages\tUpdated\tName"); err != nil {
+
+	// Show the metric being sorted by, if it isn't already a column
+	metric, showMetric := metricSorts[sortBy]
+	showMetric = showMetric && metric.header != ""
+	columns := []string{"ID", "Messages", "Duration", "Updated", "Name"}
+	if showMetric {
+		columns = []string{"ID", "Messages", "Duration", metric.header, "Updated", "Name"}
+	}
+	header, separator := i18n.Columns(columns...)
+	if _, err := fmt.Fprintln(w, header); err != nil {
 		return fmt.Errorf("failed to write header: %w", err)
 	}
-	if _, err := fmt.Fprintln(w, "--\t--------\t-------\t----"); err != nil {
+	if _, err := fmt.Fprintln(w, separator); err != nil {
 		return fmt.Errorf("failed to write separator: %w", err)
 	}
 
@@ -184,7 +427,11 @@ func outputTable(conversations []conversation, total int, searchTerm string, qui
 			convIDDisplay = rendering.MakeHyperlinkWithID(convIDDisplay, fmt.Sprintf("shannon://view/%d", c.ID), fmt.Sprintf("conv-%d", c.ID))
 		}
 
-		if _, err := fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", convIDDisplay, c.MessageCount, updatedAt, name); err != nil {
+		messages := fmt.Sprintf("%s\t%d\t%s", convIDDisplay, c.MessageCount, dates.FormatDuration(time.Duration(c.DurationSeconds)*time.Second))
+		if showMetric {
+			messages += fmt.Sprintf("\t%d", metricValue(c, sortBy))
+		}
+		if _, err := fmt.Fprintf(w, "%s\t%s\t%s\n", messages, updatedAt, name); err != nil {
 			return fmt.Errorf("failed to write row: %w", err)
 		}
 	}
@@ -194,9 +441,9 @@ func outputTable(conversations []conversation, total int, searchTerm string, qui
 	}
 
 	if !quiet {
-		fmt.Printf("\nShowing %d of %d total conversations", len(conversations), total)
+		fmt.Print("\n" + i18n.Tf("Showing %d of %d total conversations", len(conversations), total))
 		if searchTerm != "" {
-			fmt.Printf(" (filtered by '%s')", searchTerm)
+			fmt.Print(i18n.Tf(" (filtered by '%s')", searchTerm))
 		}
 		fmt.Println()
 	}
@@ -212,6 +459,7 @@ func outputJSON(conversations []conversation, total int) error {
 	}
 
 	output := map[string]interface{}{
+		schema.Field:    schema.Version,
 		"conversations": conversations,
 		"count":         len(conversations),
 		"total":         total,
@@ -226,7 +474,7 @@ func outputCSV(conversations []conversation) error {
 	w := csv.NewWriter(os.Stdout)
 
 	// Header
-	if err := w.Write([]string{"id", "uuid", "name", "message_count", "created_at", "updated_at"}); err != nil {
+	if err := w.Write([]string{"id", "uuid", "name", "message_count", "token_count", "artifact_count", "human_message_count", "duration_seconds", "active_days", "created_at", "updated_at"}); err != nil {
 		return err
 	}
 
@@ -237,6 +485,11 @@ func outputCSV(conversations []conversation) error {
 			c.UUID,
 			c.Name,
 			fmt.Sprintf("%d", c.MessageCount),
+			fmt.Sprintf("%d", c.TokenCount),
+			fmt.Sprintf("%d", c.ArtifactCount),
+			fmt.Sprintf("%d", c.HumanMessageCount),
+			fmt.Sprintf("%d", c.DurationSeconds),
+			fmt.Sprintf("%d", c.ActiveDays),
 			c.CreatedAt,
 			c.UpdatedAt,
 		}
@@ -249,7 +502,28 @@ func outputCSV(conversations []conversation) error {
 	return w.Error()
 }
 
+// metricValue returns the derived metric a sort option refers to
+func metricValue(c conversation, sort string) int {
+	switch sort {
+	case "tokens":
+		return c.TokenCount
+	case "artifacts":
+		return c.ArtifactCount
+	case "human-messages":
+		return c.HumanMessageCount
+	case "active-days":
+		return c.ActiveDays
+	default:
+		return 0
+	}
+}
+
 func parseTime(s string) time.Time {
-	t, _ := time.Parse("2006-01-02 15:04:05", s)
-	return t
+	// Timestamps are stored in Go's time format by the importer
+	for _, layout := range []string{"2006-01-02 15:04:05.999999999 -0700 MST", "2006-01-02 15:04:05", time.RFC3339} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
 }
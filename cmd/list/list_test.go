@@ -0,0 +1,66 @@
+package list
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/models"
+	"github.com/neilberkman/shannon/internal/store"
+)
+
+func TestSharedConversations(t *testing.T) {
+	s, err := store.OpenSQLite(filepath.Join(t.TempDir(), "test.db"), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = s.Close() }()
+
+	yes := true
+	_, err = s.ImportConversations("export.json", "hash-1", []models.ClaudeConversation{
+		{UUID: "c1", Name: "Go", CreatedAt: "2024-01-01T10:00:00Z", UpdatedAt: "2024-01-01T10:00:00Z",
+			ChatMessages: []models.ClaudeChatMessage{{UUID: "m1", Sender: "human", Text: "Hello", CreatedAt: "2024-01-01T10:00:00Z"}}},
+		{UUID: "c2", Name: "Rust", CreatedAt: "2024-02-01T10:00:00Z", UpdatedAt: "2024-02-01T10:00:00Z",
+			ChatMessages: []models.ClaudeChatMessage{{UUID: "m2", Sender: "human", Text: "Hello", CreatedAt: "2024-02-01T10:00:00Z", Starred: &yes}}},
+		{UUID: "c3", Name: "Zig", CreatedAt: "2024-03-01T10:00:00Z", UpdatedAt: "2024-03-01T10:00:00Z", Starred: &yes,
+			ChatMessages: []models.ClaudeChatMessage{{UUID: "m3", Sender: "human", Text: "Hello", CreatedAt: "2024-03-01T10:00:00Z"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { limit, starred = 50, false }()
+
+	tests := []struct {
+		name    string
+		limit   int
+		starred bool
+		want    []string
+		total   int
+	}{
+		{name: "limit", limit: 2, want: []string{"Zig", "Rust"}, total: 3},
+		{name: "zero limit", limit: 0, want: nil, total: 3},
+		{name: "negative limit keeps all", limit: -1, want: []string{"Zig", "Rust", "Go"}, total: 3},
+		{name: "starred conversations and messages", limit: 50, starred: true, want: []string{"Zig", "Rust"}, total: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limit, starred = tt.limit, tt.starred
+			conversations, total, err := sharedConversations(s, time.Time{}, time.Time{}, nil, nil)
+			if err != nil {
+				t.Fatalf("sharedConversations() error = %v", err)
+			}
+			if total != tt.total {
+				t.Errorf("total = %d, want %d", total, tt.total)
+			}
+			if len(conversations) != len(tt.want) {
+				t.Fatalf("got %d conversations, want %v", len(conversations), tt.want)
+			}
+			for i, c := range conversations {
+				if c.Name != tt.want[i] {
+					t.Errorf("conversation %d = %s, want %s", i, c.Name, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package onthisday
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/neilberkman/shannon/cmd/tui"
+	"github.com/neilberkman/shannon/internal/config"
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/search"
+	"github.com/spf13/cobra"
+)
+
+var (
+	date    string
+	monthly bool
+	useTUI  bool
+	format  string
+)
+
+type conversation struct {
+	ID           int64     `json:"id"`
+	Name         string    `json:"name"`
+	CreatedAt    time.Time `json:"created_at"`
+	Ago          string    `json:"ago"`
+	MessageCount int       `json:"message_count"`
+}
+
+// OnThisDayCmd represents the onthisday command
+var OnThisDayCmd = &cobra.Command{
+	Use:   "onthisday",
+	Short: "Show conversations from this day in earlier years",
+	Long: `List the conversations created on today's date in earlier years, or with
+--monthly on today's day of the month in earlier months.
+
+Examples:
+  # What was I asking about a year ago today?
+  shannon onthisday
+
+  # Include the same day in earlier months
+  shannon onthisday --monthly
+
+  # Flip through them in the TUI; ] next, [ previous
+  shannon onthisday --tui
+
+  # Another day
+  shannon onthisday --date 2025-12-25`,
+	Args: cobra.NoArgs,
+	RunE: runOnThisDay,
+}
+
+func init() {
+	OnThisDayCmd.Flags().StringVar(&date, "date", "", "day to look back from (default today)")
+	OnThisDayCmd.Flags().BoolVarP(&monthly, "monthly", "m", false, "also include this day of the month in earlier months")
+	OnThisDayCmd.Flags().BoolVar(&useTUI, "tui", false, "flip through the conversations in the TUI")
+	OnThisDayCmd.Flags().StringVarP(&format, "format", "f", "table", "output format (table/id/json)")
+}
+
+func runOnThisDay(cmd *cobra.Command, args []string) error {
+	day := time.Now()
+	if date != "" {
+		var err error
+		if day, err = time.ParseInLocation("2006-01-02", date, time.Local); err != nil {
+			return fmt.Errorf("invalid --date %q: use YYYY-MM-DD", date)
+		}
+	}
+
+	switch format {
+	case "table", "id", "json":
+	default:
+		return fmt.Errorf("unknown format %q (use table, id or json)", format)
+	}
+
+	// Get configuration
+	cfg := config.Get()
+
+	// Open database
+	database, err := db.New(cfg.Database.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer func() {
+		if err := database.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+		}
+	}()
+
+	engine := search.NewEngine(database)
+
+	found, err := engine.OnThisDay(day, monthly)
+	if err != nil {
+		return err
+	}
+
+	if len(found) == 0 {
+		if format == "json" {
+			fmt.Println("[]")
+		} else if format != "id" {
+			fmt.Printf("No conversations from %s in earlier %s.\n", dayName(day), period())
+		}
+		return nil
+	}
+
+	if useTUI {
+		ids := make([]int64, len(found))
+		for i, conv := range found {
+			ids[i] = conv.ID
+		}
+		return tui.RunCarousel(engine, "On this day: "+dayName(day), ids)
+	}
+
+	conversations := make([]conversation, len(found))
+	for i, conv := range found {
+		conversations[i] = conversation{
+			ID:           conv.ID,
+			Name:         conv.Name,
+			CreatedAt:    conv.CreatedAt,
+			Ago:          ago(conv.CreatedAt.In(day.Location()), day),
+			MessageCount: conv.MessageCount,
+		}
+	}
+
+	switch format {
+	case "id":
+		for _, conv := range conversations {
+			fmt.Println(conv.ID)
+		}
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(conversations)
+	default:
+		fmt.Printf("On this day, %s:\n\n", dayName(day))
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "WHEN\tDATE\tID\tMESSAGES\tTITLE")
+		for _, conv := range conversations {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\n",
+				conv.Ago, conv.CreatedAt.In(day.Location()).Format("2006-01-02"), conv.ID, conv.MessageCount, conv.Name)
+		}
+		return w.Flush()
+	}
+
+	return nil
+}
+
+func dayName(day time.Time) string {
+	if monthly {
+		return "the " + ordinal(day.Day())
+	}
+	return day.Format("January 2")
+}
+
+func ordinal(n int) string {
+	switch {
+	case n%100 >= 11 && n%100 <= 13:
+		return fmt.Sprintf("%dth", n)
+	case n%10 == 1:
+		return fmt.Sprintf("%dst", n)
+	case n%10 == 2:
+		return fmt.Sprintf("%dnd", n)
+	case n%10 == 3:
+		return fmt.Sprintf("%drd", n)
+	}
+	return fmt.Sprintf("%dth", n)
+}
+
+func period() string {
+	if monthly {
+		return "months"
+	}
+	return "years"
+}
+
+// ago describes how many whole years or months before day created is
+func ago(created, day time.Time) string {
+	months := (day.Year()-created.Year())*12 + int(day.Month()-created.Month())
+	if months%12 == 0 {
+		return plural(months/12, "year") + " ago"
+	}
+	return plural(months, "month") + " ago"
+}
+
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
@@ -4,19 +4,29 @@ import (
 	"bufio"
 	"fmt"
 	"os"
-	"os/exec"
-	"runtime"
-	"strconv"
 	"strings"
 
+	"github.com/neilberkman/shannon/internal/config"
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/search"
+	"github.com/neilberkman/shannon/pkg/platform"
 	"github.com/spf13/cobra"
 )
 
 // OpenCmd represents the open command
 var OpenCmd = &cobra.Command{
-	Use:   "open [conversation-id]",
+	Use:   "open [conversation]",
 	Short: "Open conversation in browser (reads ID from stdin if not provided)",
-	Long: `Open a conversation in Claude's web interface.
+	Long: `Open a conversation in Claude's web interface. Conversations split off
+another with 'shannon split' open the conversation they were split from, as
+claude.ai doesn't know about them; claude.ai has no links to single messages.
+
+Links open in the system's default browser unless ui.browser in the config
+file names a command to open them with. The URL is added to the end of the
+command, or replaces {url} in it:
+
+  ui:
+    browser: open -a "Google Chrome"
 
 Can read conversation ID from stdin, making it pipe-friendly:
 
@@ -25,7 +35,7 @@ Examples:
   claudesearch open 123
 
   # Pipe from search
-  claudesearch search "bug" --format json | jq -r '.results[0].conversation_id' | claudesearch open
+  claudesearch search "bug" --format json | jq -r '.results[0].ConversationID' | claudesearch open
 
   # Open from list
   claudesearch list --format json | jq -r '.conversations[0].id' | claudesearch open`,
@@ -50,32 +60,31 @@ func runOpen(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// Validate it's a number
-	if _, err := strconv.ParseInt(convID, 10, 64); err != nil {
-		return fmt.Errorf("invalid conversation ID: %s", convID)
+	database, err := db.New(config.Get().Database.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
 	}
+	defer func() {
+		if err := database.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+		}
+	}()
 
-	// For now, just open a search URL since we don't have the actual Claude URL structure
-	// In reality, you'd need to map the conversation ID to Claude's URL format
-	url := fmt.Sprintf("https://claude.ai/chat/%s", convID)
-
-	// Open in browser
-	var err error
-	switch runtime.GOOS {
-	case "darwin":
-		err = exec.Command("open", url).Start()
-	case "linux":
-		err = exec.Command("xdg-open", url).Start()
-	case "windows":
-		err = exec.Command("rundll32", "url.dll,FileProtocolHandler", url).Start()
-	default:
-		err = fmt.Errorf("unsupported platform")
+	// Accept a slug or alias in place of the ID
+	engine := search.NewEngine(database)
+	id, err := engine.ResolveConversation(convID)
+	if err != nil {
+		return err
 	}
-
+	url, err := engine.ClaudeURL(id)
 	if err != nil {
+		return err
+	}
+
+	if err := platform.OpenURL(url); err != nil {
 		return fmt.Errorf("failed to open browser: %w", err)
 	}
 
-	fmt.Printf("Opening conversation %s in browser...\n", convID)
+	fmt.Printf("Opened %s\n", url)
 	return nil
 }
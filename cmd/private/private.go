@@ -0,0 +1,154 @@
+package private
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/config"
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/search"
+	"github.com/spf13/cobra"
+)
+
+var format string
+
+type privateConversation struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Messages  int       `json:"messages"`
+}
+
+// NewCmd creates the private command
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "private",
+		Short: "Keep conversations out of search",
+		Long: `Private conversations are taken out of the full-text indexes, so their
+messages never turn up in a search, the TUI's or the code search's. They can
+still be listed, viewed and exported by ID, and 'shannon search
+--include-private' scans them after asking.
+
+Examples:
+  shannon private set 123
+  shannon search "salary" --include-private
+  shannon private list
+  shannon private unset 123`,
+	}
+
+	cmd.AddCommand(newSetCmd(true))
+	cmd.AddCommand(newSetCmd(false))
+	cmd.AddCommand(newListCmd())
+
+	return cmd
+}
+
+// newSetCmd creates the set subcommand, or the unset one
+func newSetCmd(private bool) *cobra.Command {
+	use, short := "set", "Make conversations private"
+	done, already := "is now private", "is already private"
+	if !private {
+		use, short = "unset", "Make private conversations searchable again"
+		done, already = "is searchable again", "isn't private"
+	}
+
+	return &cobra.Command{
+		Use:   use + " [conversation...]",
+		Short: short,
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			database, err := getDatabase()
+			if err != nil {
+				return err
+			}
+			defer closeDatabase(database)
+
+			engine := search.NewEngine(database)
+			for _, arg := range args {
+				convID, err := engine.ResolveConversation(arg)
+				if err != nil {
+					return err
+				}
+				changed, err := engine.SetPrivate(convID, private)
+				if err != nil {
+					return err
+				}
+				if changed {
+					fmt.Printf("Conversation %d %s\n", convID, done)
+				} else {
+					fmt.Printf("Conversation %d %s\n", convID, already)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// newListCmd creates the list subcommand
+func newListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List private conversations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "table" && format != "json" {
+				return fmt.Errorf("unknown format %q (use table or json)", format)
+			}
+
+			database, err := getDatabase()
+			if err != nil {
+				return err
+			}
+			defer closeDatabase(database)
+
+			convs, err := search.NewEngine(database).GetPrivateConversations()
+			if err != nil {
+				return err
+			}
+
+			if format == "json" {
+				out := make([]privateConversation, len(convs))
+				for i, c := range convs {
+					out[i] = privateConversation{ID: c.ID, Name: c.Name, UpdatedAt: c.UpdatedAt, Messages: c.Messages}
+				}
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(out)
+			}
+
+			if len(convs) == 0 {
+				fmt.Println("No private conversations.")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			_, _ = fmt.Fprintln(w, "ID\tUPDATED\tMESSAGES\tTITLE")
+			for _, c := range convs {
+				_, _ = fmt.Fprintf(w, "%d\t%s\t%d\t%s\n", c.ID, c.UpdatedAt.Format("2006-01-02"), c.Messages, c.Name)
+			}
+			return w.Flush()
+		},
+	}
+
+	cmd.Flags().StringVarP(&format, "format", "f", "table", "output format (table/json)")
+
+	return cmd
+}
+
+func getDatabase() (*db.DB, error) {
+	cfg := config.Get()
+	database, err := db.New(cfg.Database.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	return database, nil
+}
+
+func closeDatabase(database *db.DB) {
+	if err := database.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+	}
+}
@@ -0,0 +1,148 @@
+package random
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/neilberkman/shannon/cmd/tui"
+	"github.com/neilberkman/shannon/cmd/view"
+	"github.com/neilberkman/shannon/internal/config"
+	"github.com/neilberkman/shannon/internal/dates"
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/search"
+	"github.com/spf13/cobra"
+)
+
+// carouselSize is how many random conversations the TUI can flip through
+const carouselSize = 50
+
+var (
+	since       string
+	before      string
+	minMessages int
+	useTUI      bool
+	format      string
+)
+
+type conversation struct {
+	ID           int64     `json:"id"`
+	Name         string    `json:"name"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	MessageCount int       `json:"message_count"`
+}
+
+// RandomCmd represents the random command
+var RandomCmd = &cobra.Command{
+	Use:   "random",
+	Short: "Open a random conversation",
+	Long: `Pick a random conversation and show it, to rediscover old discussions.
+
+--since and --before take a date (2024-06-01, 01.06.2024, 1 Jun 2024), @2024,
+@2024-06, today, yesterday or an age such as 30d, 6w, 3m or 1y.
+
+Examples:
+  # Show a random conversation
+  shannon random
+
+  # Something from the last year with a real back-and-forth
+  shannon random --since 1y --min-messages 10
+
+  # Open in the TUI; ] picks another, [ goes back
+  shannon random --tui
+
+  # Just the ID, for scripting
+  shannon random -f id | xargs shannon export`,
+	Args: cobra.NoArgs,
+	RunE: runRandom,
+}
+
+func init() {
+	RandomCmd.Flags().StringVar(&since, "since", "", "only conversations created since this date or age")
+	RandomCmd.Flags().StringVar(&before, "before", "", "only conversations created before this date or age")
+	RandomCmd.Flags().IntVar(&minMessages, "min-messages", 0, "only conversations with at least this many messages")
+	RandomCmd.Flags().BoolVar(&useTUI, "tui", false, "open in the TUI")
+	RandomCmd.Flags().StringVarP(&format, "format", "f", "view", "output format (view/id/json)")
+}
+
+func runRandom(cmd *cobra.Command, args []string) error {
+	var filter search.ConversationFilter
+	if since != "" {
+		t, err := dates.Parse(since, time.Now())
+		if err != nil {
+			return fmt.Errorf("invalid --since: %w", err)
+		}
+		filter.Since = &t
+	}
+	if before != "" {
+		t, err := dates.Parse(before, time.Now())
+		if err != nil {
+			return fmt.Errorf("invalid --before: %w", err)
+		}
+		filter.Before = &t
+	}
+	filter.MinMessages = minMessages
+
+	switch format {
+	case "view", "id", "json":
+	default:
+		return fmt.Errorf("unknown format %q (use view, id or json)", format)
+	}
+
+	// Get configuration
+	cfg := config.Get()
+
+	// Open database
+	database, err := db.New(cfg.Database.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer func() {
+		if err := database.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+		}
+	}()
+
+	engine := search.NewEngine(database)
+
+	limit := 1
+	if useTUI {
+		limit = carouselSize
+	}
+	conversations, err := engine.RandomConversations(filter, limit)
+	if err != nil {
+		return err
+	}
+	if len(conversations) == 0 {
+		return fmt.Errorf("no conversations match the filters")
+	}
+
+	if useTUI {
+		ids := make([]int64, len(conversations))
+		for i, conv := range conversations {
+			ids[i] = conv.ID
+		}
+		return tui.RunCarousel(engine, "Random conversations", ids)
+	}
+
+	conv := conversations[0]
+	switch format {
+	case "id":
+		fmt.Println(conv.ID)
+		return nil
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(conversation{
+			ID:           conv.ID,
+			Name:         conv.Name,
+			CreatedAt:    conv.CreatedAt,
+			UpdatedAt:    conv.UpdatedAt,
+			MessageCount: conv.MessageCount,
+		})
+	default:
+		return view.Print(engine, conv.ID)
+	}
+}
@@ -0,0 +1,185 @@
+package rate
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/neilberkman/shannon/internal/config"
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/search"
+	"github.com/spf13/cobra"
+)
+
+var (
+	note   string
+	rating string
+	format string
+)
+
+// NewCmd creates the rate command
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rate",
+		Short: "Rate assistant answers as useful, obsolete or wrong",
+		Long: `Keep track of which answers you trust. Ratings and their notes are stored
+locally, survive re-imports, show up in 'shannon view' and exports, and can be
+searched with 'rating:useful' in a query or --rating.
+
+Messages are given by their number in 'shannon view' or by UUID. In the TUI,
+1, 2 and 3 rate the first answer on screen useful, obsolete or wrong, and 0
+clears its rating.
+
+Examples:
+  shannon rate set 123 4 useful
+  shannon rate set 123 6 obsolete --note "the API changed in v2"
+  shannon rate clear 123 6
+  shannon rate list --rating useful
+  shannon search "docker rating:useful"`,
+	}
+
+	cmd.AddCommand(newSetCmd())
+	cmd.AddCommand(newClearCmd())
+	cmd.AddCommand(newListCmd())
+
+	return cmd
+}
+
+// newSetCmd creates the set subcommand
+func newSetCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "set [conversation] [message] [rating]",
+		Short: "Rate an answer useful, obsolete or wrong",
+		Args:  cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := search.ValidateRating(args[2]); err != nil {
+				return err
+			}
+
+			database, err := getDatabase()
+			if err != nil {
+				return err
+			}
+			defer closeDatabase(database)
+
+			engine := search.NewEngine(database)
+			convID, err := engine.ResolveConversation(args[0])
+			if err != nil {
+				return err
+			}
+			msg, err := engine.ResolveMessage(convID, args[1])
+			if err != nil {
+				return err
+			}
+			if err := engine.RateMessage(msg.ID, args[2], note); err != nil {
+				return err
+			}
+			fmt.Printf("Rated message %s of conversation %d %s\n", args[1], convID, args[2])
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&note, "note", "", "note to keep with the rating")
+	return cmd
+}
+
+// newClearCmd creates the clear subcommand
+func newClearCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "clear [conversation] [message]",
+		Short: "Remove the rating of an answer",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			database, err := getDatabase()
+			if err != nil {
+				return err
+			}
+			defer closeDatabase(database)
+
+			engine := search.NewEngine(database)
+			convID, err := engine.ResolveConversation(args[0])
+			if err != nil {
+				return err
+			}
+			msg, err := engine.ResolveMessage(convID, args[1])
+			if err != nil {
+				return err
+			}
+			cleared, err := engine.ClearRating(msg.ID)
+			if err != nil {
+				return err
+			}
+			if !cleared {
+				fmt.Printf("Message %s of conversation %d isn't rated\n", args[1], convID)
+				return nil
+			}
+			fmt.Printf("Cleared the rating of message %s of conversation %d\n", args[1], convID)
+			return nil
+		},
+	}
+}
+
+// newListCmd creates the list subcommand
+func newListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List rated answers, most recently rated first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "table" && format != "json" {
+				return fmt.Errorf("unknown format %q (use table or json)", format)
+			}
+
+			database, err := getDatabase()
+			if err != nil {
+				return err
+			}
+			defer closeDatabase(database)
+
+			rated, err := search.NewEngine(database).GetRatedMessages(rating)
+			if err != nil {
+				return err
+			}
+
+			if format == "json" {
+				if rated == nil {
+					rated = []*search.RatedMessage{}
+				}
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(rated)
+			}
+
+			if len(rated) == 0 {
+				fmt.Println("No rated answers")
+				return nil
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			_, _ = fmt.Fprintln(w, "CONVERSATION\tRATING\tRATED\tANSWER\tNOTE")
+			for _, r := range rated {
+				_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", r.ConversationID, r.Rating,
+					r.RatedAt.Local().Format("2006-01-02"), r.Preview, strings.ReplaceAll(r.Note, "\n", " "))
+			}
+			return w.Flush()
+		},
+	}
+	cmd.Flags().StringVar(&rating, "rating", "", "only answers with this rating")
+	cmd.Flags().StringVarP(&format, "format", "f", "table", "output format (table, json)")
+	return cmd
+}
+
+func getDatabase() (*db.DB, error) {
+	cfg := config.Get()
+	database, err := db.New(cfg.Database.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	return database, nil
+}
+
+func closeDatabase(database *db.DB) {
+	if err := database.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+	}
+}
@@ -1,23 +1,45 @@
 package recent
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"sort"
 	"text/tabwriter"
 	"time"
 
 	"github.com/dustin/go-humanize"
 	"github.com/neilberkman/shannon/internal/config"
 	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/i18n"
 	"github.com/spf13/cobra"
 )
 
 var (
-	days   int
-	limit  int
-	format string
+	days    int
+	limit   int
+	format  string
+	groupBy string
+	topN    int
 )
 
+type conversation struct {
+	ID           int64     `json:"id"`
+	Name         string    `json:"name"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	MessageCount int       `json:"message_count"`
+}
+
+// rollup summarizes the conversations updated within one week or month
+type rollup struct {
+	Period            string         `json:"period"`
+	Start             time.Time      `json:"start"`
+	End               time.Time      `json:"end"`
+	ConversationCount int            `json:"conversation_count"`
+	MessageCount      int            `json:"message_count"`
+	Top               []conversation `json:"top"`
+}
+
 // RecentCmd represents the recent command
 var RecentCmd = &cobra.Command{
 	Use:   "recent",
@@ -32,14 +54,20 @@ Examples:
   claudesearch recent --days 30
 
   # Show only 5 most recent
-  claudesearch recent --limit 5`,
+  claudesearch recent --limit 5
+
+  # Roll up the last quarter by week, or by month as JSON
+  claudesearch recent --days 90 --group week
+  claudesearch recent --days 365 --group month --format json`,
 	RunE: runRecent,
 }
 
 func init() {
 	RecentCmd.Flags().IntVarP(&days, "days", "d", 7, "number of days to look back")
 	RecentCmd.Flags().IntVarP(&limit, "limit", "l", 20, "maximum number of conversations")
-	RecentCmd.Flags().StringVarP(&format, "format", "f", "table", "output format (table/id)")
+	RecentCmd.Flags().StringVarP(&format, "format", "f", "table", "output format (table/id/json)")
+	RecentCmd.Flags().StringVarP(&groupBy, "group", "g", "", "group conversations by week or month")
+	RecentCmd.Flags().IntVar(&topN, "top", 3, "number of top titles to show per group")
 }
 
 func runRecent(cmd *cobra.Command, args []string) error {
@@ -57,19 +85,31 @@ func runRecent(cmd *cobra.Command, args []string) error {
 		}
 	}()
 
+	if groupBy != "" && groupBy != "week" && groupBy != "month" {
+		return fmt.Errorf("invalid --group %q (use week or month)", groupBy)
+	}
+	if topN < 0 {
+		return fmt.Errorf("--top can't be negative")
+	}
+
 	// Calculate date threshold
 	threshold := time.Now().AddDate(0, 0, -days)
 
-	// Query recent conversations
+	// Query recent conversations. When grouping, the limit applies to the
+	// number of groups rather than conversations.
 	query := `
 		SELECT id, name, updated_at, message_count
 		FROM conversations
-		WHERE updated_at >= ?
+		WHERE updated_at >= ? AND deleted_at IS NULL
 		ORDER BY updated_at DESC
-		LIMIT ?
 	`
+	queryArgs := []interface{}{threshold.Format("2006-01-02")}
+	if groupBy == "" {
+		query += " LIMIT ?"
+		queryArgs = append(queryArgs, limit)
+	}
 
-	rows, err := database.Query(query, threshold.Format("2006-01-02"), limit)
+	rows, err := database.Query(query, queryArgs...)
 	if err != nil {
 		return fmt.Errorf("failed to query conversations: %w", err)
 	}
@@ -80,13 +120,6 @@ func runRecent(cmd *cobra.Command, args []string) error {
 	}()
 
 	// Collect results
-	type conversation struct {
-		ID           int64
-		Name         string
-		UpdatedAt    time.Time
-		MessageCount int
-	}
-
 	var conversations []conversation
 	for rows.Next() {
 		var c conversation
@@ -116,9 +149,21 @@ func runRecent(cmd *cobra.Command, args []string) error {
 		conversations = append(conversations, c)
 	}
 
+	if groupBy != "" {
+		return outputRollups(groupConversations(conversations, groupBy, limit, topN))
+	}
+
 	// Display results
+	if format == "json" {
+		return writeJSON(map[string]interface{}{
+			"conversations": conversations,
+			"count":         len(conversations),
+			"days":          days,
+		})
+	}
+
 	if len(conversations) == 0 {
-		fmt.Printf("No conversations in the last %d days\n", days)
+		fmt.Println(i18n.Tf("No conversations in the last %d days", days))
 		return nil
 	}
 
@@ -131,10 +176,11 @@ func runRecent(cmd *cobra.Command, args []string) error {
 	default:
 		// Table format
 		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-		if _, err := fmt.Fprintln(w, "ID\tMessages\tLast Updated\tName"); err != nil {
+		header, separator := i18n.Columns("ID", "Messages", "Last Updated", "Name")
+		if _, err := fmt.Fprintln(w, header); err != nil {
 			return fmt.Errorf("failed to write header: %w", err)
 		}
-		if _, err := fmt.Fprintln(w, "--\t--------\t------------\t----"); err != nil {
+		if _, err := fmt.Fprintln(w, separator); err != nil {
 			return fmt.Errorf("failed to write separator: %w", err)
 		}
 
@@ -154,6 +200,105 @@ func runRecent(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// groupConversations buckets conversations (newest first) into weekly or
+// monthly rollups, returning at most maxGroups of the most recent periods,
+// each with its maxTop longest conversations
+func groupConversations(conversations []conversation, period string, maxGroups, maxTop int) []*rollup {
+	var groups []*rollup
+	byKey := make(map[string]*rollup)
+	members := make(map[string][]conversation)
+
+	for _, c := range conversations {
+		start, end, label := periodBounds(c.UpdatedAt, period)
+		g, ok := byKey[label]
+		if !ok {
+			if maxGroups > 0 && len(groups) >= maxGroups {
+				continue
+			}
+			g = &rollup{Period: label, Start: start, End: end}
+			byKey[label] = g
+			groups = append(groups, g)
+		}
+		g.ConversationCount++
+		g.MessageCount += c.MessageCount
+		members[label] = append(members[label], c)
+	}
+
+	for _, g := range groups {
+		top := members[g.Period]
+		sort.SliceStable(top, func(i, j int) bool {
+			return top[i].MessageCount > top[j].MessageCount
+		})
+		if len(top) > maxTop {
+			top = top[:maxTop]
+		}
+		g.Top = top
+	}
+
+	return groups
+}
+
+// periodBounds returns the start and (exclusive) end of the week or month
+// containing t, along with a display label
+func periodBounds(t time.Time, period string) (time.Time, time.Time, string) {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+
+	if period == "month" {
+		start := day.AddDate(0, 0, 1-day.Day())
+		return start, start.AddDate(0, 1, 0), start.Format("January 2006")
+	}
+
+	// Weeks start on Monday, matching ISO week numbering
+	offset := (int(day.Weekday()) + 6) % 7
+	start := day.AddDate(0, 0, -offset)
+	year, week := start.ISOWeek()
+	return start, start.AddDate(0, 0, 7), fmt.Sprintf("%d-W%02d", year, week)
+}
+
+func outputRollups(groups []*rollup) error {
+	if format == "json" {
+		return writeJSON(map[string]interface{}{
+			"groups": groups,
+			"count":  len(groups),
+			"group":  groupBy,
+			"days":   days,
+		})
+	}
+
+	if len(groups) == 0 {
+		fmt.Println(i18n.Tf("No conversations in the last %d days", days))
+		return nil
+	}
+
+	for i, g := range groups {
+		if i > 0 {
+			fmt.Println()
+		}
+
+		header := g.Period
+		if groupBy == "week" {
+			header = fmt.Sprintf("%s (%s - %s)", g.Period, g.Start.Format("Jan 2"), g.End.AddDate(0, 0, -1).Format("Jan 2"))
+		}
+		fmt.Printf("%s: %d conversations, %d messages\n", header, g.ConversationCount, g.MessageCount)
+
+		for _, c := range g.Top {
+			if format == "id" {
+				fmt.Printf("  %d\n", c.ID)
+				continue
+			}
+			fmt.Printf("  %5d  %4d msgs  %s\n", c.ID, c.MessageCount, truncate(c.Name, 60))
+		}
+	}
+
+	return nil
+}
+
+func writeJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
 func formatRelativeTime(t time.Time) string {
 	return humanize.Time(t)
 }
@@ -0,0 +1,88 @@
+package recent
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPeriodBounds(t *testing.T) {
+	tests := []struct {
+		name   string
+		t      time.Time
+		period string
+		start  string
+		end    string
+		label  string
+	}{
+		{"week from wednesday", time.Date(2025, 3, 12, 15, 0, 0, 0, time.UTC), "week", "2025-03-10", "2025-03-17", "2025-W11"},
+		{"week from monday", time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC), "week", "2025-03-10", "2025-03-17", "2025-W11"},
+		{"week from sunday", time.Date(2025, 3, 16, 23, 59, 0, 0, time.UTC), "week", "2025-03-10", "2025-03-17", "2025-W11"},
+		{"week across new year", time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC), "week", "2024-12-30", "2025-01-06", "2025-W01"},
+		{"week in last year's ISO year", time.Date(2021, 1, 2, 9, 0, 0, 0, time.UTC), "week", "2020-12-28", "2021-01-04", "2020-W53"},
+		{"month", time.Date(2025, 2, 28, 23, 0, 0, 0, time.UTC), "month", "2025-02-01", "2025-03-01", "February 2025"},
+		{"month on the first", time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), "month", "2025-03-01", "2025-04-01", "March 2025"},
+		{"december", time.Date(2024, 12, 31, 12, 0, 0, 0, time.UTC), "month", "2024-12-01", "2025-01-01", "December 2024"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, label := periodBounds(tt.t, tt.period)
+			if got := start.Format("2006-01-02"); got != tt.start {
+				t.Errorf("start = %s, want %s", got, tt.start)
+			}
+			if got := end.Format("2006-01-02"); got != tt.end {
+				t.Errorf("end = %s, want %s", got, tt.end)
+			}
+			if label != tt.label {
+				t.Errorf("label = %q, want %q", label, tt.label)
+			}
+		})
+	}
+}
+
+func TestGroupConversations(t *testing.T) {
+	day := func(month time.Month, d int) time.Time {
+		return time.Date(2025, month, d, 12, 0, 0, 0, time.UTC)
+	}
+	// Newest first, as the query returns them
+	conversations := []conversation{
+		{ID: 1, UpdatedAt: day(3, 12), MessageCount: 4},
+		{ID: 2, UpdatedAt: day(3, 11), MessageCount: 10},
+		{ID: 3, UpdatedAt: day(3, 10), MessageCount: 6},
+		{ID: 4, UpdatedAt: day(3, 10), MessageCount: 2},
+		{ID: 5, UpdatedAt: day(3, 4), MessageCount: 8},
+		{ID: 6, UpdatedAt: day(2, 20), MessageCount: 1},
+	}
+
+	weeks := groupConversations(conversations, "week", 0, 2)
+	if len(weeks) != 3 {
+		t.Fatalf("expected 3 weeks, got %d", len(weeks))
+	}
+	first := weeks[0]
+	if first.Period != "2025-W11" || first.ConversationCount != 4 || first.MessageCount != 22 {
+		t.Errorf("unexpected first week: %+v", first)
+	}
+	// The top two by messages, longest first
+	if len(first.Top) != 2 || first.Top[0].ID != 2 || first.Top[1].ID != 3 {
+		t.Errorf("expected conversations 2 and 3 on top, got %+v", first.Top)
+	}
+	if weeks[1].Period != "2025-W10" || weeks[2].Period != "2025-W08" {
+		t.Errorf("expected weeks 10 and 8 next, got %s and %s", weeks[1].Period, weeks[2].Period)
+	}
+
+	// Only the most recent groups are kept
+	months := groupConversations(conversations, "month", 1, 3)
+	if len(months) != 1 || months[0].Period != "March 2025" || months[0].ConversationCount != 5 {
+		t.Errorf("expected only March with 5 conversations, got %+v", months)
+	}
+	if len(months[0].Top) != 3 {
+		t.Errorf("expected 3 top conversations, got %d", len(months[0].Top))
+	}
+
+	// No titles at all
+	for _, g := range groupConversations(conversations, "month", 0, 0) {
+		if len(g.Top) != 0 {
+			t.Errorf("expected no top conversations in %s, got %d", g.Period, len(g.Top))
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package rename
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/neilberkman/shannon/internal/config"
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/rename"
+	"github.com/neilberkman/shannon/internal/search"
+	"github.com/spf13/cobra"
+)
+
+var (
+	searchText   string
+	templateText string
+	dryRun       bool
+	restore      bool
+	assumeYes    bool
+)
+
+// NewCmd creates the rename command
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rename [conversation...]",
+		Short: "Rename conversations from a template",
+		Long: `Rename conversations, one or many at once, by giving them names made from a
+Go template. The conversations are those given by ID, slug or alias, and those
+whose name contains the --search text. The new names are listed and confirmed
+before anything changes; --dry-run only lists them.
+
+The template sees the conversation's ID, UUID, Name, CreatedAt, UpdatedAt,
+MessageCount, FirstHumanMessage and FirstAssistantMessage, and can use
+firstWords, firstLine, truncate, lower, upper and trim. A name without any
+template actions renames the conversations to exactly that.
+
+Renamed conversations keep their new names when an export is imported again,
+and slugs don't change. --restore gives them back their names from the export.
+
+Examples:
+  shannon rename --search "Untitled" --dry-run \
+    --template 'Chat {{.CreatedAt.Format "2006-01-02"}} – {{firstWords .FirstHumanMessage 6}}'
+  shannon rename 123 --template "Kubernetes upgrade notes"
+  shannon rename --search "Chat 2024" --restore`,
+		RunE: runRename,
+	}
+
+	cmd.Flags().StringVarP(&searchText, "search", "s", "", "rename the conversations whose name contains this text")
+	cmd.Flags().StringVarP(&templateText, "template", "t", "", "Go template for the new names")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "list the new names without renaming")
+	cmd.Flags().BoolVar(&restore, "restore", false, "give renamed conversations back their names from the export")
+	cmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "don't ask for confirmation")
+
+	return cmd
+}
+
+func runRename(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 && searchText == "" {
+		return fmt.Errorf("name the conversations to rename or pass --search")
+	}
+	if restore == (templateText != "") {
+		return fmt.Errorf("pass either --template or --restore")
+	}
+
+	cfg := config.Get()
+	database, err := db.New(cfg.Database.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer func() {
+		if err := database.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+		}
+	}()
+
+	ids, err := conversations(database, args)
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return fmt.Errorf("no conversation names contain %q", searchText)
+	}
+
+	if restore {
+		if dryRun {
+			return fmt.Errorf("--dry-run can't be used with --restore")
+		}
+		renames, err := rename.Restore(database, ids)
+		if err != nil {
+			return err
+		}
+		for _, r := range renames {
+			fmt.Printf("  %d: %s → %s\n", r.ConversationID, r.OldName, r.NewName)
+		}
+		fmt.Printf("Restored the names of %s\n", pluralize(len(renames), "conversation"))
+		return nil
+	}
+
+	tmpl, err := rename.ParseTemplate(templateText)
+	if err != nil {
+		return err
+	}
+	renames, err := rename.Plan(database, ids, tmpl)
+	if err != nil {
+		return err
+	}
+	if len(renames) == 0 {
+		fmt.Println("No names would change.")
+		return nil
+	}
+
+	for _, r := range renames {
+		fmt.Printf("  %d: %s → %s\n", r.ConversationID, r.OldName, r.NewName)
+	}
+	if unchanged := len(ids) - len(renames); unchanged > 0 {
+		fmt.Printf("  (%s already named that way)\n", pluralize(unchanged, "conversation"))
+	}
+	if dryRun {
+		fmt.Printf("\nWould rename %s.\n", pluralize(len(renames), "conversation"))
+		return nil
+	}
+	if !confirm(fmt.Sprintf("Rename %s?", pluralize(len(renames), "conversation"))) {
+		fmt.Println("Aborted.")
+		return nil
+	}
+
+	if err := rename.Apply(database, renames); err != nil {
+		return err
+	}
+	fmt.Printf("Renamed %s\n", pluralize(len(renames), "conversation"))
+	return nil
+}
+
+// conversations returns the conversations named by args and those whose
+// name contains the --search text, each once
+func conversations(database *db.DB, args []string) ([]int64, error) {
+	engine := search.NewEngine(database)
+	seen := make(map[int64]bool)
+	var ids []int64
+	for _, arg := range args {
+		id, err := engine.ResolveConversation(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	if searchText != "" {
+		matching, err := rename.Matching(database, searchText)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range matching {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids, nil
+}
+
+// confirm asks before continuing
+func confirm(question string) bool {
+	if assumeYes {
+		return true
+	}
+	fmt.Printf("\n%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+func pluralize(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
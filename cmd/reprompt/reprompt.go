@@ -0,0 +1,210 @@
+package reprompt
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/neilberkman/shannon/internal/clipboard"
+	"github.com/neilberkman/shannon/internal/config"
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/export"
+	"github.com/neilberkman/shannon/internal/models"
+	"github.com/neilberkman/shannon/internal/search"
+	"github.com/neilberkman/shannon/pkg/platform"
+	"github.com/spf13/cobra"
+)
+
+var (
+	messageRange string
+	onlySender   string
+	instruction  string
+	outputFile   string
+	copyPrompt   bool
+	openDraft    bool
+)
+
+// RepromptCmd represents the reprompt command
+var RepromptCmd = &cobra.Command{
+	Use:   "reprompt [conversation]",
+	Short: "Start a new claude.ai chat from an old conversation",
+	Long: `Assemble messages from an old conversation into a prompt for a new chat, to
+continue it or run it again with fresh context. The prompt quotes the
+messages and ends with an instruction, by default to pick up where the
+conversation left off.
+
+Messages are numbered as in 'shannon view'; --messages takes numbers and
+ranges such as 1-5,8,12- (an open range runs to the end). --only human keeps
+just your prompts, to re-run them.
+
+The prompt is printed unless it's written to a file with --output, copied
+with --copy or opened with --open, which starts a claude.ai chat with the
+prompt as its draft. Prompts too long to fit in a link are copied to the
+clipboard instead, ready to paste into the chat that opens.
+
+Examples:
+  shannon reprompt 123 --messages 1-5 --copy
+  shannon reprompt 123 --only human --instruction "Answer these again for Go 1.24" --open
+  shannon reprompt python-pandas-cleanup-2024-05 -o prompt.md`,
+	Args: cobra.ExactArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if onlySender != "" && onlySender != "human" && onlySender != "assistant" {
+			return fmt.Errorf("--only must be human or assistant, not %q", onlySender)
+		}
+		return nil
+	},
+	RunE: runReprompt,
+}
+
+func init() {
+	RepromptCmd.Flags().StringVar(&messageRange, "messages", "", "messages to include by number, e.g. 1-5,8,12- (default all)")
+	RepromptCmd.Flags().StringVar(&onlySender, "only", "", "only include the messages of one sender: assistant or human")
+	RepromptCmd.Flags().StringVarP(&instruction, "instruction", "i", "", "what to ask for after the quoted messages")
+	RepromptCmd.Flags().StringVarP(&outputFile, "output", "o", "", "write the prompt to this file")
+	RepromptCmd.Flags().BoolVarP(&copyPrompt, "copy", "c", false, "copy the prompt to the clipboard")
+	RepromptCmd.Flags().BoolVar(&openDraft, "open", false, "open a new claude.ai chat with the prompt as a draft")
+}
+
+func runReprompt(cmd *cobra.Command, args []string) error {
+	cfg := config.Get()
+	database, err := db.New(cfg.Database.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer func() {
+		if err := database.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+		}
+	}()
+
+	engine := search.NewEngine(database)
+	convID, err := engine.ResolveConversation(args[0])
+	if err != nil {
+		return err
+	}
+	conv, messages, err := engine.GetConversation(convID)
+	if err != nil {
+		return fmt.Errorf("failed to get conversation: %w", err)
+	}
+
+	selected, err := selectMessages(messages, messageRange)
+	if err != nil {
+		return err
+	}
+	if onlySender != "" {
+		var kept []*models.Message
+		for _, msg := range selected {
+			if msg.Sender == onlySender {
+				kept = append(kept, msg)
+			}
+		}
+		selected = kept
+	}
+	if len(selected) == 0 {
+		return fmt.Errorf("no messages selected from conversation %d", convID)
+	}
+
+	prompt := export.Prompt(conv, selected, instruction)
+
+	if outputFile == "" && !copyPrompt && !openDraft {
+		fmt.Print(prompt)
+		return nil
+	}
+
+	if outputFile != "" {
+		if err := os.WriteFile(outputFile, []byte(prompt), 0644); err != nil {
+			return fmt.Errorf("failed to write prompt: %w", err)
+		}
+		fmt.Printf("Wrote a prompt from %d message(s) to %s\n", len(selected), outputFile)
+	}
+
+	if openDraft {
+		link, ok := export.DraftURL(prompt)
+		if !ok {
+			// Paste it into a blank chat instead
+			link = "https://claude.ai/new"
+			copyPrompt = true
+		}
+		if copyPrompt {
+			if err := copyToClipboard(prompt); err != nil {
+				return err
+			}
+			fmt.Printf("Copied a prompt from %d message(s) to the clipboard\n", len(selected))
+		}
+		if err := platform.OpenURL(link); err != nil {
+			return fmt.Errorf("failed to open browser: %w", err)
+		}
+		if ok {
+			fmt.Println("Opening a new chat with the prompt as a draft...")
+		} else {
+			fmt.Println("The prompt is too long for a link; paste it into the new chat that's opening")
+		}
+		return nil
+	}
+
+	if copyPrompt {
+		if err := copyToClipboard(prompt); err != nil {
+			return err
+		}
+		fmt.Printf("Copied a prompt from %d message(s) to the clipboard\n", len(selected))
+	}
+	return nil
+}
+
+func copyToClipboard(text string) error {
+	if err := clipboard.Init(); err != nil {
+		return fmt.Errorf("clipboard unavailable: %w", err)
+	}
+	if err := clipboard.Write(text); err != nil {
+		return fmt.Errorf("failed to copy the prompt: %w", err)
+	}
+	return nil
+}
+
+// selectMessages picks the messages numbered in spec, a comma separated list
+// of numbers and ranges counted from 1 as 'shannon view' numbers them, in
+// conversation order. An empty spec selects every message.
+func selectMessages(messages []*models.Message, spec string) ([]*models.Message, error) {
+	if strings.TrimSpace(spec) == "" {
+		return messages, nil
+	}
+
+	picked := make([]bool, len(messages))
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		from, to, isRange := strings.Cut(part, "-")
+
+		first, err := strconv.Atoi(strings.TrimSpace(from))
+		if err != nil {
+			return nil, fmt.Errorf("invalid message number %q in --messages", part)
+		}
+		last := first
+		if isRange {
+			last = len(messages)
+			if to = strings.TrimSpace(to); to != "" {
+				if last, err = strconv.Atoi(to); err != nil {
+					return nil, fmt.Errorf("invalid message number %q in --messages", part)
+				}
+			}
+		}
+
+		if first > last {
+			return nil, fmt.Errorf("range %s in --messages runs backwards", part)
+		}
+		if first < 1 || last > len(messages) {
+			return nil, fmt.Errorf("%s is outside the conversation's %d messages", part, len(messages))
+		}
+		for n := first; n <= last; n++ {
+			picked[n-1] = true
+		}
+	}
+
+	var selected []*models.Message
+	for i, msg := range messages {
+		if picked[i] {
+			selected = append(selected, msg)
+		}
+	}
+	return selected, nil
+}
@@ -0,0 +1,57 @@
+package reprompt
+
+import (
+	"testing"
+
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+func TestSelectMessages(t *testing.T) {
+	messages := make([]*models.Message, 10)
+	for i := range messages {
+		messages[i] = &models.Message{ID: int64(i + 1)}
+	}
+
+	tests := []struct {
+		spec     string
+		expected []int64
+		wantErr  bool
+	}{
+		{"", []int64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, false},
+		{"1-3", []int64{1, 2, 3}, false},
+		{"8, 2-3", []int64{2, 3, 8}, false},
+		{"9-", []int64{9, 10}, false},
+		{"2,2-3", []int64{2, 3}, false},
+		{"0-2", nil, true},
+		{"5-11", nil, true},
+		{"4-2", nil, true},
+		{"two", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			selected, err := selectMessages(messages, tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %d messages", len(selected))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []int64
+			for _, msg := range selected {
+				ids = append(ids, msg.ID)
+			}
+			if len(ids) != len(tt.expected) {
+				t.Fatalf("expected messages %v, got %v", tt.expected, ids)
+			}
+			for i := range ids {
+				if ids[i] != tt.expected[i] {
+					t.Fatalf("expected messages %v, got %v", tt.expected, ids)
+				}
+			}
+		})
+	}
+}
@@ -3,15 +3,27 @@ package root
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/neilberkman/shannon/internal/config"
+	"github.com/neilberkman/shannon/internal/crash"
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/i18n"
+	"github.com/neilberkman/shannon/internal/rendering"
+	"github.com/neilberkman/shannon/internal/store"
+	"github.com/neilberkman/shannon/pkg/platform"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
 var (
 	cfgFile string
 	verbose bool
+	plain   bool
 )
 
 var (
@@ -49,10 +61,52 @@ Quick start:
 		if err := config.Init(); err != nil {
 			return fmt.Errorf("failed to initialize config: %w", err)
 		}
+		if ms := config.Get().Database.BusyTimeoutMs; ms > 0 {
+			db.BusyTimeout = time.Duration(ms) * time.Millisecond
+		}
+		db.TrashRetention = time.Duration(config.Get().Trash.RetentionDays) * 24 * time.Hour
+		labels := config.Get().UI.SenderLabels
+		rendering.SetSenderLabels(labels.Human, labels.Assistant)
+		platform.Browser = config.Get().UI.Browser
+		if plain || rendering.Plain() {
+			rendering.SetPlain()
+		}
+		localize(cmd.Root(), config.Get().UI.Language)
+		if err := checkStore(cmd); err != nil {
+			return err
+		}
+
+		crash.SetEnabled(config.Get().Crash.Reports)
+		crash.SetDir(filepath.Join(config.GetDirs().Data, "crashes"))
+		crash.AddDetail("Schema", strconv.Itoa(db.SchemaVersion))
+		crash.AddDetail("Language", i18n.Locale())
+		crash.Note("run %s", cmd.CommandPath())
 		return nil
 	},
 }
 
+// checkStore refuses to run a command that only works on the local database
+// when the config names a shared store, rather than have it quietly use the
+// local one
+func checkStore(cmd *cobra.Command) error {
+	cfg := config.Get()
+	switch cfg.Database.Driver {
+	case "", store.DriverSQLite, store.DriverPostgres:
+	default:
+		return fmt.Errorf("invalid database.driver %q (use %s or %s)", cfg.Database.Driver, store.DriverSQLite, store.DriverPostgres)
+	}
+	if !store.Shared(cfg) || cmd.Annotations[store.Annotation] != "" || !cmd.Runnable() {
+		return nil
+	}
+	for c := cmd; c != nil; c = c.Parent() {
+		if c.Name() == "help" || c.Name() == "completion" || c.Name() == cobra.ShellCompRequestCmd {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s works on the local database, and database.driver is %s; set it to %s to use it",
+		cmd.CommandPath(), cfg.Database.Driver, store.DriverSQLite)
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 func Execute() {
 	if err := RootCmd.Execute(); err != nil {
@@ -67,6 +121,21 @@ func init() {
 	// Global flags
 	RootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.config/shannon/config.yaml)")
 	RootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
+	RootCmd.PersistentFlags().BoolVar(&plain, "plain", false, "plain output without colors, text styles, hyperlinks or emoji (also set by the NO_COLOR environment variable)")
+	RootCmd.PersistentFlags().BoolVar(&plain, "no-color", false, "same as --plain")
+
+	// Help is shown before PersistentPreRunE runs, so it finds the language
+	// itself
+	help := RootCmd.HelpFunc()
+	RootCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
+		initConfig()
+		language := ""
+		if err := config.Init(); err == nil {
+			language = config.Get().UI.Language
+		}
+		localize(cmd.Root(), language)
+		help(cmd, args)
+	})
 
 	// Bind flags to viper
 	if err := viper.BindPFlag("verbose", RootCmd.PersistentFlags().Lookup("verbose")); err != nil {
@@ -83,3 +152,46 @@ func initConfig() {
 
 	viper.AutomaticEnv() // read in environment variables that match
 }
+
+// usageHeadings are the headings of cobra's usage template. Global Flags
+// comes before Flags so it is replaced as a whole.
+var usageHeadings = []string{
+	"Usage:", "Aliases:", "Examples:", "Available Commands:", "Additional Commands:",
+	"Global Flags:", "Flags:", "Additional help topics:",
+	`Use "{{.CommandPath}} [command] --help" for more information about a command.`,
+}
+
+// localize switches to the configured language, or the one the locale
+// environment variables ask for, and translates the help of every command
+func localize(root *cobra.Command, configured string) {
+	lang := i18n.Detect(configured)
+	if configured != "" && !i18n.Supported(lang) {
+		fmt.Fprintf(os.Stderr, "Warning: ui.language %q isn't available; use one of %s\n",
+			configured, strings.Join(i18n.Languages(), ", "))
+	}
+	i18n.SetLocale(lang)
+	if i18n.Locale() == i18n.English {
+		return
+	}
+
+	usage := root.UsageTemplate()
+	for _, heading := range usageHeadings {
+		usage = strings.ReplaceAll(usage, heading, i18n.T(heading))
+	}
+	root.SetUsageTemplate(usage)
+	translateCommand(root)
+}
+
+// translateCommand translates the short description and flag usages of a
+// command and its subcommands
+func translateCommand(cmd *cobra.Command) {
+	cmd.Short = i18n.T(cmd.Short)
+	translateFlag := func(f *pflag.Flag) {
+		f.Usage = i18n.T(f.Usage)
+	}
+	cmd.LocalFlags().VisitAll(translateFlag)
+	cmd.PersistentFlags().VisitAll(translateFlag)
+	for _, sub := range cmd.Commands() {
+		translateCommand(sub)
+	}
+}
@@ -0,0 +1,79 @@
+package search
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/neilberkman/shannon/internal/export"
+	"github.com/neilberkman/shannon/internal/i18n"
+	"github.com/neilberkman/shannon/internal/models"
+	"github.com/neilberkman/shannon/internal/rendering"
+	"github.com/neilberkman/shannon/internal/search"
+)
+
+// exportNotes writes research notes on what the search for raw found: one
+// Markdown file per conversation in dir with the matched messages and
+// context messages around each
+func exportNotes(engine *search.Engine, results []*models.SearchResult, raw, dir string, context int, highlighter *rendering.Highlighter) error {
+	if len(results) == 0 {
+		if !quiet {
+			fmt.Println(i18n.T("No results found."))
+		}
+		return nil
+	}
+
+	// Group matches by conversation, most relevant conversation first
+	var convIDs []int64
+	matched := make(map[int64]map[int64]bool)
+	best := make(map[int64]int64)
+	for _, r := range results {
+		if _, ok := matched[r.ConversationID]; !ok {
+			matched[r.ConversationID] = make(map[int64]bool)
+			best[r.ConversationID] = r.MessageID
+			convIDs = append(convIDs, r.ConversationID)
+		}
+		matched[r.ConversationID][r.MessageID] = true
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	for _, convID := range convIDs {
+		conv, messages, err := engine.GetConversation(convID)
+		if err != nil {
+			return fmt.Errorf("failed to export conversation %d: %w", convID, err)
+		}
+		// Matches only on other branches are shown on the thread through
+		// the best of them
+		if !anyMatched(messages, matched[convID]) {
+			if conv, messages, err = engine.GetThread(convID, best[convID]); err != nil {
+				return fmt.Errorf("failed to export conversation %d: %w", convID, err)
+			}
+		}
+
+		filename := filepath.Join(dir, export.NotesFilename(conv))
+		content := export.SearchNotes(conv, messages, matched[convID], context, raw, highlighter)
+		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+			return fmt.Errorf("failed to write file: %w", err)
+		}
+		if err := engine.LogAccess(convID, search.AccessExport); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+
+	if !quiet {
+		fmt.Printf("Wrote notes on %d matches in %d conversations to %s\n", len(results), len(convIDs), dir)
+	}
+	return nil
+}
+
+// anyMatched reports whether any of the messages is one of the matches
+func anyMatched(messages []*models.Message, matches map[int64]bool) bool {
+	for _, msg := range messages {
+		if matches[msg.ID] {
+			return true
+		}
+	}
+	return false
+}
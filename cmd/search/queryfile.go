@@ -0,0 +1,170 @@
+package search
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/neilberkman/shannon/cmd/tui"
+	"github.com/neilberkman/shannon/internal/config"
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/i18n"
+	"github.com/neilberkman/shannon/internal/models"
+	"github.com/neilberkman/shannon/internal/query"
+	"github.com/neilberkman/shannon/internal/rendering"
+	"github.com/neilberkman/shannon/internal/search"
+)
+
+// runQueryFile runs every query of --query-file with the flags' options and
+// lists each message found once, with the queries that found it
+func runQueryFile() error {
+	if showFacets || explain {
+		return fmt.Errorf("--facets and --explain are not supported with --query-file")
+	}
+	lines, err := readQueryFile(queryFile)
+	if err != nil {
+		return err
+	}
+	if len(lines) == 0 {
+		return fmt.Errorf("no queries in %s", queryFile)
+	}
+	parsed := make([]*query.Query, len(lines))
+	for i, line := range lines {
+		if parsed[i], err = parseQuery(line); err != nil {
+			return fmt.Errorf("query %q: %w", line, err)
+		}
+	}
+
+	cfg := config.Get()
+	database, err := db.New(cfg.Database.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer func() {
+		if err := database.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+		}
+	}()
+
+	engine := search.NewEngine(database)
+	engine.SetDictionary(search.NewDictionary(cfg.Search.Stopwords, cfg.Search.Synonyms))
+
+	base, err := flagOptions(cfg)
+	if err != nil {
+		return err
+	}
+
+	var results []*models.SearchResult
+	found := make(map[int64]*models.SearchResult)
+	var terms []string
+	for i, q := range parsed {
+		opts := base
+		q.Apply(&opts)
+
+		started := time.Now()
+		matches, err := engine.Search(opts)
+		if err != nil {
+			return fmt.Errorf("search for %q failed: %w", lines[i], err)
+		}
+		if err := engine.LogSearch(lines[i], time.Since(started)); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+
+		for _, r := range matches {
+			if prev, ok := found[r.MessageID]; ok {
+				prev.Queries = append(prev.Queries, lines[i])
+				continue
+			}
+			r.Queries = []string{lines[i]}
+			found[r.MessageID] = r
+			results = append(results, r)
+		}
+		terms = append(terms, search.QueryTerms(q.Text)...)
+	}
+	sortMerged(results)
+	results = capMerged(results)
+
+	if pick {
+		if len(results) == 0 {
+			fmt.Fprintln(os.Stderr, i18n.T("No results found."))
+			return nil
+		}
+		return tui.RunPicker(engine, results, queryFile)
+	}
+
+	switch format {
+	case "json":
+		return outputJSON(results, nil, nil)
+	case "csv":
+		return outputCSV(results, true)
+	default:
+		return outputTable(results, true, showSnippets, showContext, contextLines, database, quiet, rendering.NewHighlighter(terms...))
+	}
+}
+
+// readQueryFile reads the queries of a query file, or of stdin for "-": one
+// per line, skipping blank lines and # comments
+func readQueryFile(path string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open query file: %w", err)
+		}
+		defer func() { _ = f.Close() }()
+		r = f
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read query file: %w", err)
+	}
+	return lines, nil
+}
+
+// sortMerged orders the results of several queries: by date with --sort-by
+// date, and otherwise those found by the most queries first, then in the
+// order the queries found them
+func sortMerged(results []*models.SearchResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		if sortBy == "date" {
+			if sortOrder == "asc" {
+				return results[i].CreatedAt.Before(results[j].CreatedAt)
+			}
+			return results[i].CreatedAt.After(results[j].CreatedAt)
+		}
+		return len(results[i].Queries) > len(results[j].Queries)
+	})
+}
+
+// capMerged applies --distinct and --limit again to the merged results, which
+// each query only applied to its own: the first result of each conversation
+// is kept, and at most --limit results
+func capMerged(results []*models.SearchResult) []*models.SearchResult {
+	if distinct == search.DistinctConversation {
+		seen := make(map[int64]bool)
+		kept := results[:0]
+		for _, r := range results {
+			if !seen[r.ConversationID] {
+				seen[r.ConversationID] = true
+				kept = append(kept, r)
+			}
+		}
+		results = kept
+	}
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
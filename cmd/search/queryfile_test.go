@@ -0,0 +1,103 @@
+package search
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/models"
+	"github.com/neilberkman/shannon/internal/search"
+)
+
+const queries = `# Standing searches
+
+kubernetes ingress
+  from:me nginx  
+
+# Older ones
+@2024 postgres
+`
+
+func TestReadQueryFile(t *testing.T) {
+	want := []string{"kubernetes ingress", "from:me nginx", "@2024 postgres"}
+
+	path := filepath.Join(t.TempDir(), "queries.txt")
+	if err := os.WriteFile(path, []byte(queries), 0644); err != nil {
+		t.Fatal(err)
+	}
+	lines, err := readQueryFile(path)
+	if err != nil {
+		t.Fatalf("failed to read the file: %v", err)
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("expected %q, got %q", want, lines)
+	}
+
+	// - reads stdin
+	stdin := os.Stdin
+	defer func() { os.Stdin = stdin }()
+	if os.Stdin, err = os.Open(path); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Stdin.Close() }()
+	if lines, err = readQueryFile("-"); err != nil {
+		t.Fatalf("failed to read stdin: %v", err)
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("expected %q from stdin, got %q", want, lines)
+	}
+
+	if _, err := readQueryFile(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func TestSortMerged(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2025, 6, d, 0, 0, 0, 0, time.UTC) }
+	merged := func() []*models.SearchResult {
+		// In the order the queries found them
+		return []*models.SearchResult{
+			{MessageID: 1, ConversationID: 1, CreatedAt: day(3), Queries: []string{"a"}},
+			{MessageID: 2, ConversationID: 1, CreatedAt: day(1), Queries: []string{"a", "b"}},
+			{MessageID: 3, ConversationID: 2, CreatedAt: day(4), Queries: []string{"a"}},
+			{MessageID: 4, ConversationID: 3, CreatedAt: day(2), Queries: []string{"a", "b", "c"}},
+		}
+	}
+	ids := func(results []*models.SearchResult) []int64 {
+		var ids []int64
+		for _, r := range results {
+			ids = append(ids, r.MessageID)
+		}
+		return ids
+	}
+	defer func() { sortBy, sortOrder, limit, distinct = "relevance", "desc", 50, "" }()
+
+	tests := []struct {
+		name     string
+		sortBy   string
+		order    string
+		limit    int
+		distinct string
+		want     []int64
+	}{
+		{"most queries first", "relevance", "desc", 50, "", []int64{4, 2, 1, 3}},
+		{"newest first", "date", "desc", 50, "", []int64{3, 1, 4, 2}},
+		{"oldest first", "date", "asc", 50, "", []int64{2, 4, 1, 3}},
+		{"limit", "relevance", "desc", 2, "", []int64{4, 2}},
+		{"distinct", "relevance", "desc", 50, search.DistinctConversation, []int64{4, 2, 3}},
+		{"distinct then limit", "date", "desc", 2, search.DistinctConversation, []int64{3, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sortBy, sortOrder, limit, distinct = tt.sortBy, tt.order, tt.limit, tt.distinct
+			results := merged()
+			sortMerged(results)
+			if got := ids(capMerged(results)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
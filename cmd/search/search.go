@@ -1,31 +1,43 @@
 package search
 
 import (
+	"bufio"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 	"text/tabwriter"
 	"time"
+	"unicode/utf8"
 
+	"github.com/neilberkman/shannon/cmd/tui"
 	"github.com/neilberkman/shannon/internal/config"
+	"github.com/neilberkman/shannon/internal/dates"
 	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/i18n"
 	"github.com/neilberkman/shannon/internal/models"
+	"github.com/neilberkman/shannon/internal/query"
 	"github.com/neilberkman/shannon/internal/rendering"
+	"github.com/neilberkman/shannon/internal/schema"
 	"github.com/neilberkman/shannon/internal/search"
+	"github.com/neilberkman/shannon/internal/store"
 	"github.com/spf13/cobra"
 )
 
 var (
 	conversationID string
 	sender         string
+	rating         string
+	model          string
 	startDate      string
 	endDate        string
 	limit          int
 	offset         int
 	sortBy         string
 	sortOrder      string
+	rankMode       string
 	format         string
 	showSnippets   bool
 	showContext    bool
@@ -33,6 +45,18 @@ var (
 	quiet          bool
 	markdown       bool
 	noMarkdown     bool
+	showFacets     bool
+	distinct       string
+	noStem         bool
+	foldDiacritics bool
+	explain        bool
+	printSchema    bool
+	queryFile      string
+	withPrivate    bool
+	assumeYes      bool
+	pick           bool
+	exportDir      string
+	withContext    int
 )
 
 // searchCmd represents the search command
@@ -51,29 +75,115 @@ Query Syntax:
   Wildcard (prefix):  shannon search "data*"
 
 Filters:
-  By sender:          shannon search "api" --sender human
-  By date range:      shannon search "bug" --after 2024-01-01 --before 2024-12-31
-  By date (alt):      shannon search "bug" --start-date 2024-01-01 --end-date 2024-12-31
+  By sender:          shannon search "api from:h" (or --sender human)
+  By rating:          shannon search "docker rating:useful" (or --rating useful)
+  By model:           shannon search "refactor model:opus" (or --model opus)
+  Starred:            shannon search "regex is:starred" (also is:upvoted and
+                      is:downvoted, as given in claude.ai)
+  By date range:      shannon search "bug since:2024-01-01 until:2025-01-01"
+                      (or --after 2024-01-01 --before 2025-01-01)
+  Relative, inline:   shannon search "bug from:h a:2w"
+  By date (alt):      shannon search "bug" --start-date 2024-01-01 --end-date 2025-01-01
+  Relative dates:     shannon search "bug" --after 30d (also 36h, 2w, 3m, 1y,
+                      today, yesterday, @2024, @2024-06, 01.06.2024, 1 Jun 2024)
   Within conversation: shannon search "function" -c 1234
+  Long sessions:      shannon search "migration duration:>2h days:>3"
+                      (how long a conversation ran from first message to
+                      last, and on how many days; >, >=, <, <= or =)
+
+Ranking (when sorting by relevance):
+  --rank relevance    best text match first (BM25)
+  --rank recency      most recently updated conversations first
+  --rank hybrid       text match boosted for recent conversations and for
+                      conversations where many messages match
+
+Matching:
+  By default, queries that look like code (camelCase, snake_case, file.ext,
+  operators) search an index that matches words exactly as written, and other
+  queries search a stemmed index. Override the choice with:
+  --no-stem           match words exactly: "running" doesn't find "runs"
+                      and "café" doesn't find "cafe"
+  --fold-diacritics   search the stemmed index, which ignores accents:
+                      "cafe" finds "café" and "running" finds "runs"
+  code:/text: prefix  shannon search "code: handler" forces the exact index,
+                      shannon search "text: useState" the stemmed one
+  --explain           show how the query was parsed: the FTS query, the index
+                      chosen and why, the filters and the final SQL
+
+Facets:
+  --facets            also count all matches by sender, conversation, month
+                      and artifact type, before limit and offset
+
+Several queries at once:
+  --query-file FILE   run every query in FILE, one per line (blank lines and
+                      lines starting with # are skipped, - reads stdin), and
+                      list each message they find once, with the queries
+                      that found it; --offset applies per query, while
+                      --limit and --distinct apply to the merged list
+
+Private conversations:
+  Conversations made private with 'shannon private set' aren't indexed and
+  never turn up in a search unless asked for:
+  --include-private   also scan private conversations, after confirming
+                      (skip the question with --yes); their matches are
+                      listed after the others, marked [private], on the
+                      first page only
+
+Picking a message:
+  --pick              choose one result in a selector instead of listing
+                      them: enter prints its full message, c copies it to
+                      the clipboard and o opens its conversation. The
+                      selector is drawn on stderr, so the printed message
+                      can be piped: shannon search "nginx" --pick | pbcopy
+
+Research notes:
+  --export-dir DIR    write a Markdown file per matching conversation into
+                      DIR instead of listing the results, with only the
+                      matched messages, marked and with the query's words in
+                      bold, and --with-context messages before and after
+                      each; --limit still caps the matches:
+                      shannon search "kubernetes" --export-dir notes/ --with-context 2
+
+One result per conversation:
+  --distinct conversation  only the best match of each conversation, so
+                      --limit and --offset count conversations
+
+JSON output follows a published schema (see --schema) and carries a
+schema_version field that changes only on breaking changes.
 
 Note: Boolean operators (AND, OR, NOT) are case-insensitive.`,
 
-	Args: cobra.MinimumNArgs(1),
-	RunE: runSearch,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if printSchema {
+			return nil
+		}
+		if queryFile != "" {
+			if len(args) > 0 {
+				return fmt.Errorf("cannot combine --query-file with a query")
+			}
+			return nil
+		}
+		return cobra.MinimumNArgs(1)(cmd, args)
+	},
+	Annotations: map[string]string{store.Annotation: "true"},
+	RunE:        runSearch,
 }
 
 func init() {
 	SearchCmd.Flags().StringVarP(&conversationID, "conversation", "c", "", "search within specific conversation ID")
 	SearchCmd.Flags().StringVarP(&sender, "sender", "s", "", "filter by sender (human/assistant)")
-	SearchCmd.Flags().StringVar(&startDate, "start-date", "", "filter by start date (YYYY-MM-DD)")
-	SearchCmd.Flags().StringVar(&endDate, "end-date", "", "filter by end date (YYYY-MM-DD)")
+	SearchCmd.Flags().StringVar(&model, "model", "", "only answers from models whose name contains this (opus, sonnet, claude-3-5)")
+	SearchCmd.Flags().StringVar(&rating, "rating", "", "only answers rated useful, obsolete or wrong with 'shannon rate'")
+	SearchCmd.Flags().StringVar(&startDate, "start-date", "", "only messages from this date or age on (2024-06-01, 30d, @2024)")
+	SearchCmd.Flags().StringVar(&endDate, "end-date", "", "only messages before this date or age (2024-06-01, 30d, @2024)")
 	// Add shorter aliases
-	SearchCmd.Flags().StringVar(&startDate, "after", "", "filter by start date (alias for --start-date)")
-	SearchCmd.Flags().StringVar(&endDate, "before", "", "filter by end date (alias for --end-date)")
+	SearchCmd.Flags().StringVar(&startDate, "after", "", "only messages from this date or age on (alias for --start-date)")
+	SearchCmd.Flags().StringVar(&endDate, "before", "", "only messages before this date or age (alias for --end-date)")
 	SearchCmd.Flags().IntVarP(&limit, "limit", "l", 50, "maximum number of results")
 	SearchCmd.Flags().IntVar(&offset, "offset", 0, "offset for pagination")
 	SearchCmd.Flags().StringVar(&sortBy, "sort-by", "relevance", "sort by relevance or date")
 	SearchCmd.Flags().StringVar(&sortOrder, "sort-order", "desc", "sort order (asc/desc)")
+	SearchCmd.Flags().StringVar(&rankMode, "rank", "", "relevance ranking: relevance, recency or hybrid (default search.rank)")
 	SearchCmd.Flags().StringVarP(&format, "format", "f", "table", "output format (table/json/csv)")
 	SearchCmd.Flags().BoolVar(&showSnippets, "snippets", true, "show text snippets")
 	SearchCmd.Flags().BoolVar(&showContext, "context", false, "show full message context")
@@ -81,6 +191,19 @@ func init() {
 	SearchCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "suppress extra output (pipe-friendly)")
 	SearchCmd.Flags().BoolVarP(&markdown, "markdown", "m", true, "render markdown formatting in output")
 	SearchCmd.Flags().BoolVar(&noMarkdown, "no-markdown", false, "disable markdown rendering (plain text only)")
+	SearchCmd.Flags().StringVar(&distinct, "distinct", "", "return at most one result per conversation, its best match (conversation)")
+	SearchCmd.Flags().BoolVar(&showFacets, "facets", false, "summarize all matches by sender, conversation, month and artifact type")
+	SearchCmd.Flags().BoolVar(&noStem, "no-stem", false, "match words exactly as written, without stemming or ignoring accents")
+	SearchCmd.Flags().BoolVar(&foldDiacritics, "fold-diacritics", false, "search the stemmed index, which ignores accents")
+	SearchCmd.MarkFlagsMutuallyExclusive("no-stem", "fold-diacritics")
+	SearchCmd.Flags().StringVar(&queryFile, "query-file", "", "run each query in this file, one per line, merging the results (- for stdin)")
+	SearchCmd.Flags().BoolVar(&withPrivate, "include-private", false, "also search private conversations, after confirming")
+	SearchCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "don't ask for confirmation")
+	SearchCmd.Flags().BoolVar(&pick, "pick", false, "pick a result to print, copy or open instead of listing them")
+	SearchCmd.Flags().StringVar(&exportDir, "export-dir", "", "write Markdown notes on each matching conversation to this directory")
+	SearchCmd.Flags().IntVar(&withContext, "with-context", 2, "with --export-dir, messages to include before and after each match")
+	SearchCmd.Flags().BoolVar(&explain, "explain", false, "show how the query is parsed and run")
+	SearchCmd.Flags().BoolVar(&printSchema, "schema", false, "print the JSON Schema of --format json output and exit")
 	// Make no-markdown override markdown
 	SearchCmd.PreRun = func(cmd *cobra.Command, args []string) {
 		if noMarkdown {
@@ -90,15 +213,46 @@ func init() {
 }
 
 func runSearch(cmd *cobra.Command, args []string) error {
-	query := strings.Join(args, " ")
+	if printSchema {
+		return schema.Write(os.Stdout, "search")
+	}
 
-	// Validate query
-	if strings.TrimSpace(query) == "" {
-		return fmt.Errorf("search query cannot be empty")
+	if pick && (format != "table" || showFacets || explain) {
+		return fmt.Errorf("--pick can't be combined with --format, --facets or --explain")
+	}
+
+	if exportDir != "" {
+		if pick || format != "table" || showFacets || explain || queryFile != "" {
+			return fmt.Errorf("--export-dir can't be combined with --pick, --format, --facets, --explain or --query-file")
+		}
+		if withContext < 0 {
+			return fmt.Errorf("--with-context can't be negative")
+		}
+	} else if cmd.Flags().Changed("with-context") {
+		return fmt.Errorf("--with-context only applies with --export-dir")
 	}
 
 	// Get configuration
 	cfg := config.Get()
+	if store.Shared(cfg) && (queryFile != "" || pick || exportDir != "" || showFacets || explain || showContext) {
+		return fmt.Errorf("--query-file, --pick, --export-dir, --facets, --explain and --context aren't supported with the %s store", cfg.Database.Driver)
+	}
+
+	if queryFile != "" {
+		return runQueryFile()
+	}
+
+	// Filters written into the query, as in the TUI's query bar
+	raw := strings.Join(args, " ")
+	parsed, err := parseQuery(raw)
+	if err != nil {
+		return err
+	}
+	q := parsed.Text
+
+	if store.Shared(cfg) {
+		return searchShared(cfg, parsed)
+	}
 
 	// Open database
 	database, err := db.New(cfg.Database.Path)
@@ -113,21 +267,159 @@ func runSearch(cmd *cobra.Command, args []string) error {
 
 	// Create search engine
 	engine := search.NewEngine(database)
+	engine.SetDictionary(search.NewDictionary(cfg.Search.Stopwords, cfg.Search.Synonyms))
+
+	if showFacets && format == "csv" {
+		return fmt.Errorf("--facets is not supported with csv output")
+	}
+	opts, err := flagOptions(cfg)
+	if err != nil {
+		return err
+	}
+	opts.Query = q
+
+	// Filters in the query take precedence over the flags
+	parsed.Apply(&opts)
+
+	var explanation *search.Explanation
+	if explain {
+		if format == "csv" {
+			return fmt.Errorf("--explain is not supported with csv output")
+		}
+		explanation = engine.Explain(opts)
+		if format != "json" {
+			if err := outputExplanation(explanation); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Perform search
+	started := time.Now()
+	results, err := engine.Search(opts)
+	if err != nil {
+		return fmt.Errorf("search failed: %w", err)
+	}
+	if err := engine.LogSearch(raw, time.Since(started)); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	var facets *search.Facets
+	if showFacets {
+		if facets, err = engine.Facets(opts); err != nil {
+			return fmt.Errorf("failed to count facets: %w", err)
+		}
+	}
+
+	if exportDir != "" {
+		return exportNotes(engine, results, raw, exportDir, withContext, rendering.NewHighlighter(search.QueryTerms(q)...))
+	}
+
+	if pick {
+		if len(results) == 0 {
+			fmt.Fprintln(os.Stderr, i18n.T("No results found."))
+			return nil
+		}
+		return tui.RunPicker(engine, results, raw)
+	}
+
+	// Display results
+	switch format {
+	case "json":
+		return outputJSON(results, facets, explanation)
+	case "csv":
+		return outputCSV(results, false)
+	default:
+		highlighter := rendering.NewHighlighter(search.QueryTerms(q)...)
+		if err := outputTable(results, false, showSnippets, showContext, contextLines, database, quiet, highlighter); err != nil {
+			return err
+		}
+		if facets != nil && facets.Total > 0 {
+			return outputFacets(facets)
+		}
+		return nil
+	}
+}
+
+// searchShared searches a shared store, which has no search history, facets
+// or stemming options of its own; its matches print as local ones do
+func searchShared(cfg *config.Config, parsed *query.Query) error {
+	opts, err := flagOptions(cfg)
+	if err != nil {
+		return err
+	}
+	opts.Query = parsed.Text
+	parsed.Apply(&opts)
+
+	s, err := store.Open(cfg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+		}
+	}()
+
+	results, err := s.Search(opts)
+	if err != nil {
+		return fmt.Errorf("search failed: %w", err)
+	}
+
+	switch format {
+	case "json":
+		return outputJSON(results, nil, nil)
+	case "csv":
+		return outputCSV(results, false)
+	default:
+		highlighter := rendering.NewHighlighter(search.QueryTerms(parsed.Text)...)
+		return outputTable(results, false, showSnippets, false, 0, nil, quiet, highlighter)
+	}
+}
+
+// parseQuery reads the filters written into a query, as in the TUI's query
+// bar, making sure there are words left to search for
+func parseQuery(raw string) (*query.Query, error) {
+	parsed, err := query.Parse(raw, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(parsed.Text) == "" {
+		if parsed.HasFilters() {
+			return nil, fmt.Errorf("search query needs words to search for besides its filters")
+		}
+		return nil, fmt.Errorf("search query cannot be empty")
+	}
+	return parsed, nil
+}
+
+// flagOptions returns the search options set by the flags, for every query
+func flagOptions(cfg *config.Config) (search.SearchOptions, error) {
+	if rankMode == "" {
+		rankMode = cfg.Search.Rank
+	}
+	if !slices.Contains(search.RankModes, rankMode) {
+		return search.SearchOptions{}, fmt.Errorf("invalid rank %q: use %s", rankMode, strings.Join(search.RankModes, ", "))
+	}
 
-	// Build search options
 	opts := search.SearchOptions{
-		Query:     query,
 		Limit:     limit,
 		Offset:    offset,
 		SortBy:    sortBy,
 		SortOrder: sortOrder,
+		Rank:      rankMode,
+	}
+	if noStem {
+		opts.Index = search.IndexCode
+	} else if foldDiacritics {
+		opts.Index = search.IndexText
 	}
 
 	// Parse optional filters
 	if conversationID != "" {
 		var id int64
 		if _, err := fmt.Sscanf(conversationID, "%d", &id); err != nil {
-			return fmt.Errorf("invalid conversation ID: %w", err)
+			return opts, fmt.Errorf("invalid conversation ID: %w", err)
 		}
 		opts.ConversationID = &id
 	}
@@ -136,43 +428,67 @@ func runSearch(cmd *cobra.Command, args []string) error {
 		opts.Sender = sender
 	}
 
+	if model != "" {
+		opts.Model = model
+	}
+
+	if rating != "" {
+		if err := search.ValidateRating(rating); err != nil {
+			return opts, err
+		}
+		opts.Rating = rating
+	}
+
+	if distinct != "" {
+		if distinct != search.DistinctConversation {
+			return opts, fmt.Errorf("invalid --distinct %q (must be %s)", distinct, search.DistinctConversation)
+		}
+		opts.Distinct = distinct
+	}
+
 	if startDate != "" {
-		t, err := time.Parse("2006-01-02", startDate)
+		t, err := dates.Parse(startDate, time.Now())
 		if err != nil {
-			return fmt.Errorf("invalid start date: %w", err)
+			return opts, fmt.Errorf("invalid start date: %w", err)
 		}
 		opts.StartDate = &t
 	}
 
 	if endDate != "" {
-		t, err := time.Parse("2006-01-02", endDate)
+		t, err := dates.Parse(endDate, time.Now())
 		if err != nil {
-			return fmt.Errorf("invalid end date: %w", err)
+			return opts, fmt.Errorf("invalid end date: %w", err)
 		}
 		opts.EndDate = &t
 	}
 
-	// Perform search
-	results, err := engine.Search(opts)
-	if err != nil {
-		return fmt.Errorf("search failed: %w", err)
+	if withPrivate {
+		if !confirm("Include private conversations in the results?") {
+			return opts, fmt.Errorf("private conversations not included: aborted")
+		}
+		opts.IncludePrivate = true
 	}
 
-	// Display results
-	switch format {
-	case "json":
-		return outputJSON(results)
-	case "csv":
-		return outputCSV(results)
-	default:
-		return outputTable(results, showSnippets, showContext, contextLines, database, quiet)
+	return opts, nil
+}
+
+// confirm asks before continuing, on stderr so piped output stays clean
+func confirm(question string) bool {
+	if assumeYes {
+		return true
 	}
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
 }
 
-func outputTable(results []*models.SearchResult, showSnippets bool, showContext bool, contextLines int, database *db.DB, quiet bool) error {
+// outputTable prints results as a table, with the queries that found each
+// when byQuery is set
+func outputTable(results []*models.SearchResult, byQuery bool, showSnippets bool, showContext bool, contextLines int, database *db.DB, quiet bool, highlighter *rendering.Highlighter) error {
 	if len(results) == 0 {
 		if !quiet {
-			fmt.Println("No results found.")
+			fmt.Println(i18n.T("No results found."))
 		}
 		return nil
 	}
@@ -180,26 +496,33 @@ func outputTable(results []*models.SearchResult, showSnippets bool, showContext
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 
 	// Header
+	columns := []string{"ID", "Date", "Conversation", "Sender"}
+	if byQuery {
+		columns = append(columns, "Queries")
+	}
 	if showSnippets {
-		if _, err := fmt.Fprintln(w, "ID\tDate\tConversation\tSender\tSnippet"); err != nil {
-			return fmt.Errorf("failed to write header: %w", err)
-		}
-		if _, err := fmt.Fprintln(w, "--\t----\t------------\t------\t-------"); err != nil {
-			return fmt.Errorf("failed to write separator: %w", err)
-		}
+		columns = append(columns, "Snippet")
 	} else {
-		if _, err := fmt.Fprintln(w, "ID\tDate\tConversation\tSender\tMessage ID"); err != nil {
-			return fmt.Errorf("failed to write header: %w", err)
-		}
-		if _, err := fmt.Fprintln(w, "--\t----\t------------\t------\t----------"); err != nil {
-			return fmt.Errorf("failed to write separator: %w", err)
-		}
+		columns = append(columns, "Message ID")
+	}
+	header, separator := i18n.Columns(columns...)
+	if _, err := fmt.Fprintln(w, header); err != nil {
+		return fmt.Errorf("failed to write header: %w", err)
+	}
+	if _, err := fmt.Fprintln(w, separator); err != nil {
+		return fmt.Errorf("failed to write separator: %w", err)
 	}
 
 	// Results
 	for _, r := range results {
 		date := r.CreatedAt.Format("2006-01-02 15:04")
 		convName := truncate(r.ConversationName, 50)
+		if r.Branch != "" {
+			convName += " [" + r.Branch + "]"
+		}
+		if r.Private {
+			convName += " [private]"
+		}
 
 		// Create clickable conversation ID if hyperlinks are supported
 		convIDDisplay := fmt.Sprintf("%d", r.ConversationID)
@@ -208,6 +531,11 @@ func outputTable(results []*models.SearchResult, showSnippets bool, showContext
 			convIDDisplay = rendering.MakeHyperlinkWithID(convIDDisplay, fmt.Sprintf("shannon://view/%d", r.ConversationID), fmt.Sprintf("conv-%d", r.ConversationID))
 		}
 
+		sender := r.Sender
+		if byQuery {
+			sender += "\t" + truncate(strings.Join(r.Queries, ", "), 30)
+		}
+
 		if showSnippets {
 			snippet := r.Snippet
 
@@ -230,7 +558,7 @@ func outputTable(results []*models.SearchResult, showSnippets bool, showContext
 			// Clean up for tabular display
 			snippet = strings.ReplaceAll(snippet, "\n", " ")
 			snippet = truncate(snippet, 60)
-			if _, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", convIDDisplay, date, convName, r.Sender, snippet); err != nil {
+			if _, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", convIDDisplay, date, convName, sender, snippet); err != nil {
 				return fmt.Errorf("failed to write result row: %w", err)
 			}
 		} else {
@@ -239,7 +567,7 @@ func outputTable(results []*models.SearchResult, showSnippets bool, showContext
 				// Create a link to view the specific message
 				messageUUID = rendering.MakeHyperlinkWithID(messageUUID, fmt.Sprintf("shannon://message/%s", r.MessageUUID), fmt.Sprintf("msg-%s", r.MessageUUID[:8]))
 			}
-			if _, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", convIDDisplay, date, convName, r.Sender, messageUUID); err != nil {
+			if _, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", convIDDisplay, date, convName, sender, messageUUID); err != nil {
 				return fmt.Errorf("failed to write result row: %w", err)
 			}
 		}
@@ -250,20 +578,26 @@ func outputTable(results []*models.SearchResult, showSnippets bool, showContext
 	}
 
 	if !quiet {
-		fmt.Printf("\nFound %d results", len(results))
+		fmt.Print("\n" + i18n.Tf("Found %d results", len(results)))
 		if len(results) == limit {
-			fmt.Printf(" (showing first %d)", limit)
+			fmt.Print(i18n.Tf(" (showing first %d)", limit))
 		}
 		fmt.Println()
+		for _, r := range results {
+			if r.Branch != "" {
+				fmt.Println(i18n.Tf("Results marked [name] are on a branch other than main; see one in its thread with `shannon view %d --message %s`", r.ConversationID, r.MessageUUID[:8]))
+				break
+			}
+		}
 	}
 
 	// Show context if requested
 	if showContext && database != nil {
 		if !quiet {
-			fmt.Println("\n--- Message Context ---")
+			fmt.Println("\n" + i18n.T("--- Message Context ---"))
 		}
 		for _, r := range results {
-			if err := showMessageContext(database, r, contextLines); err != nil {
+			if err := showMessageContext(database, r, contextLines, highlighter); err != nil {
 				if !quiet {
 					fmt.Fprintf(os.Stderr, "Error showing context for message %s: %v\n", r.MessageUUID, err)
 				}
@@ -274,10 +608,17 @@ func outputTable(results []*models.SearchResult, showSnippets bool, showContext
 	return nil
 }
 
-func outputJSON(results []*models.SearchResult) error {
+func outputJSON(results []*models.SearchResult, facets *search.Facets, explanation *search.Explanation) error {
 	output := map[string]interface{}{
-		"results": results,
-		"count":   len(results),
+		schema.Field: schema.Version,
+		"results":    results,
+		"count":      len(results),
+	}
+	if facets != nil {
+		output["facets"] = facets
+	}
+	if explanation != nil {
+		output["explain"] = explanation
 	}
 
 	encoder := json.NewEncoder(os.Stdout)
@@ -285,11 +626,100 @@ func outputJSON(results []*models.SearchResult) error {
 	return encoder.Encode(output)
 }
 
-func outputCSV(results []*models.SearchResult) error {
+// outputExplanation prints how the query is parsed and run
+func outputExplanation(e *search.Explanation) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	filters := "none"
+	if len(e.Filters) > 0 {
+		filters = strings.Join(e.Filters, " AND ")
+	}
+	for _, row := range [][2]string{
+		{"Query:", e.Query},
+		{"FTS query:", e.FTSQuery},
+		{"Index:", fmt.Sprintf("%s (%s), %s", e.Index, e.Table, e.IndexReason)},
+		{"Filters:", filters},
+	} {
+		if _, err := fmt.Fprintf(w, "%s\t%s\n", row[0], row[1]); err != nil {
+			return fmt.Errorf("failed to write explanation: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write explanation: %w", err)
+	}
+
+	fmt.Printf("SQL:\n%s\n", e.SQL)
+	args := make([]string, len(e.Args))
+	for i, arg := range e.Args {
+		args[i] = fmt.Sprintf("$%d = %#v", i+1, arg)
+	}
+	fmt.Printf("Arguments: %s\n\n", strings.Join(args, ", "))
+	return nil
+}
+
+// maxConversationFacets caps the conversations listed in the facet summary
+const maxConversationFacets = 10
+
+// outputFacets prints how all matches are distributed
+func outputFacets(facets *search.Facets) error {
+	fmt.Printf("\n--- Facets (%d matching messages) ---\n", facets.Total)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	section := func(title string, counts []search.FacetCount, limit int, label func(search.FacetCount) string) error {
+		if len(counts) == 0 {
+			return nil
+		}
+		if _, err := fmt.Fprintf(w, "\n%s\n", title); err != nil {
+			return fmt.Errorf("failed to write facets: %w", err)
+		}
+		for i, c := range counts {
+			if limit > 0 && i == limit {
+				if _, err := fmt.Fprintf(w, "  ... and %d more\n", len(counts)-i); err != nil {
+					return fmt.Errorf("failed to write facets: %w", err)
+				}
+				break
+			}
+			if _, err := fmt.Fprintf(w, "  %s\t%d\n", label(c), c.Count); err != nil {
+				return fmt.Errorf("failed to write facets: %w", err)
+			}
+		}
+		return nil
+	}
+
+	value := func(c search.FacetCount) string { return c.Value }
+	if err := section("By sender:", facets.BySender, 0, func(c search.FacetCount) string {
+		return rendering.FormatSender(c.Value)
+	}); err != nil {
+		return err
+	}
+	if err := section("By conversation:", facets.ByConversation, maxConversationFacets, func(c search.FacetCount) string {
+		return fmt.Sprintf("[%d] %s", c.ID, truncate(c.Value, 50))
+	}); err != nil {
+		return err
+	}
+	if err := section("By month:", facets.ByMonth, 0, value); err != nil {
+		return err
+	}
+	if err := section("By artifact type:", facets.ByArtifactType, 0, value); err != nil {
+		return err
+	}
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to flush output: %w", err)
+	}
+	return nil
+}
+
+// outputCSV writes results as CSV, with a queries column listing the queries
+// that found each when byQuery is set
+func outputCSV(results []*models.SearchResult, byQuery bool) error {
 	w := csv.NewWriter(os.Stdout)
 
 	// Header
-	if err := w.Write([]string{"conversation_id", "conversation_name", "message_uuid", "sender", "created_at", "snippet"}); err != nil {
+	header := []string{"conversation_id", "conversation_name", "message_uuid", "sender", "created_at", "snippet", "branch"}
+	if byQuery {
+		header = append(header, "queries")
+	}
+	if err := w.Write(header); err != nil {
 		return err
 	}
 
@@ -302,6 +732,10 @@ func outputCSV(results []*models.SearchResult) error {
 			r.Sender,
 			r.CreatedAt.Format("2006-01-02 15:04:05"),
 			strings.ReplaceAll(r.Snippet, "\n", " "),
+			r.Branch,
+		}
+		if byQuery {
+			record = append(record, strings.Join(r.Queries, " | "))
 		}
 		if err := w.Write(record); err != nil {
 			return err
@@ -319,10 +753,27 @@ func truncate(s string, maxLen int) string {
 	return s[:maxLen-3] + "..."
 }
 
-func showMessageContext(database *db.DB, result *models.SearchResult, contextLines int) error {
+// fromMatch drops the start of text if its first match would otherwise be
+// cut off at maxLen, keeping some text before the match
+func fromMatch(text string, highlighter *rendering.Highlighter, maxLen int) string {
+	match := highlighter.Index(text)
+	if match == nil || match[1] <= maxLen-3 {
+		return text
+	}
+	start := match[0] - maxLen/4
+	if start <= 0 {
+		return text
+	}
+	for start < len(text) && !utf8.RuneStart(text[start]) {
+		start++
+	}
+	return "..." + text[start:]
+}
+
+func showMessageContext(database *db.DB, result *models.SearchResult, contextLines int, highlighter *rendering.Highlighter) error {
 	// Get messages before and after the found message
 	query := `
-		SELECT m.id, m.uuid, m.text, m.sender, m.created_at
+		SELECT m.id, m.uuid, message_text(m.text), m.sender, m.created_at
 		FROM messages m
 		WHERE m.conversation_id = ?
 		ORDER BY m.created_at
@@ -372,7 +823,11 @@ func showMessageContext(database *db.DB, result *models.SearchResult, contextLin
 	}
 
 	// Display context
-	fmt.Printf("\n[Conversation %d: %s]\n", result.ConversationID, result.ConversationName)
+	if result.Branch != "" {
+		fmt.Printf("\n[Conversation %d: %s, branch %s]\n", result.ConversationID, result.ConversationName, result.Branch)
+	} else {
+		fmt.Printf("\n[Conversation %d: %s]\n", result.ConversationID, result.ConversationName)
+	}
 	fmt.Println(strings.Repeat("-", 80))
 
 	// Calculate range
@@ -396,9 +851,11 @@ func showMessageContext(database *db.DB, result *models.SearchResult, contextLin
 		timestamp := msg.CreatedAt[:16] // Just date and time
 		sender := rendering.FormatSender(msg.Sender)
 
-		// Apply markdown rendering if enabled
+		// Apply markdown rendering if enabled, unless the query is highlighted
+		// instead: the styles of both don't survive flattening to one line
 		text := msg.Text
-		if markdown {
+		rendered := markdown && highlighter == nil
+		if rendered {
 			renderer, err := rendering.NewMarkdownRenderer(100)
 			if err == nil {
 				rendered, err := renderer.RenderMessage(msg.Text, msg.Sender, false)
@@ -408,9 +865,13 @@ func showMessageContext(database *db.DB, result *models.SearchResult, contextLin
 			}
 		}
 
-		// Clean up for display
+		// Clean up for display, starting near the first match so it shows
 		text = strings.ReplaceAll(text, "\n", " ")
-		text = truncate(text, 100)
+		if rendered {
+			text = truncate(text, 100)
+		} else {
+			text = highlighter.Highlight(truncate(fromMatch(text, highlighter, 100), 100))
+		}
 
 		if i == targetIndex {
 			fmt.Printf("%s[%s] %s: %s\n", prefix, timestamp, sender, text)
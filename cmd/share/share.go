@@ -0,0 +1,91 @@
+package share
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/neilberkman/shannon/internal/config"
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/export"
+	"github.com/neilberkman/shannon/internal/search"
+	"github.com/spf13/cobra"
+)
+
+var (
+	outputFile  string
+	messageUUID string
+)
+
+// NewCmd creates the share command
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "share [conversation]",
+		Short: "Save a conversation as a single HTML page to send to anyone",
+		Long: `Save a conversation as one self-contained HTML file that opens in any browser,
+for sending to someone who doesn't use shannon. The page needs no network
+connection: it has a search box that filters and highlights the messages,
+messages can be collapsed, and each artifact has a link that downloads it.
+
+Without --output the file is named after the conversation.
+
+Examples:
+  shannon share 123 -o chat.html
+  shannon share python-pandas-cleanup-2024-05
+  shannon share 123 --message 4f9a2c1e -o branch.html`,
+		Args: cobra.ExactArgs(1),
+		RunE: runShare,
+	}
+
+	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "file to write the page to")
+	cmd.Flags().StringVar(&messageUUID, "message", "", "share the thread through the message with this UUID, even on a branch other than main")
+
+	return cmd
+}
+
+func runShare(cmd *cobra.Command, args []string) error {
+	cfg := config.Get()
+	database, err := db.New(cfg.Database.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer func() {
+		if err := database.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+		}
+	}()
+
+	engine := search.NewEngine(database)
+
+	// Accept a slug or alias in place of the ID
+	convID, err := engine.ResolveConversation(args[0])
+	if err != nil {
+		return err
+	}
+
+	conv, messages, err := engine.GetConversation(convID)
+	if err == nil && messageUUID != "" {
+		var messageID int64
+		if messageID, err = engine.FindMessage(convID, messageUUID); err != nil {
+			return err
+		}
+		conv, messages, err = engine.GetThread(convID, messageID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get conversation: %w", err)
+	}
+
+	filename := outputFile
+	if filename == "" {
+		filename = export.DefaultFilename(conv, "html")
+	}
+	if err := os.WriteFile(filename, export.Share(conv, messages), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	fmt.Printf("Conversation shared as: %s\n", filename)
+
+	// Counts towards `shannon stats --usage`
+	if err := engine.LogAccess(convID, search.AccessExport); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	return nil
+}
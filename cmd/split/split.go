@@ -0,0 +1,118 @@
+package split
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/neilberkman/shannon/internal/config"
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/search"
+	"github.com/neilberkman/shannon/internal/split"
+	"github.com/spf13/cobra"
+)
+
+var (
+	splitAt []string
+	format  string
+)
+
+// SplitCmd represents the split command
+var SplitCmd = &cobra.Command{
+	Use:   "split [conversation]",
+	Short: "Split a conversation into separate conversations by topic",
+	Long: `Split a conversation that wandered between topics into separate local
+conversations. The conversation is cut before each message given with --at,
+by UUID or by its number in 'shannon view'; every part after the first becomes
+a new conversation that links back to the original, so searches and exports
+only bring up the part they're about.
+
+Moved messages aren't imported into the original again. Messages added to it
+in claude.ai later are imported into the original. Splits can also be made in
+the TUI with 'p' while viewing a conversation.
+
+Examples:
+  shannon split 123 --at 9
+  shannon split 123 --at 1f6e0b0c-8e8a-4c1e-9d2f-5b7a3c9e2d41
+  shannon split python-pandas-cleanup-2024-05 --at 9 --at 17`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSplit,
+}
+
+func init() {
+	SplitCmd.Flags().StringArrayVar(&splitAt, "at", nil, "start a new conversation at this message (UUID or number; repeatable)")
+	SplitCmd.Flags().StringVarP(&format, "format", "f", "table", "output format (table, json)")
+	_ = SplitCmd.MarkFlagRequired("at")
+}
+
+func runSplit(cmd *cobra.Command, args []string) error {
+	if format != "table" && format != "json" {
+		return fmt.Errorf("unknown format %q (use table or json)", format)
+	}
+
+	cfg := config.Get()
+	database, err := db.New(cfg.Database.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer func() {
+		if err := database.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+		}
+	}()
+
+	engine := search.NewEngine(database)
+	convID, err := engine.ResolveConversation(args[0])
+	if err != nil {
+		return err
+	}
+
+	at, err := messageUUIDs(engine, convID, splitAt)
+	if err != nil {
+		return err
+	}
+
+	parts, err := split.Split(database, convID, at)
+	if err != nil {
+		return fmt.Errorf("failed to split conversation: %w", err)
+	}
+
+	if format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(parts)
+	}
+
+	fmt.Printf("Split %d new conversation(s) off conversation %d:\n\n", len(parts), convID)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ID\tMESSAGES\tNAME")
+	for _, part := range parts {
+		_, _ = fmt.Fprintf(w, "%d\t%d\t%s\n", part.ID, part.Messages, part.Name)
+	}
+	return w.Flush()
+}
+
+// messageUUIDs turns the messages given with --at into UUIDs. A number is
+// the message's place in the conversation as 'shannon view' numbers it.
+func messageUUIDs(engine *search.Engine, convID int64, refs []string) ([]string, error) {
+	_, messages, err := engine.GetConversation(convID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation: %w", err)
+	}
+
+	uuids := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		n, err := strconv.Atoi(ref)
+		if err != nil {
+			uuids = append(uuids, ref)
+			continue
+		}
+		if n < 1 || n > len(messages) {
+			return nil, fmt.Errorf("conversation %d has no message %d (it has %d)", convID, n, len(messages))
+		}
+		uuids = append(uuids, messages[n-1].UUID)
+	}
+	return uuids, nil
+}
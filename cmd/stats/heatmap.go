@@ -0,0 +1,197 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"time"
+)
+
+// heatmap lays out daily message counts like GitHub's contribution graph: a
+// column per week, Sunday at the top
+type heatmap struct {
+	from   time.Time // first day shown
+	to     time.Time // day after the last day shown
+	start  time.Time // Sunday of the first week
+	counts map[string]int
+	max    int
+}
+
+// blockLevels are the cells of the text heatmap, from no messages to the most
+var blockLevels = []string{"·", "░", "▒", "▓", "█"}
+
+// kittyLevels are the cell colors of the image heatmap, GitHub's dark palette
+var kittyLevels = []color.RGBA{
+	{0x16, 0x1b, 0x22, 0xff},
+	{0x0e, 0x44, 0x29, 0xff},
+	{0x00, 0x6d, 0x32, 0xff},
+	{0x26, 0xa6, 0x41, 0xff},
+	{0x39, 0xd3, 0x53, 0xff},
+}
+
+// Image cell size and gap in pixels
+const (
+	kittyCell = 10
+	kittyGap  = 3
+)
+
+func newHeatmap(from, to time.Time, counts map[string]int) *heatmap {
+	h := &heatmap{
+		from:   from,
+		to:     to,
+		start:  from.AddDate(0, 0, -int(from.Weekday())),
+		counts: counts,
+	}
+	for _, count := range counts {
+		h.max = max(h.max, count)
+	}
+	return h
+}
+
+// weeks returns the number of columns
+func (h *heatmap) weeks() int {
+	days := int(h.to.Sub(h.start).Hours()/24 + 0.5)
+	return (days + 6) / 7
+}
+
+// day returns the date of a cell
+func (h *heatmap) day(week, weekday int) time.Time {
+	return h.start.AddDate(0, 0, week*7+weekday)
+}
+
+// level returns the intensity of a day from 0 to 4, or -1 outside the range
+func (h *heatmap) level(day time.Time) int {
+	if day.Before(h.from) || !day.Before(h.to) {
+		return -1
+	}
+	count := h.counts[day.Format("2006-01-02")]
+	if count == 0 || h.max == 0 {
+		return 0
+	}
+	// Scale to 1-4 so the busiest day is always 4
+	return (count*4 + h.max - 1) / h.max
+}
+
+// renderBlocks draws the heatmap with unicode block characters, labeled with
+// months along the top and weekdays down the side
+func (h *heatmap) renderBlocks() string {
+	const labelWidth = 4
+	weeks := h.weeks()
+	var sb strings.Builder
+
+	months := []rune(strings.Repeat(" ", labelWidth+weeks+3))
+	next := 0
+	for week := 0; week < weeks; week++ {
+		// Label the week the month starts in, if the last label has room
+		for weekday := 0; weekday < 7; weekday++ {
+			day := h.day(week, weekday)
+			if day.Day() != 1 || h.level(day) < 0 {
+				continue
+			}
+			if col := labelWidth + week; col >= next {
+				copy(months[col:], []rune(day.Format("Jan")))
+				next = col + 4
+			}
+		}
+	}
+	sb.WriteString(strings.TrimRight(string(months), " ") + "\n")
+
+	for weekday := 0; weekday < 7; weekday++ {
+		label := ""
+		switch time.Weekday(weekday) {
+		case time.Monday, time.Wednesday, time.Friday:
+			label = time.Weekday(weekday).String()[:3]
+		}
+		sb.WriteString(fmt.Sprintf("%-*s", labelWidth, label))
+
+		var row strings.Builder
+		for week := 0; week < weeks; week++ {
+			if level := h.level(h.day(week, weekday)); level >= 0 {
+				row.WriteString(blockLevels[level])
+			} else {
+				row.WriteString(" ")
+			}
+		}
+		sb.WriteString(strings.TrimRight(row.String(), " ") + "\n")
+	}
+
+	sb.WriteString(fmt.Sprintf("\n%*sLess %s More\n", labelWidth, "", strings.Join(blockLevels, "")))
+	return sb.String()
+}
+
+// renderKitty draws the heatmap as an image and returns the escape codes that
+// display it in terminals speaking the Kitty graphics protocol
+func (h *heatmap) renderKitty() (string, error) {
+	weeks := h.weeks()
+	img := image.NewRGBA(image.Rect(0, 0, weeks*(kittyCell+kittyGap)-kittyGap, 7*(kittyCell+kittyGap)-kittyGap))
+	for week := 0; week < weeks; week++ {
+		for weekday := 0; weekday < 7; weekday++ {
+			level := h.level(h.day(week, weekday))
+			if level < 0 {
+				continue
+			}
+			x0, y0 := week*(kittyCell+kittyGap), weekday*(kittyCell+kittyGap)
+			for x := x0; x < x0+kittyCell; x++ {
+				for y := y0; y < y0+kittyCell; y++ {
+					img.SetRGBA(x, y, kittyLevels[level])
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", fmt.Errorf("failed to encode heatmap: %w", err)
+	}
+	return kittyImage(buf.Bytes()), nil
+}
+
+// kittyImage wraps a PNG in Kitty graphics escape codes, split into the
+// 4096-byte chunks the protocol allows
+func kittyImage(data []byte) string {
+	const chunkSize = 4096
+	encoded := base64.StdEncoding.EncodeToString(data)
+
+	var sb strings.Builder
+	for i := 0; i < len(encoded); i += chunkSize {
+		end := min(i+chunkSize, len(encoded))
+		more := 1
+		if end == len(encoded) {
+			more = 0
+		}
+		if i == 0 {
+			sb.WriteString(fmt.Sprintf("\x1b_Ga=T,f=100,m=%d;%s\x1b\\", more, encoded[i:end]))
+		} else {
+			sb.WriteString(fmt.Sprintf("\x1b_Gm=%d;%s\x1b\\", more, encoded[i:end]))
+		}
+	}
+	return sb.String() + "\n"
+}
+
+// summary describes the total and the busiest day
+func (h *heatmap) summary() string {
+	total, active := 0, 0
+	busiest := ""
+	for day, count := range h.counts {
+		total += count
+		if count > 0 {
+			active++
+		}
+		if count > h.counts[busiest] || (count == h.counts[busiest] && day < busiest) {
+			busiest = day
+		}
+	}
+	if total == 0 {
+		return "No messages in this period"
+	}
+
+	s := fmt.Sprintf("%d messages on %d days", total, active)
+	if day, err := time.Parse("2006-01-02", busiest); err == nil {
+		s += fmt.Sprintf(", busiest %s (%d)", day.Format("Mon Jan 2, 2006"), h.counts[busiest])
+	}
+	return s
+}
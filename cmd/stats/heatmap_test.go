@@ -0,0 +1,71 @@
+package stats
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHeatmapRange(t *testing.T) {
+	now := time.Date(2025, 3, 12, 15, 0, 0, 0, time.UTC) // a Wednesday
+
+	from, to := heatmapRange(0, now)
+	if from.Weekday() != time.Sunday || from.Format("2006-01-02") != "2024-03-10" || to.Format("2006-01-02") != "2025-03-13" {
+		t.Errorf("expected 52 weeks plus this one, got %s to %s", from, to)
+	}
+
+	from, to = heatmapRange(2024, now)
+	if from.Format("2006-01-02") != "2024-01-01" || to.Format("2006-01-02") != "2025-01-01" {
+		t.Errorf("expected 2024, got %s to %s", from, to)
+	}
+
+	// The current year stops at today
+	if _, to = heatmapRange(2025, now); to.Format("2006-01-02") != "2025-03-13" {
+		t.Errorf("expected the current year to end today, got %s", to)
+	}
+}
+
+func TestHeatmapBlocks(t *testing.T) {
+	// Thursday January 30 to Wednesday February 12
+	from := time.Date(2025, 1, 30, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 2, 13, 0, 0, 0, 0, time.UTC)
+	h := newHeatmap(from, to, map[string]int{
+		"2025-01-30": 1,
+		"2025-02-03": 8,
+		"2025-02-04": 4,
+		"2025-02-12": 2,
+	})
+
+	if h.weeks() != 3 {
+		t.Errorf("expected 3 weeks, got %d", h.weeks())
+	}
+
+	want := strings.Join([]string{
+		"    Feb",
+		"     ··",
+		"Mon  █·",
+		"     ▒·",
+		"Wed  ·░",
+		"    ░·",
+		"Fri ··",
+		"    ··",
+		"",
+		"    Less ·░▒▓█ More",
+		"",
+	}, "\n")
+	if got := h.renderBlocks(); got != want {
+		t.Errorf("unexpected heatmap:\n%s\nwant:\n%s", got, want)
+	}
+
+	if got := h.summary(); got != "15 messages on 4 days, busiest Mon Feb 3, 2025 (8)" {
+		t.Errorf("unexpected summary %q", got)
+	}
+}
+
+func TestKittyImage(t *testing.T) {
+	data := []byte(strings.Repeat("x", 4000)) // 5336 bytes of base64
+	got := kittyImage(data)
+	if !strings.HasPrefix(got, "\x1b_Ga=T,f=100,m=1;") || !strings.Contains(got, "\x1b\\\x1b_Gm=0;") || !strings.HasSuffix(got, "\x1b\\\n") {
+		t.Errorf("expected the image in two chunks, got %q", got[:40])
+	}
+}
@@ -0,0 +1,99 @@
+package stats
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/neilberkman/shannon/internal/search"
+)
+
+// metricsWriter writes metric families in the OpenMetrics text format,
+// keeping the first write error
+type metricsWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (mw *metricsWriter) printf(format string, args ...interface{}) {
+	if mw.err == nil {
+		_, mw.err = fmt.Fprintf(mw.w, format, args...)
+	}
+}
+
+// family writes the metadata lines of a metric family
+func (mw *metricsWriter) family(name, kind, unit, help string) {
+	mw.printf("# TYPE %s %s\n", name, kind)
+	if unit != "" {
+		mw.printf("# UNIT %s %s\n", name, unit)
+	}
+	mw.printf("# HELP %s %s\n", name, help)
+}
+
+// gauge writes a gauge with a single sample
+func (mw *metricsWriter) gauge(name, unit, help string, value float64) {
+	mw.family(name, "gauge", unit, help)
+	mw.printf("%s %s\n", name, formatValue(value))
+}
+
+// labeled writes a gauge or counter with a sample per label value, in order
+func (mw *metricsWriter) labeled(name, kind, help, label string, values map[string]int) {
+	mw.family(name, kind, "", help)
+	sample := name
+	if kind == "counter" {
+		sample += "_total"
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		mw.printf("%s{%s=%q} %d\n", sample, label, key, values[key])
+	}
+}
+
+// writeOpenMetrics writes the archive totals, import counters and search
+// latency histogram for Prometheus to scrape or a textfile collector to pick up
+func writeOpenMetrics(out io.Writer, m *search.Metrics) error {
+	mw := &metricsWriter{w: bufio.NewWriter(out)}
+
+	mw.gauge("shannon_conversations", "", "Conversations in the archive.", float64(m.Conversations))
+	mw.labeled("shannon_messages", "gauge", "Messages in the archive by sender.", "sender", m.Messages)
+	mw.gauge("shannon_branches", "", "Conversation branches in the archive.", float64(m.Branches))
+	mw.labeled("shannon_code_blocks", "gauge", "Indexed code blocks and artifacts by kind.", "kind", m.CodeBlocks)
+	mw.labeled("shannon_rated_messages", "gauge", "Rated answers by rating.", "rating", m.Ratings)
+	mw.gauge("shannon_database_size_bytes", "bytes", "Size of the database file.", float64(m.DatabaseBytes))
+
+	mw.labeled("shannon_imports", "counter", "Imports run by outcome.", "status", m.Imports)
+	mw.family("shannon_imported_conversations", "counter", "", "Conversations added by imports.")
+	mw.printf("shannon_imported_conversations_total %d\n", m.ImportedConversations)
+	mw.family("shannon_imported_messages", "counter", "", "Messages added by imports.")
+	mw.printf("shannon_imported_messages_total %d\n", m.ImportedMessages)
+	if !m.LastImport.IsZero() {
+		mw.gauge("shannon_last_import_timestamp_seconds", "seconds", "When the last successful import ran.", float64(m.LastImport.Unix()))
+	}
+
+	mw.labeled("shannon_accesses", "counter", "Conversations viewed and exported and searches run.", "action", m.Accesses)
+
+	h := m.SearchLatency
+	mw.family("shannon_search_duration_seconds", "histogram", "seconds", "How long searches took.")
+	for i, bound := range h.Buckets {
+		mw.printf("shannon_search_duration_seconds_bucket{le=%q} %d\n", formatValue(bound), h.Counts[i])
+	}
+	mw.printf("shannon_search_duration_seconds_bucket{le=\"+Inf\"} %d\n", h.Count)
+	mw.printf("shannon_search_duration_seconds_count %d\n", h.Count)
+	mw.printf("shannon_search_duration_seconds_sum %s\n", formatValue(h.Sum))
+
+	mw.printf("# EOF\n")
+	if mw.err != nil {
+		return mw.err
+	}
+	return mw.w.Flush()
+}
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
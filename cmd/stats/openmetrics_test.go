@@ -0,0 +1,50 @@
+package stats
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/neilberkman/shannon/internal/search"
+)
+
+func TestWriteOpenMetrics(t *testing.T) {
+	m := &search.Metrics{
+		Conversations: 12,
+		Messages:      map[string]int{"human": 30, "assistant": 31},
+		Imports:       map[string]int{"success": 2},
+		SearchLatency: search.Histogram{
+			Buckets: []float64{0.01, 0.1},
+			Counts:  []int{1, 3},
+			Count:   4,
+			Sum:     0.75,
+		},
+	}
+
+	var out strings.Builder
+	if err := writeOpenMetrics(&out, m); err != nil {
+		t.Fatal(err)
+	}
+	text := out.String()
+
+	for _, want := range []string{
+		"# TYPE shannon_conversations gauge\n",
+		"shannon_conversations 12\n",
+		`shannon_messages{sender="assistant"} 31` + "\n",
+		"# TYPE shannon_imports counter\n",
+		`shannon_imports_total{status="success"} 2` + "\n",
+		"# UNIT shannon_search_duration_seconds seconds\n",
+		`shannon_search_duration_seconds_bucket{le="0.01"} 1` + "\n",
+		`shannon_search_duration_seconds_bucket{le="+Inf"} 4` + "\n",
+		"shannon_search_duration_seconds_sum 0.75\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in:\n%s", want, text)
+		}
+	}
+	if !strings.HasSuffix(text, "# EOF\n") {
+		t.Errorf("expected the exposition to end with # EOF, got:\n%s", text)
+	}
+	if strings.Contains(text, "shannon_last_import_timestamp_seconds") {
+		t.Error("expected no last import time without imports")
+	}
+}
@@ -1,27 +1,147 @@
 package stats
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/neilberkman/shannon/cmd/tui"
 	"github.com/neilberkman/shannon/internal/config"
+	"github.com/neilberkman/shannon/internal/dates"
 	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/rendering"
+	"github.com/neilberkman/shannon/internal/schema"
 	"github.com/neilberkman/shannon/internal/search"
+	"github.com/neilberkman/shannon/internal/store"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	format      string
+	printSchema bool
+	showHeatmap bool
+	year        int
+	sender      string
+	graphics    string
+	showUsage   bool
+	usageSince  string
+	usageLimit  int
+	openMetrics bool
+	useTUI      bool
 )
 
 // StatsCmd represents the stats command
 var StatsCmd = &cobra.Command{
 	Use:   "stats",
 	Short: "Show database statistics",
-	Long:  `Display statistics about your imported Claude conversations.`,
-	RunE:  runStats,
+	Long: `Display statistics about your imported Claude conversations.
+
+With --heatmap, also show a GitHub-style calendar of how many messages were
+written each day over the last year, or the calendar year given with --year.
+Terminals that support the Kitty graphics protocol (Kitty, Ghostty, WezTerm)
+get an image; others get unicode blocks.
+
+With --usage, also show the conversations you come back to most, by the
+number of days you viewed or exported them, and your most repeated searches.
+Views, exports and searches are recorded locally as you use shannon; --since
+limits them to a recent period. Conversations you keep revisiting are good
+candidates for proper documentation.
+
+With --openmetrics, print the totals, import counters and a histogram of
+search times in the OpenMetrics text format instead, for Prometheus. Write it
+where node_exporter's textfile collector looks, from cron or after each
+import, to graph the archive in Grafana.
+
+With --tui, browse the statistics as an interactive dashboard instead:
+conversations by month and code artifacts by language. Choosing a month lists
+the conversations started in it, and choosing a language its code artifacts,
+which open in the conversation they came from.
+
+Examples:
+  shannon stats
+  shannon stats --heatmap
+  shannon stats --year 2024 --sender human
+  shannon stats --heatmap --graphics blocks
+  shannon stats --usage --since 90d
+  shannon stats --openmetrics > /var/lib/node_exporter/textfile/shannon.prom
+  shannon stats --tui
+
+JSON output follows a published schema (see --schema) and carries a
+schema_version field that changes only on breaking changes.`,
+	RunE:        runStats,
+	Annotations: map[string]string{store.Annotation: "true"},
+}
+
+func init() {
+	StatsCmd.Flags().StringVarP(&format, "format", "f", "table", "output format (table/json)")
+	StatsCmd.Flags().BoolVar(&printSchema, "schema", false, "print the JSON Schema of --format json output and exit")
+	StatsCmd.Flags().BoolVar(&showHeatmap, "heatmap", false, "show a calendar heatmap of daily message activity")
+	StatsCmd.Flags().IntVar(&year, "year", 0, "heatmap of a calendar year instead of the last 52 weeks (implies --heatmap)")
+	StatsCmd.Flags().StringVar(&sender, "sender", "", "heatmap of one sender's messages: human or assistant (implies --heatmap)")
+	StatsCmd.Flags().StringVar(&graphics, "graphics", "auto", "heatmap rendering: auto, kitty or blocks")
+	StatsCmd.Flags().BoolVar(&showUsage, "usage", false, "show the most revisited conversations and most repeated searches")
+	StatsCmd.Flags().StringVar(&usageSince, "since", "", "usage since a date or age such as 30d, 6w, 3m or 1y (implies --usage)")
+	StatsCmd.Flags().IntVar(&usageLimit, "limit", 10, "number of conversations and searches shown with --usage")
+	StatsCmd.Flags().BoolVar(&openMetrics, "openmetrics", false, "print metrics in the OpenMetrics text format for Prometheus")
+	StatsCmd.Flags().BoolVar(&useTUI, "tui", false, "browse the statistics as an interactive dashboard")
 }
 
 func runStats(cmd *cobra.Command, args []string) error {
+	if printSchema {
+		return schema.Write(os.Stdout, "stats")
+	}
+	if format != "table" && format != "json" {
+		return fmt.Errorf("unknown format %q (use table or json)", format)
+	}
+	if sender != "" && sender != "human" && sender != "assistant" {
+		return fmt.Errorf("invalid sender %q (use human or assistant)", sender)
+	}
+	if graphics != "auto" && graphics != "kitty" && graphics != "blocks" {
+		return fmt.Errorf("invalid graphics %q (use auto, kitty or blocks)", graphics)
+	}
+	if year < 0 || year > time.Now().Year() {
+		return fmt.Errorf("invalid year %d", year)
+	}
+	showHeatmap = showHeatmap || year != 0 || sender != ""
+	showUsage = showUsage || usageSince != ""
+	var since time.Time
+	if usageSince != "" {
+		var err error
+		if since, err = dates.Parse(usageSince, time.Now()); err != nil {
+			return fmt.Errorf("invalid --since: %w", err)
+		}
+	}
+	if usageLimit < 1 {
+		return fmt.Errorf("invalid limit %d", usageLimit)
+	}
+	if openMetrics && (format != "table" || showHeatmap || showUsage) {
+		return fmt.Errorf("--openmetrics can't be combined with --format, --heatmap or --usage")
+	}
+	if useTUI && (format != "table" || showHeatmap || showUsage || openMetrics) {
+		return fmt.Errorf("--tui can't be combined with --format, --heatmap, --usage or --openmetrics")
+	}
+
 	// Get configuration
 	cfg := config.Get()
+	if store.Shared(cfg) {
+		if useTUI || openMetrics || showUsage {
+			return fmt.Errorf("--tui, --openmetrics and --usage aren't supported with the %s store", cfg.Database.Driver)
+		}
+		s, err := store.Open(cfg)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := s.Close(); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+			}
+		}()
+		return showStats(s, nil, since)
+	}
 
 	// Open database
 	database, err := db.New(cfg.Database.Path)
@@ -37,12 +157,57 @@ func runStats(cmd *cobra.Command, args []string) error {
 	// Create search engine
 	engine := search.NewEngine(database)
 
+	if useTUI {
+		return tui.RunDashboard(engine)
+	}
+
+	if openMetrics {
+		metrics, err := engine.GetMetrics()
+		if err != nil {
+			return err
+		}
+		return writeOpenMetrics(os.Stdout, metrics)
+	}
+
+	var usage *search.Usage
+	if showUsage {
+		if usage, err = engine.GetUsage(since, usageLimit); err != nil {
+			return err
+		}
+	}
+	return showStats(engine, usage, since)
+}
+
+// statsSource is where the totals come from: the local search engine or a
+// shared store
+type statsSource interface {
+	GetStats() (map[string]interface{}, error)
+	MessageActivity(from, to time.Time, sender string) (map[string]int, error)
+}
+
+// showStats prints the totals of source, with the heatmap if asked for and
+// the usage if there is any
+func showStats(source statsSource, usage *search.Usage, since time.Time) error {
 	// Get stats
-	stats, err := engine.GetStats()
+	stats, err := source.GetStats()
 	if err != nil {
 		return fmt.Errorf("failed to get stats: %w", err)
 	}
 
+	var activity *heatmap
+	if showHeatmap {
+		from, to := heatmapRange(year, time.Now())
+		counts, err := source.MessageActivity(from, to, sender)
+		if err != nil {
+			return err
+		}
+		activity = newHeatmap(from, to, counts)
+	}
+
+	if format == "json" {
+		return outputJSON(stats, activity, usage, since)
+	}
+
 	// Display stats
 	fmt.Println("=== Claude Search Database Statistics ===")
 	fmt.Printf("\nTotal Conversations: %d\n", stats["total_conversations"])
@@ -54,6 +219,17 @@ func runStats(cmd *cobra.Command, args []string) error {
 		fmt.Printf("  Assistant: %d\n", msgStats["assistant"])
 	}
 
+	if byModel, ok := stats["messages_by_model"].([]search.ModelUsage); ok && len(byModel) > 0 {
+		fmt.Printf("\nMessages by Model:\n")
+		width := 0
+		for _, u := range byModel {
+			width = max(width, len(u.Model))
+		}
+		for _, u := range byModel {
+			fmt.Printf("  %-*s  %d in %d conversation(s)\n", width, u.Model+":", u.Messages, u.Conversations)
+		}
+	}
+
 	if dateRange, ok := stats["date_range"].(map[string]time.Time); ok {
 		fmt.Printf("\nDate Range:\n")
 		fmt.Printf("  Oldest: %s\n", dateRange["oldest"].Format("2006-01-02"))
@@ -63,5 +239,113 @@ func runStats(cmd *cobra.Command, args []string) error {
 		fmt.Printf("  Span:   %.0f days\n", duration.Hours()/24)
 	}
 
+	if activity != nil {
+		if err := printHeatmap(activity); err != nil {
+			return err
+		}
+	}
+
+	if usage != nil {
+		printUsage(usage)
+	}
+
 	return nil
 }
+
+// heatmapRange returns the days covered by the heatmap: a calendar year up to
+// today, or the 52 full weeks before the current one and the current week so
+// far
+func heatmapRange(year int, now time.Time) (time.Time, time.Time) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	tomorrow := today.AddDate(0, 0, 1)
+	if year != 0 {
+		from := time.Date(year, time.January, 1, 0, 0, 0, 0, now.Location())
+		to := from.AddDate(1, 0, 0)
+		if to.After(tomorrow) {
+			to = tomorrow
+		}
+		return from, to
+	}
+	return today.AddDate(0, 0, -int(today.Weekday())-52*7), tomorrow
+}
+
+func printHeatmap(h *heatmap) error {
+	period := "last year"
+	if year != 0 {
+		period = strconv.Itoa(year)
+	}
+	who := "Messages"
+	if sender != "" {
+		who = strings.ToUpper(sender[:1]) + sender[1:] + " messages"
+	}
+	fmt.Printf("\n%s per day, %s:\n\n", who, period)
+
+	useKitty := graphics == "kitty" ||
+		(graphics == "auto" && rendering.IsKittyGraphicsSupported() && term.IsTerminal(int(os.Stdout.Fd())))
+	if useKitty {
+		image, err := h.renderKitty()
+		if err != nil {
+			return err
+		}
+		fmt.Print(image)
+	} else {
+		fmt.Print(h.renderBlocks())
+	}
+
+	fmt.Printf("\n%s\n", h.summary())
+	return nil
+}
+
+func outputJSON(stats map[string]interface{}, activity *heatmap, usage *search.Usage, since time.Time) error {
+	output := map[string]interface{}{
+		schema.Field:          schema.Version,
+		"total_conversations": stats["total_conversations"],
+		"total_messages":      stats["total_messages"],
+		"messages_by_sender":  stats["messages_by_sender"],
+		"messages_by_model":   stats["messages_by_model"],
+	}
+	if dateRange, ok := stats["date_range"].(map[string]time.Time); ok {
+		output["date_range"] = dateRange
+	}
+	if activity != nil {
+		output["activity"] = map[string]interface{}{
+			"from":   activity.from.Format("2006-01-02"),
+			"to":     activity.to.AddDate(0, 0, -1).Format("2006-01-02"),
+			"sender": sender,
+			"days":   activity.counts,
+		}
+	}
+	if usage != nil {
+		conversations := make([]map[string]interface{}, len(usage.Conversations))
+		for i, u := range usage.Conversations {
+			conversations[i] = map[string]interface{}{
+				"id":          u.ID,
+				"name":        u.Name,
+				"views":       u.Views,
+				"exports":     u.Exports,
+				"days":        u.Days,
+				"last_access": u.LastAccess,
+			}
+		}
+		searches := make([]map[string]interface{}, len(usage.Searches))
+		for i, u := range usage.Searches {
+			searches[i] = map[string]interface{}{
+				"query":         u.Query,
+				"count":         u.Count,
+				"last_searched": u.LastSearched,
+			}
+		}
+		usageOutput := map[string]interface{}{
+			"conversations": conversations,
+			"searches":      searches,
+		}
+		if !since.IsZero() {
+			usageOutput["since"] = since
+		}
+		output["usage"] = usageOutput
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(output)
+}
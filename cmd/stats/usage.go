@@ -0,0 +1,36 @@
+package stats
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/neilberkman/shannon/internal/search"
+)
+
+func printUsage(usage *search.Usage) {
+	fmt.Printf("\nMost Revisited Conversations:\n")
+	if len(usage.Conversations) == 0 {
+		fmt.Println("  None yet. Conversations you view or export are counted here.")
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "  ID\tDAYS\tVIEWS\tEXPORTS\tLAST\tNAME")
+		for _, u := range usage.Conversations {
+			_, _ = fmt.Fprintf(w, "  %d\t%d\t%d\t%d\t%s\t%s\n",
+				u.ID, u.Days, u.Views, u.Exports, u.LastAccess.Local().Format("2006-01-02"), u.Name)
+		}
+		_ = w.Flush()
+	}
+
+	fmt.Printf("\nMost Repeated Searches:\n")
+	if len(usage.Searches) == 0 {
+		fmt.Println("  None yet. Searches you run are counted here.")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "  COUNT\tLAST\tQUERY")
+	for _, u := range usage.Searches {
+		_, _ = fmt.Fprintf(w, "  %d\t%s\t%s\n", u.Count, u.LastSearched.Local().Format("2006-01-02"), u.Query)
+	}
+	_ = w.Flush()
+}
@@ -0,0 +1,223 @@
+package sync
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/neilberkman/shannon/internal/bundle"
+	"github.com/neilberkman/shannon/internal/config"
+	"github.com/neilberkman/shannon/internal/dates"
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/imports"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	since          string
+	all            bool
+	outputFile     string
+	restoreDeleted bool
+)
+
+// NewCmd creates the sync command
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Sync conversations between machines with bundle files",
+		Long: `Keep archives on several machines in step without copying the database.
+
+'sync export' writes the conversations that are new or changed since a point
+in time to a compact bundle file. Copy the file to another machine however you
+like and run 'sync import' there. Importing only adds what is missing, so
+overlapping bundles are harmless.
+
+Without --since, export picks up where the previous export left off.
+
+Bundles carry conversations and messages only. Messages deleted or truncated
+with 'shannon cleanup' are not deleted or truncated on the other machine, and
+conversations deleted on this machine stay deleted when a bundle that still
+contains them is imported (unless --restore-deleted is used).
+
+Examples:
+  shannon sync export -o laptop.shannon
+  shannon sync export --since 7d -o week.shannon
+  shannon sync export --since 2024-06-01 -o june.shannon
+  shannon sync import laptop.shannon`,
+	}
+
+	cmd.AddCommand(newExportCmd())
+	cmd.AddCommand(newImportCmd())
+
+	return cmd
+}
+
+// newExportCmd creates the export subcommand
+func newExportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Write new and changed conversations to a bundle",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if outputFile == "" {
+				return fmt.Errorf("output file is required (-o bundle.shannon, or -o - for stdout)")
+			}
+			if all && since != "" {
+				return fmt.Errorf("--all and --since cannot be used together")
+			}
+
+			database, err := openDatabase()
+			if err != nil {
+				return err
+			}
+			defer closeDatabase(database)
+
+			var threshold time.Time
+			switch {
+			case since != "":
+				if threshold, err = dates.Parse(since, time.Now()); err != nil {
+					return fmt.Errorf("invalid --since: %w", err)
+				}
+			case !all:
+				if threshold, err = bundle.LastExport(database); err != nil {
+					return err
+				}
+			}
+
+			b, err := bundle.Build(database, threshold)
+			if err != nil {
+				return err
+			}
+			if len(b.Conversations) == 0 {
+				fmt.Fprintf(os.Stderr, "No conversations changed since %s; nothing to export.\n", threshold.Local().Format("2006-01-02 15:04:05"))
+				return nil
+			}
+
+			var buf bytes.Buffer
+			if err := bundle.Write(&buf, b); err != nil {
+				return err
+			}
+			if outputFile == "-" {
+				if _, err := os.Stdout.Write(buf.Bytes()); err != nil {
+					return fmt.Errorf("failed to write bundle: %w", err)
+				}
+			} else if err := os.WriteFile(outputFile, buf.Bytes(), 0644); err != nil {
+				return fmt.Errorf("failed to write bundle: %w", err)
+			}
+
+			if err := bundle.SetLastExport(database, b.CreatedAt); err != nil {
+				return err
+			}
+
+			messages := 0
+			for _, conv := range b.Conversations {
+				messages += len(conv.ChatMessages)
+			}
+			scope := ""
+			if !threshold.IsZero() {
+				scope = " changed since " + threshold.Local().Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(os.Stderr, "Exported %d conversations%s (%d messages, %s)\n",
+				len(b.Conversations), scope, messages, humanize.Bytes(uint64(buf.Len())))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&since, "since", "", "export changes since a date (2024-06-01), time (RFC 3339) or duration ago (36h, 7d)")
+	cmd.Flags().BoolVar(&all, "all", false, "export every conversation")
+	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "bundle file to write (- for stdout)")
+
+	return cmd
+}
+
+// newImportCmd creates the import subcommand
+func newImportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import [bundle]",
+		Short: "Import a bundle written by 'sync export'",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := args[0]
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("failed to read bundle: %w", err)
+			}
+
+			b, err := bundle.Read(bytes.NewReader(data))
+			if err != nil {
+				return err
+			}
+			if len(b.Conversations) == 0 {
+				fmt.Println("Bundle is empty; nothing to import.")
+				return nil
+			}
+
+			database, err := openDatabase()
+			if err != nil {
+				return err
+			}
+			defer closeDatabase(database)
+
+			cfg := config.Get()
+			importer := imports.NewImporter(database, cfg.Import.BatchSize, cfg.Import.Verbose || viper.GetBool("verbose"))
+			importer.SetRestoreDeleted(restoreDeleted)
+
+			hash := sha256.Sum256(data)
+			fmt.Printf("Importing %s (exported %s)...\n", path, b.CreatedAt.Local().Format("2006-01-02 15:04:05"))
+			stats, err := importer.ImportConversations(path, hex.EncodeToString(hash[:]), b.Conversations)
+			if err != nil {
+				return fmt.Errorf("import failed: %w", err)
+			}
+
+			fmt.Printf("\nImport completed in %s:\n", stats.Duration)
+			fmt.Printf("  Conversations in bundle: %d\n", len(b.Conversations))
+			fmt.Printf("  New conversations: %d\n", stats.ConversationsImported)
+			fmt.Printf("  New messages: %d\n", stats.MessagesImported)
+			fmt.Printf("  Branches detected: %d\n", stats.BranchesDetected)
+			if stats.ConversationsReordered > 0 {
+				fmt.Printf("  Conversations reordered: %d\n", stats.ConversationsReordered)
+			}
+			if stats.ConversationsRestored > 0 {
+				fmt.Printf("  Deleted conversations restored: %d\n", stats.ConversationsRestored)
+			}
+			if stats.ConversationsSkipped > 0 {
+				fmt.Printf("  Deleted conversations skipped: %d (use --restore-deleted to import them)\n", stats.ConversationsSkipped)
+			}
+			fmt.Printf("\nRecorded as import %d (see 'shannon imports show %d')\n", stats.ImportID, stats.ImportID)
+
+			if len(stats.Errors) > 0 {
+				fmt.Printf("\nErrors encountered: %d\n", len(stats.Errors))
+				if viper.GetBool("verbose") {
+					for _, err := range stats.Errors {
+						fmt.Printf("  - %v\n", err)
+					}
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&restoreDeleted, "restore-deleted", false, "import conversations that were deleted locally")
+
+	return cmd
+}
+
+func openDatabase() (*db.DB, error) {
+	cfg := config.Get()
+	database, err := db.New(cfg.Database.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	return database, nil
+}
+
+func closeDatabase(database *db.DB) {
+	if err := database.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+	}
+}
@@ -0,0 +1,299 @@
+package tail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/artifacts"
+	"github.com/neilberkman/shannon/internal/config"
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/export"
+	"github.com/neilberkman/shannon/internal/imports"
+	"github.com/neilberkman/shannon/internal/models"
+	"github.com/neilberkman/shannon/internal/rendering"
+	"github.com/neilberkman/shannon/internal/search"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	last     int
+	interval time.Duration
+	file     string
+)
+
+// TailCmd represents the tail command
+var TailCmd = &cobra.Command{
+	Use:   "tail [conversation]",
+	Short: "Follow a conversation, printing new messages as they arrive",
+	Long: `Print the last messages of a conversation and keep printing its new messages
+as they reach the database, like tail -f, until interrupted with Ctrl+C.
+
+Messages arrive when an import, 'shannon sync' or 'shannon tui --watch
+--auto-import' adds them. With --file, tail imports them itself:
+
+  - a Claude Code session log (~/.claude/projects/*/SESSION.jsonl) is read
+    as it grows, each new line once, so a session is followed as you work;
+    the conversation can be left out to follow the log's own
+  - an export is imported again whenever it changes, once it has stopped
+    changing for an --interval
+
+Examples:
+  shannon tail 123
+  shannon tail 123 -n 0 --interval 10s
+  shannon tail --file ~/.claude/projects/-home-me-app/1f0c9b6e.jsonl
+  shannon tail 123 --file ~/backups/conversations.json`,
+	Args: cobra.MaximumNArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if interval <= 0 {
+			return fmt.Errorf("--interval must be more than 0")
+		}
+		if last < 0 {
+			return fmt.Errorf("-n can't be negative")
+		}
+		if len(args) == 0 && !imports.IsSessionLog(file) {
+			return fmt.Errorf("name the conversation to follow, or give --file a Claude Code session log")
+		}
+		return nil
+	},
+	RunE: runTail,
+}
+
+func init() {
+	TailCmd.Flags().IntVarP(&last, "messages", "n", 5, "number of the latest messages to print first")
+	TailCmd.Flags().DurationVar(&interval, "interval", 2*time.Second, "how often to look for new messages")
+	TailCmd.Flags().StringVar(&file, "file", "", "Claude Code session log (.jsonl) or export to import from as it changes")
+}
+
+func runTail(cmd *cobra.Command, args []string) error {
+	cfg := config.Get()
+	database, err := db.New(cfg.Database.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer func() {
+		if err := database.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+		}
+	}()
+
+	var followed follower
+	if file != "" {
+		if followed, err = newFollower(file, interval); err != nil {
+			return err
+		}
+	}
+
+	engine := search.NewEngine(database)
+	var convID int64
+	if session, ok := followed.(*sessionFile); ok {
+		// Read the log first, so its conversation is there to follow
+		if err := session.importChanges(database, cfg.Import.BatchSize); err != nil {
+			return fmt.Errorf("failed to import %s: %w", file, err)
+		}
+		if len(args) == 0 {
+			if convID, err = session.conversation(database); err != nil {
+				return err
+			}
+		}
+	}
+	if len(args) > 0 {
+		if convID, err = engine.ResolveConversation(args[0]); err != nil {
+			return err
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	conv, messages, err := engine.GetConversation(convID)
+	if err != nil {
+		return fmt.Errorf("failed to get conversation: %w", err)
+	}
+	fmt.Printf("=== Following: %s (%d) ===\n", conv.Name, conv.ID)
+	if hidden := len(messages) - last; hidden > 0 {
+		fmt.Printf("... %d earlier message(s)\n", hidden)
+	}
+	fmt.Println()
+
+	p := newPrinter(cfg)
+	var lastID int64
+	for i, msg := range messages {
+		if i >= len(messages)-last {
+			p.print(i+1, msg)
+		}
+		lastID = max(lastID, msg.ID)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		if followed != nil {
+			if err := followed.importChanges(database, cfg.Import.BatchSize); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to import %s: %v\n", file, err)
+			}
+		}
+
+		_, messages, err := engine.GetConversation(convID)
+		if err != nil {
+			return fmt.Errorf("failed to get conversation: %w", err)
+		}
+		for i, msg := range messages {
+			if msg.ID > lastID {
+				p.print(i+1, msg)
+				lastID = msg.ID
+			}
+		}
+	}
+}
+
+// follower imports the file tail follows whenever it changes
+type follower interface {
+	importChanges(database *db.DB, batchSize int) error
+}
+
+// newFollower follows a Claude Code session log or an export, by its name
+func newFollower(path string, stableFor time.Duration) (follower, error) {
+	state, err := imports.StatFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("file not found: %s", path)
+	}
+	if imports.IsSessionLog(path) {
+		return &sessionFile{log: imports.NewSessionLog(path)}, nil
+	}
+	return &exportFile{path: path, state: state, stableFor: stableFor, pending: true}, nil
+}
+
+// exportFile is an export imported again whenever it changes
+type exportFile struct {
+	path      string
+	state     imports.FileState // as of the last look
+	stableFor time.Duration     // how long it must be unchanged to be imported
+	pending   bool              // changed since the last import
+}
+
+// importChanges imports the export if it changed and has settled since, so
+// a file being written isn't read half way
+func (f *exportFile) importChanges(database *db.DB, batchSize int) error {
+	state, err := imports.StatFile(f.path)
+	if err != nil {
+		return err
+	}
+	prev := f.state
+	f.state = state
+	if !state.Same(prev) {
+		f.pending = true
+	}
+	if !f.pending || !state.Settled(prev, f.stableFor, time.Now()) {
+		return nil
+	}
+	f.pending = false
+
+	importer := imports.NewImporter(database, batchSize, false)
+	hash, err := imports.FileHash(f.path)
+	if err != nil {
+		return err
+	}
+	if imported, err := importer.IsImported(hash); err != nil || imported {
+		return err
+	}
+	_, err = importer.Import(f.path)
+	return err
+}
+
+// sessionFile is a Claude Code session log, whose new lines are imported as
+// they're written
+type sessionFile struct {
+	log     *imports.SessionLog
+	pending bool // messages read that haven't been imported yet
+}
+
+// importChanges reads the lines added to the log and imports the messages
+// among them
+func (f *sessionFile) importChanges(database *db.DB, batchSize int) error {
+	added, err := f.log.Read()
+	if err != nil {
+		return err
+	}
+	if added > 0 {
+		f.pending = true
+	}
+	if !f.pending {
+		return nil
+	}
+
+	importer := imports.NewImporter(database, batchSize, false)
+	if imported, err := importer.IsImported(f.log.Hash()); err != nil {
+		return err
+	} else if !imported {
+		if _, err := f.log.Import(importer); err != nil {
+			return err
+		}
+	}
+	f.pending = false
+	return nil
+}
+
+// conversation returns the ID of the session's conversation
+func (f *sessionFile) conversation(database *db.DB) (int64, error) {
+	conv := f.log.Conversation()
+	if conv == nil {
+		return 0, fmt.Errorf("no messages in the session log yet")
+	}
+	var id int64
+	if err := database.QueryRow("SELECT id FROM conversations WHERE uuid = ?", conv.UUID).Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to find the session's conversation: %w", err)
+	}
+	return id, nil
+}
+
+// printer writes messages the way 'shannon view' does, in full
+type printer struct {
+	extractor *artifacts.Extractor
+	renderer  *artifacts.TerminalRenderer
+	preview   int
+}
+
+func newPrinter(cfg *config.Config) *printer {
+	renderer := artifacts.NewTerminalRenderer()
+	renderer.Wrap = cfg.UI.ArtifactWrap
+	if width, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+		renderer.MaxWidth = width - 4 // artifacts are indented by four spaces
+	}
+	return &printer{extractor: artifacts.NewExtractor(), renderer: renderer, preview: cfg.UI.ArtifactPreviewLines}
+}
+
+// print writes the message numbered n, with its artifacts shown inline
+func (p *printer) print(n int, msg *models.Message) {
+	fmt.Printf("[%d] %s\n", n, export.MessageHeader(msg))
+
+	content := msg.Text
+	var found []*artifacts.Artifact
+	if msg.Sender == "assistant" {
+		found, _ = p.extractor.ExtractFromMessage(msg)
+		if len(found) > 0 {
+			content = p.extractor.ArtifactRegex.ReplaceAllString(content, "[Artifact: see below]")
+		}
+	}
+	content = rendering.RenderMath(content)
+	fmt.Printf("    %s\n", strings.Join(strings.Split(content, "\n"), "\n    "))
+
+	for _, artifact := range found {
+		fmt.Println()
+		for _, line := range strings.Split(p.renderer.RenderInline(artifact, false, true, p.preview), "\n") {
+			fmt.Printf("    %s\n", line)
+		}
+	}
+	fmt.Println()
+}
@@ -0,0 +1,169 @@
+package tail
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+func newTestDB(t *testing.T) *db.DB {
+	t.Helper()
+
+	database, err := db.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = database.Close() })
+	return database
+}
+
+func countMessages(t *testing.T, database *db.DB) int {
+	t.Helper()
+
+	var n int
+	if err := database.QueryRow("SELECT COUNT(*) FROM messages").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestExportFile(t *testing.T) {
+	database := newTestDB(t)
+	path := filepath.Join(t.TempDir(), "conversations.json")
+	write := func(messages ...string) {
+		t.Helper()
+		conv := models.ClaudeConversation{UUID: "conv-1", Name: "Followed", CreatedAt: "2025-06-25T09:00:00Z", UpdatedAt: "2025-06-25T09:00:00Z"}
+		for i, text := range messages {
+			conv.ChatMessages = append(conv.ChatMessages, models.ClaudeChatMessage{
+				UUID: text, Sender: []string{"human", "assistant"}[i%2], Text: text, CreatedAt: "2025-06-25T09:00:00Z",
+			})
+		}
+		data, err := json.Marshal([]models.ClaudeConversation{conv})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Written a while ago, so it has settled
+	age := func(d time.Duration) {
+		t.Helper()
+		old := time.Now().Add(-d)
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("question", "answer")
+	age(time.Hour)
+	followed, err := newFollower(path, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := followed.(*exportFile); !ok {
+		t.Fatalf("expected an export, got %T", followed)
+	}
+
+	// An export that isn't changing is imported on the first look
+	if err := followed.importChanges(database, 100); err != nil {
+		t.Fatal(err)
+	}
+	if n := countMessages(t, database); n != 2 {
+		t.Fatalf("expected 2 messages imported, got %d", n)
+	}
+
+	// A change waits until the file has been left alone for a while
+	write("question", "answer", "follow-up")
+	age(10 * time.Second)
+	if err := followed.importChanges(database, 100); err != nil {
+		t.Fatal(err)
+	}
+	if err := followed.importChanges(database, 100); err != nil {
+		t.Fatal(err)
+	}
+	if n := countMessages(t, database); n != 2 {
+		t.Errorf("expected the change to wait, got %d messages", n)
+	}
+	age(time.Hour)
+	if err := followed.importChanges(database, 100); err != nil {
+		t.Fatal(err)
+	}
+	if err := followed.importChanges(database, 100); err != nil {
+		t.Fatal(err)
+	}
+	if n := countMessages(t, database); n != 3 {
+		t.Errorf("expected the follow-up once the file settled, got %d messages", n)
+	}
+}
+
+func TestSessionFile(t *testing.T) {
+	database := newTestDB(t)
+	path := filepath.Join(t.TempDir(), "sess-1.jsonl")
+	line := func(uuid, parent, role, text string) string {
+		data, err := json.Marshal(map[string]any{
+			"type": role, "uuid": uuid, "parentUuid": parent, "sessionId": "sess-1",
+			"timestamp": "2025-06-25T09:00:00.000Z",
+			"message":   map[string]any{"role": role, "content": text},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data) + "\n"
+	}
+	appendLines := func(lines ...string) {
+		t.Helper()
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, l := range lines {
+			if _, err := f.WriteString(l); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	appendLines(line("u1", "", "user", "Why is the build failing?"))
+	followed, err := newFollower(path, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, ok := followed.(*sessionFile)
+	if !ok {
+		t.Fatalf("expected a session log, got %T", followed)
+	}
+
+	// Lines are imported as soon as they're written
+	if err := session.importChanges(database, 100); err != nil {
+		t.Fatal(err)
+	}
+	convID, err := session.conversation(database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendLines(line("a1", "u1", "assistant", "foo was renamed."))
+	if err := session.importChanges(database, 100); err != nil {
+		t.Fatal(err)
+	}
+	// Nothing new is nothing to do
+	if err := session.importChanges(database, 100); err != nil {
+		t.Fatal(err)
+	}
+
+	var count int
+	if err := database.QueryRow("SELECT COUNT(*) FROM messages WHERE conversation_id = ?", convID).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("expected both messages in the session's conversation, got %d", count)
+	}
+}
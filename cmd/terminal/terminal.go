@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/neilberkman/shannon/internal/rendering"
+	"github.com/neilberkman/shannon/internal/store"
 	"github.com/spf13/cobra"
 )
 
@@ -14,11 +15,13 @@ var TerminalCmd = &cobra.Command{
 	Long: `Display information about the current terminal's capabilities and which Shannon features are available.
 
 This command helps you understand what advanced features like hyperlinks and graphics are supported in your terminal.`,
-	RunE: runTerminal,
+	Annotations: map[string]string{store.Annotation: "true"},
+	RunE:        runTerminal,
 }
 
 func runTerminal(cmd *cobra.Command, args []string) error {
 	caps := rendering.DetectTerminalCapabilities()
+	yes, no := rendering.Symbol("✓", "yes"), rendering.Symbol("✗", "no")
 
 	fmt.Println("Terminal Information:")
 	fmt.Printf("  Type: %s\n", caps.TerminalType)
@@ -27,7 +30,7 @@ func runTerminal(cmd *cobra.Command, args []string) error {
 	fmt.Println("Supported Features:")
 
 	if caps.SupportsHyperlinks {
-		fmt.Println("  ✓ OSC 8 Hyperlinks - Clickable links in search results and conversation lists")
+		fmt.Println("  " + yes + " OSC 8 Hyperlinks - Clickable links in search results and conversation lists")
 
 		// Show a demo hyperlink
 		if rendering.IsHyperlinksSupported() {
@@ -35,31 +38,31 @@ func runTerminal(cmd *cobra.Command, args []string) error {
 			fmt.Printf("    Demo: %s\n", demoLink)
 		}
 	} else {
-		fmt.Println("  ✗ OSC 8 Hyperlinks - Not supported")
+		fmt.Println("  " + no + " OSC 8 Hyperlinks - Not supported")
 	}
 
 	if caps.SupportsGraphics {
-		fmt.Println("  ✓ Graphics Protocol - Image display support (Kitty Graphics Protocol)")
-		fmt.Println("    Note: Graphics features not yet implemented in Shannon")
+		fmt.Println("  " + yes + " Graphics Protocol - Image display support (Kitty Graphics Protocol)")
+		fmt.Println("    Used by: shannon stats --heatmap")
 	} else {
-		fmt.Println("  ✗ Graphics Protocol - Not supported")
+		fmt.Println("  " + no + " Graphics Protocol - Not supported")
 	}
 
 	if caps.SupportsAdvancedInput {
-		fmt.Println("  ✓ Advanced Input - Enhanced keyboard handling")
+		fmt.Println("  " + yes + " Advanced Input - Enhanced keyboard handling")
 	} else {
-		fmt.Println("  ✗ Advanced Input - Basic keyboard only")
+		fmt.Println("  " + no + " Advanced Input - Basic keyboard only")
 	}
 
 	fmt.Println()
 	fmt.Println("Recommendations:")
 
 	if caps.TerminalType == "ghostty" {
-		fmt.Println("  🎉 You're using Ghostty! All Shannon features are optimally supported.")
+		fmt.Println("  " + rendering.Symbol("🎉 ", "") + "You're using Ghostty! All Shannon features are optimally supported.")
 	} else if caps.SupportsHyperlinks {
-		fmt.Println("  👍 Your terminal supports hyperlinks. Enjoy clickable search results!")
+		fmt.Println("  " + rendering.Symbol("👍 ", "") + "Your terminal supports hyperlinks. Enjoy clickable search results!")
 	} else {
-		fmt.Println("  💡 For the best Shannon experience, try:")
+		fmt.Println("  " + rendering.Symbol("💡 ", "") + "For the best Shannon experience, try:")
 		fmt.Println("     - Ghostty (https://ghostty.org)")
 		fmt.Println("     - Kitty (https://sw.kovidgoyal.net/kitty/)")
 		fmt.Println("     - WezTerm (https://wezfurlong.org/wezterm/)")
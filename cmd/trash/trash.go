@@ -0,0 +1,218 @@
+package trash
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dustin/go-humanize"
+	"github.com/neilberkman/shannon/internal/cleanup"
+	"github.com/neilberkman/shannon/internal/config"
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/search"
+	"github.com/spf13/cobra"
+)
+
+var (
+	format    string
+	assumeYes bool
+)
+
+// NewCmd creates the trash command
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "trash",
+		Short: "List, restore and empty deleted conversations",
+		Long: `Conversations deleted with 'shannon cleanup conversation' go to the trash.
+While there they are left out of search, lists and stats, and re-imports skip
+them, but nothing is lost until the trash is emptied.
+
+Conversations are purged for good once they have been in the trash for
+trash.retention_days, 30 by default. Set it to 0 in the config file to keep
+them until you empty the trash yourself:
+
+  trash:
+    retention_days: 0
+
+Examples:
+  shannon cleanup conversation 42
+  shannon trash list
+  shannon trash restore 42
+  shannon trash empty`,
+	}
+
+	cmd.AddCommand(newListCmd())
+	cmd.AddCommand(newRestoreCmd())
+	cmd.AddCommand(newEmptyCmd())
+
+	return cmd
+}
+
+// newListCmd creates the list subcommand
+func newListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the conversations in the trash",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			database, err := getDatabase()
+			if err != nil {
+				return err
+			}
+			defer closeDatabase(database)
+
+			trashed, err := cleanup.TrashedConversations(database)
+			if err != nil {
+				return err
+			}
+
+			if format == "json" {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(map[string]interface{}{
+					"conversations":  trashed,
+					"count":          len(trashed),
+					"retention_days": int(db.TrashRetention.Hours() / 24),
+				})
+			}
+
+			if len(trashed) == 0 {
+				fmt.Println("The trash is empty.")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			if _, err := fmt.Fprintln(w, "ID\tName\tMessages\tDeleted\tPurged"); err != nil {
+				return fmt.Errorf("failed to write header: %w", err)
+			}
+			if _, err := fmt.Fprintln(w, "--\t----\t--------\t-------\t------"); err != nil {
+				return fmt.Errorf("failed to write separator: %w", err)
+			}
+			for _, t := range trashed {
+				purged := "when emptied"
+				if db.TrashRetention > 0 {
+					purged = humanize.Time(t.DeletedAt.Add(db.TrashRetention))
+				}
+				if _, err := fmt.Fprintf(w, "%d\t%s\t%d\t%s\t%s\n",
+					t.ID, t.Name, t.MessageCount, humanize.Time(t.DeletedAt), purged); err != nil {
+					return fmt.Errorf("failed to write row: %w", err)
+				}
+			}
+			return w.Flush()
+		},
+	}
+
+	cmd.Flags().StringVarP(&format, "format", "f", "table", "output format (table/json)")
+
+	return cmd
+}
+
+// newRestoreCmd creates the restore subcommand
+func newRestoreCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "restore [conversation...]",
+		Short: "Take conversations out of the trash",
+		Long: `Take conversations out of the trash. They show up in search, lists and stats
+again, and re-imports update them as before.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			database, err := getDatabase()
+			if err != nil {
+				return err
+			}
+			defer closeDatabase(database)
+
+			// Conversations can be named by slug or alias too
+			engine := search.NewEngine(database)
+			for _, arg := range args {
+				id, err := engine.ResolveConversation(arg)
+				if err != nil {
+					return err
+				}
+				restored, err := cleanup.RestoreConversation(database, id)
+				if err != nil {
+					return fmt.Errorf("failed to restore conversation %d: %w", id, err)
+				}
+				fmt.Printf("Restored conversation %d %q (%d messages)\n", id, restored.Name, restored.MessageCount)
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// newEmptyCmd creates the empty subcommand
+func newEmptyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "empty",
+		Short: "Permanently delete the conversations in the trash",
+		Long: `Permanently delete every conversation in the trash. This can't be undone.
+The conversations stay out of re-imports until imported with
+'shannon import --restore-deleted'.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			database, err := getDatabase()
+			if err != nil {
+				return err
+			}
+			defer closeDatabase(database)
+
+			trashed, err := cleanup.TrashedConversations(database)
+			if err != nil {
+				return err
+			}
+			if len(trashed) == 0 {
+				fmt.Println("The trash is empty.")
+				return nil
+			}
+
+			if !assumeYes {
+				fmt.Printf("Permanently delete %s in the trash? This can't be undone. [y/N] ",
+					conversations(len(trashed)))
+				answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+				answer = strings.ToLower(strings.TrimSpace(answer))
+				if answer != "y" && answer != "yes" {
+					fmt.Println("Aborted.")
+					return nil
+				}
+			}
+
+			purged, err := cleanup.EmptyTrash(database)
+			if err != nil {
+				return fmt.Errorf("failed to empty the trash: %w", err)
+			}
+			fmt.Printf("Permanently deleted %s.\n", conversations(int(purged)))
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "don't ask for confirmation")
+
+	return cmd
+}
+
+func conversations(n int) string {
+	if n == 1 {
+		return "1 conversation"
+	}
+	return fmt.Sprintf("%d conversations", n)
+}
+
+func getDatabase() (*db.DB, error) {
+	cfg := config.Get()
+	database, err := db.New(cfg.Database.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	return database, nil
+}
+
+func closeDatabase(database *db.DB) {
+	if err := database.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+	}
+}
@@ -1,13 +1,20 @@
 package tui
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/neilberkman/shannon/internal/i18n"
 	"github.com/neilberkman/shannon/internal/models"
+	"github.com/neilberkman/shannon/internal/query"
 	"github.com/neilberkman/shannon/internal/search"
 	"golang.org/x/term"
 )
@@ -27,7 +34,10 @@ const (
 
 // conversationItem implements list.Item for conversations
 type conversationItem struct {
-	conv *models.Conversation
+	conv    *models.Conversation
+	sort    string // derived metric to show alongside the message count, if any
+	matches int    // messages matching the query bar, when filtering
+	snippet string // best match for the query bar, with <mark> tags
 }
 
 func (i conversationItem) Title() string {
@@ -36,13 +46,113 @@ func (i conversationItem) Title() string {
 
 func (i conversationItem) Description() string {
 	dateStr := formatConversationDates(i.conv.CreatedAt, i.conv.UpdatedAt)
-	return fmt.Sprintf("%s • %d messages", dateStr, i.conv.MessageCount)
+	desc := fmt.Sprintf("%s • %d messages", dateStr, i.conv.MessageCount)
+	switch i.sort {
+	case sortTokens:
+		desc += fmt.Sprintf(" • ~%d tokens", i.conv.TokenCount)
+	case sortArtifacts:
+		desc += fmt.Sprintf(" • %d artifacts", i.conv.ArtifactCount)
+	case sortHumanMessages:
+		desc += fmt.Sprintf(" • %d from you", i.conv.HumanMessageCount)
+	}
+	if i.matches > 0 {
+		desc += " • " + pluralize(i.matches, "match", "matches")
+	}
+	return desc
 }
 
 func (i conversationItem) FilterValue() string {
 	return i.conv.Name
 }
 
+// Browse list sort orders, cycled with "s"
+const (
+	sortDate          = "date"
+	sortMessages      = "messages"
+	sortTokens        = "tokens"
+	sortArtifacts     = "artifacts"
+	sortHumanMessages = "human messages"
+)
+
+var browseSorts = []string{sortDate, sortMessages, sortTokens, sortArtifacts, sortHumanMessages}
+
+// browseOrders maps the browse sorts to the orders the database lists
+// conversations in
+var browseOrders = map[string]string{
+	sortDate:          search.ListByUpdated,
+	sortMessages:      search.ListByMessages,
+	sortTokens:        search.ListByTokens,
+	sortArtifacts:     search.ListByArtifacts,
+	sortHumanMessages: search.ListByHumanMessages,
+}
+
+// browsePageSize is how many conversations the browse list loads at a time
+const browsePageSize = 200
+
+// sortMetric returns the value of a conversation that a sort orders by
+func sortMetric(c *models.Conversation, by string) int {
+	switch by {
+	case sortMessages:
+		return c.MessageCount
+	case sortTokens:
+		return c.TokenCount
+	case sortArtifacts:
+		return c.ArtifactCount
+	case sortHumanMessages:
+		return c.HumanMessageCount
+	default:
+		return 0
+	}
+}
+
+// sortMatches returns the query bar matches ordered by the given sort,
+// largest first, keeping the best matches first among equals. The date sort
+// keeps them in ranking order.
+func sortMatches(matches []*search.ConversationMatch, by string) []*search.ConversationMatch {
+	sorted := make([]*search.ConversationMatch, len(matches))
+	copy(sorted, matches)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sortMetric(sorted[i].Conversation, by) > sortMetric(sorted[j].Conversation, by)
+	})
+	return sorted
+}
+
+// searchDebounce is how long typing in the query bar must pause before the
+// list is filtered, set from ui.search_debounce_ms
+var searchDebounce = 300 * time.Millisecond
+
+// filterLimit caps the conversations the query bar shows
+const filterLimit = 500
+
+// searchRank is the ranking mode for TUI searches, set from search.rank
+var searchRank = search.RankRelevance
+
+// filterResultsMsg is sent when a query bar search finishes
+type filterResultsMsg struct {
+	id      int
+	query   string
+	matches []*search.ConversationMatch
+	took    time.Duration
+	err     error
+}
+
+// searchDebounceMsg fires once typing has paused long enough to filter the list
+type searchDebounceMsg struct {
+	id int
+}
+
+// conversationPageMsg is sent when a page of the browse list has loaded
+type conversationPageMsg struct {
+	id            int
+	offset        int
+	limit         int
+	conversations []*models.Conversation
+	total         int // with counted, how many conversations there are
+	counted       bool
+	err           error
+}
+
 // browseModel is the model for browsing conversations
 type browseModel struct {
 	engine        *search.Engine
@@ -50,29 +160,55 @@ type browseModel struct {
 	list          list.Model
 	textInput     textinput.Model
 	mode          Mode
-	searching     bool
+	searching     bool // whether the query bar has focus
 	width         int
 	height        int
 
+	// Query bar state. searchID increases with every search started or
+	// canceled, so results from stale searches can be recognized and dropped.
+	// filterQuery is the query the list is filtered by, with its matches
+	// and how long finding them took.
+	spinner      spinner.Model
+	searchID     int
+	inFlight     bool
+	cancelSearch context.CancelFunc
+	searchErr    string
+	debounce     time.Duration
+	filterQuery  string
+	matches      []*search.ConversationMatch
+	filterTook   time.Duration
+
+	// sortIndex selects the current order from browseSorts
+	sortIndex int
+
+	// groupIndex selects the current grouping from browseGroups, with the
+	// groups collapsed to their headers. tags are the aliases of the
+	// conversations, by ID, read as they are grouped by tag.
+	groupIndex int
+	collapsed  map[string]bool
+	tags       map[int64][]string
+
+	// The list is loaded a page at a time in the background: the first as
+	// the view opens and the next as the selection nears the end of what's
+	// loaded, so large archives open instantly. The number of conversations
+	// is counted once, with the first page. loadID increases with every
+	// reload, so pages for an earlier order can be recognized and dropped.
+	total      int
+	counted    bool
+	loading    bool
+	loadID     int
+	loadErr    string
+	selectLast bool // select the last conversation once the rest has loaded
+
 	// Conversation view handles all conversation display and interaction
 	convView conversationView
 }
 
-// newBrowseModel creates a new browse model
+// newBrowseModel creates a new browse model. Its conversations are loaded
+// by the command from Init.
 func newBrowseModel(engine *search.Engine) browseModel {
-	// Get all conversations
-	conversations, _ := engine.GetAllConversations(10000, 0)
-
-	// Convert to list items
-	items := make([]list.Item, len(conversations))
-	for i, c := range conversations {
-		items[i] = conversationItem{conv: c}
-	}
-
-	// Create list
-	delegate := list.NewDefaultDelegate()
-	delegate.Styles.SelectedTitle = SelectedStyle
-	delegate.Styles.SelectedDesc = SelectedStyle
+	// Create list, highlighting the snippets of filtered conversations
+	delegate := newSnippetDelegate(showSnippets)
 
 	// Get actual terminal size
 	width, height, err := term.GetSize(int(os.Stdout.Fd()))
@@ -81,31 +217,325 @@ func newBrowseModel(engine *search.Engine) browseModel {
 		width, height = 80, 24
 	}
 
-	l := list.New(items, delegate, width, height-5) // Leave room for search input
-	l.Title = "Browse Conversations"
+	l := list.New(nil, delegate, width, height-5) // Leave room for search input
+	l.Title = browseTitle(sortDate)
 	l.SetShowHelp(false)
 	l.DisableQuitKeybindings()
 
 	// Create text input for search
 	ti := textinput.New()
-	ti.Placeholder = "Search conversations..."
+	ti.Placeholder = "press / to filter conversations by their messages"
 	ti.CharLimit = 100
 	ti.Width = 50
 
+	sp := spinner.New()
+	sp.Spinner = spinner.Dot
+
 	return browseModel{
-		engine:        engine,
-		conversations: conversations,
-		list:          l,
-		textInput:     ti,
-		mode:          ModeList,
-		width:         width,
-		height:        height,
+		engine:    engine,
+		list:      l,
+		textInput: ti,
+		mode:      ModeList,
+		width:     width,
+		height:    height,
+		spinner:   sp,
+		debounce:  searchDebounce,
+		loading:   true,
+	}
+}
+
+// fetchConversations loads limit conversations of the browse list starting
+// at offset, or the rest with a negative limit, counting them all if that
+// hasn't been done yet
+func (m browseModel) fetchConversations(offset, limit int) tea.Cmd {
+	id, counted, engine := m.loadID, m.counted, m.engine
+	order := browseOrders[browseSorts[m.sortIndex]]
+	return func() tea.Msg {
+		msg := conversationPageMsg{id: id, offset: offset, limit: limit}
+		if !counted {
+			if msg.total, msg.err = engine.CountConversations(); msg.err != nil {
+				return msg
+			}
+			msg.counted = true
+		}
+		msg.conversations, msg.err = engine.ListConversations(order, limit, offset)
+		return msg
+	}
+}
+
+// loadConversations fetches conversations in the background, showing the
+// spinner until they arrive
+func (m *browseModel) loadConversations(offset, limit int) tea.Cmd {
+	m.loading = true
+	m.loadErr = ""
+	return tea.Batch(m.fetchConversations(offset, limit), m.spinner.Tick)
+}
+
+// loadMore loads the next page of the browse list once the selection is
+// within a screen of the end of what's loaded
+func (m *browseModel) loadMore() tea.Cmd {
+	if m.loading || !m.counted || len(m.conversations) >= m.total || m.filterQuery != "" {
+		return nil
+	}
+	if m.list.Index() < len(m.conversations)-m.height {
+		return nil
 	}
+	return m.loadConversations(len(m.conversations), browsePageSize)
 }
 
-// Init initializes the model
+// loadRest loads all the conversations not loaded yet, in place of any
+// page on its way
+func (m *browseModel) loadRest() tea.Cmd {
+	m.loadID++
+	return m.loadConversations(len(m.conversations), -1)
+}
+
+// addConversations adds a page that has loaded to the browse list
+func (m *browseModel) addConversations(msg conversationPageMsg) tea.Cmd {
+	if msg.counted {
+		m.total, m.counted = msg.total, true
+	}
+	if msg.offset > len(m.conversations) {
+		return nil
+	}
+	m.conversations = append(m.conversations[:msg.offset:msg.offset], msg.conversations...)
+
+	// Conversations imported or trashed since counting change where the
+	// list ends
+	if msg.limit < 0 || len(msg.conversations) < msg.limit || len(m.conversations) > m.total {
+		m.total = len(m.conversations)
+	}
+
+	if m.filterQuery != "" {
+		return nil
+	}
+	cmd := m.showConversations()
+	switch {
+	case m.selectLast:
+		m.selectLast = false
+		m.list.Select(len(m.list.Items()) - 1)
+	case msg.offset == 0:
+		m.list.Select(0)
+	}
+	return cmd
+}
+
+// startSearch cancels any in-flight search and finds the conversations
+// matching text in the background, counting their matches. Filters such as
+// from:h and a:2w in the text are read as in shannon search.
+func (m *browseModel) startSearch(text string) tea.Cmd {
+	m.stopSearch()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancelSearch = cancel
+	m.inFlight = true
+	m.searchErr = ""
+	id := m.searchID
+	engine := m.engine
+
+	run := func() tea.Msg {
+		defer cancel()
+
+		started := time.Now()
+		opts := search.SearchOptions{Limit: filterLimit, Rank: searchRank}
+		parsed, err := query.Parse(text, started)
+		if err == nil && strings.TrimSpace(parsed.Text) == "" {
+			err = fmt.Errorf("add words to search for besides the filters")
+		}
+		if err != nil {
+			return filterResultsMsg{id: id, query: text, err: err}
+		}
+		parsed.Apply(&opts)
+		matches, err := engine.SearchConversationMatches(ctx, opts)
+		return filterResultsMsg{id: id, query: text, matches: matches, took: time.Since(started), err: err}
+	}
+
+	return tea.Batch(run, m.spinner.Tick)
+}
+
+// stopSearch cancels the in-flight search, if any, and invalidates its results
+func (m *browseModel) stopSearch() {
+	if m.cancelSearch != nil {
+		m.cancelSearch()
+		m.cancelSearch = nil
+	}
+	m.inFlight = false
+	m.searchID++
+}
+
+// debounceSearch filters the list by the query bar once typing pauses,
+// dropping any search for an earlier version of the query
+func (m *browseModel) debounceSearch() tea.Cmd {
+	m.stopSearch()
+	m.searchErr = ""
+	id := m.searchID
+	return tea.Tick(m.debounce, func(time.Time) tea.Msg {
+		return searchDebounceMsg{id: id}
+	})
+}
+
+// clearFilter empties the query bar and restores the full conversation list
+func (m *browseModel) clearFilter() tea.Cmd {
+	m.stopSearch()
+	m.searchErr = ""
+	m.textInput.SetValue("")
+	return m.resetList()
+}
+
+// resetList restores the full conversation list after filtered results were shown
+func (m *browseModel) resetList() tea.Cmd {
+	if m.filterQuery == "" {
+		return nil
+	}
+	m.filterQuery = ""
+	m.matches = nil
+	cmd := m.showConversations()
+	m.list.Select(0)
+	return cmd
+}
+
+// cycleSort switches the browse list to the next sort order. Filtered
+// results are sorted straight away; the full list is reloaded in the new
+// order, keeping the old one on screen until the first page, or everything
+// when grouped, arrives.
+func (m *browseModel) cycleSort() tea.Cmd {
+	m.sortIndex = (m.sortIndex + 1) % len(browseSorts)
+	m.loadID++
+	m.selectLast = false
+	// Grouped lists need every conversation
+	limit := browsePageSize
+	if browseGroups[m.groupIndex] != groupNone {
+		limit = -1
+	}
+	cmds := []tea.Cmd{m.loadConversations(0, limit)}
+	if m.filterQuery != "" {
+		cmds = append(cmds, m.showConversations())
+		m.list.Select(0)
+	}
+	return tea.Batch(cmds...)
+}
+
+// showConversations fills the list with the conversations matching the
+// query bar, or all conversations, in the current order
+func (m *browseModel) showConversations() tea.Cmd {
+	by := browseSorts[m.sortIndex]
+
+	if m.filterQuery != "" {
+		m.list.Title = filterTitle(m.filterQuery, len(m.matches), by)
+		matches := m.matches
+		if by != sortDate {
+			matches = sortMatches(matches, by)
+		}
+		items := make([]conversationItem, len(matches))
+		for i, match := range matches {
+			items[i] = conversationItem{conv: match.Conversation, sort: by, matches: match.Matches, snippet: match.Snippet}
+		}
+		return m.setItems(items)
+	}
+
+	m.list.Title = browseTitle(by)
+	if len(m.conversations) < m.total {
+		m.list.Title += fmt.Sprintf(" • %d of %d loaded", len(m.conversations), m.total)
+	}
+	items := make([]conversationItem, len(m.conversations))
+	for i, c := range m.conversations {
+		items[i] = conversationItem{conv: c, sort: by}
+	}
+	return m.setItems(items)
+}
+
+// setItems shows conversations in the list, under group headers when the
+// list is grouped
+func (m *browseModel) setItems(items []conversationItem) tea.Cmd {
+	by := browseGroups[m.groupIndex]
+	if by == groupNone {
+		listItems := make([]list.Item, len(items))
+		for i, item := range items {
+			listItems[i] = item
+		}
+		return m.list.SetItems(listItems)
+	}
+	m.list.Title += groupTitle(by)
+	return m.list.SetItems(groupItems(items, by, m.tags, m.collapsed))
+}
+
+// cycleGroup switches the browse list to the next grouping, with every
+// group expanded. Grouping needs the whole list, so the rest of it is
+// loaded.
+func (m *browseModel) cycleGroup() tea.Cmd {
+	m.groupIndex = (m.groupIndex + 1) % len(browseGroups)
+	m.collapsed = make(map[string]bool)
+	var cmds []tea.Cmd
+	if browseGroups[m.groupIndex] == groupTag {
+		names, err := m.engine.GetConversationNames(true)
+		if err != nil {
+			m.loadErr = err.Error()
+		}
+		m.tags = make(map[int64][]string, len(names))
+		for _, n := range names {
+			m.tags[n.ID] = n.Aliases
+		}
+	}
+	if browseGroups[m.groupIndex] != groupNone && m.filterQuery == "" && len(m.conversations) < m.total {
+		cmds = append(cmds, m.loadRest())
+	}
+	cmds = append(cmds, m.showConversations())
+	m.list.Select(0)
+	return tea.Batch(cmds...)
+}
+
+// setCollapsed collapses or expands the groups with the given keys, or all
+// of them if none are given, keeping the selection on the group it was in
+func (m *browseModel) setCollapsed(collapsed bool, keys ...string) tea.Cmd {
+	// The selection moves to the header of its group if that collapses
+	selected := ""
+	switch item := m.list.SelectedItem().(type) {
+	case groupHeaderItem:
+		selected = item.key
+	case conversationItem:
+		selected = groupKey(item.conv, browseGroups[m.groupIndex], m.tags)
+	}
+
+	if len(keys) == 0 {
+		for _, item := range m.list.Items() {
+			if header, ok := item.(groupHeaderItem); ok {
+				keys = append(keys, header.key)
+			}
+		}
+	}
+	for _, key := range keys {
+		m.collapsed[key] = collapsed
+	}
+	cmd := m.showConversations()
+	for i, item := range m.list.Items() {
+		if header, ok := item.(groupHeaderItem); ok && header.key == selected {
+			m.list.Select(i)
+			break
+		}
+	}
+	return cmd
+}
+
+// browseTitle returns the list title for a sort order
+func browseTitle(by string) string {
+	if by == sortDate {
+		return "Browse Conversations"
+	}
+	return fmt.Sprintf("Browse Conversations (by %s)", by)
+}
+
+// filterTitle returns the list title for conversations matching query
+func filterTitle(query string, count int, by string) string {
+	title := fmt.Sprintf("Matching %q (%s)", query, pluralize(count, "conversation", "conversations"))
+	if by != sortDate {
+		title += fmt.Sprintf(" by %s", by)
+	}
+	return title
+}
+
+// Init starts loading the conversations
 func (m browseModel) Init() tea.Cmd {
-	return nil
+	return tea.Batch(m.fetchConversations(0, browsePageSize), m.spinner.Tick)
 }
 
 // Update handles messages
@@ -117,6 +547,7 @@ func (m browseModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.width = msg.Width
 		m.height = msg.Height
 		m.list.SetSize(msg.Width, msg.Height-5) // Leave room for search
+		cmds = append(cmds, m.loadMore())
 
 		// Update conversation view if active
 		if m.mode == ModeConversation {
@@ -124,6 +555,66 @@ func (m browseModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.convView = cv
 		}
 
+	case spinner.TickMsg:
+		if m.inFlight || m.loading {
+			var cmd tea.Cmd
+			m.spinner, cmd = m.spinner.Update(msg)
+			cmds = append(cmds, cmd)
+		}
+
+	case searchDebounceMsg:
+		if msg.id == m.searchID {
+			if query := m.textInput.Value(); query != "" {
+				cmds = append(cmds, m.startSearch(query))
+			} else {
+				cmds = append(cmds, m.resetList())
+			}
+		}
+
+	case conversationPageMsg:
+		if msg.id != m.loadID {
+			break // Superseded by a reload
+		}
+		m.loading = false
+		if msg.err != nil {
+			m.loadErr = msg.err.Error()
+			break
+		}
+		cmds = append(cmds, m.addConversations(msg))
+		// A screen taller than a page needs more straight away
+		cmds = append(cmds, m.loadMore())
+
+	case filterResultsMsg:
+		if msg.id != m.searchID {
+			break // Canceled or superseded
+		}
+		m.inFlight = false
+		if msg.err != nil {
+			// Keep the last results so the query can be corrected
+			m.searchErr = msg.err.Error()
+			break
+		}
+		m.filterQuery = msg.query
+		m.matches = msg.matches
+		m.filterTook = msg.took
+		cmds = append(cmds, m.showConversations())
+		m.list.Select(0)
+
+	case paletteExportMsg:
+		// Export from the command palette, opening the conversation first
+		if m.mode == ModeList {
+			m.openConversation(msg.conv.ID)
+		}
+		if m.mode == ModeConversation {
+			m.convView.startExport()
+		}
+
+	case themeChangedMsg:
+		m.list.SetDelegate(newSnippetDelegate(showSnippets))
+		if m.mode == ModeConversation {
+			m.convView.restyle()
+		}
+
 	case tea.KeyMsg:
 		switch m.mode {
 		case ModeList:
@@ -136,76 +627,98 @@ func (m browseModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			} else if m.searching {
 				switch msg.String() {
 				case keyEnter:
-					// Perform search
-					query := m.textInput.Value()
-					if query != "" {
-						opts := search.SearchOptions{
-							Query:     query,
-							Limit:     1000,
-							SortBy:    "relevance",
-							SortOrder: "desc",
-						}
-						results, err := m.engine.Search(opts)
-						if err != nil {
-							// Log search error for debugging
-							fmt.Printf("Search error for query '%s': %v\n", query, err)
-							// Stay in search mode but clear input
-							m.textInput.SetValue("")
-						} else {
-							// Switch to search results view
-							return newSearchModel(m.engine, results, query), nil
-						}
-					}
+					// Move to the list, filtering right away if typing hasn't paused yet
 					m.searching = false
 					m.textInput.Blur()
+					query := m.textInput.Value()
+					// Only a search that already finished has been timed
+					var took time.Duration
+					if query == m.filterQuery {
+						took = m.filterTook
+					}
+					logSearch(m.engine, query, took)
+					switch {
+					case query == "":
+						cmds = append(cmds, m.clearFilter())
+					case query != m.filterQuery && !m.inFlight:
+						cmds = append(cmds, m.startSearch(query))
+					}
 				case keyEsc:
+					if m.inFlight {
+						// Cancel the running search but keep the query for editing
+						m.stopSearch()
+						break
+					}
 					m.searching = false
-					m.textInput.SetValue("")
 					m.textInput.Blur()
+					cmds = append(cmds, m.clearFilter())
+				case "up", "down":
+					// Move through the list without leaving the query bar
+					list, cmd := m.list.Update(msg)
+					m.list = list
+					cmds = append(cmds, cmd)
 				default:
+					before := m.textInput.Value()
 					ti, cmd := m.textInput.Update(msg)
 					m.textInput = ti
 					cmds = append(cmds, cmd)
+
+					// Filter once typing pauses
+					if m.textInput.Value() != before {
+						cmds = append(cmds, m.debounceSearch())
+					}
 				}
 			} else {
 				switch msg.String() {
 				case "q":
 					return m, tea.Quit
 				case "/":
+					// Edit the query bar, keeping the current query
 					m.searching = true
 					m.textInput.Focus()
+					m.textInput.CursorEnd()
 					cmds = append(cmds, textinput.Blink)
-				case keyEnter:
-					if i, ok := m.list.SelectedItem().(conversationItem); ok {
-						conv, messages, err := m.engine.GetConversation(i.conv.ID)
-						if err != nil {
-							// Log error for debugging - this will go to debug.log
-							fmt.Printf("Error loading conversation %d: %v\n", i.conv.ID, err)
-							// Could also show a temporary error message in the UI
-						} else {
-							// Create new conversation view
-							m.convView = newConversationView(conv, messages, m.width, m.height)
-							m.mode = ModeConversation
+				case keyEsc:
+					if m.filterQuery != "" || m.textInput.Value() != "" {
+						cmds = append(cmds, m.clearFilter())
+					}
+				case "s":
+					// Cycle through date, size and content-based orders
+					cmds = append(cmds, m.cycleSort())
+				case "b":
+					// Cycle through no grouping, months and tags
+					cmds = append(cmds, m.cycleGroup())
+				case "-", "+":
+					if browseGroups[m.groupIndex] != groupNone {
+						cmds = append(cmds, m.setCollapsed(msg.String() == "-"))
+					}
+				case keyEnter, " ":
+					switch i := m.list.SelectedItem().(type) {
+					case conversationItem:
+						if msg.String() == keyEnter {
+							m.openConversation(i.conv.ID)
 						}
+					case groupHeaderItem:
+						cmds = append(cmds, m.setCollapsed(!i.collapsed, i.key))
 					}
 				case "o":
 					// Open conversation in claude.ai
 					if i, ok := m.list.SelectedItem().(conversationItem); ok {
-						url := fmt.Sprintf("https://claude.ai/chat/%s", i.conv.UUID)
-						openURL(url)
+						cmds = append(cmds, openURL(claudeURL(m.engine, i.conv), i18n.T("the conversation")))
 					}
 				case "g":
 					// Jump to beginning
 					m.list.Select(0)
-				case "G":
-					// Jump to end
-					m.list.Select(len(m.conversations) - 1)
+				case "G", "end":
+					// Jump to end, loading the rest of the list first
+					if m.filterQuery == "" && len(m.conversations) < m.total {
+						m.selectLast = true
+						cmds = append(cmds, m.loadRest())
+					}
+					m.list.Select(len(m.list.Items()) - 1)
 				case "home":
 					// Jump to beginning
 					m.list.Select(0)
-				case "end":
-					// Jump to end
-					m.list.Select(len(m.conversations) - 1)
 				case "pgup":
 					// Page up
 					current := m.list.Index()
@@ -220,8 +733,8 @@ func (m browseModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					current := m.list.Index()
 					pageSize := m.height - 5
 					newIndex := current + pageSize
-					if newIndex >= len(m.conversations) {
-						newIndex = len(m.conversations) - 1
+					if newIndex >= len(m.list.Items()) {
+						newIndex = len(m.list.Items()) - 1
 					}
 					m.list.Select(newIndex)
 				// Removed custom 'down'/'j' and 'up'/'k' handlers
@@ -233,10 +746,14 @@ func (m browseModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 			}
 
+			// Load more as the selection nears the end of the list
+			cmds = append(cmds, m.loadMore())
+
 		case ModeConversation:
 			// Store the previous states
 			wasInArtifactMode := m.convView.focusedOnArtifact
 			wasInFindMode := m.convView.findActive
+			wasInSubMode := m.convView.handlesEsc()
 
 			// Delegate all conversation handling to convView
 			cv, cmd := m.convView.Update(msg)
@@ -259,6 +776,10 @@ func (m browseModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					// Don't exit conversation mode - just return
 					return m, tea.Batch(cmds...)
 				}
+				// Cleanup mode and the export picker handle esc themselves
+				if wasInSubMode {
+					return m, tea.Batch(cmds...)
+				}
 				// Only exit if not in find mode and not in artifact focus mode
 				if !m.convView.findActive && !m.convView.focusedOnArtifact {
 					m.mode = ModeList
@@ -271,23 +792,49 @@ func (m browseModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, tea.Batch(cmds...)
 }
 
+// openConversation shows a conversation in the conversation view
+func (m *browseModel) openConversation(id int64) {
+	conv, messages, err := m.engine.GetConversation(id)
+	if err != nil {
+		// Log error for debugging - this will go to debug.log
+		fmt.Printf("Error loading conversation %d: %v\n", id, err)
+		// Could also show a temporary error message in the UI
+		return
+	}
+	m.convView = newConversationView(m.engine, conv, messages, m.width, m.height)
+	m.mode = ModeConversation
+	logAccess(m.engine, conv.ID, search.AccessView)
+}
+
 // View renders the view
 func (m browseModel) View() string {
 	switch m.mode {
 	case ModeList:
-		// Search bar
-		searchBar := ""
-		if m.searching {
-			searchBar = TitleStyle.Render("Search: ") + m.textInput.View() + "\n"
-		} else {
-			searchBar = HelpStyle.Render("Press / to search") + "\n"
+		// The query bar is always shown, filtering the list as you type
+		searchBar := TitleStyle.Render("Search: ") + m.textInput.View()
+		if m.inFlight {
+			searchBar += " " + m.spinner.View() + HelpStyle.Render(i18n.Hints("searching... (esc to cancel)"))
+		} else if m.searchErr != "" {
+			searchBar += " " + HelpStyle.Render(i18n.Tf("error: %s", m.searchErr))
+		} else if m.loadErr != "" {
+			searchBar += " " + HelpStyle.Render(i18n.Tf("failed to load conversations: %s", m.loadErr))
+		} else if m.loading && len(m.conversations) == 0 {
+			searchBar += " " + m.spinner.View() + HelpStyle.Render(i18n.Hints("loading conversations..."))
 		}
+		searchBar += "\n"
 
 		// List
 		content := m.list.View()
 
 		// Help
-		help := HelpStyle.Render("↑/↓/j/k: navigate • g/G: top/bottom • PgUp/PgDn: page • enter: view • o: open in claude.ai • /: search • q: quit")
+		help := HelpStyle.Render(i18n.Hints("↑/↓/j/k: navigate • g/G: top/bottom • PgUp/PgDn: page • enter: view • o: open in claude.ai • /: search • s: sort • b: group • ctrl+k: commands • q: quit"))
+		if m.searching {
+			help = HelpStyle.Render(i18n.Hints("type to filter • ↑/↓: navigate • enter: go to list • esc: clear"))
+		} else if browseGroups[m.groupIndex] != groupNone {
+			help = HelpStyle.Render(i18n.Hints("↑/↓/j/k: navigate • enter: view or collapse/expand • -/+: collapse/expand all • o: open in claude.ai • /: search • s: sort • b: group • ctrl+k: commands • q: quit"))
+		} else if m.filterQuery != "" {
+			help = HelpStyle.Render(i18n.Hints("↑/↓/j/k: navigate • enter: view • o: open in claude.ai • /: edit search • esc: clear search • s: sort • b: group • ctrl+k: commands • q: quit"))
+		}
 
 		return searchBar + content + "\n" + help
 
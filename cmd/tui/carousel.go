@@ -0,0 +1,149 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/neilberkman/shannon/internal/clipboard"
+	"github.com/neilberkman/shannon/internal/config"
+	"github.com/neilberkman/shannon/internal/i18n"
+	"github.com/neilberkman/shannon/internal/search"
+)
+
+// carouselModel shows one conversation of a list at a time and flips to the
+// next or previous one with ] and [
+type carouselModel struct {
+	engine   *search.Engine
+	title    string
+	ids      []int64
+	index    int
+	convView conversationView
+	err      error
+	width    int
+	height   int
+}
+
+func newCarouselModel(engine *search.Engine, title string, ids []int64) carouselModel {
+	m := carouselModel{
+		engine: engine,
+		title:  title,
+		ids:    ids,
+		width:  80,
+		height: 24,
+	}
+	m.load()
+	return m
+}
+
+// load opens the conversation at the current index
+func (m *carouselModel) load() {
+	conv, messages, err := m.engine.GetConversation(m.ids[m.index])
+	if err != nil {
+		m.err = fmt.Errorf("failed to load conversation %d: %w", m.ids[m.index], err)
+		return
+	}
+	m.err = nil
+	// The carousel header takes one line
+	m.convView = newConversationView(m.engine, conv, messages, m.width, m.height-1)
+	logAccess(m.engine, conv.ID, search.AccessView)
+}
+
+func (m carouselModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m carouselModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		if m.err != nil {
+			return m, nil
+		}
+		cv, cmd := m.convView.Update(tea.WindowSizeMsg{Width: msg.Width, Height: msg.Height - 1})
+		m.convView = cv
+		return m, cmd
+
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+
+		// Let the find input and sub-modes have every key
+		if !m.convView.findActive && !m.convView.handlesEsc() {
+			switch msg.String() {
+			case "]":
+				if m.index < len(m.ids)-1 {
+					m.index++
+					m.load()
+				}
+				return m, nil
+			case "[":
+				if m.index > 0 {
+					m.index--
+					m.load()
+				}
+				return m, nil
+			}
+		}
+
+		if m.err != nil {
+			if msg.String() == "q" || msg.String() == "esc" {
+				return m, tea.Quit
+			}
+			return m, nil
+		}
+
+		wasInArtifactMode := m.convView.focusedOnArtifact
+		wasInFindMode := m.convView.findActive
+		wasInSubMode := m.convView.handlesEsc()
+
+		cv, cmd := m.convView.Update(msg)
+		m.convView = cv
+
+		switch msg.String() {
+		case "q":
+			if !wasInFindMode {
+				return m, tea.Quit
+			}
+		case "esc":
+			// Esc leaves artifact focus, find and sub-modes before quitting
+			if !wasInArtifactMode && !wasInFindMode && !wasInSubMode {
+				return m, tea.Quit
+			}
+		}
+		return m, cmd
+	}
+
+	if m.err != nil {
+		return m, nil
+	}
+	cv, cmd := m.convView.Update(msg)
+	m.convView = cv
+	return m, cmd
+}
+
+func (m carouselModel) View() string {
+	header := TitleStyle.Render(m.title) +
+		HelpStyle.Render(fmt.Sprintf(" %d/%d • ", m.index+1, len(m.ids))+i18n.Hints("]: next • [: previous"))
+	if m.err != nil {
+		return header + "\n\n" + m.err.Error() + "\n\n" + HelpStyle.Render(i18n.Hints("]: next • [: previous • q: quit"))
+	}
+	return header + "\n" + m.convView.View()
+}
+
+// RunCarousel opens the TUI on the first of ids, flipping through the rest
+// with ] and [
+func RunCarousel(engine *search.Engine, title string, ids []int64) error {
+	if len(ids) == 0 {
+		return fmt.Errorf("no conversations to show")
+	}
+
+	if err := clipboard.Init(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: clipboard initialization failed: %v\n", err)
+	}
+	applyConfig(config.Get())
+
+	return runProgram(newCarouselModel(engine, title, ids))
+}
@@ -0,0 +1,264 @@
+package tui
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dustin/go-humanize"
+	"github.com/neilberkman/shannon/internal/cleanup"
+	"github.com/neilberkman/shannon/internal/i18n"
+	"github.com/neilberkman/shannon/internal/models"
+	"github.com/neilberkman/shannon/internal/rendering"
+)
+
+// cleanupKeepLines is how many lines truncating a message from the TUI keeps
+const cleanupKeepLines = 20
+
+// startCleanup enters cleanup mode with the message at the top of the screen selected
+func (cv *conversationView) startCleanup() {
+	if cv.engine == nil || len(cv.messages) == 0 {
+		return
+	}
+
+	cv.focusedOnArtifact = false
+	cv.cleanupActive = true
+	cv.cleanupPending = ""
+	cv.cleanupIndex = 0
+	if top, _ := cv.topMessage(); top >= 0 {
+		cv.cleanupIndex = top
+	}
+	cv.updateContent()
+	cv.scrollToCleanupMessage()
+}
+
+// updateCleanup handles keys while selecting messages to clean up
+func (cv *conversationView) updateCleanup(msg tea.KeyMsg) tea.Cmd {
+	if cv.cleanupPending != "" {
+		action := cv.cleanupPending
+		cv.cleanupPending = ""
+		if msg.String() == "y" {
+			return cv.applyCleanup(action)
+		}
+		return cv.notify(i18n.T("Cancelled"))
+	}
+
+	switch msg.String() {
+	case "n", "j", "down":
+		if cv.cleanupIndex < len(cv.messages)-1 {
+			cv.cleanupIndex++
+			cv.updateContent()
+			cv.scrollToCleanupMessage()
+		}
+	case "N", "k", "up":
+		if cv.cleanupIndex > 0 {
+			cv.cleanupIndex--
+			cv.updateContent()
+			cv.scrollToCleanupMessage()
+		}
+	case "L":
+		// Jump to the largest message, usually the pasted log
+		for i, m := range cv.messages {
+			if len(m.Text) > len(cv.messages[cv.cleanupIndex].Text) {
+				cv.cleanupIndex = i
+			}
+		}
+		cv.updateContent()
+		cv.scrollToCleanupMessage()
+	case "d":
+		cv.cleanupPending = cleanup.ActionDelete
+	case "t":
+		if _, ok := cleanup.Truncate(cv.messages[cv.cleanupIndex].Text, cleanup.TruncateOptions{KeepLines: cleanupKeepLines}); !ok {
+			return cv.notify(i18n.Tf("Message is already %d lines or shorter", cleanupKeepLines))
+		}
+		cv.cleanupPending = cleanup.ActionTruncate
+	case "u":
+		return cv.undoCleanup()
+	case "esc", "x":
+		cv.cleanupActive = false
+		cv.updateContent()
+	default:
+		vp, cmd := cv.viewport.Update(msg)
+		cv.viewport = vp
+		cv.loadMore()
+		return cmd
+	}
+	return nil
+}
+
+// applyCleanup deletes or truncates the selected message
+func (cv *conversationView) applyCleanup(action string) tea.Cmd {
+	msg := cv.messages[cv.cleanupIndex]
+
+	var edit *models.MessageEdit
+	var err error
+	if action == cleanup.ActionDelete {
+		edit, err = cleanup.DeleteMessage(cv.engine.DB(), msg.ID)
+	} else {
+		edit, err = cleanup.TruncateMessage(cv.engine.DB(), msg.ID, cleanup.TruncateOptions{KeepLines: cleanupKeepLines})
+	}
+	if errors.Is(err, cleanup.ErrNothingToTruncate) {
+		return cv.notify(i18n.Tf("Message is already %d lines or shorter", cleanupKeepLines))
+	} else if err != nil {
+		return cv.notify(i18n.Tf("Error: %v", err))
+	}
+
+	cv.cleanupEdits = append(cv.cleanupEdits, edit.ID)
+	if err := cv.reloadMessages(msg.ID); err != nil {
+		return cv.notify(i18n.Tf("Error: %v", err))
+	}
+
+	notice := "✓ Deleted message (%s) • u: undo"
+	if action == cleanup.ActionTruncate {
+		notice = "✓ Truncated message (%s) • u: undo"
+	}
+	return cv.notify(i18n.Tf(notice, humanize.Bytes(uint64(edit.OriginalSize))))
+}
+
+// undoCleanup reverts the most recent change made from this view
+func (cv *conversationView) undoCleanup() tea.Cmd {
+	if len(cv.cleanupEdits) == 0 {
+		return cv.notify(i18n.T("Nothing to undo"))
+	}
+
+	editID := cv.cleanupEdits[len(cv.cleanupEdits)-1]
+	edit, err := cleanup.Undo(cv.engine.DB(), editID)
+	if err != nil {
+		return cv.notify(i18n.Tf("Error: %v", err))
+	}
+	cv.cleanupEdits = cv.cleanupEdits[:len(cv.cleanupEdits)-1]
+
+	if err := cv.reloadMessages(edit.MessageID); err != nil {
+		return cv.notify(i18n.Tf("Error: %v", err))
+	}
+	return cv.notify(i18n.T("✓ Restored message"))
+}
+
+// reloadMessages reloads the conversation after a change, keeping the
+// selection on messageID if it still exists
+func (cv *conversationView) reloadMessages(messageID int64) error {
+	if err := cv.reload(); err != nil {
+		return err
+	}
+
+	for i, m := range cv.messages {
+		if m.ID == messageID {
+			cv.cleanupIndex = i
+			break
+		}
+	}
+	if cv.cleanupIndex >= len(cv.messages) {
+		cv.cleanupIndex = len(cv.messages) - 1
+	}
+	if len(cv.messages) == 0 {
+		cv.cleanupActive = false
+	}
+
+	cv.updateContent()
+	cv.scrollToCleanupMessage()
+	return nil
+}
+
+// reload reads the conversation and its messages again after a change
+func (cv *conversationView) reload() error {
+	conv, messages, err := cv.engine.GetConversation(cv.conversation.ID)
+	if err != nil {
+		return err
+	}
+	cv.conversation = conv
+	cv.messages = messages
+	cv.collapseRepeats()
+	cv.extractArtifacts()
+	return nil
+}
+
+// cleanupHelp renders the status line shown in cleanup mode
+func (cv conversationView) cleanupHelp() string {
+	msg := cv.messages[cv.cleanupIndex]
+	size := humanize.Bytes(uint64(len(msg.Text)))
+
+	switch cv.cleanupPending {
+	case cleanup.ActionDelete:
+		return CleanupMarkerStyle.Render(i18n.Tf("Delete message %d (%s)? y: confirm • any other key: cancel", cv.cleanupIndex+1, size))
+	case cleanup.ActionTruncate:
+		return CleanupMarkerStyle.Render(i18n.Tf("Truncate message %d (%s) to %d lines? y: confirm • any other key: cancel", cv.cleanupIndex+1, size, cleanupKeepLines))
+	}
+
+	status := i18n.Tf("Cleanup: message %d/%d • %s • %s, %d lines",
+		cv.cleanupIndex+1, len(cv.messages), rendering.FormatSender(msg.Sender), size, strings.Count(msg.Text, "\n")+1)
+	return HelpStyle.Render(status + " • " + i18n.Hints("n/N: select • L: largest • d: delete • t: truncate • u: undo • esc: done"))
+}
+
+// markCleanupMessage adds a marker in front of the selected message's header
+func (cv conversationView) markCleanupMessage(content string) string {
+	start, _ := cv.window()
+	offsets := cv.messageOffsets(content)
+	if cv.cleanupIndex < start || cv.cleanupIndex-start >= len(offsets) {
+		return content
+	}
+
+	lines := strings.Split(content, "\n")
+	line := offsets[cv.cleanupIndex-start]
+	lines[line] = CleanupMarkerStyle.Render("▶ ") + lines[line]
+	return strings.Join(lines, "\n")
+}
+
+// scrollToCleanupMessage scrolls the viewport to the selected message's header
+func (cv *conversationView) scrollToCleanupMessage() {
+	cv.scrollToHeader(cv.cleanupIndex)
+}
+
+// scrollToHeader scrolls the viewport to the header of message i
+func (cv *conversationView) scrollToHeader(i int) {
+	if i < 0 {
+		return
+	}
+	cv.showMessage(i)
+	start, _ := cv.window()
+	offsets := cv.messageOffsets(cv.renderContent())
+	if i-start < len(offsets) {
+		cv.viewport.SetYOffset(offsets[i-start])
+	}
+}
+
+// messageOffsets returns the line of each rendered message's header in the
+// rendered conversation, matching the headers in message order. The first
+// offset is that of the first message in the window.
+func (cv conversationView) messageOffsets(content string) []int {
+	start, end := cv.window()
+	headers := make([]string, 0, end-start)
+	for _, msg := range cv.messages[start:end] {
+		headers = append(headers, messageHeader(msg))
+	}
+	return newLineIndex(content).findInOrder(headers)
+}
+
+// renderContent renders the window of the conversation without find
+// highlighting or markers, reusing what was rendered before where possible
+func (cv conversationView) renderContent() string {
+	if cv.renders == nil {
+		return newRenderCache().render(cv.conversation, cv.lineage, cv.displayed(), 0, len(cv.displayed()), cv.artifacts, cv.width, cv.focusedOnArtifact, cv.messageIndex, cv.artifactIndex, cv.expandedArtifacts)
+	}
+	start, end := cv.window()
+	return cv.renders.render(cv.conversation, cv.lineage, cv.displayed(), start, end, cv.artifacts, cv.width, cv.focusedOnArtifact, cv.messageIndex, cv.artifactIndex, cv.expandedArtifacts)
+}
+
+// renderMessageAt renders message i as it appears in the conversation
+func (cv conversationView) renderMessageAt(i int) string {
+	renders := cv.renders
+	if renders == nil {
+		renders = newRenderCache()
+	}
+	rendered, _ := renders.message(cv.displayed(), i, cv.artifacts, cv.width, cv.focusedOnArtifact, cv.messageIndex, cv.artifactIndex, cv.expandedArtifacts)
+	return rendered
+}
+
+// notify shows a short notification and starts its timer
+func (cv *conversationView) notify(text string) tea.Cmd {
+	cv.notification = text
+	cv.notificationTimer = 20 // 2 seconds
+	return tea.Tick(time.Millisecond*100, func(time.Time) tea.Msg {
+		return tickMsg{}
+	})
+}
@@ -0,0 +1,228 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/neilberkman/shannon/internal/clipboard"
+	"github.com/neilberkman/shannon/internal/config"
+	"github.com/neilberkman/shannon/internal/i18n"
+	"github.com/neilberkman/shannon/internal/models"
+	"github.com/neilberkman/shannon/internal/search"
+)
+
+// ClusterGroup is a topic and the conversations about it, closest first
+type ClusterGroup struct {
+	Label         string
+	Conversations []*models.Conversation
+}
+
+// clusterItem implements list.Item for topic clusters
+type clusterItem struct {
+	group ClusterGroup
+}
+
+func (i clusterItem) Title() string {
+	return i.group.Label
+}
+
+func (i clusterItem) Description() string {
+	var names []string
+	for _, conv := range i.group.Conversations[:min(3, len(i.group.Conversations))] {
+		names = append(names, conv.Name)
+	}
+	return pluralize(len(i.group.Conversations), "conversation", "conversations") + " • " + strings.Join(names, " • ")
+}
+
+func (i clusterItem) FilterValue() string {
+	return i.group.Label
+}
+
+// Levels of the cluster browser
+const (
+	clusterLevelTopics = iota
+	clusterLevelConversations
+	clusterLevelConversation
+)
+
+// clusterModel browses conversations by topic: a list of clusters, the
+// conversations of the chosen one, and the chosen conversation
+type clusterModel struct {
+	engine        *search.Engine
+	level         int
+	clusters      list.Model
+	conversations list.Model
+	convView      conversationView
+	width         int
+	height        int
+}
+
+func newClusterModel(engine *search.Engine, groups []ClusterGroup) clusterModel {
+	items := make([]list.Item, len(groups))
+	for i, group := range groups {
+		items[i] = clusterItem{group: group}
+	}
+
+	clusters := list.New(items, newSnippetDelegate(false), 80, 22)
+	clusters.Title = fmt.Sprintf("Topics (%d)", len(groups))
+	clusters.SetShowHelp(false)
+	clusters.DisableQuitKeybindings()
+
+	conversations := list.New(nil, newSnippetDelegate(false), 80, 22)
+	conversations.SetShowHelp(false)
+	conversations.DisableQuitKeybindings()
+
+	return clusterModel{
+		engine:        engine,
+		clusters:      clusters,
+		conversations: conversations,
+		width:         80,
+		height:        24,
+	}
+}
+
+func (m clusterModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m clusterModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.clusters.SetSize(msg.Width, msg.Height-2)
+		m.conversations.SetSize(msg.Width, msg.Height-2)
+		if m.level == clusterLevelConversation {
+			cv, cmd := m.convView.Update(msg)
+			m.convView = cv
+			return m, cmd
+		}
+		return m, nil
+
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+
+		switch m.level {
+		case clusterLevelTopics:
+			if m.clusters.FilterState() == list.Filtering {
+				break
+			}
+			switch msg.String() {
+			case "q", "esc":
+				if m.clusters.FilterState() == list.FilterApplied && msg.String() == "esc" {
+					break
+				}
+				return m, tea.Quit
+			case "enter":
+				if item, ok := m.clusters.SelectedItem().(clusterItem); ok {
+					items := make([]list.Item, len(item.group.Conversations))
+					for i, conv := range item.group.Conversations {
+						items[i] = conversationItem{conv: conv}
+					}
+					m.conversations.Title = item.group.Label
+					m.conversations.ResetFilter()
+					m.conversations.Select(0)
+					cmd := m.conversations.SetItems(items)
+					m.level = clusterLevelConversations
+					return m, cmd
+				}
+				return m, nil
+			}
+			l, cmd := m.clusters.Update(msg)
+			m.clusters = l
+			return m, cmd
+
+		case clusterLevelConversations:
+			if m.conversations.FilterState() == list.Filtering {
+				break
+			}
+			switch msg.String() {
+			case "q":
+				return m, tea.Quit
+			case "esc":
+				if m.conversations.FilterState() != list.FilterApplied {
+					m.level = clusterLevelTopics
+					return m, nil
+				}
+			case "enter":
+				if item, ok := m.conversations.SelectedItem().(conversationItem); ok {
+					conv, messages, err := m.engine.GetConversation(item.conv.ID)
+					if err != nil {
+						return m, nil
+					}
+					m.convView = newConversationView(m.engine, conv, messages, m.width, m.height)
+					m.level = clusterLevelConversation
+					logAccess(m.engine, conv.ID, search.AccessView)
+				}
+				return m, nil
+			}
+			l, cmd := m.conversations.Update(msg)
+			m.conversations = l
+			return m, cmd
+
+		case clusterLevelConversation:
+			wasInArtifactMode := m.convView.focusedOnArtifact
+			wasInFindMode := m.convView.findActive
+			wasInSubMode := m.convView.handlesEsc()
+
+			cv, cmd := m.convView.Update(msg)
+			m.convView = cv
+
+			switch msg.String() {
+			case "q":
+				if !wasInFindMode {
+					m.level = clusterLevelConversations
+					return m, nil
+				}
+			case "esc":
+				// Esc leaves artifact focus, find and sub-modes before going back
+				if !wasInArtifactMode && !wasInFindMode && !wasInSubMode {
+					m.level = clusterLevelConversations
+					return m, nil
+				}
+			}
+			return m, cmd
+		}
+	}
+
+	// Everything else, including typing into a list filter
+	var cmd tea.Cmd
+	switch m.level {
+	case clusterLevelTopics:
+		m.clusters, cmd = m.clusters.Update(msg)
+	case clusterLevelConversations:
+		m.conversations, cmd = m.conversations.Update(msg)
+	case clusterLevelConversation:
+		m.convView, cmd = m.convView.Update(msg)
+	}
+	return m, cmd
+}
+
+func (m clusterModel) View() string {
+	switch m.level {
+	case clusterLevelConversations:
+		return m.conversations.View() + "\n" + HelpStyle.Render(i18n.Hints("↑/↓: navigate • enter: view • /: filter • esc: topics • q: quit"))
+	case clusterLevelConversation:
+		return m.convView.View()
+	}
+	return m.clusters.View() + "\n" + HelpStyle.Render(i18n.Hints("↑/↓: navigate • enter: conversations • /: filter • q: quit"))
+}
+
+// RunClusters opens the TUI on a list of topic clusters, to browse their
+// conversations
+func RunClusters(engine *search.Engine, groups []ClusterGroup) error {
+	if len(groups) == 0 {
+		return fmt.Errorf("no clusters to show")
+	}
+
+	if err := clipboard.Init(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: clipboard initialization failed: %v\n", err)
+	}
+	applyConfig(config.Get())
+
+	return runProgram(newClusterModel(engine, groups))
+}
@@ -2,6 +2,7 @@ package tui
 
 import (
 	"fmt"
+	"log"
 	"os"
 	"strings"
 	"time"
@@ -12,15 +13,19 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	"github.com/neilberkman/shannon/internal/artifacts"
 	"github.com/neilberkman/shannon/internal/export"
+	"github.com/neilberkman/shannon/internal/i18n"
 	"github.com/neilberkman/shannon/internal/models"
+	"github.com/neilberkman/shannon/internal/search"
 )
 
 // conversationView handles the display and interaction for a single conversation
 // This is shared by both browse and search models to ensure consistent behavior
 type conversationView struct {
+	engine       *search.Engine
 	viewport     viewport.Model
 	textInput    textinput.Model
 	conversation *models.Conversation
+	lineage      *search.Lineage // the conversations it continues and is continued in
 	messages     []*models.Message
 	width        int
 	height       int
@@ -28,8 +33,8 @@ type conversationView struct {
 	// Find functionality
 	findQuery    string
 	findActive   bool
-	findMatches  []int // line numbers that match the find query
-	currentMatch int   // current match index
+	findMatches  []findMatch // occurrences of the find query
+	currentMatch int         // current match index
 
 	// Artifact support
 	artifacts         map[int64][]*artifacts.Artifact // message ID -> artifacts
@@ -38,19 +43,55 @@ type conversationView struct {
 	messageIndex      int             // which message we're viewing artifacts for
 	expandedArtifacts map[string]bool // artifact ID -> expanded state
 
+	// Rendered messages, shared by copies of the view
+	renders *renderCache
+
+	// The messages rendered, for conversations too long to render whole
+	windowStart int
+	windowEnd   int
+
+	// Cleanup support
+	cleanupActive  bool
+	cleanupIndex   int     // which message is selected for cleanup
+	cleanupPending string  // action awaiting confirmation
+	cleanupEdits   []int64 // edits made in this view, newest last
+
+	// Split support
+	splitActive  bool
+	splitIndex   int            // which message is selected
+	splitMarks   map[int64]bool // message IDs that will start new conversations
+	splitPending bool           // split awaiting confirmation
+
+	// Export picker
+	exportActive bool
+	exportFormat int // index into export.Formats
+
+	// Artifact copy format picker
+	copyActive bool
+	copyFormat int // index into artifacts.CopyFormats
+
+	// Repeated content collapsed into placeholders
+	collapseQuotes bool
+	collapsed      []*models.Message // messages with repeats collapsed, when on
+
+	// Code blocks over codeCollapseLines collapsed into placeholders
+	collapseCode  bool
+	codeCollapsed []*models.Message // shown messages with long code collapsed, when on
+
 	// Notification support
 	notification      string
 	notificationTimer int // frames until notification disappears
 }
 
 // newConversationView creates a new conversation view
-func newConversationView(conv *models.Conversation, messages []*models.Message, width, height int) conversationView {
+func newConversationView(engine *search.Engine, conv *models.Conversation, messages []*models.Message, width, height int) conversationView {
 	ti := textinput.New()
 	ti.Placeholder = "Find in conversation..."
 	ti.CharLimit = 100
 	ti.Width = 50
 
 	cv := conversationView{
+		engine:            engine,
 		viewport:          viewport.New(width, height-3),
 		textInput:         ti,
 		conversation:      conv,
@@ -59,10 +100,19 @@ func newConversationView(conv *models.Conversation, messages []*models.Message,
 		height:            height,
 		artifacts:         make(map[int64][]*artifacts.Artifact),
 		expandedArtifacts: make(map[string]bool),
+		renders:           newRenderCache(),
+	}
+
+	// The header goes without the links if they can't be loaded
+	if engine != nil && conv != nil {
+		if lineage, err := engine.GetLineage(conv.ID); err == nil {
+			cv.lineage = lineage
+		}
 	}
 
 	// Extract artifacts on creation
 	cv.extractArtifacts()
+	cv.windowEnd = renderChunk
 
 	// Set initial content
 	cv.updateContent()
@@ -76,6 +126,12 @@ func (cv conversationView) Init() tea.Cmd {
 	return nil
 }
 
+// handlesEsc reports whether the view is in a mode that consumes esc itself,
+// so the parent model shouldn't treat it as going back
+func (cv conversationView) handlesEsc() bool {
+	return cv.cleanupActive || cv.splitActive || cv.exportActive || cv.copyActive
+}
+
 // tickMsg is sent to update the notification timer
 type tickMsg struct{}
 
@@ -100,6 +156,18 @@ func (cv conversationView) Update(msg tea.Msg) (conversationView, tea.Cmd) {
 	case tickMsg:
 		// Handled above
 
+	case urlOpenedMsg:
+		// Only seen outside the main TUI, which shows it itself
+		cv.notification = msg.String()
+		cv.notificationTimer = 30 // 3 seconds
+		cmds = append(cmds, tea.Tick(time.Millisecond*100, func(time.Time) tea.Msg {
+			return tickMsg{}
+		}))
+
+	case artifactCopiedMsg:
+		// Only seen outside the main TUI, which shows it itself
+		cmds = append(cmds, cv.notify(msg.String()))
+
 	case tea.WindowSizeMsg:
 		cv.width = msg.Width
 		cv.height = msg.Height
@@ -108,7 +176,15 @@ func (cv conversationView) Update(msg tea.Msg) (conversationView, tea.Cmd) {
 		cv.updateContent()
 
 	case tea.KeyMsg:
-		if cv.findActive {
+		if cv.exportActive {
+			cmds = append(cmds, cv.updateExport(msg))
+		} else if cv.copyActive {
+			cmds = append(cmds, cv.updateCopy(msg))
+		} else if cv.cleanupActive {
+			cmds = append(cmds, cv.updateCleanup(msg))
+		} else if cv.splitActive {
+			cmds = append(cmds, cv.updateSplit(msg))
+		} else if cv.findActive {
 			switch msg.String() {
 			case "enter":
 				if cv.textInput.Value() != "" {
@@ -117,9 +193,7 @@ func (cv conversationView) Update(msg tea.Msg) (conversationView, tea.Cmd) {
 					cv.currentMatch = 0
 					// Update content to show highlights
 					cv.updateContent()
-					if len(cv.findMatches) > 0 {
-						cv.viewport.SetYOffset(cv.findMatches[0])
-					}
+					cv.scrollToMatch()
 				}
 				cv.findActive = false
 				cv.textInput.Blur()
@@ -152,7 +226,7 @@ func (cv conversationView) Update(msg tea.Msg) (conversationView, tea.Cmd) {
 				} else if len(cv.findMatches) > 0 {
 					// Next search match
 					cv.currentMatch = (cv.currentMatch + 1) % len(cv.findMatches)
-					cv.viewport.SetYOffset(cv.findMatches[cv.currentMatch])
+					cv.scrollToMatch()
 				}
 			case "N":
 				if cv.focusedOnArtifact {
@@ -163,12 +237,32 @@ func (cv conversationView) Update(msg tea.Msg) (conversationView, tea.Cmd) {
 				} else if len(cv.findMatches) > 0 {
 					// Previous search match
 					cv.currentMatch = (cv.currentMatch - 1 + len(cv.findMatches)) % len(cv.findMatches)
-					cv.viewport.SetYOffset(cv.findMatches[cv.currentMatch])
+					cv.scrollToMatch()
 				}
 			case "g":
+				if start, _ := cv.window(); start > 0 {
+					cv.moveWindow(0)
+				}
 				cv.viewport.GotoTop()
 			case "G":
+				if _, end := cv.window(); end < len(cv.messages) {
+					cv.moveWindow(len(cv.messages))
+				}
 				cv.viewport.GotoBottom()
+			case "{", "}":
+				// Jump along the timeline to the previous or next day with messages
+				dir := 1
+				if msg.String() == "{" {
+					dir = -1
+				}
+				cmds = append(cmds, cv.jumpTimeline(dir))
+			case "[", "]":
+				// Jump to the previous or next message you sent
+				dir := 1
+				if msg.String() == "[" {
+					dir = -1
+				}
+				cmds = append(cmds, cv.jumpQuestion(dir))
 			case "a":
 				// Enter artifact focus mode
 				if len(cv.artifacts) > 0 && !cv.focusedOnArtifact {
@@ -218,23 +312,46 @@ func (cv conversationView) Update(msg tea.Msg) (conversationView, tea.Cmd) {
 					return tickMsg{}
 				}))
 			case "c":
-				// Copy current artifact to clipboard if focused
+				// Pick a format and copy the current artifact if focused
 				if cv.focusedOnArtifact {
-					cv.copyCurrentArtifact()
+					cv.startCopy()
+				}
+			case "e":
+				// Pick a format and export the conversation
+				cv.startExport()
+			case "z":
+				// Collapse content repeated from earlier messages
+				cmds = append(cmds, cv.toggleCollapse())
+			case "C":
+				// Collapse long code blocks
+				cmds = append(cmds, cv.toggleCodeCollapse())
+			case "x":
+				// Select messages to delete or truncate
+				cv.startCleanup()
+			case "p":
+				// Mark where to split the conversation into parts
+				cv.startSplit()
+			case "0", "1", "2", "3":
+				// Rate the first answer on screen
+				cmds = append(cmds, cv.rateAnswer(msg.String()))
+			case "o":
+				// Render HTML, SVG and React artifacts in the browser
+				if cv.focusedOnArtifact && cv.currentArtifact() != nil && cv.currentArtifact().CanOpenInBrowser() {
+					cmds = append(cmds, cv.openCurrentArtifact())
 					cmds = append(cmds, tea.Tick(time.Millisecond*100, func(time.Time) tea.Msg {
 						return tickMsg{}
 					}))
+					break
 				}
-			case "o":
 				// Open conversation in Claude web interface
 				if cv.conversation != nil && cv.conversation.UUID != "" {
-					url := fmt.Sprintf("https://claude.ai/chat/%s", cv.conversation.UUID)
-					openURL(url)
+					cmds = append(cmds, openURL(claudeURL(cv.engine, cv.conversation), i18n.T("the conversation")))
 				}
 			default:
 				// Handle viewport scrolling
 				vp, cmd := cv.viewport.Update(msg)
 				cv.viewport = vp
+				cv.loadMore()
 				cmds = append(cmds, cmd)
 			}
 		}
@@ -253,25 +370,37 @@ func (cv conversationView) View() string {
 		findBar = TitleStyle.Render("Find: ") + cv.textInput.View() + "\n"
 	} else if cv.findQuery != "" {
 		if len(cv.findMatches) > 0 {
-			findBar = HelpStyle.Render(fmt.Sprintf("Found %d matches for '%s' • Match %d/%d • n: next • N: prev",
-				len(cv.findMatches), cv.findQuery, cv.currentMatch+1, len(cv.findMatches))) + "\n"
+			findBar = HelpStyle.Render(i18n.Tf("Found %d matches for '%s' • Match %d/%d",
+				len(cv.findMatches), cv.findQuery, cv.currentMatch+1, len(cv.findMatches))+" • "+i18n.Hints("n: next • N: prev")) + "\n"
 		} else {
-			findBar = HelpStyle.Render(fmt.Sprintf("No matches found for '%s' • Press / to search again", cv.findQuery)) + "\n"
+			findBar = HelpStyle.Render(i18n.Tf("No matches found for '%s' • Press / to search again", cv.findQuery)) + "\n"
 		}
 	}
 
 	// Help text
 	var help string
-	if cv.findActive {
-		help = HelpStyle.Render("enter: search • esc: cancel")
+	if cv.exportActive {
+		help = cv.exportHelp()
+	} else if cv.copyActive {
+		help = cv.copyHelp()
+	} else if cv.cleanupActive {
+		help = cv.cleanupHelp()
+	} else if cv.splitActive {
+		help = cv.splitHelp()
+	} else if cv.findActive {
+		help = HelpStyle.Render(i18n.Hints("enter: search • esc: cancel"))
 	} else if len(cv.artifacts) > 0 {
 		if cv.focusedOnArtifact {
-			help = HelpStyle.Render("esc: exit focus • tab: expand/collapse • n/N: navigate • s: save • c: copy • o: open • q: quit")
+			open := "o: open"
+			if a := cv.currentArtifact(); a != nil && a.CanOpenInBrowser() {
+				open = "o: open in browser"
+			}
+			help = HelpStyle.Render(i18n.Hints("esc: exit focus • tab: expand/collapse • n/N: navigate • s: save • c: copy as raw/markdown/html • " + open + " • q: quit"))
 		} else {
-			help = HelpStyle.Render("↑/↓: scroll • g/G: top/bottom • /f: find • n/N: next/prev • a: focus artifact • s: save • o: open in claude.ai • esc: back • q: quit")
+			help = HelpStyle.Render(i18n.Hints("↑/↓: scroll • g/G: top/bottom • {/}: prev/next day • [/]: prev/next question • /f: find • n/N: next/prev • a: focus artifact • s: save • e: export • z: collapse repeats • C: collapse code • x: clean up • p: split • 1-3: rate • o: open in claude.ai • ctrl+k: commands • esc: back • q: quit"))
 		}
 	} else {
-		help = HelpStyle.Render("↑/↓: scroll • g/G: top/bottom • /f: find • n/N: next/prev match • s: save • o: open in claude.ai • esc: back • q: quit")
+		help = HelpStyle.Render(i18n.Hints("↑/↓: scroll • g/G: top/bottom • {/}: prev/next day • [/]: prev/next question • /f: find • n/N: next/prev match • s: save • e: export • z: collapse repeats • C: collapse code • x: clean up • p: split • 1-3: rate • o: open in claude.ai • ctrl+k: commands • esc: back • q: quit"))
 	}
 
 	// Add notification if present
@@ -296,16 +425,13 @@ func (cv conversationView) View() string {
 
 // updateContent updates the viewport content
 func (cv *conversationView) updateContent() {
-	content := RenderConversationWithArtifacts(
-		cv.conversation,
-		cv.messages,
-		cv.artifacts,
-		cv.width,
-		cv.focusedOnArtifact,
-		cv.messageIndex,
-		cv.artifactIndex,
-		cv.expandedArtifacts,
-	)
+	content := cv.renderContent()
+
+	if cv.cleanupActive {
+		content = cv.markCleanupMessage(content)
+	} else if cv.splitActive {
+		content = cv.markSplitMessages(content)
+	}
 
 	// Apply find highlighting if we have a query
 	if cv.findQuery != "" {
@@ -315,29 +441,57 @@ func (cv *conversationView) updateContent() {
 	cv.viewport.SetContent(content)
 }
 
-// findInConversation searches for a query in the conversation
-func (cv conversationView) findInConversation(query string) []int {
+// restyle renders the conversation again with the current theme
+func (cv *conversationView) restyle() {
+	cv.renders = newRenderCache()
+	cv.updateContent()
+}
+
+// findInConversation searches for a query in the conversation's messages
+func (cv conversationView) findInConversation(query string) []findMatch {
 	if cv.conversation == nil || cv.messages == nil || query == "" {
 		return nil
 	}
 
-	content := RenderConversationWithArtifacts(cv.conversation, cv.messages, cv.artifacts, cv.width, cv.focusedOnArtifact, cv.messageIndex, cv.artifactIndex, cv.expandedArtifacts)
-	lines := strings.Split(content, "\n")
-
-	var matches []int
-	queryLower := strings.ToLower(query)
+	return cv.findInMessages(query)
+}
 
-	for i, line := range lines {
-		if strings.Contains(strings.ToLower(line), queryLower) {
-			matches = append(matches, i)
-		}
+// scrollToMatch scrolls the viewport to the current find match, scrolling
+// sideways too when the match is past the right edge
+func (cv *conversationView) scrollToMatch() {
+	if cv.currentMatch < 0 || cv.currentMatch >= len(cv.findMatches) {
+		return
 	}
 
-	return matches
+	match := cv.findMatches[cv.currentMatch]
+	cv.showMessage(match.message)
+	start, _ := cv.window()
+	offsets := cv.messageOffsets(cv.renderContent())
+	if match.message-start >= len(offsets) {
+		return
+	}
+	cv.viewport.SetYOffset(offsets[match.message-start] + match.line)
+	if match.column+match.width > cv.viewport.Width {
+		cv.viewport.SetXOffset(match.column - cv.viewport.Width/2)
+	} else {
+		cv.viewport.SetXOffset(0)
+	}
 }
 
-// extractArtifacts extracts artifacts from the loaded messages
+// extractArtifacts finds the artifacts in the loaded messages, reading them
+// from the code block index when it's up to date
 func (cv *conversationView) extractArtifacts() {
+	if cv.engine != nil && cv.conversation != nil {
+		indexed, ok, err := cv.engine.ConversationArtifacts(cv.conversation.ID)
+		if err != nil {
+			log.Printf("failed to read indexed artifacts: %v", err)
+		}
+		if ok {
+			cv.artifacts = indexed
+			return
+		}
+	}
+
 	cv.artifacts = make(map[int64][]*artifacts.Artifact)
 	extractor := artifacts.NewExtractor()
 
@@ -410,35 +564,35 @@ func (cv *conversationView) moveToPreviousArtifact() {
 
 // scrollToFocusedArtifact scrolls the viewport to show the currently focused artifact
 func (cv *conversationView) scrollToFocusedArtifact() {
-	// Get the rendered content to find exact line positions
-	content := RenderConversationWithArtifacts(cv.conversation, cv.messages, cv.artifacts, cv.width, cv.focusedOnArtifact, cv.messageIndex, cv.artifactIndex, cv.expandedArtifacts)
-	lines := strings.Split(content, "\n")
-
-	// Find the current artifact by looking for the focused indicator
-	artifactCount := 0
-	targetArtifactIndex := cv.getTotalArtifactIndex()
-
-	for i, line := range lines {
-		// Look for artifact headers - they are inside a box and contain "┌─" with an emoji and title
-		trimmed := strings.TrimSpace(line)
-		if strings.Contains(trimmed, "┌─") && (strings.Contains(trimmed, "📄") || strings.Contains(trimmed, "💻") || strings.Contains(trimmed, "🌐") || strings.Contains(trimmed, "🖼️") || strings.Contains(trimmed, "⚛️") || strings.Contains(trimmed, "📊")) {
-			if artifactCount == targetArtifactIndex {
-				// Found our artifact! Scroll to show it with some padding above
-				// Look for the box border above the artifact header
-				targetLine := i - 1
-				// Check if previous line is the top border of the box
-				if i > 0 && strings.Contains(lines[i-1], "╭") {
-					targetLine = i - 1
-				}
-				if targetLine < 0 {
-					targetLine = 0
-				}
-				cv.viewport.SetYOffset(targetLine)
-				return
+	cv.showMessage(cv.messageIndex)
+
+	// Find the header of every rendered artifact in order, so that a title
+	// that also appears in the text of a message can't be mistaken for the
+	// artifact
+	start, end := cv.window()
+	var titles []string
+	before := 0 // artifacts above the window
+	for i, msg := range cv.messages[:end] {
+		for _, artifact := range cv.artifacts[msg.ID] {
+			if i < start {
+				before++
+				continue
 			}
-			artifactCount++
+			titles = append(titles, "┌─ "+artifacts.InlineTitle(artifact))
 		}
 	}
+
+	lines := newLineIndex(cv.renderContent()).findInOrder(titles)
+	target := cv.getTotalArtifactIndex() - before
+	if target < 0 {
+		return
+	}
+	if target >= len(lines) {
+		return
+	}
+
+	// Show the top border of the box above the header
+	cv.viewport.SetYOffset(max(0, lines[target]-1))
 }
 
 // getTotalArtifactIndex returns the total index of the current artifact across all messages
@@ -470,9 +624,9 @@ func (cv *conversationView) saveConversationToMarkdown() {
 	filename := export.GenerateDefaultFilename(cv.conversation)
 
 	// Save using the export package
-	err := export.ConversationToMarkdown(cv.conversation, cv.messages, filename)
+	err := export.ConversationToMarkdown(cv.conversation, cv.shown(), filename)
 	if err != nil {
-		cv.notification = fmt.Sprintf("Error: %v", err)
+		cv.notification = i18n.Tf("Error: %v", err)
 		cv.notificationTimer = 30 // 3 seconds
 	} else {
 		cv.notification = fmt.Sprintf("✓ Saved to %s", filename)
@@ -505,7 +659,7 @@ func (cv *conversationView) saveCurrentArtifact() {
 	// Save to current directory
 	err := os.WriteFile(filename, []byte(artifact.Content), 0644)
 	if err != nil {
-		cv.notification = fmt.Sprintf("Error: %v", err)
+		cv.notification = i18n.Tf("Error: %v", err)
 		cv.notificationTimer = 30 // 3 seconds
 	} else {
 		cv.notification = fmt.Sprintf("✓ Saved to %s", filename)
@@ -513,24 +667,44 @@ func (cv *conversationView) saveCurrentArtifact() {
 	}
 }
 
-// copyCurrentArtifact copies the currently focused artifact to clipboard
-func (cv *conversationView) copyCurrentArtifact() {
+// currentArtifact returns the focused artifact, or nil if there is none
+func (cv *conversationView) currentArtifact() *artifacts.Artifact {
 	msgID := cv.getCurrentMessageWithArtifact()
 	if msgID == 0 || cv.artifacts[msgID] == nil || cv.artifactIndex >= len(cv.artifacts[msgID]) {
-		return
+		return nil
 	}
+	return cv.artifacts[msgID][cv.artifactIndex]
+}
 
-	artifact := cv.artifacts[msgID][cv.artifactIndex]
+// openCurrentArtifact writes the focused artifact to a temporary file and
+// opens it in the browser
+func (cv *conversationView) openCurrentArtifact() tea.Cmd {
+	artifact := cv.currentArtifact()
+	if artifact == nil {
+		return nil
+	}
 
-	// Copy to clipboard
-	err := writeToClipboard(artifact.Content)
+	content, ext, ok := artifact.BrowserDocument()
+	if !ok {
+		return nil
+	}
+
+	file, err := os.CreateTemp("", "shannon-artifact-*"+ext)
+	if err == nil {
+		_, err = file.WriteString(content)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}
 	if err != nil {
-		// Show user-friendly error message
-		cv.notification = "✗ Clipboard not available"
+		cv.notification = i18n.Tf("Error: %v", err)
 		cv.notificationTimer = 30 // 3 seconds
-		return
+		return nil
 	}
 
-	cv.notification = "✓ Copied to clipboard"
-	cv.notificationTimer = 20 // 2 seconds
+	what := i18n.Tf("the %s artifact", artifact.GetTypeName())
+	if artifact.Title != "" {
+		what = fmt.Sprintf("%q", artifact.Title)
+	}
+	return openURL(file.Name(), what)
 }
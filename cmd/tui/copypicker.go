@@ -0,0 +1,110 @@
+package tui
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/neilberkman/shannon/internal/artifacts"
+	"github.com/neilberkman/shannon/internal/clipboard"
+	"github.com/neilberkman/shannon/internal/i18n"
+)
+
+// startCopy opens the copy format picker for the focused artifact. The
+// format last copied in is selected, so pressing c twice repeats it.
+func (cv *conversationView) startCopy() {
+	if cv.currentArtifact() == nil {
+		return
+	}
+	cv.copyActive = true
+}
+
+// updateCopy handles keys while the copy format picker is open
+func (cv *conversationView) updateCopy(msg tea.KeyMsg) tea.Cmd {
+	formats := artifacts.CopyFormats
+	switch msg.String() {
+	case "left", "shift+tab":
+		cv.copyFormat = (cv.copyFormat - 1 + len(formats)) % len(formats)
+	case "right", "tab", "f":
+		cv.copyFormat = (cv.copyFormat + 1) % len(formats)
+	case "r", "m", "h":
+		// Copy straight away in the first format with this first letter,
+		// so r is raw rather than rich
+		for i, format := range formats {
+			if strings.HasPrefix(format, msg.String()) {
+				cv.copyFormat = i
+				break
+			}
+		}
+		cv.copyActive = false
+		return cv.copyCurrentArtifact()
+	case "c", "y", "enter":
+		cv.copyActive = false
+		return cv.copyCurrentArtifact()
+	case "esc":
+		cv.copyActive = false
+	}
+	return nil
+}
+
+// copyCurrentArtifact copies the focused artifact to the clipboard in the
+// selected format. Rich copies are made in the background, as rendering an
+// SVG can take a while, and report back with an artifactCopiedMsg.
+func (cv *conversationView) copyCurrentArtifact() tea.Cmd {
+	artifact := cv.currentArtifact()
+	if artifact == nil {
+		return nil
+	}
+
+	format := artifacts.CopyFormats[cv.copyFormat]
+	if format == artifacts.CopyRich {
+		return func() tea.Msg {
+			return artifactCopiedMsg{format: format, err: clipboard.WriteRich(richContent(artifact))}
+		}
+	}
+	return cv.notify(artifactCopiedMsg{format: format, err: clipboard.Write(artifact.CopyAs(format))}.String())
+}
+
+// artifactCopiedMsg reports how copying an artifact went
+type artifactCopiedMsg struct {
+	format string
+	err    error
+}
+
+// String is the notification shown for the result
+func (msg artifactCopiedMsg) String() string {
+	if msg.err != nil {
+		return i18n.T("✗ Clipboard not available")
+	}
+	if msg.format == artifacts.CopyRaw {
+		return i18n.T("✓ Copied to clipboard")
+	}
+	return i18n.Tf("✓ Copied to clipboard as %s", msg.format)
+}
+
+// richContent is an artifact for rich editors: an SVG as an image if it can
+// be rendered, else as markup, and HTML as itself, with the raw content for
+// applications that only take text
+func richContent(artifact *artifacts.Artifact) clipboard.Content {
+	content := clipboard.Content{Text: artifact.Content, HTML: artifact.RichHTML()}
+	if artifact.Type == artifacts.TypeSVG {
+		if png, err := artifacts.RenderSVG(artifact.Content); err == nil {
+			content.PNG = png
+		}
+	}
+	return content
+}
+
+// copyHelp renders the format picker shown in place of the help line
+func (cv conversationView) copyHelp() string {
+	options := make([]string, len(artifacts.CopyFormats))
+	for i, format := range artifacts.CopyFormats {
+		if i == cv.copyFormat {
+			options[i] = SelectedStyle.Render(" " + format + " ")
+		} else {
+			options[i] = " " + format + " "
+		}
+	}
+
+	return TitleStyle.Render("Copy as:") + " " + strings.Join(options, " ") +
+		HelpStyle.Render(i18n.Hints("←/→ or f: format • c/enter: copy • r/m/h: copy as raw/markdown/html • esc: cancel"))
+}
@@ -0,0 +1,71 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/neilberkman/shannon/internal/crash"
+)
+
+// crashGuard wraps the model of a program so that a panic writes a crash
+// report before Bubble Tea restores the terminal, and records the keys
+// pressed as the report's last operations
+type crashGuard struct {
+	model tea.Model
+}
+
+func (g crashGuard) Init() tea.Cmd {
+	defer crash.Guard()
+	return guardCmd(g.model.Init())
+}
+
+func (g crashGuard) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	defer crash.Guard()
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		crash.Note("key %s", msg.String())
+	case tea.WindowSizeMsg:
+		crash.Note("resize to %dx%d", msg.Width, msg.Height)
+	}
+	model, cmd := g.model.Update(msg)
+	return crashGuard{model}, guardCmd(cmd)
+}
+
+func (g crashGuard) View() string {
+	defer crash.Guard()
+	return g.model.View()
+}
+
+// guardCmd makes a command, and those of a batch it returns, write a crash
+// report if it panics
+func guardCmd(cmd tea.Cmd) tea.Cmd {
+	if cmd == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		defer crash.Guard()
+		msg := cmd()
+		if batch, ok := msg.(tea.BatchMsg); ok {
+			guarded := make(tea.BatchMsg, len(batch))
+			for i, c := range batch {
+				guarded[i] = guardCmd(c)
+			}
+			return guarded
+		}
+		return msg
+	}
+}
+
+// runGuarded runs a program of a guarded model, returning the final model
+// unwrapped, and after a crash where its report is
+func runGuarded(p *tea.Program) (tea.Model, error) {
+	final, err := p.Run()
+	if g, ok := final.(crashGuard); ok {
+		final = g.model
+	}
+	if errors.Is(err, tea.ErrProgramPanic) && crash.LastReport() != "" {
+		return final, fmt.Errorf("shannon crashed\n%s", crash.Message(crash.LastReport()))
+	}
+	return final, err
+}
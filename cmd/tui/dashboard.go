@@ -0,0 +1,417 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/neilberkman/shannon/internal/clipboard"
+	"github.com/neilberkman/shannon/internal/config"
+	"github.com/neilberkman/shannon/internal/i18n"
+	"github.com/neilberkman/shannon/internal/search"
+)
+
+// dashboardBarWidth is the width of the bars comparing months or languages
+const dashboardBarWidth = 20
+
+// bar draws n out of most as a bar of eighths
+func bar(n, most int) string {
+	if most <= 0 {
+		return ""
+	}
+	eighths := n * dashboardBarWidth * 8 / most
+	if eighths == 0 && n > 0 {
+		eighths = 1
+	}
+	partial := []string{"", "▏", "▎", "▍", "▌", "▋", "▊", "▉"}
+	return strings.Repeat("█", eighths/8) + partial[eighths%8]
+}
+
+// monthItem implements list.Item for a month of the dashboard
+type monthItem struct {
+	month *search.MonthActivity
+	most  int // conversations in the busiest month
+}
+
+func (i monthItem) Title() string {
+	t, err := time.Parse("2006-01", i.month.Month)
+	if err != nil {
+		return i.month.Month
+	}
+	return t.Format("January 2006")
+}
+
+func (i monthItem) Description() string {
+	return fmt.Sprintf("%s %s • %s", bar(i.month.Conversations, i.most),
+		pluralize(i.month.Conversations, "conversation", "conversations"),
+		pluralize(i.month.Messages, "message", "messages"))
+}
+
+func (i monthItem) FilterValue() string {
+	return i.Title()
+}
+
+// languageItem implements list.Item for a language of the dashboard
+type languageItem struct {
+	language *search.LanguageCount
+	most     int // artifacts in the most common language
+}
+
+func (i languageItem) Title() string {
+	return i.language.Language
+}
+
+func (i languageItem) Description() string {
+	return fmt.Sprintf("%s %s", bar(i.language.Artifacts, i.most),
+		pluralize(i.language.Artifacts, "code artifact", "code artifacts"))
+}
+
+func (i languageItem) FilterValue() string {
+	return i.language.Language
+}
+
+// artifactItem implements list.Item for an artifact found from the dashboard
+type artifactItem struct {
+	artifact *search.ArchivedArtifact
+}
+
+func (i artifactItem) Title() string {
+	if i.artifact.Title == "" {
+		return "Untitled " + i.artifact.Language
+	}
+	return i.artifact.Title
+}
+
+func (i artifactItem) Description() string {
+	lines := strings.Count(strings.TrimRight(i.artifact.Content, "\n"), "\n") + 1
+	return fmt.Sprintf("%s • %s • %s", i.artifact.ConversationName,
+		i.artifact.CreatedAt.Format("Jan 2, 2006"), pluralize(lines, "line", "lines"))
+}
+
+func (i artifactItem) FilterValue() string {
+	return i.Title() + " " + i.artifact.ConversationName
+}
+
+// Breakdowns the dashboard lists, switched with tab
+const (
+	dashboardMonths = iota
+	dashboardLanguages
+)
+
+// Levels of the dashboard
+const (
+	dashboardLevelStats = iota
+	dashboardLevelItems
+	dashboardLevelConversation
+)
+
+// dashboardModel presents the statistics of `shannon stats` as a dashboard:
+// conversations by month and code artifacts by language, where choosing a
+// month lists its conversations and choosing a language its artifacts
+type dashboardModel struct {
+	engine    *search.Engine
+	summary   string
+	months    []list.Item
+	languages []list.Item
+	breakdown int // dashboardMonths or dashboardLanguages
+	level     int
+	stats     list.Model
+	items     list.Model
+	convView  conversationView
+	width     int
+	height    int
+}
+
+func newDashboardModel(engine *search.Engine) (dashboardModel, error) {
+	m := dashboardModel{engine: engine, width: 80, height: 24}
+
+	stats, err := engine.GetStats()
+	if err != nil {
+		return m, fmt.Errorf("failed to get stats: %w", err)
+	}
+	m.summary = dashboardSummary(stats)
+
+	months, err := engine.ActivityByMonth()
+	if err != nil {
+		return m, err
+	}
+	most := 0
+	for _, month := range months {
+		most = max(most, month.Conversations)
+	}
+	for _, month := range months {
+		m.months = append(m.months, monthItem{month: month, most: most})
+	}
+
+	languages, err := engine.ArtifactLanguages()
+	if err != nil {
+		return m, err
+	}
+	most = 0
+	for _, language := range languages {
+		most = max(most, language.Artifacts)
+	}
+	for _, language := range languages {
+		m.languages = append(m.languages, languageItem{language: language, most: most})
+	}
+
+	m.stats = list.New(nil, newSnippetDelegate(false), 80, 20)
+	m.stats.SetShowHelp(false)
+	m.stats.DisableQuitKeybindings()
+	m.items = list.New(nil, newSnippetDelegate(false), 80, 22)
+	m.items.SetShowHelp(false)
+	m.items.DisableQuitKeybindings()
+	m.showBreakdown(dashboardMonths)
+	return m, nil
+}
+
+// dashboardSummary is the line of totals above the breakdowns
+func dashboardSummary(stats map[string]interface{}) string {
+	parts := []string{
+		pluralize(stats["total_conversations"].(int), "conversation", "conversations"),
+		pluralize(stats["total_messages"].(int), "message", "messages"),
+	}
+	if bySender, ok := stats["messages_by_sender"].(map[string]int); ok {
+		parts = append(parts, fmt.Sprintf("%d from you, %d from Claude", bySender["human"], bySender["assistant"]))
+	}
+	if dateRange, ok := stats["date_range"].(map[string]time.Time); ok {
+		parts = append(parts, dateRange["oldest"].Format("Jan 2006")+" – "+dateRange["newest"].Format("Jan 2006"))
+	}
+	return strings.Join(parts, " • ")
+}
+
+// showBreakdown lists months or languages
+func (m *dashboardModel) showBreakdown(breakdown int) {
+	m.breakdown = breakdown
+	m.stats.ResetFilter()
+	m.stats.Select(0)
+	if breakdown == dashboardLanguages {
+		m.stats.Title = fmt.Sprintf("Code artifacts by language (%d)", len(m.languages))
+		m.stats.SetItems(m.languages)
+	} else {
+		m.stats.Title = fmt.Sprintf("Conversations by month (%d)", len(m.months))
+		m.stats.SetItems(m.months)
+	}
+}
+
+// drillDown lists the conversations of the selected month or the artifacts
+// in the selected language
+func (m *dashboardModel) drillDown() tea.Cmd {
+	var items []list.Item
+	switch item := m.stats.SelectedItem().(type) {
+	case monthItem:
+		conversations, err := m.engine.ConversationsInMonth(item.month.Month)
+		if err != nil {
+			m.items.NewStatusMessage(err.Error())
+			return nil
+		}
+		for _, conv := range conversations {
+			items = append(items, conversationItem{conv: conv})
+		}
+		m.items.Title = fmt.Sprintf("%s (%d)", item.Title(), len(items))
+	case languageItem:
+		found, err := m.engine.AllArtifacts(search.ArtifactFilter{Language: item.language.Language})
+		if err != nil {
+			m.items.NewStatusMessage(err.Error())
+			return nil
+		}
+		for _, a := range found {
+			items = append(items, artifactItem{artifact: a})
+		}
+		m.items.Title = fmt.Sprintf("%s artifacts (%d)", item.language.Language, len(items))
+	default:
+		return nil
+	}
+
+	m.items.ResetFilter()
+	m.items.Select(0)
+	m.level = dashboardLevelItems
+	return m.items.SetItems(items)
+}
+
+// open opens the conversation of the selected item, on the artifact if it's
+// one
+func (m *dashboardModel) open() {
+	var convID int64
+	var artifact *search.ArchivedArtifact
+	switch item := m.items.SelectedItem().(type) {
+	case conversationItem:
+		convID = item.conv.ID
+	case artifactItem:
+		convID, artifact = item.artifact.ConversationID, item.artifact
+	default:
+		return
+	}
+
+	conv, messages, err := m.engine.GetConversation(convID)
+	if err != nil {
+		m.items.NewStatusMessage(err.Error())
+		return
+	}
+	m.convView = newConversationView(m.engine, conv, messages, m.width, m.height)
+	if artifact != nil {
+		m.convView.focusArtifact(artifact.MessageID, artifact.ID)
+	}
+	m.level = dashboardLevelConversation
+	logAccess(m.engine, conv.ID, search.AccessView)
+}
+
+// focusArtifact focuses an artifact of a message and scrolls to it. Nothing
+// happens if the message isn't shown, like one on another branch.
+func (cv *conversationView) focusArtifact(messageID int64, artifactID string) {
+	for i, msg := range cv.messages {
+		if msg.ID != messageID {
+			continue
+		}
+		for j, a := range cv.artifacts[messageID] {
+			if a.ID == artifactID {
+				cv.focusedOnArtifact = true
+				cv.messageIndex, cv.artifactIndex = i, j
+				cv.updateContent()
+				cv.scrollToFocusedArtifact()
+				return
+			}
+		}
+	}
+}
+
+// statsHeight is how many lines the summary and help take around the list
+const statsHeight = 4
+
+func (m dashboardModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m dashboardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.stats.SetSize(msg.Width, msg.Height-statsHeight)
+		m.items.SetSize(msg.Width, msg.Height-2)
+		if m.level == dashboardLevelConversation {
+			cv, cmd := m.convView.Update(msg)
+			m.convView = cv
+			return m, cmd
+		}
+		return m, nil
+
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+
+		switch m.level {
+		case dashboardLevelStats:
+			if m.stats.FilterState() == list.Filtering {
+				break
+			}
+			switch msg.String() {
+			case "q", "esc":
+				if m.stats.FilterState() == list.FilterApplied && msg.String() == "esc" {
+					break
+				}
+				return m, tea.Quit
+			case "tab", "shift+tab":
+				m.showBreakdown(1 - m.breakdown)
+				return m, nil
+			case "enter":
+				return m, m.drillDown()
+			}
+			l, cmd := m.stats.Update(msg)
+			m.stats = l
+			return m, cmd
+
+		case dashboardLevelItems:
+			if m.items.FilterState() == list.Filtering {
+				break
+			}
+			switch msg.String() {
+			case "q":
+				return m, tea.Quit
+			case "esc":
+				if m.items.FilterState() != list.FilterApplied {
+					m.level = dashboardLevelStats
+					return m, nil
+				}
+			case "enter":
+				m.open()
+				return m, nil
+			}
+			l, cmd := m.items.Update(msg)
+			m.items = l
+			return m, cmd
+
+		case dashboardLevelConversation:
+			wasInArtifactMode := m.convView.focusedOnArtifact
+			wasInFindMode := m.convView.findActive
+			wasInSubMode := m.convView.handlesEsc()
+
+			cv, cmd := m.convView.Update(msg)
+			m.convView = cv
+
+			switch msg.String() {
+			case "q":
+				if !wasInFindMode {
+					m.level = dashboardLevelItems
+					return m, nil
+				}
+			case "esc":
+				// Esc leaves artifact focus, find and sub-modes before going back
+				if !wasInArtifactMode && !wasInFindMode && !wasInSubMode {
+					m.level = dashboardLevelItems
+					return m, nil
+				}
+			}
+			return m, cmd
+		}
+	}
+
+	// Everything else, including typing into a list filter
+	var cmd tea.Cmd
+	switch m.level {
+	case dashboardLevelStats:
+		m.stats, cmd = m.stats.Update(msg)
+	case dashboardLevelItems:
+		m.items, cmd = m.items.Update(msg)
+	case dashboardLevelConversation:
+		m.convView, cmd = m.convView.Update(msg)
+	}
+	return m, cmd
+}
+
+func (m dashboardModel) View() string {
+	switch m.level {
+	case dashboardLevelItems:
+		return m.items.View() + "\n" + HelpStyle.Render(i18n.Hints("↑/↓: navigate • enter: view • /: filter • esc: statistics • q: quit"))
+	case dashboardLevelConversation:
+		return m.convView.View()
+	}
+	other := "languages"
+	if m.breakdown == dashboardLanguages {
+		other = "months"
+	}
+	return TitleStyle.Render(m.summary) + "\n\n" + m.stats.View() + "\n" +
+		HelpStyle.Render(i18n.Hints("↑/↓: navigate • enter: drill down • tab: "+other+" • /: filter • q: quit"))
+}
+
+// RunDashboard opens the TUI on the statistics of the archive, to drill
+// down into months and languages
+func RunDashboard(engine *search.Engine) error {
+	if _, err := engine.EnsureCodeIndex(); err != nil {
+		return fmt.Errorf("failed to build code block index: %w", err)
+	}
+	m, err := newDashboardModel(engine)
+	if err != nil {
+		return err
+	}
+
+	if err := clipboard.Init(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: clipboard initialization failed: %v\n", err)
+	}
+	applyConfig(config.Get())
+
+	return runProgram(m)
+}
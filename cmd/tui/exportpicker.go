@@ -0,0 +1,90 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/neilberkman/shannon/internal/clipboard"
+	"github.com/neilberkman/shannon/internal/export"
+	"github.com/neilberkman/shannon/internal/i18n"
+	"github.com/neilberkman/shannon/internal/search"
+)
+
+// startExport opens the export format picker
+func (cv *conversationView) startExport() {
+	if cv.conversation == nil {
+		return
+	}
+	cv.exportActive = true
+}
+
+// updateExport handles keys while the export picker is open
+func (cv *conversationView) updateExport(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "left", "shift+tab":
+		cv.exportFormat = (cv.exportFormat - 1 + len(export.Formats)) % len(export.Formats)
+	case "right", "tab":
+		cv.exportFormat = (cv.exportFormat + 1) % len(export.Formats)
+	case "m", "j", "t", "h":
+		// Jump straight to a format by its first letter
+		for i, format := range export.Formats {
+			if strings.HasPrefix(format, msg.String()) {
+				cv.exportFormat = i
+			}
+		}
+	case "c", "y":
+		cv.exportActive = false
+		return cv.exportConversation(true)
+	case "f", "enter":
+		cv.exportActive = false
+		return cv.exportConversation(false)
+	case "esc", "e":
+		cv.exportActive = false
+	}
+	return nil
+}
+
+// exportConversation exports the conversation in the selected format to the
+// clipboard or to a file in the current directory
+func (cv *conversationView) exportConversation(toClipboard bool) tea.Cmd {
+	format := export.Formats[cv.exportFormat]
+	content, err := export.Render(format, cv.conversation, cv.shown())
+	if err != nil {
+		return cv.notify(i18n.Tf("Error: %v", err))
+	}
+
+	if toClipboard {
+		if err := clipboard.Write(content); err != nil {
+			return cv.notify(i18n.T("✗ Clipboard not available"))
+		}
+		logAccess(cv.engine, cv.conversation.ID, search.AccessExport)
+		return cv.notify(i18n.Tf("✓ Copied %s to clipboard", format))
+	}
+
+	filename := export.DefaultFilename(cv.conversation, format)
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		return cv.notify(i18n.Tf("Error: %v", err))
+	}
+	if abs, err := filepath.Abs(filename); err == nil {
+		filename = abs
+	}
+	logAccess(cv.engine, cv.conversation.ID, search.AccessExport)
+	return cv.notify(i18n.Tf("✓ Exported %s to %s", format, filename))
+}
+
+// exportHelp renders the format picker shown in place of the help line
+func (cv conversationView) exportHelp() string {
+	options := make([]string, len(export.Formats))
+	for i, format := range export.Formats {
+		if i == cv.exportFormat {
+			options[i] = SelectedStyle.Render(" " + format + " ")
+		} else {
+			options[i] = " " + format + " "
+		}
+	}
+
+	return TitleStyle.Render("Export as:") + " " + strings.Join(options, " ") +
+		HelpStyle.Render(i18n.Hints("←/→ or m/j/t/h: format • c: copy to clipboard • f/enter: save to file • esc: cancel"))
+}
@@ -0,0 +1,126 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/neilberkman/shannon/internal/i18n"
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+// Browse list groupings, cycled with "b"
+const (
+	groupNone  = ""
+	groupMonth = "month"
+	groupTag   = "tag"
+)
+
+var browseGroups = []string{groupNone, groupMonth, groupTag}
+
+// untagged is the group key of conversations without an alias
+const untagged = ""
+
+// groupHeaderItem implements list.Item for the header of a group of
+// conversations in the browse list
+type groupHeaderItem struct {
+	key       string
+	label     string
+	count     int
+	collapsed bool
+}
+
+func (i groupHeaderItem) Title() string {
+	if i.collapsed {
+		return "▸ " + i.label
+	}
+	return "▾ " + i.label
+}
+
+func (i groupHeaderItem) Description() string {
+	desc := pluralize(i.count, "conversation", "conversations")
+	if i.collapsed {
+		desc += " • " + i18n.T("collapsed")
+	}
+	return desc
+}
+
+func (i groupHeaderItem) FilterValue() string {
+	return i.label
+}
+
+// groupKey returns the group a conversation belongs to: the month it was
+// last updated in, or the first of its aliases
+func groupKey(c *models.Conversation, by string, tags map[int64][]string) string {
+	switch by {
+	case groupMonth:
+		return c.UpdatedAt.Format("2006-01")
+	case groupTag:
+		if aliases := tags[c.ID]; len(aliases) > 0 {
+			return aliases[0]
+		}
+		return untagged
+	}
+	return ""
+}
+
+// groupLabel returns the header text of a group
+func groupLabel(key, by string) string {
+	switch by {
+	case groupMonth:
+		if month, err := time.Parse("2006-01", key); err == nil {
+			return month.Format("January 2006")
+		}
+	case groupTag:
+		if key == untagged {
+			return i18n.T("Untagged")
+		}
+	}
+	return key
+}
+
+// groupItems puts a header before each group of conversations, leaving out
+// the conversations of collapsed groups. Groups by month keep the order of
+// the list; tags are in alphabetical order, with untagged conversations
+// last.
+func groupItems(items []conversationItem, by string, tags map[int64][]string, collapsed map[string]bool) []list.Item {
+	var keys []string
+	groups := make(map[string][]conversationItem)
+	for _, item := range items {
+		key := groupKey(item.conv, by, tags)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], item)
+	}
+	if by == groupTag {
+		sort.Slice(keys, func(i, j int) bool {
+			if keys[i] == untagged || keys[j] == untagged {
+				return keys[j] == untagged && keys[i] != untagged
+			}
+			return keys[i] < keys[j]
+		})
+	}
+
+	grouped := make([]list.Item, 0, len(items)+len(keys))
+	for _, key := range keys {
+		header := groupHeaderItem{key: key, label: groupLabel(key, by), count: len(groups[key]), collapsed: collapsed[key]}
+		grouped = append(grouped, header)
+		if header.collapsed {
+			continue
+		}
+		for _, item := range groups[key] {
+			grouped = append(grouped, item)
+		}
+	}
+	return grouped
+}
+
+// groupTitle returns what the list title adds for a grouping
+func groupTitle(by string) string {
+	if by == groupNone {
+		return ""
+	}
+	return fmt.Sprintf(" • grouped by %s", by)
+}
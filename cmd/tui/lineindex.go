@@ -0,0 +1,77 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// lineIndex holds rendered content as the viewport shows it: one entry per
+// viewport line, with ANSI escape sequences removed. Find, match navigation
+// and artifact scrolling all locate lines through it, so a query never
+// matches escape codes and columns are measured in display cells, which
+// counts emoji and wide characters as the terminal does.
+type lineIndex struct {
+	lines []string // visible text of each line
+}
+
+// lineMatch is an occurrence of a find query in the rendered content
+type lineMatch struct {
+	line   int // viewport line
+	column int // display column where the match starts
+	width  int // display width of the match
+}
+
+// newLineIndex indexes rendered content, splitting it the same way the
+// viewport does
+func newLineIndex(content string) lineIndex {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		lines[i] = ansi.Strip(line)
+	}
+	return lineIndex{lines: lines}
+}
+
+// find returns every case-insensitive occurrence of query, in order
+func (idx lineIndex) find(query string) []lineMatch {
+	query = strings.ToLower(query)
+	if query == "" {
+		return nil
+	}
+	width := ansi.StringWidth(query)
+
+	var matches []lineMatch
+	for i, line := range idx.lines {
+		lower := strings.ToLower(line)
+		for start := 0; ; {
+			pos := strings.Index(lower[start:], query)
+			if pos < 0 {
+				break
+			}
+			pos += start
+			matches = append(matches, lineMatch{line: i, column: ansi.StringWidth(lower[:pos]), width: width})
+			start = pos + len(query)
+		}
+	}
+	return matches
+}
+
+// findInOrder returns the line of each text, searching for every text below
+// the line of the previous one. The result stops at the first text that
+// isn't found, so it can be shorter than texts.
+func (idx lineIndex) findInOrder(texts []string) []int {
+	lines := make([]int, 0, len(texts))
+	line := 0
+	for _, text := range texts {
+		for line < len(idx.lines) && !strings.Contains(idx.lines[line], text) {
+			line++
+		}
+		if line == len(idx.lines) {
+			break
+		}
+		lines = append(lines, line)
+		line++
+	}
+	return lines
+}
@@ -0,0 +1,341 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/neilberkman/shannon/internal/clipboard"
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/i18n"
+	"github.com/neilberkman/shannon/internal/models"
+	"github.com/neilberkman/shannon/internal/search"
+	"github.com/sahilm/fuzzy"
+)
+
+// paletteAction is an entry in the command palette
+type paletteAction struct {
+	id                string
+	title             string
+	key               string // the key that does the same outside the palette
+	needsConversation bool   // acts on the selected or open conversation
+	prompt            string // asks for a value before running, if set
+}
+
+const (
+	actionExport   = "export"
+	actionCopyID   = "copy-id"
+	actionCopyLink = "copy-link"
+	actionOpen     = "open"
+	actionTag      = "tag"
+	actionTheme    = "theme"
+	actionSwitchDB = "switch-db"
+)
+
+var paletteActions = []paletteAction{
+	{id: actionExport, title: "Export conversation", key: "e", needsConversation: true},
+	{id: actionCopyID, title: "Copy conversation ID", needsConversation: true},
+	{id: actionCopyLink, title: "Copy claude.ai link", needsConversation: true},
+	{id: actionOpen, title: "Open in claude.ai", key: "o", needsConversation: true},
+	{id: actionTag, title: "Tag conversation with an alias", needsConversation: true, prompt: "Alias: "},
+	{id: actionTheme, title: "Toggle light/dark theme"},
+	{id: actionSwitchDB, title: "Switch database", prompt: "Database: "},
+}
+
+// commandPalette is the ctrl+k overlay listing the actions available for
+// the current context, fuzzy filtered by what's typed
+type commandPalette struct {
+	input    textinput.Model
+	conv     *models.Conversation // the conversation actions act on, if any
+	actions  []paletteAction      // the actions available in this context
+	matches  []paletteAction      // the actions matching the input
+	selected int
+
+	// The action waiting for its value, such as the alias to tag with
+	prompting *paletteAction
+	err       string
+}
+
+// paletteExportMsg asks the current view to open the export picker for a
+// conversation
+type paletteExportMsg struct {
+	conv *models.Conversation
+}
+
+// themeChangedMsg asks the views to rebuild what they rendered with the old
+// theme
+type themeChangedMsg struct{}
+
+// newCommandPalette opens the palette for conv, which may be nil when no
+// conversation is selected
+func newCommandPalette(conv *models.Conversation) *commandPalette {
+	ti := textinput.New()
+	ti.Placeholder = "type a command"
+	ti.Prompt = "> "
+	ti.CharLimit = 200
+	ti.Width = 50
+	ti.Focus()
+
+	p := &commandPalette{input: ti, conv: conv}
+	for _, a := range paletteActions {
+		if a.needsConversation && conv == nil {
+			continue
+		}
+		p.actions = append(p.actions, a)
+	}
+	p.filter()
+	return p
+}
+
+// filter narrows the actions to those fuzzy matching the input, best first
+func (p *commandPalette) filter() {
+	p.selected = 0
+	query := strings.TrimSpace(p.input.Value())
+	if query == "" {
+		p.matches = p.actions
+		return
+	}
+
+	titles := make([]string, len(p.actions))
+	for i, a := range p.actions {
+		titles[i] = a.title
+	}
+	p.matches = nil
+	for _, match := range fuzzy.Find(query, titles) {
+		p.matches = append(p.matches, p.actions[match.Index])
+	}
+}
+
+// startPrompt switches the input to asking for the value of action
+func (p *commandPalette) startPrompt(action paletteAction, value string) {
+	p.prompting = &action
+	p.err = ""
+	p.input.Prompt = action.prompt
+	p.input.Placeholder = ""
+	p.input.SetValue(value)
+	p.input.CursorEnd()
+}
+
+// contextConversation is the conversation the palette acts on: the one open
+// in the current view, or else the one selected in its list
+func contextConversation(view tea.Model) *models.Conversation {
+	switch v := view.(type) {
+	case browseModel:
+		if v.mode == ModeConversation {
+			return v.convView.conversation
+		}
+		if i, ok := v.list.SelectedItem().(conversationItem); ok {
+			return i.conv
+		}
+	case searchModel:
+		if v.mode == ModeConversation {
+			return v.convView.conversation
+		}
+		if i, ok := v.list.SelectedItem().(searchConversationItem); ok {
+			return i.conv
+		}
+	}
+	return nil
+}
+
+// updatePalette handles keys while the command palette is open
+func (m mainModel) updatePalette(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	p := m.palette
+	switch msg.String() {
+	case keyEsc:
+		if p.prompting != nil {
+			// Back to the list of actions
+			p.prompting = nil
+			p.err = ""
+			p.input.Prompt = "> "
+			p.input.Placeholder = "type a command"
+			p.input.SetValue("")
+			p.filter()
+			return m, nil
+		}
+		m.palette = nil
+		return m, nil
+
+	case "up", "ctrl+p":
+		if p.prompting == nil && p.selected > 0 {
+			p.selected--
+		}
+		return m, nil
+
+	case "down", "ctrl+n":
+		if p.prompting == nil && p.selected < len(p.matches)-1 {
+			p.selected++
+		}
+		return m, nil
+
+	case keyEnter:
+		if p.prompting != nil {
+			return m.runAction(*p.prompting, strings.TrimSpace(p.input.Value()))
+		}
+		if len(p.matches) == 0 {
+			return m, nil
+		}
+		action := p.matches[p.selected]
+		if action.prompt != "" {
+			value := ""
+			if action.id == actionSwitchDB && m.database != nil {
+				value = m.database.path
+			}
+			p.startPrompt(action, value)
+			return m, textinput.Blink
+		}
+		return m.runAction(action, "")
+	}
+
+	before := p.input.Value()
+	var cmd tea.Cmd
+	p.input, cmd = p.input.Update(msg)
+	if p.prompting == nil && p.input.Value() != before {
+		p.filter()
+	}
+	return m, cmd
+}
+
+// runAction runs a palette action with the value prompted for, closing the
+// palette unless the value needs correcting
+func (m mainModel) runAction(action paletteAction, value string) (tea.Model, tea.Cmd) {
+	conv := m.palette.conv
+	var cmd tea.Cmd
+
+	switch action.id {
+	case actionExport:
+		cmd = func() tea.Msg { return paletteExportMsg{conv: conv} }
+
+	case actionCopyID:
+		if err := clipboard.Write(fmt.Sprintf("%d", conv.ID)); err != nil {
+			m.notify(i18n.Tf("Copy failed: %v", err))
+		} else {
+			m.notify(i18n.Tf("Copied conversation ID %d", conv.ID))
+		}
+
+	case actionCopyLink:
+		url := claudeURL(m.engine, conv)
+		if err := clipboard.Write(url); err != nil {
+			m.notify(i18n.Tf("Copy failed: %v", err))
+		} else {
+			m.notify(i18n.Tf("Copied %s", url))
+		}
+
+	case actionOpen:
+		cmd = openURL(claudeURL(m.engine, conv), i18n.T("the conversation"))
+
+	case actionTag:
+		if err := m.engine.SetAlias(conv.ID, value); err != nil {
+			m.palette.err = err.Error()
+			return m, nil
+		}
+		m.notify(i18n.Tf("Tagged conversation %d as %q", conv.ID, value))
+
+	case actionTheme:
+		m.notify(i18n.Tf("Switched to the %s theme", toggleTheme()))
+		cmd = func() tea.Msg { return themeChangedMsg{} }
+
+	case actionSwitchDB:
+		if err := m.switchDatabase(value); err != nil {
+			m.palette.err = err.Error()
+			return m, nil
+		}
+		m.notify(i18n.Tf("Switched to %s", m.database.path))
+		cmd = m.currentView.Init()
+		if m.width > 0 {
+			// Fit the new browse view to the window
+			width, height := m.width, m.height
+			cmd = tea.Batch(cmd, func() tea.Msg { return tea.WindowSizeMsg{Width: width, Height: height} })
+		}
+	}
+
+	m.palette = nil
+	return m, cmd
+}
+
+// switchDatabase opens the database at path in place of the current one and
+// starts browsing it
+func (m *mainModel) switchDatabase(path string) error {
+	if m.database == nil {
+		return fmt.Errorf("switching databases isn't available here")
+	}
+	if path == "" {
+		return fmt.Errorf("enter the path of a database")
+	}
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[2:])
+		}
+	}
+	// db.New would create a new empty database at a mistyped path
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("no database at %s", path)
+	}
+
+	database, err := db.New(path)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	if err := m.database.db.Close(); err != nil {
+		m.notify(i18n.Tf("Warning: failed to close database: %v", err))
+	}
+	m.database.db = database
+	m.database.path = path
+
+	m.engine = search.NewEngine(database)
+	m.engine.SetDictionary(m.database.dictionary)
+	m.currentView = newBrowseModel(m.engine)
+	m.viewType = ViewBrowse
+	return nil
+}
+
+// View renders the palette box
+func (p commandPalette) View() string {
+	var b strings.Builder
+	title := "Commands"
+	if p.conv != nil {
+		title = fmt.Sprintf("Commands for %s", truncateTitle(p.conv.Name, 40))
+	}
+	b.WriteString(TitleStyle.Render(title) + "\n\n")
+	b.WriteString(p.input.View() + "\n\n")
+
+	switch {
+	case p.prompting != nil:
+		b.WriteString(HelpStyle.Render(p.prompting.title) + "\n")
+		if p.err != "" {
+			b.WriteString(CleanupMarkerStyle.Render(p.err) + "\n")
+		}
+		b.WriteString("\n" + HelpStyle.Render(i18n.Hints("enter: run • esc: back")))
+		return PaletteStyle.Render(b.String())
+
+	case len(p.matches) == 0:
+		b.WriteString(HelpStyle.Render(i18n.Hints("no matching commands")) + "\n")
+
+	default:
+		for i, a := range p.matches {
+			line := "  " + a.title
+			if i == p.selected {
+				line = SelectedStyle.Render("> " + a.title)
+			}
+			if a.key != "" {
+				line += DateStyle.Render("  " + a.key)
+			}
+			b.WriteString(line + "\n")
+		}
+	}
+
+	b.WriteString("\n" + HelpStyle.Render(i18n.Hints("↑/↓: select • enter: run • esc: close")))
+	return PaletteStyle.Render(b.String())
+}
+
+// truncateTitle shortens a conversation name to fit the palette
+func truncateTitle(name string, max int) string {
+	runes := []rune(name)
+	if len(runes) <= max {
+		return name
+	}
+	return string(runes[:max-1]) + "…"
+}
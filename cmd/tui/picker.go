@@ -0,0 +1,209 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/neilberkman/shannon/internal/clipboard"
+	"github.com/neilberkman/shannon/internal/config"
+	"github.com/neilberkman/shannon/internal/i18n"
+	"github.com/neilberkman/shannon/internal/models"
+	"github.com/neilberkman/shannon/internal/rendering"
+	"github.com/neilberkman/shannon/internal/search"
+)
+
+// pickItem implements list.Item for a search result in the picker
+type pickItem struct {
+	result *models.SearchResult
+}
+
+func (i pickItem) Title() string {
+	return i.result.ConversationName
+}
+
+func (i pickItem) Description() string {
+	return fmt.Sprintf("%s • %s", rendering.FormatSender(i.result.Sender), i.result.CreatedAt.Format("Jan 2, 2006"))
+}
+
+func (i pickItem) FilterValue() string {
+	return i.result.ConversationName + " " + i.result.Text
+}
+
+// What to do with the message picked from search results
+const (
+	pickNone = iota
+	pickPrint
+	pickCopy
+)
+
+// pickModel lets one search result be picked from `shannon search --pick`,
+// to print or copy its message once the picker closes, or to read it in its
+// conversation first
+type pickModel struct {
+	engine   *search.Engine
+	list     list.Model
+	viewing  bool // the picked result's conversation is open
+	convView conversationView
+	action   int
+	picked   *models.SearchResult
+	width    int
+	height   int
+}
+
+func newPickModel(engine *search.Engine, results []*models.SearchResult, query string) pickModel {
+	items := make([]list.Item, len(results))
+	for i, r := range results {
+		items[i] = pickItem{result: r}
+	}
+	l := list.New(items, newSnippetDelegate(true), 80, 22)
+	l.Title = fmt.Sprintf("Pick a message for: %s", query)
+	l.SetShowHelp(false)
+	l.DisableQuitKeybindings()
+	return pickModel{engine: engine, list: l, width: 80, height: 24}
+}
+
+func (m pickModel) Init() tea.Cmd {
+	return nil
+}
+
+// open shows the selected result's thread, scrolled to its message
+func (m *pickModel) open() {
+	item, ok := m.list.SelectedItem().(pickItem)
+	if !ok {
+		return
+	}
+	conv, messages, err := m.engine.GetThread(item.result.ConversationID, item.result.MessageID)
+	if err != nil {
+		m.list.NewStatusMessage(err.Error())
+		return
+	}
+	m.convView = newConversationView(m.engine, conv, messages, m.width, m.height)
+	for i, msg := range messages {
+		if msg.ID == item.result.MessageID {
+			m.convView.scrollToHeader(i)
+			break
+		}
+	}
+	m.viewing = true
+	logAccess(m.engine, conv.ID, search.AccessView)
+}
+
+func (m pickModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.list.SetSize(msg.Width, msg.Height-2)
+		if m.viewing {
+			cv, cmd := m.convView.Update(msg)
+			m.convView = cv
+			return m, cmd
+		}
+		return m, nil
+
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+
+		if m.viewing {
+			wasInArtifactMode := m.convView.focusedOnArtifact
+			wasInFindMode := m.convView.findActive
+			wasInSubMode := m.convView.handlesEsc()
+
+			cv, cmd := m.convView.Update(msg)
+			m.convView = cv
+
+			switch msg.String() {
+			case "q":
+				if !wasInFindMode {
+					m.viewing = false
+					return m, nil
+				}
+			case "esc":
+				// Esc leaves artifact focus, find and sub-modes before going back
+				if !wasInArtifactMode && !wasInFindMode && !wasInSubMode {
+					m.viewing = false
+					return m, nil
+				}
+			}
+			return m, cmd
+		}
+
+		if m.list.FilterState() == list.Filtering {
+			break
+		}
+		switch msg.String() {
+		case "q", "esc":
+			if m.list.FilterState() == list.FilterApplied && msg.String() == "esc" {
+				break
+			}
+			return m, tea.Quit
+		case "enter", "c", "y":
+			item, ok := m.list.SelectedItem().(pickItem)
+			if !ok {
+				return m, nil
+			}
+			m.picked, m.action = item.result, pickPrint
+			if msg.String() != "enter" {
+				m.action = pickCopy
+			}
+			return m, tea.Quit
+		case "o":
+			m.open()
+			return m, nil
+		}
+	}
+
+	// Everything else, including typing into the filter
+	var cmd tea.Cmd
+	if m.viewing {
+		m.convView, cmd = m.convView.Update(msg)
+	} else {
+		m.list, cmd = m.list.Update(msg)
+	}
+	return m, cmd
+}
+
+func (m pickModel) View() string {
+	if m.viewing {
+		return m.convView.View()
+	}
+	return m.list.View() + "\n" +
+		HelpStyle.Render(i18n.Hints("↑/↓: navigate • enter: print • c: copy • o: open conversation • /: filter • q: cancel"))
+}
+
+// RunPicker lets one of a search's results be picked. Its message is
+// printed to stdout, or copied to the clipboard, once the picker closes. The
+// picker itself is drawn on stderr and reads the terminal, so stdout can be
+// piped.
+func RunPicker(engine *search.Engine, results []*models.SearchResult, query string) error {
+	if len(results) == 0 {
+		return fmt.Errorf("no results to pick from")
+	}
+
+	if err := clipboard.Init(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: clipboard initialization failed: %v\n", err)
+	}
+	applyConfig(config.Get())
+
+	p := tea.NewProgram(crashGuard{newPickModel(engine, results, query)}, tea.WithAltScreen(),
+		tea.WithOutput(os.Stderr), tea.WithInputTTY())
+	final, err := runGuarded(p)
+	if err != nil {
+		return fmt.Errorf("failed to run picker: %w", err)
+	}
+
+	m := final.(pickModel)
+	switch m.action {
+	case pickPrint:
+		fmt.Println(m.picked.Text)
+	case pickCopy:
+		if err := clipboard.Write(m.picked.Text); err != nil {
+			return fmt.Errorf("failed to copy message: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Copied the message from %q to the clipboard\n", m.picked.ConversationName)
+	}
+	return nil
+}
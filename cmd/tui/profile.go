@@ -0,0 +1,117 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dustin/go-humanize"
+)
+
+// keyProfile toggles the profiling overlay
+const keyProfile = "f12"
+
+// profileStats are the timings the profiling overlay shows, shared by copies
+// of the main model
+type profileStats struct {
+	visible bool
+
+	updates       int           // messages handled
+	lastMsg       string        // type of the last message handled
+	lastUpdate    time.Duration // time the last message took to handle
+	slowestUpdate time.Duration
+	lastRender    time.Duration // time the last frame took to render
+	slowestRender time.Duration
+}
+
+// updated records the time a message took to handle
+func (p *profileStats) updated(msg tea.Msg, took time.Duration) {
+	p.updates++
+	p.lastMsg = strings.TrimPrefix(fmt.Sprintf("%T", msg), "tui.")
+	p.lastUpdate = took
+	p.slowestUpdate = max(p.slowestUpdate, took)
+}
+
+// rendered records the time a frame took to render
+func (p *profileStats) rendered(took time.Duration) {
+	p.lastRender = took
+	p.slowestRender = max(p.slowestRender, took)
+}
+
+// overlay renders the timings, the size of the open conversation and memory
+// use as a line for the bottom of the screen
+func (p *profileStats) overlay(view tea.Model) string {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	parts := []string{
+		fmt.Sprintf("render %s (max %s)", roundDuration(p.lastRender), roundDuration(p.slowestRender)),
+		fmt.Sprintf("update %s (max %s, %s)", roundDuration(p.lastUpdate), roundDuration(p.slowestUpdate), p.lastMsg),
+		fmt.Sprintf("%d msgs handled", p.updates),
+	}
+	if cv := openConversation(view); cv != nil {
+		start, end := cv.window()
+		parts = append(parts, fmt.Sprintf("conversation %d messages, %d rendered", len(cv.messages), end-start))
+	}
+	parts = append(parts, fmt.Sprintf("heap %s, sys %s, %d GCs",
+		humanize.IBytes(mem.HeapAlloc), humanize.IBytes(mem.Sys), mem.NumGC))
+	return ProfileStyle.Render("⏱ " + strings.Join(parts, " • "))
+}
+
+// roundDuration rounds a duration for display
+func roundDuration(d time.Duration) time.Duration {
+	if d >= time.Millisecond {
+		return d.Round(10 * time.Microsecond)
+	}
+	return d.Round(time.Microsecond)
+}
+
+// openConversation returns the conversation view shown, if one is
+func openConversation(view tea.Model) *conversationView {
+	switch v := view.(type) {
+	case browseModel:
+		if v.mode == ModeConversation {
+			return &v.convView
+		}
+	case searchModel:
+		if v.mode == ModeConversation {
+			return &v.convView
+		}
+	}
+	return nil
+}
+
+// startProfile starts writing a CPU profile to path, returning a function
+// that stops it and writes a heap profile next to it
+func startProfile(path string) (func(), error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create profile: %w", err)
+	}
+	if err := pprof.StartCPUProfile(f); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to start CPU profile: %w", err)
+	}
+
+	return func() {
+		pprof.StopCPUProfile()
+		if err := f.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close profile: %v\n", err)
+		}
+
+		heap, err := os.Create(path + ".heap")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to create heap profile: %v\n", err)
+			return
+		}
+		defer func() { _ = heap.Close() }()
+		runtime.GC()
+		if err := pprof.WriteHeapProfile(heap); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to write heap profile: %v\n", err)
+		}
+	}, nil
+}
@@ -0,0 +1,49 @@
+package tui
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/neilberkman/shannon/internal/i18n"
+	"github.com/neilberkman/shannon/internal/search"
+)
+
+// rateAnswer rates the first assistant answer at or below the top of the
+// screen with the rating of a number key: 1 useful, 2 obsolete, 3 wrong, and
+// 0 clears the rating. A note saved from the command line is kept.
+func (cv *conversationView) rateAnswer(key string) tea.Cmd {
+	if cv.engine == nil {
+		return nil
+	}
+
+	index := -1
+	top, _ := cv.topMessage()
+	for i := max(top, 0); i < len(cv.messages); i++ {
+		if cv.messages[i].Sender == "assistant" {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return cv.notify(i18n.T("No answer on screen to rate"))
+	}
+	msg := cv.messages[index]
+
+	var notice string
+	if key == "0" {
+		if _, err := cv.engine.ClearRating(msg.ID); err != nil {
+			return cv.notify(i18n.Tf("Error: %v", err))
+		}
+		notice = i18n.Tf("✓ Cleared the rating of message %d", index+1)
+	} else {
+		rating := search.Ratings[key[0]-'1']
+		if err := cv.engine.RateMessage(msg.ID, rating, msg.RatingNote); err != nil {
+			return cv.notify(i18n.Tf("Error: %v", err))
+		}
+		notice = i18n.Tf("✓ Rated message %d %s", index+1, rating)
+	}
+
+	if err := cv.reload(); err != nil {
+		return cv.notify(i18n.Tf("Error: %v", err))
+	}
+	cv.updateContent()
+	return cv.notify(notice)
+}
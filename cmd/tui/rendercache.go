@@ -0,0 +1,168 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/neilberkman/shannon/internal/artifacts"
+	"github.com/neilberkman/shannon/internal/i18n"
+	"github.com/neilberkman/shannon/internal/models"
+	"github.com/neilberkman/shannon/internal/search"
+)
+
+// renderCache memoizes the rendered conversation. Messages are cached one by
+// one, keyed by everything that changes how they look, so moving the artifact
+// focus or expanding an artifact only re-renders the messages involved, and
+// finding or scrolling without changes re-renders nothing. Only a window of
+// messages is rendered at a time; messages outside it are dropped from the
+// cache so long conversations don't hold every rendering in memory.
+type renderCache struct {
+	layout  renderLayout
+	entries []cachedMessage // by message index
+
+	// The last full render and whether any message changed since
+	full      string
+	fullKey   fullRenderKey
+	fullValid bool
+}
+
+// renderLayout holds the settings that change how every message renders.
+// When it changes the whole cache is dropped.
+type renderLayout struct {
+	width        int
+	previewLines int
+	wrap         bool
+}
+
+// messageKey identifies a rendering of a message
+type messageKey struct {
+	msg       *models.Message
+	artifacts int    // artifacts found in the message
+	focused   int    // index of the focused artifact, -1 for none
+	expanded  string // IDs of the expanded artifacts
+}
+
+// cachedMessage is a rendered message and the key it was rendered with
+type cachedMessage struct {
+	key      messageKey
+	rendered string
+}
+
+// fullRenderKey identifies the parts of a full render outside the messages
+type fullRenderKey struct {
+	conversation      *models.Conversation
+	lineage           *search.Lineage
+	messageCount      int
+	totalArtifacts    int
+	focusedOnArtifact bool
+	start, end        int // the window of messages rendered
+}
+
+// newRenderCache creates an empty render cache
+func newRenderCache() *renderCache {
+	return &renderCache{}
+}
+
+// prepare drops the cache if the layout or the number of messages changed
+func (c *renderCache) prepare(width, messageCount int) {
+	layout := renderLayout{width: width, previewLines: artifactPreviewLines, wrap: artifactWrap}
+	if layout != c.layout || len(c.entries) != messageCount {
+		c.layout = layout
+		c.entries = make([]cachedMessage, messageCount)
+		c.fullValid = false
+	}
+}
+
+// message renders messages[i] like a full render would, reusing the cached
+// rendering if it can't have changed, and reports whether it was re-rendered
+func (c *renderCache) message(messages []*models.Message, i int, messageArtifacts map[int64][]*artifacts.Artifact, width int, focusedOnArtifact bool, messageIndex int, artifactIndex int, expandedArtifacts map[string]bool) (string, bool) {
+	c.prepare(width, len(messages))
+
+	msg := messages[i]
+	arts := messageArtifacts[msg.ID]
+	key := messageKey{msg: msg, artifacts: len(arts), focused: -1}
+	if focusedOnArtifact && i == messageIndex {
+		key.focused = artifactIndex
+	}
+	key.expanded = expandedKey(arts, expandedArtifacts)
+
+	if c.entries[i].key == key && c.entries[i].rendered != "" {
+		return c.entries[i].rendered, false
+	}
+	c.entries[i] = cachedMessage{key: key, rendered: renderMessage(msg, arts, width, key.focused, expandedArtifacts)}
+	return c.entries[i].rendered, true
+}
+
+// render renders messages[start:end] of the conversation like
+// RenderConversationWithArtifacts, with a line saying how many messages are
+// left out above and below the window, reusing cached messages whose
+// rendering can't have changed
+func (c *renderCache) render(conversation *models.Conversation, lineage *search.Lineage, messages []*models.Message, start, end int, messageArtifacts map[int64][]*artifacts.Artifact, width int, focusedOnArtifact bool, messageIndex int, artifactIndex int, expandedArtifacts map[string]bool) string {
+	c.prepare(width, len(messages))
+
+	totalArtifacts := 0
+	for _, arts := range messageArtifacts {
+		totalArtifacts += len(arts)
+	}
+
+	changed := false
+	for i := range messages {
+		if i < start || i >= end {
+			c.entries[i] = cachedMessage{}
+			continue
+		}
+		if _, rendered := c.message(messages, i, messageArtifacts, width, focusedOnArtifact, messageIndex, artifactIndex, expandedArtifacts); rendered {
+			changed = true
+		}
+	}
+
+	fullKey := fullRenderKey{
+		conversation:      conversation,
+		lineage:           lineage,
+		messageCount:      len(messages),
+		totalArtifacts:    totalArtifacts,
+		focusedOnArtifact: focusedOnArtifact,
+		start:             start,
+		end:               end,
+	}
+	if !changed && c.fullValid && c.fullKey == fullKey {
+		return c.full
+	}
+
+	var sb strings.Builder
+	separator := "\n\n" + strings.Repeat("─", width/2) + "\n\n"
+	if start > 0 {
+		sb.WriteString(HelpStyle.Render(i18n.Tf("↑ %d earlier messages", start)))
+		sb.WriteString(separator)
+	} else {
+		sb.WriteString(renderConversationHeader(conversation, lineage, messages, totalArtifacts, width))
+	}
+	for i := start; i < end; i++ {
+		sb.WriteString(c.entries[i].rendered)
+		if i < end-1 {
+			sb.WriteString(separator)
+		}
+	}
+	if end < len(messages) {
+		sb.WriteString(separator)
+		sb.WriteString(HelpStyle.Render(i18n.Tf("↓ %d more messages", len(messages)-end)))
+	} else {
+		sb.WriteString(renderConversationFooter(focusedOnArtifact, totalArtifacts))
+	}
+
+	c.full = sb.String()
+	c.fullKey = fullKey
+	c.fullValid = true
+	return c.full
+}
+
+// expandedKey lists the expanded artifacts of a message, which is usually
+// none, so that expanding one invalidates only its message
+func expandedKey(arts []*artifacts.Artifact, expandedArtifacts map[string]bool) string {
+	var expanded []string
+	for _, artifact := range arts {
+		if expandedArtifacts[artifact.ID] {
+			expanded = append(expanded, artifact.ID)
+		}
+	}
+	return strings.Join(expanded, "\x00")
+}
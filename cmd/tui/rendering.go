@@ -5,8 +5,20 @@ import (
 	"strings"
 
 	"github.com/neilberkman/shannon/internal/artifacts"
+	"github.com/neilberkman/shannon/internal/export"
+	"github.com/neilberkman/shannon/internal/i18n"
 	"github.com/neilberkman/shannon/internal/models"
 	"github.com/neilberkman/shannon/internal/rendering"
+	"github.com/neilberkman/shannon/internal/search"
+)
+
+// Inline artifact layout, set from the configuration when the TUI starts
+var (
+	artifactPreviewLines = artifacts.DefaultPreviewLines
+	artifactWrap         = true
+	// codeCollapseLines is the longest code block left alone when long
+	// code is collapsed
+	codeCollapseLines = artifacts.DefaultCollapseLines
 )
 
 // RenderConversation renders the full conversation view with plain text (debugging hang)
@@ -33,19 +45,16 @@ func renderConversationPlain(conversation *models.Conversation, messages []*mode
 	// Messages
 	for i, msg := range messages {
 		// Message header
-		displaySender := rendering.FormatSender(msg.Sender)
-		timestamp := msg.CreatedAt.Format("2006-01-02 15:04:05")
-
 		if msg.Sender == "human" {
-			sb.WriteString(ConversationStyle.Bold(true).Render(fmt.Sprintf("%s (%s)", displaySender, timestamp)))
+			sb.WriteString(ConversationStyle.Bold(true).Render(export.MessageHeader(msg)))
 		} else {
-			sb.WriteString(AssistantStyle.Render(fmt.Sprintf("%s (%s)", displaySender, timestamp)))
+			sb.WriteString(AssistantStyle.Render(export.MessageHeader(msg)))
 		}
 		sb.WriteString("\n")
 
 		// Message text with word wrap
 		text := strings.TrimSpace(msg.Text)
-		wrappedText := simpleWordWrap(text, width-4)
+		wrappedText := rendering.Wrap(text, width-4)
 		sb.WriteString(wrappedText)
 
 		if i < len(messages)-1 {
@@ -58,51 +67,15 @@ func renderConversationPlain(conversation *models.Conversation, messages []*mode
 	return sb.String()
 }
 
-// simpleWordWrap wraps text to the specified width, preserving line breaks
-func simpleWordWrap(text string, width int) string {
-	if width <= 0 {
-		return text
-	}
-
-	lines := strings.Split(text, "\n")
-	var result []string
-
-	for _, line := range lines {
-		if len(line) <= width {
-			result = append(result, line)
-			continue
-		}
-
-		// Wrap long lines
-		words := strings.Fields(line)
-		if len(words) == 0 {
-			result = append(result, line)
-			continue
-		}
-
-		currentLine := words[0]
-		for _, word := range words[1:] {
-			if len(currentLine)+1+len(word) <= width {
-				currentLine += " " + word
-			} else {
-				result = append(result, currentLine)
-				currentLine = word
-			}
-		}
-		if currentLine != "" {
-			result = append(result, currentLine)
-		}
-	}
-
-	return strings.Join(result, "\n")
-}
-
 // RenderConversationWithArtifacts renders the conversation with inline artifacts
 func RenderConversationWithArtifacts(conversation *models.Conversation, messages []*models.Message, messageArtifacts map[int64][]*artifacts.Artifact, width int, focusedOnArtifact bool, messageIndex int, artifactIndex int, expandedArtifacts map[string]bool) string {
-	var sb strings.Builder
-	renderer := artifacts.NewTerminalRenderer()
+	return newRenderCache().render(conversation, nil, messages, 0, len(messages), messageArtifacts, width, focusedOnArtifact, messageIndex, artifactIndex, expandedArtifacts)
+}
 
-	// Header
+// renderConversationHeader renders the title block above the messages, with
+// the conversations it continues and is continued in if lineage has any
+func renderConversationHeader(conversation *models.Conversation, lineage *search.Lineage, messages []*models.Message, totalArtifacts, width int) string {
+	var sb strings.Builder
 	sb.WriteString(HeaderStyle.Render(fmt.Sprintf("Conversation: %s", conversation.Name)))
 	sb.WriteString("\n")
 	sb.WriteString(DateStyle.Render(fmt.Sprintf("Messages: %d | Updated: %s",
@@ -110,94 +83,123 @@ func RenderConversationWithArtifacts(conversation *models.Conversation, messages
 		conversation.UpdatedAt.Format("2006-01-02 15:04"))))
 
 	// Add artifact count if any
-	totalArtifacts := 0
-	for _, arts := range messageArtifacts {
-		totalArtifacts += len(arts)
-	}
 	if totalArtifacts > 0 {
 		sb.WriteString(" | ")
 		sb.WriteString(DateStyle.Render(fmt.Sprintf("Artifacts: %d", totalArtifacts)))
 	}
+	if names := export.Models(messages); names != "" {
+		sb.WriteString(" | ")
+		sb.WriteString(DateStyle.Render("Model: " + names))
+	}
+	if conversation.Starred {
+		sb.WriteString(" | ")
+		sb.WriteString(DateStyle.Render("★ Starred"))
+	}
 
 	sb.WriteString("\n")
+	if lineage != nil && !lineage.Empty() {
+		var links []string
+		if lineage.Continues != nil {
+			links = append(links, fmt.Sprintf("Continues: %d %s", lineage.Continues.ID, lineage.Continues.Name))
+		}
+		if lineage.ContinuedIn != nil {
+			links = append(links, fmt.Sprintf("Continued in: %d %s", lineage.ContinuedIn.ID, lineage.ContinuedIn.Name))
+		}
+		sb.WriteString(DateStyle.Render(strings.Join(links, " | ")))
+		sb.WriteString("\n")
+	}
+	if timeline := messageTimeline(messages, width); timeline != nil {
+		sb.WriteString(DateStyle.Render(timelineLabel + timeline.String()))
+		sb.WriteString("\n")
+	}
 	sb.WriteString(strings.Repeat("─", width))
 	sb.WriteString("\n\n")
+	return sb.String()
+}
 
-	// Messages
-	for i, msg := range messages {
-		// Message header
-		displaySender := rendering.FormatSender(msg.Sender)
-		timestamp := msg.CreatedAt.Format("2006-01-02 15:04:05")
+// renderMessage renders one message with its artifacts inline. focused is the
+// index of the focused artifact, or -1 if none of the message's artifacts is.
+func renderMessage(msg *models.Message, arts []*artifacts.Artifact, width int, focused int, expandedArtifacts map[string]bool) string {
+	var sb strings.Builder
+	renderer := artifacts.NewTerminalRenderer()
+	renderer.Wrap = artifactWrap
+	renderer.MaxWidth = width - 2 // artifacts are indented by two spaces
+
+	// Message header
+	if msg.Sender == "human" {
+		sb.WriteString(ConversationStyle.Bold(true).Render(messageHeader(msg)))
+	} else {
+		sb.WriteString(AssistantStyle.Render(messageHeader(msg)))
+	}
+	sb.WriteString("\n")
 
-		if msg.Sender == "human" {
-			sb.WriteString(ConversationStyle.Bold(true).Render(fmt.Sprintf("%s (%s)", displaySender, timestamp)))
-		} else {
-			sb.WriteString(AssistantStyle.Render(fmt.Sprintf("%s (%s)", displaySender, timestamp)))
-		}
-		sb.WriteString("\n")
+	// Message text with artifacts removed
+	text := strings.TrimSpace(msg.Text)
+	if len(arts) > 0 {
+		// Remove artifact tags from display
+		extractor := artifacts.NewExtractor()
+		text = extractor.ArtifactRegex.ReplaceAllString(text, "[Artifact: see below]")
+	}
 
-		// Message text with artifacts removed
-		text := strings.TrimSpace(msg.Text)
-		if messageArtifacts[msg.ID] != nil && len(messageArtifacts[msg.ID]) > 0 {
-			// Remove artifact tags from display
-			extractor := artifacts.NewExtractor()
-			text = extractor.ArtifactRegex.ReplaceAllString(text, "[Artifact: see below]")
-		}
+	// Show LaTeX math as Unicode, then word wrap the cleaned text
+	text = rendering.RenderMath(text)
+	wrappedText := rendering.Wrap(text, width-4)
+	sb.WriteString(wrappedText)
 
-		// Word wrap the cleaned text
-		wrappedText := simpleWordWrap(text, width-4)
-		sb.WriteString(wrappedText)
+	// Render artifacts inline if present
+	if len(arts) > 0 {
+		sb.WriteString("\n\n")
 
-		// Render artifacts inline if present
-		if arts := messageArtifacts[msg.ID]; len(arts) > 0 {
-			sb.WriteString("\n\n")
+		for j, artifact := range arts {
+			// Check if this artifact is currently focused
+			isFocused := j == focused
 
-			for j, artifact := range arts {
-				// Check if this artifact is currently focused
-				isFocused := focusedOnArtifact && i == messageIndex && j == artifactIndex
-
-				// Check if this artifact is expanded (default to false = show preview)
-				// false = show maxHeight lines, true = show all lines
-				isExpanded := false
-				if expandedArtifacts != nil {
-					if expanded, exists := expandedArtifacts[artifact.ID]; exists {
-						isExpanded = expanded
-					}
+			// Check if this artifact is expanded (default to false = show preview)
+			// false = show maxHeight lines, true = show all lines
+			isExpanded := false
+			if expandedArtifacts != nil {
+				if expanded, exists := expandedArtifacts[artifact.ID]; exists {
+					isExpanded = expanded
 				}
+			}
 
-				// Render artifact inline with limited height
-				maxHeight := 10
-				artifactRender := renderer.RenderInline(artifact, isFocused, isExpanded, maxHeight)
+			// Render artifact inline with limited height
+			artifactRender := renderer.RenderInline(artifact, isFocused, isExpanded, artifactPreviewLines)
 
-				// Indent the artifact
-				lines := strings.Split(artifactRender, "\n")
-				for _, line := range lines {
-					sb.WriteString("  ")
-					sb.WriteString(line)
-					sb.WriteString("\n")
-				}
+			// Indent the artifact
+			lines := strings.Split(artifactRender, "\n")
+			for _, line := range lines {
+				sb.WriteString("  ")
+				sb.WriteString(line)
+				sb.WriteString("\n")
+			}
 
-				if j < len(arts)-1 {
-					sb.WriteString("\n")
-				}
+			if j < len(arts)-1 {
+				sb.WriteString("\n")
 			}
 		}
+	}
 
-		if i < len(messages)-1 {
-			sb.WriteString("\n\n")
-			sb.WriteString(strings.Repeat("─", width/2))
-			sb.WriteString("\n\n")
-		}
+	return sb.String()
+}
+
+// messageHeader is the sender, model and time shown above a message, and
+// its rating if it has one
+func messageHeader(msg *models.Message) string {
+	header := export.MessageHeader(msg)
+	if msg.Rating != "" {
+		header += fmt.Sprintf(" [%s]", msg.Rating)
 	}
+	return header
+}
 
+// renderConversationFooter renders the key hints below the messages
+func renderConversationFooter(focusedOnArtifact bool, totalArtifacts int) string {
 	// Help text at bottom
 	if focusedOnArtifact {
-		sb.WriteString("\n\n")
-		sb.WriteString(HelpStyle.Render("[Tab] unfocus | [s] save | [←/→] navigate artifacts | [q] back"))
+		return "\n\n" + HelpStyle.Render(i18n.Hints("[Tab] unfocus | [s] save | [←/→] navigate artifacts | [q] back"))
 	} else if totalArtifacts > 0 {
-		sb.WriteString("\n\n")
-		sb.WriteString(HelpStyle.Render("[Tab] focus artifact | [/] find | [q] back"))
+		return "\n\n" + HelpStyle.Render(i18n.Hints("[Tab] focus artifact | [/] find | [q] back"))
 	}
-
-	return sb.String()
+	return ""
 }
@@ -0,0 +1,51 @@
+package tui
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/neilberkman/shannon/internal/i18n"
+	"github.com/neilberkman/shannon/internal/models"
+	"github.com/neilberkman/shannon/internal/repeats"
+)
+
+// shown returns the messages as displayed: with repeated content collapsed
+// when that's on
+func (cv conversationView) shown() []*models.Message {
+	if cv.collapsed != nil {
+		return cv.collapsed
+	}
+	return cv.messages
+}
+
+// collapseRepeats collapses the repeated content of the messages again, after
+// they were loaded or collapsing was turned on or off
+func (cv *conversationView) collapseRepeats() repeats.Result {
+	var result repeats.Result
+	cv.collapsed = nil
+	if cv.collapseQuotes {
+		cv.collapsed, result = repeats.Collapse(cv.messages, repeats.DefaultMinLines)
+	}
+	// Long code is collapsed in what's left
+	cv.collapseLongCode()
+	return result
+}
+
+// toggleCollapse turns collapsing repeated content on or off
+func (cv *conversationView) toggleCollapse() tea.Cmd {
+	cv.collapseQuotes = !cv.collapseQuotes
+	result := cv.collapseRepeats()
+	cv.updateContent()
+	// Matches move, or go, with the text they were found in
+	if cv.findQuery != "" {
+		cv.findMatches = cv.findInConversation(cv.findQuery)
+		cv.currentMatch = 0
+	}
+
+	switch {
+	case !cv.collapseQuotes:
+		return cv.notify(i18n.T("Showing repeated content"))
+	case result.Blocks == 0:
+		return cv.notify(i18n.T("No repeated content to collapse"))
+	default:
+		return cv.notify(i18n.Tf("✓ Collapsed %d repeated block(s), %d lines", result.Blocks, result.Lines))
+	}
+}
@@ -2,16 +2,19 @@ package tui
 
 import (
 	"fmt"
+	"log"
 	"os"
-	"os/exec"
-	"runtime"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/neilberkman/shannon/internal/i18n"
 	"github.com/neilberkman/shannon/internal/models"
+	"github.com/neilberkman/shannon/internal/rendering"
 	"github.com/neilberkman/shannon/internal/search"
+	"github.com/neilberkman/shannon/pkg/platform"
 	"golang.org/x/term"
 )
 
@@ -20,9 +23,19 @@ import (
 // searchConversationItem implements list.Item for search result conversations
 type searchConversationItem struct {
 	conv     *models.Conversation
-	snippets []string // Sample snippets from matching messages
+	snippets []string               // Sample snippets from matching messages
+	matches  int                    // Number of matching messages
+	top      []*models.SearchResult // Best matching messages, shown when expanded
 }
 
+// expandedMatches is how many matching messages space shows for the selected
+// search result
+const expandedMatches = 5
+
+// expandedHeight is the height of the expanded matches pane: a header and
+// two lines per message
+const expandedHeight = 1 + 2*expandedMatches
+
 func (i searchConversationItem) Title() string {
 	return i.conv.Name
 }
@@ -67,6 +80,8 @@ type searchModel struct {
 	width         int
 	height        int
 	query         string
+	showSnippets  bool
+	expanded      bool // whether the selected result's top matches are shown
 
 	// Conversation view handles all conversation display and interaction
 	convView conversationView
@@ -74,44 +89,18 @@ type searchModel struct {
 
 // newSearchModel creates a new search model
 func newSearchModel(engine *search.Engine, results []*models.SearchResult, query string) searchModel {
-	// Group search results by conversation
-	convMap := make(map[int64]*searchConversationItem)
-
-	for _, result := range results {
-		if item, exists := convMap[result.ConversationID]; exists {
-			// Add snippet to existing conversation
-			item.snippets = append(item.snippets, result.Snippet)
-		} else {
-			// Get conversation details
-			conv, _, err := engine.GetConversation(result.ConversationID)
-			if err != nil {
-				continue // Skip if we can't get conversation details
-			}
-
-			// Create new conversation item
-			convMap[result.ConversationID] = &searchConversationItem{
-				conv:     conv,
-				snippets: []string{result.Snippet},
-			}
-		}
-	}
+	grouped := groupSearchResults(engine, results)
 
 	// Convert to list items and store conversations
-	items := make([]list.Item, 0, len(convMap))
-	conversations := make([]*models.Conversation, 0, len(convMap))
-	for _, item := range convMap {
-		// Limit snippets to avoid overwhelming display
-		if len(item.snippets) > 3 {
-			item.snippets = item.snippets[:3]
-		}
-		items = append(items, *item)
+	items := make([]list.Item, 0, len(grouped))
+	conversations := make([]*models.Conversation, 0, len(grouped))
+	for _, item := range grouped {
+		items = append(items, item)
 		conversations = append(conversations, item.conv)
 	}
 
-	// Create list
-	delegate := list.NewDefaultDelegate()
-	delegate.Styles.SelectedTitle = SelectedStyle
-	delegate.Styles.SelectedDesc = SelectedStyle
+	// Create list with highlighted snippets
+	delegate := newSnippetDelegate(showSnippets)
 
 	// Get actual terminal size
 	width, height, err := term.GetSize(int(os.Stdout.Fd()))
@@ -140,7 +129,53 @@ func newSearchModel(engine *search.Engine, results []*models.SearchResult, query
 		width:         width,
 		height:        height,
 		query:         query,
+		showSnippets:  showSnippets,
+	}
+}
+
+// groupSearchResults groups search results by conversation, keeping the order
+// in which conversations first appear in the results
+func groupSearchResults(engine *search.Engine, results []*models.SearchResult) []searchConversationItem {
+	convMap := make(map[int64]*searchConversationItem)
+	var order []int64
+
+	for _, result := range results {
+		if item, exists := convMap[result.ConversationID]; exists {
+			// Add snippet to existing conversation
+			item.snippets = append(item.snippets, result.Snippet)
+			item.matches++
+			if len(item.top) < expandedMatches {
+				item.top = append(item.top, result)
+			}
+		} else {
+			// Get conversation details
+			conv, _, err := engine.GetConversation(result.ConversationID)
+			if err != nil {
+				continue // Skip if we can't get conversation details
+			}
+
+			// Create new conversation item
+			convMap[result.ConversationID] = &searchConversationItem{
+				conv:     conv,
+				snippets: []string{result.Snippet},
+				matches:  1,
+				top:      []*models.SearchResult{result},
+			}
+			order = append(order, result.ConversationID)
+		}
+	}
+
+	items := make([]searchConversationItem, 0, len(order))
+	for _, id := range order {
+		item := convMap[id]
+		// Limit snippets to avoid overwhelming display
+		if len(item.snippets) > 3 {
+			item.snippets = item.snippets[:3]
+		}
+		items = append(items, *item)
 	}
+
+	return items
 }
 
 // Init initializes the model
@@ -156,7 +191,7 @@ func (m searchModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
-		m.list.SetSize(msg.Width, msg.Height-3)
+		m.resizeList()
 
 		// Update conversation view if active
 		if m.mode == ModeConversation {
@@ -164,6 +199,23 @@ func (m searchModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.convView = cv
 		}
 
+	case paletteExportMsg:
+		// Export from the command palette, opening the conversation first
+		if m.mode == ModeList {
+			m.openConversation(msg.conv.ID)
+		}
+		if m.mode == ModeConversation {
+			m.convView.startExport()
+		}
+		skipComponentUpdate = true
+
+	case themeChangedMsg:
+		m.list.SetDelegate(newSnippetDelegate(m.showSnippets))
+		if m.mode == ModeConversation {
+			m.convView.restyle()
+		}
+		skipComponentUpdate = true
+
 	case tea.KeyMsg:
 		switch m.mode {
 		case ModeList:
@@ -182,22 +234,26 @@ func (m searchModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			case "enter", "v":
 				// Both enter and v do the SAME thing - go to conversation view
 				if i, ok := m.list.SelectedItem().(searchConversationItem); ok {
-					conv, messages, err := m.engine.GetConversation(i.conv.ID)
-					if err != nil {
-						// Log error for debugging
-						fmt.Printf("Error loading conversation %d: %v\n", i.conv.ID, err)
+					if len(i.top) > 0 && i.top[0].Branch != "" {
+						cmds = append(cmds, m.openThread(i.top[0]))
 					} else {
-						// Create new conversation view
-						m.convView = newConversationView(conv, messages, m.width, m.height)
-						m.mode = ModeConversation
-						m.selected = m.list.Index()
+						m.openConversation(i.conv.ID)
 					}
 				}
+			case " ":
+				// Show or hide the best matching messages of the selected result
+				m.expanded = !m.expanded
+				m.resizeList()
+				skipComponentUpdate = true
+			case "m":
+				// Toggle between the best snippet and the number of matches
+				m.showSnippets = !m.showSnippets
+				m.list.SetDelegate(newSnippetDelegate(m.showSnippets))
+				skipComponentUpdate = true
 			case "o":
 				// Open conversation in claude.ai
 				if i, ok := m.list.SelectedItem().(searchConversationItem); ok {
-					url := fmt.Sprintf("https://claude.ai/chat/%s", i.conv.UUID)
-					openURL(url)
+					cmds = append(cmds, openURL(claudeURL(m.engine, i.conv), i18n.T("the conversation")))
 				}
 			case "g":
 				// Jump to beginning
@@ -239,6 +295,7 @@ func (m searchModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			// Store the previous states
 			wasInArtifactMode := m.convView.focusedOnArtifact
 			wasInFindMode := m.convView.findActive
+			wasInSubMode := m.convView.handlesEsc()
 
 			// Delegate all conversation handling to convView
 			cv, cmd := m.convView.Update(msg)
@@ -260,6 +317,10 @@ func (m searchModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					// Don't exit conversation mode - just return
 					return m, tea.Batch(cmds...)
 				}
+				// Cleanup mode and the export picker handle esc themselves
+				if wasInSubMode {
+					return m, tea.Batch(cmds...)
+				}
 				// Only exit if not in find mode and not in artifact focus mode
 				if !m.convView.findActive && !m.convView.focusedOnArtifact {
 					m.mode = ModeList
@@ -282,12 +343,52 @@ func (m searchModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, tea.Batch(cmds...)
 }
 
+// openConversation shows a conversation in the conversation view
+func (m *searchModel) openConversation(id int64) {
+	conv, messages, err := m.engine.GetConversation(id)
+	if err != nil {
+		// Log error for debugging
+		fmt.Printf("Error loading conversation %d: %v\n", id, err)
+		return
+	}
+	m.convView = newConversationView(m.engine, conv, messages, m.width, m.height)
+	m.mode = ModeConversation
+	logAccess(m.engine, conv.ID, search.AccessView)
+	m.selected = m.list.Index()
+}
+
+// openThread shows the thread through a search result on a branch other
+// than main, which the conversation as usually shown leaves out, scrolled to
+// the result
+func (m *searchModel) openThread(hit *models.SearchResult) tea.Cmd {
+	conv, messages, err := m.engine.GetThread(hit.ConversationID, hit.MessageID)
+	if err != nil {
+		fmt.Printf("Error loading conversation %d: %v\n", hit.ConversationID, err)
+		return nil
+	}
+	m.convView = newConversationView(m.engine, conv, messages, m.width, m.height)
+	m.mode = ModeConversation
+	logAccess(m.engine, conv.ID, search.AccessView)
+	m.selected = m.list.Index()
+
+	for i, msg := range messages {
+		if msg.ID == hit.MessageID {
+			m.convView.scrollToHeader(i)
+			break
+		}
+	}
+	return m.convView.notify(i18n.Tf("Showing branch %s, where the best match is", hit.Branch))
+}
+
 // View renders the view
 func (m searchModel) View() string {
 	switch m.mode {
 	case ModeList:
 		content := m.list.View()
-		help := HelpStyle.Render("↑/↓/j/k: navigate • g/G: top/bottom • PgUp/PgDn: page • enter: view • o: open in claude.ai • q: quit")
+		if m.expanded {
+			content += "\n" + m.renderExpanded()
+		}
+		help := HelpStyle.Render(i18n.Hints("↑/↓/j/k: navigate • g/G: top/bottom • PgUp/PgDn: page • enter: view • space: expand • m: snippets/matches • o: open in claude.ai • ctrl+k: commands • q: quit"))
 		return content + "\n" + help
 
 	case ModeConversation:
@@ -298,6 +399,38 @@ func (m searchModel) View() string {
 	return ""
 }
 
+// resizeList fits the list to the window, leaving room for the expanded
+// matches pane when it's shown
+func (m *searchModel) resizeList() {
+	height := m.height - 3
+	if m.expanded {
+		height -= expandedHeight
+	}
+	m.list.SetSize(m.width, height)
+}
+
+// renderExpanded renders the best matching messages of the selected result,
+// with their highlighted snippets, at a fixed height so the list doesn't jump
+func (m searchModel) renderExpanded() string {
+	lines := make([]string, 0, expandedHeight)
+	if i, ok := m.list.SelectedItem().(searchConversationItem); ok {
+		header := fmt.Sprintf("Top %d of %s in %s", len(i.top), pluralize(i.matches, "match", "matches"), i.conv.Name)
+		header, _ = truncateRunes(header, nil, m.width)
+		lines = append(lines, TitleStyle.Render(header))
+		for _, r := range i.top {
+			meta := fmt.Sprintf("  %s • %s", rendering.FormatSender(r.Sender), r.CreatedAt.Format("Jan 2, 2006 15:04"))
+			if r.Branch != "" {
+				meta += " • branch " + r.Branch
+			}
+			lines = append(lines, HelpStyle.Render(meta), "  "+highlightSnippet(r.Snippet, m.width-2))
+		}
+	}
+	for len(lines) < expandedHeight {
+		lines = append(lines, "")
+	}
+	return strings.Join(lines, "\n")
+}
+
 // The following methods have been moved to conversationView:
 // - findInConversation
 // - renderConversationWithHighlights
@@ -305,20 +438,59 @@ func (m searchModel) View() string {
 // - getCurrentMessageWithArtifact
 // - saveCurrentArtifact
 
-// openURL opens a URL in the default browser
-func openURL(url string) {
-	var cmd string
-	var args []string
-
-	switch runtime.GOOS {
-	case "windows":
-		cmd = "cmd"
-		args = []string{"/c", "start"}
-	case "darwin":
-		cmd = "open"
-	default: // "linux", "freebsd", "openbsd", "netbsd"
-		cmd = "xdg-open"
+// urlOpenedMsg reports how opening something in the browser went
+type urlOpenedMsg struct {
+	what string // what was opened, for the notification
+	err  error
+}
+
+// String is the notification shown for the result
+func (msg urlOpenedMsg) String() string {
+	if msg.err != nil {
+		return i18n.Tf("Couldn't open %s: %v", msg.what, msg.err)
+	}
+	return i18n.Tf("Opened %s in the browser", msg.what)
+}
+
+// openURL opens url in the browser in the background, reporting back with a
+// urlOpenedMsg
+func openURL(url, what string) tea.Cmd {
+	return func() tea.Msg {
+		return urlOpenedMsg{what: what, err: platform.OpenURL(url)}
+	}
+}
+
+// claudeURL is the claude.ai link to conv, or to the conversation it was
+// split from
+func claudeURL(engine *search.Engine, conv *models.Conversation) string {
+	if engine != nil {
+		url, err := engine.ClaudeURL(conv.ID)
+		if err == nil {
+			return url
+		}
+		log.Printf("%v", err)
+	}
+	return search.ConversationURL(conv.UUID)
+}
+
+// logAccess records a conversation viewed or exported in the TUI for
+// `shannon stats --usage`. Failures go to the debug log rather than the screen.
+func logAccess(engine *search.Engine, convID int64, action string) {
+	if engine == nil {
+		return
+	}
+	if err := engine.LogAccess(convID, action); err != nil {
+		log.Printf("%v", err)
+	}
+}
+
+// logSearch records a search run in the TUI for `shannon stats --usage`,
+// with how long it took if known
+func logSearch(engine *search.Engine, query string, took time.Duration) {
+	if engine == nil {
+		return
+	}
+	if err := engine.LogSearch(query, took); err != nil {
+		log.Printf("%v", err)
 	}
-	args = append(args, url)
-	_ = exec.Command(cmd, args...).Start()
 }
@@ -0,0 +1,109 @@
+package tui
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/neilberkman/shannon/internal/artifacts"
+	"github.com/neilberkman/shannon/internal/i18n"
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+// displayed returns the messages as rendered: as shown, with long code
+// blocks collapsed when that's on. Exports take what's shown instead, so
+// they never have the placeholders.
+func (cv conversationView) displayed() []*models.Message {
+	if cv.codeCollapsed != nil {
+		return cv.codeCollapsed
+	}
+	return cv.shown()
+}
+
+// collapseLongCode collapses the long code blocks of the messages shown
+// again, after they changed or collapsing was turned on or off, and returns
+// how many blocks it collapsed
+func (cv *conversationView) collapseLongCode() int {
+	if !cv.collapseCode {
+		cv.codeCollapsed = nil
+		return 0
+	}
+	extractor := artifacts.NewExtractor()
+	shown := cv.shown()
+	cv.codeCollapsed = make([]*models.Message, len(shown))
+	blocks := 0
+	for i, msg := range shown {
+		var n int
+		cv.codeCollapsed[i], n = extractor.CollapseLongCode(msg, codeCollapseLines, codePlaceholder)
+		blocks += n
+	}
+	return blocks
+}
+
+// codePlaceholder is the line a collapsed code block is shown as
+func codePlaceholder(language string, lines int) string {
+	if language == "" {
+		return i18n.Tf("[code, %d lines · C to show]", lines)
+	}
+	return i18n.Tf("[%s code, %d lines · C to show]", language, lines)
+}
+
+// toggleCodeCollapse turns collapsing long code blocks on or off
+func (cv *conversationView) toggleCodeCollapse() tea.Cmd {
+	cv.collapseCode = !cv.collapseCode
+	blocks := cv.collapseLongCode()
+	cv.updateContent()
+	// Matches move, or go, with the code they were found in
+	if cv.findQuery != "" {
+		cv.findMatches = cv.findInConversation(cv.findQuery)
+		cv.currentMatch = 0
+	}
+
+	switch {
+	case !cv.collapseCode:
+		return cv.notify(i18n.T("Showing all code"))
+	case blocks == 0:
+		return cv.notify(i18n.Tf("No code blocks over %d lines", codeCollapseLines))
+	default:
+		return cv.notify(i18n.Tf("✓ Collapsed %d code block(s) over %d lines", blocks, codeCollapseLines))
+	}
+}
+
+// jumpQuestion scrolls to the start of the next message you sent, or with
+// dir -1 to the start of the one at the top of the screen or else the one
+// before it, so a conversation can be skimmed question by question
+func (cv *conversationView) jumpQuestion(dir int) tea.Cmd {
+	top, line := cv.topMessage()
+	target := -1
+	if dir > 0 {
+		for i := top + 1; i < len(cv.messages); i++ {
+			if cv.messages[i].Sender == "human" {
+				target = i
+				break
+			}
+		}
+	} else {
+		from := top
+		if top >= 0 && line == 0 {
+			from = top - 1
+		}
+		for i := from; i >= 0; i-- {
+			if cv.messages[i].Sender == "human" {
+				target = i
+				break
+			}
+		}
+	}
+	if target < 0 {
+		return nil
+	}
+
+	cv.scrollToHeader(target)
+	question, questions := 0, 0
+	for i, msg := range cv.messages {
+		if msg.Sender == "human" {
+			questions++
+			if i <= target {
+				question++
+			}
+		}
+	}
+	return cv.notify(i18n.Tf("Question %d of %d", question, questions))
+}
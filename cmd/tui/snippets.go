@@ -0,0 +1,187 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// showSnippets controls whether search results start out showing the best
+// snippet or only the number of matches, set from config before the TUI starts
+var showSnippets = true
+
+// snippetDelegate renders search result conversations and conversations
+// matching the browse query bar, highlighting the matched terms of the snippet
+// instead of stripping the <mark> tags
+type snippetDelegate struct {
+	list.DefaultDelegate
+	showSnippets bool
+}
+
+// newSnippetDelegate creates a delegate showing snippets or match counts
+func newSnippetDelegate(showSnippets bool) snippetDelegate {
+	d := list.NewDefaultDelegate()
+	d.Styles.SelectedTitle = SelectedStyle
+	d.Styles.SelectedDesc = SelectedStyle
+	return snippetDelegate{DefaultDelegate: d, showSnippets: showSnippets}
+}
+
+// Render renders a search result item or a filtered browse item, falling
+// back to the default delegate for other items
+func (d snippetDelegate) Render(w io.Writer, m list.Model, index int, item list.Item) {
+	// Browse items always show their match count, before the snippet
+	var title, desc, best string
+	var matches int
+	ok := false
+	switch i := item.(type) {
+	case searchConversationItem:
+		ok = true
+		title = i.Title()
+		desc = fmt.Sprintf("%s • %d messages • ", formatConversationDates(i.conv.CreatedAt, i.conv.UpdatedAt), i.conv.MessageCount)
+		matches = i.matches
+		if len(i.snippets) > 0 {
+			best = i.snippets[0]
+		}
+	case pickItem:
+		ok = true
+		title = i.Title()
+		desc = i.Description() + " • "
+		best = i.result.Snippet
+	case conversationItem:
+		if i.matches > 0 {
+			ok = true
+			title = i.Title()
+			desc = i.Description() + " • "
+			best = i.snippet
+		}
+	}
+	if !ok || m.Width() <= 0 {
+		d.DefaultDelegate.Render(w, m, index, item)
+		return
+	}
+
+	s := &d.Styles
+	textwidth := m.Width() - s.NormalTitle.GetPaddingLeft() - s.NormalTitle.GetPaddingRight()
+
+	title, _ = truncateRunes(title, nil, textwidth)
+
+	var highlighted []int
+	if d.showSnippets && best != "" {
+		var snippet string
+		snippet, highlighted = parseSnippet(best)
+		offset := len([]rune(desc))
+		for n := range highlighted {
+			highlighted[n] += offset
+		}
+		desc += snippet
+	} else if matches > 0 {
+		desc += pluralize(matches, "match", "matches")
+	} else {
+		desc = strings.TrimSuffix(desc, " • ")
+	}
+	desc, highlighted = truncateRunes(desc, highlighted, textwidth)
+
+	titleStyle, descStyle := s.NormalTitle, s.NormalDesc
+	isSelected := index == m.Index() && m.FilterState() != list.Filtering
+	if isSelected {
+		titleStyle, descStyle = s.SelectedTitle, s.SelectedDesc
+	}
+
+	if len(highlighted) > 0 {
+		unmatched := descStyle.Inline(true)
+		matched := unmatched.Inherit(SnippetMatchStyle)
+		desc = lipgloss.StyleRunes(desc, highlighted, matched, unmatched)
+	}
+
+	_, _ = fmt.Fprintf(w, "%s\n%s", titleStyle.Render(title), descStyle.Render(desc))
+}
+
+// parseSnippet strips the <mark> tags FTS adds around matches, returning the
+// plain snippet and the rune indices that were inside marks
+func parseSnippet(snippet string) (string, []int) {
+	snippet = strings.ReplaceAll(snippet, "\n", " ")
+
+	var sb strings.Builder
+	var highlighted []int
+	pos := 0
+	inMark := false
+
+	for len(snippet) > 0 {
+		tag := "<mark>"
+		if inMark {
+			tag = "</mark>"
+		}
+
+		idx := strings.Index(snippet, tag)
+		segment := snippet
+		if idx >= 0 {
+			segment = snippet[:idx]
+		}
+
+		for _, r := range segment {
+			if inMark {
+				highlighted = append(highlighted, pos)
+			}
+			sb.WriteRune(r)
+			pos++
+		}
+
+		if idx < 0 {
+			break
+		}
+		snippet = snippet[idx+len(tag):]
+		inMark = !inMark
+	}
+
+	return sb.String(), highlighted
+}
+
+// highlightSnippet renders an FTS snippet on one line of at most width cells,
+// highlighting its matched terms
+func highlightSnippet(snippet string, width int) string {
+	plain, highlighted := parseSnippet(snippet)
+	plain, highlighted = truncateRunes(plain, highlighted, width)
+	if len(highlighted) == 0 {
+		return plain
+	}
+	unmatched := lipgloss.NewStyle().Inline(true)
+	return lipgloss.StyleRunes(plain, highlighted, unmatched.Inherit(SnippetMatchStyle), unmatched)
+}
+
+// truncateRunes shortens s to fit width cells, adding an ellipsis and dropping
+// highlight indices that no longer exist
+func truncateRunes(s string, highlighted []int, width int) (string, []int) {
+	if width <= 0 || lipgloss.Width(s) <= width {
+		return s, highlighted
+	}
+
+	runes := []rune(s)
+	cells := 0
+	cut := 0
+	for cut < len(runes) {
+		w := lipgloss.Width(string(runes[cut]))
+		if cells+w > width-1 {
+			break
+		}
+		cells += w
+		cut++
+	}
+
+	kept := highlighted[:0:0]
+	for _, idx := range highlighted {
+		if idx < cut {
+			kept = append(kept, idx)
+		}
+	}
+	return string(runes[:cut]) + "…", kept
+}
+
+func pluralize(n int, singular, plural string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, singular)
+	}
+	return fmt.Sprintf("%d %s", n, plural)
+}
@@ -0,0 +1,137 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/neilberkman/shannon/internal/i18n"
+	"github.com/neilberkman/shannon/internal/split"
+)
+
+// startSplit enters split mode with the message at the top of the screen
+// selected
+func (cv *conversationView) startSplit() {
+	if cv.engine == nil || len(cv.messages) < 2 {
+		return
+	}
+
+	cv.focusedOnArtifact = false
+	cv.splitActive = true
+	cv.splitPending = false
+	cv.splitMarks = make(map[int64]bool)
+	cv.splitIndex = 0
+	if top, _ := cv.topMessage(); top >= 0 {
+		cv.splitIndex = top
+	}
+	cv.updateContent()
+	cv.scrollToHeader(cv.splitIndex)
+}
+
+// updateSplit handles keys while marking where to split the conversation
+func (cv *conversationView) updateSplit(msg tea.KeyMsg) tea.Cmd {
+	if cv.splitPending {
+		cv.splitPending = false
+		if msg.String() == "y" {
+			return cv.applySplit()
+		}
+		return cv.notify(i18n.T("Cancelled"))
+	}
+
+	switch msg.String() {
+	case "n", "j", "down":
+		if cv.splitIndex < len(cv.messages)-1 {
+			cv.splitIndex++
+			cv.updateContent()
+			cv.scrollToHeader(cv.splitIndex)
+		}
+	case "N", "k", "up":
+		if cv.splitIndex > 0 {
+			cv.splitIndex--
+			cv.updateContent()
+			cv.scrollToHeader(cv.splitIndex)
+		}
+	case "m", " ":
+		if cv.splitIndex == 0 {
+			return cv.notify(i18n.T("The first message stays in this conversation"))
+		}
+		id := cv.messages[cv.splitIndex].ID
+		if cv.splitMarks[id] {
+			delete(cv.splitMarks, id)
+		} else {
+			cv.splitMarks[id] = true
+		}
+		cv.updateContent()
+	case "enter":
+		if len(cv.splitMarks) == 0 {
+			return cv.notify(i18n.T("Mark a message to start a new conversation at with m"))
+		}
+		cv.splitPending = true
+	case "esc", "p":
+		cv.splitActive = false
+		cv.splitMarks = nil
+		cv.updateContent()
+	default:
+		vp, cmd := cv.viewport.Update(msg)
+		cv.viewport = vp
+		cv.loadMore()
+		return cmd
+	}
+	return nil
+}
+
+// applySplit moves the marked parts of the conversation into conversations
+// of their own and reloads what's left
+func (cv *conversationView) applySplit() tea.Cmd {
+	var at []string
+	for _, m := range cv.messages {
+		if cv.splitMarks[m.ID] {
+			at = append(at, m.UUID)
+		}
+	}
+
+	parts, err := split.Split(cv.engine.DB(), cv.conversation.ID, at)
+	if err != nil {
+		return cv.notify(i18n.Tf("Error: %v", err))
+	}
+
+	cv.splitActive = false
+	cv.splitMarks = nil
+	if err := cv.reload(); err != nil {
+		return cv.notify(i18n.Tf("Error: %v", err))
+	}
+	cv.updateContent()
+
+	ids := make([]string, len(parts))
+	for i, part := range parts {
+		ids[i] = fmt.Sprintf("%d", part.ID)
+	}
+	return cv.notify(i18n.Tf("✓ Split off %d conversation(s): %s", len(parts), strings.Join(ids, ", ")))
+}
+
+// splitHelp renders the status line shown in split mode
+func (cv conversationView) splitHelp() string {
+	if cv.splitPending {
+		return CleanupMarkerStyle.Render(i18n.Tf("Split into %d conversations? y: confirm • any other key: cancel", len(cv.splitMarks)+1))
+	}
+	status := i18n.Tf("Split: message %d/%d • %d marked", cv.splitIndex+1, len(cv.messages), len(cv.splitMarks))
+	return HelpStyle.Render(status + " • " + i18n.Hints("n/N: select • m: mark new conversation start • enter: split • esc: done"))
+}
+
+// markSplitMessages adds markers in front of the headers of the selected
+// message and of the messages that will start new conversations
+func (cv conversationView) markSplitMessages(content string) string {
+	start, _ := cv.window()
+	offsets := cv.messageOffsets(content)
+	lines := strings.Split(content, "\n")
+	for i, line := range offsets {
+		index := start + i
+		if cv.splitMarks[cv.messages[index].ID] {
+			lines[line] = SplitMarkerStyle.Render("✂ ") + lines[line]
+		}
+		if index == cv.splitIndex {
+			lines[line] = CleanupMarkerStyle.Render("▶ ") + lines[line]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -8,52 +8,157 @@ import (
 	"github.com/charmbracelet/lipgloss"
 )
 
-// Shared TUI styles
+// theme holds the colors the TUI styles are built from
+type theme struct {
+	accent    string // titles, headers and the selection
+	onAccent  string // text on the accent and on notifications
+	text      string
+	muted     string // help lines and dates
+	human     string
+	assistant string
+	match     string // matched terms in snippets
+	cleanup   string
+	split     string
+}
+
+// themes are the color schemes ui.theme and the command palette choose from
+var themes = map[string]theme{
+	"dark": {
+		accent: "#7D56F4", onAccent: "#FAFAFA", text: "#FAFAFA", muted: "#626262",
+		human: "#04B575", assistant: "#04B5FF", match: "#FFD700",
+		cleanup: "#FF5F87", split: "#5FD7FF",
+	},
+	"light": {
+		accent: "#5A3FC0", onAccent: "#FAFAFA", text: "#1C1C1C", muted: "#767676",
+		human: "#00875F", assistant: "#005F87", match: "#AF5F00",
+		cleanup: "#D7005F", split: "#0087AF",
+	},
+}
+
+// currentTheme is the name of the theme in use
+var currentTheme = "dark"
+
+// Shared TUI styles, set by setTheme
 var (
+	TitleStyle        lipgloss.Style
+	SelectedStyle     lipgloss.Style
+	HelpStyle         lipgloss.Style
+	ConversationStyle lipgloss.Style
+	DateStyle         lipgloss.Style
+	SnippetStyle      lipgloss.Style
+	HeaderStyle       lipgloss.Style
+	AssistantStyle    lipgloss.Style
+	NotificationStyle lipgloss.Style
+
+	// Find highlight style that respects terminal themes
+	FindHighlightStyle lipgloss.Style
+
+	// Marker for the message selected in cleanup mode
+	CleanupMarkerStyle lipgloss.Style
+
+	// Marker for the messages a split will start new conversations at
+	SplitMarkerStyle lipgloss.Style
+
+	// Highlight for matched terms in search result snippets
+	SnippetMatchStyle lipgloss.Style
+
+	// Box around the command palette
+	PaletteStyle lipgloss.Style
+
+	// Line of timings shown by the profiling overlay
+	ProfileStyle lipgloss.Style
+)
+
+func init() {
+	setTheme(currentTheme)
+}
+
+// setTheme builds the shared styles from the named theme, returning false if
+// there is no such theme
+func setTheme(name string) bool {
+	t, ok := themes[name]
+	if !ok {
+		return false
+	}
+	currentTheme = name
+
 	TitleStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(lipgloss.Color("#7D56F4")).
-			PaddingLeft(2)
+		Bold(true).
+		Foreground(lipgloss.Color(t.accent)).
+		PaddingLeft(2)
 
 	SelectedStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#FAFAFA")).
-			Background(lipgloss.Color("#7D56F4"))
+		Foreground(lipgloss.Color(t.onAccent)).
+		Background(lipgloss.Color(t.accent))
 
 	HelpStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#626262")).
-			PaddingLeft(2)
+		Foreground(lipgloss.Color(t.muted)).
+		PaddingLeft(2)
 
 	ConversationStyle = lipgloss.NewStyle().
-				Foreground(lipgloss.Color("#04B575"))
+		Foreground(lipgloss.Color(t.human))
 
 	DateStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#626262"))
+		Foreground(lipgloss.Color(t.muted))
 
 	SnippetStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#FAFAFA")).
-			PaddingLeft(4)
+		Foreground(lipgloss.Color(t.text)).
+		PaddingLeft(4)
 
 	HeaderStyle = lipgloss.NewStyle().
-			Bold(true).
-			Background(lipgloss.Color("#7D56F4")).
-			Foreground(lipgloss.Color("#FAFAFA")).
-			Padding(0, 1)
+		Bold(true).
+		Background(lipgloss.Color(t.accent)).
+		Foreground(lipgloss.Color(t.onAccent)).
+		Padding(0, 1)
 
 	AssistantStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(lipgloss.Color("#04B5FF"))
+		Bold(true).
+		Foreground(lipgloss.Color(t.assistant))
 
 	NotificationStyle = lipgloss.NewStyle().
-				Background(lipgloss.Color("#04B575")).
-				Foreground(lipgloss.Color("#FAFAFA")).
-				Padding(0, 1).
-				Bold(true)
+		Background(lipgloss.Color(t.human)).
+		Foreground(lipgloss.Color(t.onAccent)).
+		Padding(0, 1).
+		Bold(true)
 
-	// Find highlight style that respects terminal themes
 	FindHighlightStyle = lipgloss.NewStyle().
-				Reverse(true).
-				Bold(true)
-)
+		Reverse(true).
+		Bold(true)
+
+	CleanupMarkerStyle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color(t.cleanup))
+
+	SplitMarkerStyle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color(t.split))
+
+	SnippetMatchStyle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color(t.match))
+
+	PaletteStyle = lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color(t.accent)).
+		Padding(0, 1)
+
+	ProfileStyle = lipgloss.NewStyle().
+		Background(lipgloss.Color(t.accent)).
+		Foreground(lipgloss.Color(t.onAccent)).
+		Padding(0, 1)
+
+	return true
+}
+
+// toggleTheme switches between the dark and light themes
+func toggleTheme() string {
+	if currentTheme == "light" {
+		setTheme("dark")
+	} else {
+		setTheme("light")
+	}
+	return currentTheme
+}
 
 // sanitizeFilename makes a filename safe for the filesystem
 func sanitizeFilename(name string) string {
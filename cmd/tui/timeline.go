@@ -0,0 +1,85 @@
+package tui
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/neilberkman/shannon/internal/i18n"
+	"github.com/neilberkman/shannon/internal/models"
+	"github.com/neilberkman/shannon/internal/rendering"
+)
+
+// timelineLabel starts the timeline line of the conversation header
+const timelineLabel = "Timeline: "
+
+// messageTimeline counts the messages by day for a header width columns
+// wide, or returns nil if they were all sent on one day
+func messageTimeline(messages []*models.Message, width int) *rendering.Timeline {
+	times := make([]time.Time, len(messages))
+	for i, msg := range messages {
+		times[i] = msg.CreatedAt
+	}
+	return rendering.NewTimeline(times, width-len(timelineLabel))
+}
+
+// jumpTimeline scrolls to the first message of the next stretch of the
+// timeline with messages, or with dir -1 to the start of the stretch at the
+// top of the screen or else the one before it
+func (cv *conversationView) jumpTimeline(dir int) tea.Cmd {
+	timeline := messageTimeline(cv.messages, cv.width)
+	if timeline == nil {
+		return nil
+	}
+	cell := func(i int) int {
+		return timeline.Cell(cv.messages[i].CreatedAt)
+	}
+
+	top, line := cv.topMessage()
+	target := -1
+	if dir > 0 {
+		if top < 0 {
+			top = 0
+		}
+		for i := top + 1; i < len(cv.messages); i++ {
+			if cell(i) != cell(top) {
+				target = i
+				break
+			}
+		}
+	} else if top >= 0 {
+		// The start of the stretch at the top, unless it's already there
+		target = cv.stretchStart(top, cell)
+		if target == top && line == 0 {
+			target = -1
+			if top > 0 {
+				target = cv.stretchStart(top-1, cell)
+			}
+		}
+	}
+	if target < 0 {
+		return nil
+	}
+
+	cv.scrollToHeader(target)
+	c := cell(target)
+	count := 0
+	for i := range cv.messages {
+		if cell(i) == c {
+			count++
+		}
+	}
+	when := timeline.CellStart(c).Format("2006-01-02")
+	if timeline.DaysPerCell > 1 {
+		when += i18n.T(" to ") + timeline.CellStart(c).AddDate(0, 0, timeline.DaysPerCell-1).Format("2006-01-02")
+	}
+	return cv.notify(i18n.Tf("%s: %d messages", when, count))
+}
+
+// stretchStart returns the first of the consecutive messages in the same
+// timeline column as message i
+func (cv conversationView) stretchStart(i int, cell func(int) int) int {
+	for i > 0 && cell(i-1) == cell(i) {
+		i--
+	}
+	return i
+}
@@ -6,12 +6,18 @@ import (
 	"strings"
 	"time"
 
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/neilberkman/shannon/internal/clipboard"
 	"github.com/neilberkman/shannon/internal/config"
 	"github.com/neilberkman/shannon/internal/db"
 	"github.com/neilberkman/shannon/internal/discovery"
+	"github.com/neilberkman/shannon/internal/models"
+	"github.com/neilberkman/shannon/internal/query"
 	"github.com/neilberkman/shannon/internal/search"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 // ViewType represents the current active view
@@ -29,28 +35,51 @@ type mainModel struct {
 	viewType         ViewType
 	width            int
 	height           int
-	watchFiles       bool
-	scanner          *discovery.Scanner
+	watcher          *exportWatcher // looks for new exports, with --watch
 	notification     string
 	notificationTime time.Time
+
+	// The command palette, while it's open
+	palette *commandPalette
+
+	// The database in use, if it can be switched from the command palette
+	database *openDatabase
+
+	// Timings for the profiling overlay toggled by F12
+	profile *profileStats
+}
+
+// openDatabase is the database the TUI has open, shared by copies of the
+// model so the one switched to last is the one closed on exit
+type openDatabase struct {
+	db         *db.DB
+	path       string
+	dictionary *search.Dictionary // stopwords and synonyms for searching it
 }
 
 // newMainModel creates a new main model
-func newMainModel(engine *search.Engine, initialQuery string, watchFiles bool) mainModel {
+func newMainModel(engine *search.Engine, initialQuery string, watcher *exportWatcher) mainModel {
 	var currentView tea.Model
 	var viewType ViewType
 
 	if initialQuery != "" {
 		// Start with search view
 		opts := search.SearchOptions{
-			Query:     initialQuery,
 			Limit:     1000,
 			SortBy:    "relevance",
 			SortOrder: "desc",
+			Rank:      searchRank,
 		}
 
-		results, err := engine.Search(opts)
+		started := time.Now()
+		parsed, err := query.Parse(initialQuery, started)
+		var results []*models.SearchResult
+		if err == nil {
+			parsed.Apply(&opts)
+			results, err = engine.Search(opts)
+		}
 		if err == nil {
+			logSearch(engine, initialQuery, time.Since(started))
 			currentView = newSearchModel(engine, results, initialQuery)
 			viewType = ViewSearch
 		} else {
@@ -64,28 +93,18 @@ func newMainModel(engine *search.Engine, initialQuery string, watchFiles bool) m
 		viewType = ViewBrowse
 	}
 
-	var scanner *discovery.Scanner
-	if watchFiles {
-		scanner = discovery.NewScanner()
-	}
-
 	return mainModel{
 		engine:      engine,
 		currentView: currentView,
 		viewType:    viewType,
-		watchFiles:  watchFiles,
-		scanner:     scanner,
+		watcher:     watcher,
+		profile:     &profileStats{},
 	}
 }
 
 // checkExportsMsg is sent when we should check for new exports
 type checkExportsMsg struct{}
 
-// newExportsFoundMsg is sent when new exports are discovered
-type newExportsFoundMsg struct {
-	count int
-}
-
 // switchToBrowseMsg signals that we want to switch to browse mode
 type switchToBrowseMsg struct{}
 
@@ -97,17 +116,31 @@ func (m mainModel) Init() tea.Cmd {
 	cmds = append(cmds, m.currentView.Init())
 
 	// Start export checking if watching
-	if m.watchFiles {
-		cmds = append(cmds, tea.Tick(time.Minute*2, func(t time.Time) tea.Msg {
-			return checkExportsMsg{}
-		}))
+	if m.watcher != nil {
+		cmds = append(cmds, m.watcher.tick(watchInterval))
 	}
 
 	return tea.Batch(cmds...)
 }
 
-// Update handles messages and routes them to child views
+// Update handles messages and routes them to child views, timing them for
+// the profiling overlay
 func (m mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if m.profile == nil {
+		return m.update(msg)
+	}
+	if key, ok := msg.(tea.KeyMsg); ok && key.String() == keyProfile {
+		m.profile.visible = !m.profile.visible
+		return m, nil
+	}
+	started := time.Now()
+	model, cmd := m.update(msg)
+	m.profile.updated(msg, time.Since(started))
+	return model, cmd
+}
+
+// update handles a message
+func (m mainModel) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
@@ -118,36 +151,50 @@ func (m mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, cmd
 
 	case checkExportsMsg:
-		if m.scanner != nil {
-			return m, tea.Batch(
-				func() tea.Msg {
-					exports, err := m.scanner.GetRecentExports(time.Minute * 5)
-					if err != nil || len(exports) == 0 {
-						return nil
-					}
-					return newExportsFoundMsg{count: len(exports)}
-				},
-				tea.Tick(time.Minute*2, func(t time.Time) tea.Msg {
-					return checkExportsMsg{}
-				}),
-			)
+		if m.watcher != nil {
+			return m, m.watcher.scan(m.engine)
 		}
 
-	case newExportsFoundMsg:
-		m.notification = fmt.Sprintf("🆕 Found %d new Claude export(s) in Downloads", msg.count)
-		m.notificationTime = time.Now()
+	case exportsScannedMsg:
+		note, cmd := m.watcher.update(msg, m.engine)
+		if note != "" {
+			m.notify(note)
+		}
+		return m, cmd
+
+	case exportImportedMsg:
+		m.notify(m.watcher.imported(msg))
+		return m, nil
+
+	case urlOpenedMsg:
+		m.notify(msg.String())
+		return m, nil
+
+	case artifactCopiedMsg:
+		m.notify(msg.String())
+		return m, nil
 
 	case switchToBrowseMsg:
 		// Switch from search to browse mode
 		m.currentView = newBrowseModel(m.engine)
 		m.viewType = ViewBrowse
-		return m, nil
+		return m, m.currentView.Init()
 
 	case tea.KeyMsg:
 		// Handle global keybindings
 		switch msg.String() {
 		case "ctrl+c":
 			return m, tea.Quit
+		case "ctrl+k":
+			if m.palette == nil {
+				m.palette = newCommandPalette(contextConversation(m.currentView))
+				return m, textinput.Blink
+			}
+			m.palette = nil
+			return m, nil
+		}
+		if m.palette != nil {
+			return m.updatePalette(msg)
 		}
 	}
 
@@ -170,11 +217,28 @@ func (m mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// notify shows a message at the bottom of the screen for a while
+func (m *mainModel) notify(message string) {
+	m.notification = message
+	m.notificationTime = time.Now()
+}
+
 // View renders the current view
 func (m mainModel) View() string {
+	if m.palette != nil && m.width > 0 {
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, m.palette.View())
+	}
+
+	started := time.Now()
 	view := m.currentView.View()
+	if m.profile != nil {
+		m.profile.rendered(time.Since(started))
+		if m.profile.visible {
+			view += "\n" + m.profile.overlay(m.currentView)
+		}
+	}
 
-	// Add notification if recent and watching
+	// Add notification if recent
 	if m.notification != "" && time.Since(m.notificationTime) < time.Second*10 {
 		// Show notification at the bottom for 10 seconds
 		view += "\n" + NotificationStyle.Render(m.notification)
@@ -194,6 +258,9 @@ func checkViewSwitch(currentView tea.Model) (tea.Model, bool) {
 var (
 	initialQuery string
 	watchFiles   bool
+	autoImport   bool
+	stableFor    time.Duration
+	profilePath  string
 )
 
 // TuiCmd represents the tui command
@@ -209,17 +276,48 @@ Examples:
   claudesearch tui "machine learning"
   
   # Launch TUI in browse mode
-  claudesearch tui`,
+  claudesearch tui
+
+In browse mode, the query bar filters the conversation list as you type,
+showing how many messages of each conversation match. Searches run in the
+background; press esc while one is running to cancel it.
+
+With --watch, new Claude exports in your Downloads folder are reported at the
+bottom of the screen, each once; exports already imported aren't reported.
+--auto-import also imports them in the background once the file has been
+unchanged for --stable-for, so a download in progress is left alone, and
+shows the result at the bottom of the screen.
+
+F12 toggles a line at the bottom of the screen with the time the last frame
+took to render and the last message to handle, the slowest of each, the size
+of the open conversation and memory use. --profile writes pprof data for a
+closer look with 'go tool pprof'.`,
 	RunE: runTUI,
 }
 
 func init() {
 	TuiCmd.Flags().BoolVarP(&watchFiles, "watch", "w", false, "watch Downloads folder for new Claude exports")
+	TuiCmd.Flags().BoolVar(&autoImport, "auto-import", false, "import the exports --watch finds (implies --watch)")
+	TuiCmd.Flags().DurationVar(&stableFor, "stable-for", 30*time.Second, "how long an export must be unchanged before --auto-import imports it")
+	TuiCmd.Flags().StringVar(&profilePath, "profile", "", "write a CPU profile to this file while the TUI runs, and a heap profile to FILE.heap on exit")
+	TuiCmd.Flags().Bool("live", false, "show live results while typing a search")
+	if err := TuiCmd.Flags().MarkDeprecated("live", "the query bar always filters while you type"); err != nil {
+		panic(fmt.Sprintf("failed to deprecate flag: %v", err))
+	}
+	TuiCmd.Flags().Int("artifact-lines", 10, "lines shown for collapsed artifacts (also ui.artifact_preview_lines)")
+	TuiCmd.Flags().Bool("artifact-wrap", true, "wrap long artifact lines instead of cutting them off (also ui.artifact_wrap)")
+
+	if err := viper.BindPFlag("ui.artifact_preview_lines", TuiCmd.Flags().Lookup("artifact-lines")); err != nil {
+		panic(fmt.Sprintf("failed to bind flag: %v", err))
+	}
+	if err := viper.BindPFlag("ui.artifact_wrap", TuiCmd.Flags().Lookup("artifact-wrap")); err != nil {
+		panic(fmt.Sprintf("failed to bind flag: %v", err))
+	}
 }
 
 func runTUI(cmd *cobra.Command, args []string) error {
 	// Initialize clipboard support
-	if err := initClipboard(); err != nil {
+	if err := clipboard.Init(); err != nil {
 		// Log but don't fail - clipboard might not be available in all environments
 		fmt.Fprintf(os.Stderr, "Warning: clipboard initialization failed: %v\n", err)
 	}
@@ -231,30 +329,74 @@ func runTUI(cmd *cobra.Command, args []string) error {
 
 	// Get configuration
 	cfg := config.Get()
+	applyConfig(cfg)
 
 	// Open database
 	database, err := db.New(cfg.Database.Path)
 	if err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
 	}
+	// The command palette can switch to another database, so close whichever
+	// is open at the end
+	open := &openDatabase{
+		db:         database,
+		path:       cfg.Database.Path,
+		dictionary: search.NewDictionary(cfg.Search.Stopwords, cfg.Search.Synonyms),
+	}
 	defer func() {
-		if err := database.Close(); err != nil {
+		if err := open.db.Close(); err != nil {
 			fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
 		}
 	}()
 
-	// Create search engine
+	// Create main model
 	engine := search.NewEngine(database)
+	engine.SetDictionary(open.dictionary)
+	var watcher *exportWatcher
+	if watchFiles || autoImport {
+		watcher = newExportWatcher(discovery.NewScanner(), autoImport, stableFor, cfg.Import.BatchSize)
+	}
+	model := newMainModel(engine, initialQuery, watcher)
+	model.database = open
 
-	// Create main model
-	model := newMainModel(engine, initialQuery, watchFiles)
+	if profilePath != "" {
+		stop, err := startProfile(profilePath)
+		if err != nil {
+			return err
+		}
+		defer stop()
+	}
+
+	return runProgram(model)
+}
+
+// applyConfig sets the package-wide UI settings from the configuration
+func applyConfig(cfg *config.Config) {
+	if cfg.UI.Theme != "" && !setTheme(cfg.UI.Theme) {
+		fmt.Fprintf(os.Stderr, "Warning: unknown ui.theme %q, using %s\n", cfg.UI.Theme, currentTheme)
+	}
+	showSnippets = cfg.Search.ShowSnippets
+	searchRank = cfg.Search.Rank
+	artifactWrap = cfg.UI.ArtifactWrap
+	if cfg.UI.ArtifactPreviewLines > 0 {
+		artifactPreviewLines = cfg.UI.ArtifactPreviewLines
+	}
+	if cfg.UI.CodeCollapseLines > 0 {
+		codeCollapseLines = cfg.UI.CodeCollapseLines
+	}
+	if cfg.UI.SearchDebounceMs > 0 {
+		searchDebounce = time.Duration(cfg.UI.SearchDebounceMs) * time.Millisecond
+	}
+}
 
+// runProgram runs a full-screen TUI program, logging to debug.log if it can
+func runProgram(model tea.Model) error {
 	// Start TUI with logging for debugging
 	debugFile, err := tea.LogToFile("debug.log", "debug")
 	if err != nil {
 		// If logging setup fails, continue without it
-		p := tea.NewProgram(model, tea.WithAltScreen())
-		if _, err := p.Run(); err != nil {
+		p := tea.NewProgram(crashGuard{model}, tea.WithAltScreen())
+		if _, err := runGuarded(p); err != nil {
 			return fmt.Errorf("failed to run TUI: %w", err)
 		}
 		return nil
@@ -265,8 +407,8 @@ func runTUI(cmd *cobra.Command, args []string) error {
 		}
 	}()
 
-	p := tea.NewProgram(model, tea.WithAltScreen())
-	if _, err := p.Run(); err != nil {
+	p := tea.NewProgram(crashGuard{model}, tea.WithAltScreen())
+	if _, err := runGuarded(p); err != nil {
 		return fmt.Errorf("failed to run TUI: %w", err)
 	}
 
@@ -1,16 +1,22 @@
 package tui
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/neilberkman/shannon/internal/artifacts"
+	"github.com/neilberkman/shannon/internal/crash"
 	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/imports"
 	"github.com/neilberkman/shannon/internal/models"
 	"github.com/neilberkman/shannon/internal/search"
+	"github.com/neilberkman/shannon/internal/split"
 )
 
 // assertViewMatchesSnapshot compares the model's view with a golden file.
@@ -79,7 +85,7 @@ func setupTestDB(t *testing.T) *search.Engine {
 
 func TestBrowseView_Initial(t *testing.T) {
 	engine := setupTestDB(t)
-	model := newBrowseModel(engine)
+	model := newLoadedBrowseModel(t, engine)
 	model.list.SetSize(80, 24) // Set a fixed size for consistent test output
 
 	view := model.View()
@@ -88,7 +94,7 @@ func TestBrowseView_Initial(t *testing.T) {
 
 func TestBrowseView_NavigateDown(t *testing.T) {
 	engine := setupTestDB(t)
-	model := newBrowseModel(engine)
+	model := newLoadedBrowseModel(t, engine)
 	model.list.SetSize(80, 24)
 
 	// Send a 'down' key press
@@ -102,7 +108,7 @@ func TestBrowseView_NavigateDown(t *testing.T) {
 
 func TestBrowseView_NavigateUp(t *testing.T) {
 	engine := setupTestDB(t)
-	model := newBrowseModel(engine)
+	model := newLoadedBrowseModel(t, engine)
 	model.list.SetSize(80, 24)
 
 	// Go down, then up
@@ -116,3 +122,1390 @@ func TestBrowseView_NavigateUp(t *testing.T) {
 	// Should be the same as the initial view
 	assertViewMatchesSnapshot(t, view, "browse_initial")
 }
+
+// newLoadedBrowseModel creates a browse model and loads its conversations
+func newLoadedBrowseModel(t *testing.T, engine *search.Engine) browseModel {
+	t.Helper()
+
+	model := newBrowseModel(engine)
+	return loadBrowse(t, model, model.Init())
+}
+
+// loadBrowse feeds a browse model the pages of conversations its command
+// loads, and any pages they lead to
+func loadBrowse(t *testing.T, model browseModel, cmd tea.Cmd) browseModel {
+	t.Helper()
+
+	for _, msg := range pageMsgs(cmd) {
+		updated, next := model.Update(msg)
+		model = loadBrowse(t, updated.(browseModel), next)
+	}
+	return model
+}
+
+// pageMsgs runs a possibly batched command and returns the pages of
+// conversations it loaded
+func pageMsgs(cmd tea.Cmd) []tea.Msg {
+	if cmd == nil {
+		return nil
+	}
+	switch msg := cmd().(type) {
+	case tea.BatchMsg:
+		var msgs []tea.Msg
+		for _, c := range msg {
+			msgs = append(msgs, pageMsgs(c)...)
+		}
+		return msgs
+	case conversationPageMsg:
+		return []tea.Msg{msg}
+	}
+	return nil
+}
+
+// runSearchCmd executes a batched search command and returns the message
+// produced by the search itself, skipping spinner ticks
+func runSearchCmd(t *testing.T, cmd tea.Cmd) tea.Msg {
+	t.Helper()
+
+	batch, ok := cmd().(tea.BatchMsg)
+	if !ok {
+		t.Fatal("expected a batched command")
+	}
+	for _, c := range batch {
+		if c == nil {
+			continue
+		}
+		if msg, ok := c().(filterResultsMsg); ok {
+			return msg
+		}
+	}
+	t.Fatal("no search result message produced")
+	return nil
+}
+
+// insertMessages adds messages to the test conversations, as conversation
+// ID and text pairs
+func insertMessages(t *testing.T, engine *search.Engine, messages ...any) {
+	t.Helper()
+
+	for i := 0; i < len(messages); i += 2 {
+		convID := messages[i].(int)
+		if _, err := engine.DB().Exec("INSERT OR IGNORE INTO branches (id, conversation_id, name) VALUES (?, ?, 'main')", convID, convID); err != nil {
+			t.Fatalf("failed to insert branch: %v", err)
+		}
+		if _, err := engine.DB().Exec(
+			"INSERT INTO messages (uuid, conversation_id, sender, text, created_at, branch_id, sequence) VALUES (?, ?, 'human', ?, ?, ?, ?)",
+			fmt.Sprintf("msg-%d", i), convID, messages[i+1], time.Date(2025, 6, 25, 9, 0, 0, 0, time.UTC), convID, i,
+		); err != nil {
+			t.Fatalf("failed to insert message: %v", err)
+		}
+	}
+}
+
+func TestBrowseView_QueryBar(t *testing.T) {
+	engine := setupTestDB(t)
+	insertMessages(t, engine,
+		2, "deploying with kubernetes",
+		2, "kubernetes ingress rules",
+		3, "a kubernetes question",
+		1, "something else",
+	)
+	model := newLoadedBrowseModel(t, engine)
+	model.list.SetSize(80, 24)
+
+	// The query bar is shown before it has focus
+	if !strings.Contains(model.View(), "Search:") {
+		t.Error("expected the query bar to always be shown")
+	}
+
+	updatedModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'/'}})
+	model = updatedModel.(browseModel)
+	updatedModel, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("kube")})
+	model = updatedModel.(browseModel)
+	stale := searchDebounceMsg{id: model.searchID}
+	updatedModel, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("rnetes")})
+	model = updatedModel.(browseModel)
+
+	// A debounce tick from before the last keystroke doesn't start a search
+	updatedModel, _ = model.Update(stale)
+	model = updatedModel.(browseModel)
+	if model.inFlight {
+		t.Error("expected stale debounce tick to be ignored")
+	}
+
+	updatedModel, cmd := model.Update(searchDebounceMsg{id: model.searchID})
+	model = updatedModel.(browseModel)
+	if !model.inFlight || cmd == nil {
+		t.Fatal("expected debounce tick to start a search in the background")
+	}
+	if !strings.Contains(model.View(), "searching...") {
+		t.Error("expected the view to show a loading indicator")
+	}
+	result := runSearchCmd(t, cmd)
+
+	// ESC cancels the in-flight search and keeps the query, dropping its results
+	updatedModel, _ = model.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	model = updatedModel.(browseModel)
+	if model.inFlight || !model.searching || model.textInput.Value() != "kubernetes" {
+		t.Errorf("expected canceled search to keep the query, got inFlight=%v searching=%v query=%q",
+			model.inFlight, model.searching, model.textInput.Value())
+	}
+	updatedModel, _ = model.Update(result)
+	model = updatedModel.(browseModel)
+	if model.filterQuery != "" || len(model.list.Items()) != 3 {
+		t.Error("expected results of a canceled search to be ignored")
+	}
+
+	// Enter filters right away and moves to the list
+	updatedModel, cmd = model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model = updatedModel.(browseModel)
+	if model.searching {
+		t.Error("expected enter to move focus to the list")
+	}
+	updatedModel, _ = model.Update(runSearchCmd(t, cmd))
+	model = updatedModel.(browseModel)
+
+	items := model.list.Items()
+	if model.filterQuery != "kubernetes" || len(items) != 2 {
+		t.Fatalf("expected 2 conversations matching %q, got %d", model.filterQuery, len(items))
+	}
+	first := items[0].(conversationItem)
+	if first.conv.ID != 2 || first.matches != 2 || !strings.Contains(first.Description(), "2 matches") {
+		t.Errorf("expected conversation 2 with 2 matches first, got %d with %q", first.conv.ID, first.Description())
+	}
+	if !strings.Contains(model.View(), `Matching "kubernetes" (2 conversations)`) {
+		t.Error("expected the list title to show the query and match count")
+	}
+
+	// The filtered list keeps its sort order cycling
+	updatedModel, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'s'}})
+	model = updatedModel.(browseModel)
+	if len(model.list.Items()) != 2 || model.list.Items()[0].(conversationItem).conv.ID != 2 {
+		t.Error("expected sorting by messages to keep the filter")
+	}
+
+	// ESC in the list clears the filter
+	updatedModel, _ = model.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	model = updatedModel.(browseModel)
+	if model.filterQuery != "" || model.textInput.Value() != "" || len(model.list.Items()) != 3 {
+		t.Errorf("expected full list after esc, got %d items", len(model.list.Items()))
+	}
+
+	// Filters in the query work as in shannon search
+	for query, want := range map[string]int{"kubernetes from:h": 2, "kubernetes from:a": 0, "kubernetes b:2025-01-01": 0} {
+		result := runSearchCmd(t, model.startSearch(query)).(filterResultsMsg)
+		if result.err != nil || len(result.matches) != want {
+			t.Errorf("expected %d conversations matching %q, got %d (%v)", want, query, len(result.matches), result.err)
+		}
+	}
+	if result := runSearchCmd(t, model.startSearch("from:h")).(filterResultsMsg); result.err == nil {
+		t.Error("expected a query of only filters to be refused")
+	}
+}
+
+func TestBrowseView_CycleSort(t *testing.T) {
+	engine := setupTestDB(t)
+	if _, err := engine.DB().Exec("UPDATE conversations SET token_count = id * 100 WHERE id != 2"); err != nil {
+		t.Fatal(err)
+	}
+
+	model := newLoadedBrowseModel(t, engine)
+	model.list.SetSize(80, 24)
+
+	firstTitle := func() string {
+		return model.list.Items()[0].(conversationItem).conv.Name
+	}
+
+	// Date → messages
+	updatedModel, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'s'}})
+	model = loadBrowse(t, updatedModel.(browseModel), cmd)
+	if model.list.Title != "Browse Conversations (by messages)" || firstTitle() != "Another Test Convo" {
+		t.Errorf("expected sort by messages, got title %q, first %q", model.list.Title, firstTitle())
+	}
+
+	// Messages → tokens
+	updatedModel, cmd = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'s'}})
+	model = loadBrowse(t, updatedModel.(browseModel), cmd)
+	if firstTitle() != "Final Test" {
+		t.Errorf("expected conversation with most tokens first, got %q", firstTitle())
+	}
+	if desc := model.list.Items()[0].(conversationItem).Description(); !strings.Contains(desc, "~300 tokens") {
+		t.Errorf("expected token count in description, got %q", desc)
+	}
+}
+
+func TestBrowseView_LazyLoading(t *testing.T) {
+	engine := setupTestDB(t)
+	for id := 4; id <= 2*browsePageSize+50; id++ {
+		if _, err := engine.DB().Exec("INSERT INTO conversations (id, uuid, name, created_at, updated_at, message_count) VALUES (?, ?, ?, '2025-01-01 10:00:00', '2025-01-01 10:00:00', ?)",
+			id, fmt.Sprintf("uuid-%d", id), fmt.Sprintf("Conversation %d", id), id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	total := 2*browsePageSize + 50
+	key := func(model browseModel, msg tea.KeyMsg) browseModel {
+		updated, cmd := model.Update(msg)
+		return loadBrowse(t, updated.(browseModel), cmd)
+	}
+
+	// Nothing is loaded before the first paint
+	model := newBrowseModel(engine)
+	model.list.SetSize(80, 24)
+	if len(model.list.Items()) != 0 || !strings.Contains(model.View(), "loading conversations...") {
+		t.Fatalf("expected the view to open while loading:\n%s", model.View())
+	}
+
+	model = loadBrowse(t, model, model.Init())
+	if len(model.list.Items()) != browsePageSize || model.total != total {
+		t.Fatalf("expected the first page of %d, got %d of %d", total, len(model.list.Items()), model.total)
+	}
+	if want := fmt.Sprintf("%d of %d loaded", browsePageSize, total); !strings.Contains(model.list.Title, want) {
+		t.Errorf("expected the title to show %q, got %q", want, model.list.Title)
+	}
+
+	// Nearing the end of what's loaded loads the next page
+	model.list.Select(browsePageSize - 10)
+	model = key(model, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}})
+	if len(model.list.Items()) != 2*browsePageSize || model.list.Index() != browsePageSize-9 {
+		t.Fatalf("expected a second page without moving the selection, got %d items at %d", len(model.list.Items()), model.list.Index())
+	}
+
+	// A page for an order that's since been replaced is dropped
+	stale := conversationPageMsg{id: model.loadID - 1, offset: 0, limit: browsePageSize}
+	updated, _ := model.Update(stale)
+	if model = updated.(browseModel); len(model.list.Items()) != 2*browsePageSize {
+		t.Error("expected a stale page to be ignored")
+	}
+
+	// G loads the rest and selects the last conversation
+	model = key(model, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'G'}})
+	if len(model.list.Items()) != total || model.list.Index() != total-1 {
+		t.Fatalf("expected all %d conversations with the last selected, got %d at %d", total, len(model.list.Items()), model.list.Index())
+	}
+	if model.list.Title != browseTitle(sortDate) {
+		t.Errorf("expected a plain title once everything's loaded, got %q", model.list.Title)
+	}
+
+	// Sorting reloads the first page in the new order
+	model = key(model, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'s'}})
+	first := model.list.Items()[0].(conversationItem).conv
+	if len(model.list.Items()) != browsePageSize || model.list.Index() != 0 || first.ID != int64(total) {
+		t.Errorf("expected the page with the most messages first, got %d items, first %d", len(model.list.Items()), first.ID)
+	}
+}
+
+func TestBrowseView_Groups(t *testing.T) {
+	engine := setupTestDB(t)
+	if _, err := engine.DB().Exec("UPDATE conversations SET updated_at = '2025-05-01 10:00:00' WHERE id = 1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.SetAlias(1, "work"); err != nil {
+		t.Fatal(err)
+	}
+	model := newLoadedBrowseModel(t, engine)
+	model.list.SetSize(80, 40)
+	key := func(k string) {
+		t.Helper()
+		msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+		if k == keyEnter {
+			msg = tea.KeyMsg{Type: tea.KeyEnter}
+		}
+		updated, cmd := model.Update(msg)
+		model = loadBrowse(t, updated.(browseModel), cmd)
+	}
+	// layout describes the list as headers and conversation IDs
+	layout := func() string {
+		var parts []string
+		for _, item := range model.list.Items() {
+			switch i := item.(type) {
+			case groupHeaderItem:
+				parts = append(parts, i.Title())
+			case conversationItem:
+				parts = append(parts, fmt.Sprint(i.conv.ID))
+			}
+		}
+		return strings.Join(parts, " ")
+	}
+
+	key("b")
+	if got, want := layout(), "▾ June 2025 2 3 ▾ May 2025 1"; got != want {
+		t.Fatalf("grouped by month: got %q, want %q", got, want)
+	}
+	if !strings.Contains(model.list.Title, "grouped by month") {
+		t.Errorf("expected the title to name the grouping, got %q", model.list.Title)
+	}
+
+	// Enter on a header collapses its group
+	key(keyEnter)
+	if got, want := layout(), "▸ June 2025 ▾ May 2025 1"; got != want {
+		t.Errorf("collapsed June: got %q, want %q", got, want)
+	}
+	if !strings.Contains(model.list.Items()[0].(groupHeaderItem).Description(), "2 conversations") {
+		t.Error("expected a collapsed header to count its conversations")
+	}
+	key("+")
+	if got, want := layout(), "▾ June 2025 2 3 ▾ May 2025 1"; got != want {
+		t.Errorf("expanded all: got %q, want %q", got, want)
+	}
+
+	// Collapsing everything from a conversation selects its group's header
+	model.list.Select(4)
+	key("-")
+	if got, want := layout(), "▸ June 2025 ▸ May 2025"; got != want || model.list.Index() != 1 {
+		t.Errorf("collapsed all: got %q with %d selected, want %q with 1", got, model.list.Index(), want)
+	}
+
+	key("b")
+	if got, want := layout(), "▾ work 1 ▾ Untagged 2 3"; got != want {
+		t.Errorf("grouped by tag: got %q, want %q", got, want)
+	}
+	key("b")
+	if got, want := layout(), "2 3 1"; got != want {
+		t.Errorf("ungrouped: got %q, want %q", got, want)
+	}
+}
+
+func TestParseSnippet(t *testing.T) {
+	plain, highlighted := parseSnippet("a <mark>test</mark> of\n<mark>marks</mark>")
+	if plain != "a test of marks" {
+		t.Errorf("unexpected plain snippet %q", plain)
+	}
+	expected := []int{2, 3, 4, 5, 10, 11, 12, 13, 14}
+	if len(highlighted) != len(expected) {
+		t.Fatalf("expected highlights %v, got %v", expected, highlighted)
+	}
+	for i := range expected {
+		if highlighted[i] != expected[i] {
+			t.Fatalf("expected highlights %v, got %v", expected, highlighted)
+		}
+	}
+
+	truncated, kept := truncateRunes(plain, highlighted, 8)
+	if truncated != "a test …" || len(kept) != 4 {
+		t.Errorf("unexpected truncation %q with highlights %v", truncated, kept)
+	}
+}
+
+func TestSearchView_ToggleSnippets(t *testing.T) {
+	engine := setupTestDB(t)
+	results := []*models.SearchResult{
+		{ConversationID: 1, Snippet: "first <mark>needle</mark> here"},
+		{ConversationID: 1, Snippet: "second <mark>needle</mark>"},
+		{ConversationID: 2, Snippet: "another <mark>needle</mark>"},
+	}
+
+	model := newSearchModel(engine, results, "needle")
+	model.list.SetSize(80, 24)
+
+	view := model.View()
+	if !strings.Contains(view, "first needle here") || strings.Contains(view, "<mark>") {
+		t.Errorf("expected snippet without mark tags in view:\n%s", view)
+	}
+
+	updatedModel, _ := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'m'}})
+	model = updatedModel.(searchModel)
+	view = model.View()
+	if strings.Contains(view, "first needle here") || !strings.Contains(view, "2 matches") || !strings.Contains(view, "1 match") {
+		t.Errorf("expected match counts instead of snippets in view:\n%s", view)
+	}
+}
+
+func TestSearchView_ExpandMatches(t *testing.T) {
+	engine := setupTestDB(t)
+	var results []*models.SearchResult
+	for i := 1; i <= expandedMatches+2; i++ {
+		results = append(results, &models.SearchResult{ConversationID: 1, Sender: "human", Snippet: fmt.Sprintf("match %d <mark>needle</mark>", i)})
+	}
+	results = append(results, &models.SearchResult{ConversationID: 2, Sender: "assistant", Snippet: "other <mark>needle</mark>"})
+
+	model := newSearchModel(engine, results, "needle")
+	model.height = 30
+	model.width = 80
+	model.resizeList()
+
+	updatedModel, _ := model.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+	model = updatedModel.(searchModel)
+	if !model.expanded || model.list.Height() != 30-3-expandedHeight {
+		t.Fatalf("expected space to expand the selected result and shrink the list, got expanded=%v height=%d", model.expanded, model.list.Height())
+	}
+
+	// Only the best matches of the selected conversation are shown
+	expanded := model.renderExpanded()
+	if !strings.Contains(expanded, fmt.Sprintf("Top %d of %d matches", expandedMatches, expandedMatches+2)) {
+		t.Errorf("expected a header counting the matches shown:\n%s", expanded)
+	}
+	if !strings.Contains(expanded, fmt.Sprintf("match %d needle", expandedMatches)) || strings.Contains(expanded, fmt.Sprintf("match %d needle", expandedMatches+1)) {
+		t.Errorf("expected the first %d matches:\n%s", expandedMatches, expanded)
+	}
+	if strings.Contains(expanded, "<mark>") || strings.Count(expanded, "\n") != expandedHeight-1 {
+		t.Errorf("expected %d lines of highlighted snippets:\n%s", expandedHeight, expanded)
+	}
+
+	// The pane follows the selection
+	updatedModel, _ = model.Update(tea.KeyMsg{Type: tea.KeyDown})
+	model = updatedModel.(searchModel)
+	if expanded := model.renderExpanded(); !strings.Contains(expanded, "other needle") {
+		t.Errorf("expected the next result's matches after moving down:\n%s", expanded)
+	}
+
+	updatedModel, _ = model.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+	model = updatedModel.(searchModel)
+	if model.expanded || model.list.Height() != 30-3 {
+		t.Error("expected space again to collapse the matches")
+	}
+}
+
+func TestConversationView_Cleanup(t *testing.T) {
+	engine := setupTestDB(t)
+
+	fixedTime := time.Date(2025, 6, 25, 9, 0, 0, 0, time.UTC)
+	if _, err := engine.DB().Exec("INSERT INTO branches (id, conversation_id, name) VALUES (1, 3, 'main')"); err != nil {
+		t.Fatal(err)
+	}
+	texts := []string{"Here is my log:\n" + strings.Repeat("ERROR something failed\n", 50), "That error means the disk is full."}
+	for i, text := range texts {
+		sender := "human"
+		if i == 1 {
+			sender = "assistant"
+		}
+		if _, err := engine.DB().Exec(`
+			INSERT INTO messages (id, uuid, conversation_id, sender, text, created_at, branch_id, sequence)
+			VALUES (?, ?, 3, ?, ?, ?, 1, ?)
+		`, i+1, fmt.Sprintf("msg-%d", i+1), sender, text, fixedTime.Add(time.Duration(i)*time.Minute), i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	conv, messages, err := engine.GetConversation(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cv := newConversationView(engine, conv, messages, 100, 30)
+
+	press := func(key string) {
+		t.Helper()
+		cv, _ = cv.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+	}
+
+	press("x")
+	if !cv.cleanupActive || cv.cleanupIndex != 0 {
+		t.Fatalf("expected cleanup mode on the first message, got active=%v index=%d", cv.cleanupActive, cv.cleanupIndex)
+	}
+
+	// Truncating needs confirmation; any other key cancels
+	press("t")
+	press("n")
+	if len(cv.messages[0].Text) != len(texts[0]) {
+		t.Fatal("expected cancelled truncation to leave the message alone")
+	}
+
+	press("t")
+	press("y")
+	if !strings.Contains(cv.messages[0].Text, "[truncated:") {
+		t.Errorf("expected truncated message, got %q", cv.messages[0].Text)
+	}
+
+	press("j")
+	press("d")
+	if !strings.Contains(cv.View(), "Delete message 2") {
+		t.Errorf("expected delete confirmation in view:\n%s", cv.View())
+	}
+	press("y")
+	if len(cv.messages) != 1 || cv.conversation.MessageCount != 1 {
+		t.Fatalf("expected 1 message after delete, got %d (message_count %d)", len(cv.messages), cv.conversation.MessageCount)
+	}
+
+	// Undo restores the most recent change first
+	press("u")
+	if len(cv.messages) != 2 || cv.cleanupIndex != 1 {
+		t.Fatalf("expected deleted message to be restored and selected, got %d messages, index %d", len(cv.messages), cv.cleanupIndex)
+	}
+	press("u")
+	if cv.messages[0].Text != texts[0] {
+		t.Errorf("expected original text after undo, got %q", cv.messages[0].Text)
+	}
+}
+
+func TestConversationView_Split(t *testing.T) {
+	engine := setupTestDB(t)
+	insertMessages(t, engine, 2, "How do I parse flags?", 2, "Use the flag package.", 2, "Unrelated: how do I bake bread?")
+
+	conv, messages, err := engine.GetConversation(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cv := newConversationView(engine, conv, messages, 100, 30)
+
+	press := func(key string) {
+		t.Helper()
+		cv, _ = cv.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+	}
+
+	press("p")
+	if !cv.splitActive || cv.splitIndex != 0 {
+		t.Fatalf("expected split mode on the first message, got active=%v index=%d", cv.splitActive, cv.splitIndex)
+	}
+
+	// The first message can't start a new conversation
+	press("m")
+	if len(cv.splitMarks) != 0 {
+		t.Fatal("expected the first message not to be marked")
+	}
+
+	press("j")
+	press("j")
+	press("m")
+	if !strings.Contains(cv.View(), "✂") {
+		t.Errorf("expected a split marker in view:\n%s", cv.View())
+	}
+
+	// Splitting needs confirmation; any other key cancels
+	cv, _ = cv.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	press("n")
+	if len(cv.messages) != 3 {
+		t.Fatal("expected cancelled split to leave the conversation alone")
+	}
+
+	cv, _ = cv.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if !strings.Contains(cv.View(), "Split into 2 conversations?") {
+		t.Errorf("expected split confirmation in view:\n%s", cv.View())
+	}
+	press("y")
+	if cv.splitActive || len(cv.messages) != 2 || cv.conversation.MessageCount != 2 {
+		t.Fatalf("expected 2 messages left after split, got %d (message_count %d)", len(cv.messages), cv.conversation.MessageCount)
+	}
+
+	links, err := split.GetLinks(engine.DB(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(links.Parts) != 1 || links.Parts[0].Name != "Another Test Convo (part 2)" || links.Parts[0].Messages != 1 {
+		t.Errorf("expected one new conversation with the last message, got %+v", links.Parts)
+	}
+}
+
+func TestConversationView_Rate(t *testing.T) {
+	engine := setupTestDB(t)
+	insertMessages(t, engine, 1, "How do I parse flags?", 1, "Use the flag package.")
+	if _, err := engine.DB().Exec("UPDATE messages SET sender = 'assistant' WHERE text = 'Use the flag package.'"); err != nil {
+		t.Fatal(err)
+	}
+
+	conv, messages, err := engine.GetConversation(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cv := newConversationView(engine, conv, messages, 100, 30)
+
+	// The question is at the top, so the answer below it is rated
+	cv, _ = cv.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("3")})
+	if cv.messages[1].Rating != search.RatingWrong || !strings.Contains(cv.View(), "[wrong]") {
+		t.Fatalf("expected the answer to be rated wrong, got %q:\n%s", cv.messages[1].Rating, cv.View())
+	}
+
+	cv, _ = cv.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("0")})
+	if cv.messages[1].Rating != "" {
+		t.Errorf("expected the rating to be cleared, got %q", cv.messages[1].Rating)
+	}
+}
+
+func TestConversationView_Export(t *testing.T) {
+	engine := setupTestDB(t)
+	t.Chdir(t.TempDir())
+
+	conv, messages, err := engine.GetConversation(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cv := newConversationView(engine, conv, messages, 100, 30)
+
+	cv, _ = cv.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("e")})
+	if !cv.exportActive || !strings.Contains(cv.View(), "Export as:") {
+		t.Fatalf("expected export picker in view:\n%s", cv.View())
+	}
+
+	cv, _ = cv.Update(tea.KeyMsg{Type: tea.KeyRight})
+	cv, _ = cv.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("f")})
+	if cv.exportActive {
+		t.Error("expected picker to close after exporting")
+	}
+
+	files, err := filepath.Glob("*.json")
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected one JSON export, got %v (%v)", files, err)
+	}
+	if !strings.Contains(cv.notification, files[0]) {
+		t.Errorf("expected notification to show the destination, got %q", cv.notification)
+	}
+
+	// esc closes the picker without exporting
+	cv, _ = cv.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("e")})
+	cv, _ = cv.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if cv.exportActive {
+		t.Error("expected esc to close the picker")
+	}
+}
+
+func TestRenderCache(t *testing.T) {
+	conv := &models.Conversation{ID: 1, Name: "Artifacts", UpdatedAt: time.Date(2025, 6, 25, 9, 0, 0, 0, time.UTC)}
+	var messages []*models.Message
+	for i := 0; i < 4; i++ {
+		text := fmt.Sprintf("Question %d", i)
+		sender := "human"
+		if i%2 == 1 {
+			sender = "assistant"
+			text = fmt.Sprintf(`Here you go:
+<antArtifact identifier="art-%d" type="application/vnd.ant.code" language="go" title="Snippet %d">
+%s
+</antArtifact>`, i, i, strings.Repeat("fmt.Println(\"hi\")\n", 15))
+		}
+		messages = append(messages, &models.Message{ID: int64(i + 1), Sender: sender, Text: text, CreatedAt: conv.UpdatedAt.Add(time.Duration(i) * time.Minute)})
+	}
+	cv := conversationView{conversation: conv, messages: messages, width: 80, expandedArtifacts: make(map[string]bool), renders: newRenderCache()}
+	cv.extractArtifacts()
+
+	check := func(step string) {
+		t.Helper()
+		want := RenderConversationWithArtifacts(cv.conversation, cv.messages, cv.artifacts, cv.width, cv.focusedOnArtifact, cv.messageIndex, cv.artifactIndex, cv.expandedArtifacts)
+		if got := cv.renderContent(); got != want {
+			t.Errorf("%s: cached render differs from a fresh render:\n%s\n---\n%s", step, got, want)
+		}
+	}
+
+	check("initial")
+	first := cv.renders.full
+	check("unchanged")
+	if !cv.renders.fullValid || cv.renders.full != first {
+		t.Error("expected an unchanged view to reuse the cached render")
+	}
+
+	cv.focusedOnArtifact, cv.messageIndex = true, 1
+	check("focus")
+	cv.expandedArtifacts["art-1"] = true
+	check("expand")
+	cv.messageIndex = 3
+	check("move focus")
+	cv.width = 60
+	check("resize")
+	if cv.renders.layout.width != 60 {
+		t.Error("expected the cache to be reset for the new width")
+	}
+	cv.messages = cv.messages[:3]
+	check("message removed")
+}
+
+func TestLineIndex(t *testing.T) {
+	idx := newLineIndex("\x1b[1mHuman (2025-06-25)\x1b[0m\r\n🙂 café \x1b[38;5;205mNeedle\x1b[0m and needle")
+
+	if got := idx.find("1m"); len(got) != 0 {
+		t.Errorf("expected escape sequences not to match, got %+v", got)
+	}
+	got := idx.find("NEEDLE")
+	want := []lineMatch{{line: 1, column: 8, width: 6}, {line: 1, column: 19, width: 6}}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected matches %+v, got %+v", want, got)
+	}
+	if got := idx.findInOrder([]string{"Human", "Human"}); len(got) != 1 || got[0] != 0 {
+		t.Errorf("expected each text to be found below the previous one, got %v", got)
+	}
+}
+
+func TestConversationView_ScrollToArtifact(t *testing.T) {
+	conv := &models.Conversation{ID: 1, Name: "Artifacts", UpdatedAt: time.Date(2025, 6, 25, 9, 0, 0, 0, time.UTC)}
+	var messages []*models.Message
+	for i, kind := range []string{"text/markdown", "application/vnd.ant.code", "image/svg+xml"} {
+		messages = append(messages, &models.Message{
+			ID:     int64(i + 1),
+			Sender: "assistant",
+			// The title of the next artifact appears in the text before it
+			Text: fmt.Sprintf(`See Doc %d below
+<antArtifact identifier="doc-%d" type="%s" title="Doc %d">
+%s
+</antArtifact>`, i+1, i, kind, i, strings.Repeat("line\n", 5)),
+			CreatedAt: conv.UpdatedAt.Add(time.Duration(i) * time.Minute),
+		})
+	}
+	cv := newConversationView(nil, conv, messages, 100, 12)
+
+	for i := range messages {
+		cv.focusedOnArtifact, cv.messageIndex, cv.artifactIndex = true, i, 0
+		cv.updateContent()
+		cv.scrollToFocusedArtifact()
+
+		lines := newLineIndex(cv.renderContent()).lines
+		top, header := lines[cv.viewport.YOffset], lines[cv.viewport.YOffset+1]
+		if !strings.Contains(top, "╭") || !strings.Contains(header, fmt.Sprintf("Doc %d", i)) {
+			t.Errorf("artifact %d: expected the box to start at the top, got %q and %q", i, top, header)
+		}
+	}
+}
+
+func TestConversationView_CopyFormat(t *testing.T) {
+	conv := &models.Conversation{ID: 1, Name: "Artifacts", UpdatedAt: time.Date(2025, 6, 25, 9, 0, 0, 0, time.UTC)}
+	messages := []*models.Message{{ID: 1, Sender: "assistant", CreatedAt: conv.UpdatedAt,
+		Text: `<antArtifact identifier="main" type="application/vnd.ant.code" language="go" title="main.go">package main</antArtifact>`}}
+	cv := newConversationView(nil, conv, messages, 100, 30)
+
+	// c only copies with an artifact focused
+	cv, _ = cv.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("c")})
+	if cv.copyActive {
+		t.Fatal("expected no copy picker without a focused artifact")
+	}
+
+	cv, _ = cv.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a")})
+	cv, _ = cv.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("c")})
+	if !cv.copyActive || !strings.Contains(cv.View(), "Copy as:") || !cv.handlesEsc() {
+		t.Fatalf("expected copy picker in view:\n%s", cv.View())
+	}
+
+	cv, _ = cv.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("f")})
+	if artifacts.CopyFormats[cv.copyFormat] != artifacts.CopyMarkdown {
+		t.Errorf("expected f to move to the next format, got %s", artifacts.CopyFormats[cv.copyFormat])
+	}
+	cv, _ = cv.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("c")})
+	if cv.copyActive || cv.notification == "" {
+		t.Errorf("expected picker to close and report the copy, got %q", cv.notification)
+	}
+
+	// The format is remembered for the next copy, and esc cancels
+	cv, _ = cv.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("c")})
+	if artifacts.CopyFormats[cv.copyFormat] != artifacts.CopyMarkdown {
+		t.Errorf("expected the last format to be selected, got %s", artifacts.CopyFormats[cv.copyFormat])
+	}
+	cv, _ = cv.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if cv.copyActive || !cv.focusedOnArtifact {
+		t.Error("expected esc to close the picker and stay on the artifact")
+	}
+
+	// A rich copy is made in the background and reported when it's done
+	cv.copyFormat = slices.Index(artifacts.CopyFormats, artifacts.CopyRich)
+	cv.notification = ""
+	cmd := cv.copyCurrentArtifact()
+	if cmd == nil || cv.notification != "" {
+		t.Fatalf("expected the rich copy to be left to a command, got %q", cv.notification)
+	}
+	copied, ok := cmd().(artifactCopiedMsg)
+	if !ok {
+		t.Fatal("expected the command to report the copy")
+	}
+	cv, _ = cv.Update(copied)
+	if cv.notification != copied.String() {
+		t.Errorf("expected the copy to be reported, got %q", cv.notification)
+	}
+}
+
+func TestConversationView_LazyRendering(t *testing.T) {
+	// A message a minute, all on one day
+	conv := &models.Conversation{ID: 1, Name: "Long", UpdatedAt: time.Date(2025, 6, 25, 6, 0, 0, 0, time.UTC)}
+	var messages []*models.Message
+	for i := 0; i < 1000; i++ {
+		messages = append(messages, &models.Message{
+			ID:        int64(i + 1),
+			Sender:    []string{"human", "assistant"}[i%2],
+			Text:      fmt.Sprintf("Message number %04d", i),
+			CreatedAt: conv.UpdatedAt.Add(time.Duration(i) * time.Minute),
+		})
+	}
+	cv := newConversationView(nil, conv, messages, 100, 20)
+	key := func(keys ...string) {
+		for _, k := range keys {
+			msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+			switch k {
+			case "enter":
+				msg = tea.KeyMsg{Type: tea.KeyEnter}
+			case "pgdown":
+				msg = tea.KeyMsg{Type: tea.KeyPgDown}
+			case "pgup":
+				msg = tea.KeyMsg{Type: tea.KeyPgUp}
+			}
+			cv, _ = cv.Update(msg)
+		}
+	}
+	rendered := func() int {
+		n := 0
+		for _, entry := range cv.renders.entries {
+			if entry.rendered != "" {
+				n++
+			}
+		}
+		return n
+	}
+	topLine := func() string {
+		return newLineIndex(cv.renderContent()).lines[cv.viewport.YOffset]
+	}
+
+	content := cv.renderContent()
+	if rendered() != renderChunk || !strings.Contains(content, "Message number 0099") || strings.Contains(content, "Message number 0100") ||
+		!strings.Contains(content, "↓ 900 more messages") {
+		t.Fatalf("expected only the first %d messages to be rendered, got %d", renderChunk, rendered())
+	}
+
+	// Scrolling down loads more without moving what's on screen
+	for i := 0; i < 200 && cv.windowEnd == renderChunk; i++ {
+		before := topLine()
+		key("pgdown")
+		if cv.windowEnd != renderChunk && !strings.Contains(before, strings.TrimSpace(topLine())) {
+			t.Errorf("expected %q at the top after loading more, got %q", before, topLine())
+		}
+	}
+	if cv.windowEnd != 2*renderChunk {
+		t.Fatalf("expected scrolling to load another chunk, window is %d-%d", cv.windowStart, cv.windowEnd)
+	}
+
+	// G renders the end, dropping the start
+	key("G")
+	content = cv.renderContent()
+	if !strings.Contains(content, "Message number 0999") || !strings.Contains(content, "↑ 900 earlier messages") || rendered() != renderChunk {
+		t.Errorf("expected only the last chunk to be rendered, window is %d-%d", cv.windowStart, cv.windowEnd)
+	}
+	for i := 0; i < 1000 && cv.windowStart > 0; i++ {
+		key("pgup")
+	}
+	if cv.windowStart != 0 || rendered() > maxWindowMessages {
+		t.Errorf("expected scrolling up to reach the start with at most %d messages rendered, window is %d-%d", maxWindowMessages, cv.windowStart, cv.windowEnd)
+	}
+
+	// Find covers messages that aren't rendered
+	key("g", "/")
+	key(strings.Split("number 0500", "")...)
+	key("enter")
+	if len(cv.findMatches) != 1 || !strings.Contains(topLine(), "Message number 0500") {
+		t.Errorf("expected find to scroll to message 500, got %d matches and %q at the top", len(cv.findMatches), topLine())
+	}
+}
+
+func TestConversationView_TimelineJump(t *testing.T) {
+	conv := &models.Conversation{ID: 1, Name: "Weeks", UpdatedAt: time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)}
+	days := []int{1, 1, 1, 3, 3, 10}
+	var messages []*models.Message
+	for i, day := range days {
+		messages = append(messages, &models.Message{
+			ID:        int64(i + 1),
+			Sender:    []string{"human", "assistant"}[i%2],
+			Text:      strings.Repeat(fmt.Sprintf("Message %d\n", i), 20),
+			CreatedAt: time.Date(2025, 3, day, 9, i, 0, 0, time.UTC),
+		})
+	}
+	cv := newConversationView(nil, conv, messages, 100, 12)
+	if !strings.Contains(cv.renderContent(), "Timeline: 2025-03-01 █ ▆      ▃ 2025-03-10") {
+		t.Errorf("expected a timeline in the header, got %q", strings.Split(cv.renderContent(), "\n")[:3])
+	}
+
+	top := func() int {
+		index, _ := cv.topMessage()
+		return index
+	}
+	press := func(k string) {
+		cv, _ = cv.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)})
+	}
+
+	for _, step := range []struct {
+		key      string
+		expected int
+	}{
+		{"}", 3}, {"}", 5}, {"}", 5}, {"{", 3}, {"{", 0},
+	} {
+		press(step.key)
+		if top() != step.expected {
+			t.Fatalf("after %s expected message %d at the top, got %d", step.key, step.expected, top())
+		}
+	}
+	if cv.notification != "2025-03-01: 3 messages" {
+		t.Errorf("expected the day jumped to to be shown, got %q", cv.notification)
+	}
+}
+
+func TestConversationView_CollapseRepeats(t *testing.T) {
+	conv := &models.Conversation{ID: 1, Name: "Crash", UpdatedAt: time.Date(2025, 6, 25, 9, 0, 0, 0, time.UTC)}
+	trace := "goroutine 1 [running]:\nmain.parse(...)\n\t/src/main.go:42\nmain.main()\n\t/src/main.go:17 +0x1d"
+	messages := []*models.Message{
+		{ID: 1, Sender: "human", Text: "Why does this crash?\n" + trace, CreatedAt: conv.UpdatedAt},
+		{ID: 2, Sender: "assistant", Text: "From the trace:\n" + trace + "\nparse reads past the end", CreatedAt: conv.UpdatedAt},
+	}
+	cv := newConversationView(nil, conv, messages, 100, 30)
+
+	cv, _ = cv.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("z")})
+	content := cv.renderContent()
+	if !strings.Contains(content, "[repeated content, 5 lines]") || strings.Count(content, "main.go:42") != 1 {
+		t.Errorf("expected the quoted trace to be collapsed:\n%s", content)
+	}
+	if messages[1].Text != "From the trace:\n"+trace+"\nparse reads past the end" {
+		t.Error("expected the loaded messages to be left alone")
+	}
+
+	cv, _ = cv.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("z")})
+	if content := cv.renderContent(); strings.Contains(content, "repeated content") {
+		t.Errorf("expected z to show the repeats again:\n%s", content)
+	}
+}
+
+func TestConversationView_QuestionJump(t *testing.T) {
+	conv := &models.Conversation{ID: 1, Name: "Questions", UpdatedAt: time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)}
+	senders := []string{"human", "assistant", "assistant", "human", "assistant", "human", "assistant"}
+	var messages []*models.Message
+	for i, sender := range senders {
+		messages = append(messages, &models.Message{
+			ID:        int64(i + 1),
+			Sender:    sender,
+			Text:      strings.Repeat(fmt.Sprintf("Message %d\n", i), 20),
+			CreatedAt: conv.UpdatedAt.Add(time.Duration(i) * time.Minute),
+		})
+	}
+	cv := newConversationView(nil, conv, messages, 100, 12)
+
+	for _, step := range []struct {
+		key      string
+		expected int
+	}{
+		{"]", 0}, {"]", 3}, {"]", 5}, {"]", 5}, {"[", 3}, {"[", 0},
+	} {
+		cv, _ = cv.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(step.key)})
+		if top, _ := cv.topMessage(); top != step.expected {
+			t.Fatalf("after %s expected message %d at the top, got %d", step.key, step.expected, top)
+		}
+	}
+	if cv.notification != "Question 1 of 3" {
+		t.Errorf("expected the question jumped to to be shown, got %q", cv.notification)
+	}
+
+	// Scrolled into an answer, [ goes back to the question it answers
+	cv, _ = cv.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("]")})
+	cv, _ = cv.Update(tea.KeyMsg{Type: tea.KeyDown})
+	cv, _ = cv.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("[")})
+	if top, line := cv.topMessage(); top != 3 || line != 0 {
+		t.Errorf("expected [ to go back to the start of message 3, got message %d line %d", top, line)
+	}
+}
+
+func TestConversationView_CollapseCode(t *testing.T) {
+	conv := &models.Conversation{ID: 1, Name: "Code", UpdatedAt: time.Date(2025, 6, 25, 9, 0, 0, 0, time.UTC)}
+	code := strings.TrimSuffix(strings.Repeat("fmt.Println(\"hello\")\n", codeCollapseLines+1), "\n")
+	messages := []*models.Message{
+		{ID: 1, Sender: "human", Text: "Print a greeting a lot", CreatedAt: conv.UpdatedAt},
+		{ID: 2, Sender: "assistant", Text: "Like this:\n```go\n" + code + "\n```\nAnd short:\n```go\nfmt.Println()\n```", CreatedAt: conv.UpdatedAt},
+	}
+	cv := newConversationView(nil, conv, messages, 100, 30)
+
+	cv, _ = cv.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("C")})
+	content := cv.renderContent()
+	if !strings.Contains(content, fmt.Sprintf("[go code, %d lines · C to show]", codeCollapseLines+1)) ||
+		strings.Contains(content, "hello") || !strings.Contains(content, "fmt.Println()") {
+		t.Errorf("expected only the long code block to be collapsed:\n%s", content)
+	}
+	if !strings.Contains(cv.shown()[1].Text, "hello") {
+		t.Error("expected exports to keep the code")
+	}
+
+	// Collapsing repeats keeps the code collapsed
+	cv, _ = cv.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("z")})
+	if strings.Contains(cv.renderContent(), "hello") {
+		t.Error("expected the code to stay collapsed after z")
+	}
+
+	cv, _ = cv.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("C")})
+	if content := cv.renderContent(); !strings.Contains(content, "hello") || strings.Contains(content, "C to show") {
+		t.Errorf("expected C to show the code again:\n%s", content)
+	}
+}
+
+func TestCarousel(t *testing.T) {
+	engine := setupTestDB(t)
+
+	var m tea.Model = newCarouselModel(engine, "Random conversations", []int64{2, 3, 1})
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 100, Height: 30})
+	key := func(s string) tea.Cmd {
+		var cmd tea.Cmd
+		m, cmd = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)})
+		return cmd
+	}
+	current := func() string { return m.(carouselModel).convView.conversation.Name }
+
+	if current() != "Another Test Convo" || !strings.Contains(m.View(), "1/3") {
+		t.Fatalf("expected the first conversation, got %q", current())
+	}
+	key("[")
+	if current() != "Another Test Convo" {
+		t.Errorf("expected [ on the first conversation to stay, got %q", current())
+	}
+	key("]")
+	key("]")
+	key("]")
+	if current() != "Test Conversation 1" || !strings.Contains(m.View(), "3/3") {
+		t.Errorf("expected ] to stop at the last conversation, got %q", current())
+	}
+	key("[")
+	if current() != "Final Test" {
+		t.Errorf("expected [ to go back, got %q", current())
+	}
+
+	// While finding, ] is typed into the find input
+	key("/")
+	key("]")
+	if current() != "Final Test" || m.(carouselModel).convView.textInput.Value() != "]" {
+		t.Errorf("expected ] to be typed into find, got %q", m.(carouselModel).convView.textInput.Value())
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if cmd := key("q"); cmd == nil || cmd() != tea.Quit() {
+		t.Error("expected q to quit")
+	}
+}
+
+func TestClusterModel(t *testing.T) {
+	engine := setupTestDB(t)
+	conversations, err := engine.GetAllConversations(10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups := []ClusterGroup{
+		{Label: "tests, convos", Conversations: conversations[:2]},
+		{Label: "final", Conversations: conversations[2:]},
+	}
+
+	var m tea.Model = newClusterModel(engine, groups)
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 100, Height: 30})
+	key := func(msg tea.KeyMsg) tea.Cmd {
+		var cmd tea.Cmd
+		m, cmd = m.Update(msg)
+		return cmd
+	}
+	enter, esc := tea.KeyMsg{Type: tea.KeyEnter}, tea.KeyMsg{Type: tea.KeyEsc}
+
+	if view := m.View(); !strings.Contains(view, "tests, convos") || !strings.Contains(view, "2 conversations") {
+		t.Fatalf("expected the clusters with their sizes, got:\n%s", view)
+	}
+	key(enter)
+	if view := m.View(); m.(clusterModel).level != clusterLevelConversations || !strings.Contains(view, conversations[1].Name) {
+		t.Fatalf("expected the first cluster's conversations, got:\n%s", view)
+	}
+	key(enter)
+	if cm := m.(clusterModel); cm.level != clusterLevelConversation || cm.convView.conversation.ID != conversations[0].ID {
+		t.Fatal("expected enter to open the selected conversation")
+	}
+	key(esc)
+	key(esc)
+	if m.(clusterModel).level != clusterLevelTopics {
+		t.Error("expected esc to go back to the clusters")
+	}
+	if cmd := key(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")}); cmd == nil || cmd() != tea.Quit() {
+		t.Error("expected q to quit")
+	}
+}
+
+func TestStatsDashboard(t *testing.T) {
+	engine := setupTestDB(t)
+	if _, err := engine.DB().Exec("INSERT INTO branches (id, conversation_id, name) VALUES (1, 3, 'main')"); err != nil {
+		t.Fatal(err)
+	}
+	text := "Here you go:\n<antArtifact identifier=\"fib\" type=\"application/vnd.ant.code\" language=\"python\" title=\"Fibonacci\">\ndef fib(n):\n    return n\n</antArtifact>"
+	if _, err := engine.DB().Exec(`
+		INSERT INTO messages (id, uuid, conversation_id, sender, text, created_at, branch_id, sequence)
+		VALUES (1, 'msg-1', 3, 'assistant', ?, ?, 1, 0)
+	`, text, time.Date(2025, 6, 25, 10, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := engine.RebuildCodeIndex(); err != nil {
+		t.Fatal(err)
+	}
+
+	dm, err := newDashboardModel(engine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m tea.Model = dm
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 100, Height: 30})
+	key := func(msg tea.KeyMsg) {
+		m, _ = m.Update(msg)
+	}
+	enter, esc, tab := tea.KeyMsg{Type: tea.KeyEnter}, tea.KeyMsg{Type: tea.KeyEsc}, tea.KeyMsg{Type: tea.KeyTab}
+
+	if view := m.View(); !strings.Contains(view, "June 2025") || !strings.Contains(view, "3 conversations") {
+		t.Fatalf("expected the conversations by month, got:\n%s", view)
+	}
+	key(enter)
+	if view := m.View(); m.(dashboardModel).level != dashboardLevelItems || !strings.Contains(view, "Another Test Convo") {
+		t.Fatalf("expected the month's conversations, got:\n%s", view)
+	}
+	key(esc)
+	key(tab)
+	if view := m.View(); !strings.Contains(view, "python") || !strings.Contains(view, "1 code artifact") {
+		t.Fatalf("expected the artifacts by language, got:\n%s", view)
+	}
+	key(enter)
+	if view := m.View(); !strings.Contains(view, "Fibonacci") {
+		t.Fatalf("expected the language's artifacts, got:\n%s", view)
+	}
+	key(enter)
+	dm = m.(dashboardModel)
+	if dm.level != dashboardLevelConversation || dm.convView.conversation.ID != 3 {
+		t.Fatal("expected enter to open the artifact's conversation")
+	}
+	if !dm.convView.focusedOnArtifact || dm.convView.messageIndex != 0 {
+		t.Error("expected the artifact to be focused")
+	}
+}
+
+func TestPicker(t *testing.T) {
+	engine := setupTestDB(t)
+	if _, err := engine.DB().Exec("INSERT INTO branches (id, conversation_id, name) VALUES (1, 3, 'main')"); err != nil {
+		t.Fatal(err)
+	}
+	created := time.Date(2025, 6, 25, 10, 0, 0, 0, time.UTC)
+	if _, err := engine.DB().Exec(`
+		INSERT INTO messages (id, uuid, conversation_id, sender, text, created_at, branch_id, sequence)
+		VALUES (1, 'msg-1', 3, 'assistant', 'Reload nginx with nginx -s reload', ?, 1, 0)
+	`, created); err != nil {
+		t.Fatal(err)
+	}
+	results := []*models.SearchResult{{
+		ConversationID: 3, ConversationName: "Final Test", MessageID: 1, Sender: "assistant",
+		Text: "Reload nginx with nginx -s reload", Snippet: "Reload <mark>nginx</mark> with", CreatedAt: created,
+	}}
+
+	var m tea.Model = newPickModel(engine, results, "nginx")
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 100, Height: 30})
+	key := func(msg tea.KeyMsg) tea.Cmd {
+		var cmd tea.Cmd
+		m, cmd = m.Update(msg)
+		return cmd
+	}
+	runes := func(s string) tea.KeyMsg { return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)} }
+
+	if view := m.View(); !strings.Contains(view, "Final Test") || !strings.Contains(view, "Reload nginx with") {
+		t.Fatalf("expected the result with its snippet, got:\n%s", view)
+	}
+	key(runes("o"))
+	if pm := m.(pickModel); !pm.viewing || pm.convView.conversation.ID != 3 {
+		t.Fatal("expected o to open the result's conversation")
+	}
+	key(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.(pickModel).viewing {
+		t.Fatal("expected esc to go back to the results")
+	}
+
+	if cmd := key(runes("c")); cmd == nil || cmd() != tea.Quit() {
+		t.Fatal("expected c to close the picker")
+	}
+	if pm := m.(pickModel); pm.action != pickCopy || pm.picked != results[0] {
+		t.Errorf("expected the result to be picked for copying, got action %d", pm.action)
+	}
+}
+
+func TestCommandPalette(t *testing.T) {
+	engine := setupTestDB(t)
+	t.Cleanup(func() { setTheme("dark") })
+
+	var m tea.Model = newMainModel(engine, "", nil)
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 100, Height: 30})
+	for _, msg := range pageMsgs(m.Init()) {
+		m, _ = m.Update(msg)
+	}
+	key := func(msg tea.KeyMsg) tea.Cmd {
+		var cmd tea.Cmd
+		m, cmd = m.Update(msg)
+		return cmd
+	}
+	typeText := func(s string) { key(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}) }
+	ctrlK, enter := tea.KeyMsg{Type: tea.KeyCtrlK}, tea.KeyMsg{Type: tea.KeyEnter}
+
+	// The palette acts on the selected conversation
+	key(ctrlK)
+	p := m.(mainModel).palette
+	if p == nil || p.conv == nil || p.conv.ID != 1 {
+		t.Fatalf("expected the palette to open for conversation 1, got %+v", p)
+	}
+	if view := m.View(); !strings.Contains(view, "Commands for Test Conversation 1") || !strings.Contains(view, "Switch database") {
+		t.Fatalf("expected the palette in the view, got:\n%s", view)
+	}
+
+	typeText("thm")
+	if len(p.matches) != 1 || p.matches[0].id != actionTheme {
+		t.Fatalf("expected fuzzy filtering to leave the theme toggle, got %+v", p.matches)
+	}
+	key(enter)
+	if m.(mainModel).palette != nil || currentTheme != "light" {
+		t.Fatalf("expected the theme toggle to run and close the palette, theme %s", currentTheme)
+	}
+
+	// Tagging prompts for the alias
+	key(ctrlK)
+	typeText("alias")
+	key(enter)
+	typeText("bad alias!")
+	key(enter)
+	if p := m.(mainModel).palette; p == nil || p.err == "" {
+		t.Fatal("expected an invalid alias to keep the prompt open with an error")
+	}
+	m.(mainModel).palette.input.SetValue("palette")
+	key(enter)
+	if id, err := engine.ResolveConversation("palette"); err != nil || id != 1 {
+		t.Fatalf("expected the alias to name conversation 1, got %d (%v)", id, err)
+	}
+
+	// Exporting opens the conversation with the export picker
+	key(ctrlK)
+	typeText("export")
+	cmd := key(enter)
+	if cmd == nil {
+		t.Fatal("expected export to ask the view to export")
+	}
+	m, _ = m.Update(cmd())
+	if bm := m.(mainModel).currentView.(browseModel); bm.mode != ModeConversation || !bm.convView.exportActive {
+		t.Fatal("expected the export picker to open on the conversation")
+	}
+
+	// Switching to a database that doesn't exist keeps the prompt open
+	other := filepath.Join(t.TempDir(), "other.db")
+	mm := m.(mainModel)
+	mm.database = &openDatabase{db: engine.DB(), path: ":memory:"}
+	m = mm
+	key(ctrlK)
+	typeText("switch")
+	key(enter)
+	if p := m.(mainModel).palette; p.input.Value() != ":memory:" {
+		t.Fatalf("expected the prompt to start from the current database, got %q", p.input.Value())
+	}
+	m.(mainModel).palette.input.SetValue(other)
+	key(enter)
+	if p := m.(mainModel).palette; p == nil || !strings.Contains(p.err, "no database") {
+		t.Fatal("expected a missing database to be refused")
+	}
+
+	created, err := db.New(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := created.Close(); err != nil {
+		t.Fatal(err)
+	}
+	key(enter)
+	mm = m.(mainModel)
+	t.Cleanup(func() { _ = mm.database.db.Close() })
+	if mm.palette != nil || mm.database.path != other {
+		t.Fatalf("expected to switch to %s, got %s", other, mm.database.path)
+	}
+	if bm := mm.currentView.(browseModel); len(bm.conversations) != 0 {
+		t.Errorf("expected to browse the empty database, got %d conversations", len(bm.conversations))
+	}
+}
+
+func TestProfileOverlay(t *testing.T) {
+	engine := setupTestDB(t)
+
+	var m tea.Model = newMainModel(engine, "", nil)
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 100, Height: 30})
+	for _, msg := range pageMsgs(m.Init()) {
+		m, _ = m.Update(msg)
+	}
+	if strings.Contains(m.View(), "⏱") {
+		t.Fatal("expected no overlay until F12 is pressed")
+	}
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyF12})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	view := m.View()
+	for _, want := range []string{"⏱ render", "update", "KeyMsg", "conversation", "heap"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("expected %q in the overlay:\n%s", want, view)
+		}
+	}
+	if profile := m.(mainModel).profile; profile.updates == 0 || profile.lastRender == 0 {
+		t.Errorf("expected updates and renders to be timed, got %+v", profile)
+	}
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyF12})
+	if strings.Contains(m.View(), "⏱") {
+		t.Error("expected F12 to hide the overlay again")
+	}
+}
+
+func TestExportWatcher(t *testing.T) {
+	engine := setupTestDB(t)
+	path := filepath.Join(t.TempDir(), "conversations.json")
+	export := `[{"uuid": "watched", "name": "Watched", "created_at": "2024-01-01T10:00:00Z", "updated_at": "2024-01-01T10:00:00Z",
+	 "chat_messages": [{"uuid": "watched-1", "sender": "human", "text": "hello", "created_at": "2024-01-01T10:00:00Z"}]}]`
+	if err := os.WriteFile(path, []byte(export), 0644); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := imports.FileHash(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := watchedFile{path: path, FileState: imports.FileState{Size: int64(len(export)), ModTime: time.Now()}, hash: hash}
+	scanned := func(f watchedFile) exportsScannedMsg { return exportsScannedMsg{files: []watchedFile{f}} }
+
+	// Without auto-import each export is reported once
+	w := newExportWatcher(nil, false, 10*time.Second, 0)
+	if note, _ := w.update(scanned(file), engine); !strings.Contains(note, "Found 1 new Claude export") {
+		t.Errorf("expected the export to be reported, got %q", note)
+	}
+	if note, _ := w.update(scanned(file), engine); note != "" || len(w.importing) != 0 {
+		t.Errorf("expected no repeat report and no import, got %q", note)
+	}
+
+	// With it, an export is imported once it's been unchanged for the window
+	w = newExportWatcher(nil, true, 10*time.Second, 0)
+	if note, _ := w.update(scanned(file), engine); !strings.Contains(note, "importing once it's unchanged for 10s") {
+		t.Errorf("expected the export to be reported as waiting, got %q", note)
+	}
+	if w.update(scanned(file), engine); len(w.importing) != 0 {
+		t.Error("expected an export written moments ago not to be imported")
+	}
+	file.ModTime = time.Now().Add(-time.Minute)
+	if w.update(scanned(file), engine); len(w.importing) != 0 {
+		t.Error("expected an export that changed since the last scan not to be imported")
+	}
+	if note, _ := w.update(scanned(file), engine); note != "" || !w.importing[hash] {
+		t.Fatalf("expected the settled export to be imported without another report, got %q", note)
+	}
+
+	msg := w.importExport(engine, file)().(exportImportedMsg)
+	if note := w.imported(msg); note != "✓ Imported conversations.json: 1 conversation(s), 1 message(s)" {
+		t.Errorf("unexpected import result %q", note)
+	}
+	var imported int
+	if err := engine.DB().QueryRow("SELECT COUNT(*) FROM conversations WHERE uuid = 'watched'").Scan(&imported); err != nil || imported != 1 {
+		t.Errorf("expected the conversation to be imported, got %d (%v)", imported, err)
+	}
+
+	// Exports already imported are left alone, even by a new watcher
+	file.imported = true
+	w = newExportWatcher(nil, true, 10*time.Second, 0)
+	for i := 0; i < 2; i++ {
+		if note, _ := w.update(scanned(file), engine); note != "" || len(w.importing) != 0 {
+			t.Errorf("expected an imported export to be ignored, got %q", note)
+		}
+	}
+}
+
+// panicModel panics on any key
+type panicModel struct{}
+
+func (panicModel) Init() tea.Cmd                       { return nil }
+func (panicModel) Update(tea.Msg) (tea.Model, tea.Cmd) { panic("bad key") }
+func (panicModel) View() string                        { return "" }
+
+func TestCrashGuard(t *testing.T) {
+	dir := t.TempDir()
+	crash.SetDir(dir)
+	defer crash.SetDir("")
+
+	func() {
+		defer func() {
+			if r := recover(); r != "bad key" {
+				t.Errorf("recovered %v, want the panic to reach Bubble Tea", r)
+			}
+		}()
+		crashGuard{panicModel{}}.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	}()
+
+	if filepath.Dir(crash.LastReport()) != dir {
+		t.Fatalf("no crash report in %s", dir)
+	}
+	report, err := os.ReadFile(crash.LastReport())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(report), "key x\n") || !strings.Contains(string(report), "Panic: bad key") {
+		t.Errorf("report doesn't record the key and panic:\n%s", report)
+	}
+}
@@ -0,0 +1,211 @@
+package tui
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/neilberkman/shannon/internal/discovery"
+	"github.com/neilberkman/shannon/internal/i18n"
+	"github.com/neilberkman/shannon/internal/imports"
+	"github.com/neilberkman/shannon/internal/models"
+	"github.com/neilberkman/shannon/internal/search"
+)
+
+const (
+	// watchInterval is how often --watch looks for new exports
+	watchInterval = 2 * time.Minute
+	// watchRecent is how recently an export must have changed to be new
+	watchRecent = 5 * time.Minute
+)
+
+// exportWatcher looks for new Claude exports while the TUI runs, reporting
+// each once and, with auto-import on, importing it once the file has stopped
+// changing. Copies of the main model share it.
+type exportWatcher struct {
+	scanner    *discovery.Scanner
+	autoImport bool
+	stableFor  time.Duration // how long a file must be unchanged to be imported
+	batchSize  int           // messages per insert when importing
+
+	seen      map[string]watchedFile // the last look at each export, by path
+	reported  map[string]bool        // hashes already reported or imported
+	importing map[string]bool        // hashes being imported
+	failed    map[string]bool        // hashes whose import failed, not retried
+}
+
+// watchedFile is an export as a scan found it
+type watchedFile struct {
+	imports.FileState
+	path     string
+	hash     string
+	imported bool // already in the import history
+}
+
+// exportsScannedMsg carries what a scan for exports found
+type exportsScannedMsg struct {
+	files []watchedFile
+	err   error
+}
+
+// exportImportedMsg reports an export imported in the background
+type exportImportedMsg struct {
+	file  watchedFile
+	stats *models.ImportStats
+	err   error
+}
+
+// newExportWatcher creates a watcher for the scanner's folders
+func newExportWatcher(scanner *discovery.Scanner, autoImport bool, stableFor time.Duration, batchSize int) *exportWatcher {
+	return &exportWatcher{
+		scanner:    scanner,
+		autoImport: autoImport,
+		stableFor:  stableFor,
+		batchSize:  batchSize,
+		seen:       make(map[string]watchedFile),
+		reported:   make(map[string]bool),
+		importing:  make(map[string]bool),
+		failed:     make(map[string]bool),
+	}
+}
+
+// tick schedules the next scan
+func (w *exportWatcher) tick(after time.Duration) tea.Cmd {
+	return tea.Tick(after, func(time.Time) tea.Msg {
+		return checkExportsMsg{}
+	})
+}
+
+// scan looks for recent exports in the background, hashing the ones that
+// changed since the last scan and checking them against the import history
+func (w *exportWatcher) scan(engine *search.Engine) tea.Cmd {
+	seen := make(map[string]watchedFile, len(w.seen))
+	for path, file := range w.seen {
+		seen[path] = file
+	}
+	importer := imports.NewImporter(engine.DB(), 0, false)
+
+	return func() tea.Msg {
+		exports, err := w.scanner.GetRecentExports(watchRecent)
+		if err != nil {
+			return exportsScannedMsg{err: err}
+		}
+
+		var files []watchedFile
+		for _, export := range exports {
+			if !export.IsValid {
+				continue
+			}
+			// Exports inside a zip are hashed by their archive
+			path, _, _ := strings.Cut(export.Path, "!")
+			file := watchedFile{path: export.Path, FileState: imports.FileState{Size: export.Size, ModTime: export.ModTime}}
+			if prev, ok := seen[export.Path]; ok && prev.Same(file.FileState) {
+				file.hash = prev.hash
+			} else if file.hash, err = imports.FileHash(path); err != nil {
+				continue
+			}
+			if file.imported, err = importer.IsImported(file.hash); err != nil {
+				return exportsScannedMsg{err: err}
+			}
+			files = append(files, file)
+		}
+		return exportsScannedMsg{files: files}
+	}
+}
+
+// update decides what to do about the exports a scan found: which to report,
+// which to import, and when to look again
+func (w *exportWatcher) update(msg exportsScannedMsg, engine *search.Engine) (string, tea.Cmd) {
+	if msg.err != nil {
+		return "", w.tick(watchInterval)
+	}
+
+	var found, pending []string // new exports, and those to be imported
+	var cmds []tea.Cmd
+	waiting := false
+	seen := make(map[string]watchedFile, len(msg.files))
+	for _, file := range msg.files {
+		prev := w.seen[file.path]
+		seen[file.path] = file
+
+		if file.imported {
+			// Imported before, maybe from another path: nothing to say
+			w.reported[file.hash] = true
+			continue
+		}
+		if w.importing[file.hash] || w.failed[file.hash] {
+			continue
+		}
+		// Exports inside a zip have to be unzipped to be imported
+		importable := w.autoImport && !strings.Contains(file.path, "!")
+		if !w.reported[file.hash] {
+			w.reported[file.hash] = true
+			if importable {
+				pending = append(pending, filepath.Base(file.path))
+			} else {
+				found = append(found, filepath.Base(file.path))
+			}
+		}
+		if !importable {
+			continue
+		}
+
+		// Import once the file is the same as on the last scan and hasn't
+		// been written to for stableFor, so a download in progress is left
+		// alone
+		if !file.Settled(prev.FileState, w.stableFor, time.Now()) {
+			waiting = true
+			continue
+		}
+		w.importing[file.hash] = true
+		cmds = append(cmds, w.importExport(engine, file))
+	}
+	w.seen = seen
+
+	// Look again soon while an export is settling
+	next := watchInterval
+	if waiting {
+		next = w.stableFor
+	}
+	cmds = append(cmds, w.tick(next))
+
+	var notes []string
+	if len(found) > 0 {
+		notes = append(notes, i18n.Tf("🆕 Found %d new Claude export(s) in Downloads", len(found)))
+	}
+	switch {
+	case len(pending) == 1:
+		notes = append(notes, i18n.Tf("🆕 Found %s, importing once it's unchanged for %s", pending[0], w.stableFor))
+	case len(pending) > 1:
+		notes = append(notes, i18n.Tf("🆕 Found %d new Claude exports, importing once they're unchanged for %s", len(pending), w.stableFor))
+	}
+	return strings.Join(notes, " • "), tea.Batch(cmds...)
+}
+
+// imported records the end of a background import and describes it
+func (w *exportWatcher) imported(msg exportImportedMsg) string {
+	delete(w.importing, msg.file.hash)
+	w.reported[msg.file.hash] = true
+
+	name := filepath.Base(msg.file.path)
+	if msg.err != nil {
+		w.failed[msg.file.hash] = true
+		return i18n.Tf("✗ Failed to import %s: %v", name, msg.err)
+	}
+	note := i18n.Tf("✓ Imported %s: %d conversation(s), %d message(s)", name,
+		msg.stats.ConversationsImported, msg.stats.MessagesImported)
+	if len(msg.stats.Errors) > 0 {
+		note += i18n.Tf(", %d skipped with errors", len(msg.stats.Errors))
+	}
+	return note
+}
+
+// importExport imports an export in the background
+func (w *exportWatcher) importExport(engine *search.Engine, file watchedFile) tea.Cmd {
+	importer := imports.NewImporter(engine.DB(), w.batchSize, false)
+	return func() tea.Msg {
+		stats, err := importer.Import(file.path)
+		return exportImportedMsg{file: file, stats: stats, err: err}
+	}
+}
@@ -0,0 +1,121 @@
+package tui
+
+import "strings"
+
+// Long conversations are rendered a window of messages at a time, so opening
+// one doesn't wait for thousands of messages to render. Scrolling within a
+// screen of either end of the window renders the next chunk of messages
+// there, dropping a chunk from the other end once the window is full.
+const (
+	// renderChunk is how many messages are rendered at a time
+	renderChunk = 100
+	// maxWindowMessages is the most messages rendered at once. Shorter
+	// conversations are rendered whole.
+	maxWindowMessages = 3 * renderChunk
+)
+
+// lazy reports whether the conversation is too long to render whole
+func (cv conversationView) lazy() bool {
+	return len(cv.messages) > maxWindowMessages
+}
+
+// window returns the range of messages rendered
+func (cv conversationView) window() (start, end int) {
+	if !cv.lazy() {
+		return 0, len(cv.messages)
+	}
+	start = min(max(cv.windowStart, 0), len(cv.messages)-1)
+	end = min(max(cv.windowEnd, start+1), len(cv.messages))
+	return start, end
+}
+
+// moveWindow renders a chunk of messages from start, the first chunk or the
+// last one if start is out of range
+func (cv *conversationView) moveWindow(start int) {
+	if !cv.lazy() {
+		return
+	}
+	cv.windowStart = min(max(start, 0), len(cv.messages)-renderChunk)
+	cv.windowEnd = cv.windowStart + renderChunk
+	cv.updateContent()
+}
+
+// showMessage moves the window to a chunk around message i unless it is
+// already rendered
+func (cv *conversationView) showMessage(i int) {
+	if start, end := cv.window(); i >= start && i < end {
+		return
+	}
+	cv.moveWindow(i - renderChunk/2)
+}
+
+// topMessage returns the message at the top of the viewport and how many
+// lines of it are scrolled past
+func (cv conversationView) topMessage() (index, line int) {
+	start, _ := cv.window()
+	index, line = -1, 0
+	for i, offset := range cv.messageOffsets(cv.renderContent()) {
+		if offset > cv.viewport.YOffset {
+			break
+		}
+		index, line = start+i, cv.viewport.YOffset-offset
+	}
+	return index, line
+}
+
+// loadMore extends the window when the viewport comes within a screen of its
+// start or end, keeping the same text at the top of the viewport
+func (cv *conversationView) loadMore() {
+	if !cv.lazy() {
+		return
+	}
+	start, end := cv.window()
+	height := max(cv.viewport.Height, 1)
+	atBottom := end < len(cv.messages) && cv.viewport.YOffset+2*height >= cv.viewport.TotalLineCount()
+	atTop := start > 0 && cv.viewport.YOffset < height
+	if !atBottom && !atTop {
+		return
+	}
+
+	anchor, line := cv.topMessage()
+	if atBottom {
+		end = min(end+renderChunk, len(cv.messages))
+		start = max(start, end-maxWindowMessages)
+	} else {
+		start = max(start-renderChunk, 0)
+		end = min(end, start+maxWindowMessages)
+	}
+	cv.windowStart, cv.windowEnd = start, end
+	cv.updateContent()
+
+	if anchor < start || anchor >= end {
+		return
+	}
+	if offsets := cv.messageOffsets(cv.renderContent()); anchor-start < len(offsets) {
+		cv.viewport.SetYOffset(offsets[anchor-start] + line)
+	}
+}
+
+// findInMessages returns the lines of every case-insensitive occurrence of
+// query in the rendered messages, rendering only the messages whose text
+// could contain it
+func (cv conversationView) findInMessages(query string) []findMatch {
+	lower := strings.ToLower(query)
+	var matches []findMatch
+	for i, msg := range cv.displayed() {
+		if !strings.Contains(strings.ToLower(msg.Text), lower) && !strings.Contains(strings.ToLower(messageHeader(msg)), lower) {
+			continue
+		}
+		rendered := cv.renderMessageAt(i)
+		for _, match := range newLineIndex(rendered).find(query) {
+			matches = append(matches, findMatch{message: i, lineMatch: match})
+		}
+	}
+	return matches
+}
+
+// findMatch is an occurrence of a find query in a message
+type findMatch struct {
+	message   int // index of the message
+	lineMatch     // line within the rendered message
+}
@@ -5,13 +5,22 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/neilberkman/shannon/internal/artifacts"
 	"github.com/neilberkman/shannon/internal/config"
+	"github.com/neilberkman/shannon/internal/dates"
 	"github.com/neilberkman/shannon/internal/db"
 	"github.com/neilberkman/shannon/internal/export"
+	"github.com/neilberkman/shannon/internal/filter"
+	"github.com/neilberkman/shannon/internal/models"
+	"github.com/neilberkman/shannon/internal/rendering"
+	"github.com/neilberkman/shannon/internal/repeats"
 	"github.com/neilberkman/shannon/internal/search"
+	"github.com/neilberkman/shannon/internal/split"
+	"github.com/neilberkman/shannon/internal/store"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
 var (
@@ -19,23 +28,34 @@ var (
 	showArtifacts bool
 	fullArtifacts bool
 	outputFile    string
+	grepQuery     string
+	messageUUID   string
+	collapseQuote bool
+	includes      []string
+	excludes      []string
 )
 
 // ViewCmd represents the view command
 var ViewCmd = &cobra.Command{
-	Use:   "view [conversation-id]",
+	Use:   "view [conversation]",
 	Short: "View a conversation with all messages",
 	Long: `View a full conversation with all messages, including branch information if available.
 
 Example:
   shannon view 123
+  shannon view python-pandas-cleanup-2024-05
   shannon view 123 --branches
   shannon view 123 --show-artifacts
   shannon view 123 --full-artifacts
+  shannon view 123 --grep "error handling"
+  shannon view 123 --message 4f9a2c1e
+  shannon view 123 --collapse-quotes
+  shannon view 123 --exclude-pattern '(?i)^\s*continue\W*$'
   shannon view 123 --output conversation.md
   shannon view 123 -o conversation.md`,
-	Args: cobra.ExactArgs(1),
-	RunE: runView,
+	Args:        cobra.ExactArgs(1),
+	Annotations: map[string]string{store.Annotation: "true"},
+	RunE:        runView,
 }
 
 func init() {
@@ -43,17 +63,24 @@ func init() {
 	ViewCmd.Flags().BoolVar(&showArtifacts, "show-artifacts", true, "show artifacts inline")
 	ViewCmd.Flags().BoolVar(&fullArtifacts, "full-artifacts", false, "show complete artifact content")
 	ViewCmd.Flags().StringVarP(&outputFile, "output", "o", "", "export conversation to markdown file")
+	ViewCmd.Flags().StringVar(&grepQuery, "grep", "", "show only the messages containing this text, in full and highlighted")
+	ViewCmd.Flags().BoolVar(&collapseQuote, "collapse-quotes", false, "collapse content repeated from earlier messages, like quoted pastes, into a placeholder")
+	ViewCmd.Flags().StringArrayVar(&includes, "include-pattern", nil, "only show messages matching this regular expression (repeatable: any of them)")
+	ViewCmd.Flags().StringArrayVar(&excludes, "exclude-pattern", nil, "leave out messages matching this regular expression (repeatable)")
+	ViewCmd.Flags().StringVar(&messageUUID, "message", "", "show the thread through the message with this UUID, or the start of it, even on a branch other than main")
 }
 
 func runView(cmd *cobra.Command, args []string) error {
-	// Parse conversation ID
-	convID, err := strconv.ParseInt(args[0], 10, 64)
+	messageFilter, err := filter.New("", includes, excludes)
 	if err != nil {
-		return fmt.Errorf("invalid conversation ID: %w", err)
+		return err
 	}
 
 	// Get configuration
 	cfg := config.Get()
+	if store.Shared(cfg) {
+		return viewShared(cfg, args[0], messageFilter)
+	}
 
 	// Open database
 	database, err := db.New(cfg.Database.Path)
@@ -69,30 +96,247 @@ func runView(cmd *cobra.Command, args []string) error {
 	// Create search engine
 	engine := search.NewEngine(database)
 
-	// Get conversation and messages
+	// Accept a slug or alias in place of the ID
+	convID, err := engine.ResolveConversation(args[0])
+	if err != nil {
+		return err
+	}
+
+	// Get conversation and messages: the main branch, or the thread through
+	// the --message, which can be on another branch
 	conv, messages, err := engine.GetConversation(convID)
+	var branch string
+	if err == nil && messageUUID != "" {
+		var messageID int64
+		if messageID, err = engine.FindMessage(convID, messageUUID); err != nil {
+			return err
+		}
+		if branch, err = engine.BranchName(messageID); err != nil {
+			return err
+		}
+		conv, messages, err = engine.GetThread(convID, messageID)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to get conversation: %w", err)
 	}
 
+	messages, collapsed, err := narrow(convID, messages, messageFilter)
+	if err != nil {
+		return err
+	}
+
 	// If output file specified, export to markdown and exit
 	if outputFile != "" {
-		// Use provided filename or generate default
-		filename := outputFile
-		if err := export.ConversationToMarkdown(conv, messages, filename); err != nil {
-			return fmt.Errorf("failed to export conversation: %w", err)
+		if err := exportTo(conv, messages); err != nil {
+			return err
 		}
-		fmt.Printf("Conversation exported to: %s\n", filename)
+		logAccess(engine, convID, search.AccessExport)
 		return nil
 	}
 
+	highlighter, err := grepHighlighter(convID, messages)
+	if err != nil {
+		return err
+	}
+
+	slug, err := engine.GetSlug(convID)
+	if err != nil {
+		return err
+	}
+	links, err := split.GetLinks(database, convID)
+	if err != nil {
+		return err
+	}
+	lineage, err := engine.GetLineage(convID)
+	if err != nil {
+		return err
+	}
+	printConversation(conv, slug, links, lineage, branch, messages, highlighter, collapsed)
+	logAccess(engine, convID, search.AccessView)
+	return nil
+}
+
+// viewShared shows a conversation of a shared store, by its ID; slugs,
+// aliases, links between conversations and the access log are kept only in
+// the local database
+func viewShared(cfg *config.Config, ref string, messageFilter *filter.Filter) error {
+	if messageUUID != "" {
+		return fmt.Errorf("--message isn't supported with the %s store", cfg.Database.Driver)
+	}
+	convID, err := strconv.ParseInt(ref, 10, 64)
+	if err != nil {
+		return fmt.Errorf("conversation %q not found; the %s store takes conversation IDs", ref, cfg.Database.Driver)
+	}
+
+	s, err := store.Open(cfg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := s.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+		}
+	}()
+
+	conv, messages, err := s.GetConversation(convID)
+	if err != nil {
+		return fmt.Errorf("failed to get conversation: %w", err)
+	}
+	messages, collapsed, err := narrow(convID, messages, messageFilter)
+	if err != nil {
+		return err
+	}
+	if outputFile != "" {
+		return exportTo(conv, messages)
+	}
+	highlighter, err := grepHighlighter(convID, messages)
+	if err != nil {
+		return err
+	}
+	printConversation(conv, "", &split.Links{}, &search.Lineage{}, "", messages, highlighter, collapsed)
+	return nil
+}
+
+// narrow collapses repeated content before anything looks at the text, so
+// it's gone from the export and --grep doesn't match it, then leaves out the
+// messages the patterns drop, as export does
+func narrow(convID int64, messages []*models.Message, messageFilter *filter.Filter) ([]*models.Message, repeats.Result, error) {
+	var collapsed repeats.Result
+	if collapseQuote {
+		messages, collapsed = repeats.Collapse(messages, repeats.DefaultMinLines)
+	}
+	if !messageFilter.IsEmpty() {
+		if messages = messageFilter.Apply(messages); len(messages) == 0 {
+			return nil, collapsed, fmt.Errorf("no messages in conversation %d are left by the patterns", convID)
+		}
+	}
+	return messages, collapsed, nil
+}
+
+// exportTo writes the conversation to the --output markdown file
+func exportTo(conv *models.Conversation, messages []*models.Message) error {
+	if err := export.ConversationToMarkdown(conv, messages, outputFile); err != nil {
+		return fmt.Errorf("failed to export conversation: %w", err)
+	}
+	fmt.Printf("Conversation exported to: %s\n", outputFile)
+	return nil
+}
+
+// grepHighlighter returns the highlighter showing only the messages
+// containing the --grep text, or nil without --grep
+func grepHighlighter(convID int64, messages []*models.Message) (*rendering.Highlighter, error) {
+	highlighter := rendering.NewHighlighter(grepQuery)
+	if grepQuery == "" {
+		return highlighter, nil
+	}
+	if highlighter == nil {
+		return nil, fmt.Errorf("--grep needs some text to look for")
+	}
+	for _, msg := range messages {
+		if highlighter.Match(msg.Text) {
+			return highlighter, nil
+		}
+	}
+	return nil, fmt.Errorf("no messages in conversation %d contain %q", convID, grepQuery)
+}
+
+// Print writes a conversation to stdout the way `shannon view` shows it, for
+// commands that pick conversations to display
+func Print(engine *search.Engine, convID int64) error {
+	conv, messages, err := engine.GetConversation(convID)
+	if err != nil {
+		return fmt.Errorf("failed to get conversation: %w", err)
+	}
+	slug, err := engine.GetSlug(convID)
+	if err != nil {
+		return err
+	}
+	links, err := split.GetLinks(engine.DB(), convID)
+	if err != nil {
+		return err
+	}
+	lineage, err := engine.GetLineage(convID)
+	if err != nil {
+		return err
+	}
+	printConversation(conv, slug, links, lineage, "", messages, nil, repeats.Result{})
+	logAccess(engine, convID, search.AccessView)
+	return nil
+}
+
+// logAccess records the view or export in the access log for
+// `shannon stats --usage`
+func logAccess(engine *search.Engine, convID int64, action string) {
+	if err := engine.LogAccess(convID, action); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// printConversation writes the conversation header, including the
+// conversations it was split from and into, those it continues and is
+// continued in and the branch shown unless it's main, and its messages. With a highlighter, only the messages it matches
+// are written, in full and with the matches highlighted. What collapsing
+// repeated content did, if anything, is noted in the header.
+func printConversation(conv *models.Conversation, slug string, links *split.Links, lineage *search.Lineage, branch string, messages []*models.Message, highlighter *rendering.Highlighter, collapsed repeats.Result) {
+	cfg := config.Get()
+
 	// Display conversation info
 	fmt.Printf("=== Conversation: %s ===\n", conv.Name)
 	fmt.Printf("ID: %d\n", conv.ID)
+	if slug != "" {
+		fmt.Printf("Slug: %s\n", slug)
+	}
 	fmt.Printf("UUID: %s\n", conv.UUID)
 	fmt.Printf("Created: %s\n", conv.CreatedAt.Format("2006-01-02 15:04:05"))
 	fmt.Printf("Updated: %s\n", conv.UpdatedAt.Format("2006-01-02 15:04:05"))
-	fmt.Printf("Messages: %d\n\n", len(messages))
+	if conv.ActiveDays > 1 {
+		fmt.Printf("Duration: %s, active on %d days\n", dates.FormatDuration(conv.Duration()), conv.ActiveDays)
+	} else if conv.ActiveDays == 1 {
+		fmt.Printf("Duration: %s\n", dates.FormatDuration(conv.Duration()))
+	}
+	if links.Source != nil {
+		fmt.Printf("Split from: %d %s\n", links.Source.ID, links.Source.Name)
+	}
+	for _, part := range links.Parts {
+		fmt.Printf("Split into: %d %s\n", part.ID, part.Name)
+	}
+	if lineage.Continues != nil {
+		fmt.Printf("Continues: %d %s\n", lineage.Continues.ID, lineage.Continues.Name)
+	}
+	if lineage.ContinuedIn != nil {
+		fmt.Printf("Continued in: %d %s\n", lineage.ContinuedIn.ID, lineage.ContinuedIn.Name)
+	}
+	if branch != "" {
+		fmt.Printf("Branch: %s\n", branch)
+	}
+	if names := export.Models(messages); names != "" {
+		fmt.Printf("Model: %s\n", names)
+	}
+	if conv.Starred {
+		fmt.Println("Starred: yes")
+	}
+	if highlighter != nil {
+		matching := 0
+		for _, msg := range messages {
+			if highlighter.Match(msg.Text) {
+				matching++
+			}
+		}
+		fmt.Printf("Messages: %d (%d matching)\n", len(messages), matching)
+	} else {
+		fmt.Printf("Messages: %d\n", len(messages))
+	}
+	if collapsed.Blocks > 0 {
+		fmt.Printf("Collapsed: %d repeated block(s), %d lines\n", collapsed.Blocks, collapsed.Lines)
+	}
+	width := 80
+	if w, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+		width = w
+	}
+	if timeline := messageTimeline(messages, width-len("Timeline: ")); timeline != nil {
+		fmt.Printf("Timeline: %s\n", timeline)
+	}
+	fmt.Println()
 
 	// Extract artifacts if requested
 	var artifactExtractor *artifacts.Extractor
@@ -116,8 +360,16 @@ func runView(cmd *cobra.Command, args []string) error {
 	// Display messages
 	currentBranch := int64(-1)
 	renderer := artifacts.NewTerminalRenderer()
+	renderer.Wrap = cfg.UI.ArtifactWrap
+	if width, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+		renderer.MaxWidth = width - 4 // artifacts are indented by four spaces
+	}
 
 	for i, msg := range messages {
+		if highlighter != nil && !highlighter.Match(msg.Text) {
+			continue
+		}
+
 		// Show branch info if requested and branch changed
 		if showBranches && msg.BranchID != currentBranch {
 			currentBranch = msg.BranchID
@@ -125,7 +377,10 @@ func runView(cmd *cobra.Command, args []string) error {
 		}
 
 		// Message header
-		fmt.Printf("[%d] %s (%s)\n", i+1, msg.Sender, msg.CreatedAt.Format("2006-01-02 15:04:05"))
+		fmt.Printf("[%d] %s\n", i+1, export.MessageHeader(msg))
+		if msg.Rating != "" {
+			fmt.Printf("    Rated %s\n", export.RatingText(msg))
+		}
 
 		// Show parent info if exists
 		if msg.ParentID != nil {
@@ -140,10 +395,14 @@ func runView(cmd *cobra.Command, args []string) error {
 			content = removeArtifactTags(content)
 		}
 
-		// Display message text (truncated if needed)
-		lines := strings.Split(content, "\n")
+		// Show LaTeX math as Unicode
+		content = rendering.RenderMath(content)
+
+		// Display message text (truncated if needed, but not when grepping so
+		// every match shows)
+		lines := strings.Split(highlighter.Highlight(content), "\n")
 		maxLines := 20
-		if !fullArtifacts {
+		if !fullArtifacts && highlighter == nil {
 			if len(lines) > maxLines {
 				fmt.Printf("    %s\n", strings.Join(lines[:maxLines], "\n    "))
 				fmt.Printf("    ... (%d more lines)\n", len(lines)-maxLines)
@@ -161,8 +420,7 @@ func runView(cmd *cobra.Command, args []string) error {
 				if fullArtifacts {
 					fmt.Printf("    %s\n", renderer.RenderDetail(artifact))
 				} else {
-					maxHeight := 10
-					inline := renderer.RenderInline(artifact, false, true, maxHeight)
+					inline := renderer.RenderInline(artifact, false, true, cfg.UI.ArtifactPreviewLines)
 					// Indent the artifact display
 					lines := strings.Split(inline, "\n")
 					for _, line := range lines {
@@ -178,8 +436,16 @@ func runView(cmd *cobra.Command, args []string) error {
 
 		fmt.Println()
 	}
+}
 
-	return nil
+// messageTimeline counts the messages by day, or returns nil if they were
+// all sent on one day
+func messageTimeline(messages []*models.Message, width int) *rendering.Timeline {
+	times := make([]time.Time, len(messages))
+	for i, msg := range messages {
+		times[i] = msg.CreatedAt
+	}
+	return rendering.NewTimeline(times, width)
 }
 
 // removeArtifactTags removes artifact XML tags from content
@@ -0,0 +1,105 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/config"
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/jobs"
+	"github.com/spf13/cobra"
+)
+
+var (
+	rate  float64
+	once  bool
+	poll  time.Duration
+	quiet bool
+)
+
+// NewCmd creates the worker command
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "worker",
+		Short: "Run queued enrichment jobs in the background",
+		Long: `Run the jobs queued with 'shannon jobs add', one at a time and no faster
+than --rate a second, so other shannon commands can use the database at the
+same time. The worker keeps waiting for new jobs until interrupted, or with
+--once stops when the queue is empty. A job interrupted by Ctrl+C is queued
+again.
+
+Examples:
+  shannon jobs add secrets
+  shannon worker --once
+  shannon worker --rate 2 &`,
+		Args: cobra.NoArgs,
+		RunE: runWorker,
+	}
+
+	cmd.Flags().Float64Var(&rate, "rate", 10, "most jobs to start a second")
+	cmd.Flags().BoolVar(&once, "once", false, "stop when the queue is empty")
+	cmd.Flags().DurationVar(&poll, "poll", 30*time.Second, "how often to check an empty queue for new jobs")
+	cmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "only report failed jobs")
+
+	return cmd
+}
+
+func runWorker(cmd *cobra.Command, args []string) error {
+	if rate <= 0 {
+		return fmt.Errorf("--rate must be more than 0")
+	}
+	if !once && poll <= 0 {
+		return fmt.Errorf("--poll must be more than 0")
+	}
+
+	cfg := config.Get()
+	database, err := db.New(cfg.Database.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer func() {
+		if err := database.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	w := &jobs.Worker{
+		DB:       database,
+		Interval: time.Duration(float64(time.Second) / rate),
+		Done: func(job *jobs.Job, err error) {
+			switch {
+			case err == nil:
+				if !quiet {
+					fmt.Printf("%s of conversation %d: done\n", job.Kind, job.ConversationID)
+				}
+			case job.Status == jobs.StatusFailed:
+				fmt.Fprintf(os.Stderr, "%s of conversation %d failed, giving up after %d attempts: %v\n",
+					job.Kind, job.ConversationID, job.Attempts, err)
+			default:
+				fmt.Fprintf(os.Stderr, "%s of conversation %d failed, will retry: %v\n", job.Kind, job.ConversationID, err)
+			}
+		},
+	}
+	if !once {
+		w.Poll = poll
+		if !quiet {
+			fmt.Println("Waiting for jobs; press Ctrl+C to stop.")
+		}
+	}
+
+	ran, err := w.Run(ctx)
+	if err != nil {
+		return err
+	}
+	if !quiet {
+		fmt.Printf("Ran %d jobs.\n", ran)
+	}
+	return nil
+}
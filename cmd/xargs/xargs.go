@@ -23,7 +23,7 @@ Examples:
   claudesearch list --format json | jq -r '.conversations[].id' | claudesearch xargs export
 
   # Open conversations in editor
-  claudesearch search "TODO" --format json | jq -r '.results[].conversation_id' | sort -u | claudesearch xargs edit
+  claudesearch search "TODO" --format json | jq -r '.results[].ConversationID' | sort -u | claudesearch xargs edit
 
   # View multiple conversations
   echo -e "123\n456\n789" | claudesearch xargs view`,
@@ -27,6 +27,9 @@ type Artifact struct {
 	Content        string
 	MessageID      int64
 	ConversationID int64
+	// Source is KindArtifact for an antArtifact tag and KindCodeBlock for a
+	// fenced code block extracted as an artifact
+	Source string
 }
 
 // Extractor handles extracting artifacts from Claude messages
@@ -35,6 +38,9 @@ type Extractor struct {
 	ArtifactRegex *regexp.Regexp
 	// AttrRegex extracts attributes from the opening tag
 	AttrRegex *regexp.Regexp
+	// CodeBlockLines makes ExtractFromMessage also extract fenced code
+	// blocks of at least this many lines as artifacts; 0 leaves them out
+	CodeBlockLines int
 }
 
 // NewExtractor creates a new artifact extractor
@@ -72,11 +78,20 @@ func (e *Extractor) ExtractFromMessage(msg *models.Message) ([]*Artifact, error)
 			Content:        content,
 			MessageID:      msg.ID,
 			ConversationID: msg.ConversationID,
+			Source:         KindArtifact,
 		}
 
 		artifacts = append(artifacts, artifact)
 	}
 
+	if e.CodeBlockLines > 0 {
+		for _, block := range e.ExtractCodeBlocks(msg) {
+			if block.Kind == KindCodeBlock && Lines(block.Content) >= e.CodeBlockLines {
+				artifacts = append(artifacts, CodeBlockArtifact(msg, block))
+			}
+		}
+	}
+
 	return artifacts, nil
 }
 
@@ -332,6 +332,43 @@ func TestParseAttributes(t *testing.T) {
 	}
 }
 
+func TestCopyAs(t *testing.T) {
+	code := &Artifact{Type: TypeCode, Language: "golang", Content: "if a < b {\n\treturn \"```\"\n}"}
+	tests := []struct {
+		artifact *Artifact
+		format   string
+		expected string
+	}{
+		{code, CopyRaw, code.Content},
+		{code, CopyMarkdown, "````go\n" + code.Content + "\n````"},
+		{code, CopyHTML, "<pre><code class=\"language-go\">if a &lt; b {\n\treturn &#34;```&#34;\n}</code></pre>"},
+		{&Artifact{Type: TypeSVG, Content: "<svg/>\n"}, CopyMarkdown, "```svg\n<svg/>\n```"},
+		{&Artifact{Type: "text/plain", Content: "notes"}, CopyHTML, "<pre><code>notes</code></pre>"},
+		{code, CopyRich, code.Content},
+		{code, "pdf", code.Content},
+	}
+
+	for _, tt := range tests {
+		if got := tt.artifact.CopyAs(tt.format); got != tt.expected {
+			t.Errorf("CopyAs(%q) = %q, want %q", tt.format, got, tt.expected)
+		}
+	}
+
+	// Rich editors get HTML and SVG as they are, and code as a snippet
+	for _, tt := range []struct {
+		artifact *Artifact
+		expected string
+	}{
+		{&Artifact{Type: TypeHTML, Content: "<h1>Hi</h1>"}, "<h1>Hi</h1>"},
+		{&Artifact{Type: TypeSVG, Content: "<svg/>"}, "<svg/>"},
+		{code, code.CopyAs(CopyHTML)},
+	} {
+		if got := tt.artifact.RichHTML(); got != tt.expected {
+			t.Errorf("RichHTML() of %s = %q, want %q", tt.artifact.Type, got, tt.expected)
+		}
+	}
+}
+
 // Helper function to compare artifacts
 func compareArtifacts(a, b *Artifact) bool {
 	return a.ID == b.ID &&
@@ -0,0 +1,76 @@
+package artifacts
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// exportDefaultRegex matches the default export of a React component
+var exportDefaultRegex = regexp.MustCompile(`(?m)^\s*export\s+default\s+`)
+
+// reactImportRegex matches an import of React's default export
+var reactImportRegex = regexp.MustCompile(`(?m)^\s*import\s+React\b`)
+
+// reactImportMap resolves the packages React artifacts usually import
+const reactImportMap = `{
+  "imports": {
+    "react": "https://esm.sh/react@18",
+    "react/": "https://esm.sh/react@18/",
+    "react-dom": "https://esm.sh/react-dom@18",
+    "react-dom/client": "https://esm.sh/react-dom@18/client",
+    "lucide-react": "https://esm.sh/lucide-react?deps=react@18",
+    "recharts": "https://esm.sh/recharts?deps=react@18,react-dom@18"
+  }
+}`
+
+// CanOpenInBrowser reports whether the artifact renders in a web browser
+func (a *Artifact) CanOpenInBrowser() bool {
+	return a.Type == TypeHTML || a.Type == TypeSVG || a.Type == TypeReact
+}
+
+// BrowserDocument returns a document a web browser can render and the file
+// extension to save it with. React components are wrapped in a page that
+// loads React and a JSX compiler from a CDN, so opening them needs network
+// access. ok is false for artifacts that don't render in a browser.
+func (a *Artifact) BrowserDocument() (content string, ext string, ok bool) {
+	switch a.Type {
+	case TypeHTML:
+		return a.Content, ".html", true
+	case TypeSVG:
+		return a.Content, ".svg", true
+	case TypeReact:
+		return reactHarness(a.Title, a.Content), ".html", true
+	}
+	return "", "", false
+}
+
+// reactHarness wraps a React component in a minimal page that renders its
+// default export
+func reactHarness(title, source string) string {
+	if !reactImportRegex.MatchString(source) {
+		source = "import React from 'react';\n" + source
+	}
+
+	// Bind the default export to a name the page can mount
+	mount := ""
+	if loc := exportDefaultRegex.FindStringIndex(source); loc != nil {
+		source = source[:loc[0]] + "\nconst ShannonArtifact = " + source[loc[1]:]
+		mount = "\nimport { createRoot } from 'react-dom/client';\ncreateRoot(document.getElementById('root')).render(<ShannonArtifact />);\n"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	sb.WriteString(fmt.Sprintf("<title>%s</title>\n", html.EscapeString(title)))
+	sb.WriteString("<script src=\"https://cdn.tailwindcss.com\"></script>\n")
+	sb.WriteString("<script src=\"https://unpkg.com/@babel/standalone/babel.min.js\"></script>\n")
+	sb.WriteString(fmt.Sprintf("<script type=\"importmap\">\n%s\n</script>\n", reactImportMap))
+	sb.WriteString("</head>\n<body>\n<div id=\"root\"></div>\n")
+	sb.WriteString("<script type=\"text/babel\" data-type=\"module\" data-presets=\"react\">\n")
+	// Keep the component from ending the script element early
+	sb.WriteString(strings.ReplaceAll(source, "</script", "<\\/script"))
+	sb.WriteString(mount)
+	sb.WriteString("</script>\n</body>\n</html>\n")
+	return sb.String()
+}
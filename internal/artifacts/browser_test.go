@@ -0,0 +1,50 @@
+package artifacts
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBrowserDocument(t *testing.T) {
+	svg := &Artifact{Type: TypeSVG, Content: "<svg></svg>"}
+	if content, ext, ok := svg.BrowserDocument(); !ok || ext != ".svg" || content != svg.Content {
+		t.Errorf("expected SVG to be opened as is, got %q %q %v", content, ext, ok)
+	}
+
+	code := &Artifact{Type: TypeCode, Language: "go", Content: "package main"}
+	if code.CanOpenInBrowser() {
+		t.Error("expected code artifacts not to open in a browser")
+	}
+	if _, _, ok := code.BrowserDocument(); ok {
+		t.Error("expected no browser document for code")
+	}
+
+	react := &Artifact{
+		Type:  TypeReact,
+		Title: "Counter <demo>",
+		Content: `import { useState } from 'react';
+
+export default function Counter() {
+  const [n, setN] = useState(0);
+  return <button onClick={() => setN(n + 1)}>{n}</button>;
+}`,
+	}
+	content, ext, ok := react.BrowserDocument()
+	if !ok || ext != ".html" {
+		t.Fatalf("expected React to open as HTML, got %q %v", ext, ok)
+	}
+	for _, want := range []string{
+		"<title>Counter &lt;demo&gt;</title>",
+		`"react": "https://esm.sh/react@18"`,
+		"import React from 'react';",
+		"const ShannonArtifact = function Counter()",
+		"render(<ShannonArtifact />)",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("expected harness to contain %q:\n%s", want, content)
+		}
+	}
+	if strings.Contains(content, "export default") {
+		t.Error("expected the default export to be rewritten")
+	}
+}
@@ -0,0 +1,221 @@
+package artifacts
+
+import (
+	"database/sql"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+// Code block kinds stored in the code block index
+const (
+	KindCodeBlock = "codeblock"
+	KindArtifact  = "artifact"
+)
+
+// CodeBlock is a fenced code block or artifact body found in a message
+type CodeBlock struct {
+	Kind     string
+	Language string
+	Title    string
+	// Identifier and Type are the artifact's attributes, empty for code blocks
+	Identifier string
+	Type       string
+	// StartLine is the 1-based line within the message text where the code begins
+	StartLine int
+	Content   string
+}
+
+var fenceRegex = regexp.MustCompile("^\\s*(`{3,}|~{3,})\\s*([^\\s`]*)")
+
+// languageAliases maps common fence info strings to a canonical language name
+var languageAliases = map[string]string{
+	"golang":     "go",
+	"py":         "python",
+	"python3":    "python",
+	"js":         "javascript",
+	"node":       "javascript",
+	"ts":         "typescript",
+	"rs":         "rust",
+	"sh":         "bash",
+	"shell":      "bash",
+	"zsh":        "bash",
+	"yml":        "yaml",
+	"c++":        "cpp",
+	"cs":         "csharp",
+	"c#":         "csharp",
+	"rb":         "ruby",
+	"kt":         "kotlin",
+	"md":         "markdown",
+	"postgresql": "sql",
+}
+
+// NormalizeLanguage returns the canonical lowercase name for a language tag
+func NormalizeLanguage(language string) string {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if canonical, ok := languageAliases[language]; ok {
+		return canonical
+	}
+	return language
+}
+
+// ExtractCodeBlocks finds all fenced code blocks and artifact bodies in a message.
+// Fences inside an artifact are treated as part of the artifact.
+func (e *Extractor) ExtractCodeBlocks(msg *models.Message) []*CodeBlock {
+	var blocks []*CodeBlock
+	text := msg.Text
+
+	// Line ranges covered by artifacts, so their fences aren't indexed twice
+	type lineRange struct{ start, end int }
+	var covered []lineRange
+
+	if msg.Sender == "assistant" {
+		for _, idx := range e.ArtifactRegex.FindAllStringSubmatchIndex(text, -1) {
+			attrs := e.parseAttributes(text[idx[2]:idx[3]])
+
+			start, end := idx[4], idx[5]
+			for start < end && (text[start] == '\n' || text[start] == '\r') {
+				start++
+			}
+			content := strings.TrimRight(text[start:end], " \t\r\n")
+			startLine := strings.Count(text[:start], "\n") + 1
+
+			blocks = append(blocks, &CodeBlock{
+				Kind:       KindArtifact,
+				Language:   artifactLanguage(attrs),
+				Title:      attrs["title"],
+				Identifier: attrs["identifier"],
+				Type:       attrs["type"],
+				StartLine:  startLine,
+				Content:    content,
+			})
+			covered = append(covered, lineRange{
+				start: strings.Count(text[:idx[0]], "\n") + 1,
+				end:   strings.Count(text[:idx[1]], "\n") + 1,
+			})
+		}
+	}
+
+	lines := strings.Split(text, "\n")
+	for i := 0; i < len(lines); i++ {
+		match := fenceRegex.FindStringSubmatch(lines[i])
+		if match == nil {
+			continue
+		}
+
+		fence := match[1]
+		end := i + 1
+		for end < len(lines) && !isClosingFence(lines[end], fence) {
+			end++
+		}
+
+		inArtifact := false
+		for _, r := range covered {
+			if i+1 >= r.start && i+1 <= r.end {
+				inArtifact = true
+				break
+			}
+		}
+
+		if !inArtifact {
+			blocks = append(blocks, &CodeBlock{
+				Kind:      KindCodeBlock,
+				Language:  NormalizeLanguage(match[2]),
+				StartLine: i + 2,
+				Content:   strings.Join(lines[i+1:min(end, len(lines))], "\n"),
+			})
+		}
+
+		i = end
+	}
+
+	return blocks
+}
+
+// parallelThreshold is the number of messages below which extracting code
+// blocks on one goroutine is faster than spreading them out
+const parallelThreshold = 64
+
+// ExtractAllCodeBlocks extracts the code blocks of many messages, spreading
+// the work over the CPUs. The blocks of messages[i] are at index i.
+func (e *Extractor) ExtractAllCodeBlocks(messages []*models.Message) [][]*CodeBlock {
+	blocks := make([][]*CodeBlock, len(messages))
+	workers := runtime.GOMAXPROCS(0)
+	if len(messages) < parallelThreshold || workers < 2 {
+		for i, msg := range messages {
+			blocks[i] = e.ExtractCodeBlocks(msg)
+		}
+		return blocks
+	}
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				blocks[i] = e.ExtractCodeBlocks(messages[i])
+			}
+		}()
+	}
+	for i := range messages {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return blocks
+}
+
+// IndexMessage stores the code blocks of a message in the code block index
+func (e *Extractor) IndexMessage(tx *sql.Tx, msg *models.Message) error {
+	return InsertCodeBlocks(tx, msg, e.ExtractCodeBlocks(msg))
+}
+
+// InsertCodeBlocks stores code blocks already extracted from a message in the
+// code block index
+func InsertCodeBlocks(tx *sql.Tx, msg *models.Message, blocks []*CodeBlock) error {
+	for _, block := range blocks {
+		if _, err := tx.Exec(`
+			INSERT INTO code_blocks (message_id, conversation_id, kind, language, title, identifier, artifact_type, start_line, content)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, msg.ID, msg.ConversationID, block.Kind, block.Language, block.Title,
+			block.Identifier, block.Type, block.StartLine, block.Content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isClosingFence reports whether line closes a block opened with fence
+func isClosingFence(line, fence string) bool {
+	trimmed := strings.TrimSpace(line)
+	return len(trimmed) >= len(fence) &&
+		strings.Trim(trimmed, fence[:1]) == "" &&
+		trimmed[0] == fence[0]
+}
+
+// artifactLanguage derives a language for an artifact from its attributes
+func artifactLanguage(attrs map[string]string) string {
+	if lang := attrs["language"]; lang != "" {
+		return NormalizeLanguage(lang)
+	}
+
+	switch attrs["type"] {
+	case TypeHTML:
+		return "html"
+	case TypeSVG:
+		return "svg"
+	case TypeReact:
+		return "jsx"
+	case TypeMermaid:
+		return "mermaid"
+	case TypeMarkdown:
+		return "markdown"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,162 @@
+package artifacts
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+func TestExtractCodeBlocks(t *testing.T) {
+	extractor := NewExtractor()
+
+	msg := &models.Message{
+		Sender: "assistant",
+		Text: "Set a timeout on the client:\n" +
+			"\n" +
+			"```golang\n" +
+			"client := &http.Client{Timeout: 10 * time.Second}\n" +
+			"```\n" +
+			"\n" +
+			"<antArtifact identifier=\"readme\" type=\"text/markdown\" title=\"README\">\n" +
+			"# Usage\n" +
+			"```bash\n" +
+			"go run .\n" +
+			"```\n" +
+			"</antArtifact>\n" +
+			"~~~\n" +
+			"unclosed",
+	}
+
+	blocks := extractor.ExtractCodeBlocks(msg)
+	if len(blocks) != 3 {
+		t.Fatalf("expected 3 blocks, got %d: %+v", len(blocks), blocks)
+	}
+
+	artifact := blocks[0]
+	if artifact.Kind != KindArtifact || artifact.Language != "markdown" || artifact.Title != "README" || artifact.StartLine != 8 ||
+		artifact.Identifier != "readme" || artifact.Type != TypeMarkdown {
+		t.Errorf("unexpected artifact block: %+v", artifact)
+	}
+
+	fenced := blocks[1]
+	if fenced.Kind != KindCodeBlock || fenced.Language != "go" || fenced.StartLine != 4 {
+		t.Errorf("unexpected fenced block: %+v", fenced)
+	}
+	if fenced.Content != "client := &http.Client{Timeout: 10 * time.Second}" {
+		t.Errorf("unexpected fenced content: %q", fenced.Content)
+	}
+
+	unclosed := blocks[2]
+	if unclosed.Language != "" || unclosed.StartLine != 14 || unclosed.Content != "unclosed" {
+		t.Errorf("unexpected unclosed block: %+v", unclosed)
+	}
+
+	// Human messages never contain artifacts, but can contain pasted code
+	msg.Sender = "human"
+	if blocks := extractor.ExtractCodeBlocks(msg); len(blocks) != 3 || blocks[0].Kind != KindCodeBlock {
+		t.Errorf("expected only fenced blocks for human message, got %+v", blocks)
+	}
+}
+
+func TestExtractAllCodeBlocks(t *testing.T) {
+	extractor := NewExtractor()
+
+	// Enough messages to be spread over the CPUs
+	messages := make([]*models.Message, 3*parallelThreshold)
+	for i := range messages {
+		text := "no code here"
+		if i%2 == 0 {
+			text = fmt.Sprintf("```go\nfmt.Println(%d)\n```", i)
+		}
+		messages[i] = &models.Message{ID: int64(i), Sender: "assistant", Text: text}
+	}
+
+	all := extractor.ExtractAllCodeBlocks(messages)
+	if len(all) != len(messages) {
+		t.Fatalf("expected blocks for %d messages, got %d", len(messages), len(all))
+	}
+	for i, blocks := range all {
+		if i%2 == 1 {
+			if len(blocks) != 0 {
+				t.Errorf("message %d: expected no blocks, got %+v", i, blocks)
+			}
+			continue
+		}
+		if len(blocks) != 1 || blocks[0].Content != fmt.Sprintf("fmt.Println(%d)", i) {
+			t.Errorf("message %d: expected its own block, got %+v", i, blocks)
+		}
+	}
+}
+
+func TestSegments(t *testing.T) {
+	extractor := NewExtractor()
+
+	msg := &models.Message{
+		Sender: "assistant",
+		Text: "Set a timeout:\n" +
+			"```golang\n" +
+			"client := &http.Client{}\n" +
+			"```\n" +
+			"Then document it.\n" +
+			"<antArtifact identifier=\"readme\" type=\"text/markdown\" title=\"README\">\n" +
+			"# Usage\n" +
+			"```bash\n" +
+			"go run .\n" +
+			"```\n" +
+			"</antArtifact>\n",
+	}
+
+	segments := extractor.Segments(msg)
+	kinds := make([]string, len(segments))
+	for i, segment := range segments {
+		kinds[i] = segment.Kind
+	}
+	if strings.Join(kinds, ",") != "text,codeblock,text,artifact" {
+		t.Fatalf("unexpected segments: %v", kinds)
+	}
+	if segments[0].Content != "Set a timeout:" || segments[1].Language != "go" || segments[1].Content != "client := &http.Client{}" {
+		t.Errorf("unexpected prose or code: %+v %+v", segments[0], segments[1])
+	}
+	if artifact := segments[3].Artifact; artifact == nil || artifact.Title != "README" || !strings.Contains(artifact.Content, "go run .") {
+		t.Errorf("expected the artifact to keep its fences, got %+v", artifact)
+	}
+
+	// Human messages never contain artifacts
+	msg.Sender = "human"
+	for _, segment := range extractor.Segments(msg) {
+		if segment.Kind == KindArtifact {
+			t.Errorf("unexpected artifact in human message: %+v", segment)
+		}
+	}
+}
+
+func TestCollapseLongCode(t *testing.T) {
+	extractor := NewExtractor()
+	placeholder := func(language string, lines int) string {
+		return fmt.Sprintf("[%s code, %d lines]", language, lines)
+	}
+	long := strings.TrimSuffix(strings.Repeat("fmt.Println()\n", 4), "\n")
+
+	msg := &models.Message{
+		Sender: "assistant",
+		Text: "Short:\n```sh\nls\n```\nLong:\n```golang\n" + long + "\n```\n" +
+			"<antArtifact identifier=\"main\" type=\"application/vnd.ant.code\" title=\"main.go\">\n```go\n" + long + "\n```\n</antArtifact>\n" +
+			"Unclosed:\n~~~\n" + long,
+	}
+	collapsed, blocks := extractor.CollapseLongCode(msg, 3, placeholder)
+	want := "Short:\n```sh\nls\n```\nLong:\n[go code, 4 lines]\n" +
+		"<antArtifact identifier=\"main\" type=\"application/vnd.ant.code\" title=\"main.go\">\n```go\n" + long + "\n```\n</antArtifact>\n" +
+		"Unclosed:\n[ code, 4 lines]"
+	if blocks != 2 || collapsed.Text != want {
+		t.Errorf("expected 2 blocks collapsed into\n%s\ngot %d:\n%s", want, blocks, collapsed.Text)
+	}
+	if collapsed == msg || !strings.Contains(msg.Text, "```golang") {
+		t.Error("expected a copy, leaving the message alone")
+	}
+
+	if same, blocks := extractor.CollapseLongCode(msg, 4, placeholder); same != msg || blocks != 0 {
+		t.Errorf("expected nothing collapsed at 4 lines, got %d blocks", blocks)
+	}
+}
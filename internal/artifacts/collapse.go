@@ -0,0 +1,77 @@
+package artifacts
+
+import (
+	"strings"
+
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+// DefaultCollapseLines is the longest code block, in lines, left alone when
+// long code blocks are collapsed and no other size is given
+const DefaultCollapseLines = 20
+
+// CollapseLongCode replaces each fenced code block of a message with more
+// than maxLines lines of code by the line placeholder returns for its
+// language and length, so a long technical conversation can be skimmed.
+// Artifacts are left alone; they collapse on their own. It returns a copy of
+// the message if anything was collapsed, and the message itself otherwise,
+// along with the number of blocks collapsed.
+func (e *Extractor) CollapseLongCode(msg *models.Message, maxLines int, placeholder func(language string, lines int) string) (*models.Message, int) {
+	if maxLines <= 0 {
+		maxLines = DefaultCollapseLines
+	}
+
+	var sb strings.Builder
+	blocks := 0
+	text := msg.Text
+	if msg.Sender == "assistant" {
+		last := 0
+		for _, idx := range e.ArtifactRegex.FindAllStringIndex(text, -1) {
+			collapsed, n := collapseFences(text[last:idx[0]], maxLines, placeholder)
+			sb.WriteString(collapsed)
+			sb.WriteString(text[idx[0]:idx[1]])
+			blocks += n
+			last = idx[1]
+		}
+		text = text[last:]
+	}
+	collapsed, n := collapseFences(text, maxLines, placeholder)
+	sb.WriteString(collapsed)
+	blocks += n
+
+	if blocks == 0 {
+		return msg, 0
+	}
+	cp := *msg
+	cp.Text = sb.String()
+	return &cp, blocks
+}
+
+// collapseFences collapses the fenced code blocks of text longer than
+// maxLines, fences included. An unclosed fence runs to the end of the text.
+func collapseFences(text string, maxLines int, placeholder func(language string, lines int) string) (string, int) {
+	lines := strings.Split(text, "\n")
+	var out []string
+	blocks := 0
+	for i := 0; i < len(lines); i++ {
+		match := fenceRegex.FindStringSubmatch(lines[i])
+		if match == nil {
+			out = append(out, lines[i])
+			continue
+		}
+
+		end := i + 1
+		for end < len(lines) && !isClosingFence(lines[end], match[1]) {
+			end++
+		}
+		code := min(end, len(lines)) - i - 1
+		if code > maxLines {
+			out = append(out, placeholder(NormalizeLanguage(match[2]), code))
+			blocks++
+		} else {
+			out = append(out, lines[i:min(end+1, len(lines))]...)
+		}
+		i = end
+	}
+	return strings.Join(out, "\n"), blocks
+}
@@ -0,0 +1,58 @@
+package artifacts
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// Copy formats: how an artifact is wrapped for where it will be pasted
+const (
+	CopyRaw      = "raw"      // the content as written, for an editor or IDE
+	CopyMarkdown = "markdown" // a fenced code block, for Slack, GitHub or notes
+	CopyHTML     = "html"     // a <pre><code> snippet, for docs and web pages
+	CopyRich     = "rich"     // formatted, for rich editors: see RichHTML and RenderSVG
+)
+
+// CopyFormats lists the copy formats in the order they're offered
+var CopyFormats = []string{CopyRaw, CopyMarkdown, CopyHTML, CopyRich}
+
+// CopyAs returns the artifact's content wrapped in a copy format. Unknown
+// formats give the raw content.
+func (a *Artifact) CopyAs(format string) string {
+	language := artifactLanguage(map[string]string{"language": a.Language, "type": a.Type})
+	switch format {
+	case CopyMarkdown:
+		return Fence(a.Content, language)
+	case CopyHTML:
+		class := ""
+		if language != "" {
+			class = fmt.Sprintf(" class=\"language-%s\"", html.EscapeString(language))
+		}
+		return fmt.Sprintf("<pre><code%s>%s</code></pre>", class, html.EscapeString(strings.TrimRight(a.Content, "\n")))
+	default:
+		return a.Content
+	}
+}
+
+// RichHTML returns the artifact as HTML for rich editors to paste with its
+// formatting: HTML and SVG artifacts as they are, so the page or the image
+// shows, and anything else as a <pre><code> snippet
+func (a *Artifact) RichHTML() string {
+	switch a.Type {
+	case TypeHTML, TypeSVG:
+		return a.Content
+	default:
+		return a.CopyAs(CopyHTML)
+	}
+}
+
+// Fence wraps code in a Markdown code fence longer than any backtick run
+// inside it
+func Fence(code, language string) string {
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	return fence + language + "\n" + strings.TrimRight(code, "\n") + "\n" + fence
+}
@@ -0,0 +1,109 @@
+package artifacts
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+// DefaultCodeBlockLines is the size, in lines, from which a fenced code block
+// is worth treating as an artifact when no other size is given
+const DefaultCodeBlockLines = 10
+
+// languageSignals are patterns that give away the language of untagged code,
+// tried in order, so the more specific come first
+var languageSignals = []struct {
+	language string
+	pattern  *regexp.Regexp
+}{
+	{"php", regexp.MustCompile(`^<\?php`)},
+	{"svg", regexp.MustCompile(`(?i)^<svg[\s>]`)},
+	{"html", regexp.MustCompile(`(?i)^(<!doctype html|<html[\s>]|<(head|body|div)[\s>])`)},
+	{"xml", regexp.MustCompile(`^<\?xml`)},
+	{"mermaid", regexp.MustCompile(`^(graph (TD|TB|LR|RL|BT)|flowchart |sequenceDiagram|classDiagram|erDiagram|gantt)`)},
+	{"go", regexp.MustCompile(`(?m)^package \w+$|^func (\(\w+ \*?\w+\) )?\w+\(.*\{$`)},
+	{"rust", regexp.MustCompile(`(?m)^\s*(pub )?fn \w+.*\{$|^\s*let mut |^use \w+::`)},
+	{"python", regexp.MustCompile(`(?m)^\s*def \w+\(.*\):$|^\s*from [\w.]+ import |^\s*class \w+(\(.*\))?:$|^if __name__ ==`)},
+	{"java", regexp.MustCompile(`(?m)^\s*public (static )?(class|void|interface) `)},
+	{"cpp", regexp.MustCompile(`(?m)^#include <(iostream|vector|string|memory)>|std::`)},
+	{"c", regexp.MustCompile(`(?m)^#include [<"]`)},
+	{"typescript", regexp.MustCompile(`(?m)^\s*(export )?(interface|type) \w+ (=|\{)|: (string|number|boolean)[;,)=]`)},
+	{"javascript", regexp.MustCompile(`(?m)^\s*(const|let|var) \w+ = |=> \{|^\s*function \w*\(|require\(['"]|console\.log\(`)},
+	{"dockerfile", regexp.MustCompile(`(?m)^FROM \S+( AS \w+)?$[\s\S]*^(RUN|COPY|CMD|ENTRYPOINT|WORKDIR|ENV) `)},
+	{"sql", regexp.MustCompile(`(?im)^\s*(select\s[\s\S]+?\sfrom\s|insert into |create (table|index|view) |update \w+ set |delete from )`)},
+	{"bash", regexp.MustCompile(`(?m)^#!/(usr/)?bin/(env )?(ba|z)?sh|^\s*\$ \w|^\s*(sudo|apt(-get)?|brew|npm|pip|cd|export|echo|mkdir|git|docker|kubectl|curl) `)},
+	{"css", regexp.MustCompile(`(?m)^\s*[.#]?[\w-]+( [\w.#-]+)* \{$\s*^\s*[\w-]+: [^;]+;`)},
+	{"yaml", regexp.MustCompile(`(?m)^[\w-]+:( .+)?$\s*^(  )+[\w-]+:`)},
+}
+
+// DetectLanguage guesses the language of code from its content, for code
+// blocks whose fence doesn't name one. It returns "" when nothing gives the
+// language away.
+func DetectLanguage(code string) string {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return ""
+	}
+	if strings.HasPrefix(code, "#!") {
+		shebang, _, _ := strings.Cut(code, "\n")
+		switch {
+		case strings.Contains(shebang, "python"):
+			return "python"
+		case strings.Contains(shebang, "node"):
+			return "javascript"
+		case strings.Contains(shebang, "ruby"):
+			return "ruby"
+		}
+	}
+	if (strings.HasPrefix(code, "{") || strings.HasPrefix(code, "[")) && json.Valid([]byte(code)) {
+		return "json"
+	}
+	for _, signal := range languageSignals {
+		if signal.pattern.MatchString(code) {
+			return signal.language
+		}
+	}
+	return ""
+}
+
+// codeBlockTypes are the artifact types of code blocks in languages that
+// have an artifact type of their own
+var codeBlockTypes = map[string]string{
+	"html":     TypeHTML,
+	"svg":      TypeSVG,
+	"markdown": TypeMarkdown,
+	"mermaid":  TypeMermaid,
+}
+
+// CodeBlockArtifact turns a fenced code block of a message into an artifact,
+// so it can be listed and saved like one. Its language is the fence's, or
+// detected from the code when the fence has none.
+func CodeBlockArtifact(msg *models.Message, block *CodeBlock) *Artifact {
+	language := block.Language
+	if language == "" {
+		language = DetectLanguage(block.Content)
+	}
+	artifactType, ok := codeBlockTypes[language]
+	if !ok {
+		artifactType = TypeCode
+	}
+
+	return &Artifact{
+		ID:             fmt.Sprintf("codeblock-%d-%d", msg.ID, block.StartLine),
+		Type:           artifactType,
+		Language:       language,
+		Title:          fmt.Sprintf("Code block at line %d of message %d", block.StartLine, msg.ID),
+		Content:        block.Content,
+		MessageID:      msg.ID,
+		ConversationID: msg.ConversationID,
+		Source:         KindCodeBlock,
+	}
+}
+
+// Lines returns how many lines content has
+func Lines(content string) int {
+	return strings.Count(strings.TrimRight(content, "\n"), "\n") + 1
+}
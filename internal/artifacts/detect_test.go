@@ -0,0 +1,81 @@
+package artifacts
+
+import (
+	"testing"
+
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected string
+	}{
+		{"package main\n\nfunc main() {\n}", "go"},
+		{"def greet(name):\n    return name", "python"},
+		{"#!/usr/bin/env python3\nprint('hi')", "python"},
+		{"fn main() {\n    let mut x = 1;\n}", "rust"},
+		{"const add = (a, b) => {\n  return a + b\n}", "javascript"},
+		{"interface User {\n  name: string;\n}", "typescript"},
+		{"SELECT id, name\nFROM users\nWHERE active = 1;", "sql"},
+		{"$ npm install\n$ npm test", "bash"},
+		{"#!/bin/bash\nset -e", "bash"},
+		{`{"name": "shannon", "version": 1}`, "json"},
+		{"<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>", "svg"},
+		{"<!DOCTYPE html>\n<html></html>", "html"},
+		{"services:\n  web:\n    image: nginx", "yaml"},
+		{"FROM golang:1.24 AS build\nRUN go build", "dockerfile"},
+		{"graph TD\n  A --> B", "mermaid"},
+		{"just some words\nthat aren't code", ""},
+	}
+	for _, tt := range tests {
+		if got := DetectLanguage(tt.code); got != tt.expected {
+			t.Errorf("DetectLanguage(%q) = %q, want %q", tt.code, got, tt.expected)
+		}
+	}
+}
+
+func TestExtractCodeBlockArtifacts(t *testing.T) {
+	msg := &models.Message{
+		ID:             7,
+		ConversationID: 3,
+		Sender:         "assistant",
+		Text: "<antArtifact identifier=\"notes\" type=\"text/markdown\" title=\"Notes\">\n# Notes\n</antArtifact>\n" +
+			"A short one:\n```\nls\n```\n" +
+			"And a longer one:\n```\nimport os\nfrom pathlib import Path\nprint(Path.cwd())\n```\n" +
+			"```html\n<p>\nhi\n</p>\n```",
+	}
+
+	// Code blocks are left out unless asked for
+	extractor := NewExtractor()
+	found, err := extractor.ExtractFromMessage(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0].Source != KindArtifact {
+		t.Fatalf("expected only the tagged artifact, got %+v", found)
+	}
+
+	extractor.CodeBlockLines = 3
+	found, err = extractor.ExtractFromMessage(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 3 {
+		t.Fatalf("expected the artifact and two code blocks, got %+v", found)
+	}
+	python, page := found[1], found[2]
+	if python.Source != KindCodeBlock || python.Type != TypeCode || python.Language != "python" ||
+		python.ID != "codeblock-7-10" || python.ConversationID != 3 || python.GetFileExtension() != ".py" {
+		t.Errorf("unexpected untagged code block artifact: %+v", python)
+	}
+	if page.Type != TypeHTML || page.Language != "html" || page.GetFileExtension() != ".html" {
+		t.Errorf("expected an HTML block to be an HTML artifact, got %+v", page)
+	}
+
+	// Human messages have no artifacts, fenced or not
+	msg.Sender = "human"
+	if found, _ := extractor.ExtractFromMessage(msg); len(found) != 0 {
+		t.Errorf("expected no artifacts in a human message, got %+v", found)
+	}
+}
@@ -0,0 +1,88 @@
+package artifacts
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// ErrNoSVGRenderer means none of the tools RenderSVG uses is installed
+var ErrNoSVGRenderer = errors.New("no SVG renderer found (install librsvg, resvg, ImageMagick or Inkscape)")
+
+// renderTimeout bounds how long an SVG renderer may take
+const renderTimeout = 15 * time.Second
+
+// pngSignature starts every PNG file
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// svgRenderer is a command line tool that renders an SVG file to a PNG file
+type svgRenderer struct {
+	command string
+	args    func(in, out, dir string) []string
+	output  string // the file written in dir
+}
+
+// svgRenderers are tried in order. qlmanage is macOS's Quick Look, which
+// names its thumbnails after the input. ImageMagick 6's convert is left out
+// on Windows, where convert.exe is the tool that converts FAT volumes.
+var svgRenderers = []svgRenderer{
+	{"rsvg-convert", func(in, out, dir string) []string { return []string{"--format", "png", "--output", out, in} }, "artifact.png"},
+	{"resvg", func(in, out, dir string) []string { return []string{in, out} }, "artifact.png"},
+	{"magick", func(in, out, dir string) []string { return []string{in, out} }, "artifact.png"},
+	{"convert", func(in, out, dir string) []string { return []string{in, out} }, "artifact.png"},
+	{"inkscape", func(in, out, dir string) []string {
+		return []string{in, "--export-type=png", "--export-filename=" + out}
+	}, "artifact.png"},
+	{"qlmanage", func(in, out, dir string) []string { return []string{"-t", "-s", "1024", "-o", dir, in} }, "artifact.svg.png"},
+}
+
+// RenderSVG renders an SVG image to PNG with the first renderer installed
+func RenderSVG(svg string) ([]byte, error) {
+	var renderer *svgRenderer
+	for i := range svgRenderers {
+		if svgRenderers[i].command == "convert" && runtime.GOOS == "windows" {
+			continue
+		}
+		if _, err := exec.LookPath(svgRenderers[i].command); err == nil {
+			renderer = &svgRenderers[i]
+			break
+		}
+	}
+	if renderer == nil {
+		return nil, ErrNoSVGRenderer
+	}
+
+	dir, err := os.MkdirTemp("", "shannon-svg-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	in := filepath.Join(dir, "artifact.svg")
+	if err := os.WriteFile(in, []byte(svg), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write SVG: %w", err)
+	}
+	out := filepath.Join(dir, renderer.output)
+
+	ctx, cancel := context.WithTimeout(context.Background(), renderTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, renderer.command, renderer.args(in, out, dir)...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("%s failed: %w: %s", renderer.command, err, bytes.TrimSpace(output))
+	}
+
+	png, err := os.ReadFile(out)
+	if err != nil {
+		return nil, fmt.Errorf("%s wrote no image: %w", renderer.command, err)
+	}
+	if !bytes.HasPrefix(png, pngSignature) {
+		return nil, fmt.Errorf("%s didn't write a PNG image", renderer.command)
+	}
+	return png, nil
+}
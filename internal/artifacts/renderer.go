@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/neilberkman/shannon/internal/rendering"
 )
 
 // Renderer interface for different output formats
@@ -14,8 +15,20 @@ type Renderer interface {
 	RenderInline(artifact *Artifact, focused bool, expanded bool, maxHeight int) string
 }
 
+// DefaultPreviewLines is how many lines a collapsed inline artifact shows
+const DefaultPreviewLines = 10
+
+// defaultMaxWidth caps inline artifact boxes when no width is set
+const defaultMaxWidth = 100
+
 // TerminalRenderer renders artifacts for terminal display
 type TerminalRenderer struct {
+	// MaxWidth caps the rendered width of inline artifacts. Set it from the
+	// terminal width; zero caps the content lines at 100 columns.
+	MaxWidth int
+	// Wrap wraps long lines in inline artifacts instead of cutting them off
+	Wrap bool
+
 	artifactStyle lipgloss.Style
 	focusedStyle  lipgloss.Style
 	titleStyle    lipgloss.Style
@@ -52,7 +65,7 @@ func (r *TerminalRenderer) RenderList(artifacts []*Artifact) string {
 
 	var lines []string
 	for i, artifact := range artifacts {
-		icon := getArtifactIcon(artifact.Type)
+		icon := Icon(artifact.Type)
 		typeName := artifact.GetTypeName()
 
 		line := fmt.Sprintf("[%d] %s %s - %s",
@@ -69,7 +82,7 @@ func (r *TerminalRenderer) RenderList(artifacts []*Artifact) string {
 
 // RenderDetail renders full artifact content
 func (r *TerminalRenderer) RenderDetail(artifact *Artifact) string {
-	icon := getArtifactIcon(artifact.Type)
+	icon := Icon(artifact.Type)
 	header := fmt.Sprintf("%s %s", icon, r.titleStyle.Render(artifact.Title))
 
 	if artifact.Language != "" {
@@ -83,10 +96,8 @@ func (r *TerminalRenderer) RenderDetail(artifact *Artifact) string {
 
 // RenderInline renders an artifact inline within a conversation view
 func (r *TerminalRenderer) RenderInline(artifact *Artifact, focused bool, expanded bool, maxHeight int) string {
-	icon := getArtifactIcon(artifact.Type)
-
 	// Base header content
-	headerContent := fmt.Sprintf(" %s %s ", icon, artifact.Title)
+	headerContent := " " + InlineTitle(artifact) + " "
 	if artifact.Language != "" {
 		headerContent += fmt.Sprintf("(%s) ", artifact.Language)
 	}
@@ -95,10 +106,15 @@ func (r *TerminalRenderer) RenderInline(artifact *Artifact, focused bool, expand
 	lines := strings.Split(artifact.Content, "\n")
 
 	// Find the maximum line width for proper box formatting
-	maxWidth := 50
+	widthCap := defaultMaxWidth
+	if r.MaxWidth > 0 {
+		// The outer border and padding take 4 columns and the header one more
+		widthCap = max(r.MaxWidth-5, 20)
+	}
+	maxWidth := min(50, widthCap)
 	for _, line := range lines {
-		if len(line)+4 > maxWidth { // +4 for "│ " and " │"
-			maxWidth = min(len(line)+4, 100) // Cap at 100 chars total
+		if lineWidth(line)+4 > maxWidth { // +4 for "│ " and " │"
+			maxWidth = min(lineWidth(line)+4, widthCap)
 		}
 	}
 
@@ -125,27 +141,40 @@ func (r *TerminalRenderer) RenderInline(artifact *Artifact, focused bool, expand
 	var contentLines []string
 	innerWidth := maxWidth - 4 // Account for "│ " and " │"
 
-	// Determine how many lines to show
-	linesToShow := len(lines)
-	if !expanded && len(lines) > maxHeight {
-		// Show preview (maxHeight lines) when collapsed
-		linesToShow = maxHeight
-	}
+	// When collapsed, show a preview of at most maxHeight rows. Wrapped
+	// lines take several rows; a line that doesn't fit is left for later.
+	linesShown := 0
+	for _, line := range lines {
+		rows := []string{line}
+		if r.Wrap {
+			rows = rendering.WrapLine(line, innerWidth)
+		} else if lineWidth(line) > innerWidth {
+			rows = []string{rendering.TruncateWidth(line, innerWidth)}
+		}
 
-	for i := 0; i < linesToShow; i++ {
-		displayLine := lines[i]
-		if len(displayLine) > innerWidth {
-			displayLine = displayLine[:innerWidth-3] + "..."
+		if !expanded && len(contentLines)+len(rows) > maxHeight {
+			if len(contentLines) == 0 {
+				// A single line taller than the preview still shows its start
+				for _, row := range rows[:maxHeight] {
+					contentLines = append(contentLines, fmt.Sprintf("│ %s │", padRight(row, innerWidth)))
+				}
+				linesShown = 1
+			}
+			break
+		}
+
+		for _, row := range rows {
+			contentLines = append(contentLines, fmt.Sprintf("│ %s │", padRight(row, innerWidth)))
 		}
-		contentLines = append(contentLines, fmt.Sprintf("│ %s │", padRight(displayLine, innerWidth)))
+		linesShown++
 	}
 
 	// Build footer
 	footer := "└"
 
 	// Show "more lines" info if collapsed and there are more lines
-	if !expanded && len(lines) > maxHeight {
-		moreInfo := fmt.Sprintf("─ ... (%d more lines) ", len(lines)-maxHeight)
+	if !expanded && linesShown < len(lines) {
+		moreInfo := fmt.Sprintf("─ ... (%d more lines) ", len(lines)-linesShown)
 		if focused {
 			saveText := " [s] save "
 			padding := max(0, maxWidth-len(moreInfo)-len(saveText)-2)
@@ -206,7 +235,7 @@ func (r *MarkdownRenderer) RenderList(artifacts []*Artifact) string {
 	lines = append(lines, "## Artifacts\n")
 
 	for i, artifact := range artifacts {
-		icon := getArtifactIcon(artifact.Type)
+		icon := Icon(artifact.Type)
 		typeName := artifact.GetTypeName()
 
 		line := fmt.Sprintf("%d. %s **%s** - %s",
@@ -223,7 +252,7 @@ func (r *MarkdownRenderer) RenderList(artifacts []*Artifact) string {
 
 // RenderDetail renders full artifact content as markdown
 func (r *MarkdownRenderer) RenderDetail(artifact *Artifact) string {
-	icon := getArtifactIcon(artifact.Type)
+	icon := Icon(artifact.Type)
 	header := fmt.Sprintf("## %s %s\n", icon, artifact.Title)
 
 	if artifact.Language != "" {
@@ -248,30 +277,43 @@ func (r *MarkdownRenderer) RenderInline(artifact *Artifact, focused bool, expand
 
 // Helper functions
 
-func getArtifactIcon(artifactType string) string {
+// InlineTitle returns the icon and title that head an inline artifact box,
+// which is how the TUI finds an artifact in the rendered conversation
+func InlineTitle(artifact *Artifact) string {
+	return Icon(artifact.Type) + " " + artifact.Title
+}
+
+// Icon returns the emoji that marks an artifact of the given type, or its
+// name in brackets when output is plain
+func Icon(artifactType string) string {
 	switch artifactType {
 	case TypeCode:
-		return "📄"
+		return rendering.Symbol("📄", "[code]")
 	case TypeMarkdown:
-		return "📝"
+		return rendering.Symbol("📝", "[markdown]")
 	case TypeHTML:
-		return "🌐"
+		return rendering.Symbol("🌐", "[html]")
 	case TypeSVG:
-		return "🎨"
+		return rendering.Symbol("🎨", "[svg]")
 	case TypeReact:
-		return "⚛️"
+		return rendering.Symbol("⚛️", "[react]")
 	case TypeMermaid:
-		return "📊"
+		return rendering.Symbol("📊", "[mermaid]")
 	default:
-		return "📋"
+		return rendering.Symbol("📋", "[artifact]")
 	}
 }
 
 func padRight(s string, width int) string {
-	if len(s) >= width {
+	if lineWidth(s) >= width {
 		return s
 	}
-	return s + strings.Repeat(" ", width-len(s))
+	return s + strings.Repeat(" ", width-lineWidth(s))
+}
+
+// lineWidth returns the number of terminal columns a line takes up
+func lineWidth(s string) int {
+	return rendering.StringWidth(s)
 }
 
 func max(a, b int) int {
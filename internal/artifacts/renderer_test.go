@@ -0,0 +1,36 @@
+package artifacts
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderInlineWrap(t *testing.T) {
+	long := strings.Repeat("word ", 30) + "end"
+	artifact := &Artifact{Type: TypeCode, Title: "Notes", Content: long + "\nshort\nlast"}
+
+	r := NewTerminalRenderer()
+	r.MaxWidth = 40
+
+	cut := r.RenderInline(artifact, false, true, DefaultPreviewLines)
+	if !strings.Contains(cut, "...") || strings.Contains(cut, "end") {
+		t.Errorf("expected long line to be cut off without wrapping:\n%s", cut)
+	}
+
+	r.Wrap = true
+	wrapped := r.RenderInline(artifact, false, true, DefaultPreviewLines)
+	if !strings.Contains(wrapped, "end") {
+		t.Errorf("expected wrapped line to keep its end:\n%s", wrapped)
+	}
+	for _, line := range strings.Split(wrapped, "\n") {
+		if w := lineWidth(line); w > 40 {
+			t.Errorf("line is %d wide, expected at most 40: %q", w, line)
+		}
+	}
+
+	// The wrapped line fills the preview on its own, leaving the rest for later
+	preview := r.RenderInline(artifact, false, false, 3)
+	if !strings.Contains(preview, "(2 more lines)") {
+		t.Errorf("expected remaining lines to be counted:\n%s", preview)
+	}
+}
@@ -0,0 +1,92 @@
+package artifacts
+
+import (
+	"strings"
+
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+// KindText marks a segment of prose between code blocks and artifacts
+const KindText = "text"
+
+// Segment is a consecutive part of a message: prose, a fenced code block or
+// an artifact, in the order they appear
+type Segment struct {
+	Kind     string // KindText, KindCodeBlock or KindArtifact
+	Language string // for code blocks
+	Content  string
+	Artifact *Artifact // for artifacts
+}
+
+// Segments splits a message into prose, fenced code blocks and artifacts, so
+// exporters can map each to its own kind of block. Empty prose is dropped.
+func (e *Extractor) Segments(msg *models.Message) []*Segment {
+	var segments []*Segment
+	text := msg.Text
+
+	if msg.Sender == "assistant" {
+		last := 0
+		for _, idx := range e.ArtifactRegex.FindAllStringSubmatchIndex(text, -1) {
+			segments = append(segments, splitFences(text[last:idx[0]])...)
+
+			attrs := e.parseAttributes(text[idx[2]:idx[3]])
+			artifact := &Artifact{
+				ID:             attrs["identifier"],
+				Type:           attrs["type"],
+				Language:       attrs["language"],
+				Title:          attrs["title"],
+				Content:        strings.TrimSpace(text[idx[4]:idx[5]]),
+				MessageID:      msg.ID,
+				ConversationID: msg.ConversationID,
+			}
+			segments = append(segments, &Segment{
+				Kind:     KindArtifact,
+				Language: artifactLanguage(attrs),
+				Content:  artifact.Content,
+				Artifact: artifact,
+			})
+			last = idx[1]
+		}
+		text = text[last:]
+	}
+
+	return append(segments, splitFences(text)...)
+}
+
+// splitFences splits text into prose and fenced code blocks. An unclosed
+// fence runs to the end of the text.
+func splitFences(text string) []*Segment {
+	var segments []*Segment
+	var prose []string
+	flush := func() {
+		if content := strings.TrimSpace(strings.Join(prose, "\n")); content != "" {
+			segments = append(segments, &Segment{Kind: KindText, Content: content})
+		}
+		prose = nil
+	}
+
+	lines := strings.Split(text, "\n")
+	for i := 0; i < len(lines); i++ {
+		match := fenceRegex.FindStringSubmatch(lines[i])
+		if match == nil {
+			prose = append(prose, lines[i])
+			continue
+		}
+
+		end := i + 1
+		for end < len(lines) && !isClosingFence(lines[end], match[1]) {
+			end++
+		}
+
+		flush()
+		segments = append(segments, &Segment{
+			Kind:     KindCodeBlock,
+			Language: NormalizeLanguage(match[2]),
+			Content:  strings.Join(lines[i+1:min(end, len(lines))], "\n"),
+		})
+		i = end
+	}
+	flush()
+
+	return segments
+}
@@ -0,0 +1,124 @@
+package artifacts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrNoSyntaxCheck is returned by Validate for artifacts whose language it
+// can't check, or whose checker isn't installed
+var ErrNoSyntaxCheck = errors.New("no syntax check available")
+
+// ValidateTimeout is how long a toolchain gets to check one artifact
+var ValidateTimeout = 10 * time.Second
+
+// SyntaxError is a code artifact that doesn't parse
+type SyntaxError struct {
+	Checker string // what found the error, such as "python3 -m py_compile"
+	Message string // the checker's report
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Checker, e.Message)
+}
+
+// syntaxCheckers run a toolchain's syntax check on a file, by language
+var syntaxCheckers = map[string][]string{
+	"python":     {"python3", "-m", "py_compile"},
+	"javascript": {"node", "--check"},
+	"bash":       {"bash", "-n"},
+	"ruby":       {"ruby", "-c"},
+	"php":        {"php", "-l"},
+}
+
+// Validate checks that a code artifact parses: Go and JSON in process, and
+// other languages with their toolchain's syntax check when it is installed.
+// It returns a *SyntaxError for code that doesn't parse, and
+// ErrNoSyntaxCheck when the artifact can't be checked.
+func Validate(a *Artifact) error {
+	if a.Type != TypeCode {
+		return ErrNoSyntaxCheck
+	}
+	switch language := NormalizeLanguage(a.Language); language {
+	case "go":
+		return validateGo(a.Content)
+	case "json":
+		if err := json.Unmarshal([]byte(a.Content), new(interface{})); err != nil {
+			return &SyntaxError{Checker: "encoding/json", Message: err.Error()}
+		}
+		return nil
+	default:
+		command, ok := syntaxCheckers[language]
+		if !ok {
+			return ErrNoSyntaxCheck
+		}
+		return runSyntaxCheck(command, getLanguageExtension(language), a.Content)
+	}
+}
+
+// validateGo parses Go source, which may be a whole file or, as often in
+// answers, just declarations or statements
+func validateGo(content string) error {
+	fset := token.NewFileSet()
+	if strings.HasPrefix(strings.TrimSpace(content), "package ") {
+		if _, err := parser.ParseFile(fset, "artifact.go", content, parser.AllErrors); err != nil {
+			return &SyntaxError{Checker: "go/parser", Message: err.Error()}
+		}
+		return nil
+	}
+	// Without a package clause, try declarations and then statements
+	if _, err := parser.ParseFile(fset, "artifact.go", "package p\n"+content, parser.AllErrors); err == nil {
+		return nil
+	}
+	if _, err := parser.ParseFile(fset, "artifact.go", "package p\nfunc _() {\n"+content+"\n}", parser.AllErrors); err != nil {
+		return &SyntaxError{Checker: "go/parser", Message: err.Error()}
+	}
+	return nil
+}
+
+// runSyntaxCheck writes content to a temporary file and runs a checker on it
+func runSyntaxCheck(command []string, ext, content string) error {
+	tool, err := exec.LookPath(command[0])
+	if err != nil {
+		return fmt.Errorf("%w: %s isn't installed", ErrNoSyntaxCheck, command[0])
+	}
+
+	dir, err := os.MkdirTemp("", "shannon-validate-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	path := filepath.Join(dir, "artifact"+ext)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ValidateTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, tool, append(command[1:], path)...)
+	cmd.Dir = dir
+	var output bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &output, &output
+	err = cmd.Run()
+	if ctx.Err() != nil {
+		return fmt.Errorf("%s took longer than %s", command[0], ValidateTimeout)
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		message := strings.ReplaceAll(strings.TrimSpace(output.String()), path, "artifact"+ext)
+		return &SyntaxError{Checker: strings.Join(command, " "), Message: message}
+	} else if err != nil {
+		return fmt.Errorf("failed to run %s: %w", command[0], err)
+	}
+	return nil
+}
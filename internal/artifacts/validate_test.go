@@ -0,0 +1,50 @@
+package artifacts
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	code := func(language, content string) *Artifact {
+		return &Artifact{Type: TypeCode, Language: language, Content: content}
+	}
+
+	type testCase struct {
+		name     string
+		artifact *Artifact
+		valid    bool
+	}
+	tests := []testCase{
+		{"go file", code("go", "package main\n\nfunc main() {}\n"), true},
+		{"go declarations", code("golang", "func add(a, b int) int { return a + b }"), true},
+		{"go statements", code("go", "x := 1\nfmt.Println(x)"), true},
+		{"broken go", code("go", "func main() {\n\tfmt.Println(\"hi\"\n}"), false},
+		{"json", code("json", `{"a": [1, 2]}`), true},
+		{"broken json", code("json", `{"a": [1, 2}`), false},
+	}
+	if _, err := exec.LookPath("python3"); err == nil {
+		tests = append(tests,
+			testCase{"python", code("py", "def f(x):\n    return x * 2\n"), true},
+			testCase{"broken python", code("python", "def f(x:\n    return x\n"), false},
+		)
+	}
+
+	for _, tt := range tests {
+		err := Validate(tt.artifact)
+		var syntaxErr *SyntaxError
+		switch {
+		case tt.valid && err != nil:
+			t.Errorf("%s: expected it to parse, got %v", tt.name, err)
+		case !tt.valid && !errors.As(err, &syntaxErr):
+			t.Errorf("%s: expected a syntax error, got %v", tt.name, err)
+		}
+	}
+
+	for _, a := range []*Artifact{code("cobol", "DISPLAY 'HI'."), {Type: TypeSVG, Content: "<svg"}} {
+		if err := Validate(a); !errors.Is(err, ErrNoSyntaxCheck) {
+			t.Errorf("expected no check for %s %s, got %v", a.Type, a.Language, err)
+		}
+	}
+}
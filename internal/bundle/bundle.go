@@ -0,0 +1,223 @@
+package bundle
+
+import (
+	"compress/gzip"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+const (
+	// Format identifies sync bundles
+	Format = "shannon-sync"
+	// Version is the bundle format version written by this build
+	Version = 1
+
+	// lastExportKey is the metadata key holding the time of the last export
+	lastExportKey = "sync_last_export"
+)
+
+// Bundle is a set of conversations exported from one database for import
+// into another. Conversations use the Claude export format, so importing a
+// bundle goes through the regular importer.
+type Bundle struct {
+	Format        string                      `json:"format"`
+	Version       int                         `json:"version"`
+	CreatedAt     time.Time                   `json:"created_at"`
+	Since         *time.Time                  `json:"since,omitempty"`
+	Conversations []models.ClaudeConversation `json:"conversations"`
+}
+
+// Build collects the conversations that were created, updated or received
+// new messages since the given time. A zero since collects every conversation.
+func Build(database *db.DB, since time.Time) (*Bundle, error) {
+	b := &Bundle{
+		Format:    Format,
+		Version:   Version,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	query := "SELECT id, uuid, name, created_at, updated_at, starred FROM conversations WHERE deleted_at IS NULL"
+	var args []interface{}
+	if !since.IsZero() {
+		s := since.UTC()
+		b.Since = &s
+		threshold := s.Format("2006-01-02 15:04:05")
+		query += `
+			AND (updated_at >= ? OR imported_at >= ?
+			   OR EXISTS (
+				SELECT 1 FROM messages m
+				JOIN import_history h ON m.import_id = h.id
+				WHERE m.conversation_id = conversations.id AND h.imported_at >= ?
+			   ))`
+		args = append(args, threshold, threshold, threshold)
+	}
+	query += " ORDER BY id"
+
+	rows, err := database.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query conversations: %w", err)
+	}
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		var conv models.ClaudeConversation
+		var createdAt, updatedAt time.Time
+		var starred bool
+		if err := rows.Scan(&id, &conv.UUID, &conv.Name, &createdAt, &updatedAt, &starred); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("failed to scan conversation: %w", err)
+		}
+		conv.CreatedAt = formatTime(createdAt)
+		conv.UpdatedAt = formatTime(updatedAt)
+		// Only stars are carried: a conversation that isn't starred here may
+		// just come from an export that didn't say, and an absent star keeps
+		// the receiving database's
+		if starred {
+			conv.Starred = &starred
+		}
+		ids = append(ids, id)
+		b.Conversations = append(b.Conversations, conv)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close rows: %w", err)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read conversations: %w", err)
+	}
+
+	// Messages are loaded after the conversation rows are closed, since the
+	// database only has a single connection
+	for i, id := range ids {
+		messages, err := loadMessages(database, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load messages of conversation %d: %w", id, err)
+		}
+		b.Conversations[i].ChatMessages = messages
+	}
+
+	return b, nil
+}
+
+// loadMessages returns every message of a conversation, including all
+// branches, with parents ahead of their replies
+func loadMessages(database *db.DB, convID int64) ([]models.ClaudeChatMessage, error) {
+	rows, err := database.Query(`
+		SELECT m.uuid, m.sender, message_text(m.text), m.created_at, p.uuid, m.model, m.starred, m.feedback
+		FROM messages m
+		LEFT JOIN messages p ON m.parent_id = p.id
+		WHERE m.conversation_id = ?
+		ORDER BY m.created_at, m.id
+	`, convID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close rows: %v\n", err)
+		}
+	}()
+
+	messages := []models.ClaudeChatMessage{}
+	for rows.Next() {
+		var msg models.ClaudeChatMessage
+		var createdAt time.Time
+		var parentUUID sql.NullString
+		var starred bool
+		var feedback string
+		if err := rows.Scan(&msg.UUID, &msg.Sender, &msg.Text, &createdAt, &parentUUID, &msg.Model, &starred, &feedback); err != nil {
+			return nil, err
+		}
+		msg.CreatedAt = formatTime(createdAt)
+		if starred {
+			msg.Starred = &starred
+		}
+		if feedback != "" {
+			msg.Feedback, _ = json.Marshal(feedback)
+		}
+		if parentUUID.Valid {
+			msg.ParentID = &parentUUID.String
+		}
+		messages = append(messages, msg)
+	}
+
+	return messages, rows.Err()
+}
+
+// Write writes the bundle as gzip-compressed JSON
+func Write(w io.Writer, b *Bundle) error {
+	gz := gzip.NewWriter(w)
+	if err := json.NewEncoder(gz).Encode(b); err != nil {
+		return fmt.Errorf("failed to encode bundle: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to compress bundle: %w", err)
+	}
+	return nil
+}
+
+// Read reads a bundle written by Write
+func Read(r io.Reader) (*Bundle, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a shannon sync bundle: %w", err)
+	}
+	defer func() {
+		if err := gz.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close bundle: %v\n", err)
+		}
+	}()
+
+	var b Bundle
+	if err := json.NewDecoder(gz).Decode(&b); err != nil {
+		return nil, fmt.Errorf("failed to decode bundle: %w", err)
+	}
+	if b.Format != Format {
+		return nil, fmt.Errorf("not a shannon sync bundle")
+	}
+	if b.Version > Version {
+		return nil, fmt.Errorf("bundle version %d is newer than this version of shannon supports (%d); upgrade shannon", b.Version, Version)
+	}
+
+	return &b, nil
+}
+
+// LastExport returns when a bundle was last exported from the database, or
+// the zero time if none was
+func LastExport(database *db.DB) (time.Time, error) {
+	var value string
+	err := database.QueryRow("SELECT value FROM metadata WHERE key = ?", lastExportKey).Scan(&value)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	} else if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read last export time: %w", err)
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid last export time %q: %w", value, err)
+	}
+	return t, nil
+}
+
+// SetLastExport records when a bundle was exported, so the next export can
+// pick up where it left off
+func SetLastExport(database *db.DB, t time.Time) error {
+	_, err := database.Exec("INSERT OR REPLACE INTO metadata (key, value) VALUES (?, ?)",
+		lastExportKey, t.UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return fmt.Errorf("failed to record export time: %w", err)
+	}
+	return nil
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
@@ -0,0 +1,219 @@
+package bundle
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/imports"
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+func newTestDB(t *testing.T) *db.DB {
+	t.Helper()
+
+	database, err := db.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := database.Close(); err != nil {
+			t.Errorf("failed to close database: %v", err)
+		}
+	})
+	return database
+}
+
+func importFile(t *testing.T, database *db.DB, conversations []models.ClaudeConversation) {
+	t.Helper()
+
+	data, err := json.Marshal(conversations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "export.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := imports.NewImporter(database, 100, false).Import(path); err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+}
+
+func roundTrip(t *testing.T, b *Bundle) *Bundle {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := Write(&buf, b); err != nil {
+		t.Fatal(err)
+	}
+	read, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("failed to read bundle: %v", err)
+	}
+	return read
+}
+
+func count(t *testing.T, database *db.DB, query string, args ...interface{}) int {
+	t.Helper()
+	var n int
+	if err := database.QueryRow(query, args...).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestSync(t *testing.T) {
+	desktop, laptop := newTestDB(t), newTestDB(t)
+
+	parent := "msg-1"
+	yes := true
+	conv := models.ClaudeConversation{
+		UUID: "conv-1", Name: "Sync test", Starred: &yes,
+		CreatedAt: "2024-01-01T10:00:00Z", UpdatedAt: "2024-01-01T10:05:00Z",
+		ChatMessages: []models.ClaudeChatMessage{
+			{UUID: "msg-1", Sender: "human", Text: "How do I sync?", CreatedAt: "2024-01-01T10:00:00Z"},
+			{UUID: "msg-2", Sender: "assistant", Text: "Use a bundle.", CreatedAt: "2024-01-01T10:01:00Z", ParentID: &parent, Model: "claude-3-opus",
+				Starred: &yes, Feedback: json.RawMessage(`{"type": "thumbs_up"}`)},
+		},
+	}
+	other := models.ClaudeConversation{
+		UUID: "conv-2", Name: "Unrelated",
+		CreatedAt: "2024-01-01T11:00:00Z", UpdatedAt: "2024-01-01T11:00:00Z",
+		ChatMessages: []models.ClaudeChatMessage{
+			{UUID: "msg-3", Sender: "human", Text: "Hello", CreatedAt: "2024-01-01T11:00:00Z"},
+		},
+	}
+	importFile(t, desktop, []models.ClaudeConversation{conv, other})
+
+	// A full bundle carries everything
+	full, err := Build(desktop, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	full = roundTrip(t, full)
+	if len(full.Conversations) != 2 || full.Since != nil {
+		t.Fatalf("expected 2 conversations in a full bundle, got %d", len(full.Conversations))
+	}
+	if _, err := imports.NewImporter(laptop, 100, false).ImportConversations("full.shannon", "hash-1", full.Conversations); err != nil {
+		t.Fatalf("bundle import failed: %v", err)
+	}
+	if n := count(t, laptop, "SELECT COUNT(*) FROM messages"); n != 3 {
+		t.Errorf("expected 3 messages after sync, got %d", n)
+	}
+	if n := count(t, laptop, `
+		SELECT COUNT(*) FROM messages m JOIN messages p ON m.parent_id = p.id
+		WHERE m.uuid = 'msg-2' AND p.uuid = 'msg-1'`); n != 1 {
+		t.Error("expected reply to keep its parent")
+	}
+	if n := count(t, laptop, "SELECT COUNT(*) FROM messages WHERE uuid = 'msg-2' AND model = 'claude-3-opus'"); n != 1 {
+		t.Error("expected reply to keep its model")
+	}
+	if n := count(t, laptop, "SELECT COUNT(*) FROM messages WHERE uuid = 'msg-2' AND starred AND feedback = 'up'"); n != 1 {
+		t.Error("expected reply to keep its star and feedback")
+	}
+	if n := count(t, laptop, "SELECT COUNT(*) FROM conversations WHERE starred"); n != 1 {
+		t.Errorf("expected only the starred conversation to be starred, got %d", n)
+	}
+	if n := count(t, laptop, "SELECT COUNT(*) FROM messages_fts WHERE messages_fts MATCH 'bundle'"); n != 1 {
+		t.Errorf("expected synced messages to be searchable, got %d matches", n)
+	}
+
+	if _, err := imports.NewImporter(laptop, 100, false).ImportConversations("full.shannon", "hash-1", full.Conversations); err == nil {
+		t.Error("expected importing the same bundle twice to fail")
+	}
+
+	// Pretend the first import happened long ago, then continue the conversation
+	for _, q := range []string{
+		"UPDATE conversations SET imported_at = '2020-01-01 00:00:00'",
+		"UPDATE import_history SET imported_at = '2020-01-01 00:00:00'",
+	} {
+		if _, err := desktop.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	followUp := "msg-2"
+	conv.UpdatedAt = "2024-01-02T09:00:00Z"
+	conv.ChatMessages = append(conv.ChatMessages, models.ClaudeChatMessage{
+		UUID: "msg-4", Sender: "human", Text: "Thanks", CreatedAt: "2024-01-02T09:00:00Z", ParentID: &followUp,
+	})
+	importFile(t, desktop, []models.ClaudeConversation{conv})
+
+	since := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	incremental, err := Build(desktop, since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	incremental = roundTrip(t, incremental)
+	if len(incremental.Conversations) != 1 || incremental.Conversations[0].UUID != "conv-1" {
+		t.Fatalf("expected only the continued conversation, got %+v", incremental.Conversations)
+	}
+	if incremental.Since == nil || !incremental.Since.Equal(since) {
+		t.Errorf("expected since to be recorded, got %v", incremental.Since)
+	}
+
+	// A star given on the laptop stays, since the bundle doesn't carry the
+	// desktop's lack of one
+	if _, err := laptop.Exec("UPDATE messages SET starred = 1 WHERE uuid = 'msg-1'"); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := imports.NewImporter(laptop, 100, false).ImportConversations("incremental.shannon", "hash-2", incremental.Conversations)
+	if err != nil {
+		t.Fatalf("incremental import failed: %v", err)
+	}
+	if stats.MessagesImported != 1 || stats.ConversationsImported != 0 {
+		t.Errorf("expected 1 new message in an existing conversation, got %+v", stats)
+	}
+	if n := count(t, laptop, "SELECT message_count FROM conversations WHERE uuid = 'conv-1'"); n != 3 {
+		t.Errorf("expected message_count 3, got %d", n)
+	}
+	if n := count(t, laptop, "SELECT COUNT(*) FROM messages WHERE starred"); n != 2 {
+		t.Errorf("expected the laptop's star and the synced one, got %d starred messages", n)
+	}
+}
+
+func TestReadRejectsOtherFiles(t *testing.T) {
+	if _, err := Read(strings.NewReader(`{"format": "shannon-sync"}`)); err == nil {
+		t.Error("expected uncompressed input to be rejected")
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, &Bundle{Format: "other", Version: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Read(&buf); err == nil {
+		t.Error("expected a bundle of another format to be rejected")
+	}
+
+	buf.Reset()
+	if err := Write(&buf, &Bundle{Format: Format, Version: Version + 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Read(&buf); err == nil {
+		t.Error("expected a newer bundle version to be rejected")
+	}
+}
+
+func TestLastExport(t *testing.T) {
+	database := newTestDB(t)
+
+	last, err := LastExport(database)
+	if err != nil || !last.IsZero() {
+		t.Fatalf("expected no last export, got %v (%v)", last, err)
+	}
+
+	now := time.Now()
+	if err := SetLastExport(database, now); err != nil {
+		t.Fatal(err)
+	}
+	last, err = LastExport(database)
+	if err != nil || !last.Equal(now) {
+		t.Errorf("expected %v, got %v (%v)", now, last, err)
+	}
+}
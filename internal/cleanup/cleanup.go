@@ -0,0 +1,513 @@
+package cleanup
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dustin/go-humanize"
+	"github.com/neilberkman/shannon/internal/artifacts"
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+// Edit actions recorded in the message_edits table
+const (
+	ActionDelete   = "delete"
+	ActionTruncate = "truncate"
+)
+
+// UndoWindow is how long a deleted or truncated message can be restored.
+// After that the original text is purged from the database.
+var UndoWindow = 7 * 24 * time.Hour
+
+// ErrNothingToTruncate is returned when a message is already within the
+// requested size
+var ErrNothingToTruncate = errors.New("message is already within the requested size")
+
+// TruncateOptions controls how much of a message is kept when truncating.
+// Zero values mean no limit; at least one limit must be set.
+type TruncateOptions struct {
+	KeepLines int
+	KeepChars int
+}
+
+// LargeMessage describes a message for picking cleanup candidates
+type LargeMessage struct {
+	MessageID        int64     `json:"message_id"`
+	ConversationID   int64     `json:"conversation_id"`
+	ConversationName string    `json:"conversation_name"`
+	Sender           string    `json:"sender"`
+	CreatedAt        time.Time `json:"created_at"`
+	Size             int       `json:"size"`
+	Lines            int       `json:"lines"`
+	Preview          string    `json:"preview"`
+}
+
+// storedMessage is a full messages row as needed to restore it
+type storedMessage struct {
+	models.Message
+	ImportID *int64
+}
+
+// DeleteMessage removes a message from the database. Replies to it are
+// attached to its parent so the conversation thread stays connected.
+func DeleteMessage(database *db.DB, messageID int64) (*models.MessageEdit, error) {
+	tx, err := database.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollback(tx)
+
+	msg, err := loadMessage(tx, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	children, err := queryIDs(tx, "SELECT id FROM messages WHERE parent_id = ? ORDER BY id", messageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find replies: %w", err)
+	}
+	if _, err := tx.Exec("UPDATE messages SET parent_id = ? WHERE parent_id = ?", msg.ParentID, messageID); err != nil {
+		return nil, fmt.Errorf("failed to reattach replies: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM messages WHERE id = ?", messageID); err != nil {
+		return nil, fmt.Errorf("failed to delete message: %w", err)
+	}
+
+	edit, err := recordEdit(tx, ActionDelete, msg, children)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := refreshConversation(tx, msg.ConversationID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+	return edit, nil
+}
+
+// TruncateMessage shortens a message to the requested size, appending a note
+// about how much was removed
+func TruncateMessage(database *db.DB, messageID int64, opts TruncateOptions) (*models.MessageEdit, error) {
+	if opts.KeepLines <= 0 && opts.KeepChars <= 0 {
+		return nil, fmt.Errorf("truncation needs a line or character limit")
+	}
+
+	tx, err := database.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollback(tx)
+
+	msg, err := loadMessage(tx, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	truncated, ok := Truncate(msg.Text, opts)
+	if !ok {
+		return nil, ErrNothingToTruncate
+	}
+
+	if _, err := tx.Exec("UPDATE messages SET text = ? WHERE id = ?", truncated, messageID); err != nil {
+		return nil, fmt.Errorf("failed to truncate message: %w", err)
+	}
+
+	edit, err := recordEdit(tx, ActionTruncate, msg, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := msg.Message
+	updated.Text = truncated
+	if err := reindexCodeBlocks(tx, &updated); err != nil {
+		return nil, err
+	}
+
+	if err := refreshConversation(tx, msg.ConversationID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+	return edit, nil
+}
+
+// Truncate returns text cut down to the limits in opts followed by a marker
+// line, and false if the text already fits
+func Truncate(text string, opts TruncateOptions) (string, bool) {
+	kept := text
+	lines := strings.Split(text, "\n")
+	if opts.KeepLines > 0 && len(lines) > opts.KeepLines {
+		kept = strings.Join(lines[:opts.KeepLines], "\n")
+	}
+	if opts.KeepChars > 0 && utf8.RuneCountInString(kept) > opts.KeepChars {
+		kept = string([]rune(kept)[:opts.KeepChars])
+	}
+	if kept == text {
+		return text, false
+	}
+
+	removedLines := len(lines) - strings.Count(kept, "\n") - 1
+	marker := fmt.Sprintf("[truncated: %s removed", humanize.Bytes(uint64(len(text)-len(kept))))
+	if removedLines > 0 {
+		marker += fmt.Sprintf(", %d more lines", removedLines)
+	}
+	marker += "]"
+
+	return strings.TrimRight(kept, " \t\r\n") + "\n\n" + marker, true
+}
+
+// Undo restores the message changed by an edit. Edits to the same message
+// must be undone newest first.
+func Undo(database *db.DB, editID int64) (*models.MessageEdit, error) {
+	tx, err := database.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollback(tx)
+
+	var msg storedMessage
+	var edit models.MessageEdit
+	var reparented sql.NullString
+	var purgedAt sql.NullTime
+	err = tx.QueryRow(`
+		SELECT id, action, message_id, conversation_id, message_uuid, sender, original_text,
+		       message_created_at, parent_id, branch_id, sequence, import_id, model, starred, feedback,
+		       reparented_ids, edited_at, purged_at
+		FROM message_edits WHERE id = ?
+	`, editID).Scan(&edit.ID, &edit.Action, &msg.ID, &msg.ConversationID, &msg.UUID, &msg.Sender, &msg.Text,
+		&msg.CreatedAt, &msg.ParentID, &msg.BranchID, &msg.Sequence, &msg.ImportID, &msg.Model, &msg.Starred, &msg.Feedback,
+		&reparented, &edit.EditedAt, &purgedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("edit %d not found", editID)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to load edit %d: %w", editID, err)
+	}
+	if purgedAt.Valid {
+		return nil, fmt.Errorf("edit %d is older than the undo window and can no longer be undone", editID)
+	}
+	edit.MessageID = msg.ID
+	edit.ConversationID = msg.ConversationID
+	edit.Sender = msg.Sender
+	edit.OriginalSize = len(msg.Text)
+
+	var newer int64
+	err = tx.QueryRow(`
+		SELECT id FROM message_edits
+		WHERE message_id = ? AND id > ? AND purged_at IS NULL
+		ORDER BY id DESC LIMIT 1
+	`, msg.ID, editID).Scan(&newer)
+	if err == nil {
+		return nil, fmt.Errorf("message %d was edited again later; undo edit %d first", msg.ID, newer)
+	} else if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	switch edit.Action {
+	case ActionDelete:
+		if err := restoreMessage(tx, &msg, reparented.String); err != nil {
+			return nil, err
+		}
+	case ActionTruncate:
+		result, err := tx.Exec("UPDATE messages SET text = ? WHERE id = ?", msg.Text, msg.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore message text: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return nil, fmt.Errorf("message %d no longer exists", msg.ID)
+		}
+	}
+
+	if err := reindexCodeBlocks(tx, &msg.Message); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec("DELETE FROM message_edits WHERE id = ?", editID); err != nil {
+		return nil, fmt.Errorf("failed to remove edit record: %w", err)
+	}
+
+	if err := refreshConversation(tx, msg.ConversationID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+	return &edit, nil
+}
+
+// ListEdits returns the edits that can still be undone, newest first. Pass a
+// conversation ID of 0 to list edits across all conversations.
+func ListEdits(database *db.DB, conversationID int64) ([]*models.MessageEdit, error) {
+	query := `
+		SELECT id, action, message_id, conversation_id, sender, LENGTH(CAST(original_text AS BLOB)), edited_at
+		FROM message_edits
+		WHERE purged_at IS NULL
+	`
+	var args []interface{}
+	if conversationID > 0 {
+		query += " AND conversation_id = ?"
+		args = append(args, conversationID)
+	}
+	query += " ORDER BY id DESC"
+
+	rows, err := database.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query edits: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close rows: %v\n", err)
+		}
+	}()
+
+	var edits []*models.MessageEdit
+	for rows.Next() {
+		var e models.MessageEdit
+		if err := rows.Scan(&e.ID, &e.Action, &e.MessageID, &e.ConversationID, &e.Sender, &e.OriginalSize, &e.EditedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan edit: %w", err)
+		}
+		edits = append(edits, &e)
+	}
+	return edits, rows.Err()
+}
+
+// PurgeExpired drops the original text of edits older than window, making them
+// permanent. The edit rows are kept so deleted messages stay deleted when the
+// conversation is imported again. Returns the number of edits purged.
+func PurgeExpired(database *db.DB, window time.Duration) (int, error) {
+	result, err := database.Exec(`
+		UPDATE message_edits
+		SET original_text = '', purged_at = CURRENT_TIMESTAMP
+		WHERE purged_at IS NULL AND edited_at < ?
+	`, time.Now().Add(-window).UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge expired edits: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return int(n), nil
+}
+
+// LargestMessages returns the biggest messages by text size, optionally
+// limited to one conversation
+func LargestMessages(database *db.DB, conversationID int64, limit int) ([]*LargeMessage, error) {
+	query := `
+		SELECT m.id, m.conversation_id, c.name, m.sender, m.created_at, message_text(m.text)
+		FROM messages m
+		JOIN conversations c ON m.conversation_id = c.id
+		WHERE c.deleted_at IS NULL
+	`
+	var args []interface{}
+	if conversationID > 0 {
+		query += " AND m.conversation_id = ?"
+		args = append(args, conversationID)
+	}
+	query += " ORDER BY LENGTH(CAST(message_text(m.text) AS BLOB)) DESC, m.id LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := database.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query messages: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close rows: %v\n", err)
+		}
+	}()
+
+	var messages []*LargeMessage
+	for rows.Next() {
+		var m LargeMessage
+		var text string
+		if err := rows.Scan(&m.MessageID, &m.ConversationID, &m.ConversationName, &m.Sender, &m.CreatedAt, &text); err != nil {
+			return nil, fmt.Errorf("failed to scan message: %w", err)
+		}
+		m.Size = len(text)
+		m.Lines = strings.Count(text, "\n") + 1
+		m.Preview = preview(text, 60)
+		messages = append(messages, &m)
+	}
+	return messages, rows.Err()
+}
+
+// loadMessage reads the full row of a message
+func loadMessage(tx *sql.Tx, messageID int64) (*storedMessage, error) {
+	var msg storedMessage
+	err := tx.QueryRow(`
+		SELECT id, uuid, conversation_id, sender, message_text(text), created_at, parent_id, branch_id, sequence, import_id, model,
+		       starred, feedback
+		FROM messages WHERE id = ?
+	`, messageID).Scan(&msg.ID, &msg.UUID, &msg.ConversationID, &msg.Sender, &msg.Text,
+		&msg.CreatedAt, &msg.ParentID, &msg.BranchID, &msg.Sequence, &msg.ImportID, &msg.Model, &msg.Starred, &msg.Feedback)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("message %d not found", messageID)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to load message %d: %w", messageID, err)
+	}
+	return &msg, nil
+}
+
+// recordEdit saves the original message so the edit can be undone
+func recordEdit(tx *sql.Tx, action string, msg *storedMessage, reparented []int64) (*models.MessageEdit, error) {
+	var reparentedJSON interface{}
+	if len(reparented) > 0 {
+		data, err := json.Marshal(reparented)
+		if err != nil {
+			return nil, err
+		}
+		reparentedJSON = string(data)
+	}
+
+	editedAt := time.Now().UTC()
+	result, err := tx.Exec(`
+		INSERT INTO message_edits (action, message_id, conversation_id, message_uuid, sender, original_text,
+			message_created_at, parent_id, branch_id, sequence, import_id, model, starred, feedback, reparented_ids, edited_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, action, msg.ID, msg.ConversationID, msg.UUID, msg.Sender, msg.Text,
+		msg.CreatedAt, msg.ParentID, msg.BranchID, msg.Sequence, msg.ImportID, msg.Model, msg.Starred, msg.Feedback, reparentedJSON, editedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record edit: %w", err)
+	}
+
+	id, _ := result.LastInsertId()
+	return &models.MessageEdit{
+		ID:             id,
+		Action:         action,
+		MessageID:      msg.ID,
+		ConversationID: msg.ConversationID,
+		Sender:         msg.Sender,
+		OriginalSize:   len(msg.Text),
+		EditedAt:       editedAt,
+	}, nil
+}
+
+// restoreMessage re-inserts a deleted message with its original ID and gives
+// it back the replies that were attached to its parent
+func restoreMessage(tx *sql.Tx, msg *storedMessage, reparented string) error {
+	var exists int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM messages WHERE uuid = ?", msg.UUID).Scan(&exists); err != nil {
+		return err
+	}
+	if exists > 0 {
+		return fmt.Errorf("message %s already exists again", msg.UUID)
+	}
+
+	if err := tx.QueryRow("SELECT COUNT(*) FROM branches WHERE id = ?", msg.BranchID).Scan(&exists); err != nil {
+		return err
+	}
+	if exists == 0 {
+		return fmt.Errorf("the branch message %d belonged to no longer exists", msg.ID)
+	}
+
+	// The parent may have been deleted in the meantime
+	if msg.ParentID != nil {
+		if err := tx.QueryRow("SELECT COUNT(*) FROM messages WHERE id = ?", *msg.ParentID).Scan(&exists); err != nil {
+			return err
+		}
+		if exists == 0 {
+			msg.ParentID = nil
+		}
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO messages (id, uuid, conversation_id, sender, text, created_at, parent_id, branch_id, sequence, import_id, model,
+			starred, feedback)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, msg.ID, msg.UUID, msg.ConversationID, msg.Sender, msg.Text, msg.CreatedAt,
+		msg.ParentID, msg.BranchID, msg.Sequence, msg.ImportID, msg.Model, msg.Starred, msg.Feedback); err != nil {
+		return fmt.Errorf("failed to restore message: %w", err)
+	}
+
+	if reparented == "" {
+		return nil
+	}
+	var children []int64
+	if err := json.Unmarshal([]byte(reparented), &children); err != nil {
+		return fmt.Errorf("invalid reparented message list: %w", err)
+	}
+	for _, child := range children {
+		if _, err := tx.Exec("UPDATE messages SET parent_id = ? WHERE id = ?", msg.ID, child); err != nil {
+			return fmt.Errorf("failed to reattach message %d: %w", child, err)
+		}
+	}
+	return nil
+}
+
+// reindexCodeBlocks replaces the code block index entries of a message
+func reindexCodeBlocks(tx *sql.Tx, msg *models.Message) error {
+	if _, err := tx.Exec("DELETE FROM code_blocks WHERE message_id = ?", msg.ID); err != nil {
+		return fmt.Errorf("failed to clear code blocks: %w", err)
+	}
+	if err := artifacts.NewExtractor().IndexMessage(tx, msg); err != nil {
+		return fmt.Errorf("failed to index code blocks: %w", err)
+	}
+	return nil
+}
+
+// refreshConversation recomputes the message count and derived metrics of a
+// conversation after one of its messages changed
+func refreshConversation(tx *sql.Tx, conversationID int64) error {
+	if _, err := tx.Exec(`
+		UPDATE conversations
+		SET message_count = (SELECT COUNT(*) FROM messages WHERE conversation_id = ?)
+		WHERE id = ?
+	`, conversationID, conversationID); err != nil {
+		return fmt.Errorf("failed to update conversation %d: %w", conversationID, err)
+	}
+	if _, err := tx.Exec(db.RefreshConversationStatsSQL, conversationID); err != nil {
+		return fmt.Errorf("failed to update conversation %d statistics: %w", conversationID, err)
+	}
+	return nil
+}
+
+func queryIDs(tx *sql.Tx, query string, args ...interface{}) ([]int64, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close rows: %v\n", err)
+		}
+	}()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func rollback(tx *sql.Tx) {
+	if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+		fmt.Fprintf(os.Stderr, "Warning: failed to rollback transaction: %v\n", err)
+	}
+}
+
+// preview returns the first line of text shortened to n runes
+func preview(text string, n int) string {
+	line := strings.TrimSpace(text)
+	if idx := strings.IndexByte(line, '\n'); idx >= 0 {
+		line = strings.TrimSpace(line[:idx])
+	}
+	if utf8.RuneCountInString(line) > n {
+		line = string([]rune(line)[:n-3]) + "..."
+	}
+	return line
+}
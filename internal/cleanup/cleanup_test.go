@@ -0,0 +1,242 @@
+package cleanup
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/imports"
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+var testLog = strings.Repeat("2024-01-01 ERROR connection refused\n", 200) + "panic: zanzibar"
+
+func setupTestDB(t *testing.T) (*db.DB, string) {
+	t.Helper()
+
+	tmpDir := t.TempDir()
+	database, err := db.New(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := database.Close(); err != nil {
+			t.Errorf("failed to close database: %v", err)
+		}
+	})
+
+	parent1, parent2 := "msg-1", "msg-2"
+	data, err := json.Marshal([]models.ClaudeConversation{
+		{
+			UUID: "conv-1", Name: "Debugging the server",
+			CreatedAt: "2024-01-01T10:00:00Z", UpdatedAt: "2024-01-01T10:05:00Z",
+			ChatMessages: []models.ClaudeChatMessage{
+				{UUID: "msg-1", Sender: "human", Text: "Why does it crash?\n" + testLog, CreatedAt: "2024-01-01T10:00:00Z"},
+				{UUID: "msg-2", Sender: "assistant", Text: "The port is closed.\n```go\nnet.Dial(\"tcp\", addr)\n```", CreatedAt: "2024-01-01T10:01:00Z", ParentID: &parent1},
+				{UUID: "msg-3", Sender: "human", Text: "Thanks", CreatedAt: "2024-01-01T10:02:00Z", ParentID: &parent2},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(tmpDir, "export.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := imports.NewImporter(database, 100, false).Import(path); err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+
+	return database, path
+}
+
+func messageID(t *testing.T, database *db.DB, uuid string) int64 {
+	t.Helper()
+	var id int64
+	if err := database.QueryRow("SELECT id FROM messages WHERE uuid = ?", uuid).Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func count(t *testing.T, database *db.DB, query string, args ...interface{}) int {
+	t.Helper()
+	var n int
+	if err := database.QueryRow(query, args...).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestTruncate(t *testing.T) {
+	text := "one\ntwo\nthree\nfour"
+
+	got, ok := Truncate(text, TruncateOptions{KeepLines: 2})
+	if !ok || !strings.HasPrefix(got, "one\ntwo\n\n[truncated:") || !strings.HasSuffix(got, "2 more lines]") {
+		t.Errorf("unexpected truncation: %q", got)
+	}
+
+	got, ok = Truncate(text, TruncateOptions{KeepChars: 5})
+	if !ok || !strings.HasPrefix(got, "one\nt\n\n[truncated:") {
+		t.Errorf("unexpected character truncation: %q", got)
+	}
+
+	if _, ok := Truncate(text, TruncateOptions{KeepLines: 10}); ok {
+		t.Error("expected short text to be left alone")
+	}
+}
+
+func TestTruncateAndUndo(t *testing.T) {
+	database, _ := setupTestDB(t)
+	id := messageID(t, database, "msg-1")
+
+	edit, err := TruncateMessage(database, id, TruncateOptions{KeepLines: 3})
+	if err != nil {
+		t.Fatalf("truncate failed: %v", err)
+	}
+	if edit.Action != ActionTruncate || edit.OriginalSize <= 1000 {
+		t.Errorf("unexpected edit: %+v", edit)
+	}
+
+	if n := count(t, database, "SELECT COUNT(*) FROM messages_fts WHERE messages_fts MATCH 'zanzibar'"); n != 0 {
+		t.Errorf("expected truncated text to be gone from the FTS index, got %d matches", n)
+	}
+	if n := count(t, database, "SELECT COUNT(*) FROM messages_fts WHERE messages_fts MATCH 'crash'"); n != 1 {
+		t.Errorf("expected kept text to stay searchable, got %d matches", n)
+	}
+	if _, err := TruncateMessage(database, id, TruncateOptions{KeepLines: 10}); err != ErrNothingToTruncate {
+		t.Errorf("expected ErrNothingToTruncate, got %v", err)
+	}
+
+	tokensAfter := count(t, database, "SELECT token_count FROM conversations")
+	if _, err := Undo(database, edit.ID); err != nil {
+		t.Fatalf("undo failed: %v", err)
+	}
+	if n := count(t, database, "SELECT COUNT(*) FROM messages_fts WHERE messages_fts MATCH 'zanzibar'"); n != 1 {
+		t.Errorf("expected restored text to be searchable, got %d matches", n)
+	}
+	if tokens := count(t, database, "SELECT token_count FROM conversations"); tokens <= tokensAfter {
+		t.Errorf("expected token count to grow back after undo, got %d (truncated %d)", tokens, tokensAfter)
+	}
+	if n := count(t, database, "SELECT COUNT(*) FROM message_edits"); n != 0 {
+		t.Errorf("expected undone edit to be removed, got %d edits", n)
+	}
+}
+
+func TestDeleteAndUndo(t *testing.T) {
+	database, exportPath := setupTestDB(t)
+	first, second, third := messageID(t, database, "msg-1"), messageID(t, database, "msg-2"), messageID(t, database, "msg-3")
+
+	edit, err := DeleteMessage(database, second)
+	if err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+
+	if n := count(t, database, "SELECT message_count FROM conversations"); n != 2 {
+		t.Errorf("expected message_count 2, got %d", n)
+	}
+	if n := count(t, database, "SELECT parent_id FROM messages WHERE id = ?", third); int64(n) != first {
+		t.Errorf("expected reply to be reattached to message %d, got %d", first, n)
+	}
+	if n := count(t, database, "SELECT COUNT(*) FROM messages_fts_code WHERE messages_fts_code MATCH 'Dial'"); n != 0 {
+		t.Errorf("expected deleted message to be gone from the code FTS index, got %d matches", n)
+	}
+	if n := count(t, database, "SELECT COUNT(*) FROM code_blocks"); n != 0 {
+		t.Errorf("expected code blocks of the deleted message to be removed, got %d", n)
+	}
+
+	// Re-importing the same conversation doesn't bring the message back
+	data, err := os.ReadFile(exportPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newerExport := filepath.Join(filepath.Dir(exportPath), "newer.json")
+	if err := os.WriteFile(newerExport, append(data, '\n'), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := imports.NewImporter(database, 100, false).Import(newerExport); err != nil {
+		t.Fatalf("re-import failed: %v", err)
+	}
+	if n := count(t, database, "SELECT COUNT(*) FROM messages"); n != 2 {
+		t.Errorf("expected deleted message to stay deleted after re-import, got %d messages", n)
+	}
+
+	edits, err := ListEdits(database, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(edits) != 1 || edits[0].ID != edit.ID || edits[0].Action != ActionDelete {
+		t.Fatalf("unexpected edits: %+v", edits)
+	}
+
+	if _, err := Undo(database, edit.ID); err != nil {
+		t.Fatalf("undo failed: %v", err)
+	}
+	if n := count(t, database, "SELECT message_count FROM conversations"); n != 3 {
+		t.Errorf("expected message_count 3 after undo, got %d", n)
+	}
+	if n := count(t, database, "SELECT parent_id FROM messages WHERE id = ?", third); int64(n) != second {
+		t.Errorf("expected reply to point at the restored message, got parent %d", n)
+	}
+	if n := count(t, database, "SELECT COUNT(*) FROM messages_fts_code WHERE messages_fts_code MATCH 'Dial'"); n != 1 {
+		t.Errorf("expected restored message to be searchable, got %d matches", n)
+	}
+	if n := count(t, database, "SELECT COUNT(*) FROM code_blocks"); n != 1 {
+		t.Errorf("expected restored message to be re-indexed, got %d code blocks", n)
+	}
+}
+
+func TestUndoOrderAndPurge(t *testing.T) {
+	database, _ := setupTestDB(t)
+	id := messageID(t, database, "msg-1")
+
+	truncate, err := TruncateMessage(database, id, TruncateOptions{KeepLines: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	del, err := DeleteMessage(database, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Undo(database, truncate.ID); err == nil {
+		t.Error("expected undoing an older edit of the same message to fail")
+	}
+
+	purged, err := PurgeExpired(database, -time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged != 2 {
+		t.Errorf("expected 2 purged edits, got %d", purged)
+	}
+	if _, err := Undo(database, del.ID); err == nil {
+		t.Error("expected purged edit to be permanent")
+	}
+	if edits, err := ListEdits(database, 0); err != nil || len(edits) != 0 {
+		t.Errorf("expected no undoable edits, got %v (%v)", edits, err)
+	}
+}
+
+func TestLargestMessages(t *testing.T) {
+	database, _ := setupTestDB(t)
+
+	messages, err := LargestMessages(database, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(messages))
+	}
+	if messages[0].MessageID != messageID(t, database, "msg-1") || messages[0].Lines != 202 {
+		t.Errorf("unexpected largest message: %+v", messages[0])
+	}
+	if messages[0].Preview != "Why does it crash?" {
+		t.Errorf("unexpected preview %q", messages[0].Preview)
+	}
+}
@@ -0,0 +1,162 @@
+package cleanup
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+// TrashConversation moves a conversation to the trash. Its messages drop out
+// of the search indexes and it disappears from search, lists and stats, but
+// nothing is deleted until the trash is emptied or the conversation has been
+// there longer than the retention period. A tombstone is left as well, so
+// importing an export that still contains the conversation doesn't bring it
+// back; RestoreConversation removes both.
+func TrashConversation(database *db.DB, conversationID int64) (*models.TrashedConversation, error) {
+	tx, err := database.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollback(tx)
+
+	trashed := models.TrashedConversation{ID: conversationID}
+	var deletedAt sql.NullTime
+	err = tx.QueryRow("SELECT uuid, name, message_count, deleted_at FROM conversations WHERE id = ?", conversationID).
+		Scan(&trashed.UUID, &trashed.Name, &trashed.MessageCount, &deletedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("conversation %d not found", conversationID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load conversation %d: %w", conversationID, err)
+	}
+	if deletedAt.Valid {
+		return nil, fmt.Errorf("conversation %d is already in the trash", conversationID)
+	}
+
+	if _, err := tx.Exec(`
+		INSERT OR REPLACE INTO deleted_conversations (uuid, name, message_count)
+		VALUES (?, ?, ?)
+	`, trashed.UUID, trashed.Name, trashed.MessageCount); err != nil {
+		return nil, fmt.Errorf("failed to record deletion: %w", err)
+	}
+
+	// The conversations_trash trigger takes the messages out of the search
+	// indexes
+	if _, err := tx.Exec("UPDATE conversations SET deleted_at = CURRENT_TIMESTAMP WHERE id = ?", conversationID); err != nil {
+		return nil, fmt.Errorf("failed to move conversation to the trash: %w", err)
+	}
+	if err := tx.QueryRow("SELECT deleted_at FROM conversations WHERE id = ?", conversationID).Scan(&trashed.DeletedAt); err != nil {
+		return nil, fmt.Errorf("failed to read deletion time: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+	return &trashed, nil
+}
+
+// RestoreConversation takes a conversation out of the trash, putting its
+// messages back in the search indexes and removing its tombstone
+func RestoreConversation(database *db.DB, conversationID int64) (*models.TrashedConversation, error) {
+	tx, err := database.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollback(tx)
+
+	restored := models.TrashedConversation{ID: conversationID}
+	var deletedAt sql.NullTime
+	err = tx.QueryRow("SELECT uuid, name, message_count, deleted_at FROM conversations WHERE id = ?", conversationID).
+		Scan(&restored.UUID, &restored.Name, &restored.MessageCount, &deletedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("conversation %d not found", conversationID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load conversation %d: %w", conversationID, err)
+	}
+	if !deletedAt.Valid {
+		return nil, fmt.Errorf("conversation %d is not in the trash", conversationID)
+	}
+	restored.DeletedAt = deletedAt.Time
+
+	// The conversations_restore trigger indexes the messages again
+	if _, err := tx.Exec("UPDATE conversations SET deleted_at = NULL WHERE id = ?", conversationID); err != nil {
+		return nil, fmt.Errorf("failed to restore conversation: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM deleted_conversations WHERE uuid = ?", restored.UUID); err != nil {
+		return nil, fmt.Errorf("failed to remove tombstone: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+	return &restored, nil
+}
+
+// TrashedConversations returns the conversations in the trash, most recently
+// trashed first
+func TrashedConversations(database *db.DB) ([]*models.TrashedConversation, error) {
+	rows, err := database.Query(`
+		SELECT id, uuid, name, message_count, deleted_at
+		FROM conversations
+		WHERE deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC, id DESC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query trash: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close rows: %v\n", err)
+		}
+	}()
+
+	var trashed []*models.TrashedConversation
+	for rows.Next() {
+		var t models.TrashedConversation
+		if err := rows.Scan(&t.ID, &t.UUID, &t.Name, &t.MessageCount, &t.DeletedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan trashed conversation: %w", err)
+		}
+		trashed = append(trashed, &t)
+	}
+	return trashed, rows.Err()
+}
+
+// EmptyTrash permanently deletes every conversation in the trash and returns
+// how many there were. Their tombstones stay, so the conversations can only
+// come back by importing them with --restore-deleted.
+func EmptyTrash(database *db.DB) (int64, error) {
+	return database.PurgeTrash(time.Now())
+}
+
+// DeletedConversations returns the tombstones of deleted conversations,
+// newest first
+func DeletedConversations(database *db.DB) ([]*models.DeletedConversation, error) {
+	rows, err := database.Query(`
+		SELECT uuid, name, message_count, deleted_at
+		FROM deleted_conversations
+		ORDER BY deleted_at DESC, uuid
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deleted conversations: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close rows: %v\n", err)
+		}
+	}()
+
+	var deleted []*models.DeletedConversation
+	for rows.Next() {
+		var d models.DeletedConversation
+		if err := rows.Scan(&d.UUID, &d.Name, &d.MessageCount, &d.DeletedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan deleted conversation: %w", err)
+		}
+		deleted = append(deleted, &d)
+	}
+	return deleted, rows.Err()
+}
@@ -0,0 +1,133 @@
+package cleanup
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/imports"
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+func TestTrashConversation(t *testing.T) {
+	database, path := setupTestDB(t)
+
+	var convID int64
+	if err := database.QueryRow("SELECT id FROM conversations WHERE uuid = 'conv-1'").Scan(&convID); err != nil {
+		t.Fatal(err)
+	}
+	matches := "SELECT COUNT(*) FROM messages_fts WHERE messages_fts MATCH 'zanzibar'"
+
+	trashed, err := TrashConversation(database, convID)
+	if err != nil {
+		t.Fatalf("trash failed: %v", err)
+	}
+	if trashed.UUID != "conv-1" || trashed.MessageCount != 3 || trashed.DeletedAt.IsZero() {
+		t.Errorf("unexpected trashed conversation: %+v", trashed)
+	}
+	if n := count(t, database, "SELECT COUNT(*) FROM messages"); n != 3 {
+		t.Errorf("expected messages to be kept in the trash, got %d", n)
+	}
+	if n := count(t, database, matches); n != 0 {
+		t.Errorf("expected trashed messages to be gone from the FTS index, got %d matches", n)
+	}
+	if _, err := TrashConversation(database, convID); err == nil {
+		t.Error("expected trashing a trashed conversation to fail")
+	}
+	assertIndexesInSync(t, database)
+
+	list, err := TrashedConversations(database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].ID != convID || list[0].Name != "Debugging the server" {
+		t.Errorf("unexpected trash: %+v", list)
+	}
+
+	// Restoring puts the messages back in the index
+	if _, err := RestoreConversation(database, convID); err != nil {
+		t.Fatalf("restore failed: %v", err)
+	}
+	if n := count(t, database, matches); n == 0 {
+		t.Error("expected restored messages to be searchable again")
+	}
+	if n := count(t, database, "SELECT COUNT(*) FROM deleted_conversations"); n != 0 {
+		t.Errorf("expected the tombstone to be removed on restore, got %d", n)
+	}
+	if _, err := RestoreConversation(database, convID); err == nil {
+		t.Error("expected restoring a conversation that isn't trashed to fail")
+	}
+	assertIndexesInSync(t, database)
+
+	// Emptying the trash deletes it for good
+	if _, err := TrashConversation(database, convID); err != nil {
+		t.Fatal(err)
+	}
+	if purged, err := database.PurgeTrash(time.Now().Add(-time.Hour)); err != nil || purged != 0 {
+		t.Errorf("expected nothing trashed an hour ago to be purged, got %d (%v)", purged, err)
+	}
+	if purged, err := EmptyTrash(database); err != nil || purged != 1 {
+		t.Fatalf("expected one conversation to be purged, got %d (%v)", purged, err)
+	}
+	for _, table := range []string{"conversations", "messages", "code_blocks"} {
+		if n := count(t, database, "SELECT COUNT(*) FROM "+table); n != 0 {
+			t.Errorf("expected %s to be deleted, got %d", table, n)
+		}
+	}
+	assertIndexesInSync(t, database)
+
+	deleted, err := DeletedConversations(database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 1 || deleted[0].Name != "Debugging the server" {
+		t.Errorf("unexpected deleted conversations: %+v", deleted)
+	}
+
+	// A newer export that still contains the conversation doesn't bring it back
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var conversations []models.ClaudeConversation
+	if err := json.Unmarshal(data, &conversations); err != nil {
+		t.Fatal(err)
+	}
+	stats, err := imports.NewImporter(database, 100, false).ImportConversations("newer.json", "hash-newer", conversations)
+	if err != nil {
+		t.Fatalf("re-import failed: %v", err)
+	}
+	if stats.ConversationsSkipped != 1 || stats.ConversationsImported != 0 {
+		t.Errorf("expected the deleted conversation to be skipped, got %+v", stats)
+	}
+	if n := count(t, database, "SELECT COUNT(*) FROM conversations"); n != 0 {
+		t.Errorf("expected no conversations after re-import, got %d", n)
+	}
+
+	// Unless asked to restore it
+	importer := imports.NewImporter(database, 100, false)
+	importer.SetRestoreDeleted(true)
+	stats, err = importer.ImportConversations("newest.json", "hash-newest", conversations)
+	if err != nil {
+		t.Fatalf("restoring import failed: %v", err)
+	}
+	if stats.ConversationsRestored != 1 || stats.ConversationsImported != 1 || stats.MessagesImported != 3 {
+		t.Errorf("expected the conversation to be restored, got %+v", stats)
+	}
+	if n := count(t, database, "SELECT COUNT(*) FROM deleted_conversations"); n != 0 {
+		t.Errorf("expected the tombstone to be removed, got %d", n)
+	}
+}
+
+// assertIndexesInSync fails if the full-text indexes disagree with the
+// messages they cover
+func assertIndexesInSync(t *testing.T, database *db.DB) {
+	t.Helper()
+	for _, table := range []string{"messages_fts", "messages_fts_code"} {
+		if _, err := database.Exec("INSERT INTO " + table + "(" + table + ", rank) VALUES ('integrity-check', 1)"); err != nil {
+			t.Errorf("%s out of sync: %v", table, err)
+		}
+	}
+}
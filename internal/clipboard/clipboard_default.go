@@ -1,15 +1,16 @@
 //go:build !darwin && !windows
 
-package tui
+package clipboard
 
 import (
 	"bytes"
 	"fmt"
+	"os"
 	"os/exec"
 )
 
-// initClipboard is a no-op on systems without clipboard support
-func initClipboard() error {
+// Init checks for a clipboard tool on systems without native clipboard support
+func Init() error {
 	// Check if xclip or xsel is available
 	if _, err := exec.LookPath("xclip"); err == nil {
 		return nil
@@ -24,8 +25,20 @@ func initClipboard() error {
 	return fmt.Errorf("no clipboard tool found (install xclip, xsel, or wl-clipboard)")
 }
 
-// writeToClipboard attempts to use xclip, xsel, or wl-copy if available
-func writeToClipboard(text string) error {
+// ready reports whether to write to the clipboard: false in tests, an error
+// if there's no clipboard tool
+func ready() (bool, error) {
+	if os.Getenv("GO_TEST") == "1" || os.Getenv("CI") != "" {
+		return false, nil
+	}
+	if err := Init(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Write attempts to use xclip, xsel, or wl-copy if available
+func Write(text string) error {
 	// Try xclip first (most common)
 	if _, err := exec.LookPath("xclip"); err == nil {
 		cmd := exec.Command("xclip", "-selection", "clipboard")
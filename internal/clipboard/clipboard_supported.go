@@ -1,6 +1,6 @@
 //go:build darwin || windows
 
-package tui
+package clipboard
 
 import (
 	"fmt"
@@ -12,8 +12,8 @@ import (
 var clipboardInitialized bool
 var clipboardErr error
 
-// initClipboard initializes the clipboard
-func initClipboard() error {
+// Init initializes the clipboard
+func Init() error {
 	// Skip initialization in test environment
 	if os.Getenv("GO_TEST") == "1" || os.Getenv("CI") != "" {
 		return nil
@@ -32,18 +32,27 @@ func initClipboard() error {
 	return clipboardErr
 }
 
-// writeToClipboard writes text to the clipboard
-func writeToClipboard(text string) error {
+// ready reports whether to write to the clipboard: false in tests, an error
+// if Init failed
+func ready() (bool, error) {
 	// Skip in test environment
 	if os.Getenv("GO_TEST") == "1" || os.Getenv("CI") != "" {
-		return nil
+		return false, nil
 	}
 
 	if !clipboardInitialized {
 		if clipboardErr != nil {
-			return clipboardErr
+			return false, clipboardErr
 		}
-		return fmt.Errorf("clipboard not initialized")
+		return false, fmt.Errorf("clipboard not initialized")
+	}
+	return true, nil
+}
+
+// Write writes text to the clipboard
+func Write(text string) error {
+	if ok, err := ready(); !ok {
+		return err
 	}
 
 	// Catch any panics from clipboard.Write()
@@ -0,0 +1,4 @@
+// Package clipboard writes text to the system clipboard, natively on macOS
+// and Windows and through xclip, xsel or wl-copy elsewhere. WriteRich also
+// copies HTML or an image, where the platform allows.
+package clipboard
@@ -0,0 +1,60 @@
+package clipboard
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Clipboard formats of Content
+const (
+	MIMEText = "text/plain"
+	MIMEHTML = "text/html"
+	MIMEPNG  = "image/png"
+)
+
+// Content is something to copy in several formats at once, so that each
+// application pastes the richest one it takes: a rich editor the HTML or the
+// image, a terminal the text
+type Content struct {
+	Text string // always written
+	HTML string // or empty
+	PNG  []byte // or nil
+}
+
+// richest returns the richest format of the content and its data, or the
+// text if it has no other
+func (c Content) richest() (string, []byte) {
+	switch {
+	case len(c.PNG) > 0:
+		return MIMEPNG, c.PNG
+	case c.HTML != "":
+		return MIMEHTML, []byte(c.HTML)
+	default:
+		return MIMEText, []byte(c.Text)
+	}
+}
+
+// Markers of the HTML Format that the Windows clipboard takes HTML in
+const (
+	cfHTMLStart = "<!--StartFragment-->"
+	cfHTMLEnd   = "<!--EndFragment-->"
+)
+
+// cfHTML wraps a fragment of HTML in the Windows clipboard's HTML Format: a
+// header giving the byte offsets of the document and of the fragment in it
+func cfHTML(fragment string) []byte {
+	const header = "Version:0.9\r\nStartHTML:%010d\r\nEndHTML:%010d\r\nStartFragment:%010d\r\nEndFragment:%010d\r\n"
+	headerLen := len(fmt.Sprintf(header, 0, 0, 0, 0))
+
+	var doc bytes.Buffer
+	doc.WriteString("<html><body>\r\n" + cfHTMLStart)
+	startFragment := headerLen + doc.Len()
+	doc.WriteString(fragment)
+	endFragment := headerLen + doc.Len()
+	doc.WriteString(cfHTMLEnd + "\r\n</body></html>")
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, header, headerLen, headerLen+doc.Len(), startFragment, endFragment)
+	out.Write(doc.Bytes())
+	return out.Bytes()
+}
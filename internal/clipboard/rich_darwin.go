@@ -0,0 +1,44 @@
+//go:build darwin
+
+package clipboard
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+
+	clipboard "golang.design/x/clipboard"
+)
+
+// WriteRich writes content to the clipboard: an image natively, HTML along
+// with its text through AppleScript, so that plain text editors still paste
+// the text
+func WriteRich(c Content) (err error) {
+	mime, data := c.richest()
+	switch mime {
+	case MIMEText:
+		return Write(c.Text)
+	case MIMEPNG:
+		if ok, err := ready(); !ok {
+			return err
+		}
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("clipboard write panicked: %v", r)
+			}
+		}()
+		clipboard.Write(clipboard.FmtImage, data)
+		return nil
+	}
+
+	if ok, err := ready(); !ok {
+		return err
+	}
+	script := fmt.Sprintf("set the clipboard to {«class HTML»:«data HTML%X», «class utf8»:«data utf8%X»}", data, []byte(c.Text))
+	cmd := exec.Command("osascript", "-")
+	cmd.Stdin = bytes.NewReader([]byte(script))
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("osascript failed: %w: %s", err, bytes.TrimSpace(output))
+	}
+	return nil
+}
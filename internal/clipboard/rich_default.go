@@ -0,0 +1,35 @@
+//go:build !darwin && !windows
+
+package clipboard
+
+import (
+	"bytes"
+	"os/exec"
+)
+
+// WriteRich writes content to the clipboard in its richest format. xclip
+// and wl-copy hold one format at a time, so the image or HTML takes the place
+// of the text; xsel can't type its data, so with it only the text is copied.
+func WriteRich(c Content) error {
+	mime, data := c.richest()
+	if mime == MIMEText {
+		return Write(c.Text)
+	}
+	if ok, err := ready(); !ok {
+		return err
+	}
+
+	if _, err := exec.LookPath("xclip"); err == nil {
+		cmd := exec.Command("xclip", "-selection", "clipboard", "-t", mime)
+		cmd.Stdin = bytes.NewReader(data)
+		return cmd.Run()
+	}
+
+	if _, err := exec.LookPath("wl-copy"); err == nil {
+		cmd := exec.Command("wl-copy", "--type", mime)
+		cmd.Stdin = bytes.NewReader(data)
+		return cmd.Run()
+	}
+
+	return Write(c.Text)
+}
@@ -0,0 +1,43 @@
+package clipboard
+
+import (
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestRichest(t *testing.T) {
+	tests := []struct {
+		content Content
+		mime    string
+	}{
+		{Content{Text: "a", HTML: "<b>a</b>", PNG: []byte{1}}, MIMEPNG},
+		{Content{Text: "a", HTML: "<b>a</b>"}, MIMEHTML},
+		{Content{Text: "a"}, MIMEText},
+	}
+	for _, tt := range tests {
+		if mime, _ := tt.content.richest(); mime != tt.mime {
+			t.Errorf("richest(%+v) = %s, want %s", tt.content, mime, tt.mime)
+		}
+	}
+}
+
+func TestCFHTML(t *testing.T) {
+	fragment := "<svg>é</svg>"
+	out := string(cfHTML(fragment))
+
+	offset := func(name string) int {
+		match := regexp.MustCompile(name + `:(\d{10})\r\n`).FindStringSubmatch(out)
+		if match == nil {
+			t.Fatalf("no %s in %q", name, out)
+		}
+		n, _ := strconv.Atoi(match[1])
+		return n
+	}
+	if got := out[offset("StartFragment"):offset("EndFragment")]; got != fragment {
+		t.Errorf("fragment = %q, want %q", got, fragment)
+	}
+	if got := out[offset("StartHTML"):offset("EndHTML")]; got[:6] != "<html>" || offset("EndHTML") != len(out) {
+		t.Errorf("document = %q, want the whole <html> after the header", got)
+	}
+}
@@ -0,0 +1,69 @@
+//go:build windows
+
+package clipboard
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	clipboard "golang.design/x/clipboard"
+)
+
+// setHTMLScript puts the HTML Format and text files named by its
+// environment on the clipboard together
+const setHTMLScript = `Add-Type -AssemblyName System.Windows.Forms
+$d = New-Object System.Windows.Forms.DataObject
+$html = [System.IO.File]::ReadAllBytes($env:SHANNON_CLIPBOARD_HTML)
+$d.SetData('HTML Format', (New-Object System.IO.MemoryStream(,$html)))
+$d.SetText([System.IO.File]::ReadAllText($env:SHANNON_CLIPBOARD_TEXT, [System.Text.Encoding]::UTF8))
+[System.Windows.Forms.Clipboard]::SetDataObject($d, $true)`
+
+// WriteRich writes content to the clipboard: an image natively, HTML along
+// with its text through PowerShell, so that plain text editors still paste
+// the text
+func WriteRich(c Content) (err error) {
+	mime, data := c.richest()
+	switch mime {
+	case MIMEText:
+		return Write(c.Text)
+	case MIMEPNG:
+		if ok, err := ready(); !ok {
+			return err
+		}
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("clipboard write panicked: %v", r)
+			}
+		}()
+		clipboard.Write(clipboard.FmtImage, data)
+		return nil
+	}
+
+	if ok, err := ready(); !ok {
+		return err
+	}
+	dir, err := os.MkdirTemp("", "shannon-clipboard-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	htmlFile := filepath.Join(dir, "clipboard.html")
+	textFile := filepath.Join(dir, "clipboard.txt")
+	if err := os.WriteFile(htmlFile, cfHTML(string(data)), 0600); err != nil {
+		return fmt.Errorf("failed to write HTML: %w", err)
+	}
+	if err := os.WriteFile(textFile, []byte(c.Text), 0600); err != nil {
+		return fmt.Errorf("failed to write text: %w", err)
+	}
+
+	cmd := exec.Command("powershell", "-NoProfile", "-STA", "-Command", setHTMLScript)
+	cmd.Env = append(os.Environ(), "SHANNON_CLIPBOARD_HTML="+htmlFile, "SHANNON_CLIPBOARD_TEXT="+textFile)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("powershell failed: %w: %s", err, bytes.TrimSpace(output))
+	}
+	return nil
+}
@@ -0,0 +1,354 @@
+// Package cluster groups documents by topic with TF-IDF vectors and
+// spherical k-means, labelling each group with its most distinctive terms.
+package cluster
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// minTermLength drops short words, which are mostly noise
+	minTermLength = 3
+	// maxVocabulary is how many terms, by document frequency, vectors use
+	maxVocabulary = 5000
+	// maxIterations bounds k-means when assignments keep changing
+	maxIterations = 50
+)
+
+// titleWeight is how many times a title counts, since it usually names the
+// topic
+const titleWeight = 3
+
+// stopwords are common English words that say nothing about a topic
+var stopwords = toSet(strings.Fields(`
+	about above after again against all also and any are aren't because been before being
+	below between both but can can't cannot could couldn't did didn't does doesn't doing don't
+	down during each few for from further get got had hadn't has hasn't have haven't having
+	here how i'd i'll i'm i've into isn't it's its itself just let's like make more most much
+	must mustn't myself need not now off once only other ought our ours ourselves out over own
+	please same shan't she should shouldn't some such than thank thanks that that's the their
+	theirs them themselves then there there's these they they'd they'll they're they've this
+	those through too under until use used using very want was wasn't way we'd we'll we're
+	we've were weren't what what's when when's where where's which while who who's whom why
+	why's will with won't would wouldn't yes you you'd you'll you're you've your yours yourself
+	yourselves one two also something anything everything work works working help know think
+	see look going well still even really new example sure right okay
+`))
+
+// Builder collects documents to cluster
+type Builder struct {
+	stopwords map[string]bool
+	ids       []int64
+	counts    []map[string]int // term counts by document
+	df        map[string]int   // documents containing each term
+}
+
+// NewBuilder creates a builder that also ignores extra stopwords
+func NewBuilder(extraStopwords ...string) *Builder {
+	b := &Builder{stopwords: make(map[string]bool), df: make(map[string]int)}
+	for word := range stopwords {
+		b.stopwords[word] = true
+	}
+	for _, word := range extraStopwords {
+		b.stopwords[strings.ToLower(word)] = true
+	}
+	return b
+}
+
+// Add adds a document with its title and text
+func (b *Builder) Add(id int64, title, text string) {
+	counts := make(map[string]int)
+	for i := 0; i < titleWeight; i++ {
+		b.count(counts, title)
+	}
+	b.count(counts, text)
+
+	for term := range counts {
+		b.df[term]++
+	}
+	b.ids = append(b.ids, id)
+	b.counts = append(b.counts, counts)
+}
+
+// count adds the terms of text to counts
+func (b *Builder) count(counts map[string]int, text string) {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+	for _, word := range words {
+		word = strings.Trim(word, "'")
+		if len([]rune(word)) < minTermLength || b.stopwords[word] || isNumber(word) {
+			continue
+		}
+		counts[word]++
+	}
+}
+
+// Cluster is a group of documents about the same topic
+type Cluster struct {
+	Terms   []string // most distinctive terms, best first
+	Members []Member // closest to the topic first
+}
+
+// Member is a document in a cluster
+type Member struct {
+	ID         int64
+	Similarity float64 // cosine similarity to the cluster's centroid
+}
+
+// Result is the outcome of clustering
+type Result struct {
+	Clusters    []*Cluster // largest first
+	Unclustered []int64    // documents without any usable terms
+}
+
+// Cluster groups the documents into at most k clusters, labelled with up to
+// terms terms each. The same seed gives the same clusters for the same
+// documents.
+func (b *Builder) Cluster(k, terms int, seed int64) *Result {
+	vocabulary := b.vocabulary()
+	index := make(map[string]int, len(vocabulary))
+	for i, term := range vocabulary {
+		index[term] = i
+	}
+
+	// TF-IDF vectors with sublinear term frequency, normalized to unit length
+	n := float64(len(b.ids))
+	result := &Result{}
+	var vectors []sparseVector
+	var ids []int64
+	for d, counts := range b.counts {
+		var v sparseVector
+		for term, count := range counts {
+			if i, ok := index[term]; ok {
+				idf := math.Log(n / float64(b.df[term]))
+				v = append(v, entry{i, (1 + math.Log(float64(count))) * idf})
+			}
+		}
+		if !v.normalize() {
+			result.Unclustered = append(result.Unclustered, b.ids[d])
+			continue
+		}
+		sort.Slice(v, func(i, j int) bool { return v[i].index < v[j].index })
+		vectors = append(vectors, v)
+		ids = append(ids, b.ids[d])
+	}
+	if len(vectors) == 0 || k < 1 {
+		return result
+	}
+	k = min(k, len(vectors))
+
+	rng := rand.New(rand.NewSource(seed))
+	centroids := initCentroids(vectors, k, len(vocabulary), rng)
+	assignment := make([]int, len(vectors))
+	similarity := make([]float64, len(vectors))
+	for iteration := 0; iteration < maxIterations; iteration++ {
+		changed := false
+		for d, v := range vectors {
+			best, bestSim := 0, math.Inf(-1)
+			for c, centroid := range centroids {
+				if sim := v.dot(centroid); sim > bestSim {
+					best, bestSim = c, sim
+				}
+			}
+			if iteration == 0 || assignment[d] != best {
+				changed = true
+			}
+			assignment[d], similarity[d] = best, bestSim
+		}
+		if !changed {
+			break
+		}
+		centroids = updateCentroids(vectors, assignment, centroids)
+	}
+
+	clusters := make([]*Cluster, len(centroids))
+	for c := range clusters {
+		clusters[c] = &Cluster{Terms: topTerms(centroids[c], vocabulary, terms)}
+	}
+	for d := range vectors {
+		c := clusters[assignment[d]]
+		c.Members = append(c.Members, Member{ID: ids[d], Similarity: similarity[d]})
+	}
+	for _, c := range clusters {
+		if len(c.Members) == 0 {
+			continue
+		}
+		sort.SliceStable(c.Members, func(i, j int) bool { return c.Members[i].Similarity > c.Members[j].Similarity })
+		result.Clusters = append(result.Clusters, c)
+	}
+	sort.SliceStable(result.Clusters, func(i, j int) bool {
+		return len(result.Clusters[i].Members) > len(result.Clusters[j].Members)
+	})
+	return result
+}
+
+// vocabulary returns the terms used for vectors: those in more than one
+// document but not in most of them, the most frequent first
+func (b *Builder) vocabulary() []string {
+	maxDF := len(b.ids) / 2
+	if len(b.ids) < 4 {
+		maxDF = len(b.ids)
+	}
+	var vocabulary []string
+	for term, df := range b.df {
+		if df >= 2 && df <= maxDF {
+			vocabulary = append(vocabulary, term)
+		}
+	}
+	sort.Slice(vocabulary, func(i, j int) bool {
+		if b.df[vocabulary[i]] != b.df[vocabulary[j]] {
+			return b.df[vocabulary[i]] > b.df[vocabulary[j]]
+		}
+		return vocabulary[i] < vocabulary[j]
+	})
+	if len(vocabulary) > maxVocabulary {
+		vocabulary = vocabulary[:maxVocabulary]
+	}
+	return vocabulary
+}
+
+// initCentroids picks k starting centroids with k-means++: each one is a
+// document picked with probability growing with its distance from the
+// centroids picked before
+func initCentroids(vectors []sparseVector, k, dims int, rng *rand.Rand) [][]float64 {
+	centroids := [][]float64{vectors[rng.Intn(len(vectors))].dense(dims)}
+	distance := make([]float64, len(vectors))
+	for i := range distance {
+		distance[i] = math.Inf(1)
+	}
+	for len(centroids) < k {
+		last := centroids[len(centroids)-1]
+		total := 0.0
+		for i, v := range vectors {
+			distance[i] = math.Min(distance[i], 1-v.dot(last))
+			total += distance[i]
+		}
+		if total <= 0 {
+			break // every document is identical to a centroid
+		}
+		target := rng.Float64() * total
+		pick := len(vectors) - 1
+		for i, d := range distance {
+			if target -= d; target <= 0 {
+				pick = i
+				break
+			}
+		}
+		centroids = append(centroids, vectors[pick].dense(dims))
+	}
+	return centroids
+}
+
+// updateCentroids moves each centroid to the normalized mean of its
+// documents, keeping the old one for a cluster that lost all of them
+func updateCentroids(vectors []sparseVector, assignment []int, old [][]float64) [][]float64 {
+	centroids := make([][]float64, len(old))
+	for c := range centroids {
+		centroids[c] = make([]float64, len(old[c]))
+	}
+	sizes := make([]int, len(old))
+	for d, v := range vectors {
+		c := assignment[d]
+		sizes[c]++
+		for _, e := range v {
+			centroids[c][e.index] += e.weight
+		}
+	}
+	for c, centroid := range centroids {
+		if sizes[c] == 0 {
+			centroids[c] = old[c]
+			continue
+		}
+		norm := 0.0
+		for _, w := range centroid {
+			norm += w * w
+		}
+		if norm = math.Sqrt(norm); norm > 0 {
+			for i := range centroid {
+				centroid[i] /= norm
+			}
+		}
+	}
+	return centroids
+}
+
+// topTerms returns the n terms with the largest weights in a centroid
+func topTerms(centroid []float64, vocabulary []string, n int) []string {
+	order := make([]int, len(centroid))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return centroid[order[i]] > centroid[order[j]] })
+
+	var terms []string
+	for _, i := range order {
+		if len(terms) == n || centroid[i] <= 0 {
+			break
+		}
+		terms = append(terms, vocabulary[i])
+	}
+	return terms
+}
+
+// entry is a non-zero component of a sparse vector
+type entry struct {
+	index  int
+	weight float64
+}
+
+type sparseVector []entry
+
+// normalize scales the vector to unit length, reporting false for an empty
+// vector
+func (v sparseVector) normalize() bool {
+	norm := 0.0
+	for _, e := range v {
+		norm += e.weight * e.weight
+	}
+	if norm == 0 {
+		return false
+	}
+	norm = math.Sqrt(norm)
+	for i := range v {
+		v[i].weight /= norm
+	}
+	return true
+}
+
+func (v sparseVector) dot(dense []float64) float64 {
+	sum := 0.0
+	for _, e := range v {
+		sum += e.weight * dense[e.index]
+	}
+	return sum
+}
+
+func (v sparseVector) dense(dims int) []float64 {
+	d := make([]float64, dims)
+	for _, e := range v {
+		d[e.index] = e.weight
+	}
+	return d
+}
+
+func isNumber(word string) bool {
+	for _, r := range word {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func toSet(words []string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, word := range words {
+		set[word] = true
+	}
+	return set
+}
@@ -0,0 +1,66 @@
+package cluster
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestCluster(t *testing.T) {
+	topics := map[string][]string{
+		"python": {"pandas dataframe", "pandas csv dataframe", "dataframe groupby pandas", "pandas merge dataframe"},
+		"docker": {"docker container image", "container compose docker", "docker image registry", "container volume docker"},
+		"baking": {"sourdough bread starter", "bread flour sourdough", "starter flour bread", "sourdough bread oven"},
+	}
+	b := NewBuilder("ignored")
+	topicOf := make(map[int64]string)
+	id := int64(0)
+	for _, topic := range []string{"python", "docker", "baking"} {
+		for _, text := range topics[topic] {
+			id++
+			topicOf[id] = topic
+			b.Add(id, fmt.Sprintf("Question %d", id), text+" and some ignored words")
+		}
+	}
+	b.Add(100, "Hi", "ok")
+
+	result := b.Cluster(3, 2, 1)
+	if !reflect.DeepEqual(result.Unclustered, []int64{100}) {
+		t.Errorf("expected the document without terms to be unclustered, got %v", result.Unclustered)
+	}
+	if len(result.Clusters) != 3 {
+		t.Fatalf("expected 3 clusters, got %d", len(result.Clusters))
+	}
+	labels := map[string][]string{"python": {"dataframe", "pandas"}, "docker": {"container", "docker"}, "baking": {"bread", "sourdough"}}
+	for _, c := range result.Clusters {
+		topic := topicOf[c.Members[0].ID]
+		if len(c.Members) != 4 {
+			t.Errorf("expected 4 members in the %s cluster, got %d", topic, len(c.Members))
+		}
+		for _, m := range c.Members {
+			if topicOf[m.ID] != topic {
+				t.Errorf("document %d about %s is in the %s cluster", m.ID, topicOf[m.ID], topic)
+			}
+		}
+		if len(c.Terms) != 2 || !contains(labels[topic], c.Terms[0]) || !contains(labels[topic], c.Terms[1]) {
+			t.Errorf("expected the %s cluster to be labelled %v, got %v", topic, labels[topic], c.Terms)
+		}
+	}
+
+	// Clustering is repeatable
+	if again := b.Cluster(3, 2, 1); !reflect.DeepEqual(again, result) {
+		t.Error("expected the same seed to give the same clusters")
+	}
+	if more := b.Cluster(50, 2, 1); len(more.Clusters) > 12 {
+		t.Errorf("expected at most one cluster per document, got %d", len(more.Clusters))
+	}
+}
+
+func contains(terms []string, term string) bool {
+	for _, t := range terms {
+		if t == term {
+			return true
+		}
+	}
+	return false
+}
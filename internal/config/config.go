@@ -11,24 +11,92 @@ import (
 type Config struct {
 	Database struct {
 		Path string `mapstructure:"path"`
+		// BusyTimeoutMs is how long to wait for another shannon process to
+		// release the database before failing
+		BusyTimeoutMs int `mapstructure:"busy_timeout_ms"`
+		// Driver is where the archive is kept: "sqlite" for the database at
+		// Path, or "postgres" for one shared by several machines at URL
+		Driver string `mapstructure:"driver"`
+		// URL is the connection URL of the postgres archive
+		URL string `mapstructure:"url"`
 	} `mapstructure:"database"`
 
 	Search struct {
 		MaxResults    int  `mapstructure:"max_results"`
 		ShowSnippets  bool `mapstructure:"show_snippets"`
 		SnippetLength int  `mapstructure:"snippet_length"`
+		// Rank is the default ranking mode: relevance, recency or hybrid
+		Rank string `mapstructure:"rank"`
+		// Stopwords are dropped from multi-word queries
+		Stopwords []string `mapstructure:"stopwords"`
+		// Synonyms are groups of interchangeable terms, such as
+		// [js, javascript]; searching for one finds any of its group
+		Synonyms [][]string `mapstructure:"synonyms"`
 	} `mapstructure:"search"`
 
 	UI struct {
 		Theme          string `mapstructure:"theme"`
 		PageSize       int    `mapstructure:"page_size"`
 		HighlightColor string `mapstructure:"highlight_color"`
+		// SearchDebounceMs is how long typing in the TUI query bar must pause
+		// before the conversation list is filtered
+		SearchDebounceMs int `mapstructure:"search_debounce_ms"`
+		// ArtifactPreviewLines is how many lines collapsed artifacts show
+		ArtifactPreviewLines int `mapstructure:"artifact_preview_lines"`
+		// ArtifactWrap wraps long artifact lines instead of cutting them off
+		ArtifactWrap bool `mapstructure:"artifact_wrap"`
+		// CodeCollapseLines is the longest code block the TUI leaves alone
+		// when collapsing long code
+		CodeCollapseLines int `mapstructure:"code_collapse_lines"`
+		// SenderLabels name the two sides of a conversation in views and
+		// exports
+		SenderLabels struct {
+			Human     string `mapstructure:"human"`
+			Assistant string `mapstructure:"assistant"`
+		} `mapstructure:"sender_labels"`
+		// Browser is the command links and artifacts are opened with;
+		// empty uses the system's default browser
+		Browser string `mapstructure:"browser"`
+		// Language is the language of tables, notices and help, as a code
+		// like "de"; empty follows LANG and the other locale variables
+		Language string `mapstructure:"language"`
 	} `mapstructure:"ui"`
 
 	Import struct {
 		BatchSize int  `mapstructure:"batch_size"`
 		Verbose   bool `mapstructure:"verbose"`
 	} `mapstructure:"import"`
+
+	Trash struct {
+		// RetentionDays is how long deleted conversations stay in the trash
+		// before they are purged for good; 0 keeps them until emptied
+		RetentionDays int `mapstructure:"retention_days"`
+	} `mapstructure:"trash"`
+
+	Crash struct {
+		// Reports writes a report to the crashes directory of the data
+		// directory when shannon crashes; nothing is sent anywhere
+		Reports bool `mapstructure:"reports"`
+	} `mapstructure:"crash"`
+
+	// Export holds the credentials for publishing conversations to wikis and
+	// gists
+	Export struct {
+		Notion struct {
+			Token  string `mapstructure:"token"`
+			Parent string `mapstructure:"parent"`
+		} `mapstructure:"notion"`
+		Confluence struct {
+			URL    string `mapstructure:"url"`
+			User   string `mapstructure:"user"`
+			Token  string `mapstructure:"token"`
+			Space  string `mapstructure:"space"`
+			Parent string `mapstructure:"parent"`
+		} `mapstructure:"confluence"`
+		Gist struct {
+			Token string `mapstructure:"token"`
+		} `mapstructure:"gist"`
+	} `mapstructure:"export"`
 }
 
 var (
@@ -44,10 +112,13 @@ func Init() error {
 	}
 	dirs = appDirs
 
-	// Set up Viper
-	viper.SetConfigName("config")
+	// Set up Viper. A config file named with --config is read instead of
+	// the one in the config directory; naming the default would drop it.
+	if viper.ConfigFileUsed() == "" {
+		viper.SetConfigName("config")
+		viper.AddConfigPath(dirs.Config)
+	}
 	viper.SetConfigType("yaml")
-	viper.AddConfigPath(dirs.Config)
 
 	// Set defaults
 	setDefaults()
@@ -77,20 +148,38 @@ func Init() error {
 func setDefaults() {
 	// Database defaults
 	viper.SetDefault("database.path", "")
+	viper.SetDefault("database.busy_timeout_ms", 5000)
+	viper.SetDefault("database.driver", "sqlite")
+	viper.SetDefault("database.url", "")
 
 	// Search defaults
 	viper.SetDefault("search.max_results", 50)
 	viper.SetDefault("search.show_snippets", true)
 	viper.SetDefault("search.snippet_length", 200)
+	viper.SetDefault("search.rank", "relevance")
 
 	// UI defaults
 	viper.SetDefault("ui.theme", "dark")
 	viper.SetDefault("ui.page_size", 20)
 	viper.SetDefault("ui.highlight_color", "yellow")
+	viper.SetDefault("ui.search_debounce_ms", 300)
+	viper.SetDefault("ui.artifact_preview_lines", 10)
+	viper.SetDefault("ui.artifact_wrap", true)
+	viper.SetDefault("ui.code_collapse_lines", 20)
+	viper.SetDefault("ui.sender_labels.human", "You")
+	viper.SetDefault("ui.sender_labels.assistant", "Claude")
+	viper.SetDefault("ui.browser", "")
+	viper.SetDefault("ui.language", "")
 
 	// Import defaults
 	viper.SetDefault("import.batch_size", 1000)
 	viper.SetDefault("import.verbose", false)
+
+	// Trash defaults
+	viper.SetDefault("trash.retention_days", 30)
+
+	// Crash report defaults
+	viper.SetDefault("crash.reports", true)
 }
 
 func Get() *Config {
@@ -100,6 +189,12 @@ func Get() *Config {
 	return cfg
 }
 
+// File returns the config file that was read, or "" if none was found and
+// the defaults are in use
+func File() string {
+	return viper.ConfigFileUsed()
+}
+
 func GetDirs() *platform.Dirs {
 	if dirs == nil {
 		panic("config not initialized")
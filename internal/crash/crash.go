@@ -0,0 +1,206 @@
+// Package crash writes a report to a local file when shannon panics, with
+// the stack, the command, versions and the last operations, for attaching
+// to a bug report. Nothing is ever sent anywhere.
+package crash
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IssuesURL is where crash reports are wanted
+const IssuesURL = "https://github.com/neilberkman/shannon/issues"
+
+// maxOperations is how many of the last operations a report lists
+const maxOperations = 25
+
+// operation is something shannon did, kept for the next crash report
+type operation struct {
+	at   time.Time
+	what string
+}
+
+// detail is a fact about the setup, such as the schema version
+type detail struct {
+	name, value string
+}
+
+var (
+	mu         sync.Mutex
+	enabled    = true
+	dir        string // "" for the temporary directory
+	version    = "dev"
+	commit     = "none"
+	date       = "unknown"
+	details    []detail
+	operations []operation
+	last       string // the last report written
+)
+
+// SetVersion sets the build the reports are of
+func SetVersion(v, c, d string) {
+	mu.Lock()
+	defer mu.Unlock()
+	version, commit, date = v, c, d
+}
+
+// SetDir sets the directory reports are written to, created when needed
+func SetDir(d string) {
+	mu.Lock()
+	defer mu.Unlock()
+	dir = d
+}
+
+// SetEnabled turns reports on or off. Without them a panic prints Go's
+// usual trace.
+func SetEnabled(on bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	enabled = on
+}
+
+// AddDetail adds a fact about the setup to reports, replacing an earlier
+// value of the same name
+func AddDetail(name, value string) {
+	mu.Lock()
+	defer mu.Unlock()
+	for i := range details {
+		if details[i].name == name {
+			details[i].value = value
+			return
+		}
+	}
+	details = append(details, detail{name, value})
+}
+
+// Note records an operation for the next report, forgetting the oldest
+// beyond the last few
+func Note(format string, args ...interface{}) {
+	mu.Lock()
+	defer mu.Unlock()
+	operations = append(operations, operation{time.Now(), fmt.Sprintf(format, args...)})
+	if len(operations) > maxOperations {
+		operations = operations[len(operations)-maxOperations:]
+	}
+}
+
+// LastReport returns the path of the last report written, or "" if none was
+func LastReport() string {
+	mu.Lock()
+	defer mu.Unlock()
+	return last
+}
+
+// Handle is deferred by main: it writes a report of a panic, tells where to
+// find it and exits. With reports off, the panic goes on as usual.
+func Handle() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	stack := debug.Stack()
+	if !isEnabled() {
+		panic(r)
+	}
+	path, err := Write(r, stack)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "panic: %v\n\n%s\nFailed to write a crash report: %v\n", r, stack, err)
+		os.Exit(2)
+	}
+	fmt.Fprintf(os.Stderr, "shannon crashed: %v\n", r)
+	fmt.Fprintln(os.Stderr, Message(path))
+	os.Exit(2)
+}
+
+// Guard is deferred by code that recovers from panics itself, such as the
+// TUI, which restores the terminal: it writes a report of a panic and
+// panics again
+func Guard() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	if isEnabled() {
+		if _, err := Write(r, debug.Stack()); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write a crash report: %v\n", err)
+		}
+	}
+	panic(r)
+}
+
+// Message tells where a report was written and what to do with it
+func Message(path string) string {
+	return fmt.Sprintf("A crash report is in %s\nPlease check it for anything private and attach it to an issue at %s", path, IssuesURL)
+}
+
+func isEnabled() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return enabled
+}
+
+// Write writes a report of a panic with its stack and returns its path
+func Write(r interface{}, stack []byte) (string, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	reportDir := dir
+	if reportDir == "" {
+		reportDir = filepath.Join(os.TempDir(), "shannon-crashes")
+	}
+	if err := os.MkdirAll(reportDir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", reportDir, err)
+	}
+	now := time.Now()
+	f, err := os.CreateTemp(reportDir, "crash-"+now.Format("20060102-150405")+"-*.txt")
+	if err != nil {
+		return "", fmt.Errorf("failed to create crash report: %w", err)
+	}
+	if _, err := f.WriteString(report(now, r, stack)); err != nil {
+		_ = f.Close()
+		return "", fmt.Errorf("failed to write crash report: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("failed to write crash report: %w", err)
+	}
+	last = f.Name()
+	return last, nil
+}
+
+// report formats a crash report; mu is held
+func report(now time.Time, r interface{}, stack []byte) string {
+	var sb strings.Builder
+	sb.WriteString("shannon crash report\n\n")
+	fmt.Fprintf(&sb, "Time:     %s\n", now.Format(time.RFC3339))
+	fmt.Fprintf(&sb, "Version:  %s (commit %s, built %s)\n", version, commit, date)
+	fmt.Fprintf(&sb, "Go:       %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(&sb, "Command:  %s\n", strings.Join(os.Args, " "))
+	if term := os.Getenv("TERM"); term != "" {
+		fmt.Fprintf(&sb, "Terminal: %s\n", term)
+	}
+	for _, d := range details {
+		fmt.Fprintf(&sb, "%-9s %s\n", d.name+":", d.value)
+	}
+	fmt.Fprintf(&sb, "\nPanic: %v\n", r)
+
+	sb.WriteString("\nLast operations:\n")
+	if len(operations) == 0 {
+		sb.WriteString("  none recorded\n")
+	}
+	for _, op := range operations {
+		fmt.Fprintf(&sb, "  %s  %s\n", op.at.Format("15:04:05.000"), op.what)
+	}
+
+	sb.WriteString("\nStack:\n")
+	sb.Write(stack)
+	if len(stack) > 0 && stack[len(stack)-1] != '\n' {
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
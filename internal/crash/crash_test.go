@@ -0,0 +1,74 @@
+package crash
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	SetDir(dir)
+	defer SetDir("")
+	SetVersion("1.2.3", "abc123", "2026-01-02")
+	AddDetail("Schema", "19")
+	AddDetail("Schema", "20")
+	for i := 0; i < maxOperations+5; i++ {
+		Note("operation %d", i)
+	}
+
+	path, err := Write("boom", []byte("goroutine 1 [running]:\nmain.main()"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(path) != dir || LastReport() != path {
+		t.Errorf("report written to %s, want a file in %s", path, dir)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := string(content)
+
+	for _, want := range []string{
+		"Version:  1.2.3 (commit abc123, built 2026-01-02)",
+		"Schema:   20\n",
+		"Panic: boom",
+		"operation 5\n",
+		fmt.Sprintf("operation %d\n", maxOperations+4),
+		"Stack:\ngoroutine 1 [running]:\nmain.main()\n",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report doesn't contain %q:\n%s", want, report)
+		}
+	}
+	// Only the last operations are kept
+	if strings.Contains(report, "operation 4\n") {
+		t.Errorf("report keeps operations beyond the last %d:\n%s", maxOperations, report)
+	}
+	if strings.Contains(report, "Schema:   19") {
+		t.Error("a replaced detail is still in the report")
+	}
+}
+
+func TestGuard(t *testing.T) {
+	dir := t.TempDir()
+	SetDir(dir)
+	defer SetDir("")
+
+	defer func() {
+		if r := recover(); r != "guarded" {
+			t.Errorf("recovered %v, want the panic to go on", r)
+		}
+		matches, _ := filepath.Glob(filepath.Join(dir, "crash-*.txt"))
+		if len(matches) != 1 {
+			t.Errorf("expected one crash report, found %v", matches)
+		}
+	}()
+	func() {
+		defer Guard()
+		panic("guarded")
+	}()
+}
@@ -0,0 +1,146 @@
+// Package dates parses the dates and relative times accepted by date flags
+// such as --after, --before and --since, so every command reads them alike.
+package dates
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	agePattern     = regexp.MustCompile(`^(\d+)\s*([hdwmy])$`)
+	periodPattern  = regexp.MustCompile(`^@(\d{4})(?:-(\d{1,2}))?$`)
+	slashedPattern = regexp.MustCompile(`^(\d{1,2})/(\d{1,2})/(\d{4})$`)
+)
+
+// timestampLayouts are parsed as instants
+var timestampLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+}
+
+// dateLayouts are parsed as the start of the day. Dotted dates are day first,
+// as written across most of Europe.
+var dateLayouts = []string{
+	"2006-01-02",
+	"2006/1/2",
+	"2006.1.2",
+	"2.1.2006",
+	"2 Jan 2006",
+	"2 January 2006",
+	"Jan 2 2006",
+	"January 2 2006",
+	"Jan 2, 2006",
+	"January 2, 2006",
+	"2-Jan-2006",
+}
+
+// Parse returns the time s names, in the local time zone unless it says
+// otherwise. It accepts
+//
+//   - a timestamp: RFC 3339 or 2024-06-01 14:30
+//   - a date: 2024-06-01, 2024/06/01, 01.06.2024, 1 Jun 2024 or Jun 1, 2024;
+//     01/06/2024 only when the day can't be mistaken for the month
+//   - a year or month: @2024, @2024-06
+//   - today or yesterday
+//   - an age before now: 36h, 30d, 2w, 3m or 1y
+//
+// Dates, years and months stand for their start, so "--after @2024" means
+// from the first of January and "--before 2024-06-01" means up to that day.
+func Parse(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	lower := strings.ToLower(s)
+
+	switch lower {
+	case "today":
+		return startOfDay(now), nil
+	case "yesterday":
+		return startOfDay(now).AddDate(0, 0, -1), nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range timestampLayouts {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+
+	if match := slashedPattern.FindStringSubmatch(s); match != nil {
+		return parseSlashed(s, match, now.Location())
+	}
+
+	if match := periodPattern.FindStringSubmatch(s); match != nil {
+		year, _ := strconv.Atoi(match[1])
+		month := 1
+		if match[2] != "" {
+			month, _ = strconv.Atoi(match[2])
+			if month < 1 || month > 12 {
+				return time.Time{}, fmt.Errorf("invalid month in %q", s)
+			}
+		}
+		return time.Date(year, time.Month(month), 1, 0, 0, 0, 0, now.Location()), nil
+	}
+
+	if match := agePattern.FindStringSubmatch(lower); match != nil {
+		n, _ := strconv.Atoi(match[1])
+		switch match[2] {
+		case "h":
+			return now.Add(-time.Duration(n) * time.Hour), nil
+		case "d":
+			return now.AddDate(0, 0, -n), nil
+		case "w":
+			return now.AddDate(0, 0, -7*n), nil
+		case "m":
+			return now.AddDate(0, -n, 0), nil
+		default:
+			return now.AddDate(-n, 0, 0), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognized date %q: use a date (2024-06-01, 01.06.2024, 1 Jun 2024), @2024, @2024-06, today, yesterday or an age (36h, 30d, 2w, 3m, 1y)", s)
+}
+
+// parseSlashed reads 01/06/2024 as day first or month first when only one of
+// them makes a valid date
+func parseSlashed(s string, match []string, loc *time.Location) (time.Time, error) {
+	a, _ := strconv.Atoi(match[1])
+	b, _ := strconv.Atoi(match[2])
+	year, _ := strconv.Atoi(match[3])
+
+	dayFirst := validDate(year, b, a)
+	monthFirst := validDate(year, a, b)
+	switch {
+	case dayFirst && monthFirst && a != b:
+		return time.Time{}, fmt.Errorf("ambiguous date %q: it could be day or month first, use %04d-%02d-%02d or %04d-%02d-%02d", s, year, b, a, year, a, b)
+	case monthFirst:
+		return time.Date(year, time.Month(a), b, 0, 0, 0, 0, loc), nil
+	case dayFirst:
+		return time.Date(year, time.Month(b), a, 0, 0, 0, 0, loc), nil
+	default:
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+}
+
+// validDate reports whether the day exists in the month
+func validDate(year, month, day int) bool {
+	if month < 1 || month > 12 || day < 1 {
+		return false
+	}
+	return day <= time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
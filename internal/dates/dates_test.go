@@ -0,0 +1,96 @@
+package dates
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	now := time.Date(2024, 6, 15, 14, 30, 0, 0, time.UTC)
+	day := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"2024-03-05", day(2024, 3, 5)},
+		{"2024/03/05", day(2024, 3, 5)},
+		{"2024.3.5", day(2024, 3, 5)},
+		{"05.03.2024", day(2024, 3, 5)},
+		{"5 Mar 2024", day(2024, 3, 5)},
+		{"5 march 2024", day(2024, 3, 5)},
+		{"March 5, 2024", day(2024, 3, 5)},
+		{"25/03/2024", day(2024, 3, 25)},
+		{"03/25/2024", day(2024, 3, 25)},
+		{"03/03/2024", day(2024, 3, 3)},
+		{"2024-03-05 09:15", time.Date(2024, 3, 5, 9, 15, 0, 0, time.UTC)},
+		{"2024-03-05T09:15:00+02:00", time.Date(2024, 3, 5, 7, 15, 0, 0, time.UTC)},
+		{"@2023", day(2023, 1, 1)},
+		{"@2023-11", day(2023, 11, 1)},
+		{"today", day(2024, 6, 15)},
+		{"Yesterday", day(2024, 6, 14)},
+		{"36h", now.Add(-36 * time.Hour)},
+		{"30d", day(2024, 5, 16).Add(14*time.Hour + 30*time.Minute)},
+		{"2w", now.AddDate(0, 0, -14)},
+		{"3m", now.AddDate(0, -3, 0)},
+		{"1y", now.AddDate(-1, 0, 0)},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in, now)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("Parse(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "soon", "05/03/2024", "31/31/2024", "@2024-13", "2024-02-30", "10x"} {
+		if got, err := Parse(in, now); err == nil {
+			t.Errorf("Parse(%q) = %v, want an error", in, got)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"90s", 90 * time.Second},
+		{"45m", 45 * time.Minute},
+		{"2H", 2 * time.Hour},
+		{"2h30m", 2*time.Hour + 30*time.Minute},
+		{"3d", 72 * time.Hour},
+		{"1w 2d", 9 * 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		got, err := ParseDuration(tt.in)
+		if err != nil {
+			t.Errorf("ParseDuration(%q): %v", tt.in, err)
+		} else if got != tt.want {
+			t.Errorf("ParseDuration(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+	for _, in := range []string{"", "2", "2x", "h2", "2h junk"} {
+		if got, err := ParseDuration(in); err == nil {
+			t.Errorf("ParseDuration(%q) = %v, want an error", in, got)
+		}
+	}
+
+	for d, want := range map[time.Duration]string{
+		30 * time.Second:              "<1m",
+		45 * time.Minute:              "45m",
+		2 * time.Hour:                 "2h",
+		2*time.Hour + 15*time.Minute:  "2h 15m",
+		3 * 24 * time.Hour:            "3d",
+		76*time.Hour + 59*time.Minute: "3d 4h",
+	} {
+		if got := FormatDuration(d); got != want {
+			t.Errorf("FormatDuration(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
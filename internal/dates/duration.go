@@ -0,0 +1,68 @@
+package dates
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// durationPattern matches one number and unit of a duration such as 2h30m
+var durationPattern = regexp.MustCompile(`(\d+)\s*([smhdw])`)
+
+// durationUnits are the units ParseDuration accepts
+var durationUnits = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
+
+// ParseDuration reads a length of time such as 90s, 45m, 2h, 2h30m, 3d or
+// 2w. Unlike time.ParseDuration it takes days and weeks, and m is minutes.
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return 0, fmt.Errorf("empty duration")
+	}
+	var total time.Duration
+	rest := s
+	for rest != "" {
+		match := durationPattern.FindStringSubmatchIndex(rest)
+		if match == nil || match[0] != 0 {
+			return 0, fmt.Errorf("invalid duration %q (use e.g. 45m, 2h, 2h30m, 3d or 2w)", s)
+		}
+		n, err := strconv.Atoi(rest[match[2]:match[3]])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q: %w", s, err)
+		}
+		total += time.Duration(n) * durationUnits[rest[match[4]:match[5]]]
+		rest = strings.TrimSpace(rest[match[1]:])
+	}
+	return total, nil
+}
+
+// FormatDuration writes a length of time in its two largest units, such as
+// 45m, 2h 15m or 3d 4h, rounded down; under a minute is "<1m"
+func FormatDuration(d time.Duration) string {
+	if d < time.Minute {
+		return "<1m"
+	}
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+	switch {
+	case days > 0 && hours > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case days > 0:
+		return fmt.Sprintf("%dd", days)
+	case hours > 0 && minutes > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	case hours > 0:
+		return fmt.Sprintf("%dh", hours)
+	default:
+		return fmt.Sprintf("%dm", minutes)
+	}
+}
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// BusyTimeout is how long to wait for another process to release the
+// database before giving up, set from database.busy_timeout_ms
+var BusyTimeout = 5 * time.Second
+
+// BusyError reports a database that stayed locked by another process for
+// longer than BusyTimeout
+type BusyError struct {
+	Path    string
+	Holders []string // processes with the database open, where they can be found
+	Err     error
+}
+
+func (e *BusyError) Error() string {
+	msg := fmt.Sprintf("database %s is locked by another process (waited %s): %v", e.Path, BusyTimeout, e.Err)
+	if len(e.Holders) > 0 {
+		msg += fmt.Sprintf("\nIt is open in: %s", strings.Join(e.Holders, "; "))
+	} else {
+		msg += "\nAnother shannon command, such as an import or the TUI, is probably writing to it."
+	}
+	return msg + "\nTry again when it finishes, or raise database.busy_timeout_ms in the config file."
+}
+
+func (e *BusyError) Unwrap() error {
+	return e.Err
+}
+
+// isBusy reports whether err means another connection holds a lock on the
+// database
+func isBusy(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	code := sqliteErr.Code() & 0xff
+	return code == sqlite3.SQLITE_BUSY || code == sqlite3.SQLITE_LOCKED
+}
+
+// retry runs fn until it succeeds, fails with something other than a busy
+// database, or BusyTimeout has passed, backing off between attempts. SQLite's
+// own busy_timeout covers most waits; this catches the locks it gives up on
+// right away, such as a checkpoint or recovery in progress.
+func (db *DB) retry(fn func() error) error {
+	deadline := time.Now().Add(BusyTimeout)
+	wait := 10 * time.Millisecond
+	for {
+		err := fn()
+		if !isBusy(err) {
+			return err
+		}
+		if time.Now().Add(wait).After(deadline) {
+			return &BusyError{Path: db.path, Holders: lockHolders(db.path), Err: err}
+		}
+		time.Sleep(wait)
+		if wait *= 2; wait > time.Second {
+			wait = time.Second
+		}
+	}
+}
+
+// lockHolders names the other processes that have the database or its WAL
+// open, as "pid 123: shannon import export.json". It relies on /proc, so it
+// finds nothing outside Linux.
+func lockHolders(dbPath string) []string {
+	abs, err := filepath.Abs(dbPath)
+	if err != nil {
+		return nil
+	}
+	files := map[string]bool{abs: true, abs + "-wal": true, abs + "-shm": true}
+
+	fds, _ := filepath.Glob("/proc/[0-9]*/fd/*")
+	self := strconv.Itoa(os.Getpid())
+	seen := make(map[string]bool)
+	var holders []string
+	for _, fd := range fds {
+		pid := strings.Split(fd, "/")[2]
+		if pid == self || seen[pid] {
+			continue
+		}
+		if target, err := os.Readlink(fd); err != nil || !files[target] {
+			continue
+		}
+		seen[pid] = true
+
+		command := "unknown command"
+		if cmdline, err := os.ReadFile(filepath.Join("/proc", pid, "cmdline")); err == nil && len(cmdline) > 0 {
+			command = strings.Join(strings.Fields(strings.ReplaceAll(string(cmdline), "\x00", " ")), " ")
+		}
+		holders = append(holders, fmt.Sprintf("pid %s: %s", pid, command))
+	}
+	return holders
+}
@@ -0,0 +1,247 @@
+package db
+
+import (
+	"bytes"
+	"compress/flate"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"os"
+
+	"modernc.org/sqlite"
+)
+
+// Compressed message text is stored as a BLOB starting with textMagic and a
+// codec byte, so it can't be mistaken for plain text, which stays TEXT. Only
+// DEFLATE is written for now; the codec byte leaves room for others.
+const (
+	textMagic    = "shz"
+	codecDeflate = byte(1)
+)
+
+// CompressionKey is the metadata key that, when set, makes imports store new
+// message text compressed
+const CompressionKey = "text_compression"
+
+// MinCompressSize is the smallest message, in bytes, worth compressing;
+// shorter ones rarely get smaller once the header is added
+const MinCompressSize = 256
+
+// compressBatch is how many messages are rewritten per transaction by
+// CompressMessages and DecompressMessages
+const compressBatch = 500
+
+// MessageTextSQL is the SQL function that reads message text, compressed or
+// not. Anything that reads messages.text in SQL goes through it, including
+// the view the full-text indexes are built from, so search and snippets see
+// the plain text.
+const MessageTextSQL = "message_text"
+
+func init() {
+	sqlite.MustRegisterDeterministicScalarFunction(MessageTextSQL, 1, func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		blob, ok := args[0].([]byte)
+		if !ok || !isCompressed(blob) {
+			return args[0], nil
+		}
+		return DecompressText(blob)
+	})
+}
+
+// isCompressed reports whether a stored value is compressed message text
+func isCompressed(stored []byte) bool {
+	return len(stored) > len(textMagic) && string(stored[:len(textMagic)]) == textMagic
+}
+
+// CompressText compresses message text for storage
+func CompressText(text string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(textMagic)
+	buf.WriteByte(codecDeflate)
+	// Writing to a bytes.Buffer at a valid level can't fail
+	w, _ := flate.NewWriter(&buf, flate.BestCompression)
+	_, _ = io.WriteString(w, text)
+	_ = w.Close()
+	return buf.Bytes()
+}
+
+// DecompressText returns the text of a value written by CompressText
+func DecompressText(stored []byte) (string, error) {
+	if !isCompressed(stored) {
+		return "", fmt.Errorf("message text is not compressed")
+	}
+	switch codec := stored[len(textMagic)]; codec {
+	case codecDeflate:
+		text, err := io.ReadAll(flate.NewReader(bytes.NewReader(stored[len(textMagic)+1:])))
+		if err != nil {
+			return "", fmt.Errorf("failed to decompress message text: %w", err)
+		}
+		return string(text), nil
+	default:
+		return "", fmt.Errorf("message text compressed with unknown codec %d", codec)
+	}
+}
+
+// StoredText returns what to store in messages.text for text: compressed
+// when compress is set and that makes it smaller, otherwise the text itself
+func StoredText(text string, compress bool) interface{} {
+	if !compress || len(text) < MinCompressSize {
+		return text
+	}
+	if compressed := CompressText(text); len(compressed) < len(text) {
+		return compressed
+	}
+	return text
+}
+
+// CompressionEnabled reports whether new message text is stored compressed
+func CompressionEnabled(q Queryer) (bool, error) {
+	rows, err := q.Query("SELECT value FROM metadata WHERE key = ?", CompressionKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to read compression setting: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	enabled := rows.Next()
+	return enabled, rows.Err()
+}
+
+// CompressionResult is what compressing or decompressing the messages did
+type CompressionResult struct {
+	Messages int64 // messages rewritten
+	Before   int64 // bytes of message text stored before
+	After    int64 // bytes of message text stored after
+}
+
+// TextSize returns how many bytes the stored message text takes up, and how
+// many of them are compressed
+func (db *DB) TextSize() (total, compressed int64, err error) {
+	err = db.conn.QueryRow(`
+		SELECT COALESCE(SUM(LENGTH(CAST(text AS BLOB))), 0),
+			COALESCE(SUM(CASE WHEN typeof(text) = 'blob' THEN LENGTH(text) ELSE 0 END), 0)
+		FROM messages
+	`).Scan(&total, &compressed)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to measure message text: %w", err)
+	}
+	return total, compressed, nil
+}
+
+// CompressMessages compresses the text of every message where that saves
+// space and turns on compression for later imports. The full-text indexes
+// aren't touched, since the text they see doesn't change. The database file
+// only shrinks once it is vacuumed.
+func (db *DB) CompressMessages() (*CompressionResult, error) {
+	if _, err := db.conn.Exec("INSERT OR REPLACE INTO metadata (key, value) VALUES (?, 'deflate')", CompressionKey); err != nil {
+		return nil, fmt.Errorf("failed to turn on compression: %w", err)
+	}
+	where := fmt.Sprintf("typeof(text) = 'text' AND LENGTH(CAST(text AS BLOB)) >= %d", MinCompressSize)
+	return db.rewriteText(where, true, func(stored []byte) ([]byte, bool, error) {
+		compressed := CompressText(string(stored))
+		return compressed, len(compressed) < len(stored), nil
+	})
+}
+
+// DecompressMessages stores the text of every message uncompressed again and
+// turns off compression for later imports
+func (db *DB) DecompressMessages() (*CompressionResult, error) {
+	if _, err := db.conn.Exec("DELETE FROM metadata WHERE key = ?", CompressionKey); err != nil {
+		return nil, fmt.Errorf("failed to turn off compression: %w", err)
+	}
+	return db.rewriteText("typeof(text) = 'blob'", false, func(stored []byte) ([]byte, bool, error) {
+		if !isCompressed(stored) {
+			return nil, false, nil
+		}
+		text, err := DecompressText(stored)
+		return []byte(text), true, err
+	})
+}
+
+// textRewrite returns what to store in place of a message's stored text, and
+// whether to store it at all
+type textRewrite func(stored []byte) ([]byte, bool, error)
+
+// rewriteText rewrites the stored text of the messages matching where, a
+// batch of compressBatch messages per transaction so a large database
+// doesn't hold the write lock for the whole run. What compressed rewrites
+// return is stored as a BLOB, and what the others return as TEXT.
+func (db *DB) rewriteText(where string, compressed bool, rewrite textRewrite) (*CompressionResult, error) {
+	result := &CompressionResult{}
+	var after int64
+	for {
+		tx, err := db.Begin()
+		if err != nil {
+			return nil, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		n, last, err := rewriteBatch(tx, where, after, compressed, rewrite, result)
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("failed to commit: %w", err)
+		}
+		if n < compressBatch {
+			return result, nil
+		}
+		after = last
+	}
+}
+
+// rewriteBatch rewrites the next batch of messages after the message with
+// ID after, returning how many it looked at and the last one's ID
+func rewriteBatch(tx *sql.Tx, where string, after int64, compressed bool, rewrite textRewrite, result *CompressionResult) (int, int64, error) {
+	rows, err := tx.Query("SELECT id, CAST(text AS BLOB) FROM messages WHERE id > ? AND "+where+" ORDER BY id LIMIT ?",
+		after, compressBatch)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read messages: %w", err)
+	}
+	updates := make(map[int64]interface{})
+	n := 0
+	for rows.Next() {
+		var stored []byte
+		if err := rows.Scan(&after, &stored); err != nil {
+			_ = rows.Close()
+			return 0, 0, fmt.Errorf("failed to scan message: %w", err)
+		}
+		n++
+
+		value, ok, err := rewrite(stored)
+		if err != nil {
+			_ = rows.Close()
+			return 0, 0, fmt.Errorf("message %d: %w", after, err)
+		}
+		if !ok {
+			continue
+		}
+		if compressed {
+			updates[after] = value
+		} else {
+			updates[after] = string(value)
+		}
+		result.Messages++
+		result.Before += int64(len(stored))
+		result.After += int64(len(value))
+	}
+	if err := rows.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to close rows: %v\n", err)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, 0, fmt.Errorf("failed to read messages: %w", err)
+	}
+
+	for id, value := range updates {
+		if _, err := tx.Exec("UPDATE messages SET text = ? WHERE id = ?", value, id); err != nil {
+			return 0, 0, fmt.Errorf("failed to rewrite message %d: %w", id, err)
+		}
+	}
+	return n, after, nil
+}
+
+// Vacuum rebuilds the database file, returning the space freed by deleted
+// and rewritten rows to the file system
+func (db *DB) Vacuum() error {
+	if _, err := db.conn.Exec("VACUUM"); err != nil {
+		return fmt.Errorf("failed to vacuum database: %w", err)
+	}
+	return nil
+}
@@ -1,20 +1,69 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"os"
 	"time"
 
+	"github.com/neilberkman/shannon/internal/crash"
 	_ "modernc.org/sqlite"
 )
 
 type DB struct {
 	conn *sql.DB
+	path string
 }
 
 func New(dbPath string) (*DB, error) {
-	// Open database with pragmas for performance and FTS5
-	conn, err := sql.Open("sqlite", dbPath+"?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)")
+	crash.Note("open database %s", dbPath)
+	conn, err := open(dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	db := &DB{conn: conn, path: dbPath}
+
+	version, err := db.schemaVersion()
+	if err != nil {
+		return nil, closeWith(conn, err)
+	}
+
+	switch {
+	case version == 0:
+		// Initialize schema
+		if err := db.initSchema(); err != nil {
+			return nil, closeWith(conn, fmt.Errorf("failed to initialize schema: %w", err))
+		}
+
+		// Bring the new database up to the current schema version
+		if err := db.migrate(); err != nil {
+			return nil, closeWith(conn, fmt.Errorf("failed to migrate schema: %w", err))
+		}
+	case version != SchemaVersion:
+		// Older databases are upgraded only when asked to, with a backup,
+		// and newer ones may hold data this build doesn't understand
+		return nil, closeWith(conn, &VersionError{Path: dbPath, Version: version})
+	}
+
+	// Conversations left in the trash past the retention period go for good.
+	// Failing to purge them shouldn't stop anything else from running.
+	if err := db.purgeExpiredTrash(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to purge expired trash: %v\n", err)
+	}
+
+	return db, nil
+}
+
+// open opens the database file with pragmas for performance and FTS5.
+// Connections wait up to BusyTimeout for other processes to release their
+// locks, and transactions take the write lock when they begin, so two
+// writers queue up instead of one failing halfway through.
+func open(dbPath string) (*sql.DB, error) {
+	dsn := fmt.Sprintf("%s?_pragma=busy_timeout(%d)&_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)&_txlock=immediate",
+		dbPath, BusyTimeout.Milliseconds())
+	conn, err := sql.Open("sqlite", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -24,17 +73,15 @@ func New(dbPath string) (*DB, error) {
 	conn.SetMaxIdleConns(1)
 	conn.SetConnMaxLifetime(time.Hour)
 
-	db := &DB{conn: conn}
+	return conn, nil
+}
 
-	// Initialize schema
-	if err := db.initSchema(); err != nil {
-		if closeErr := conn.Close(); closeErr != nil {
-			return nil, fmt.Errorf("failed to initialize schema: %w (also failed to close connection: %v)", err, closeErr)
-		}
-		return nil, fmt.Errorf("failed to initialize schema: %w", err)
+// closeWith closes conn after a failure to open the database, returning err
+func closeWith(conn *sql.DB, err error) error {
+	if closeErr := conn.Close(); closeErr != nil {
+		return fmt.Errorf("%w (also failed to close connection: %v)", err, closeErr)
 	}
-
-	return db, nil
+	return err
 }
 
 func (db *DB) Close() error {
@@ -114,13 +161,15 @@ func (db *DB) initSchema() error {
 	END;
 	
 	CREATE TRIGGER IF NOT EXISTS messages_ad AFTER DELETE ON messages BEGIN
-		DELETE FROM messages_fts WHERE rowid = old.id;
-		DELETE FROM messages_fts_code WHERE rowid = old.id;
+		INSERT INTO messages_fts(messages_fts, rowid, text) VALUES ('delete', old.id, old.text);
+		INSERT INTO messages_fts_code(messages_fts_code, rowid, text) VALUES ('delete', old.id, old.text);
 	END;
 	
-	CREATE TRIGGER IF NOT EXISTS messages_au AFTER UPDATE ON messages BEGIN
-		UPDATE messages_fts SET text = new.text WHERE rowid = new.id;
-		UPDATE messages_fts_code SET text = new.text WHERE rowid = new.id;
+	CREATE TRIGGER IF NOT EXISTS messages_au AFTER UPDATE OF text ON messages BEGIN
+		INSERT INTO messages_fts(messages_fts, rowid, text) VALUES ('delete', old.id, old.text);
+		INSERT INTO messages_fts_code(messages_fts_code, rowid, text) VALUES ('delete', old.id, old.text);
+		INSERT INTO messages_fts(rowid, text) VALUES (new.id, new.text);
+		INSERT INTO messages_fts_code(rowid, text) VALUES (new.id, new.text);
 	END;
 	
 	-- Import tracking table
@@ -151,22 +200,565 @@ func (db *DB) initSchema() error {
 	return err
 }
 
-// Begin starts a new transaction
+// migrations upgrade the schema one version at a time. The statements at
+// index i move the database from schema version i+1 to i+2.
+var migrations = [][]string{
+	// v2: per-import statistics and provenance for `shannon imports`
+	{
+		`ALTER TABLE import_history ADD COLUMN branches_detected INTEGER DEFAULT 0`,
+		`ALTER TABLE import_history ADD COLUMN duration_ms INTEGER DEFAULT 0`,
+		`ALTER TABLE import_history ADD COLUMN errors_count INTEGER DEFAULT 0`,
+		`CREATE TABLE IF NOT EXISTS import_errors (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			import_id INTEGER NOT NULL,
+			conversation_uuid TEXT,
+			error_message TEXT NOT NULL,
+			FOREIGN KEY (import_id) REFERENCES import_history(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_import_errors_import_id ON import_errors(import_id)`,
+		`ALTER TABLE conversations ADD COLUMN import_id INTEGER`,
+		`ALTER TABLE messages ADD COLUMN import_id INTEGER`,
+		`CREATE INDEX IF NOT EXISTS idx_messages_import_id ON messages(import_id)`,
+	},
+	// v3: fenced code blocks and artifact bodies for `shannon grep-code`
+	{
+		`CREATE TABLE IF NOT EXISTS code_blocks (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			message_id INTEGER NOT NULL,
+			conversation_id INTEGER NOT NULL,
+			kind TEXT NOT NULL CHECK(kind IN ('codeblock', 'artifact')),
+			language TEXT,
+			title TEXT,
+			start_line INTEGER NOT NULL,
+			content TEXT NOT NULL,
+			FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_code_blocks_message_id ON code_blocks(message_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_code_blocks_language ON code_blocks(language)`,
+		// Databases with existing messages are indexed on first use of grep-code
+		`INSERT OR IGNORE INTO metadata (key, value)
+			SELECT 'code_index_version', '1' WHERE NOT EXISTS (SELECT 1 FROM messages)`,
+	},
+	// v4: derived per-conversation metrics for sorting in list and browse
+	{
+		`ALTER TABLE conversations ADD COLUMN token_count INTEGER DEFAULT 0`,
+		`ALTER TABLE conversations ADD COLUMN artifact_count INTEGER DEFAULT 0`,
+		`ALTER TABLE conversations ADD COLUMN human_message_count INTEGER DEFAULT 0`,
+		`UPDATE conversations SET ` + countStatsColumns,
+	},
+	// v5: message cleanup with undo for `shannon cleanup`. The original delete
+	// and update triggers issued DELETE/UPDATE against the external-content
+	// FTS tables, which leaves stale terms behind; FTS5 expects the special
+	// 'delete' command instead, so the triggers are replaced and both indexes
+	// rebuilt from the messages table.
+	{
+		`DROP TRIGGER IF EXISTS messages_ad`,
+		`CREATE TRIGGER messages_ad AFTER DELETE ON messages BEGIN
+			INSERT INTO messages_fts(messages_fts, rowid, text) VALUES ('delete', old.id, old.text);
+			INSERT INTO messages_fts_code(messages_fts_code, rowid, text) VALUES ('delete', old.id, old.text);
+		END`,
+		`DROP TRIGGER IF EXISTS messages_au`,
+		`CREATE TRIGGER messages_au AFTER UPDATE OF text ON messages BEGIN
+			INSERT INTO messages_fts(messages_fts, rowid, text) VALUES ('delete', old.id, old.text);
+			INSERT INTO messages_fts_code(messages_fts_code, rowid, text) VALUES ('delete', old.id, old.text);
+			INSERT INTO messages_fts(rowid, text) VALUES (new.id, new.text);
+			INSERT INTO messages_fts_code(rowid, text) VALUES (new.id, new.text);
+		END`,
+		`INSERT INTO messages_fts(messages_fts) VALUES ('rebuild')`,
+		`INSERT INTO messages_fts_code(messages_fts_code) VALUES ('rebuild')`,
+		`CREATE TABLE IF NOT EXISTS message_edits (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			action TEXT NOT NULL CHECK(action IN ('delete', 'truncate')),
+			message_id INTEGER NOT NULL,
+			conversation_id INTEGER NOT NULL,
+			message_uuid TEXT NOT NULL,
+			sender TEXT NOT NULL,
+			original_text TEXT NOT NULL,
+			message_created_at DATETIME NOT NULL,
+			parent_id INTEGER,
+			branch_id INTEGER NOT NULL,
+			sequence INTEGER NOT NULL,
+			import_id INTEGER,
+			reparented_ids TEXT,
+			edited_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			purged_at DATETIME,
+			FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_message_edits_conversation_id ON message_edits(conversation_id)`,
+	},
+	// v6: tombstones for conversations deleted with `shannon cleanup
+	// conversation`, so re-importing an export doesn't bring them back
+	{
+		`CREATE TABLE IF NOT EXISTS deleted_conversations (
+			uuid TEXT PRIMARY KEY,
+			name TEXT NOT NULL,
+			message_count INTEGER DEFAULT 0,
+			deleted_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`,
+	},
+	// v7: the unstemmed index keeps diacritics, so `shannon search --no-stem`
+	// matches words exactly as written while the stemmed index folds them
+	{
+		`DROP TABLE IF EXISTS messages_fts_code`,
+		`CREATE VIRTUAL TABLE messages_fts_code USING fts5(
+			text,
+			content=messages,
+			content_rowid=id,
+			tokenize='unicode61 remove_diacritics 0'
+		)`,
+		`INSERT INTO messages_fts_code(messages_fts_code) VALUES ('rebuild')`,
+	},
+	// v8: memorable names for conversations. Slugs are generated from the
+	// title on first use and never change; aliases are set by the user.
+	{
+		`ALTER TABLE conversations ADD COLUMN slug TEXT`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_conversations_slug ON conversations(slug)`,
+		`CREATE TABLE IF NOT EXISTS conversation_aliases (
+			alias TEXT PRIMARY KEY,
+			conversation_id INTEGER NOT NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_conversation_aliases_conversation_id ON conversation_aliases(conversation_id)`,
+	},
+	// v9: local record of conversations viewed and exported and of searches
+	// run, for `shannon stats --usage`
+	{
+		`CREATE TABLE IF NOT EXISTS access_log (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			action TEXT NOT NULL CHECK(action IN ('view', 'export', 'search')),
+			conversation_id INTEGER,
+			query TEXT,
+			accessed_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_access_log_conversation_id ON access_log(conversation_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_access_log_accessed_at ON access_log(accessed_at)`,
+	},
+	// v10: conversations split off from another with `shannon split`, so
+	// each part links back to where it came from and re-imports of the
+	// original don't bring the moved messages back
+	{
+		`CREATE TABLE IF NOT EXISTS conversation_splits (
+			conversation_id INTEGER PRIMARY KEY,
+			source_id INTEGER NOT NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE,
+			FOREIGN KEY (source_id) REFERENCES conversations(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_conversation_splits_source_id ON conversation_splits(source_id)`,
+	},
+	// v11: personal ratings and notes on messages for `shannon rate`. They
+	// live apart from the messages so imports never touch them.
+	{
+		`CREATE TABLE IF NOT EXISTS message_ratings (
+			message_id INTEGER PRIMARY KEY,
+			rating TEXT NOT NULL CHECK(rating IN ('useful', 'obsolete', 'wrong')),
+			note TEXT NOT NULL DEFAULT '',
+			rated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_message_ratings_rating ON message_ratings(rating)`,
+	},
+	// v12: how long each logged search took, for the search latency
+	// histogram of `shannon stats --openmetrics`
+	{
+		`ALTER TABLE access_log ADD COLUMN duration_ms REAL`,
+	},
+	// v13: conversations deleted with `shannon cleanup conversation` wait in
+	// the trash until restored or purged. The full-text indexes read their
+	// text through a view that leaves trashed conversations out, and the
+	// triggers only index messages of conversations that aren't trashed, so
+	// trashing and restoring move a conversation's messages out of and back
+	// into search while the indexes stay in sync with what they cover.
+	{
+		`ALTER TABLE conversations ADD COLUMN deleted_at DATETIME`,
+		`CREATE INDEX IF NOT EXISTS idx_conversations_deleted_at ON conversations(deleted_at)`,
+		`CREATE VIEW IF NOT EXISTS searchable_messages AS
+			SELECT id, text
+			FROM messages
+			WHERE conversation_id NOT IN (SELECT id FROM conversations WHERE deleted_at IS NOT NULL)`,
+		`DROP TABLE IF EXISTS messages_fts`,
+		`CREATE VIRTUAL TABLE messages_fts USING fts5(
+			text,
+			content=searchable_messages,
+			content_rowid=id,
+			tokenize='porter unicode61'
+		)`,
+		`DROP TABLE IF EXISTS messages_fts_code`,
+		`CREATE VIRTUAL TABLE messages_fts_code USING fts5(
+			text,
+			content=searchable_messages,
+			content_rowid=id,
+			tokenize='unicode61 remove_diacritics 0'
+		)`,
+		`DROP TRIGGER IF EXISTS messages_ai`,
+		`CREATE TRIGGER messages_ai AFTER INSERT ON messages
+		WHEN (SELECT deleted_at FROM conversations WHERE id = new.conversation_id) IS NULL BEGIN
+			INSERT INTO messages_fts(rowid, text) VALUES (new.id, new.text);
+			INSERT INTO messages_fts_code(rowid, text) VALUES (new.id, new.text);
+		END`,
+		`DROP TRIGGER IF EXISTS messages_ad`,
+		`CREATE TRIGGER messages_ad AFTER DELETE ON messages
+		WHEN (SELECT deleted_at FROM conversations WHERE id = old.conversation_id) IS NULL BEGIN
+			INSERT INTO messages_fts(messages_fts, rowid, text) VALUES ('delete', old.id, old.text);
+			INSERT INTO messages_fts_code(messages_fts_code, rowid, text) VALUES ('delete', old.id, old.text);
+		END`,
+		`DROP TRIGGER IF EXISTS messages_au`,
+		`CREATE TRIGGER messages_au AFTER UPDATE OF text ON messages
+		WHEN (SELECT deleted_at FROM conversations WHERE id = new.conversation_id) IS NULL BEGIN
+			INSERT INTO messages_fts(messages_fts, rowid, text) VALUES ('delete', old.id, old.text);
+			INSERT INTO messages_fts_code(messages_fts_code, rowid, text) VALUES ('delete', old.id, old.text);
+			INSERT INTO messages_fts(rowid, text) VALUES (new.id, new.text);
+			INSERT INTO messages_fts_code(rowid, text) VALUES (new.id, new.text);
+		END`,
+		`CREATE TRIGGER conversations_trash AFTER UPDATE OF deleted_at ON conversations
+		WHEN old.deleted_at IS NULL AND new.deleted_at IS NOT NULL BEGIN
+			INSERT INTO messages_fts(messages_fts, rowid, text)
+				SELECT 'delete', id, text FROM messages WHERE conversation_id = new.id;
+			INSERT INTO messages_fts_code(messages_fts_code, rowid, text)
+				SELECT 'delete', id, text FROM messages WHERE conversation_id = new.id;
+		END`,
+		`CREATE TRIGGER conversations_restore AFTER UPDATE OF deleted_at ON conversations
+		WHEN old.deleted_at IS NOT NULL AND new.deleted_at IS NULL BEGIN
+			INSERT INTO messages_fts(rowid, text)
+				SELECT id, text FROM messages WHERE conversation_id = new.id;
+			INSERT INTO messages_fts_code(rowid, text)
+				SELECT id, text FROM messages WHERE conversation_id = new.id;
+		END`,
+		`INSERT INTO messages_fts(messages_fts) VALUES ('rebuild')`,
+		`INSERT INTO messages_fts_code(messages_fts_code) VALUES ('rebuild')`,
+	},
+	// v14: artifacts are read from the code block index instead of being
+	// extracted every time a conversation is opened, so the index keeps their
+	// identifier and type too. Databases with messages are re-indexed on next
+	// use (code index version 2).
+	{
+		`ALTER TABLE code_blocks ADD COLUMN identifier TEXT`,
+		`ALTER TABLE code_blocks ADD COLUMN artifact_type TEXT`,
+		`CREATE INDEX IF NOT EXISTS idx_code_blocks_conversation_id ON code_blocks(conversation_id)`,
+		`UPDATE metadata SET value = '2'
+			WHERE key = 'code_index_version' AND NOT EXISTS (SELECT 1 FROM messages)`,
+	},
+	// v15: conversations renamed with `shannon rename` keep the name from
+	// the export in original_name, where re-imports update it instead of
+	// undoing the rename
+	{
+		`ALTER TABLE conversations ADD COLUMN original_name TEXT`,
+	},
+	// v16: a queue of enrichment jobs run in the background by
+	// `shannon worker`, one per kind and conversation, and the results they
+	// store as JSON
+	{
+		`CREATE TABLE IF NOT EXISTS jobs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			kind TEXT NOT NULL,
+			conversation_id INTEGER NOT NULL,
+			status TEXT NOT NULL DEFAULT 'pending' CHECK(status IN ('pending', 'running', 'done', 'failed')),
+			attempts INTEGER NOT NULL DEFAULT 0,
+			error TEXT NOT NULL DEFAULT '',
+			queued_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			started_at DATETIME,
+			finished_at DATETIME,
+			UNIQUE (kind, conversation_id),
+			FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_jobs_status ON jobs(status)`,
+		`CREATE TABLE IF NOT EXISTS enrichments (
+			conversation_id INTEGER NOT NULL,
+			kind TEXT NOT NULL,
+			result TEXT NOT NULL,
+			message_count INTEGER NOT NULL,
+			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (conversation_id, kind),
+			FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE
+		)`,
+	},
+	// v17: message text may be stored compressed (`shannon db compress`), so
+	// the full-text indexes and their triggers read it through
+	// message_text(). Compressing a message changes what's stored but not its
+	// text, which leaves the indexes alone.
+	{
+		`DROP VIEW IF EXISTS searchable_messages`,
+		`CREATE VIEW searchable_messages AS
+			SELECT id, message_text(text) AS text
+			FROM messages
+			WHERE conversation_id NOT IN (SELECT id FROM conversations WHERE deleted_at IS NOT NULL)`,
+		`DROP TRIGGER IF EXISTS messages_ai`,
+		`CREATE TRIGGER messages_ai AFTER INSERT ON messages
+		WHEN (SELECT deleted_at FROM conversations WHERE id = new.conversation_id) IS NULL BEGIN
+			INSERT INTO messages_fts(rowid, text) VALUES (new.id, message_text(new.text));
+			INSERT INTO messages_fts_code(rowid, text) VALUES (new.id, message_text(new.text));
+		END`,
+		`DROP TRIGGER IF EXISTS messages_ad`,
+		`CREATE TRIGGER messages_ad AFTER DELETE ON messages
+		WHEN (SELECT deleted_at FROM conversations WHERE id = old.conversation_id) IS NULL BEGIN
+			INSERT INTO messages_fts(messages_fts, rowid, text) VALUES ('delete', old.id, message_text(old.text));
+			INSERT INTO messages_fts_code(messages_fts_code, rowid, text) VALUES ('delete', old.id, message_text(old.text));
+		END`,
+		`DROP TRIGGER IF EXISTS messages_au`,
+		`CREATE TRIGGER messages_au AFTER UPDATE OF text ON messages
+		WHEN (SELECT deleted_at FROM conversations WHERE id = new.conversation_id) IS NULL
+			AND message_text(old.text) IS NOT message_text(new.text) BEGIN
+			INSERT INTO messages_fts(messages_fts, rowid, text) VALUES ('delete', old.id, message_text(old.text));
+			INSERT INTO messages_fts_code(messages_fts_code, rowid, text) VALUES ('delete', old.id, message_text(old.text));
+			INSERT INTO messages_fts(rowid, text) VALUES (new.id, message_text(new.text));
+			INSERT INTO messages_fts_code(rowid, text) VALUES (new.id, message_text(new.text));
+		END`,
+		`DROP TRIGGER IF EXISTS conversations_trash`,
+		`CREATE TRIGGER conversations_trash AFTER UPDATE OF deleted_at ON conversations
+		WHEN old.deleted_at IS NULL AND new.deleted_at IS NOT NULL BEGIN
+			INSERT INTO messages_fts(messages_fts, rowid, text)
+				SELECT 'delete', id, message_text(text) FROM messages WHERE conversation_id = new.id;
+			INSERT INTO messages_fts_code(messages_fts_code, rowid, text)
+				SELECT 'delete', id, message_text(text) FROM messages WHERE conversation_id = new.id;
+		END`,
+		`DROP TRIGGER IF EXISTS conversations_restore`,
+		`CREATE TRIGGER conversations_restore AFTER UPDATE OF deleted_at ON conversations
+		WHEN old.deleted_at IS NOT NULL AND new.deleted_at IS NULL BEGIN
+			INSERT INTO messages_fts(rowid, text)
+				SELECT id, message_text(text) FROM messages WHERE conversation_id = new.id;
+			INSERT INTO messages_fts_code(rowid, text)
+				SELECT id, message_text(text) FROM messages WHERE conversation_id = new.id;
+		END`,
+	},
+	// v18: the model that wrote each assistant message, for exports that
+	// record it. Messages imported earlier get theirs when the export is
+	// imported again.
+	{
+		`ALTER TABLE messages ADD COLUMN model TEXT NOT NULL DEFAULT ''`,
+		`CREATE INDEX IF NOT EXISTS idx_messages_model ON messages(model)`,
+		`ALTER TABLE message_edits ADD COLUMN model TEXT NOT NULL DEFAULT ''`,
+	},
+	// v19: private conversations (`shannon private`) are kept out of the
+	// full-text indexes like trashed ones, so they can't turn up in a search
+	// by accident. Making a conversation private or public again moves its
+	// messages out of or back into the indexes.
+	{
+		`ALTER TABLE conversations ADD COLUMN private INTEGER NOT NULL DEFAULT 0`,
+		`DROP VIEW IF EXISTS searchable_messages`,
+		`CREATE VIEW searchable_messages AS
+			SELECT id, message_text(text) AS text
+			FROM messages
+			WHERE conversation_id NOT IN (SELECT id FROM conversations WHERE deleted_at IS NOT NULL OR private)`,
+		`DROP TRIGGER IF EXISTS messages_ai`,
+		`CREATE TRIGGER messages_ai AFTER INSERT ON messages
+		WHEN NOT EXISTS (SELECT 1 FROM conversations WHERE id = new.conversation_id AND (deleted_at IS NOT NULL OR private)) BEGIN
+			INSERT INTO messages_fts(rowid, text) VALUES (new.id, message_text(new.text));
+			INSERT INTO messages_fts_code(rowid, text) VALUES (new.id, message_text(new.text));
+		END`,
+		`DROP TRIGGER IF EXISTS messages_ad`,
+		`CREATE TRIGGER messages_ad AFTER DELETE ON messages
+		WHEN NOT EXISTS (SELECT 1 FROM conversations WHERE id = old.conversation_id AND (deleted_at IS NOT NULL OR private)) BEGIN
+			INSERT INTO messages_fts(messages_fts, rowid, text) VALUES ('delete', old.id, message_text(old.text));
+			INSERT INTO messages_fts_code(messages_fts_code, rowid, text) VALUES ('delete', old.id, message_text(old.text));
+		END`,
+		`DROP TRIGGER IF EXISTS messages_au`,
+		`CREATE TRIGGER messages_au AFTER UPDATE OF text ON messages
+		WHEN NOT EXISTS (SELECT 1 FROM conversations WHERE id = new.conversation_id AND (deleted_at IS NOT NULL OR private))
+			AND message_text(old.text) IS NOT message_text(new.text) BEGIN
+			INSERT INTO messages_fts(messages_fts, rowid, text) VALUES ('delete', old.id, message_text(old.text));
+			INSERT INTO messages_fts_code(messages_fts_code, rowid, text) VALUES ('delete', old.id, message_text(old.text));
+			INSERT INTO messages_fts(rowid, text) VALUES (new.id, message_text(new.text));
+			INSERT INTO messages_fts_code(rowid, text) VALUES (new.id, message_text(new.text));
+		END`,
+		`DROP TRIGGER IF EXISTS conversations_trash`,
+		`CREATE TRIGGER conversations_trash AFTER UPDATE OF deleted_at ON conversations
+		WHEN old.deleted_at IS NULL AND new.deleted_at IS NOT NULL AND NOT new.private BEGIN
+			INSERT INTO messages_fts(messages_fts, rowid, text)
+				SELECT 'delete', id, message_text(text) FROM messages WHERE conversation_id = new.id;
+			INSERT INTO messages_fts_code(messages_fts_code, rowid, text)
+				SELECT 'delete', id, message_text(text) FROM messages WHERE conversation_id = new.id;
+		END`,
+		`DROP TRIGGER IF EXISTS conversations_restore`,
+		`CREATE TRIGGER conversations_restore AFTER UPDATE OF deleted_at ON conversations
+		WHEN old.deleted_at IS NOT NULL AND new.deleted_at IS NULL AND NOT new.private BEGIN
+			INSERT INTO messages_fts(rowid, text)
+				SELECT id, message_text(text) FROM messages WHERE conversation_id = new.id;
+			INSERT INTO messages_fts_code(rowid, text)
+				SELECT id, message_text(text) FROM messages WHERE conversation_id = new.id;
+		END`,
+		`CREATE TRIGGER conversations_private AFTER UPDATE OF private ON conversations
+		WHEN NOT old.private AND new.private AND new.deleted_at IS NULL BEGIN
+			INSERT INTO messages_fts(messages_fts, rowid, text)
+				SELECT 'delete', id, message_text(text) FROM messages WHERE conversation_id = new.id;
+			INSERT INTO messages_fts_code(messages_fts_code, rowid, text)
+				SELECT 'delete', id, message_text(text) FROM messages WHERE conversation_id = new.id;
+		END`,
+		`CREATE TRIGGER conversations_public AFTER UPDATE OF private ON conversations
+		WHEN old.private AND NOT new.private AND new.deleted_at IS NULL BEGIN
+			INSERT INTO messages_fts(rowid, text)
+				SELECT id, message_text(text) FROM messages WHERE conversation_id = new.id;
+			INSERT INTO messages_fts_code(rowid, text)
+				SELECT id, message_text(text) FROM messages WHERE conversation_id = new.id;
+		END`,
+	},
+	// v20: stars and thumbs up or down given in claude.ai, for exports that
+	// record them, so is:starred and is:upvoted can find them
+	{
+		`ALTER TABLE conversations ADD COLUMN starred INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE messages ADD COLUMN starred INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE messages ADD COLUMN feedback TEXT NOT NULL DEFAULT ''`,
+		`CREATE INDEX IF NOT EXISTS idx_messages_starred ON messages(conversation_id) WHERE starred`,
+		`ALTER TABLE message_edits ADD COLUMN starred INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE message_edits ADD COLUMN feedback TEXT NOT NULL DEFAULT ''`,
+	},
+	// v21: how long conversations ran and on how many days, so duration:
+	// and days: can tell working sessions from one-off questions
+	{
+		`ALTER TABLE conversations ADD COLUMN duration_seconds INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE conversations ADD COLUMN active_days INTEGER NOT NULL DEFAULT 0`,
+		`UPDATE conversations SET ` + spanStatsColumns,
+	},
+	// v22: conversations linked with `shannon link` as carrying on from an
+	// earlier one. Each continues at most one and is continued in at most
+	// one, so linked conversations form chains.
+	{
+		`CREATE TABLE IF NOT EXISTS conversation_continuations (
+			conversation_id INTEGER PRIMARY KEY,
+			previous_id INTEGER NOT NULL UNIQUE,
+			linked_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE,
+			FOREIGN KEY (previous_id) REFERENCES conversations(id) ON DELETE CASCADE
+		)`,
+	},
+}
+
+// countStatsColumns recomputes the token, artifact and human message counts
+// of a conversation from its messages. Tokens are estimated at roughly four
+// characters each.
+const countStatsColumns = `
+	token_count = (SELECT COALESCE(SUM((LENGTH(message_text(text)) + 3) / 4), 0)
+		FROM messages WHERE conversation_id = conversations.id),
+	artifact_count = (SELECT COALESCE(SUM((LENGTH(message_text(text)) - LENGTH(REPLACE(message_text(text), '<antArtifact', ''))) / LENGTH('<antArtifact')), 0)
+		FROM messages WHERE conversation_id = conversations.id AND sender = 'assistant'),
+	human_message_count = (SELECT COUNT(*)
+		FROM messages WHERE conversation_id = conversations.id AND sender = 'human')
+`
+
+// spanStatsColumns recomputes the seconds from the first message of a
+// conversation to the last and the days, in UTC, it has messages on. Only
+// the date and time of the stored timestamps are read, which are in UTC.
+const spanStatsColumns = `
+	duration_seconds = (SELECT COALESCE(CAST(ROUND((julianday(MAX(substr(created_at, 1, 19))) - julianday(MIN(substr(created_at, 1, 19)))) * 86400) AS INTEGER), 0)
+		FROM messages WHERE conversation_id = conversations.id),
+	active_days = (SELECT COUNT(DISTINCT substr(created_at, 1, 10))
+		FROM messages WHERE conversation_id = conversations.id)
+`
+
+// conversationStatsColumns recomputes the derived metrics of a conversation
+// from its messages
+const conversationStatsColumns = countStatsColumns + "," + spanStatsColumns
+
+// RefreshConversationStatsSQL recomputes the derived metrics for the
+// conversation whose ID is passed as the only argument
+const RefreshConversationStatsSQL = `UPDATE conversations SET ` + conversationStatsColumns + ` WHERE id = ?`
+
+// SchemaVersion is the schema version this build of shannon expects
+var SchemaVersion = len(migrations) + 1
+
+// schemaVersion returns the schema version recorded in the database, or 0
+// for a database that hasn't been initialized
+func (db *DB) schemaVersion() (int, error) {
+	var tables int
+	if err := db.conn.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'metadata'").Scan(&tables); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if tables == 0 {
+		return 0, nil
+	}
+
+	var versionStr string
+	if err := db.conn.QueryRow("SELECT value FROM metadata WHERE key = 'schema_version'").Scan(&versionStr); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(versionStr, "%d", &version); err != nil {
+		return 0, fmt.Errorf("invalid schema version %q: %w", versionStr, err)
+	}
+	return version, nil
+}
+
+// migrate applies any pending schema migrations
+func (db *DB) migrate() error {
+	version, err := db.schemaVersion()
+	if err != nil {
+		return err
+	}
+
+	for v := version; v < SchemaVersion; v++ {
+		tx, err := db.conn.Begin()
+		if err != nil {
+			return err
+		}
+
+		for _, stmt := range migrations[v-1] {
+			if _, err := tx.Exec(stmt); err != nil {
+				_ = tx.Rollback()
+				return fmt.Errorf("migration to version %d failed: %w", v+1, err)
+			}
+		}
+
+		if _, err := tx.Exec("UPDATE metadata SET value = ? WHERE key = 'schema_version'", fmt.Sprintf("%d", v+1)); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("failed to record schema version %d: %w", v+1, err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration to version %d: %w", v+1, err)
+		}
+	}
+
+	return nil
+}
+
+// Begin starts a new transaction, waiting for the write lock
 func (db *DB) Begin() (*sql.Tx, error) {
-	return db.conn.Begin()
+	var tx *sql.Tx
+	err := db.retry(func() (err error) {
+		tx, err = db.conn.Begin()
+		return err
+	})
+	return tx, err
 }
 
 // Exec executes a query without returning rows
 func (db *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
-	return db.conn.Exec(query, args...)
+	var result sql.Result
+	err := db.retry(func() (err error) {
+		result, err = db.conn.Exec(query, args...)
+		return err
+	})
+	return result, err
 }
 
 // Query executes a query that returns rows
 func (db *DB) Query(query string, args ...interface{}) (*sql.Rows, error) {
-	return db.conn.Query(query, args...)
+	var rows *sql.Rows
+	err := db.retry(func() (err error) {
+		rows, err = db.conn.Query(query, args...)
+		return err
+	})
+	return rows, err
+}
+
+// QueryContext executes a query that returns rows and can be canceled via ctx
+func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	var rows *sql.Rows
+	err := db.retry(func() (err error) {
+		rows, err = db.conn.QueryContext(ctx, query, args...)
+		return err
+	})
+	return rows, err
 }
 
 // QueryRow executes a query that returns a single row
 func (db *DB) QueryRow(query string, args ...interface{}) *sql.Row {
-	return db.conn.QueryRow(query, args...)
+	var row *sql.Row
+	_ = db.retry(func() error {
+		row = db.conn.QueryRow(query, args...)
+		return row.Err()
+	})
+	return row
 }
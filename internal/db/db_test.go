@@ -1,9 +1,15 @@
 package db
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestDatabaseInit(t *testing.T) {
@@ -109,3 +115,458 @@ func TestTransaction(t *testing.T) {
 		t.Errorf("expected test_value, got %s", value)
 	}
 }
+
+func TestBusy(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	first, err := New(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = first.Close() }()
+	second, err := New(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = second.Close() }()
+
+	insert := "INSERT INTO metadata (key, value) VALUES (?, 'x')"
+
+	// A write waits for the other process's transaction to finish
+	tx, err := first.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec(insert, "first"); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error)
+	go func() {
+		_, err := second.Exec(insert, "second")
+		done <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("expected the second write to wait for the first, got %v", err)
+	}
+
+	// Past the timeout, it fails with a BusyError
+	defer func(timeout time.Duration) { BusyTimeout = timeout }(BusyTimeout)
+	BusyTimeout = 50 * time.Millisecond
+	blocker := mustOpen(t, dbPath)
+	defer func() { _ = blocker.Close() }()
+	waiting := &DB{conn: mustOpen(t, dbPath), path: dbPath}
+	defer func() { _ = waiting.Close() }()
+
+	tx, err = blocker.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	_, err = waiting.Begin()
+	var busy *BusyError
+	if !errors.As(err, &busy) || busy.Path != dbPath || !strings.Contains(err.Error(), "locked by another process") {
+		t.Errorf("expected a BusyError for %s, got %v", dbPath, err)
+	}
+}
+
+// mustOpen opens a connection with the current BusyTimeout
+func mustOpen(t *testing.T, dbPath string) *sql.DB {
+	t.Helper()
+	conn, err := open(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func TestVersionGate(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	// A database left at the first schema version by an old release
+	conn, err := open(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := (&DB{conn: conn}).initSchema(); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var versionErr *VersionError
+	if _, err := New(dbPath); !errors.As(err, &versionErr) || versionErr.Version != 1 || versionErr.Newer() {
+		t.Fatalf("opening an old database: got %v, want a version error", err)
+	}
+
+	result, err := Upgrade(dbPath, false)
+	if err != nil {
+		t.Fatalf("upgrade failed: %v", err)
+	}
+	if result.From != 1 || result.To != SchemaVersion {
+		t.Errorf("upgraded from %d to %d, want 1 to %d", result.From, result.To, SchemaVersion)
+	}
+	backup, err := New(result.Backup)
+	if !errors.As(err, &versionErr) || versionErr.Version != 1 {
+		t.Errorf("backup: got %v, want a version 1 database", err)
+	} else if backup != nil {
+		_ = backup.Close()
+	}
+
+	database, err := New(dbPath)
+	if err != nil {
+		t.Fatalf("opening the upgraded database: %v", err)
+	}
+
+	// The same database as a newer release would leave it
+	if _, err := database.Exec("CREATE TABLE tags (name TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.Exec("UPDATE metadata SET value = ? WHERE key = 'schema_version'", fmt.Sprint(SchemaVersion+1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := New(dbPath); !errors.As(err, &versionErr) || !versionErr.Newer() {
+		t.Fatalf("opening a newer database: got %v, want a version error", err)
+	}
+	if _, err := Upgrade(dbPath, false); !errors.As(err, &versionErr) {
+		t.Errorf("upgrading a newer database: got %v, want a version error", err)
+	}
+
+	c, err := CheckDowngrade(dbPath)
+	if err != nil {
+		t.Fatalf("downgrade check failed: %v", err)
+	}
+	if !c.Compatible() || c.Version != SchemaVersion+1 {
+		t.Errorf("got version %d, missing %v, want version %d and nothing missing", c.Version, c.Missing, SchemaVersion+1)
+	}
+	if want := []string{"column tags.name", "table tags"}; !reflect.DeepEqual(c.Extra, want) {
+		t.Errorf("got extra %v, want %v", c.Extra, want)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	if _, err := Inspect(dbPath); err == nil {
+		t.Error("expected an error for a missing database")
+	}
+
+	database, err := New(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		`INSERT INTO conversations (id, uuid, name, created_at, updated_at) VALUES (1, 'c1', 'One', '2024-01-01', '2024-01-01')`,
+		`INSERT INTO branches (id, conversation_id, name) VALUES (1, 1, 'main')`,
+		`INSERT INTO messages (id, uuid, conversation_id, sender, text, created_at, branch_id, sequence) VALUES (1, 'm1', 1, 'human', 'hello world', '2024-01-01', 1, 0)`,
+	} {
+		if _, err := database.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	failing := func(inspection *Inspection) []string {
+		var names []string
+		for _, check := range inspection.Checks {
+			if !check.OK {
+				names = append(names, check.Name)
+			}
+		}
+		return names
+	}
+
+	inspection, err := Inspect(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inspection.Version != SchemaVersion || inspection.Conversations != 1 || inspection.Messages != 1 || inspection.Size == 0 {
+		t.Errorf("unexpected inspection %+v", inspection)
+	}
+	if len(inspection.Checks) == 0 || len(failing(inspection)) > 0 {
+		t.Errorf("expected passing checks, got %+v", inspection.Checks)
+	}
+
+	// Drop a message from the stemmed index and leave a message behind its
+	// deleted conversation
+	for _, stmt := range []string{
+		`INSERT INTO messages_fts(messages_fts, rowid, text) VALUES ('delete', 1, 'hello world')`,
+		`PRAGMA foreign_keys = OFF`,
+		`DELETE FROM conversations`,
+	} {
+		if _, err := database.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := database.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	inspection, err = Inspect(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"full-text index messages_fts in sync with messages", "no orphaned rows"}
+	if got := failing(inspection); !reflect.DeepEqual(got, want) {
+		t.Errorf("got failing checks %v, want %v", got, want)
+	}
+	for _, check := range inspection.Checks {
+		if check.Name == "no orphaned rows" && check.Detail != "messages without a conversation: 1; branches without a conversation: 1" {
+			t.Errorf("unexpected orphans %q", check.Detail)
+		}
+	}
+}
+
+func TestCheckOrder(t *testing.T) {
+	database, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := database.Close(); err != nil {
+			t.Errorf("failed to close database: %v", err)
+		}
+	}()
+
+	// Conversation 1 is in order. In conversation 2 a reply was numbered
+	// before its question and two messages share a number, as appending
+	// messages from a later export used to leave them.
+	for _, stmt := range []string{
+		`INSERT INTO conversations (id, uuid, name, created_at, updated_at) VALUES (1, 'c1', 'Ordered', '2024-01-01', '2024-01-01')`,
+		`INSERT INTO conversations (id, uuid, name, created_at, updated_at) VALUES (2, 'c2', 'Appended', '2024-01-01', '2024-01-01')`,
+		`INSERT INTO branches (id, conversation_id, name) VALUES (1, 1, 'main'), (2, 2, 'main')`,
+		`INSERT INTO messages (id, uuid, conversation_id, sender, text, created_at, parent_id, branch_id, sequence) VALUES
+			(1, 'm1', 1, 'human', 'a', '2024-01-01 10:00:00', NULL, 1, 0),
+			(2, 'm2', 1, 'assistant', 'b', '2024-01-01 10:01:00', 1, 1, 1),
+			(3, 'm3', 2, 'human', 'c', '2024-01-01 10:00:00', NULL, 2, 0),
+			(4, 'm4', 2, 'assistant', 'd', '2024-01-01 10:01:00', 3, 2, 1),
+			(5, 'm5', 2, 'human', 'e', '2024-01-02 10:00:00', 4, 2, 1),
+			(6, 'm6', 2, 'assistant', 'f', '2024-01-02 10:01:00', 5, 2, 0)`,
+	} {
+		if _, err := database.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	problems, err := database.CheckOrder()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []OrderProblem{{ConversationID: 2, Name: "Appended", Messages: 4, Misplaced: 3, Duplicates: 2}}
+	if !reflect.DeepEqual(problems, want) {
+		t.Errorf("got problems %+v, want %+v", problems, want)
+	}
+
+	// Repairing leaves an ordered conversation alone
+	if moved, err := RepairOrder(database, 1); err != nil || moved != 0 {
+		t.Errorf("repairing an ordered conversation moved %d messages (%v)", moved, err)
+	}
+	moved, err := RepairOrder(database, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved != 2 {
+		t.Errorf("moved %d messages, want 2", moved)
+	}
+
+	rows, err := database.Query("SELECT uuid FROM messages WHERE conversation_id = 2 ORDER BY sequence")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var order []string
+	for rows.Next() {
+		var uuid string
+		if err := rows.Scan(&uuid); err != nil {
+			t.Fatal(err)
+		}
+		order = append(order, uuid)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"m3", "m4", "m5", "m6"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("got order %v, want %v", order, want)
+	}
+
+	if problems, err := database.CheckOrder(); err != nil || len(problems) != 0 {
+		t.Errorf("expected no problems after repair, got %+v (%v)", problems, err)
+	}
+}
+
+func TestTrashRetention(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	database, err := New(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Conversation 1 was trashed long ago and conversation 2 yesterday
+	for _, stmt := range []string{
+		`INSERT INTO conversations (id, uuid, name, created_at, updated_at) VALUES (1, 'c1', 'Old', '2024-01-01', '2024-01-01'), (2, 'c2', 'Recent', '2024-01-01', '2024-01-01')`,
+		`INSERT INTO branches (id, conversation_id, name) VALUES (1, 1, 'main'), (2, 2, 'main')`,
+		`INSERT INTO messages (id, uuid, conversation_id, sender, text, created_at, branch_id, sequence) VALUES
+			(1, 'm1', 1, 'human', 'hello world', '2024-01-01', 1, 0),
+			(2, 'm2', 2, 'human', 'hello again', '2024-01-01', 2, 0)`,
+		`UPDATE conversations SET deleted_at = datetime('now', '-40 days') WHERE id = 1`,
+		`UPDATE conversations SET deleted_at = datetime('now', '-1 day') WHERE id = 2`,
+	} {
+		if _, err := database.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var matches int
+	if err := database.QueryRow("SELECT COUNT(*) FROM messages_fts WHERE messages_fts MATCH 'hello'").Scan(&matches); err != nil {
+		t.Fatal(err)
+	}
+	if matches != 0 {
+		t.Errorf("expected trashed messages to be left out of the index, got %d matches", matches)
+	}
+	if err := database.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	remaining := func(retention time.Duration) []string {
+		t.Helper()
+		TrashRetention = retention
+		database, err := New(dbPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() {
+			if err := database.Close(); err != nil {
+				t.Errorf("failed to close database: %v", err)
+			}
+		}()
+		rows, err := database.Query("SELECT uuid FROM conversations ORDER BY id")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = rows.Close() }()
+		var uuids []string
+		for rows.Next() {
+			var uuid string
+			if err := rows.Scan(&uuid); err != nil {
+				t.Fatal(err)
+			}
+			uuids = append(uuids, uuid)
+		}
+		return uuids
+	}
+	defer func(retention time.Duration) { TrashRetention = retention }(TrashRetention)
+
+	// Without a retention period nothing is purged
+	if got := remaining(0); !reflect.DeepEqual(got, []string{"c1", "c2"}) {
+		t.Errorf("got %v after opening without retention, want both conversations", got)
+	}
+	if got := remaining(30 * 24 * time.Hour); !reflect.DeepEqual(got, []string{"c2"}) {
+		t.Errorf("got %v after opening with 30 days retention, want only c2", got)
+	}
+
+	inspection, err := Inspect(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, check := range inspection.Checks {
+		if !check.OK {
+			t.Errorf("%s: %s", check.Name, check.Detail)
+		}
+	}
+}
+
+func TestCompressMessages(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	database, err := New(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := database.Close(); err != nil {
+			t.Errorf("failed to close database: %v", err)
+		}
+	}()
+
+	long := strings.Repeat("the deployment rolls out one replica at a time. ", 20)
+	for _, stmt := range []string{
+		`INSERT INTO conversations (id, uuid, name, created_at, updated_at) VALUES (1, 'c1', 'Deploys', '2024-01-01', '2024-01-01')`,
+		`INSERT INTO branches (id, conversation_id, name) VALUES (1, 1, 'main')`,
+		`INSERT INTO messages (id, uuid, conversation_id, sender, text, created_at, branch_id, sequence) VALUES
+			(1, 'm1', 1, 'human', 'short question about kubernetes', '2024-01-01', 1, 0),
+			(2, 'm2', 1, 'assistant', '` + long + `', '2024-01-01', 1, 1)`,
+	} {
+		if _, err := database.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := database.CompressMessages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Messages != 1 || result.Before != int64(len(long)) || result.After >= result.Before {
+		t.Errorf("expected only the long message to be compressed, got %+v", result)
+	}
+	var kind string
+	if err := database.QueryRow("SELECT typeof(text) FROM messages WHERE id = 2").Scan(&kind); err != nil {
+		t.Fatal(err)
+	}
+	if kind != "blob" {
+		t.Errorf("expected the long message to be stored compressed, got %s", kind)
+	}
+
+	// Reads, search and snippets see the plain text
+	var text, snippet string
+	if err := database.QueryRow("SELECT message_text(text) FROM messages WHERE id = 2").Scan(&text); err != nil {
+		t.Fatal(err)
+	}
+	if text != long {
+		t.Errorf("expected the text back unchanged, got %q", text)
+	}
+	if err := database.QueryRow(`SELECT snippet(messages_fts, 0, '[', ']', '...', 4)
+		FROM messages_fts WHERE messages_fts MATCH 'replica'`).Scan(&snippet); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(snippet, "[replica]") {
+		t.Errorf("expected a snippet of the plain text, got %q", snippet)
+	}
+
+	// Imports see the setting
+	enabled, err := CompressionEnabled(database.conn)
+	if err != nil || !enabled {
+		t.Errorf("expected compression to be turned on, got %v, %v", enabled, err)
+	}
+	if _, ok := StoredText(long, true).([]byte); !ok {
+		t.Error("expected long text to be stored compressed")
+	}
+	if _, ok := StoredText("hi", true).(string); !ok {
+		t.Error("expected short text to be stored as it is")
+	}
+
+	// Editing a compressed message keeps the index in sync
+	if _, err := database.Exec("UPDATE messages SET text = 'nothing to see' WHERE id = 2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.Exec("UPDATE messages SET text = ? WHERE id = 2", CompressText(long)); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err = database.DecompressMessages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Messages != 1 || result.After != int64(len(long)) {
+		t.Errorf("expected the long message to be decompressed, got %+v", result)
+	}
+	if enabled, _ := CompressionEnabled(database.conn); enabled {
+		t.Error("expected compression to be turned off")
+	}
+	for _, check := range []HealthCheck{database.checkFTS("messages_fts"), database.checkFTS("messages_fts_code")} {
+		if !check.OK {
+			t.Errorf("%s: %s", check.Name, check.Detail)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// HealthCheck is the outcome of one check of a database's consistency
+type HealthCheck struct {
+	Name   string
+	OK     bool
+	Detail string // what is wrong, empty when OK
+}
+
+// Inspection describes a database file without requiring its schema version
+// to match this build's
+type Inspection struct {
+	Path          string
+	Size          int64 // bytes, including the write-ahead log
+	Version       int   // schema version of the database, 0 if not created yet
+	Conversations int
+	Messages      int
+	Checks        []HealthCheck // run only when the schema version matches
+}
+
+// orphanChecks count rows whose parent row is gone, which foreign keys
+// should prevent but older versions and manual edits don't always have
+var orphanChecks = []struct {
+	what  string
+	query string
+}{
+	{"messages without a conversation", `SELECT COUNT(*) FROM messages WHERE conversation_id NOT IN (SELECT id FROM conversations)`},
+	{"messages without a branch", `SELECT COUNT(*) FROM messages WHERE branch_id NOT IN (SELECT id FROM branches)`},
+	{"branches without a conversation", `SELECT COUNT(*) FROM branches WHERE conversation_id NOT IN (SELECT id FROM conversations)`},
+	{"code blocks without a message", `SELECT COUNT(*) FROM code_blocks WHERE message_id NOT IN (SELECT id FROM messages)`},
+	{"aliases without a conversation", `SELECT COUNT(*) FROM conversation_aliases WHERE conversation_id NOT IN (SELECT id FROM conversations)`},
+	{"access log entries without a conversation", `SELECT COUNT(*) FROM access_log WHERE conversation_id IS NOT NULL AND conversation_id NOT IN (SELECT id FROM conversations)`},
+	{"ratings without a message", `SELECT COUNT(*) FROM message_ratings WHERE message_id NOT IN (SELECT id FROM messages)`},
+	{"split records without a conversation", `SELECT COUNT(*) FROM conversation_splits WHERE conversation_id NOT IN (SELECT id FROM conversations) OR source_id NOT IN (SELECT id FROM conversations)`},
+	{"continuation links without a conversation", `SELECT COUNT(*) FROM conversation_continuations WHERE conversation_id NOT IN (SELECT id FROM conversations) OR previous_id NOT IN (SELECT id FROM conversations)`},
+}
+
+// Inspect reports on the database at dbPath, which must exist, and checks
+// its consistency if its schema matches this build: SQLite's own integrity
+// check, whether the full-text indexes match the messages, and rows left
+// behind by deleted parents. Nothing is changed.
+func Inspect(dbPath string) (*Inspection, error) {
+	info, err := os.Stat(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("no database at %s: %w", dbPath, err)
+	}
+	result := &Inspection{Path: dbPath, Size: info.Size()}
+	if wal, err := os.Stat(dbPath + "-wal"); err == nil {
+		result.Size += wal.Size()
+	}
+
+	conn, err := open(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	db := &DB{conn: conn, path: dbPath}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+		}
+	}()
+
+	if result.Version, err = db.schemaVersion(); err != nil {
+		return nil, err
+	}
+	if result.Version != SchemaVersion {
+		return result, nil
+	}
+
+	if err := conn.QueryRow("SELECT COUNT(*) FROM conversations").Scan(&result.Conversations); err != nil {
+		return nil, fmt.Errorf("failed to count conversations: %w", err)
+	}
+	if err := conn.QueryRow("SELECT COUNT(*) FROM messages").Scan(&result.Messages); err != nil {
+		return nil, fmt.Errorf("failed to count messages: %w", err)
+	}
+
+	result.Checks = append(result.Checks, db.checkIntegrity())
+	for _, table := range []string{"messages_fts", "messages_fts_code"} {
+		result.Checks = append(result.Checks, db.checkFTS(table))
+	}
+	result.Checks = append(result.Checks, db.checkOrphans())
+
+	return result, nil
+}
+
+// checkIntegrity runs SQLite's quick check of the file's structure
+func (db *DB) checkIntegrity() HealthCheck {
+	check := HealthCheck{Name: "database integrity"}
+	rows, err := db.conn.Query("PRAGMA quick_check")
+	if err != nil {
+		check.Detail = err.Error()
+		return check
+	}
+	var problems []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			_ = rows.Close()
+			check.Detail = err.Error()
+			return check
+		}
+		if line != "ok" {
+			problems = append(problems, line)
+		}
+	}
+	if err := rows.Close(); err != nil {
+		check.Detail = err.Error()
+		return check
+	}
+	check.OK = len(problems) == 0
+	check.Detail = strings.Join(problems, "; ")
+	return check
+}
+
+// checkFTS compares a full-text index with the messages it indexes
+func (db *DB) checkFTS(table string) HealthCheck {
+	check := HealthCheck{Name: fmt.Sprintf("full-text index %s in sync with messages", table)}
+	_, err := db.conn.Exec(fmt.Sprintf("INSERT INTO %s(%s, rank) VALUES ('integrity-check', 1)", table, table))
+	if err != nil {
+		check.Detail = err.Error()
+		return check
+	}
+	check.OK = true
+	return check
+}
+
+// checkOrphans counts rows left behind by deleted parents
+func (db *DB) checkOrphans() HealthCheck {
+	check := HealthCheck{Name: "no orphaned rows"}
+	var problems []string
+	for _, orphans := range orphanChecks {
+		var count int
+		if err := db.conn.QueryRow(orphans.query).Scan(&count); err != nil {
+			problems = append(problems, fmt.Sprintf("failed to count %s: %v", orphans.what, err))
+		} else if count > 0 {
+			problems = append(problems, fmt.Sprintf("%s: %d", orphans.what, count))
+		}
+	}
+	check.OK = len(problems) == 0
+	check.Detail = strings.Join(problems, "; ")
+	return check
+}
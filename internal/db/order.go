@@ -0,0 +1,218 @@
+package db
+
+import (
+	"container/heap"
+	"database/sql"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Queryer runs statements on the database or in a transaction
+type Queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// orderedMessage is what deciding the order of a message needs
+type orderedMessage struct {
+	id        int64
+	parentID  sql.NullInt64
+	createdAt time.Time
+	sequence  int
+}
+
+// OrderProblem is a conversation whose message sequence numbers disagree with
+// the order its messages were written in
+type OrderProblem struct {
+	ConversationID int64
+	Name           string
+	Messages       int
+	Misplaced      int // messages not where their parent links and times put them
+	Duplicates     int // messages sharing a sequence number with another
+}
+
+// CheckOrder lists the conversations with inconsistent message ordering
+func (db *DB) CheckOrder() ([]OrderProblem, error) {
+	rows, err := db.conn.Query(`
+		SELECT c.id, c.name, m.id, m.parent_id, m.created_at, m.sequence
+		FROM messages m
+		JOIN conversations c ON m.conversation_id = c.id
+		ORDER BY c.id, m.sequence, m.created_at, m.id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query messages: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close rows: %v\n", err)
+		}
+	}()
+
+	var problems []OrderProblem
+	var current OrderProblem
+	var messages []orderedMessage
+	flush := func() {
+		if len(messages) == 0 {
+			return
+		}
+		current.Messages = len(messages)
+		current.Misplaced, current.Duplicates = orderProblems(messages)
+		if current.Misplaced > 0 || current.Duplicates > 0 {
+			problems = append(problems, current)
+		}
+		messages = messages[:0]
+	}
+	for rows.Next() {
+		var convID int64
+		var name string
+		var m orderedMessage
+		if err := rows.Scan(&convID, &name, &m.id, &m.parentID, &m.createdAt, &m.sequence); err != nil {
+			return nil, fmt.Errorf("failed to scan message: %w", err)
+		}
+		if convID != current.ConversationID {
+			flush()
+			current = OrderProblem{ConversationID: convID, Name: name}
+		}
+		messages = append(messages, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+
+	return problems, nil
+}
+
+// RepairOrder renumbers the messages of a conversation in the order their
+// parent links and times say they were written, if their sequence numbers
+// disagree with it, and returns how many messages it moved. q is the
+// database or a transaction on it.
+func RepairOrder(q Queryer, convID int64) (int, error) {
+	rows, err := q.Query(`
+		SELECT id, parent_id, created_at, sequence
+		FROM messages
+		WHERE conversation_id = ?
+		ORDER BY sequence, created_at, id
+	`, convID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query messages: %w", err)
+	}
+	var messages []orderedMessage
+	for rows.Next() {
+		var m orderedMessage
+		if err := rows.Scan(&m.id, &m.parentID, &m.createdAt, &m.sequence); err != nil {
+			_ = rows.Close()
+			return 0, fmt.Errorf("failed to scan message: %w", err)
+		}
+		messages = append(messages, m)
+	}
+	if err := rows.Close(); err != nil {
+		return 0, err
+	}
+
+	if misplaced, duplicates := orderProblems(messages); misplaced == 0 && duplicates == 0 {
+		return 0, nil
+	}
+
+	moved := 0
+	for sequence, m := range deriveOrder(messages) {
+		if m.sequence == sequence {
+			continue
+		}
+		if _, err := q.Exec("UPDATE messages SET sequence = ? WHERE id = ?", sequence, m.id); err != nil {
+			return moved, fmt.Errorf("failed to renumber message %d: %w", m.id, err)
+		}
+		moved++
+	}
+	return moved, nil
+}
+
+// orderProblems compares messages, in sequence order, with the order they
+// were written in
+func orderProblems(messages []orderedMessage) (misplaced, duplicates int) {
+	for i, m := range deriveOrder(messages) {
+		if m.id != messages[i].id {
+			misplaced++
+		}
+		if i > 0 && messages[i].sequence == messages[i-1].sequence {
+			duplicates++
+		}
+	}
+	return misplaced, duplicates
+}
+
+// deriveOrder orders messages so that every message comes after its parent
+// and otherwise by time, the existing sequence and ID breaking ties. A
+// message whose parent isn't among them starts its own thread.
+func deriveOrder(messages []orderedMessage) []orderedMessage {
+	index := make(map[int64]int, len(messages))
+	for i, m := range messages {
+		index[m.id] = i
+	}
+	children := make(map[int64][]int)
+	ready := &messageHeap{messages: messages}
+	for i, m := range messages {
+		if _, ok := index[m.parentID.Int64]; m.parentID.Valid && ok && m.parentID.Int64 != m.id {
+			children[m.parentID.Int64] = append(children[m.parentID.Int64], i)
+		} else {
+			ready.order = append(ready.order, i)
+		}
+	}
+	heap.Init(ready)
+
+	ordered := make([]orderedMessage, 0, len(messages))
+	placed := make([]bool, len(messages))
+	for ready.Len() > 0 {
+		i := heap.Pop(ready).(int)
+		placed[i] = true
+		ordered = append(ordered, messages[i])
+		for _, child := range children[messages[i].id] {
+			heap.Push(ready, child)
+		}
+	}
+
+	// Messages in a cycle of parent links are never ready; keep them last
+	if len(ordered) < len(messages) {
+		rest := &messageHeap{messages: messages}
+		for i := range messages {
+			if !placed[i] {
+				rest.order = append(rest.order, i)
+			}
+		}
+		heap.Init(rest)
+		for rest.Len() > 0 {
+			ordered = append(ordered, messages[heap.Pop(rest).(int)])
+		}
+	}
+	return ordered
+}
+
+// messageHeap holds indexes into messages, earliest message first
+type messageHeap struct {
+	messages []orderedMessage
+	order    []int
+}
+
+func (h *messageHeap) Len() int { return len(h.order) }
+
+func (h *messageHeap) Less(i, j int) bool {
+	a, b := h.messages[h.order[i]], h.messages[h.order[j]]
+	if !a.createdAt.Equal(b.createdAt) {
+		return a.createdAt.Before(b.createdAt)
+	}
+	if a.sequence != b.sequence {
+		return a.sequence < b.sequence
+	}
+	return a.id < b.id
+}
+
+func (h *messageHeap) Swap(i, j int) { h.order[i], h.order[j] = h.order[j], h.order[i] }
+
+func (h *messageHeap) Push(x interface{}) { h.order = append(h.order, x.(int)) }
+
+func (h *messageHeap) Pop() interface{} {
+	last := h.order[len(h.order)-1]
+	h.order = h.order[:len(h.order)-1]
+	return last
+}
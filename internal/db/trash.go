@@ -0,0 +1,68 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// TrashRetention is how long trashed conversations are kept before they are
+// purged for good, set from trash.retention_days. Zero or less keeps them
+// until the trash is emptied.
+var TrashRetention = 30 * 24 * time.Hour
+
+// trashTimeLayout is how SQLite's CURRENT_TIMESTAMP writes deleted_at
+const trashTimeLayout = "2006-01-02 15:04:05"
+
+// PurgeTrash permanently deletes the conversations moved to the trash up to
+// cutoff and returns how many there were. Their tombstones stay, so imports
+// keep skipping them.
+func (db *DB) PurgeTrash(cutoff time.Time) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	trashed := "SELECT id FROM conversations WHERE deleted_at IS NOT NULL AND deleted_at <= ?"
+	upTo := cutoff.UTC().Format(trashTimeLayout)
+
+	// Trashed messages are already out of the search indexes, so the delete
+	// trigger leaves the indexes alone while the conversation is still
+	// marked; branches, code blocks, edits and ratings cascade
+	if _, err := tx.Exec("DELETE FROM messages WHERE conversation_id IN ("+trashed+")", upTo); err != nil {
+		return 0, fmt.Errorf("failed to delete trashed messages: %w", err)
+	}
+	result, err := tx.Exec("DELETE FROM conversations WHERE id IN ("+trashed+")", upTo)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete trashed conversations: %w", err)
+	}
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count purged conversations: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit: %w", err)
+	}
+	return purged, nil
+}
+
+// purgeExpiredTrash applies TrashRetention, checking first so opening the
+// database only takes the write lock when there is something to purge
+func (db *DB) purgeExpiredTrash() error {
+	if TrashRetention <= 0 {
+		return nil
+	}
+	cutoff := time.Now().Add(-TrashRetention)
+
+	var expired int
+	if err := db.QueryRow("SELECT COUNT(*) FROM conversations WHERE deleted_at IS NOT NULL AND deleted_at <= ?",
+		cutoff.UTC().Format(trashTimeLayout)).Scan(&expired); err != nil {
+		return fmt.Errorf("failed to check the trash: %w", err)
+	}
+	if expired == 0 {
+		return nil
+	}
+	_, err := db.PurgeTrash(cutoff)
+	return err
+}
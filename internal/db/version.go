@@ -0,0 +1,236 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// VersionError reports a database whose schema version differs from the one
+// this build of shannon uses
+type VersionError struct {
+	Path    string
+	Version int // schema version of the database
+}
+
+// Newer reports whether the database was written by a newer shannon
+func (e *VersionError) Newer() bool {
+	return e.Version > SchemaVersion
+}
+
+func (e *VersionError) Error() string {
+	if e.Newer() {
+		return fmt.Sprintf("database %s has schema version %d, but this version of shannon only supports up to %d.\n"+
+			"Upgrade shannon, or run `shannon db downgrade-check` to see how to use it with this version.",
+			e.Path, e.Version, SchemaVersion)
+	}
+	return fmt.Sprintf("database %s has schema version %d, but this version of shannon needs version %d.\n"+
+		"Run `shannon db upgrade` to migrate it; a backup is made first.",
+		e.Path, e.Version, SchemaVersion)
+}
+
+// UpgradeResult describes a schema upgrade
+type UpgradeResult struct {
+	From   int
+	To     int
+	Backup string // copy of the database before the upgrade, if one was made
+}
+
+// Upgrade migrates the database at dbPath to the schema version of this
+// build. Unless noBackup is set, a copy of the database is saved next to it
+// first, named after the version it had.
+func Upgrade(dbPath string, noBackup bool) (*UpgradeResult, error) {
+	conn, err := open(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	db := &DB{conn: conn, path: dbPath}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+		}
+	}()
+
+	version, err := db.schemaVersion()
+	if err != nil {
+		return nil, err
+	}
+	result := &UpgradeResult{From: version, To: SchemaVersion}
+	switch {
+	case version == 0:
+		return nil, fmt.Errorf("database %s has not been created yet; import an export first", dbPath)
+	case version > SchemaVersion:
+		return nil, &VersionError{Path: dbPath, Version: version}
+	case version == SchemaVersion:
+		return result, nil
+	}
+
+	if !noBackup {
+		result.Backup = fmt.Sprintf("%s.schema-v%d-%s.bak", dbPath, version, time.Now().Format("20060102-150405"))
+		if _, err := conn.Exec("VACUUM INTO ?", result.Backup); err != nil {
+			return nil, fmt.Errorf("failed to back up database: %w", err)
+		}
+	}
+
+	if err := db.migrate(); err != nil {
+		return nil, fmt.Errorf("failed to migrate schema: %w", err)
+	}
+	return result, nil
+}
+
+// Compatibility compares a database with the schema of this build
+type Compatibility struct {
+	Version int      // schema version of the database
+	Missing []string // tables, columns and indexes this build uses that the database lacks
+	Extra   []string // tables, columns and indexes unknown to this build
+	Backups []string // backups of the database from before an upgrade past this build's version
+}
+
+// Compatible reports whether the database has everything this build reads
+func (c *Compatibility) Compatible() bool {
+	return len(c.Missing) == 0
+}
+
+// CheckDowngrade compares the database at dbPath with the schema this build
+// creates, for deciding what to do with a database written by a newer
+// shannon. Backups that `shannon db upgrade` made when it moved the database
+// past this build's version are listed as a way back.
+func CheckDowngrade(dbPath string) (*Compatibility, error) {
+	conn, err := open(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	db := &DB{conn: conn, path: dbPath}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+		}
+	}()
+
+	version, err := db.schemaVersion()
+	if err != nil {
+		return nil, err
+	}
+	if version == 0 {
+		return nil, fmt.Errorf("database %s has not been created yet", dbPath)
+	}
+
+	// The schema this build would create, from an empty database
+	expectedConn, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open reference database: %w", err)
+	}
+	expectedConn.SetMaxOpenConns(1)
+	expected := &DB{conn: expectedConn}
+	defer func() {
+		if err := expectedConn.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close reference database: %v\n", err)
+		}
+	}()
+	if err := expected.initSchema(); err != nil {
+		return nil, fmt.Errorf("failed to create reference schema: %w", err)
+	}
+	if err := expected.migrate(); err != nil {
+		return nil, fmt.Errorf("failed to create reference schema: %w", err)
+	}
+
+	want, err := expected.schemaObjects()
+	if err != nil {
+		return nil, err
+	}
+	have, err := db.schemaObjects()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Compatibility{Version: version}
+	for object := range want {
+		if !have[object] {
+			c.Missing = append(c.Missing, object)
+		}
+	}
+	for object := range have {
+		if !want[object] {
+			c.Extra = append(c.Extra, object)
+		}
+	}
+	sort.Strings(c.Missing)
+	sort.Strings(c.Extra)
+
+	backups, err := filepath.Glob(fmt.Sprintf("%s.schema-v%d-*.bak", dbPath, SchemaVersion))
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	c.Backups = backups
+
+	return c, nil
+}
+
+// schemaObjects lists the tables, columns, indexes and triggers of the
+// database, as "table messages", "column messages.text" and so on
+func (db *DB) schemaObjects() (map[string]bool, error) {
+	rows, err := db.conn.Query(`
+		SELECT type, name, COALESCE(sql, '') FROM sqlite_master
+		WHERE type IN ('table', 'index', 'trigger') AND name NOT LIKE 'sqlite_%'
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+
+	objects := make(map[string]bool)
+	var tables, virtual []string
+	for rows.Next() {
+		var kind, name, definition string
+		if err := rows.Scan(&kind, &name, &definition); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("failed to read schema: %w", err)
+		}
+		objects[kind+" "+name] = true
+		if kind == "table" {
+			tables = append(tables, name)
+		}
+		if strings.HasPrefix(strings.ToUpper(definition), "CREATE VIRTUAL TABLE") {
+			virtual = append(virtual, name)
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	// The shadow tables of full-text indexes are an implementation detail
+	shadow := make(map[string]bool)
+	for _, name := range virtual {
+		for _, suffix := range []string{"data", "idx", "content", "docsize", "config"} {
+			shadow[name+"_"+suffix] = true
+			delete(objects, "table "+name+"_"+suffix)
+		}
+	}
+
+	for _, table := range tables {
+		if shadow[table] {
+			continue
+		}
+		columns, err := db.conn.Query("SELECT name FROM pragma_table_info(?)", table)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+		}
+		for columns.Next() {
+			var column string
+			if err := columns.Scan(&column); err != nil {
+				_ = columns.Close()
+				return nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+			}
+			objects["column "+table+"."+column] = true
+		}
+		if err := columns.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	return objects, nil
+}
@@ -0,0 +1,283 @@
+// Package demo makes up realistic conversations for demos and benchmarks, so
+// shannon can be shown and measured without a real archive.
+package demo
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+// Options control what Generate makes up
+type Options struct {
+	Conversations int
+	Seed          int64     // the same seed makes up the same conversations
+	End           time.Time // the conversations are spread over the year before
+}
+
+// Chances, out of 100, of a conversation or an answer having each feature
+const (
+	artifactChance = 30 // an answer with a code block has it as an artifact
+	codeChance     = 60 // an answer has a code block
+	branchChance   = 15 // a conversation has an edited question with its own answer
+	starChance     = 5  // a conversation is starred
+)
+
+// topic is a subject conversations are about
+type topic struct {
+	language string // of its code
+	subject  string
+	titles   []string
+	asks     []string
+	code     []string
+}
+
+var topics = []topic{
+	{
+		language: "python", subject: "pandas",
+		titles: []string{"Cleaning a CSV with pandas", "Pandas groupby question", "Merging dataframes"},
+		asks: []string{
+			"How do I drop rows where the email column is empty in pandas?",
+			"My groupby is returning a MultiIndex, how do I flatten it?",
+			"What's the fastest way to merge two dataframes on a date range?",
+			"Can you explain why this raises SettingWithCopyWarning?",
+		},
+		code: []string{
+			"import pandas as pd\n\ndf = pd.read_csv(\"users.csv\")\ndf = df.dropna(subset=[\"email\"])\ndf[\"email\"] = df[\"email\"].str.lower()\nprint(df.head())",
+			"summary = (\n    df.groupby([\"region\", \"month\"])\n      .agg(total=(\"amount\", \"sum\"), orders=(\"id\", \"count\"))\n      .reset_index()\n)",
+		},
+	},
+	{
+		language: "go", subject: "HTTP clients",
+		titles: []string{"Go HTTP client timeouts", "Retrying requests in Go", "Context cancellation"},
+		asks: []string{
+			"How should I set timeouts on an http.Client in Go?",
+			"What's a good way to retry requests with exponential backoff?",
+			"Why does my goroutine leak when the request is cancelled?",
+			"Should I reuse the http.Client or make one per request?",
+		},
+		code: []string{
+			"client := &http.Client{\n\tTimeout: 10 * time.Second,\n\tTransport: &http.Transport{\n\t\tMaxIdleConnsPerHost: 10,\n\t\tIdleConnTimeout:     90 * time.Second,\n\t},\n}",
+			"func retry(ctx context.Context, attempts int, fn func() error) error {\n\tdelay := 100 * time.Millisecond\n\tfor i := 0; ; i++ {\n\t\terr := fn()\n\t\tif err == nil || i == attempts-1 {\n\t\t\treturn err\n\t\t}\n\t\tselect {\n\t\tcase <-time.After(delay):\n\t\t\tdelay *= 2\n\t\tcase <-ctx.Done():\n\t\t\treturn ctx.Err()\n\t\t}\n\t}\n}",
+		},
+	},
+	{
+		language: "yaml", subject: "Kubernetes",
+		titles: []string{"Kubernetes deployment", "Helm chart values", "Pod keeps restarting"},
+		asks: []string{
+			"My pod is in CrashLoopBackOff, how do I find out why?",
+			"How do I set resource limits for this deployment?",
+			"What's the difference between a readiness and a liveness probe?",
+			"Can you write a deployment for a service with three replicas?",
+		},
+		code: []string{
+			"apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: api\nspec:\n  replicas: 3\n  selector:\n    matchLabels:\n      app: api\n  template:\n    metadata:\n      labels:\n        app: api\n    spec:\n      containers:\n        - name: api\n          image: example/api:1.4.2\n          resources:\n            limits:\n              memory: 256Mi\n              cpu: 500m",
+		},
+	},
+	{
+		language: "sql", subject: "Postgres",
+		titles: []string{"Slow Postgres query", "Postgres indexes", "Window functions in SQL"},
+		asks: []string{
+			"This query takes 8 seconds, which index would help?",
+			"How do I get the latest order for each customer?",
+			"When should I use a partial index in Postgres?",
+			"Can you explain what EXPLAIN ANALYZE is telling me here?",
+		},
+		code: []string{
+			"SELECT DISTINCT ON (customer_id) customer_id, id, created_at\nFROM orders\nORDER BY customer_id, created_at DESC;",
+			"CREATE INDEX CONCURRENTLY idx_orders_pending\n    ON orders (created_at)\n    WHERE status = 'pending';",
+		},
+	},
+	{
+		language: "typescript", subject: "React",
+		titles: []string{"React state question", "Debouncing a search box", "useEffect running twice"},
+		asks: []string{
+			"Why does my useEffect run twice in development?",
+			"How do I debounce a search input in React?",
+			"Should this state live in the parent or the child component?",
+			"How can I type the props of a generic list component?",
+		},
+		code: []string{
+			"function useDebounced<T>(value: T, delay = 300): T {\n  const [debounced, setDebounced] = useState(value);\n  useEffect(() => {\n    const id = setTimeout(() => setDebounced(value), delay);\n    return () => clearTimeout(id);\n  }, [value, delay]);\n  return debounced;\n}",
+		},
+	},
+	{
+		language: "bash", subject: "shell scripting",
+		titles: []string{"Bash script for backups", "Finding large files", "Shell loop over filenames"},
+		asks: []string{
+			"How do I loop over files with spaces in their names in bash?",
+			"Can you write a script that backs up a directory and keeps the last 7 copies?",
+			"What does set -euo pipefail actually do?",
+		},
+		code: []string{
+			"#!/usr/bin/env bash\nset -euo pipefail\n\nsrc=${1:?usage: backup.sh DIR}\ndest=\"$HOME/backups/$(date +%F)\"\nmkdir -p \"$dest\"\nrsync -a --delete \"$src/\" \"$dest/\"\nls -1d \"$HOME\"/backups/* | head -n -7 | xargs -r rm -rf",
+		},
+	},
+}
+
+// General text for questions and answers whatever the topic
+var (
+	followUps = []string{
+		"Thanks, that works. What about when the input is empty?",
+		"Can you explain the second part in more detail?",
+		"That's close, but I'm getting an error on the last line.",
+		"How would I test this?",
+		"Is there a simpler way to do it?",
+		"What are the trade-offs compared to the approach you mentioned first?",
+		"continue",
+	}
+	openings = []string{
+		"Good question. The short answer is that it depends on how the %s code is structured, but here's the usual approach.",
+		"There are a few ways to handle this with %s. The one I'd recommend is below.",
+		"This comes up a lot with %s. The cause is usually one of two things.",
+		"Here's how I'd approach it in %s, step by step.",
+	}
+	explanations = []string{
+		"The key point is to keep the expensive work out of the hot path and do it once up front.",
+		"Note that this keeps the original data untouched, which makes it easier to debug later.",
+		"If you run into performance problems, measure first: the bottleneck is often not where you'd expect.",
+		"You could also do this with a library, but the standard approach is easy enough to read and maintain.",
+		"Be careful with edge cases like empty input, duplicates and time zones.",
+		"Each step is small enough to test on its own, which is worth doing before wiring it all together.",
+	}
+	modelNames = []string{"claude-3-5-sonnet-20241022", "claude-3-opus-20240229", "claude-3-5-haiku-20241022"}
+)
+
+// Generate makes up conversations in Claude's export format, spread over the
+// year before opts.End, with varied lengths, code blocks, artifacts and
+// branches
+func Generate(opts Options) []models.ClaudeConversation {
+	r := rand.New(rand.NewSource(opts.Seed))
+	end := opts.End
+	if end.IsZero() {
+		end = time.Now()
+	}
+	start := end.AddDate(-1, 0, 0)
+	span := end.Sub(start)
+
+	conversations := make([]models.ClaudeConversation, opts.Conversations)
+	for i := range conversations {
+		created := start.Add(time.Duration(r.Int63n(int64(span))))
+		conversations[i] = generateConversation(r, i, created)
+	}
+	// Oldest first, as in Claude's exports; the timestamps sort as text
+	sort.Slice(conversations, func(i, j int) bool {
+		return conversations[i].CreatedAt < conversations[j].CreatedAt
+	})
+	return conversations
+}
+
+// generateConversation makes up conversation n about a random topic
+func generateConversation(r *rand.Rand, n int, created time.Time) models.ClaudeConversation {
+	t := topics[r.Intn(len(topics))]
+	model := modelNames[r.Intn(len(modelNames))]
+	conv := models.ClaudeConversation{
+		UUID:      uuid(r),
+		Name:      pick(r, t.titles),
+		CreatedAt: timestamp(created),
+	}
+	if r.Intn(100) < starChance {
+		starred := true
+		conv.Starred = &starred
+	}
+
+	// Mostly short conversations, some long working sessions
+	exchanges := 1 + r.Intn(4)
+	if r.Intn(10) == 0 {
+		exchanges += 10 + r.Intn(30)
+	}
+
+	at := created
+	var parent *string
+	var lastQuestion models.ClaudeChatMessage
+	for i := 0; i < exchanges; i++ {
+		ask := pick(r, t.asks)
+		if i > 0 && r.Intn(3) > 0 {
+			ask = pick(r, followUps)
+		}
+		question := message(r, "human", ask, at, parent)
+		at = at.Add(time.Duration(20+r.Intn(60)) * time.Second)
+		answer := message(r, "assistant", answerText(r, t, n, i), at, &question.UUID)
+		answer.Model = model
+		conv.ChatMessages = append(conv.ChatMessages, question, answer)
+		parent = &answer.UUID
+		lastQuestion = question
+		at = at.Add(time.Duration(1+r.Intn(15)) * time.Minute)
+		// Some sessions go on the next day
+		if r.Intn(20) == 0 {
+			at = at.Add(time.Duration(12+r.Intn(48)) * time.Hour)
+		}
+	}
+
+	// An edited question starts a branch with its own answer, after the main
+	// thread so the main thread keeps the first replies
+	if r.Intn(100) < branchChance && lastQuestion.ParentID != nil {
+		edited := message(r, "human", lastQuestion.Text+" (I should have said: it needs to work with large inputs too)", at, lastQuestion.ParentID)
+		at = at.Add(time.Minute)
+		reply := message(r, "assistant", answerText(r, t, n, exchanges), at, &edited.UUID)
+		reply.Model = model
+		conv.ChatMessages = append(conv.ChatMessages, edited, reply)
+	}
+
+	conv.UpdatedAt = conv.ChatMessages[len(conv.ChatMessages)-1].CreatedAt
+	return conv
+}
+
+// answerText makes up answer i of conversation n, sometimes with code, which
+// may be an artifact
+func answerText(r *rand.Rand, t topic, n, i int) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(pick(r, openings), t.subject))
+	sb.WriteString("\n\n")
+	for p, sentences := 0, 1+r.Intn(3); p < sentences; p++ {
+		sb.WriteString(pick(r, explanations))
+		sb.WriteString(" ")
+	}
+	if r.Intn(100) < codeChance {
+		code := pick(r, t.code)
+		if r.Intn(100) < artifactChance {
+			sb.WriteString(fmt.Sprintf("\n\n<antArtifact identifier=\"%s-%d-%d\" type=\"application/vnd.ant.code\" language=\"%s\" title=\"%s example\">\n%s\n</antArtifact>",
+				strings.ReplaceAll(t.subject, " ", "-"), n+1, i+1, t.language, t.subject, code))
+		} else {
+			sb.WriteString(fmt.Sprintf("\n\n```%s\n%s\n```", t.language, code))
+		}
+		sb.WriteString("\n\n")
+		sb.WriteString(pick(r, explanations))
+	}
+	return sb.String()
+}
+
+// message makes up a message from sender with text
+func message(r *rand.Rand, sender, text string, at time.Time, parent *string) models.ClaudeChatMessage {
+	return models.ClaudeChatMessage{
+		UUID:      uuid(r),
+		Sender:    sender,
+		Text:      text,
+		Content:   []models.ClaudeMessageContent{{Type: "text", Text: text}},
+		CreatedAt: timestamp(at),
+		ParentID:  parent,
+	}
+}
+
+// uuid makes up a random version 4 UUID
+func uuid(r *rand.Rand) string {
+	var b [16]byte
+	r.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// timestamp writes a time the way Claude's exports do
+func timestamp(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000000Z")
+}
+
+// pick returns a random one of options
+func pick(r *rand.Rand, options []string) string {
+	return options[r.Intn(len(options))]
+}
@@ -0,0 +1,51 @@
+package demo
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerate(t *testing.T) {
+	opts := Options{Conversations: 200, Seed: 42, End: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)}
+	conversations := Generate(opts)
+	if len(conversations) != 200 {
+		t.Fatalf("expected 200 conversations, got %d", len(conversations))
+	}
+	if !reflect.DeepEqual(conversations, Generate(opts)) {
+		t.Error("expected the same seed to make up the same conversations")
+	}
+
+	var artifacts, branches int
+	for i, conv := range conversations {
+		if i > 0 && conv.CreatedAt < conversations[i-1].CreatedAt {
+			t.Fatalf("expected conversations oldest first, got %s after %s", conv.CreatedAt, conversations[i-1].CreatedAt)
+		}
+		seen := make(map[string]bool)
+		children := make(map[string]int)
+		for _, msg := range conv.ChatMessages {
+			if msg.ParentID != nil {
+				if !seen[*msg.ParentID] {
+					t.Fatalf("message %s comes before its parent", msg.UUID)
+				}
+				children[*msg.ParentID]++
+			}
+			seen[msg.UUID] = true
+			if strings.Contains(msg.Text, "<antArtifact") {
+				artifacts++
+			}
+		}
+		for _, n := range children {
+			if n > 1 {
+				branches++
+			}
+		}
+		if conv.ChatMessages[0].Sender != "human" || conv.UpdatedAt != conv.ChatMessages[len(conv.ChatMessages)-1].CreatedAt {
+			t.Errorf("conversation %s doesn't start with a question and end when its last message was sent", conv.UUID)
+		}
+	}
+	if artifacts == 0 || branches == 0 {
+		t.Errorf("expected some artifacts and branches, got %d and %d", artifacts, branches)
+	}
+}
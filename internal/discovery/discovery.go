@@ -10,6 +10,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/neilberkman/shannon/internal/imports"
 	"github.com/neilberkman/shannon/internal/models"
 	"github.com/neilberkman/shannon/pkg/platform"
 )
@@ -164,18 +165,32 @@ func (s *Scanner) scanDirectory(dir string) ([]*ExportFile, error) {
 		if entry.IsDir() {
 			// Check if this is a data export directory (data-YYYY-MM-DD-HH-MM-SS format)
 			if strings.HasPrefix(name, "data-20") || strings.HasPrefix(name, "data-19") {
-				// Look for conversations.json inside this directory
-				subPath := filepath.Join(dir, name, "conversations.json")
-				if info, err := os.Stat(subPath); err == nil && !info.IsDir() {
-					export := &ExportFile{
-						Path:    subPath,
-						Size:    info.Size(),
-						ModTime: info.ModTime(),
+				// Look for conversations.json inside this directory, possibly compressed
+				for _, file := range conversationsFiles {
+					subPath := filepath.Join(dir, name, file)
+					if info, err := os.Stat(subPath); err == nil && !info.IsDir() {
+						export := &ExportFile{
+							Path:    subPath,
+							Size:    info.Size(),
+							ModTime: info.ModTime(),
+						}
+						export.IsValid, export.ErrorMessage, export.Preview = s.validateAndPreview(subPath)
+						exports = append(exports, export)
 					}
-					export.IsValid, export.ErrorMessage, export.Preview = s.validateAndPreview(subPath)
-					exports = append(exports, export)
 				}
 			}
+		} else if isCompressedJSON(name) {
+			// Compressed exports like conversations.json.gz
+			path := filepath.Join(dir, name)
+			if info, err := entry.Info(); err == nil && s.isLikelyClaudeExport(path, info) {
+				export := &ExportFile{
+					Path:    path,
+					Size:    info.Size(),
+					ModTime: info.ModTime(),
+				}
+				export.IsValid, export.ErrorMessage, export.Preview = s.validateAndPreview(path)
+				exports = append(exports, export)
+			}
 		} else {
 			// Check if this is a zip file that might contain Claude exports
 			if strings.HasSuffix(strings.ToLower(name), ".zip") &&
@@ -192,15 +207,33 @@ func (s *Scanner) scanDirectory(dir string) ([]*ExportFile, error) {
 	return exports, nil
 }
 
+// conversationsFiles are the names an export's conversations file may have
+// inside a data export directory
+var conversationsFiles = []string{
+	"conversations.json",
+	"conversations.json.gz",
+	"conversations.json.bz2",
+	"conversations.json.zst",
+}
+
+// isCompressedJSON reports whether a file name looks like a compressed JSON
+// file, such as conversations.json.gz
+func isCompressedJSON(name string) bool {
+	trimmed := imports.TrimCompressionExt(name)
+	return trimmed != name && strings.HasSuffix(strings.ToLower(trimmed), ".json")
+}
+
 // isLikelyClaudeExport checks if a file looks like a Claude export
 func (s *Scanner) isLikelyClaudeExport(path string, info os.FileInfo) bool {
+	compressed := isCompressedJSON(path)
+
 	// Must be JSON file
-	if !strings.HasSuffix(strings.ToLower(path), ".json") {
+	if !compressed && !strings.HasSuffix(strings.ToLower(path), ".json") {
 		return false
 	}
 
-	// Skip very small files (< 1KB)
-	if info.Size() < 1024 {
+	// Skip very small files (< 1KB); compressed exports can be much smaller
+	if !compressed && info.Size() < 1024 {
 		return false
 	}
 
@@ -229,9 +262,10 @@ func (s *Scanner) isLikelyClaudeExport(path string, info os.FileInfo) bool {
 	return false
 }
 
-// validateAndPreview checks if the file is a valid Claude export and extracts preview info
+// validateAndPreview checks if the file is a valid Claude export and extracts
+// preview info. Compressed files are decompressed on the fly.
 func (s *Scanner) validateAndPreview(path string) (bool, string, *ExportPreview) {
-	file, err := os.Open(path)
+	file, err := imports.OpenExport(path)
 	if err != nil {
 		return false, fmt.Sprintf("Cannot open file: %v", err), nil
 	}
@@ -0,0 +1,51 @@
+package export
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+// ChainPart is one conversation of a chain of follow-ups with the messages
+// to export
+type ChainPart struct {
+	Conversation *models.Conversation
+	Messages     []*models.Message
+}
+
+// ChainFormats are the formats RenderChain writes a chain in; Claude JSON
+// and EPUB take several conversations in one file already
+var ChainFormats = []string{"markdown", "text"}
+
+// RenderChain renders a chain of conversations, each carrying on from the
+// one before, as one document: each conversation in turn, saying which
+// conversation continues it
+func RenderChain(format string, parts []ChainPart) (string, error) {
+	var render func(*models.Conversation, []*models.Message) string
+	var separator string
+	switch format {
+	case "markdown":
+		render, separator = Markdown, "---\n\n"
+	case "text":
+		render, separator = Text, strings.Repeat("#", 80)+"\n\n"
+	default:
+		return "", fmt.Errorf("a chain can't be exported as %s (use %s, claude-json or epub)", format, strings.Join(ChainFormats, ", "))
+	}
+
+	var sb strings.Builder
+	for i, part := range parts {
+		sb.WriteString(render(part.Conversation, part.Messages))
+		if i == len(parts)-1 {
+			break
+		}
+		next := parts[i+1].Conversation
+		if format == "markdown" {
+			sb.WriteString(fmt.Sprintf("*Continued in conversation %d: %s*\n\n", next.ID, next.Name))
+		} else {
+			sb.WriteString(fmt.Sprintf("Continued in conversation %d: %s\n\n", next.ID, next.Name))
+		}
+		sb.WriteString(separator)
+	}
+	return sb.String(), nil
+}
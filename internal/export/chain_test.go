@@ -0,0 +1,37 @@
+package export
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+func TestRenderChain(t *testing.T) {
+	created := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	part := func(id int64, name, text string) ChainPart {
+		return ChainPart{
+			Conversation: &models.Conversation{ID: id, Name: name, CreatedAt: created, UpdatedAt: created},
+			Messages:     []*models.Message{{ID: id, Sender: "human", Text: text, CreatedAt: created}},
+		}
+	}
+	parts := []ChainPart{part(1, "Pooling", "first question"), part(7, "Pooling, continued", "second question")}
+
+	out, err := RenderChain("markdown", parts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, second := strings.Index(out, "first question"), strings.Index(out, "second question")
+	link := strings.Index(out, "*Continued in conversation 7: Pooling, continued*")
+	if first < 0 || link < first || second < link {
+		t.Errorf("expected both conversations in order with the link between them, got:\n%s", out)
+	}
+	if strings.Count(out, "Continued in") != 1 {
+		t.Errorf("expected only the first conversation to say where it's continued, got:\n%s", out)
+	}
+
+	if _, err := RenderChain("html", parts); err == nil {
+		t.Error("expected an error for a format chains can't be rendered in")
+	}
+}
@@ -0,0 +1,275 @@
+package export
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/neilberkman/shannon/internal/artifacts"
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+// The longest message, in characters, each chat takes: Slack cuts longer
+// ones off and Discord refuses them
+const (
+	SlackLimit   = 4000
+	DiscordLimit = 2000
+)
+
+// continuedNote ends a chunk where a message had to be cut to fit
+const continuedNote = "(continued in the next message)"
+
+// chatDialect is how a chat app wants its Markdown
+type chatDialect struct {
+	limit  int
+	italic string                      // wraps the continuation note
+	line   func(string) string         // converts a line of prose
+	fence  func(string, string) string // fences code in a language
+}
+
+var slackDialect = chatDialect{
+	limit:  SlackLimit,
+	italic: "_",
+	line:   slackLine,
+	// mrkdwn has no languages on fences, nor longer fences for code that
+	// contains one
+	fence: func(code, _ string) string { return "```\n" + strings.TrimRight(code, "\n") + "\n```" },
+}
+
+var discordDialect = chatDialect{
+	limit:  DiscordLimit,
+	italic: "*",
+	line:   discordLine,
+	fence:  artifacts.Fence,
+}
+
+// Slack renders a conversation as Slack mrkdwn, split into chunks that each
+// fit in a Slack message, separated by scissors lines
+func Slack(conv *models.Conversation, messages []*models.Message) string {
+	return joinChunks(chatChunks(slackDialect, conv, messages))
+}
+
+// Discord renders a conversation as Discord Markdown, split into chunks that
+// each fit in a Discord message, separated by scissors lines
+func Discord(conv *models.Conversation, messages []*models.Message) string {
+	return joinChunks(chatChunks(discordDialect, conv, messages))
+}
+
+// joinChunks puts the chunks of a chat export one after the other, with a
+// line marking where to cut between them
+func joinChunks(chunks []string) string {
+	if len(chunks) == 1 {
+		return chunks[0] + "\n"
+	}
+	var sb strings.Builder
+	for i, chunk := range chunks {
+		sb.WriteString(fmt.Sprintf("✂ ─── %d/%d ───\n\n", i+1, len(chunks)))
+		sb.WriteString(chunk + "\n\n")
+	}
+	return sb.String()
+}
+
+// chatChunks renders a conversation in a chat dialect as paste-sized chunks.
+// Messages are kept whole in a chunk where they fit; longer ones are cut
+// between paragraphs, or lines if need be, with a note where they're cut.
+// Code cut across chunks is closed and reopened so each chunk renders.
+func chatChunks(dialect chatDialect, conv *models.Conversation, messages []*models.Message) []string {
+	c := &chunker{dialect: dialect}
+	c.add(fmt.Sprintf("%s %s", dialect.line("**"+conv.Name+"**"), conv.CreatedAt.Format("2006-01-02")), false)
+
+	extractor := artifacts.NewExtractor()
+	for _, msg := range messages {
+		c.startMessage()
+		c.add(dialect.line("**"+MessageHeader(msg)+"**"), false)
+		for _, segment := range extractor.Segments(msg) {
+			switch segment.Kind {
+			case artifacts.KindCodeBlock:
+				c.add(dialect.fence(segment.Content, segment.Language), true)
+			case artifacts.KindArtifact:
+				c.add(dialect.line("**Artifact: "+segment.Artifact.Title+"**"), false)
+				c.add(dialect.fence(segment.Content, segment.Language), true)
+			default:
+				for _, paragraph := range strings.Split(segment.Content, "\n\n") {
+					if paragraph = strings.TrimSpace(paragraph); paragraph != "" {
+						c.add(chatProse(dialect, paragraph), false)
+					}
+				}
+			}
+		}
+	}
+	c.flush(false)
+	return c.chunks
+}
+
+// chatProse converts a paragraph of prose line by line
+func chatProse(dialect chatDialect, paragraph string) string {
+	lines := strings.Split(paragraph, "\n")
+	for i, line := range lines {
+		lines[i] = dialect.line(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// chunker packs blocks of a chat export into chunks of at most the
+// dialect's limit
+type chunker struct {
+	dialect chatDialect
+	chunks  []string
+	current []string // blocks of the chunk being packed
+	size    int      // characters in current, with the blank lines between
+	started bool     // the current message has blocks in the chunk
+}
+
+// startMessage marks where a message begins, so a chunk ending before it
+// needs no continuation note
+func (c *chunker) startMessage() {
+	c.started = false
+}
+
+// fenceReserve is the space kept in a chunk for closing a fence cut by it
+const fenceReserve = 8
+
+// room is how many characters a chunk can hold, leaving space for closing a
+// fence and the continuation note
+func (c *chunker) room() int {
+	return c.dialect.limit - fenceReserve - utf8.RuneCountInString(continuedNote) - 2*len(c.dialect.italic) - 2
+}
+
+// add adds a block, starting a new chunk when it doesn't fit in this one and
+// cutting it up when it doesn't fit in any. code is set for fenced blocks.
+func (c *chunker) add(block string, code bool) {
+	n := utf8.RuneCountInString(block)
+	if c.size > 0 && c.size+2+n > c.room() {
+		c.flush(c.started)
+	}
+	if n <= c.room() {
+		c.append(block)
+		return
+	}
+
+	// Too long for any chunk: cut it between lines, reopening the fence of
+	// code in every chunk after the first
+	lines := strings.Split(block, "\n")
+	fence, closing := "", ""
+	if code {
+		fence = lines[0]
+		closing = fence[:len(fence)-len(strings.TrimLeft(fence, "`"))]
+	}
+	var part []string
+	size := 0
+	for _, line := range lines {
+		for _, piece := range splitRunes(line, c.room()-len(fence)-1) {
+			if len(part) > 0 && size+1+utf8.RuneCountInString(piece) > c.room() {
+				if code {
+					part = append(part, closing)
+				}
+				c.append(strings.Join(part, "\n"))
+				c.flush(true)
+				part, size = nil, 0
+				if code {
+					part, size = []string{fence}, utf8.RuneCountInString(fence)
+				}
+			}
+			part = append(part, piece)
+			size += 1 + utf8.RuneCountInString(piece)
+		}
+	}
+	c.append(strings.Join(part, "\n"))
+}
+
+// append adds a block that fits to the current chunk
+func (c *chunker) append(block string) {
+	if len(c.current) > 0 {
+		c.size += 2
+	}
+	c.current = append(c.current, block)
+	c.size += utf8.RuneCountInString(block)
+	c.started = true
+}
+
+// flush ends the current chunk, noting that its last message goes on in
+// the next if continued
+func (c *chunker) flush(continued bool) {
+	if len(c.current) == 0 {
+		return
+	}
+	if continued {
+		c.current = append(c.current, c.dialect.italic+continuedNote+c.dialect.italic)
+	}
+	c.chunks = append(c.chunks, strings.Join(c.current, "\n\n"))
+	c.current, c.size = nil, 0
+}
+
+// splitRunes cuts a line into pieces of at most n characters
+func splitRunes(line string, n int) []string {
+	runes := []rune(line)
+	if len(runes) <= n {
+		return []string{line}
+	}
+	var pieces []string
+	for len(runes) > n {
+		pieces = append(pieces, string(runes[:n]))
+		runes = runes[n:]
+	}
+	return append(pieces, string(runes))
+}
+
+var (
+	mdHeading = regexp.MustCompile(`^(#{1,6})\s+(.+?)\s*#*$`)
+	mdBullet  = regexp.MustCompile(`^(\s*)[-*+]\s+`)
+	mdBold    = regexp.MustCompile(`\*\*(.+?)\*\*|__(.+?)__`)
+	mdItalic  = regexp.MustCompile(`\*([^*\s](?:[^*]*[^*\s])?)\*`)
+	mdStrike  = regexp.MustCompile(`~~(.+?)~~`)
+	mdLink    = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+)
+
+// slackLine converts a line of Markdown to mrkdwn: headings and **bold**
+// become *bold*, *italics* _italics_, ~~strikes~~ ~strikes~, bullets •, and
+// links their text followed by the URL, which Slack links when pasted.
+// Inline code is left as it is.
+func slackLine(line string) string {
+	if m := mdHeading.FindStringSubmatch(line); m != nil {
+		return "*" + outsideCode(m[2], stripBold) + "*"
+	}
+	line = mdBullet.ReplaceAllString(line, "${1}• ")
+	return outsideCode(line, func(text string) string {
+		// Bold is set aside while italics are converted, since both use *
+		text = mdBold.ReplaceAllString(text, "\x00$1$2\x00")
+		text = mdItalic.ReplaceAllString(text, "_${1}_")
+		text = strings.ReplaceAll(text, "\x00", "*")
+		text = mdStrike.ReplaceAllString(text, "~$1~")
+		return mdLink.ReplaceAllStringFunc(text, func(link string) string {
+			m := mdLink.FindStringSubmatch(link)
+			if m[1] == m[2] {
+				return m[2]
+			}
+			return m[1] + " (" + m[2] + ")"
+		})
+	})
+}
+
+// discordLine converts a line of Markdown for Discord, which takes most of it
+// as it is but has only three levels of headings
+func discordLine(line string) string {
+	if m := mdHeading.FindStringSubmatch(line); m != nil && len(m[1]) > 3 {
+		return "**" + outsideCode(m[2], stripBold) + "**"
+	}
+	return line
+}
+
+// stripBold removes bold markers, for text that is made bold as a whole
+func stripBold(text string) string {
+	return mdBold.ReplaceAllString(text, "$1$2")
+}
+
+// outsideCode applies convert to the parts of a line that aren't inline code
+func outsideCode(line string, convert func(string) string) string {
+	parts := strings.Split(line, "`")
+	// Even parts are outside code; after an unclosed backtick, the rest of
+	// the line is an odd one and left alone
+	for i := 0; i < len(parts); i += 2 {
+		parts[i] = convert(parts[i])
+	}
+	return strings.Join(parts, "`")
+}
@@ -0,0 +1,80 @@
+package export
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+func TestSlackLine(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected string
+	}{
+		{"## Setting up", "*Setting up*"},
+		{"Use **bold** and *italics* here", "Use *bold* and _italics_ here"},
+		{"- first item", "• first item"},
+		{"  * nested item", "  • nested item"},
+		{"~~old~~ new", "~old~ new"},
+		{"See [the docs](https://go.dev/doc)", "See the docs (https://go.dev/doc)"},
+		{"Run `a **b** c` first", "Run `a **b** c` first"},
+		{"2 * 3 * 4", "2 * 3 * 4"},
+	}
+	for _, tt := range tests {
+		if got := slackLine(tt.line); got != tt.expected {
+			t.Errorf("slackLine(%q) = %q, want %q", tt.line, got, tt.expected)
+		}
+	}
+
+	if got := discordLine("#### Deep heading"); got != "**Deep heading**" {
+		t.Errorf("expected deep headings to be bold on Discord, got %q", got)
+	}
+	if got := discordLine("## Heading"); got != "## Heading" {
+		t.Errorf("expected Discord to keep headings it has, got %q", got)
+	}
+}
+
+func TestChatChunks(t *testing.T) {
+	created := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	conv := &models.Conversation{ID: 1, Name: "Logs", CreatedAt: created, UpdatedAt: created}
+	code := strings.Repeat("fmt.Println(\"a line of code\")\n", 200)
+	messages := []*models.Message{
+		{ID: 1, Sender: "human", Text: "Print some lines", CreatedAt: created},
+		{ID: 2, Sender: "assistant", Text: "Here:\n\n```go\n" + code + "```\n\nThat's it.", CreatedAt: created},
+	}
+
+	short := chatChunks(slackDialect, conv, messages[:1])
+	if len(short) != 1 || !strings.HasPrefix(short[0], "*Logs* 2024-03-01") {
+		t.Fatalf("expected a short conversation in one chunk, got %q", short)
+	}
+	if out := Slack(conv, messages[:1]); strings.Contains(out, "✂") {
+		t.Errorf("expected no scissors for a single chunk:\n%s", out)
+	}
+
+	for name, dialect := range map[string]chatDialect{"slack": slackDialect, "discord": discordDialect} {
+		chunks := chatChunks(dialect, conv, messages)
+		if len(chunks) < 2 {
+			t.Fatalf("%s: expected the long answer to be cut, got %d chunk(s)", name, len(chunks))
+		}
+		for i, chunk := range chunks {
+			if n := len([]rune(chunk)); n > dialect.limit {
+				t.Errorf("%s: chunk %d has %d characters, over the limit of %d", name, i+1, n, dialect.limit)
+			}
+			if strings.Count(chunk, "```")%2 != 0 {
+				t.Errorf("%s: chunk %d leaves a code fence open:\n%s", name, i+1, chunk)
+			}
+			if last := i == len(chunks)-1; strings.Contains(chunk, continuedNote) == last {
+				t.Errorf("%s: chunk %d should note a cut message only if another chunk follows", name, i+1)
+			}
+		}
+		if !strings.Contains(chunks[1], "```") {
+			t.Errorf("%s: expected the code to be fenced again after the cut:\n%s", name, chunks[1])
+		}
+	}
+
+	if out := Discord(conv, messages); !strings.Contains(out, "✂ ─── 1/") || !strings.Contains(out, "```go") {
+		t.Errorf("expected scissors lines and fence languages in the Discord export:\n%s", out[:200])
+	}
+}
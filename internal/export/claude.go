@@ -0,0 +1,149 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+// FormatClaudeJSON is the format of Claude's own conversations.json export.
+// Unlike the other formats it holds every exported conversation in one file.
+const FormatClaudeJSON = "claude-json"
+
+// claudeTimeFormat matches the timestamps in Claude's exports
+const claudeTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+type claudeConversation struct {
+	UUID         string          `json:"uuid"`
+	Name         string          `json:"name"`
+	CreatedAt    string          `json:"created_at"`
+	UpdatedAt    string          `json:"updated_at"`
+	Model        string          `json:"model,omitempty"`
+	Starred      *bool           `json:"is_starred,omitempty"`
+	ChatMessages []claudeMessage `json:"chat_messages"`
+}
+
+type claudeMessage struct {
+	UUID              string          `json:"uuid"`
+	Text              string          `json:"text"`
+	Content           []claudeContent `json:"content"`
+	Sender            string          `json:"sender"`
+	CreatedAt         string          `json:"created_at"`
+	UpdatedAt         string          `json:"updated_at"`
+	Attachments       []interface{}   `json:"attachments"`
+	Files             []interface{}   `json:"files"`
+	ParentMessageUUID string          `json:"parent_message_uuid,omitempty"`
+	Model             string          `json:"model,omitempty"`
+	Starred           *bool           `json:"starred,omitempty"`
+	Feedback          string          `json:"feedback,omitempty"`
+}
+
+type claudeContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// ClaudeExport accumulates conversations in the structure of Claude's
+// official export, so subsets of the archive can be read by tools that
+// consume it, including `shannon import`
+type ClaudeExport struct {
+	conversations []claudeConversation
+}
+
+// Add adds a conversation. Messages from every branch can be included; each
+// keeps a link to its parent if the parent is included too.
+func (e *ClaudeExport) Add(conv *models.Conversation, messages []*models.Message) {
+	uuids := make(map[int64]string, len(messages))
+	for _, msg := range messages {
+		uuids[msg.ID] = msg.UUID
+	}
+
+	chatMessages := make([]claudeMessage, 0, len(messages))
+	for _, msg := range messages {
+		created := claudeTime(msg.CreatedAt)
+		m := claudeMessage{
+			UUID:        msg.UUID,
+			Text:        msg.Text,
+			Content:     []claudeContent{{Type: "text", Text: msg.Text}},
+			Sender:      msg.Sender,
+			CreatedAt:   created,
+			UpdatedAt:   created,
+			Attachments: []interface{}{},
+			Files:       []interface{}{},
+			Model:       msg.Model,
+			Starred:     starred(msg.Starred),
+			Feedback:    msg.Feedback,
+		}
+		if msg.ParentID != nil {
+			m.ParentMessageUUID = uuids[*msg.ParentID]
+		}
+		chatMessages = append(chatMessages, m)
+	}
+
+	e.conversations = append(e.conversations, claudeConversation{
+		UUID:         conv.UUID,
+		Name:         conv.Name,
+		CreatedAt:    claudeTime(conv.CreatedAt),
+		UpdatedAt:    claudeTime(conv.UpdatedAt),
+		Model:        conversationModel(messages),
+		Starred:      starred(conv.Starred),
+		ChatMessages: chatMessages,
+	})
+}
+
+// starred returns a star to write out, or nil for none. Importing keeps the
+// stars already there when an export doesn't say, and an unstarred
+// conversation or message may just come from an export that didn't.
+func starred(star bool) *bool {
+	if !star {
+		return nil
+	}
+	return &star
+}
+
+// conversationModel returns the model that wrote every assistant message,
+// or nothing if they don't all have the same one. Importing gives the
+// conversation's model to the messages without one of their own, so it can
+// only be set when that changes nothing.
+func conversationModel(messages []*models.Message) string {
+	model := ""
+	for _, msg := range messages {
+		if msg.Sender != "assistant" {
+			continue
+		}
+		if msg.Model == "" || (model != "" && msg.Model != model) {
+			return ""
+		}
+		model = msg.Model
+	}
+	return model
+}
+
+// Len returns the number of conversations added
+func (e *ClaudeExport) Len() int {
+	return len(e.conversations)
+}
+
+// JSON renders the conversations as a conversations.json file
+func (e *ClaudeExport) JSON() (string, error) {
+	conversations := e.conversations
+	if conversations == nil {
+		conversations = []claudeConversation{}
+	}
+	// Claude doesn't escape HTML in its exports, and neither do we
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(conversations); err != nil {
+		return "", fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	return buf.String(), nil
+}
+
+func claudeTime(t time.Time) string {
+	return t.UTC().Format(claudeTimeFormat)
+}
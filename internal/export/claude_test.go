@@ -0,0 +1,192 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/imports"
+	"github.com/neilberkman/shannon/internal/models"
+	"github.com/neilberkman/shannon/internal/search"
+)
+
+// branchedExport has a human message edited after the first reply, giving the
+// conversation a second branch
+const branchedExport = `[{"uuid": "conv-1", "name": "Retries <b>", "created_at": "2024-03-01T09:30:00.000000Z", "updated_at": "2024-03-01T09:40:00.000000Z",
+ "chat_messages": [
+  {"uuid": "m1", "sender": "human", "text": "How do I retry?", "created_at": "2024-03-01T09:30:00.000000Z"},
+  {"uuid": "m2", "sender": "assistant", "text": "Use a <loop>.", "created_at": "2024-03-01T09:31:00.000000Z", "parent_message_uuid": "m1", "model": "claude-3-5-sonnet"},
+  {"uuid": "m3", "sender": "human", "text": "With backoff?", "created_at": "2024-03-01T09:32:00.000000Z", "parent_message_uuid": "m2"},
+  {"uuid": "m4", "sender": "human", "text": "How do I retry with jitter?", "created_at": "2024-03-01T09:35:00.000000Z", "parent_message_uuid": "m2"}
+ ]}]`
+
+type exportedMessage struct {
+	UUID, Sender, Text, Parent, CreatedAt, Model, Feedback string
+	Starred                                                bool
+}
+
+// importAndExport imports a Claude export into a fresh database and exports
+// all of it again in the same format
+func importAndExport(t *testing.T, data string) string {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "conversations.json")
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	database, err := db.New(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := database.Close(); err != nil {
+			t.Errorf("failed to close database: %v", err)
+		}
+	}()
+	if _, err := imports.NewImporter(database, 100, false).Import(path); err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+
+	engine := search.NewEngine(database)
+	conversations, err := engine.GetAllConversations(10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var claude ClaudeExport
+	for _, summary := range conversations {
+		conv, _, err := engine.GetConversation(summary.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		messages, err := engine.GetAllMessages(conv.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		claude.Add(conv, messages)
+	}
+	out, err := claude.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func parseMessages(t *testing.T, data string) (models.ClaudeConversation, []exportedMessage) {
+	t.Helper()
+	var conversations []models.ClaudeConversation
+	if err := json.Unmarshal([]byte(data), &conversations); err != nil {
+		t.Fatalf("invalid export: %v", err)
+	}
+	if len(conversations) != 1 {
+		t.Fatalf("expected 1 conversation, got %d", len(conversations))
+	}
+	var messages []exportedMessage
+	for _, msg := range conversations[0].ChatMessages {
+		m := exportedMessage{UUID: msg.UUID, Sender: msg.Sender, Text: msg.Text, CreatedAt: msg.CreatedAt, Model: msg.Model,
+			Feedback: string(msg.Feedback), Starred: msg.Starred != nil && *msg.Starred}
+		if msg.ParentID != nil {
+			m.Parent = *msg.ParentID
+		}
+		messages = append(messages, m)
+	}
+	return conversations[0], messages
+}
+
+func TestClaudeExportRoundTrip(t *testing.T) {
+	exported := importAndExport(t, branchedExport)
+	conv, messages := parseMessages(t, exported)
+	original, originalMessages := parseMessages(t, branchedExport)
+
+	if conv.UUID != original.UUID || conv.Name != original.Name || conv.CreatedAt != original.CreatedAt || conv.UpdatedAt != original.UpdatedAt {
+		t.Errorf("conversation changed: %+v", conv)
+	}
+	if !reflect.DeepEqual(messages, originalMessages) {
+		t.Errorf("expected messages %+v, got %+v", originalMessages, messages)
+	}
+
+	// Exporting the reimported export gives the same file
+	if again := importAndExport(t, exported); again != exported {
+		t.Errorf("second round trip changed the export:\n%s\nvs\n%s", exported, again)
+	}
+}
+
+func TestClaudeExportModels(t *testing.T) {
+	// The conversation's model is given to the reply without one of its own
+	const data = `[{"uuid": "conv-1", "name": "Models", "created_at": "2024-03-01T09:30:00.000000Z", "updated_at": "2024-03-01T09:40:00.000000Z",
+ "model": "claude-3-opus",
+ "chat_messages": [
+  {"uuid": "m1", "sender": "human", "text": "Hi", "created_at": "2024-03-01T09:30:00.000000Z"},
+  {"uuid": "m2", "sender": "assistant", "text": "Hello", "created_at": "2024-03-01T09:31:00.000000Z", "parent_message_uuid": "m1"},
+  {"uuid": "m3", "sender": "human", "text": "Again", "created_at": "2024-03-01T09:32:00.000000Z", "parent_message_uuid": "m2"},
+  {"uuid": "m4", "sender": "assistant", "text": "Hello again", "created_at": "2024-03-01T09:33:00.000000Z", "parent_message_uuid": "m3", "model": "claude-3-5-sonnet"}
+ ]}]`
+
+	exported := importAndExport(t, data)
+	conv, messages := parseMessages(t, exported)
+	if conv.Model != "" {
+		t.Errorf("expected no conversation model with two models, got %q", conv.Model)
+	}
+	var got []string
+	for _, msg := range messages {
+		got = append(got, msg.Model)
+	}
+	if want := []string{"", "claude-3-opus", "", "claude-3-5-sonnet"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected models %q, got %q", want, got)
+	}
+	if again := importAndExport(t, exported); again != exported {
+		t.Errorf("second round trip changed the export:\n%s\nvs\n%s", exported, again)
+	}
+
+	// One model for every reply is the conversation's too
+	conv, _ = parseMessages(t, importAndExport(t, branchedExport))
+	if conv.Model != "claude-3-5-sonnet" {
+		t.Errorf("expected conversation model claude-3-5-sonnet, got %q", conv.Model)
+	}
+}
+
+func TestClaudeExportStarsAndFeedback(t *testing.T) {
+	const data = `[{"uuid": "conv-1", "name": "Stars", "created_at": "2024-03-01T09:30:00.000000Z", "updated_at": "2024-03-01T09:40:00.000000Z",
+ "is_starred": true,
+ "chat_messages": [
+  {"uuid": "m1", "sender": "human", "text": "Hi", "created_at": "2024-03-01T09:30:00.000000Z"},
+  {"uuid": "m2", "sender": "assistant", "text": "Hello", "created_at": "2024-03-01T09:31:00.000000Z", "parent_message_uuid": "m1",
+   "starred": true, "feedback": {"type": "thumbs_up"}},
+  {"uuid": "m3", "sender": "human", "text": "Again", "created_at": "2024-03-01T09:32:00.000000Z", "parent_message_uuid": "m2"},
+  {"uuid": "m4", "sender": "assistant", "text": "Hello again", "created_at": "2024-03-01T09:33:00.000000Z", "parent_message_uuid": "m3",
+   "feedback": "down"}
+ ]}]`
+
+	exported := importAndExport(t, data)
+	conv, messages := parseMessages(t, exported)
+	if conv.Starred == nil || !*conv.Starred {
+		t.Error("expected the conversation to stay starred")
+	}
+	var got []string
+	for _, msg := range messages {
+		got = append(got, fmt.Sprintf("%t %s", msg.Starred, msg.Feedback))
+	}
+	want := []string{"false ", `true "up"`, "false ", `false "down"`}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected stars and feedback %q, got %q", want, got)
+	}
+	if again := importAndExport(t, exported); again != exported {
+		t.Errorf("second round trip changed the export:\n%s\nvs\n%s", exported, again)
+	}
+
+	// What isn't starred or rated is left out, so importing the export
+	// elsewhere keeps what's there
+	for _, absent := range []string{`"starred": false`, `"is_starred": false`, `"feedback": ""`} {
+		if strings.Contains(exported, absent) {
+			t.Errorf("expected %s to be left out", absent)
+		}
+	}
+	if strings.Contains(importAndExport(t, branchedExport), "starred") {
+		t.Error("expected no stars in an export without any")
+	}
+}
@@ -0,0 +1,152 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+
+	"github.com/neilberkman/shannon/internal/artifacts"
+	"github.com/neilberkman/shannon/internal/models"
+	"github.com/neilberkman/shannon/internal/rendering"
+)
+
+// confluenceLanguages maps normalized languages to the ones the Confluence
+// code macro highlights
+var confluenceLanguages = map[string]string{
+	"bash": "bash", "cpp": "cpp", "csharp": "c#", "css": "css", "diff": "diff",
+	"go": "go", "html": "html", "java": "java", "javascript": "js", "jsx": "js",
+	"json": "js", "kotlin": "kotlin", "php": "php", "python": "py", "ruby": "ruby",
+	"scala": "scala", "sql": "sql", "svg": "xml", "swift": "swift",
+	"tsx": "typescript", "typescript": "typescript", "xml": "xml", "yaml": "yaml",
+}
+
+// ConfluencePublisher creates Confluence pages through the Confluence REST
+// API. With a User it authenticates with an API token as on Confluence Cloud;
+// without one the token is sent as a personal access token as on Server and
+// Data Center.
+type ConfluencePublisher struct {
+	BaseURL  string // e.g. https://example.atlassian.net/wiki
+	User     string
+	Token    string
+	Space    string // key of the space the page is created in
+	ParentID string // optional page the conversation is created under
+	Client   *http.Client
+}
+
+// Publish creates a Confluence page for the conversation and returns its URL
+func (p *ConfluencePublisher) Publish(ctx context.Context, conv *models.Conversation, messages []*models.Message) (string, error) {
+	switch {
+	case p.BaseURL == "":
+		return "", fmt.Errorf("a Confluence URL is required")
+	case p.Token == "":
+		return "", fmt.Errorf("a Confluence API token is required")
+	case p.Space == "":
+		return "", fmt.Errorf("a Confluence space key is required")
+	}
+
+	page := map[string]interface{}{
+		"type":  "page",
+		"title": conv.Name,
+		"space": map[string]string{"key": p.Space},
+		"body": map[string]interface{}{
+			"storage": map[string]string{
+				"value":          confluenceStorage(conv, messages),
+				"representation": "storage",
+			},
+		},
+	}
+	if p.ParentID != "" {
+		page["ancestors"] = []map[string]string{{"id": p.ParentID}}
+	}
+
+	var created struct {
+		ID    string `json:"id"`
+		Links struct {
+			Base  string `json:"base"`
+			WebUI string `json:"webui"`
+		} `json:"_links"`
+	}
+	baseURL := strings.TrimRight(p.BaseURL, "/")
+	if err := sendJSON(ctx, p.Client, http.MethodPost, baseURL+"/rest/api/content", p.authorize, page, &created); err != nil {
+		return "", fmt.Errorf("failed to create Confluence page: %w", err)
+	}
+
+	if created.Links.Base == "" {
+		created.Links.Base = baseURL
+	}
+	if created.Links.WebUI == "" {
+		return fmt.Sprintf("%s/pages/viewpage.action?pageId=%s", created.Links.Base, created.ID), nil
+	}
+	return created.Links.Base + created.Links.WebUI, nil
+}
+
+func (p *ConfluencePublisher) authorize(req *http.Request) {
+	if p.User != "" {
+		req.SetBasicAuth(p.User, p.Token)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+p.Token)
+	}
+}
+
+// confluenceStorage converts a conversation to Confluence storage format: a
+// heading per message, paragraphs for prose and code macros for fenced code
+// and artifacts
+func confluenceStorage(conv *models.Conversation, messages []*models.Message) string {
+	extractor := artifacts.NewExtractor()
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("<p><em>Conversation %d • created %s • updated %s • %d messages</em></p>",
+		conv.ID, conv.CreatedAt.Format("2006-01-02 15:04:05"), conv.UpdatedAt.Format("2006-01-02 15:04:05"), len(messages)))
+
+	for _, msg := range messages {
+		sb.WriteString("<hr/>")
+		sb.WriteString(fmt.Sprintf("<h2>%s (%s)</h2>",
+			html.EscapeString(rendering.FormatSender(msg.Sender)), msg.CreatedAt.Format("2006-01-02 15:04:05")))
+
+		for _, segment := range extractor.Segments(msg) {
+			switch segment.Kind {
+			case artifacts.KindText:
+				writeConfluenceParagraphs(&sb, segment.Content)
+			case artifacts.KindCodeBlock:
+				writeConfluenceCode(&sb, segment.Content, segment.Language, "")
+			case artifacts.KindArtifact:
+				artifact := segment.Artifact
+				if artifact.Type == artifacts.TypeMarkdown {
+					sb.WriteString(fmt.Sprintf("<h3>%s</h3>", html.EscapeString(artifacts.InlineTitle(artifact))))
+					writeConfluenceParagraphs(&sb, artifact.Content)
+				} else {
+					title := fmt.Sprintf("%s (%s)", artifact.Title, artifact.GetTypeName())
+					writeConfluenceCode(&sb, artifact.Content, segment.Language, title)
+				}
+			}
+		}
+	}
+
+	return sb.String()
+}
+
+// writeConfluenceParagraphs writes prose as paragraphs, keeping line breaks
+func writeConfluenceParagraphs(sb *strings.Builder, text string) {
+	for _, p := range paragraphs(text) {
+		sb.WriteString("<p>")
+		sb.WriteString(strings.ReplaceAll(html.EscapeString(p), "\n", "<br/>"))
+		sb.WriteString("</p>")
+	}
+}
+
+// writeConfluenceCode writes a code macro, titled if title isn't empty
+func writeConfluenceCode(sb *strings.Builder, content, language, title string) {
+	sb.WriteString(`<ac:structured-macro ac:name="code">`)
+	if title != "" {
+		sb.WriteString(fmt.Sprintf(`<ac:parameter ac:name="title">%s</ac:parameter>`, html.EscapeString(title)))
+	}
+	if lang, ok := confluenceLanguages[language]; ok {
+		sb.WriteString(fmt.Sprintf(`<ac:parameter ac:name="language">%s</ac:parameter>`, lang))
+	}
+	// A CDATA section can't contain its own terminator, so split it there
+	content = strings.ReplaceAll(content, "]]>", "]]]]><![CDATA[>")
+	sb.WriteString("<ac:plain-text-body><![CDATA[" + content + "]]></ac:plain-text-body>")
+	sb.WriteString("</ac:structured-macro>")
+}
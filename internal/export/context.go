@@ -0,0 +1,239 @@
+package export
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+// Strategies for picking the messages of a context window
+const (
+	// ContextRecent keeps the latest messages, cutting the start of the
+	// earliest one kept to fill the budget
+	ContextRecent = "recent"
+	// ContextRelevant keeps the messages matching a query, best first, each
+	// with the question or answer it belongs to
+	ContextRelevant = "relevant"
+)
+
+// minTruncatedTokens is the least of a message worth keeping when cutting it
+// to fit a context window
+const minTruncatedTokens = 50
+
+// truncatedMark starts a message cut to fit a context window
+const truncatedMark = "[…] "
+
+// EstimateTokens estimates the tokens of text at roughly four characters
+// each, as the conversation metrics do
+func EstimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// ContextWindow is a conversation's messages picked to fit a token budget,
+// to paste into a new chat as context
+type ContextWindow struct {
+	Conversation *models.Conversation
+	Strategy     string
+	Query        string // for ContextRelevant
+	Instruction  string
+	Total        int // messages in the conversation
+	MaxTokens    int
+
+	picked []int          // indexes of the messages kept, in conversation order
+	texts  map[int]string // messages cut to fit, by index
+	all    []*models.Message
+}
+
+// Messages returns the messages kept, in conversation order
+func (w *ContextWindow) Messages() []*models.Message {
+	messages := make([]*models.Message, len(w.picked))
+	for n, index := range w.picked {
+		messages[n] = w.all[index]
+	}
+	return messages
+}
+
+// Truncated reports whether a message was cut to fit
+func (w *ContextWindow) Truncated() bool {
+	return len(w.texts) > 0
+}
+
+// Tokens estimates the tokens of the block
+func (w *ContextWindow) Tokens() int {
+	return EstimateTokens(w.Block())
+}
+
+// RecentContext keeps the latest messages of a conversation that fit within
+// maxTokens, including the block around them
+func RecentContext(conv *models.Conversation, messages []*models.Message, maxTokens int, instruction string) (*ContextWindow, error) {
+	w := newContextWindow(conv, messages, ContextRecent, "", maxTokens, instruction)
+	for index := len(messages) - 1; index >= 0; index-- {
+		if w.add(index) {
+			continue
+		}
+		w.addTruncated(index)
+		break
+	}
+	return w, w.check()
+}
+
+// RelevantContext keeps the messages of a conversation ranked by relevance to
+// query, as message IDs best first, that fit within maxTokens. Each message
+// brings the question it answers or the answer it got when that fits too, so
+// exchanges stay whole. Messages too long to fit are passed over for the next.
+func RelevantContext(conv *models.Conversation, messages []*models.Message, query string, ranked []int64, maxTokens int, instruction string) (*ContextWindow, error) {
+	w := newContextWindow(conv, messages, ContextRelevant, query, maxTokens, instruction)
+	indexes := make(map[int64]int, len(messages))
+	for index, msg := range messages {
+		indexes[msg.ID] = index
+	}
+
+	for _, id := range ranked {
+		index, ok := indexes[id]
+		if !ok || w.has(index) || !w.add(index) {
+			continue
+		}
+		partner := index + 1
+		if messages[index].Sender == "assistant" {
+			partner = index - 1
+		}
+		if partner >= 0 && partner < len(messages) && messages[partner].Sender != messages[index].Sender && !w.has(partner) {
+			w.add(partner)
+		}
+	}
+	if len(w.picked) == 0 && len(ranked) > 0 {
+		return nil, fmt.Errorf("no message relevant to %q fits within %d tokens", query, maxTokens)
+	}
+	return w, w.check()
+}
+
+func newContextWindow(conv *models.Conversation, messages []*models.Message, strategy, query string, maxTokens int, instruction string) *ContextWindow {
+	if strings.TrimSpace(instruction) == "" {
+		instruction = DefaultPromptInstruction
+	}
+	return &ContextWindow{
+		Conversation: conv,
+		Strategy:     strategy,
+		Query:        query,
+		Instruction:  strings.TrimSpace(instruction),
+		Total:        len(messages),
+		MaxTokens:    maxTokens,
+		texts:        make(map[int]string),
+		all:          messages,
+	}
+}
+
+// check fails a window that kept nothing
+func (w *ContextWindow) check() error {
+	if len(w.picked) > 0 {
+		return nil
+	}
+	if w.Total == 0 {
+		return fmt.Errorf("conversation %d has no messages", w.Conversation.ID)
+	}
+	if w.Strategy == ContextRelevant {
+		return fmt.Errorf("no messages in conversation %d match %q", w.Conversation.ID, w.Query)
+	}
+	return fmt.Errorf("%d tokens is too few for any of conversation %d's messages", w.MaxTokens, w.Conversation.ID)
+}
+
+func (w *ContextWindow) has(index int) bool {
+	n := sort.SearchInts(w.picked, index)
+	return n < len(w.picked) && w.picked[n] == index
+}
+
+// add keeps the message at index if the block still fits
+func (w *ContextWindow) add(index int) bool {
+	n := sort.SearchInts(w.picked, index)
+	w.picked = append(w.picked, 0)
+	copy(w.picked[n+1:], w.picked[n:])
+	w.picked[n] = index
+	if w.Tokens() <= w.MaxTokens {
+		return true
+	}
+	w.picked = append(w.picked[:n], w.picked[n+1:]...)
+	return false
+}
+
+// addTruncated keeps as much of the end of the message at index as fits,
+// as long as that's worth keeping
+func (w *ContextWindow) addTruncated(index int) {
+	text := strings.TrimSpace(w.all[index].Text)
+	w.texts[index] = ""
+	if !w.add(index) {
+		delete(w.texts, index)
+		return
+	}
+	room := (w.MaxTokens - w.Tokens()) * 4
+	for room >= minTruncatedTokens*4 {
+		w.texts[index] = truncatedMark + tail(text, room-len(truncatedMark))
+		if w.Tokens() <= w.MaxTokens {
+			return
+		}
+		room -= 16
+	}
+	// Too little room left to be worth it
+	w.remove(index)
+	delete(w.texts, index)
+}
+
+func (w *ContextWindow) remove(index int) {
+	n := sort.SearchInts(w.picked, index)
+	w.picked = append(w.picked[:n], w.picked[n+1:]...)
+}
+
+// tail returns the last bytes of text up to max, starting at a rune
+func tail(text string, max int) string {
+	if max <= 0 {
+		return ""
+	}
+	if len(text) <= max {
+		return text
+	}
+	start := len(text) - max
+	for start < len(text) && !utf8.RuneStart(text[start]) {
+		start++
+	}
+	return text[start:]
+}
+
+// Block formats the messages kept as a block to paste into a new chat,
+// numbered as 'shannon view' numbers them, noting the messages left out
+// between them, and ends it with the instruction
+func (w *ContextWindow) Block() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Here is context from an earlier conversation we had, %q from %s: ",
+		w.Conversation.Name, w.Conversation.CreatedAt.Format("January 2, 2006"))
+	switch {
+	case len(w.picked) == w.Total:
+		fmt.Fprintf(&b, "all %d of its messages.\n\n", w.Total)
+	case w.Strategy == ContextRelevant:
+		fmt.Fprintf(&b, "%d of its %d messages, the most relevant to %q.\n\n", len(w.picked), w.Total, w.Query)
+	default:
+		fmt.Fprintf(&b, "the last %d of its %d messages.\n\n", len(w.picked), w.Total)
+	}
+
+	b.WriteString("<conversation>\n")
+	next := 0
+	for _, index := range w.picked {
+		if index > next {
+			fmt.Fprintf(&b, "<omitted messages=\"%d\"/>\n", index-next)
+		}
+		msg := w.all[index]
+		text, truncated := w.texts[index]
+		if !truncated {
+			text = strings.TrimSpace(msg.Text)
+		}
+		fmt.Fprintf(&b, "<message n=\"%d\" from=%q>\n%s\n</message>\n", index+1, msg.Sender, text)
+		next = index + 1
+	}
+	if next < w.Total && len(w.picked) > 0 {
+		fmt.Fprintf(&b, "<omitted messages=\"%d\"/>\n", w.Total-next)
+	}
+	b.WriteString("</conversation>\n\n")
+	b.WriteString(w.Instruction + "\n")
+	return b.String()
+}
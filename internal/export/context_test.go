@@ -0,0 +1,93 @@
+package export
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+func contextMessages() []*models.Message {
+	return []*models.Message{
+		{ID: 1, Sender: "human", Text: "How do I drop empty rows in pandas?"},
+		{ID: 2, Sender: "assistant", Text: "Use df.dropna(), " + strings.Repeat("which drops rows with missing values. ", 20)},
+		{ID: 3, Sender: "human", Text: "And how do I rename a column?"},
+		{ID: 4, Sender: "assistant", Text: "Use df.rename(columns={'a': 'b'})."},
+		{ID: 5, Sender: "human", Text: "Thanks, now write it to parquet."},
+		{ID: 6, Sender: "assistant", Text: "Call df.to_parquet('out.parquet')."},
+	}
+}
+
+func TestRecentContext(t *testing.T) {
+	conv := &models.Conversation{ID: 1, Name: "Pandas cleanup", CreatedAt: time.Date(2024, 5, 3, 9, 30, 0, 0, time.UTC)}
+	messages := contextMessages()
+
+	w, err := RecentContext(conv, messages, 10000, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(w.Messages()) != 6 || w.Truncated() {
+		t.Errorf("expected all messages to fit, kept %d", len(w.Messages()))
+	}
+	if block := w.Block(); !strings.Contains(block, "all 6 of its messages") || !strings.HasSuffix(block, DefaultPromptInstruction+"\n") {
+		t.Errorf("unexpected block:\n%s", block)
+	}
+
+	// Too small for the long answer: its end is kept, cut to fill the budget
+	w, err = RecentContext(conv, messages, 220, "Continue in polars.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept := w.Messages()
+	if len(kept) != 5 || kept[0].ID != 2 || !w.Truncated() {
+		t.Fatalf("expected messages 2 to 6 with 2 cut, got %d from %d (truncated %v)", len(kept), kept[0].ID, w.Truncated())
+	}
+	if w.Tokens() > 220 {
+		t.Errorf("block of %d tokens exceeds the budget", w.Tokens())
+	}
+	block := w.Block()
+	for _, want := range []string{
+		"the last 5 of its 6 messages",
+		"<omitted messages=\"1\"/>\n<message n=\"2\" from=\"assistant\">\n" + truncatedMark,
+		"<message n=\"6\" from=\"assistant\">\nCall df.to_parquet('out.parquet').\n</message>",
+		"</conversation>\n\nContinue in polars.\n",
+	} {
+		if !strings.Contains(block, want) {
+			t.Errorf("block doesn't contain %q:\n%s", want, block)
+		}
+	}
+
+	if _, err := RecentContext(conv, messages, 20, ""); err == nil {
+		t.Error("expected a budget too small for any message to fail")
+	}
+}
+
+func TestRelevantContext(t *testing.T) {
+	conv := &models.Conversation{ID: 1, Name: "Pandas cleanup", CreatedAt: time.Date(2024, 5, 3, 9, 30, 0, 0, time.UTC)}
+	messages := contextMessages()
+
+	// The answer about renaming ranks first and brings its question
+	w, err := RelevantContext(conv, messages, "rename", []int64{4, 2}, 160, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept := w.Messages()
+	if len(kept) != 2 || kept[0].ID != 3 || kept[1].ID != 4 {
+		t.Fatalf("expected messages 3 and 4, got %v", kept)
+	}
+	block := w.Block()
+	for _, want := range []string{
+		`2 of its 6 messages, the most relevant to "rename"`,
+		"<conversation>\n<omitted messages=\"2\"/>\n<message n=\"3\" from=\"human\">",
+		"</message>\n<omitted messages=\"2\"/>\n</conversation>",
+	} {
+		if !strings.Contains(block, want) {
+			t.Errorf("block doesn't contain %q:\n%s", want, block)
+		}
+	}
+
+	if _, err := RelevantContext(conv, messages, "polars", nil, 1000, ""); err == nil {
+		t.Error("expected a query matching nothing to fail")
+	}
+}
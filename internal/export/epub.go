@@ -0,0 +1,316 @@
+package export
+
+import (
+	"archive/zip"
+	"crypto/sha1"
+	"fmt"
+	"hash/crc32"
+	"html"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/artifacts"
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+// FormatEPUB is an e-book with a chapter per conversation. Like
+// claude-json it holds every exported conversation in one file.
+const FormatEPUB = "epub"
+
+// epubMimetype is the content of an EPUB's mimetype file
+const epubMimetype = "application/epub+zip"
+
+// epubTimeFormat is the format of dcterms:modified
+const epubTimeFormat = "2006-01-02T15:04:05Z"
+
+// epubChapter is a conversation rendered as a chapter
+type epubChapter struct {
+	title   string
+	uuid    string
+	created time.Time
+	updated time.Time
+	body    string
+}
+
+// EPUB accumulates conversations as the chapters of an e-book, for reading
+// long conversations on an e-reader
+type EPUB struct {
+	// Title is the book's title; empty uses the name of its only
+	// conversation, or a count of them
+	Title    string
+	chapters []epubChapter
+}
+
+// Add adds a conversation as the next chapter
+func (b *EPUB) Add(conv *models.Conversation, messages []*models.Message) {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("<h1>%s</h1>\n", xhtmlEscape(conv.Name)))
+	meta := fmt.Sprintf("%d messages &#183; %s to %s", len(messages),
+		conv.CreatedAt.Format("2006-01-02"), conv.UpdatedAt.Format("2006-01-02"))
+	if names := Models(messages); names != "" {
+		meta += " &#183; " + xhtmlEscape(names)
+	}
+	sb.WriteString(fmt.Sprintf("<p class=\"meta\">%s</p>\n", meta))
+
+	extractor := artifacts.NewExtractor()
+	for _, msg := range messages {
+		sb.WriteString(fmt.Sprintf("<h2 class=\"%s\">%s</h2>\n", xhtmlEscape(msg.Sender), xhtmlEscape(MessageHeader(msg))))
+		if msg.Rating != "" {
+			sb.WriteString(fmt.Sprintf("<p class=\"rating\">Rated %s</p>\n", xhtmlEscape(RatingText(msg))))
+		}
+		for _, segment := range extractor.Segments(msg) {
+			switch segment.Kind {
+			case artifacts.KindArtifact:
+				title := segment.Artifact.Title
+				if title == "" {
+					title = segment.Artifact.ID
+				}
+				sb.WriteString(fmt.Sprintf("<p class=\"artifact\">%s (%s)</p>\n",
+					xhtmlEscape(title), xhtmlEscape(segment.Artifact.GetTypeName())))
+				sb.WriteString(fmt.Sprintf("<pre><code>%s</code></pre>\n", xhtmlEscape(strings.TrimRight(segment.Artifact.Content, "\n"))))
+			case artifacts.KindCodeBlock:
+				sb.WriteString(fmt.Sprintf("<pre><code>%s</code></pre>\n", xhtmlEscape(strings.TrimRight(segment.Content, "\n"))))
+			default:
+				writeParagraphs(&sb, segment.Content)
+			}
+		}
+	}
+
+	b.chapters = append(b.chapters, epubChapter{
+		title:   conv.Name,
+		uuid:    conv.UUID,
+		created: conv.CreatedAt,
+		updated: conv.UpdatedAt,
+		body:    sb.String(),
+	})
+}
+
+// Len returns the number of conversations added
+func (b *EPUB) Len() int {
+	return len(b.chapters)
+}
+
+// writeParagraphs writes text as paragraphs, split at blank lines, keeping
+// its line breaks
+func writeParagraphs(sb *strings.Builder, text string) {
+	for _, paragraph := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		paragraph = strings.TrimSpace(paragraph)
+		if paragraph == "" {
+			continue
+		}
+		lines := strings.Split(paragraph, "\n")
+		for i, line := range lines {
+			lines[i] = xhtmlEscape(line)
+		}
+		sb.WriteString("<p>" + strings.Join(lines, "<br/>\n") + "</p>\n")
+	}
+}
+
+// xhtmlEscape escapes text for XHTML, dropping the characters XML doesn't
+// allow, such as most control characters
+func xhtmlEscape(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\t' || r == '\n' || r == '\r':
+			return r
+		case r < 0x20 || r > 0x10FFFF || (r >= 0xD800 && r <= 0xDFFF) || r == 0xFFFE || r == 0xFFFF:
+			return -1
+		}
+		return r
+	}, s)
+	return html.EscapeString(s)
+}
+
+// title returns the book's title
+func (b *EPUB) title() string {
+	switch {
+	case b.Title != "":
+		return b.Title
+	case len(b.chapters) == 1:
+		return b.chapters[0].title
+	}
+	return fmt.Sprintf("%d Claude conversations", len(b.chapters))
+}
+
+// identifier derives the book's identifier from its conversations, so the
+// same conversations make the same book and e-readers replace rather than
+// duplicate it
+func (b *EPUB) identifier() string {
+	h := sha1.New()
+	for _, c := range b.chapters {
+		h.Write([]byte(c.uuid + "\n"))
+	}
+	sum := h.Sum(nil)
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// Write writes the book as an EPUB 3 file, with an EPUB 2 table of contents
+// for older readers
+func (b *EPUB) Write(w io.Writer) error {
+	if len(b.chapters) == 0 {
+		return fmt.Errorf("no conversations to put in the book")
+	}
+
+	var created, modified time.Time
+	for i, c := range b.chapters {
+		if i == 0 || c.created.Before(created) {
+			created = c.created
+		}
+		if c.updated.After(modified) {
+			modified = c.updated
+		}
+	}
+	title := xhtmlEscape(b.title())
+	id := b.identifier()
+
+	zw := zip.NewWriter(w)
+	// The mimetype comes first, uncompressed and without extra fields or a
+	// data descriptor, so readers can recognize the file from its first bytes
+	mimetype, err := zw.CreateRaw(&zip.FileHeader{
+		Name:               "mimetype",
+		Method:             zip.Store,
+		CRC32:              crc32.ChecksumIEEE([]byte(epubMimetype)),
+		CompressedSize64:   uint64(len(epubMimetype)),
+		UncompressedSize64: uint64(len(epubMimetype)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write EPUB: %w", err)
+	}
+	if _, err := io.WriteString(mimetype, epubMimetype); err != nil {
+		return fmt.Errorf("failed to write EPUB: %w", err)
+	}
+
+	files := []struct{ name, content string }{
+		{"META-INF/container.xml", epubContainer},
+		{"OEBPS/style.css", epubCSS},
+		{"OEBPS/content.opf", b.packageDocument(id, title, created, modified)},
+		{"OEBPS/nav.xhtml", b.navDocument(title)},
+		{"OEBPS/toc.ncx", b.ncx(id, title)},
+	}
+	for i, c := range b.chapters {
+		files = append(files, struct{ name, content string }{
+			"OEBPS/" + chapterFile(i),
+			fmt.Sprintf(epubChapterPage, xhtmlEscape(c.title), c.body),
+		})
+	}
+	for _, f := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: modified})
+		if err != nil {
+			return fmt.Errorf("failed to write EPUB: %w", err)
+		}
+		if _, err := io.WriteString(fw, f.content); err != nil {
+			return fmt.Errorf("failed to write EPUB: %w", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to write EPUB: %w", err)
+	}
+	return nil
+}
+
+// chapterFile names the file of the i-th chapter
+func chapterFile(i int) string {
+	return fmt.Sprintf("chapter-%03d.xhtml", i+1)
+}
+
+// packageDocument lists the book's metadata, files and reading order
+func (b *EPUB) packageDocument(id, title string, created, modified time.Time) string {
+	var manifest, spine strings.Builder
+	for i := range b.chapters {
+		manifest.WriteString(fmt.Sprintf("    <item id=\"chapter-%d\" href=\"%s\" media-type=\"application/xhtml+xml\"/>\n", i+1, chapterFile(i)))
+		spine.WriteString(fmt.Sprintf("    <itemref idref=\"chapter-%d\"/>\n", i+1))
+	}
+	return fmt.Sprintf(epubPackage, xhtmlEscape(id), title,
+		created.UTC().Format("2006-01-02"), modified.UTC().Format(epubTimeFormat),
+		manifest.String(), spine.String())
+}
+
+// navDocument is the EPUB 3 table of contents
+func (b *EPUB) navDocument(title string) string {
+	var items strings.Builder
+	for i, c := range b.chapters {
+		items.WriteString(fmt.Sprintf("      <li><a href=\"%s\">%s</a></li>\n", chapterFile(i), xhtmlEscape(c.title)))
+	}
+	return fmt.Sprintf(epubNav, title, items.String())
+}
+
+// ncx is the EPUB 2 table of contents
+func (b *EPUB) ncx(id, title string) string {
+	var points strings.Builder
+	for i, c := range b.chapters {
+		points.WriteString(fmt.Sprintf("    <navPoint id=\"chapter-%d\" playOrder=\"%d\"><navLabel><text>%s</text></navLabel><content src=\"%s\"/></navPoint>\n",
+			i+1, i+1, xhtmlEscape(c.title), chapterFile(i)))
+	}
+	return fmt.Sprintf(epubNCX, xhtmlEscape(id), title, points.String())
+}
+
+const epubContainer = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+const epubPackage = `<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:identifier id="book-id">%s</dc:identifier>
+    <dc:title>%s</dc:title>
+    <dc:language>en</dc:language>
+    <dc:creator>Claude</dc:creator>
+    <dc:publisher>shannon</dc:publisher>
+    <dc:date>%s</dc:date>
+    <meta property="dcterms:modified">%s</meta>
+  </metadata>
+  <manifest>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+    <item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/>
+    <item id="style" href="style.css" media-type="text/css"/>
+%s  </manifest>
+  <spine toc="ncx">
+%s  </spine>
+</package>
+`
+
+const epubNav = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops">
+<head><title>%s</title></head>
+<body>
+  <nav epub:type="toc" id="toc">
+    <h1>Contents</h1>
+    <ol>
+%s    </ol>
+  </nav>
+</body>
+</html>
+`
+
+const epubNCX = `<?xml version="1.0" encoding="UTF-8"?>
+<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/" version="2005-1">
+  <head><meta name="dtb:uid" content="%s"/></head>
+  <docTitle><text>%s</text></docTitle>
+  <navMap>
+%s  </navMap>
+</ncx>
+`
+
+const epubChapterPage = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml">
+<head>
+<title>%s</title>
+<link rel="stylesheet" type="text/css" href="style.css"/>
+</head>
+<body>
+%s</body>
+</html>
+`
+
+const epubCSS = `h2 { font-size: 1em; margin-top: 2em; border-top: 1px solid #999; padding-top: 0.5em; }
+h2.human { font-style: italic; }
+.meta, .rating, .artifact { font-size: 0.85em; color: #555; }
+pre { white-space: pre-wrap; font-size: 0.8em; background: #f4f4f4; padding: 0.5em; }
+`
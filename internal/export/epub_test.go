@@ -0,0 +1,122 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+func TestEPUB(t *testing.T) {
+	created := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	book := &EPUB{}
+	book.Add(&models.Conversation{UUID: "conv-1", Name: "Retries <b>", CreatedAt: created, UpdatedAt: created.Add(time.Hour)},
+		[]*models.Message{
+			{Sender: "human", Text: "How do I retry?\x01", CreatedAt: created},
+			{Sender: "assistant", Text: "Use a <loop>:\n\n```go\nfor i := 0; i < 3; i++ {}\n```", CreatedAt: created.Add(time.Minute)},
+		})
+	book.Add(&models.Conversation{UUID: "conv-2", Name: "Backoff", CreatedAt: created.Add(24 * time.Hour), UpdatedAt: created.Add(48 * time.Hour)},
+		[]*models.Message{{Sender: "human", Text: "And jitter?", CreatedAt: created.Add(24 * time.Hour)}})
+	if book.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", book.Len())
+	}
+
+	var buf bytes.Buffer
+	if err := book.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Readers sniff the first entry, which must be the uncompressed mimetype
+	if first := r.File[0]; first.Name != "mimetype" || first.Method != zip.Store || len(first.Extra) > 0 {
+		t.Errorf("first entry = %s (method %d, %d bytes extra), want stored mimetype without extra fields", first.Name, first.Method, len(first.Extra))
+	}
+	if !bytes.HasPrefix(buf.Bytes()[30:], []byte("mimetypeapplication/epub+zip")) {
+		t.Error("the mimetype isn't at the start of the file")
+	}
+
+	files := make(map[string]string)
+	for _, f := range r.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(rc)
+		_ = rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = string(content)
+	}
+	if files["mimetype"] != "application/epub+zip" {
+		t.Errorf("mimetype = %q", files["mimetype"])
+	}
+	for _, name := range []string{"META-INF/container.xml", "OEBPS/content.opf", "OEBPS/nav.xhtml", "OEBPS/toc.ncx", "OEBPS/chapter-001.xhtml", "OEBPS/chapter-002.xhtml"} {
+		content, ok := files[name]
+		if !ok {
+			t.Errorf("missing %s", name)
+			continue
+		}
+		// Every document must be well-formed XML
+		decoder := xml.NewDecoder(strings.NewReader(content))
+		decoder.Strict = true
+		decoder.Entity = xml.HTMLEntity
+		for {
+			if _, err := decoder.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Errorf("%s isn't well-formed: %v", name, err)
+				break
+			}
+		}
+	}
+
+	opf := files["OEBPS/content.opf"]
+	for _, want := range []string{
+		"<dc:title>2 Claude conversations</dc:title>",
+		"<dc:date>2024-03-01</dc:date>",
+		`<meta property="dcterms:modified">2024-03-03T09:30:00Z</meta>`,
+		`<itemref idref="chapter-2"/>`,
+	} {
+		if !strings.Contains(opf, want) {
+			t.Errorf("content.opf doesn't contain %q:\n%s", want, opf)
+		}
+	}
+
+	chapter := files["OEBPS/chapter-001.xhtml"]
+	for _, want := range []string{
+		"<h1>Retries &lt;b&gt;</h1>",
+		"<p>Use a &lt;loop&gt;:</p>",
+		"<pre><code>for i := 0; i &lt; 3; i++ {}</code></pre>",
+	} {
+		if !strings.Contains(chapter, want) {
+			t.Errorf("chapter doesn't contain %q:\n%s", want, chapter)
+		}
+	}
+	if !strings.Contains(files["OEBPS/nav.xhtml"], `<a href="chapter-002.xhtml">Backoff</a>`) {
+		t.Errorf("nav doesn't link the second chapter:\n%s", files["OEBPS/nav.xhtml"])
+	}
+
+	// The same conversations give the same book
+	var again bytes.Buffer
+	if err := book.Write(&again); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), again.Bytes()) {
+		t.Error("writing the same book twice gave different files")
+	}
+}
+
+func TestEPUBEmpty(t *testing.T) {
+	if err := (&EPUB{}).Write(io.Discard); err == nil {
+		t.Error("expected an error for a book without conversations")
+	}
+}
@@ -0,0 +1,185 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/neilberkman/shannon/internal/models"
+	"github.com/neilberkman/shannon/internal/rendering"
+	"github.com/neilberkman/shannon/internal/schema"
+)
+
+// Formats lists the formats a conversation can be exported in
+var Formats = []string{"markdown", "json", "text", "html"}
+
+// Render renders a conversation in the given format. Unknown formats fall
+// back to Markdown.
+func Render(format string, conv *models.Conversation, messages []*models.Message) (string, error) {
+	switch format {
+	case "json":
+		return JSON(conv, messages)
+	case "text":
+		return Text(conv, messages), nil
+	case "html":
+		return string(ConversationToHTML(conv, messages)), nil
+	case "marp":
+		return Marp(conv, messages), nil
+	case "reveal":
+		return Reveal(conv, messages), nil
+	case "slack":
+		return Slack(conv, messages), nil
+	case "discord":
+		return Discord(conv, messages), nil
+	default:
+		return Markdown(conv, messages), nil
+	}
+}
+
+// Extension returns the file extension for an export format
+func Extension(format string) string {
+	switch format {
+	case "json":
+		return ".json"
+	case "text", "slack":
+		return ".txt"
+	case "html", "reveal":
+		return ".html"
+	default:
+		return ".md"
+	}
+}
+
+// Markdown renders a conversation as Markdown
+func Markdown(conv *models.Conversation, messages []*models.Message) string {
+	var sb strings.Builder
+
+	// Header
+	sb.WriteString(fmt.Sprintf("# %s\n\n", conv.Name))
+	sb.WriteString(fmt.Sprintf("**ID:** %d  \n", conv.ID))
+	sb.WriteString(fmt.Sprintf("**Created:** %s  \n", conv.CreatedAt.Format("2006-01-02 15:04:05")))
+	sb.WriteString(fmt.Sprintf("**Updated:** %s  \n", conv.UpdatedAt.Format("2006-01-02 15:04:05")))
+	if names := Models(messages); names != "" {
+		sb.WriteString(fmt.Sprintf("**Model:** %s  \n", names))
+	}
+	sb.WriteString(fmt.Sprintf("**Messages:** %d  \n\n", len(messages)))
+	sb.WriteString("---\n\n")
+
+	// Messages
+	for i, msg := range messages {
+		sb.WriteString(fmt.Sprintf("## %s\n\n", MessageHeader(msg)))
+		if msg.Rating != "" {
+			sb.WriteString(fmt.Sprintf("*Rated %s*\n\n", RatingText(msg)))
+		}
+
+		// Handle code blocks in message text
+		text := strings.ReplaceAll(msg.Text, "```", "````")
+		sb.WriteString(text)
+		sb.WriteString("\n\n")
+
+		// Add separator between messages (except last)
+		if i < len(messages)-1 {
+			sb.WriteString("---\n\n")
+		}
+	}
+
+	return sb.String()
+}
+
+// Text renders a conversation as plain text
+func Text(conv *models.Conversation, messages []*models.Message) string {
+	var sb strings.Builder
+
+	// Header
+	sb.WriteString(fmt.Sprintf("CONVERSATION: %s\n", conv.Name))
+	sb.WriteString(fmt.Sprintf("ID: %d\n", conv.ID))
+	sb.WriteString(fmt.Sprintf("Created: %s\n", conv.CreatedAt.Format("2006-01-02 15:04:05")))
+	sb.WriteString(fmt.Sprintf("Updated: %s\n", conv.UpdatedAt.Format("2006-01-02 15:04:05")))
+	if names := Models(messages); names != "" {
+		sb.WriteString(fmt.Sprintf("Model: %s\n", names))
+	}
+	sb.WriteString(fmt.Sprintf("Messages: %d\n", len(messages)))
+	sb.WriteString(strings.Repeat("=", 80) + "\n\n")
+
+	// Messages
+	for _, msg := range messages {
+		timestamp := msg.CreatedAt.Format("2006-01-02 15:04:05")
+		sb.WriteString(fmt.Sprintf("[%s] %s", timestamp, rendering.FormatSender(msg.Sender)))
+		if msg.Model != "" {
+			sb.WriteString(" · " + msg.Model)
+		}
+		sb.WriteString("\n")
+		if msg.Rating != "" {
+			sb.WriteString(fmt.Sprintf("Rated %s\n", RatingText(msg)))
+		}
+		sb.WriteString(strings.Repeat("-", 40) + "\n")
+		sb.WriteString(msg.Text)
+		sb.WriteString("\n\n")
+	}
+
+	return sb.String()
+}
+
+// RatingText describes the rating of a message with its note, if any
+func RatingText(msg *models.Message) string {
+	if msg.RatingNote == "" {
+		return msg.Rating
+	}
+	return msg.Rating + ": " + msg.RatingNote
+}
+
+// Models lists the models that wrote a conversation's messages in the order
+// they first answered, or is empty if the export didn't record them
+func Models(messages []*models.Message) string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, msg := range messages {
+		if msg.Model != "" && !seen[msg.Model] {
+			seen[msg.Model] = true
+			names = append(names, msg.Model)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// MessageHeader is a message's sender, with the model that wrote it if
+// known, its time, and the star and feedback it was given in claude.ai
+func MessageHeader(msg *models.Message) string {
+	sender := rendering.FormatSender(msg.Sender)
+	if msg.Model != "" {
+		sender += " · " + msg.Model
+	}
+	header := fmt.Sprintf("%s (%s)", sender, msg.CreatedAt.Format("2006-01-02 15:04:05"))
+	if msg.Starred {
+		header += " ★"
+	}
+	switch msg.Feedback {
+	case models.FeedbackUp:
+		header += " [thumbs up]"
+	case models.FeedbackDown:
+		header += " [thumbs down]"
+	}
+	return header
+}
+
+// JSON renders a conversation as indented JSON
+func JSON(conv *models.Conversation, messages []*models.Message) (string, error) {
+	data := map[string]interface{}{
+		schema.Field: schema.Version,
+		"conversation": map[string]interface{}{
+			"id":         conv.ID,
+			"uuid":       conv.UUID,
+			"name":       conv.Name,
+			"created_at": conv.CreatedAt,
+			"updated_at": conv.UpdatedAt,
+		},
+		"messages": messages,
+	}
+
+	jsonBytes, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	return string(jsonBytes), nil
+}
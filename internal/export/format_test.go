@@ -0,0 +1,64 @@
+package export
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/models"
+	"github.com/neilberkman/shannon/internal/rendering"
+)
+
+func TestSenderLabels(t *testing.T) {
+	human, assistant := rendering.HumanLabel, rendering.AssistantLabel
+	t.Cleanup(func() { rendering.HumanLabel, rendering.AssistantLabel = human, assistant })
+	rendering.SetSenderLabels("Neil", "")
+
+	created := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	conv := &models.Conversation{ID: 1, Name: "Labels", CreatedAt: created, UpdatedAt: created}
+	messages := []*models.Message{
+		{ID: 1, Sender: "human", Text: "Hi", CreatedAt: created},
+		{ID: 2, Sender: "assistant", Text: "Hello", CreatedAt: created},
+	}
+
+	for _, format := range []string{"markdown", "text", "html"} {
+		out, err := Render(format, conv, messages)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out, "Neil") || !strings.Contains(out, "Claude") {
+			t.Errorf("expected %s export to use the sender labels:\n%s", format, out)
+		}
+		if strings.Contains(out, "HUMAN") || strings.Contains(out, "ASSISTANT") {
+			t.Errorf("expected no raw sender names in %s export:\n%s", format, out)
+		}
+	}
+}
+
+func TestModels(t *testing.T) {
+	created := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	conv := &models.Conversation{ID: 1, Name: "Models", CreatedAt: created, UpdatedAt: created}
+	messages := []*models.Message{
+		{ID: 1, Sender: "human", Text: "Hi", CreatedAt: created},
+		{ID: 2, Sender: "assistant", Text: "Hello", CreatedAt: created, Model: "claude-3-opus-20240229"},
+		{ID: 3, Sender: "assistant", Text: "Again", CreatedAt: created, Model: "claude-3-5-sonnet-20241022"},
+		{ID: 4, Sender: "assistant", Text: "And again", CreatedAt: created, Model: "claude-3-opus-20240229"},
+	}
+
+	if got, want := Models(messages), "claude-3-opus-20240229, claude-3-5-sonnet-20241022"; got != want {
+		t.Errorf("expected models %q, got %q", want, got)
+	}
+	if got := Models(messages[:1]); got != "" {
+		t.Errorf("expected no models without any recorded, got %q", got)
+	}
+
+	out := Markdown(conv, messages)
+	for _, want := range []string{
+		"**Model:** claude-3-opus-20240229, claude-3-5-sonnet-20241022",
+		"## Claude · claude-3-5-sonnet-20241022 (2024-03-01 09:30:00)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected Markdown export to contain %q:\n%s", want, out)
+		}
+	}
+}
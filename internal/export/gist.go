@@ -0,0 +1,121 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/neilberkman/shannon/internal/artifacts"
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+const (
+	githubAPI        = "https://api.github.com"
+	githubAPIVersion = "2022-11-28"
+)
+
+// GistPublisher shares conversations as GitHub gists: the conversation as
+// markdown, and each artifact as a file of its own. The token needs the gist
+// scope.
+type GistPublisher struct {
+	Token   string
+	Public  bool   // listed on the owner's profile instead of secret
+	BaseURL string // API URL, defaults to the public GitHub API
+	Client  *http.Client
+}
+
+type gistFile struct {
+	Content string `json:"content"`
+}
+
+// Publish creates a gist for the conversation and returns its URL
+func (p *GistPublisher) Publish(ctx context.Context, conv *models.Conversation, messages []*models.Message) (string, error) {
+	if p.Token == "" {
+		return "", fmt.Errorf("a GitHub token with the gist scope is required")
+	}
+
+	var gist struct {
+		URL string `json:"html_url"`
+	}
+	err := sendJSON(ctx, p.Client, http.MethodPost, p.baseURL()+"/gists", p.authorize, map[string]interface{}{
+		"description": conv.Name,
+		"public":      p.Public,
+		"files":       gistFiles(conv, messages),
+	}, &gist)
+	if err != nil {
+		return "", fmt.Errorf("failed to create gist: %w", err)
+	}
+	return gist.URL, nil
+}
+
+func (p *GistPublisher) baseURL() string {
+	if p.BaseURL != "" {
+		return strings.TrimRight(p.BaseURL, "/")
+	}
+	return githubAPI
+}
+
+func (p *GistPublisher) authorize(req *http.Request) {
+	req.Header.Set("Authorization", "Bearer "+p.Token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", githubAPIVersion)
+}
+
+// gistFiles names the files of a conversation's gist: the markdown export,
+// then the artifacts in the order they were written. Names are made unique
+// by numbering repeats, such as the revisions of an artifact.
+func gistFiles(conv *models.Conversation, messages []*models.Message) map[string]gistFile {
+	files := make(map[string]gistFile)
+	add := func(name, content string) {
+		if strings.TrimSpace(content) == "" {
+			return // gists can't have empty files
+		}
+		base, ext := name, ""
+		if dot := strings.LastIndex(name, "."); dot > 0 {
+			base, ext = name[:dot], name[dot:]
+		}
+		for n := 2; ; n++ {
+			if _, taken := files[name]; !taken {
+				break
+			}
+			name = fmt.Sprintf("%s-%d%s", base, n, ext)
+		}
+		files[name] = gistFile{Content: content}
+	}
+
+	add(gistFilename(conv.Name, "conversation")+".md", Markdown(conv, messages))
+
+	extractor := artifacts.NewExtractor()
+	for _, msg := range messages {
+		found, _ := extractor.ExtractFromMessage(msg)
+		for _, artifact := range found {
+			name := gistFilename(artifact.Title, gistFilename(artifact.ID, "artifact"))
+			ext := artifact.GetFileExtension()
+			if !strings.HasSuffix(strings.ToLower(name), ext) {
+				name += ext
+			}
+			add(name, artifact.Content)
+		}
+	}
+	return files
+}
+
+// gistFilename makes a title usable as a gist file name, which can't contain
+// slashes, falling back to fallback for an empty title
+func gistFilename(title, fallback string) string {
+	name := strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '\n', '\r', '\t':
+			return '-'
+		}
+		return r
+	}, strings.TrimSpace(title))
+	if runes := []rune(name); len(runes) > 100 {
+		name = string(runes[:100])
+	}
+	if name = strings.TrimSpace(name); name == "" {
+		return fallback
+	}
+	return name
+}
@@ -0,0 +1,94 @@
+package export
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/models"
+	"github.com/neilberkman/shannon/internal/rendering"
+)
+
+// ConversationToHTML renders a conversation as a standalone HTML document.
+// The title, dates and claude.ai link are stored as metadata so desktop
+// search tools like Spotlight and Recoll can index them.
+func ConversationToHTML(conv *models.Conversation, messages []*models.Message) []byte {
+	var sb strings.Builder
+
+	sb.WriteString("<!DOCTYPE html>\n<html>\n<head>\n")
+	sb.WriteString("<meta charset=\"utf-8\">\n")
+	sb.WriteString(fmt.Sprintf("<title>%s</title>\n", html.EscapeString(conv.Name)))
+	writeMeta(&sb, "description", summary(messages, 200))
+	writeMeta(&sb, "generator", "shannon")
+	writeMeta(&sb, "date", conv.CreatedAt.UTC().Format(time.RFC3339))
+	writeMeta(&sb, "dcterms.created", conv.CreatedAt.UTC().Format(time.RFC3339))
+	writeMeta(&sb, "dcterms.modified", conv.UpdatedAt.UTC().Format(time.RFC3339))
+	writeMeta(&sb, "shannon:conversation-id", fmt.Sprintf("%d", conv.ID))
+	if conv.UUID != "" {
+		sb.WriteString(fmt.Sprintf("<link rel=\"canonical\" href=\"https://claude.ai/chat/%s\">\n", html.EscapeString(conv.UUID)))
+	}
+	sb.WriteString("<style>pre { white-space: pre-wrap; font-family: inherit; }</style>\n")
+	if hasMath(messages) {
+		sb.WriteString(mathJax)
+	}
+	sb.WriteString("</head>\n<body>\n")
+
+	sb.WriteString(fmt.Sprintf("<h1>%s</h1>\n", html.EscapeString(conv.Name)))
+	sb.WriteString(fmt.Sprintf("<p>Conversation %d &middot; %d messages &middot; updated %s</p>\n",
+		conv.ID, len(messages), conv.UpdatedAt.Format("2006-01-02 15:04")))
+
+	for _, msg := range messages {
+		sb.WriteString("<hr>\n")
+		sb.WriteString(fmt.Sprintf("<h2>%s <small>%s</small></h2>\n",
+			html.EscapeString(rendering.FormatSender(msg.Sender)), msg.CreatedAt.Format("2006-01-02 15:04:05")))
+		if msg.Rating != "" {
+			sb.WriteString(fmt.Sprintf("<p><em>Rated %s</em></p>\n", html.EscapeString(RatingText(msg))))
+		}
+		sb.WriteString(fmt.Sprintf("<pre>%s</pre>\n", html.EscapeString(strings.TrimSpace(msg.Text))))
+	}
+
+	sb.WriteString("</body>\n</html>\n")
+	return []byte(sb.String())
+}
+
+// mathJax typesets the LaTeX in messages, which stays in the page as written.
+// Messages are in <pre>, which MathJax skips by default.
+const mathJax = `<script>
+MathJax = {
+  tex: {inlineMath: [['$', '$'], ['\\(', '\\)']], displayMath: [['$$', '$$'], ['\\[', '\\]']]},
+  options: {skipHtmlTags: {'[-]': ['pre']}}
+};
+</script>
+<script id="MathJax-script" async src="https://cdn.jsdelivr.net/npm/mathjax@3/es5/tex-mml-chtml.js"></script>
+`
+
+// hasMath reports whether any message contains LaTeX math, so pages without
+// it don't load MathJax
+func hasMath(messages []*models.Message) bool {
+	for _, msg := range messages {
+		if rendering.HasMath(msg.Text) {
+			return true
+		}
+	}
+	return false
+}
+
+func writeMeta(sb *strings.Builder, name, content string) {
+	sb.WriteString(fmt.Sprintf("<meta name=\"%s\" content=\"%s\">\n", name, html.EscapeString(content)))
+}
+
+// summary returns the start of the first human message, collapsed to one line
+func summary(messages []*models.Message, maxRunes int) string {
+	for _, msg := range messages {
+		if msg.Sender != "human" {
+			continue
+		}
+		text := strings.Join(strings.Fields(msg.Text), " ")
+		if runes := []rune(text); len(runes) > maxRunes {
+			text = string(runes[:maxRunes-3]) + "..."
+		}
+		return text
+	}
+	return ""
+}
@@ -0,0 +1,44 @@
+package export
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+func TestConversationToHTML(t *testing.T) {
+	created := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	conv := &models.Conversation{ID: 7, UUID: "abc-123", Name: "Parsing <html> & friends", CreatedAt: created, UpdatedAt: created.Add(time.Hour)}
+	messages := []*models.Message{
+		{ID: 1, Sender: "human", Text: "How do I parse\n\n<div> tags?", CreatedAt: created},
+		{ID: 2, Sender: "assistant", Text: "Use golang.org/x/net/html.", CreatedAt: created.Add(time.Minute), Rating: "useful", RatingNote: "works <well>"},
+	}
+
+	out := string(ConversationToHTML(conv, messages))
+
+	for _, want := range []string{
+		"<title>Parsing &lt;html&gt; &amp; friends</title>",
+		`<meta name="description" content="How do I parse &lt;div&gt; tags?">`,
+		`<meta name="dcterms.modified" content="2024-03-01T10:30:00Z">`,
+		`<link rel="canonical" href="https://claude.ai/chat/abc-123">`,
+		"<pre>How do I parse\n\n&lt;div&gt; tags?</pre>",
+		"<h2>Claude <small>2024-03-01 09:31:00</small></h2>",
+		"<p><em>Rated useful: works &lt;well&gt;</em></p>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected HTML to contain %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "MathJax") {
+		t.Error("expected no MathJax without math")
+	}
+
+	// LaTeX stays as written, for MathJax to typeset
+	messages[1].Text = `The area is $\pi r^2$.`
+	out = string(ConversationToHTML(conv, messages))
+	if !strings.Contains(out, "mathjax@3") || !strings.Contains(out, `<pre>The area is $\pi r^2$.</pre>`) {
+		t.Errorf("expected raw LaTeX and MathJax:\n%s", out)
+	}
+}
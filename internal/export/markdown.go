@@ -20,6 +20,9 @@ func ConversationToMarkdown(conv *models.Conversation, messages []*models.Messag
 	sb.WriteString(fmt.Sprintf("**Conversation ID:** %d\n\n", conv.ID))
 	sb.WriteString(fmt.Sprintf("**Created:** %s\n\n", conv.CreatedAt.Format("2006-01-02 15:04:05")))
 	sb.WriteString(fmt.Sprintf("**Updated:** %s\n\n", conv.UpdatedAt.Format("2006-01-02 15:04:05")))
+	if names := Models(messages); names != "" {
+		sb.WriteString(fmt.Sprintf("**Model:** %s\n\n", names))
+	}
 	sb.WriteString(fmt.Sprintf("**Messages:** %d\n\n", len(messages)))
 	sb.WriteString("---\n\n")
 
@@ -37,13 +40,11 @@ func ConversationToMarkdown(conv *models.Conversation, messages []*models.Messag
 
 	// Write messages
 	for i, msg := range messages {
-		// Message header with sender and timestamp
-		sender := msg.Sender
-		if len(sender) > 0 {
-			sender = strings.ToUpper(sender[:1]) + sender[1:]
+		// Message header with sender, model and timestamp
+		sb.WriteString(fmt.Sprintf("## %s\n\n", MessageHeader(msg)))
+		if msg.Rating != "" {
+			sb.WriteString(fmt.Sprintf("*Rated %s*\n\n", RatingText(msg)))
 		}
-		timestamp := msg.CreatedAt.Format("2006-01-02 15:04:05")
-		sb.WriteString(fmt.Sprintf("## %s (%s)\n\n", sender, timestamp))
 
 		// Message content
 		content := msg.Text
@@ -88,8 +89,18 @@ func ConversationToMarkdown(conv *models.Conversation, messages []*models.Messag
 
 // GenerateDefaultFilename creates a default filename for a conversation export
 func GenerateDefaultFilename(conv *models.Conversation) string {
-	// Sanitize conversation name for filename
-	name := conv.Name
+	return DefaultFilename(conv, "markdown")
+}
+
+// DefaultFilename creates a default filename for a conversation exported in format
+func DefaultFilename(conv *models.Conversation, format string) string {
+	// Add timestamp to make unique
+	timestamp := time.Now().Format("20060102-150405")
+	return fmt.Sprintf("%s-%s%s", safeName(conv.Name), timestamp, Extension(format))
+}
+
+// safeName makes a conversation name safe to use in a filename
+func safeName(name string) string {
 	name = strings.ReplaceAll(name, "/", "-")
 	name = strings.ReplaceAll(name, "\\", "-")
 	name = strings.ReplaceAll(name, ":", "-")
@@ -105,10 +116,7 @@ func GenerateDefaultFilename(conv *models.Conversation) string {
 	if len(name) > 100 {
 		name = name[:100]
 	}
-
-	// Add timestamp to make unique
-	timestamp := time.Now().Format("20060102-150405")
-	return fmt.Sprintf("%s-%s.md", name, timestamp)
+	return name
 }
 
 // removeArtifactTags removes artifact XML tags from content
@@ -0,0 +1,92 @@
+package export
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/neilberkman/shannon/internal/models"
+	"github.com/neilberkman/shannon/internal/rendering"
+)
+
+// NotesFilename names the file of research notes from a conversation, by
+// its ID so notes from the same search written again replace the old ones
+func NotesFilename(conv *models.Conversation) string {
+	return fmt.Sprintf("%d-%s.md", conv.ID, safeName(conv.Name))
+}
+
+// SearchNotes renders what a search for query found in a conversation as
+// Markdown research notes: the matched messages, marked as matches, each
+// with up to context messages before and after it and the rest left out,
+// and the words the highlighter finds in bold outside code blocks
+func SearchNotes(conv *models.Conversation, messages []*models.Message, matched map[int64]bool, context int, query string, highlighter *rendering.Highlighter) string {
+	keep := make([]bool, len(messages))
+	matches := 0
+	for i, msg := range messages {
+		if !matched[msg.ID] {
+			continue
+		}
+		matches++
+		for j := max(i-context, 0); j <= min(i+context, len(messages)-1); j++ {
+			keep[j] = true
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# %s\n\n", conv.Name))
+	sb.WriteString(fmt.Sprintf("**ID:** %d  \n", conv.ID))
+	sb.WriteString(fmt.Sprintf("**Search:** %s  \n", query))
+	sb.WriteString(fmt.Sprintf("**Updated:** %s  \n", conv.UpdatedAt.Format("2006-01-02 15:04:05")))
+	sb.WriteString(fmt.Sprintf("**Matches:** %d of %d messages  \n\n", matches, len(messages)))
+	sb.WriteString("---\n\n")
+
+	var parts []string
+	skipped := 0
+	leftOut := func() {
+		if skipped > 0 {
+			parts = append(parts, fmt.Sprintf("*… %d message(s) left out …*", skipped))
+			skipped = 0
+		}
+	}
+	for i, msg := range messages {
+		if !keep[i] {
+			skipped++
+			continue
+		}
+		leftOut()
+
+		var part strings.Builder
+		part.WriteString(fmt.Sprintf("## %d. %s", i+1, MessageHeader(msg)))
+		if matched[msg.ID] {
+			part.WriteString(" · match")
+		}
+		part.WriteString("\n\n")
+		if msg.Rating != "" {
+			part.WriteString(fmt.Sprintf("*Rated %s*\n\n", RatingText(msg)))
+		}
+		part.WriteString(markProse(strings.ReplaceAll(msg.Text, "```", "````"), highlighter))
+		parts = append(parts, part.String())
+	}
+	leftOut()
+
+	sb.WriteString(strings.Join(parts, "\n\n---\n\n"))
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// markProse puts the matches of the highlighter in bold, leaving code blocks
+// alone where the asterisks would show as they are
+func markProse(text string, highlighter *rendering.Highlighter) string {
+	lines := strings.Split(text, "\n")
+	inCode := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inCode = !inCode
+			continue
+		}
+		if !inCode {
+			lines[i] = highlighter.Wrap(line, "**", "**")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
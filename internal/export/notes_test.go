@@ -0,0 +1,56 @@
+package export
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/models"
+	"github.com/neilberkman/shannon/internal/rendering"
+)
+
+func TestSearchNotes(t *testing.T) {
+	created := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	conv := &models.Conversation{ID: 12, Name: "Cluster: setup", CreatedAt: created, UpdatedAt: created}
+	texts := []string{
+		"hello",
+		"How do I set up ingress?",
+		"Use an Ingress resource:\n```yaml\nkind: Ingress\n```\nThen check the ingress controller.",
+		"thanks",
+		"what about TLS?",
+		"Add a tls section",
+		"and monitoring?",
+		"Prometheus",
+	}
+	var messages []*models.Message
+	for i, text := range texts {
+		messages = append(messages, &models.Message{ID: int64(i + 1), Sender: []string{"assistant", "human"}[i%2], Text: text, CreatedAt: created})
+	}
+
+	out := SearchNotes(conv, messages, map[int64]bool{3: true}, 1, "ingress", rendering.NewHighlighter("ingress"))
+
+	for _, want := range []string{
+		"# Cluster: setup\n",
+		"**Search:** ingress  \n",
+		"**Matches:** 1 of 8 messages",
+		"*… 1 message(s) left out …*",
+		"## 2. You",
+		"## 3. Claude (2024-05-01 09:00:00) · match",
+		"Use an **Ingress** resource:\n````yaml\nkind: Ingress\n````\nThen check the **ingress** controller.",
+		"## 4. You",
+		"*… 4 message(s) left out …*",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected the notes to contain %q, got:\n%s", want, out)
+		}
+	}
+	for _, unwanted := range []string{"hello", "TLS", "Prometheus", "## 2. You (2024-05-01 09:00:00) · match"} {
+		if strings.Contains(out, unwanted) {
+			t.Errorf("expected the notes to leave out %q, got:\n%s", unwanted, out)
+		}
+	}
+
+	if name := NotesFilename(conv); name != "12-Cluster- setup.md" {
+		t.Errorf("unexpected notes filename %q", name)
+	}
+}
@@ -0,0 +1,183 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/neilberkman/shannon/internal/artifacts"
+	"github.com/neilberkman/shannon/internal/models"
+	"github.com/neilberkman/shannon/internal/rendering"
+)
+
+const (
+	notionAPI     = "https://api.notion.com/v1"
+	notionVersion = "2022-06-28"
+
+	// Notion accepts at most 100 blocks per request, 100 rich text objects
+	// per block and 2000 characters per rich text object
+	notionMaxBlocks = 100
+	notionMaxTexts  = 100
+	notionMaxText   = 2000
+)
+
+// notionLanguages maps normalized languages to the ones Notion highlights
+var notionLanguages = map[string]string{
+	"bash": "bash", "c": "c", "cpp": "c++", "csharp": "c#", "css": "css",
+	"diff": "diff", "dockerfile": "docker", "go": "go", "graphql": "graphql",
+	"html": "html", "java": "java", "javascript": "javascript", "jsx": "javascript",
+	"json": "json", "kotlin": "kotlin", "markdown": "markdown", "mermaid": "mermaid",
+	"php": "php", "python": "python", "ruby": "ruby", "rust": "rust",
+	"scala": "scala", "sql": "sql", "svg": "xml", "swift": "swift",
+	"tsx": "typescript", "typescript": "typescript", "xml": "xml", "yaml": "yaml",
+}
+
+// NotionPublisher creates Notion pages through the Notion API. The
+// integration the token belongs to must have access to the parent page.
+type NotionPublisher struct {
+	Token    string
+	ParentID string // page the conversation is created under
+	BaseURL  string // API URL, defaults to the public Notion API
+	Client   *http.Client
+}
+
+type notionBlock map[string]interface{}
+
+// Publish creates a Notion page for the conversation and returns its URL
+func (p *NotionPublisher) Publish(ctx context.Context, conv *models.Conversation, messages []*models.Message) (string, error) {
+	if p.Token == "" {
+		return "", fmt.Errorf("a Notion integration token is required")
+	}
+	if p.ParentID == "" {
+		return "", fmt.Errorf("a Notion parent page ID is required")
+	}
+
+	blocks := notionBlocks(conv, messages)
+	first := blocks[:min(len(blocks), notionMaxBlocks)]
+
+	var page struct {
+		ID  string `json:"id"`
+		URL string `json:"url"`
+	}
+	err := sendJSON(ctx, p.Client, http.MethodPost, p.baseURL()+"/pages", p.authorize, map[string]interface{}{
+		"parent": map[string]string{"page_id": p.ParentID},
+		"properties": map[string]interface{}{
+			"title": map[string]interface{}{"title": notionText(conv.Name)},
+		},
+		"children": first,
+	}, &page)
+	if err != nil {
+		return "", fmt.Errorf("failed to create Notion page: %w", err)
+	}
+
+	// Append the remaining blocks in batches
+	for start := len(first); start < len(blocks); start += notionMaxBlocks {
+		batch := blocks[start:min(len(blocks), start+notionMaxBlocks)]
+		url := fmt.Sprintf("%s/blocks/%s/children", p.baseURL(), page.ID)
+		if err := sendJSON(ctx, p.Client, http.MethodPatch, url, p.authorize, map[string]interface{}{"children": batch}, nil); err != nil {
+			return page.URL, fmt.Errorf("failed to add content to Notion page: %w", err)
+		}
+	}
+
+	return page.URL, nil
+}
+
+func (p *NotionPublisher) baseURL() string {
+	if p.BaseURL != "" {
+		return strings.TrimRight(p.BaseURL, "/")
+	}
+	return notionAPI
+}
+
+func (p *NotionPublisher) authorize(req *http.Request) {
+	req.Header.Set("Authorization", "Bearer "+p.Token)
+	req.Header.Set("Notion-Version", notionVersion)
+}
+
+// notionBlocks converts a conversation to Notion blocks: a heading per
+// message, paragraphs for prose, code blocks for fenced code and artifacts
+func notionBlocks(conv *models.Conversation, messages []*models.Message) []notionBlock {
+	extractor := artifacts.NewExtractor()
+
+	blocks := []notionBlock{
+		notionBlockOf("paragraph", notionText(fmt.Sprintf("Conversation %d • created %s • updated %s • %d messages",
+			conv.ID, conv.CreatedAt.Format("2006-01-02 15:04:05"), conv.UpdatedAt.Format("2006-01-02 15:04:05"), len(messages)))),
+	}
+	paragraph := func(text string) {
+		for _, p := range paragraphs(text) {
+			for _, chunk := range chunkString(p, notionMaxTexts*notionMaxText) {
+				blocks = append(blocks, notionBlockOf("paragraph", notionText(chunk)))
+			}
+		}
+	}
+	code := func(content, language string) {
+		for _, chunk := range chunkString(content, notionMaxTexts*notionMaxText) {
+			blocks = append(blocks, notionCode(chunk, language))
+		}
+	}
+
+	for _, msg := range messages {
+		blocks = append(blocks, notionBlock{"object": "block", "type": "divider", "divider": map[string]interface{}{}})
+		blocks = append(blocks, notionBlockOf("heading_2", notionText(
+			fmt.Sprintf("%s (%s)", rendering.FormatSender(msg.Sender), msg.CreatedAt.Format("2006-01-02 15:04:05")))))
+
+		for _, segment := range extractor.Segments(msg) {
+			switch segment.Kind {
+			case artifacts.KindText:
+				paragraph(segment.Content)
+			case artifacts.KindCodeBlock:
+				code(segment.Content, segment.Language)
+			case artifacts.KindArtifact:
+				artifact := segment.Artifact
+				blocks = append(blocks, notionBlockOf("heading_3", notionText(
+					fmt.Sprintf("%s (%s)", artifacts.InlineTitle(artifact), artifact.GetTypeName()))))
+				if artifact.Type == artifacts.TypeMarkdown {
+					paragraph(artifact.Content)
+				} else {
+					code(artifact.Content, segment.Language)
+				}
+			}
+		}
+	}
+
+	return blocks
+}
+
+// notionBlockOf creates a block of a type that only holds rich text
+func notionBlockOf(kind string, text []map[string]interface{}) notionBlock {
+	return notionBlock{
+		"object": "block",
+		"type":   kind,
+		kind:     map[string]interface{}{"rich_text": text},
+	}
+}
+
+// notionCode creates a code block, falling back to plain text for languages
+// Notion doesn't know
+func notionCode(content, language string) notionBlock {
+	lang, ok := notionLanguages[language]
+	if !ok {
+		lang = "plain text"
+	}
+	return notionBlock{
+		"object": "block",
+		"type":   "code",
+		"code": map[string]interface{}{
+			"rich_text": notionText(content),
+			"language":  lang,
+		},
+	}
+}
+
+// notionText creates rich text, split into objects short enough for the API
+func notionText(s string) []map[string]interface{} {
+	var text []map[string]interface{}
+	for _, chunk := range chunkString(s, notionMaxText) {
+		text = append(text, map[string]interface{}{
+			"type": "text",
+			"text": map[string]string{"content": chunk},
+		})
+	}
+	return text
+}
@@ -0,0 +1,46 @@
+package export
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+// DefaultPromptInstruction ends a prompt built from an old conversation when
+// no instruction is given
+const DefaultPromptInstruction = "Let's pick up where this conversation left off."
+
+// maxDraftURLLength keeps draft URLs within what browsers and claude.ai's
+// servers accept
+const maxDraftURLLength = 8000
+
+// Prompt assembles messages from an old conversation into a prompt that
+// starts a new chat with them as context, followed by instruction
+func Prompt(conv *models.Conversation, messages []*models.Message, instruction string) string {
+	if strings.TrimSpace(instruction) == "" {
+		instruction = DefaultPromptInstruction
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Here is an earlier conversation we had, %q from %s, for context.\n\n",
+		conv.Name, conv.CreatedAt.Format("January 2, 2006"))
+	b.WriteString("<conversation>\n")
+	for _, msg := range messages {
+		fmt.Fprintf(&b, "<message from=%q>\n%s\n</message>\n", msg.Sender, strings.TrimSpace(msg.Text))
+	}
+	b.WriteString("</conversation>\n\n")
+	b.WriteString(strings.TrimSpace(instruction) + "\n")
+	return b.String()
+}
+
+// DraftURL is a claude.ai link that opens a new chat with prompt filled in.
+// It returns false if the prompt is too long to fit in a URL.
+func DraftURL(prompt string) (string, bool) {
+	link := "https://claude.ai/new?q=" + url.QueryEscape(prompt)
+	if len(link) > maxDraftURLLength {
+		return "", false
+	}
+	return link, true
+}
@@ -0,0 +1,56 @@
+package export
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+func TestPrompt(t *testing.T) {
+	created := time.Date(2024, 5, 3, 9, 30, 0, 0, time.UTC)
+	conv := &models.Conversation{ID: 1, Name: "Pandas cleanup", CreatedAt: created}
+	messages := []*models.Message{
+		{ID: 1, Sender: "human", Text: "How do I drop empty rows?\n"},
+		{ID: 2, Sender: "assistant", Text: "Use df.dropna()."},
+	}
+
+	prompt := Prompt(conv, messages, "")
+	expected := `Here is an earlier conversation we had, "Pandas cleanup" from May 3, 2024, for context.
+
+<conversation>
+<message from="human">
+How do I drop empty rows?
+</message>
+<message from="assistant">
+Use df.dropna().
+</message>
+</conversation>
+
+` + DefaultPromptInstruction + "\n"
+	if prompt != expected {
+		t.Errorf("unexpected prompt:\n%s", prompt)
+	}
+
+	if prompt := Prompt(conv, messages, "Now do it in polars."); !strings.HasSuffix(prompt, "</conversation>\n\nNow do it in polars.\n") {
+		t.Errorf("expected the instruction to end the prompt:\n%s", prompt)
+	}
+
+	link, ok := DraftURL(prompt)
+	if !ok {
+		t.Fatal("expected a short prompt to fit in a URL")
+	}
+	parsed, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Host != "claude.ai" || parsed.Query().Get("q") != prompt {
+		t.Errorf("unexpected draft URL %s", link)
+	}
+
+	if _, ok := DraftURL(strings.Repeat("x", maxDraftURLLength)); ok {
+		t.Error("expected a long prompt not to fit in a URL")
+	}
+}
@@ -0,0 +1,136 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+// Publishers lists the services a conversation can be published to: wikis,
+// as a page, and GitHub, as a gist
+var Publishers = []string{"notion", "confluence", "gist"}
+
+// Publisher creates wiki pages or gists from conversations
+type Publisher interface {
+	// Publish creates a page or gist for the conversation and returns its URL
+	Publish(ctx context.Context, conv *models.Conversation, messages []*models.Message) (string, error)
+}
+
+// IsPublisher reports whether format names a service rather than a file format
+func IsPublisher(format string) bool {
+	for _, p := range Publishers {
+		if format == p {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultClient is used by publishers without their own HTTP client
+var defaultClient = &http.Client{Timeout: 30 * time.Second}
+
+// APIError is an error response from a wiki API
+type APIError struct {
+	Status  int
+	Message string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("API request failed with status %d", e.Status)
+	}
+	return fmt.Sprintf("API request failed with status %d: %s", e.Status, e.Message)
+}
+
+// sendJSON sends body as JSON and decodes the JSON response into out.
+// authorize adds the credentials to the request.
+func sendJSON(ctx context.Context, client *http.Client, method, url string, authorize func(*http.Request), body, out interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	authorize(req)
+
+	if client == nil {
+		client = defaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &APIError{Status: resp.StatusCode, Message: errorMessage(respBody)}
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// errorMessage extracts the message from a JSON error response, falling back
+// to the start of the body
+func errorMessage(body []byte) string {
+	var resp struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &resp); err == nil && resp.Message != "" {
+		return resp.Message
+	}
+	message := strings.TrimSpace(string(body))
+	if len(message) > 200 {
+		message = message[:200] + "..."
+	}
+	return message
+}
+
+// chunkString splits s into pieces of at most size runes
+func chunkString(s string, size int) []string {
+	runes := []rune(s)
+	if len(runes) <= size {
+		return []string{s}
+	}
+
+	var chunks []string
+	for len(runes) > size {
+		chunks = append(chunks, string(runes[:size]))
+		runes = runes[size:]
+	}
+	if len(runes) > 0 {
+		chunks = append(chunks, string(runes))
+	}
+	return chunks
+}
+
+// paragraphs splits prose at blank lines
+func paragraphs(text string) []string {
+	var result []string
+	for _, p := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		if p = strings.TrimSpace(p); p != "" {
+			result = append(result, p)
+		}
+	}
+	return result
+}
@@ -0,0 +1,192 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+func publishFixture() (*models.Conversation, []*models.Message) {
+	created := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	conv := &models.Conversation{ID: 7, Name: "Retry loops", CreatedAt: created, UpdatedAt: created}
+	messages := []*models.Message{
+		{ID: 1, Sender: "human", Text: "How do I retry?\n\nWith backoff.", CreatedAt: created},
+		{ID: 2, Sender: "assistant", Text: "Like this:\n```golang\nfor i := 0; i < 3; i++ {}\n```\n" +
+			"<antArtifact identifier=\"retry\" type=\"application/vnd.ant.code\" language=\"python\" title=\"retry.py\">\nif a]]>b: pass\n</antArtifact>",
+			CreatedAt: created.Add(time.Minute)},
+	}
+	return conv, messages
+}
+
+func TestNotionPublisher(t *testing.T) {
+	var requests []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" || r.Header.Get("Notion-Version") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = fmt.Fprint(w, `{"message": "API token is invalid."}`)
+			return
+		}
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		body["path"] = r.Method + " " + r.URL.Path
+		requests = append(requests, body)
+		_, _ = fmt.Fprint(w, `{"id": "page-1", "url": "https://www.notion.so/page-1"}`)
+	}))
+	defer server.Close()
+
+	conv, messages := publishFixture()
+	// Enough messages to need a second request
+	for i := 0; i < 30; i++ {
+		messages = append(messages, &models.Message{ID: int64(10 + i), Sender: "human", Text: "more", CreatedAt: conv.CreatedAt})
+	}
+
+	publisher := &NotionPublisher{Token: "secret", ParentID: "parent-1", BaseURL: server.URL}
+	url, err := publisher.Publish(context.Background(), conv, messages)
+	if err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if url != "https://www.notion.so/page-1" {
+		t.Errorf("unexpected URL %q", url)
+	}
+	if len(requests) != 2 || requests[0]["path"] != "POST /pages" || requests[1]["path"] != "PATCH /blocks/page-1/children" {
+		t.Fatalf("expected a page to be created and then extended, got %v", requests)
+	}
+
+	page, _ := json.Marshal(requests[0])
+	for _, want := range []string{
+		`"parent":{"page_id":"parent-1"}`,
+		`"code":{"language":"go","rich_text":[{"text":{"content":"for i := 0; i \u003c 3; i++ {}"}`,
+		`"code":{"language":"python"`,
+		`"heading_3":{"rich_text":[{"text":{"content":"📄 retry.py (python code)"}`,
+	} {
+		if !strings.Contains(string(page), want) {
+			t.Errorf("expected page request to contain %s:\n%s", want, page)
+		}
+	}
+
+	publisher.Token = "wrong"
+	_, err = publisher.Publish(context.Background(), conv, messages)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusUnauthorized || apiErr.Message != "API token is invalid." {
+		t.Errorf("expected an API error, got %v", err)
+	}
+}
+
+func TestConfluencePublisher(t *testing.T) {
+	var page map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, token, ok := r.BasicAuth(); !ok || user != "me@example.com" || token != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&page); err != nil {
+			t.Error(err)
+		}
+		_, _ = fmt.Fprint(w, `{"id": "42", "_links": {"base": "https://example.atlassian.net/wiki", "webui": "/spaces/ENG/pages/42"}}`)
+	}))
+	defer server.Close()
+
+	conv, messages := publishFixture()
+	publisher := &ConfluencePublisher{BaseURL: server.URL, User: "me@example.com", Token: "secret", Space: "ENG", ParentID: "7"}
+	url, err := publisher.Publish(context.Background(), conv, messages)
+	if err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if url != "https://example.atlassian.net/wiki/spaces/ENG/pages/42" {
+		t.Errorf("unexpected URL %q", url)
+	}
+
+	body := page["body"].(map[string]interface{})["storage"].(map[string]interface{})["value"].(string)
+	for _, want := range []string{
+		"<p>How do I retry?</p><p>With backoff.</p>",
+		`<ac:parameter ac:name="language">go</ac:parameter><ac:plain-text-body><![CDATA[for i := 0; i < 3; i++ {}]]>`,
+		`<ac:parameter ac:name="title">retry.py (python code)</ac:parameter><ac:parameter ac:name="language">py</ac:parameter>`,
+		"<![CDATA[if a]]]]><![CDATA[>b: pass]]>",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected page body to contain %s:\n%s", want, body)
+		}
+	}
+	if ancestors := page["ancestors"].([]interface{}); len(ancestors) != 1 {
+		t.Errorf("expected the parent page as ancestor, got %v", ancestors)
+	}
+}
+
+func TestGistPublisher(t *testing.T) {
+	var gist struct {
+		Description string                       `json:"description"`
+		Public      bool                         `json:"public"`
+		Files       map[string]map[string]string `json:"files"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = fmt.Fprint(w, `{"message": "Bad credentials"}`)
+			return
+		}
+		if r.Method != http.MethodPost || r.URL.Path != "/gists" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&gist); err != nil {
+			t.Error(err)
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = fmt.Fprint(w, `{"id": "abc", "html_url": "https://gist.github.com/me/abc"}`)
+	}))
+	defer server.Close()
+
+	conv, messages := publishFixture()
+	// A revision of the artifact gets a file of its own, an empty one none
+	messages = append(messages,
+		&models.Message{ID: 3, Sender: "assistant", CreatedAt: conv.CreatedAt,
+			Text: "<antArtifact identifier=\"retry\" type=\"application/vnd.ant.code\" language=\"python\" title=\"retry.py\">\nretry()\n</antArtifact>"},
+		&models.Message{ID: 4, Sender: "assistant", CreatedAt: conv.CreatedAt,
+			Text: "<antArtifact identifier=\"empty\" type=\"text/markdown\" title=\"notes/draft\">\n</antArtifact>"})
+
+	publisher := &GistPublisher{Token: "secret", Public: true, BaseURL: server.URL}
+	url, err := publisher.Publish(context.Background(), conv, messages)
+	if err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if url != "https://gist.github.com/me/abc" {
+		t.Errorf("unexpected URL %q", url)
+	}
+	if gist.Description != "Retry loops" || !gist.Public {
+		t.Errorf("unexpected gist %+v", gist)
+	}
+
+	var names []string
+	for name := range gist.Files {
+		names = append(names, name)
+	}
+	if len(gist.Files) != 3 {
+		t.Fatalf("expected the conversation and two revisions of the artifact, got %v", names)
+	}
+	if !strings.Contains(gist.Files["Retry loops.md"]["content"], "How do I retry?") {
+		t.Errorf("expected the conversation as markdown, got %v", names)
+	}
+	if gist.Files["retry.py"]["content"] != "if a]]>b: pass" || gist.Files["retry-2.py"]["content"] != "retry()" {
+		t.Errorf("expected both revisions of the artifact, got %v", gist.Files)
+	}
+
+	publisher.Token = ""
+	if _, err := publisher.Publish(context.Background(), conv, messages); err == nil {
+		t.Error("expected an error without a token")
+	}
+	publisher.Token = "wrong"
+	_, err = publisher.Publish(context.Background(), conv, messages)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusUnauthorized || apiErr.Message != "Bad credentials" {
+		t.Errorf("expected the API error, got %v", err)
+	}
+}
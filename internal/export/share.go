@@ -0,0 +1,237 @@
+package export
+
+import (
+	"encoding/base64"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/neilberkman/shannon/internal/artifacts"
+	"github.com/neilberkman/shannon/internal/models"
+	"github.com/neilberkman/shannon/internal/rendering"
+)
+
+// sharePreviewRunes is the most of a message shown in its collapsed summary
+const sharePreviewRunes = 90
+
+// Share renders a conversation as a single HTML page to send to someone who
+// doesn't use shannon. Everything the page needs is inside it: messages can
+// be collapsed and searched without a network connection, and each artifact
+// can be downloaded as a file.
+func Share(conv *models.Conversation, messages []*models.Message) []byte {
+	var sb strings.Builder
+
+	sb.WriteString("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n")
+	sb.WriteString("<meta charset=\"utf-8\">\n")
+	sb.WriteString("<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n")
+	sb.WriteString(fmt.Sprintf("<title>%s</title>\n", html.EscapeString(conv.Name)))
+	writeMeta(&sb, "generator", "shannon")
+	sb.WriteString("<style>\n" + shareCSS + "</style>\n")
+	sb.WriteString("</head>\n<body>\n<header>\n")
+
+	sb.WriteString(fmt.Sprintf("<h1>%s</h1>\n", html.EscapeString(conv.Name)))
+	sb.WriteString(fmt.Sprintf("<p class=\"meta\">%d messages &middot; %s to %s</p>\n",
+		len(messages), conv.CreatedAt.Format("2006-01-02 15:04"), conv.UpdatedAt.Format("2006-01-02 15:04")))
+	sb.WriteString("<nav>\n<input id=\"find\" type=\"search\" placeholder=\"Search this conversation\" autocomplete=\"off\">\n")
+	sb.WriteString("<span id=\"count\"></span>\n")
+	sb.WriteString("<button type=\"button\" id=\"expand\">Expand all</button>\n")
+	sb.WriteString("<button type=\"button\" id=\"collapse\">Collapse all</button>\n</nav>\n</header>\n<main>\n")
+
+	extractor := artifacts.NewExtractor()
+	taken := make(map[string]bool)
+	for i, msg := range messages {
+		sb.WriteString(fmt.Sprintf("<details class=\"message %s\" id=\"message-%d\" open>\n", html.EscapeString(msg.Sender), i+1))
+		sb.WriteString(fmt.Sprintf("<summary><strong>%s</strong> <time>%s</time> <span class=\"preview\">%s</span></summary>\n",
+			html.EscapeString(rendering.FormatSender(msg.Sender)), msg.CreatedAt.Format("2006-01-02 15:04:05"),
+			html.EscapeString(preview(extractor, msg.Text, sharePreviewRunes))))
+		if msg.Rating != "" {
+			sb.WriteString(fmt.Sprintf("<p class=\"rating\">Rated %s</p>\n", html.EscapeString(RatingText(msg))))
+		}
+		for _, segment := range extractor.Segments(msg) {
+			switch segment.Kind {
+			case artifacts.KindArtifact:
+				writeShareArtifact(&sb, segment.Artifact, taken)
+			case artifacts.KindCodeBlock:
+				sb.WriteString(fmt.Sprintf("<pre><code>%s</code></pre>\n", html.EscapeString(strings.TrimRight(segment.Content, "\n"))))
+			default:
+				sb.WriteString(fmt.Sprintf("<div class=\"text\">%s</div>\n", html.EscapeString(strings.TrimSpace(segment.Content))))
+			}
+		}
+		sb.WriteString("</details>\n")
+	}
+
+	sb.WriteString("</main>\n")
+	sb.WriteString("<footer>Shared with <a href=\"https://github.com/neilberkman/shannon\">shannon</a></footer>\n")
+	sb.WriteString("<script>\n" + shareJS + "</script>\n")
+	sb.WriteString("</body>\n</html>\n")
+	return []byte(sb.String())
+}
+
+// writeShareArtifact writes an artifact with a link that downloads it. The
+// file is in the link itself, so the page stays a single file.
+func writeShareArtifact(sb *strings.Builder, artifact *artifacts.Artifact, taken map[string]bool) {
+	name := gistFilename(artifact.Title, gistFilename(artifact.ID, "artifact"))
+	if ext := artifact.GetFileExtension(); !strings.HasSuffix(strings.ToLower(name), ext) {
+		name += ext
+	}
+	name = shareFilename(name, taken)
+
+	mime := "text/plain"
+	switch artifact.Type {
+	case artifacts.TypeHTML, artifacts.TypeSVG, artifacts.TypeMarkdown:
+		mime = artifact.Type
+	}
+	href := fmt.Sprintf("data:%s;charset=utf-8;base64,%s", mime, base64.StdEncoding.EncodeToString([]byte(artifact.Content)))
+
+	title := artifact.Title
+	if title == "" {
+		title = artifact.ID
+	}
+	sb.WriteString("<figure class=\"artifact\">\n")
+	sb.WriteString(fmt.Sprintf("<figcaption><strong>%s</strong> <span>%s</span> <a download=\"%s\" href=\"%s\">Download %s</a></figcaption>\n",
+		html.EscapeString(title), html.EscapeString(artifact.GetTypeName()), html.EscapeString(name), href, html.EscapeString(name)))
+	sb.WriteString(fmt.Sprintf("<pre><code>%s</code></pre>\n</figure>\n", html.EscapeString(artifact.Content)))
+}
+
+// shareFilename numbers repeats of a download name, such as the revisions of
+// an artifact, so saving one doesn't offer to replace another
+func shareFilename(name string, taken map[string]bool) string {
+	base, ext := name, ""
+	if dot := strings.LastIndex(name, "."); dot > 0 {
+		base, ext = name[:dot], name[dot:]
+	}
+	candidate := name
+	for n := 2; taken[strings.ToLower(candidate)]; n++ {
+		candidate = fmt.Sprintf("%s-%d%s", base, n, ext)
+	}
+	taken[strings.ToLower(candidate)] = true
+	return candidate
+}
+
+// preview returns the start of a message collapsed to one line, without its
+// artifact tags
+func preview(extractor *artifacts.Extractor, text string, maxRunes int) string {
+	text = extractor.ArtifactRegex.ReplaceAllString(text, "[artifact]")
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > maxRunes {
+		text = string(runes[:maxRunes-1]) + "…"
+	}
+	return text
+}
+
+const shareCSS = `body { max-width: 52rem; margin: 0 auto; padding: 0 1rem 2rem; font: 16px/1.5 system-ui, sans-serif; color: #1f2328; background: #fff; }
+header { position: sticky; top: 0; background: #fff; padding: 0.5rem 0; border-bottom: 1px solid #d0d7de; }
+h1 { font-size: 1.4rem; margin: 0.5rem 0 0; }
+.meta, time, .rating, footer { color: #656d76; font-size: 0.85rem; }
+nav { display: flex; gap: 0.5rem; align-items: center; }
+#find { flex: 1; padding: 0.3rem 0.5rem; font: inherit; }
+details.message { margin: 1rem 0; padding: 0.5rem 1rem; border: 1px solid #d0d7de; border-radius: 6px; }
+details.message.human { background: #f6f8fa; }
+summary { cursor: pointer; }
+details[open] .preview { display: none; }
+.preview { color: #656d76; }
+.text { white-space: pre-wrap; margin: 0.75rem 0; }
+pre { background: #f6f8fa; border: 1px solid #d0d7de; border-radius: 6px; padding: 0.75rem; overflow-x: auto; }
+details.human pre { background: #fff; }
+figure.artifact { margin: 0.75rem 0; }
+figcaption span { color: #656d76; font-size: 0.85rem; }
+figcaption a { float: right; font-size: 0.85rem; }
+mark { background: #fff3a3; }
+mark.current { background: #ffb347; }
+[hidden] { display: none !important; }
+@media (prefers-color-scheme: dark) {
+  body, header { color: #e6edf3; background: #0d1117; }
+  details.message, pre, header { border-color: #30363d; }
+  details.message.human, pre { background: #161b22; }
+  details.human pre { background: #0d1117; }
+  mark { background: #bb800966; color: inherit; }
+  a { color: #4493f8; }
+}
+`
+
+// shareJS searches the messages of the page: messages without the words
+// typed are hidden and the words are highlighted in the rest. Enter moves to
+// the next match.
+const shareJS = `(function () {
+  var find = document.getElementById('find');
+  var count = document.getElementById('count');
+  var messages = Array.prototype.slice.call(document.querySelectorAll('details.message'));
+  var marks = [];
+  var current = -1;
+
+  function clear() {
+    marks.forEach(function (mark) {
+      var parent = mark.parentNode;
+      parent.replaceChild(document.createTextNode(mark.textContent), mark);
+      parent.normalize();
+    });
+    marks = [];
+    current = -1;
+  }
+
+  function highlight(root, word) {
+    var walker = document.createTreeWalker(root, NodeFilter.SHOW_TEXT);
+    var nodes = [];
+    while (walker.nextNode()) {
+      if (walker.currentNode.parentNode.closest('summary')) continue;
+      nodes.push(walker.currentNode);
+    }
+    nodes.forEach(function (node) {
+      var text = node.nodeValue;
+      var lower = text.toLowerCase();
+      var at = lower.indexOf(word);
+      if (at < 0) return;
+      var fragment = document.createDocumentFragment();
+      var last = 0;
+      for (; at >= 0; at = lower.indexOf(word, last)) {
+        fragment.appendChild(document.createTextNode(text.slice(last, at)));
+        var mark = document.createElement('mark');
+        mark.textContent = text.slice(at, at + word.length);
+        fragment.appendChild(mark);
+        last = at + word.length;
+      }
+      fragment.appendChild(document.createTextNode(text.slice(last)));
+      node.parentNode.replaceChild(fragment, node);
+    });
+  }
+
+  function search() {
+    clear();
+    var words = find.value.toLowerCase().split(/\s+/).filter(Boolean);
+    var shown = 0;
+    messages.forEach(function (message) {
+      var text = message.textContent.toLowerCase();
+      var match = words.every(function (word) { return text.indexOf(word) >= 0; });
+      message.hidden = !match;
+      if (!match) return;
+      shown++;
+      if (words.length) {
+        message.open = true;
+        words.forEach(function (word) { highlight(message, word); });
+      }
+    });
+    marks = Array.prototype.slice.call(document.querySelectorAll('mark'));
+    count.textContent = words.length ? shown + ' of ' + messages.length + ' messages' : '';
+  }
+
+  function next() {
+    if (!marks.length) return;
+    if (current >= 0) marks[current].classList.remove('current');
+    current = (current + 1) % marks.length;
+    marks[current].classList.add('current');
+    marks[current].scrollIntoView({ block: 'center' });
+  }
+
+  find.addEventListener('input', search);
+  find.addEventListener('keydown', function (event) {
+    if (event.key === 'Enter') { event.preventDefault(); next(); }
+    if (event.key === 'Escape') { find.value = ''; search(); }
+  });
+  document.getElementById('expand').addEventListener('click', function () {
+    messages.forEach(function (message) { message.open = true; });
+  });
+  document.getElementById('collapse').addEventListener('click', function () {
+    messages.forEach(function (message) { message.open = false; });
+  });
+})();
+`
@@ -0,0 +1,47 @@
+package export
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+func TestShare(t *testing.T) {
+	created := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	conv := &models.Conversation{ID: 7, Name: "Deploying <app>", CreatedAt: created, UpdatedAt: created.Add(time.Hour)}
+	artifact := `<antArtifact identifier="deploy" type="application/vnd.ant.code" language="yaml" title="deploy.yaml">kind: Deployment
+name: "</script>"</antArtifact>`
+	messages := []*models.Message{
+		{ID: 1, Sender: "human", Text: "How do I deploy <this>?", CreatedAt: created},
+		{ID: 2, Sender: "assistant", Text: "Like this:\n\n" + artifact + "\n\nThen revise it:\n\n" + artifact, CreatedAt: created.Add(time.Minute)},
+	}
+
+	out := string(Share(conv, messages))
+
+	encoded := base64.StdEncoding.EncodeToString([]byte("kind: Deployment\nname: \"</script>\""))
+	for _, want := range []string{
+		"<title>Deploying &lt;app&gt;</title>",
+		`<details class="message human" id="message-1" open>`,
+		`<span class="preview">How do I deploy &lt;this&gt;?</span>`,
+		`<div class="text">Like this:</div>`,
+		`<a download="deploy.yaml" href="data:text/plain;charset=utf-8;base64,` + encoded + `">`,
+		`<a download="deploy-2.yaml"`,
+		"name: &#34;&lt;/script&gt;&#34;",
+		`<input id="find" type="search"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected page to contain %q:\n%s", want, out)
+		}
+	}
+
+	// The page is one file: nothing is loaded from elsewhere
+	if strings.Contains(out, "src=") || strings.Contains(out, "<link") {
+		t.Errorf("expected no external resources:\n%s", out)
+	}
+	if strings.Count(out, "<script>") != 1 || strings.Count(out, "</script>") != 1 {
+		t.Error("expected message text not to close the page's script")
+	}
+}
@@ -0,0 +1,152 @@
+package export
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/neilberkman/shannon/internal/artifacts"
+	"github.com/neilberkman/shannon/internal/models"
+	"github.com/neilberkman/shannon/internal/rendering"
+)
+
+// slideHeadingRunes is the longest question shown as a slide heading
+const slideHeadingRunes = 70
+
+// thematicBreakRegex matches Markdown horizontal rules, which both Marp and
+// reveal.js would read as the start of a new slide
+var thematicBreakRegex = regexp.MustCompile(`^ {0,3}(?:(?:-[ \t]*){3,}|(?:\*[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+
+// revealCDN is where reveal.js presentations load reveal.js from
+const revealCDN = "https://unpkg.com/reveal.js@5"
+
+// Marp renders a conversation as a Marp slide deck: a title slide, a slide
+// for each question with its answer, and a code slide for each artifact
+func Marp(conv *models.Conversation, messages []*models.Message) string {
+	var sb strings.Builder
+	sb.WriteString("---\nmarp: true\npaginate: true\n")
+	sb.WriteString(fmt.Sprintf("title: %s\n---\n\n", strconv.Quote(conv.Name)))
+	sb.WriteString(strings.Join(slides(conv, messages), "\n\n---\n\n"))
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// Reveal renders the slides of Marp as a standalone reveal.js presentation
+// that loads reveal.js from a CDN
+func Reveal(conv *models.Conversation, messages []*models.Message) string {
+	var sb strings.Builder
+	sb.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	sb.WriteString(fmt.Sprintf("<title>%s</title>\n", html.EscapeString(conv.Name)))
+	sb.WriteString(fmt.Sprintf("<link rel=\"stylesheet\" href=\"%s/dist/reveal.css\">\n", revealCDN))
+	sb.WriteString(fmt.Sprintf("<link rel=\"stylesheet\" href=\"%s/dist/theme/white.css\">\n", revealCDN))
+	sb.WriteString(fmt.Sprintf("<link rel=\"stylesheet\" href=\"%s/plugin/highlight/monokai.css\">\n", revealCDN))
+	sb.WriteString("</head>\n<body>\n<div class=\"reveal\">\n<div class=\"slides\">\n")
+
+	// One section per slide, so nothing in a message can split a slide
+	for _, slide := range slides(conv, messages) {
+		sb.WriteString("<section data-markdown><textarea data-template>\n")
+		sb.WriteString(html.EscapeString(slide))
+		sb.WriteString("\n</textarea></section>\n")
+	}
+
+	sb.WriteString("</div>\n</div>\n")
+	sb.WriteString(fmt.Sprintf("<script src=\"%s/dist/reveal.js\"></script>\n", revealCDN))
+	sb.WriteString(fmt.Sprintf("<script src=\"%s/plugin/markdown/markdown.js\"></script>\n", revealCDN))
+	sb.WriteString(fmt.Sprintf("<script src=\"%s/plugin/highlight/highlight.js\"></script>\n", revealCDN))
+	sb.WriteString("<script>Reveal.initialize({ hash: true, plugins: [RevealMarkdown, RevealHighlight] });</script>\n")
+	sb.WriteString("</body>\n</html>\n")
+	return sb.String()
+}
+
+// slides splits a conversation into the Markdown of its slides. Each human
+// message starts a slide headed by the question, followed by the answers up
+// to the next question; artifacts in the answers get slides of their own
+// after it.
+func slides(conv *models.Conversation, messages []*models.Message) []string {
+	title := fmt.Sprintf("# %s\n\n%s · %d messages", conv.Name, conv.CreatedAt.Format("January 2, 2006"), len(messages))
+	result := []string{title}
+
+	extractor := artifacts.NewExtractor()
+	var current strings.Builder
+	var pending []*artifacts.Segment
+	flush := func() {
+		if current.Len() > 0 {
+			result = append(result, strings.TrimSpace(current.String()))
+			current.Reset()
+		}
+		for _, segment := range pending {
+			result = append(result, artifactSlide(segment))
+		}
+		pending = nil
+	}
+
+	for _, msg := range messages {
+		if msg.Sender == "human" {
+			flush()
+			question := strings.TrimSpace(msg.Text)
+			heading := slideHeading(question)
+			current.WriteString("## " + heading + "\n\n")
+			if heading != strings.Join(strings.Fields(question), " ") {
+				current.WriteString(quote(slideMarkdown(extractor, msg, nil)) + "\n\n")
+			}
+			continue
+		}
+
+		current.WriteString(fmt.Sprintf("**%s:** ", rendering.FormatSender(msg.Sender)))
+		current.WriteString(slideMarkdown(extractor, msg, &pending) + "\n\n")
+	}
+	flush()
+
+	return result
+}
+
+// slideMarkdown renders a message for a slide without the horizontal rules
+// that would split it, appending its artifacts to pending and referring to
+// them in their place
+func slideMarkdown(extractor *artifacts.Extractor, msg *models.Message, pending *[]*artifacts.Segment) string {
+	var parts []string
+	for _, segment := range extractor.Segments(msg) {
+		switch segment.Kind {
+		case artifacts.KindCodeBlock:
+			parts = append(parts, artifacts.Fence(segment.Content, segment.Language))
+		case artifacts.KindArtifact:
+			*pending = append(*pending, segment)
+			parts = append(parts, fmt.Sprintf("*Artifact: %s (after this slide)*", segment.Artifact.Title))
+		default:
+			var lines []string
+			for _, line := range strings.Split(segment.Content, "\n") {
+				if !thematicBreakRegex.MatchString(line) {
+					lines = append(lines, line)
+				}
+			}
+			parts = append(parts, strings.Join(lines, "\n"))
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// artifactSlide renders an artifact segment as a code slide
+func artifactSlide(segment *artifacts.Segment) string {
+	return fmt.Sprintf("## %s\n\n%s", segment.Artifact.Title, artifacts.Fence(segment.Content, segment.Language))
+}
+
+// slideHeading collapses a question to one line short enough for a heading,
+// cut at a word boundary
+func slideHeading(question string) string {
+	line := strings.Join(strings.Fields(question), " ")
+	if runes := []rune(line); len(runes) > slideHeadingRunes {
+		line = string(runes[:slideHeadingRunes-1])
+		if space := strings.LastIndex(line, " "); space > 0 {
+			line = line[:space]
+		}
+		line += "…"
+	}
+	return line
+}
+
+// quote turns Markdown into a blockquote
+func quote(markdown string) string {
+	return "> " + strings.ReplaceAll(markdown, "\n", "\n> ")
+}
@@ -0,0 +1,50 @@
+package export
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+func TestSlides(t *testing.T) {
+	created := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	conv := &models.Conversation{ID: 7, Name: "Cache design", CreatedAt: created, UpdatedAt: created}
+	messages := []*models.Message{
+		{ID: 1, Sender: "human", Text: "How should we cache?", CreatedAt: created},
+		{ID: 2, Sender: "assistant", Text: "Use an LRU.\n\n---\n\nHere it is:\n<antArtifact identifier=\"lru\" type=\"application/vnd.ant.code\" language=\"go\" title=\"LRU cache\">\ntype LRU struct{}\n</antArtifact>", CreatedAt: created},
+		{ID: 3, Sender: "human", Text: "And how do we invalidate entries when the underlying rows change in the database?\nWith triggers?", CreatedAt: created},
+		{ID: 4, Sender: "assistant", Text: "Version them:\n```sql\n---\nSELECT 1;\n```", CreatedAt: created},
+	}
+
+	got := slides(conv, messages)
+	want := []string{
+		"# Cache design\n\nMarch 1, 2024 · 4 messages",
+		"## How should we cache?\n\n**Claude:** Use an LRU.\n\n\nHere it is:\n\n*Artifact: LRU cache (after this slide)*",
+		"## LRU cache\n\n```go\ntype LRU struct{}\n```",
+		"## And how do we invalidate entries when the underlying rows change in…\n\n" +
+			"> And how do we invalidate entries when the underlying rows change in the database?\n> With triggers?\n\n" +
+			"**Claude:** Version them:\n\n```sql\n---\nSELECT 1;\n```",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d slides, got %d:\n%s", len(want), len(got), strings.Join(got, "\n=====\n"))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("slide %d:\ngot  %q\nwant %q", i, got[i], want[i])
+		}
+	}
+
+	// Marp separates the slides with rules after its front matter
+	deck := Marp(conv, messages)
+	if !strings.HasPrefix(deck, "---\nmarp: true\n") || strings.Count(deck, "\n---\n") != len(want)+1 {
+		t.Errorf("unexpected Marp deck:\n%s", deck)
+	}
+
+	// reveal.js gets a section per slide, escaped so messages can't end it
+	page := Reveal(conv, append(messages, &models.Message{ID: 5, Sender: "human", Text: "What about </textarea>?"}))
+	if strings.Count(page, "<section data-markdown>") != len(want)+1 || !strings.Contains(page, "## What about &lt;/textarea&gt;?") {
+		t.Errorf("unexpected reveal.js page:\n%s", page)
+	}
+}
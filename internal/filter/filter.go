@@ -0,0 +1,89 @@
+// Package filter picks the messages of a conversation to show or export, so
+// view and export leave out the same ones for the same flags
+package filter
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+// Filter keeps the messages of a sender whose text matches the include
+// patterns and none of the exclude patterns. The zero Filter keeps every
+// message.
+type Filter struct {
+	Sender  string           // only messages of this sender, or empty for both
+	Include []*regexp.Regexp // messages must match one of these, if any
+	Exclude []*regexp.Regexp // messages matching any of these are dropped
+}
+
+// New returns a filter for a sender and the include and exclude patterns,
+// as given to --include-pattern and --exclude-pattern
+func New(sender string, include, exclude []string) (*Filter, error) {
+	f := &Filter{Sender: sender}
+	var err error
+	if f.Include, err = compile("--include-pattern", include); err != nil {
+		return nil, err
+	}
+	if f.Exclude, err = compile("--exclude-pattern", exclude); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// compile compiles the patterns of a flag
+func compile(flag string, patterns []string) ([]*regexp.Regexp, error) {
+	var compiled []*regexp.Regexp
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q: %w", flag, pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// IsEmpty reports whether the filter keeps every message
+func (f *Filter) IsEmpty() bool {
+	return f == nil || f.Sender == "" && len(f.Include) == 0 && len(f.Exclude) == 0
+}
+
+// Keep reports whether a message passes the filter
+func (f *Filter) Keep(msg *models.Message) bool {
+	if f.IsEmpty() {
+		return true
+	}
+	if f.Sender != "" && msg.Sender != f.Sender {
+		return false
+	}
+	if len(f.Include) > 0 && !matchesAny(f.Include, msg.Text) {
+		return false
+	}
+	return !matchesAny(f.Exclude, msg.Text)
+}
+
+// Apply returns the messages that pass the filter, in order
+func (f *Filter) Apply(messages []*models.Message) []*models.Message {
+	if f.IsEmpty() {
+		return messages
+	}
+	var kept []*models.Message
+	for _, msg := range messages {
+		if f.Keep(msg) {
+			kept = append(kept, msg)
+		}
+	}
+	return kept
+}
+
+// matchesAny reports whether any of the patterns matches text
+func matchesAny(patterns []*regexp.Regexp, text string) bool {
+	for _, re := range patterns {
+		if re.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+func TestFilter(t *testing.T) {
+	messages := []*models.Message{
+		{ID: 1, Sender: "human", Text: "How do I parse JSON in Go?"},
+		{ID: 2, Sender: "assistant", Text: "Use encoding/json:\n```go\njson.Unmarshal(data, &v)\n```"},
+		{ID: 3, Sender: "human", Text: "  Continue  "},
+		{ID: 4, Sender: "assistant", Text: "And to stream it, use json.NewDecoder."},
+	}
+	ids := func(kept []*models.Message) []int64 {
+		var got []int64
+		for _, msg := range kept {
+			got = append(got, msg.ID)
+		}
+		return got
+	}
+
+	tests := []struct {
+		name             string
+		sender           string
+		include, exclude []string
+		want             []int64
+	}{
+		{"nothing", "", nil, nil, []int64{1, 2, 3, 4}},
+		{"exclude", "", nil, []string{`(?i)^\s*continue\s*$`}, []int64{1, 2, 4}},
+		{"include any", "", []string{"Unmarshal", "NewDecoder"}, nil, []int64{2, 4}},
+		{"include and exclude", "", []string{"(?i)json"}, []string{"```"}, []int64{1, 4}},
+		{"sender", "assistant", nil, []string{"stream"}, []int64{2}},
+	}
+	for _, tt := range tests {
+		f, err := New(tt.sender, tt.include, tt.exclude)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got := ids(f.Apply(messages))
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: kept %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: kept %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+
+	if _, err := New("", nil, []string{"(unclosed"}); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
@@ -0,0 +1,271 @@
+package i18n
+
+// german is the German catalog
+var german = map[string]string{
+	// Command help
+	"Usage:":                  "Aufruf:",
+	"Aliases:":                "Aliasse:",
+	"Examples:":               "Beispiele:",
+	"Available Commands:":     "Verfügbare Befehle:",
+	"Additional Commands:":    "Weitere Befehle:",
+	"Global Flags:":           "Globale Optionen:",
+	"Flags:":                  "Optionen:",
+	"Additional help topics:": "Weitere Hilfethemen:",
+	`Use "{{.CommandPath}} [command] --help" for more information about a command.`: `Mehr zu einem Befehl mit "{{.CommandPath}} [Befehl] --help".`,
+	"Help about any command": "Hilfe zu jedem Befehl",
+	"Generate the autocompletion script for the specified shell": "Das Skript zur automatischen Vervollständigung für die angegebene Shell erzeugen",
+	"config file (default is $HOME/.config/shannon/config.yaml)": "Konfigurationsdatei (Standard: $HOME/.config/shannon/config.yaml)",
+	"verbose output": "ausführliche Ausgabe",
+	"plain output without colors, text styles, hyperlinks or emoji (also set by the NO_COLOR environment variable)": "schlichte Ausgabe ohne Farben, Textstile, Hyperlinks oder Emoji (auch über die Umgebungsvariable NO_COLOR)",
+	"same as --plain": "wie --plain",
+
+	// Commands
+	"Search your AI conversation history":                                "Den Verlauf deiner KI-Unterhaltungen durchsuchen",
+	"Build a context block from a conversation that fits a token budget": "Aus einer Unterhaltung einen Kontextblock bauen, der in ein Token-Budget passt",
+	"Check a database written by a newer version":                        "Eine von einer neueren Version geschriebene Datenbank prüfen",
+	"Copy the local database to the shared store":                        "Die lokale Datenbank in den gemeinsamen Speicher kopieren",
+	"Delete messages":                                                       "Nachrichten löschen",
+	"Delete or truncate individual messages":                                "Einzelne Nachrichten löschen oder kürzen",
+	"Execute commands with conversation IDs from stdin":                     "Befehle mit Unterhaltungs-IDs von stdin ausführen",
+	"Export conversations to files, wikis or gists":                         "Unterhaltungen in Dateien, Wikis oder Gists exportieren",
+	"Extract and manage artifacts from conversations":                       "Artefakte aus Unterhaltungen extrahieren und verwalten",
+	"Extract artifacts from a conversation to files":                        "Artefakte einer Unterhaltung in Dateien extrahieren",
+	"Find conversations where you asked a similar question":                 "Unterhaltungen finden, in denen du eine ähnliche Frage gestellt hast",
+	"Find Claude export files in common locations":                          "Claude-Exportdateien an den üblichen Orten finden",
+	"Find conversations whose messages are out of order":                    "Unterhaltungen finden, deren Nachrichten nicht in Reihenfolge sind",
+	"Follow a conversation, printing new messages as they arrive":           "Einer Unterhaltung folgen und neue Nachrichten ausgeben, sobald sie eintreffen",
+	"Generate a database of made-up conversations for demos and benchmarks": "Eine Datenbank erfundener Unterhaltungen für Vorführungen und Benchmarks erzeugen",
+	"Give a conversation an alias":                                          "Einer Unterhaltung einen Alias geben",
+	"Group conversations by topic":                                          "Unterhaltungen nach Thema gruppieren",
+	"Import a Claude export file":                                           "Eine Claude-Exportdatei importieren",
+	"Import a bundle written by 'sync export'":                              "Ein von 'sync export' geschriebenes Paket importieren",
+	"Keep conversations out of search":                                      "Unterhaltungen aus der Suche heraushalten",
+	"Launch interactive TUI interface":                                      "Die interaktive Oberfläche starten",
+	"Link a conversation to the earlier one it continues":                   "Eine Unterhaltung mit der früheren verknüpfen, die sie fortsetzt",
+	"List all conversations":                                                "Alle Unterhaltungen auflisten",
+	"List artifacts in a conversation":                                      "Die Artefakte einer Unterhaltung auflisten",
+	"List changes that can still be undone":                                 "Änderungen auflisten, die sich noch rückgängig machen lassen",
+	"List conversations with aliases and their slugs":                       "Unterhaltungen mit Aliassen und ihre Slugs auflisten",
+	"List deleted conversations kept out of imports":                        "Gelöschte Unterhaltungen auflisten, die nicht wieder importiert werden",
+	"List previous imports":                                                 "Frühere Importe auflisten",
+	"List private conversations":                                            "Private Unterhaltungen auflisten",
+	"List rated answers, most recently rated first":                         "Bewertete Antworten auflisten, zuletzt bewertete zuerst",
+	"List the conversations in the trash":                                   "Die Unterhaltungen im Papierkorb auflisten",
+	"List the largest messages":                                             "Die größten Nachrichten auflisten",
+	"List, restore and empty deleted conversations":                         "Gelöschte Unterhaltungen auflisten, wiederherstellen und endgültig löschen",
+	"Make conversations private":                                            "Unterhaltungen privat machen",
+	"Make private conversations searchable again":                           "Private Unterhaltungen wieder durchsuchbar machen",
+	"Manage the database schema":                                            "Das Datenbankschema verwalten",
+	"Migrate the database to this version's schema":                         "Die Datenbank auf das Schema dieser Version migrieren",
+	"Move whole conversations to the trash":                                 "Ganze Unterhaltungen in den Papierkorb verschieben",
+	"Name conversations with slugs and aliases":                             "Unterhaltungen mit Slugs und Aliassen benennen",
+	"Open a conversation in your editor":                                    "Eine Unterhaltung im Editor öffnen",
+	"Open a random conversation":                                            "Eine zufällige Unterhaltung öffnen",
+	"Open conversation in browser (reads ID from stdin if not provided)":    "Unterhaltung im Browser öffnen (liest die ID von stdin, wenn keine angegeben ist)",
+	"Permanently delete the conversations in the trash":                     "Die Unterhaltungen im Papierkorb endgültig löschen",
+	"Queue enrichments for the background worker and follow their progress": "Anreicherungen für den Hintergrunddienst einreihen und ihren Fortschritt verfolgen",
+	"Queue failed jobs again":                                               "Fehlgeschlagene Aufträge erneut einreihen",
+	"Queue jobs for conversations":                                          "Aufträge für Unterhaltungen einreihen",
+	"Rate an answer useful, obsolete or wrong":                              "Eine Antwort als nützlich, veraltet oder falsch bewerten",
+	"Rate assistant answers as useful, obsolete or wrong":                   "Antworten des Assistenten als nützlich, veraltet oder falsch bewerten",
+	"Rebuild the code block and artifact index":                             "Den Index der Codeblöcke und Artefakte neu aufbauen",
+	"Remove aliases":                                                        "Aliasse entfernen",
+	"Remove conversations and messages introduced by an import":             "Von einem Import hinzugefügte Unterhaltungen und Nachrichten entfernen",
+	"Remove the rating of an answer":                                        "Die Bewertung einer Antwort entfernen",
+	"Rename conversations from a template":                                  "Unterhaltungen nach einer Vorlage umbenennen",
+	"Restore a deleted or truncated message":                                "Eine gelöschte oder gekürzte Nachricht wiederherstellen",
+	"Run queued enrichment jobs in the background":                          "Eingereihte Anreicherungsaufträge im Hintergrund ausführen",
+	"Save a conversation as a single HTML page to send to anyone":           "Eine Unterhaltung als einzelne HTML-Seite zum Weitergeben speichern",
+	"Search for artifacts containing specific text":                         "Nach Artefakten mit einem bestimmten Text suchen",
+	"Search only inside code blocks and artifacts":                          "Nur in Codeblöcken und Artefakten suchen",
+	"Search through conversations":                                          "Unterhaltungen durchsuchen",
+	"Show and manage import history":                                        "Den Importverlauf anzeigen und verwalten",
+	"Show conversations from this day in earlier years":                     "Unterhaltungen von diesem Tag in früheren Jahren anzeigen",
+	"Show database statistics":                                              "Datenbankstatistiken anzeigen",
+	"Show details and errors for an import":                                 "Details und Fehler eines Imports anzeigen",
+	"Show how far the queued jobs have got, and why any failed":             "Zeigen, wie weit die eingereihten Aufträge sind und warum welche fehlschlugen",
+	"Show recent conversations":                                             "Neuere Unterhaltungen anzeigen",
+	"Show terminal capabilities and features":                               "Fähigkeiten und Funktionen des Terminals anzeigen",
+	"Show what finished jobs found":                                         "Zeigen, was abgeschlossene Aufträge gefunden haben",
+	"Show where shannon keeps its files and check the database":             "Zeigen, wo shannon seine Dateien ablegt, und die Datenbank prüfen",
+	"Split a conversation into separate conversations by topic":             "Eine Unterhaltung nach Thema in getrennte Unterhaltungen aufteilen",
+	"Start a new claude.ai chat from an old conversation":                   "Einen neuen claude.ai-Chat aus einer alten Unterhaltung beginnen",
+	"Store the text of the messages compressed":                             "Den Text der Nachrichten komprimiert speichern",
+	"Sync conversations between machines with bundle files":                 "Unterhaltungen mit Paketdateien zwischen Rechnern abgleichen",
+	"Take conversations out of the trash":                                   "Unterhaltungen aus dem Papierkorb holen",
+	"Truncate messages, keeping only their beginning":                       "Nachrichten kürzen und nur ihren Anfang behalten",
+	"View a conversation with all messages":                                 "Eine Unterhaltung mit allen Nachrichten anzeigen",
+	"View a specific artifact":                                              "Ein bestimmtes Artefakt anzeigen",
+	"Write conversation files for desktop search tools":                     "Unterhaltungsdateien für Desktop-Suchwerkzeuge schreiben",
+	"Write new and changed conversations to a bundle":                       "Neue und geänderte Unterhaltungen in ein Paket schreiben",
+	"Write the artifacts of every conversation to files":                    "Die Artefakte aller Unterhaltungen in Dateien schreiben",
+
+	// Tables
+	"ID":           "ID",
+	"Date":         "Datum",
+	"Conversation": "Unterhaltung",
+	"Sender":       "Absender",
+	"Queries":      "Anfragen",
+	"Snippet":      "Ausschnitt",
+	"Message ID":   "Nachrichten-ID",
+	"Messages":     "Nachrichten",
+	"Updated":      "Geändert",
+	"Last Updated": "Zuletzt geändert",
+	"Name":         "Name",
+	"Tokens":       "Tokens",
+	"Artifacts":    "Artefakte",
+	"Human":        "Mensch",
+	"Duration":     "Dauer",
+	"Days":         "Tage",
+
+	// Command output
+	"No results found.":                            "Keine Ergebnisse gefunden.",
+	"Found %d results":                             "%d Ergebnisse gefunden",
+	" (showing first %d)":                          " (die ersten %d werden gezeigt)",
+	"--- Message Context ---":                      "--- Nachrichtenkontext ---",
+	"No conversations found.":                      "Keine Unterhaltungen gefunden.",
+	"Showing %d of %d total conversations":         "%d von insgesamt %d Unterhaltungen",
+	" (filtered by '%s')":                          " (gefiltert nach '%s')",
+	"You haven't asked anything like that before.": "So etwas hast du noch nicht gefragt.",
+	"Asked before, most similar first:":            "Schon gefragt, das Ähnlichste zuerst:",
+	"Asked before: %s":                             "Schon gefragt: %s",
+	"Read one with `shannon view <id>`":            "Lies eine mit `shannon view <id>`",
+	"Continues:":                                   "Setzt fort:",
+	"Continued in:":                                "Fortgesetzt in:",
+	"No earlier conversations look like this one continues them.":                                                      "Keine frühere Unterhaltung sieht aus, als setze diese sie fort.",
+	"It may continue, most likely first:":                                                                              "Sie setzt vielleicht fort, die wahrscheinlichste zuerst:",
+	"Link one with `shannon link %d --continues <id>`":                                                                 "Verknüpfe eine mit `shannon link %d --continues <id>`",
+	"No conversations in the last %d days":                                                                             "Keine Unterhaltungen in den letzten %d Tagen",
+	"Results marked [name] are on a branch other than main; see one in its thread with `shannon view %d --message %s`": "Mit [Name] markierte Ergebnisse liegen auf einem anderen Zweig als main; zeige eines in seinem Verlauf mit `shannon view %d --message %s`",
+
+	// Notifications
+	"Cancelled":                                            "Abgebrochen",
+	"Error: %v":                                            "Fehler: %v",
+	"error: %s":                                            "Fehler: %s",
+	"failed to load conversations: %s":                     "Unterhaltungen konnten nicht geladen werden: %s",
+	"Warning: failed to close database: %v":                "Warnung: Datenbank konnte nicht geschlossen werden: %v",
+	"Copy failed: %v":                                      "Kopieren fehlgeschlagen: %v",
+	"Copied %s":                                            "%s kopiert",
+	"Copied conversation ID %d":                            "Unterhaltungs-ID %d kopiert",
+	"✓ Copied to clipboard":                                "✓ In die Zwischenablage kopiert",
+	"✓ Copied to clipboard as %s":                          "✓ Als %s in die Zwischenablage kopiert",
+	"✓ Copied %s to clipboard":                             "✓ %s in die Zwischenablage kopiert",
+	"✗ Clipboard not available":                            "✗ Zwischenablage nicht verfügbar",
+	"✓ Exported %s to %s":                                  "✓ %s nach %s exportiert",
+	"Couldn't open %s: %v":                                 "%s konnte nicht geöffnet werden: %v",
+	"Opened %s in the browser":                             "%s im Browser geöffnet",
+	"the conversation":                                     "die Unterhaltung",
+	"the %s artifact":                                      "das Artefakt (%s)",
+	"Tagged conversation %d as %q":                         "Unterhaltung %d als %q markiert",
+	"Switched to the %s theme":                             "Zum Thema %s gewechselt",
+	"Switched to %s":                                       "Zu %s gewechselt",
+	"Showing branch %s, where the best match is":           "Zweig %s mit dem besten Treffer wird gezeigt",
+	"%s: %d messages":                                      "%s: %d Nachrichten",
+	" to ":                                                 " bis ",
+	"No answer on screen to rate":                          "Keine Antwort zum Bewerten auf dem Bildschirm",
+	"✓ Cleared the rating of message %d":                   "✓ Bewertung von Nachricht %d entfernt",
+	"✓ Rated message %d %s":                                "✓ Nachricht %d bewertet: %s",
+	"Showing repeated content":                             "Wiederholter Inhalt wird gezeigt",
+	"No repeated content to collapse":                      "Kein wiederholter Inhalt zum Einklappen",
+	"✓ Collapsed %d repeated block(s), %d lines":           "✓ %d wiederholte(n) Block/Blöcke eingeklappt, %d Zeilen",
+	"Showing all code":                                     "Aller Code wird gezeigt",
+	"No code blocks over %d lines":                         "Keine Codeblöcke mit mehr als %d Zeilen",
+	"✓ Collapsed %d code block(s) over %d lines":           "✓ %d Codeblock/-blöcke mit mehr als %d Zeilen eingeklappt",
+	"[code, %d lines · C to show]":                         "[Code, %d Zeilen · C zum Zeigen]",
+	"[%s code, %d lines · C to show]":                      "[%s-Code, %d Zeilen · C zum Zeigen]",
+	"Question %d of %d":                                    "Frage %d von %d",
+	"Message is already %d lines or shorter":               "Die Nachricht hat schon höchstens %d Zeilen",
+	"✓ Deleted message (%s) • u: undo":                     "✓ Nachricht gelöscht (%s) • u: rückgängig",
+	"✓ Truncated message (%s) • u: undo":                   "✓ Nachricht gekürzt (%s) • u: rückgängig",
+	"Nothing to undo":                                      "Nichts rückgängig zu machen",
+	"✓ Restored message":                                   "✓ Nachricht wiederhergestellt",
+	"The first message stays in this conversation":         "Die erste Nachricht bleibt in dieser Unterhaltung",
+	"Mark a message to start a new conversation at with m": "Mit m eine Nachricht markieren, bei der eine neue Unterhaltung beginnt",
+	"✓ Split off %d conversation(s): %s":                   "✓ %d Unterhaltung(en) abgeteilt: %s",
+	"✗ Failed to import %s: %v":                            "✗ Import von %s fehlgeschlagen: %v",
+	"✓ Imported %s: %d conversation(s), %d message(s)":     "✓ %s importiert: %d Unterhaltung(en), %d Nachricht(en)",
+	", %d skipped with errors":                             ", %d wegen Fehlern übersprungen",
+	"🆕 Found %d new Claude export(s) in Downloads":         "🆕 %d neue(r) Claude-Export(e) in Downloads gefunden",
+	"🆕 Found %s, importing once it's unchanged for %s":     "🆕 %s gefunden, Import sobald unverändert seit %s",
+	"🆕 Found %d new Claude exports, importing once they're unchanged for %s": "🆕 %d neue Claude-Exporte gefunden, Import sobald unverändert seit %s",
+
+	// Status lines
+	"Found %d matches for '%s' • Match %d/%d":                                  "%d Treffer für '%s' • Treffer %d/%d",
+	"No matches found for '%s' • Press / to search again":                      "Keine Treffer für '%s' • / für eine neue Suche",
+	"Cleanup: message %d/%d • %s • %s, %d lines":                               "Aufräumen: Nachricht %d/%d • %s • %s, %d Zeilen",
+	"Split: message %d/%d • %d marked":                                         "Aufteilen: Nachricht %d/%d • %d markiert",
+	"Delete message %d (%s)? y: confirm • any other key: cancel":               "Nachricht %d (%s) löschen? y: bestätigen • andere Taste: abbrechen",
+	"Truncate message %d (%s) to %d lines? y: confirm • any other key: cancel": "Nachricht %d (%s) auf %d Zeilen kürzen? y: bestätigen • andere Taste: abbrechen",
+	"Split into %d conversations? y: confirm • any other key: cancel":          "In %d Unterhaltungen aufteilen? y: bestätigen • andere Taste: abbrechen",
+	"↑ %d earlier messages":                                                    "↑ %d frühere Nachrichten",
+	"↓ %d more messages":                                                       "↓ %d weitere Nachrichten",
+	"loading conversations...":                                                 "Unterhaltungen werden geladen...",
+	"searching... (esc to cancel)":                                             "Suche läuft... (esc zum Abbrechen)",
+	"no matching commands":                                                     "keine passenden Befehle",
+	"type to filter":                                                           "tippen zum Filtern",
+	"[Tab] focus artifact | [/] find | [q] back":                               "[Tab] Artefakt fokussieren | [/] suchen | [q] zurück",
+	"[Tab] unfocus | [s] save | [←/→] navigate artifacts | [q] back":           "[Tab] Fokus lösen | [s] speichern | [←/→] zwischen Artefakten wechseln | [q] zurück",
+
+	// Key hint actions
+	"back":                        "zurück",
+	"cancel":                      "abbrechen",
+	"clean up":                    "aufräumen",
+	"clear":                       "leeren",
+	"clear search":                "Suche leeren",
+	"close":                       "schließen",
+	"collapse code":               "Code einklappen",
+	"collapse repeats":            "Wiederholungen einklappen",
+	"commands":                    "Befehle",
+	"conversations":               "Unterhaltungen",
+	"copy":                        "kopieren",
+	"copy as raw/markdown/html":   "als Text/Markdown/HTML kopieren",
+	"copy to clipboard":           "in die Zwischenablage kopieren",
+	"delete":                      "löschen",
+	"done":                        "fertig",
+	"drill down":                  "aufklappen",
+	"edit search":                 "Suche bearbeiten",
+	"exit focus":                  "Fokus verlassen",
+	"expand":                      "ausklappen",
+	"expand/collapse":             "aus-/einklappen",
+	"export":                      "exportieren",
+	"filter":                      "filtern",
+	"find":                        "suchen",
+	"focus artifact":              "Artefakt fokussieren",
+	"format":                      "Format",
+	"go to list":                  "zur Liste",
+	"languages":                   "Sprachen",
+	"largest":                     "größte",
+	"mark new conversation start": "Beginn einer neuen Unterhaltung markieren",
+	"months":                      "Monate",
+	"navigate":                    "bewegen",
+	"next":                        "weiter",
+	"next/prev":                   "weiter/zurück",
+	"next/prev match":             "nächster/vorheriger Treffer",
+	"open":                        "öffnen",
+	"open conversation":           "Unterhaltung öffnen",
+	"open in browser":             "im Browser öffnen",
+	"open in claude.ai":           "in claude.ai öffnen",
+	"page":                        "blättern",
+	"prev":                        "zurück",
+	"prev/next day":               "voriger/nächster Tag",
+	"prev/next question":          "vorige/nächste Frage",
+	"previous":                    "zurück",
+	"print":                       "ausgeben",
+	"quit":                        "beenden",
+	"rate":                        "bewerten",
+	"run":                         "ausführen",
+	"save":                        "speichern",
+	"save to file":                "in Datei speichern",
+	"scroll":                      "scrollen",
+	"search":                      "suchen",
+	"select":                      "auswählen",
+	"snippets/matches":            "Ausschnitte/Treffer",
+	"sort":                        "sortieren",
+	"split":                       "aufteilen",
+	"statistics":                  "Statistik",
+	"top/bottom":                  "Anfang/Ende",
+	"topics":                      "Themen",
+	"truncate":                    "kürzen",
+	"undo":                        "rückgängig",
+	"view":                        "anzeigen",
+}
@@ -0,0 +1,125 @@
+// Package i18n translates what shannon shows people: table headers, notices,
+// key hints and command help. Strings are looked up by their English text, as
+// with gettext, so code reads the same as before and a string without a
+// translation is shown in English.
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// English is the language strings are written in, and the fallback
+const English = "en"
+
+// catalogs hold the translations of each language besides English, keyed by
+// the English text
+var catalogs = map[string]map[string]string{
+	"de": german,
+}
+
+var (
+	locale  = English
+	catalog map[string]string
+)
+
+// Languages returns the codes of the languages shannon can be shown in
+func Languages() []string {
+	return []string{English, "de"}
+}
+
+// Supported reports whether a language, as Normalize returns it, has a
+// translation
+func Supported(lang string) bool {
+	_, ok := catalogs[lang]
+	return ok || lang == English
+}
+
+// Normalize turns a locale like "de_DE.UTF-8", "de-AT" or "DE" into its
+// language code. "C" and "POSIX" are English.
+func Normalize(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "_-.@"); i >= 0 {
+		tag = tag[:i]
+	}
+	if tag == "c" || tag == "posix" {
+		return English
+	}
+	return tag
+}
+
+// Detect returns the language to show: the configured one if set, otherwise
+// the first of the SHANNON_LANG, LC_ALL, LC_MESSAGES and LANG environment
+// variables that is set, otherwise English
+func Detect(configured string) string {
+	if configured != "" {
+		return Normalize(configured)
+	}
+	for _, name := range []string{"SHANNON_LANG", "LC_ALL", "LC_MESSAGES", "LANG"} {
+		if v := os.Getenv(name); v != "" {
+			return Normalize(v)
+		}
+	}
+	return English
+}
+
+// SetLocale switches the language for the rest of the run. Languages without
+// a translation fall back to English.
+func SetLocale(lang string) {
+	lang = Normalize(lang)
+	catalog = catalogs[lang]
+	if catalog == nil {
+		lang = English
+	}
+	locale = lang
+}
+
+// Locale returns the language strings are translated into
+func Locale() string {
+	return locale
+}
+
+// T returns the translation of s, or s itself if it has none
+func T(s string) string {
+	if t, ok := catalog[s]; ok {
+		return t
+	}
+	return s
+}
+
+// Tf translates a format string and formats it with args
+func Tf(format string, args ...interface{}) string {
+	return fmt.Sprintf(T(format), args...)
+}
+
+// Hints translates a line of key hints such as "enter: view • q: quit". The
+// keys stay as they are and each action is translated on its own, so lines
+// that share actions share translations.
+func Hints(line string) string {
+	if catalog == nil {
+		return line
+	}
+	hints := strings.Split(line, " • ")
+	for i, hint := range hints {
+		if key, action, ok := strings.Cut(hint, ": "); ok {
+			hints[i] = key + ": " + T(action)
+		} else {
+			hints[i] = T(hint)
+		}
+	}
+	return strings.Join(hints, " • ")
+}
+
+// Columns translates the column names of a table and returns its tab
+// separated header line and the line of dashes under it
+func Columns(names ...string) (header, separator string) {
+	headers := make([]string, len(names))
+	dashes := make([]string, len(names))
+	for i, name := range names {
+		headers[i] = T(name)
+		dashes[i] = strings.Repeat("-", utf8.RuneCountInString(headers[i]))
+	}
+	return strings.Join(headers, "\t"), strings.Join(dashes, "\t")
+}
@@ -0,0 +1,69 @@
+package i18n
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	for _, name := range []string{"SHANNON_LANG", "LC_ALL", "LC_MESSAGES", "LANG"} {
+		t.Setenv(name, "")
+	}
+	if got := Detect(""); got != English {
+		t.Errorf("Detect with no locale = %q, want %q", got, English)
+	}
+
+	t.Setenv("LANG", "de_DE.UTF-8")
+	if got := Detect(""); got != "de" {
+		t.Errorf("Detect with LANG=de_DE.UTF-8 = %q, want de", got)
+	}
+	t.Setenv("LC_ALL", "C")
+	if got := Detect(""); got != English {
+		t.Errorf("Detect with LC_ALL=C = %q, want %q", got, English)
+	}
+	if got := Detect("DE-at"); got != "de" {
+		t.Errorf("Detect with a configured language = %q, want de", got)
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	defer SetLocale(English)
+
+	SetLocale("fr_FR")
+	if Locale() != English {
+		t.Errorf("Locale after an untranslated language = %q, want %q", Locale(), English)
+	}
+
+	SetLocale("de_DE.UTF-8")
+	if got := T("No results found."); got != "Keine Ergebnisse gefunden." {
+		t.Errorf("T = %q", got)
+	}
+	if got := T("not in the catalog"); got != "not in the catalog" {
+		t.Errorf("T of an untranslated string = %q", got)
+	}
+	if got := Tf("Found %d results", 3); got != "3 Ergebnisse gefunden" {
+		t.Errorf("Tf = %q", got)
+	}
+	if got := Hints("↑/↓: navigate • enter: view • x: unknown"); got != "↑/↓: bewegen • enter: anzeigen • x: unknown" {
+		t.Errorf("Hints = %q", got)
+	}
+	header, separator := Columns("ID", "Conversation")
+	if header != "ID\tUnterhaltung" || separator != "--\t------------" {
+		t.Errorf("Columns = %q, %q", header, separator)
+	}
+}
+
+// verbs matches the verbs of a format string
+var verbs = regexp.MustCompile(`%[-+# 0-9.]*[a-zA-Z%]`)
+
+func TestCatalogsKeepVerbs(t *testing.T) {
+	for lang, catalog := range catalogs {
+		for english, translated := range catalog {
+			want := strings.Join(verbs.FindAllString(english, -1), " ")
+			if got := strings.Join(verbs.FindAllString(translated, -1), " "); got != want {
+				t.Errorf("%s: %q has verbs %q, want %q like %q", lang, translated, got, want, english)
+			}
+		}
+	}
+}
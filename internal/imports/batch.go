@@ -0,0 +1,125 @@
+package imports
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/neilberkman/shannon/internal/db"
+)
+
+// maxVariables is SQLite's limit on the parameters of one statement
+const maxVariables = 32766
+
+// Columns written by the multi-row INSERTs
+var (
+	messageColumns   = []string{"id", "uuid", "conversation_id", "sender", "text", "created_at", "parent_id", "branch_id", "sequence", "import_id", "model", "starred", "feedback"}
+	codeBlockColumns = []string{"message_id", "conversation_id", "kind", "language", "title", "identifier", "artifact_type", "start_line", "content"}
+)
+
+// importTx is the transaction of an import. It prepares the statements run
+// for every conversation once, which close with the transaction, and assigns
+// message IDs itself so messages can be written in multi-row batches with
+// their parents already known.
+type importTx struct {
+	*sql.Tx
+	batchSize     int
+	nextMessageID int64
+	statements    map[string]*sql.Stmt
+	compress      bool // store message text compressed (`shannon db compress`)
+}
+
+func newImportTx(tx *sql.Tx, batchSize int) (*importTx, error) {
+	// A large page cache keeps the indexes of a big import in memory instead
+	// of spilling them to disk before the commit
+	if _, err := tx.Exec("PRAGMA cache_size = -262144"); err != nil { // 256MB
+		return nil, fmt.Errorf("failed to set cache size: %w", err)
+	}
+
+	// Continue from the AUTOINCREMENT counter so deleted IDs aren't reused
+	var nextID int64
+	err := tx.QueryRow(`
+		SELECT MAX(COALESCE((SELECT MAX(id) FROM messages), 0),
+		           COALESCE((SELECT seq FROM sqlite_sequence WHERE name = 'messages'), 0)) + 1
+	`).Scan(&nextID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get next message ID: %w", err)
+	}
+
+	compress, err := db.CompressionEnabled(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	return &importTx{
+		Tx:            tx,
+		batchSize:     batchSize,
+		nextMessageID: nextID,
+		statements:    make(map[string]*sql.Stmt),
+		compress:      compress,
+	}, nil
+}
+
+// stmt returns query prepared on the transaction, preparing it on first use
+func (t *importTx) stmt(query string) (*sql.Stmt, error) {
+	if stmt, ok := t.statements[query]; ok {
+		return stmt, nil
+	}
+	stmt, err := t.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	t.statements[query] = stmt
+	return stmt, nil
+}
+
+// exec runs query as a prepared statement
+func (t *importTx) exec(query string, args ...interface{}) (sql.Result, error) {
+	stmt, err := t.stmt(query)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.Exec(args...)
+}
+
+// queryRow runs query as a prepared statement
+func (t *importTx) queryRow(query string, args ...interface{}) *sql.Row {
+	stmt, err := t.stmt(query)
+	if err != nil {
+		// Let Scan report the failure like any other query error
+		return t.QueryRow(query, args...)
+	}
+	return stmt.QueryRow(args...)
+}
+
+// newMessageID reserves the ID of the next message inserted
+func (t *importTx) newMessageID() int64 {
+	id := t.nextMessageID
+	t.nextMessageID++
+	return id
+}
+
+// insertRows writes rows to table with multi-row INSERTs of up to batchSize
+// rows each
+func (t *importTx) insertRows(table string, columns []string, rows [][]interface{}) error {
+	size := min(t.batchSize, maxVariables/len(columns))
+	for start := 0; start < len(rows); start += size {
+		batch := rows[start:min(start+size, len(rows))]
+
+		placeholders := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+		query := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", table, strings.Join(columns, ", "),
+			strings.TrimSuffix(strings.Repeat(placeholders+", ", len(batch)), ", "))
+
+		args := make([]interface{}, 0, len(batch)*len(columns))
+		for _, row := range batch {
+			args = append(args, row...)
+		}
+		if _, err := t.exec(query, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
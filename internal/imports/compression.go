@@ -0,0 +1,123 @@
+package imports
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression formats recognized in export files
+const (
+	CompressionNone  = ""
+	CompressionGzip  = "gzip"
+	CompressionBzip2 = "bzip2"
+	CompressionZstd  = "zstd"
+)
+
+// Magic bytes at the start of compressed files
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	bzip2Magic = []byte("BZh")
+	zstdMagic  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// compressedExtensions are the file name suffixes of compressed exports
+var compressedExtensions = []string{".gz", ".gzip", ".bz2", ".zst", ".zstd"}
+
+// TrimCompressionExt returns a file name without a compression suffix, so
+// "conversations.json.gz" becomes "conversations.json"
+func TrimCompressionExt(name string) string {
+	lower := strings.ToLower(name)
+	for _, ext := range compressedExtensions {
+		if strings.HasSuffix(lower, ext) {
+			return name[:len(name)-len(ext)]
+		}
+	}
+	return name
+}
+
+// DetectCompression identifies the compression of data from its first bytes
+func DetectCompression(header []byte) string {
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		return CompressionGzip
+	case bytes.HasPrefix(header, bzip2Magic):
+		return CompressionBzip2
+	case bytes.HasPrefix(header, zstdMagic):
+		return CompressionZstd
+	}
+	return CompressionNone
+}
+
+// exportReader reads an export file, decompressing it if needed
+type exportReader struct {
+	io.Reader
+	file        *os.File
+	closer      io.Closer // decompressor to close before the file, if any
+	compression string
+}
+
+// Close closes the decompressor and the underlying file
+func (r *exportReader) Close() error {
+	if r.closer != nil {
+		if err := r.closer.Close(); err != nil {
+			_ = r.file.Close()
+			return err
+		}
+	}
+	return r.file.Close()
+}
+
+// OpenExport opens an export file for reading. Files compressed with gzip,
+// bzip2 or zstd are decompressed transparently; the format is detected from the file
+// contents, not its name.
+func OpenExport(path string) (io.ReadCloser, error) {
+	r, err := openExport(path)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func openExport(path string) (*exportReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	buffered := bufio.NewReader(file)
+	header, err := buffered.Peek(len(zstdMagic))
+	if err != nil && err != io.EOF {
+		_ = file.Close()
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	r := &exportReader{Reader: buffered, file: file, compression: DetectCompression(header)}
+	switch r.compression {
+	case CompressionGzip:
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			_ = file.Close()
+			return nil, fmt.Errorf("failed to read gzip data: %w", err)
+		}
+		r.Reader, r.closer = gz, gz
+	case CompressionBzip2:
+		r.Reader = bzip2.NewReader(buffered)
+	case CompressionZstd:
+		zr, err := zstd.NewReader(buffered)
+		if err != nil {
+			_ = file.Close()
+			return nil, fmt.Errorf("failed to read zstd data: %w", err)
+		}
+		rc := zr.IOReadCloser()
+		r.Reader, r.closer = rc, rc
+	}
+	return r, nil
+}
@@ -0,0 +1,135 @@
+package imports
+
+import (
+	"compress/gzip"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+// bzip2Export is `[{"uuid":"c"}]` compressed with bzip2; the standard library
+// can only decompress bzip2
+const bzip2Export = "425a68393141592653597c23e5620000029b8010000010000a0c20020a2000220d0d3210030d08e161380ebc5dc914e14241f08f9588"
+
+func TestOpenExport(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	data, err := hex.DecodeString(bzip2Export)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The format is detected from the contents, whatever the file is called
+	path := filepath.Join(tmpDir, "conversations.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	r, err := OpenExport(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != `[{"uuid":"c"}]` {
+		t.Errorf("unexpected bzip2 content: %q", content)
+	}
+
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zst := filepath.Join(tmpDir, "conversations.json.zst")
+	if err := os.WriteFile(zst, enc.EncodeAll([]byte(`[{"uuid":"z"}]`), nil), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if r, err = OpenExport(zst); err != nil {
+		t.Fatal(err)
+	}
+	if content, err = io.ReadAll(r); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != `[{"uuid":"z"}]` {
+		t.Errorf("unexpected zstd content: %q", content)
+	}
+
+	// A truncated frame is reported when it's read
+	if err := os.WriteFile(zst, []byte{0x28, 0xb5, 0x2f, 0xfd, 0x00}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if r, err = OpenExport(zst); err == nil {
+		_, err = io.ReadAll(r)
+		_ = r.Close()
+	}
+	if err == nil {
+		t.Error("expected an error for a truncated zstd file")
+	}
+
+	if got := TrimCompressionExt("export.JSON.GZ"); got != "export.JSON" {
+		t.Errorf("unexpected trimmed name %q", got)
+	}
+}
+
+func TestImportGzip(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	database, err := db.New(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := database.Close(); err != nil {
+			t.Errorf("failed to close database: %v", err)
+		}
+	}()
+
+	data, err := json.Marshal([]models.ClaudeConversation{
+		{
+			UUID: "conv-1", Name: "Compressed",
+			CreatedAt: "2024-01-01T10:00:00Z", UpdatedAt: "2024-01-01T10:05:00Z",
+			ChatMessages: []models.ClaudeChatMessage{
+				{UUID: "msg-1", Sender: "human", Text: "Does this survive gzip?", CreatedAt: "2024-01-01T10:00:00Z"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(tmpDir, "conversations.json.gz")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(file)
+	if _, err := gz.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := NewImporter(database, 100, false).Import(path)
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if stats.ConversationsImported != 1 || stats.MessagesImported != 1 {
+		t.Errorf("unexpected import stats: %+v", stats)
+	}
+}
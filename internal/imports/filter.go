@@ -0,0 +1,57 @@
+package imports
+
+import (
+	"strings"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+// Filter picks the conversations of an export to import. The zero value
+// matches every conversation.
+type Filter struct {
+	Matching    string    // text the name or a message must contain, ignoring case
+	After       time.Time // last updated at or after this time, when set
+	Before      time.Time // last updated before this time, when set
+	MinMessages int       // at least this many messages
+}
+
+// Match reports whether conv passes every criterion of the filter
+func (f *Filter) Match(conv *models.ClaudeConversation) bool {
+	if len(conv.ChatMessages) < f.MinMessages {
+		return false
+	}
+
+	if !f.After.IsZero() || !f.Before.IsZero() {
+		updatedAt, err := ParseTime(conv.UpdatedAt)
+		if err != nil {
+			// Import it and let the import report the bad time
+			return true
+		}
+		if !f.After.IsZero() && updatedAt.Before(f.After) {
+			return false
+		}
+		if !f.Before.IsZero() && !updatedAt.Before(f.Before) {
+			return false
+		}
+	}
+
+	if f.Matching == "" {
+		return true
+	}
+	needle := strings.ToLower(f.Matching)
+	if strings.Contains(strings.ToLower(conv.Name), needle) {
+		return true
+	}
+	for _, msg := range conv.ChatMessages {
+		if strings.Contains(strings.ToLower(msg.Text), needle) {
+			return true
+		}
+		for _, content := range msg.Content {
+			if strings.Contains(strings.ToLower(content.Text), needle) {
+				return true
+			}
+		}
+	}
+	return false
+}
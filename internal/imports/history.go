@@ -0,0 +1,251 @@
+package imports
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+// UndoStats reports what was removed when undoing an import
+type UndoStats struct {
+	ConversationsRemoved int
+	MessagesRemoved      int
+	ConversationsUpdated int
+}
+
+const importRecordColumns = `
+	id, file_path, file_hash, imported_at,
+	COALESCE(conversations_count, 0), COALESCE(messages_count, 0),
+	COALESCE(branches_detected, 0), COALESCE(errors_count, 0),
+	COALESCE(duration_ms, 0), status, COALESCE(error_message, '')
+`
+
+// ListImports returns the most recent imports, newest first
+func ListImports(database *db.DB, limit int) ([]*models.ImportRecord, error) {
+	rows, err := database.Query(`
+		SELECT `+importRecordColumns+`
+		FROM import_history
+		ORDER BY id DESC
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query import history: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close rows: %v\n", err)
+		}
+	}()
+
+	var records []*models.ImportRecord
+	for rows.Next() {
+		record, err := scanImportRecord(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan import: %w", err)
+		}
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
+
+// GetImport returns a single import along with the conversations that failed during it
+func GetImport(database *db.DB, importID int64) (*models.ImportRecord, []models.ImportError, error) {
+	row := database.QueryRow(`SELECT `+importRecordColumns+` FROM import_history WHERE id = ?`, importID)
+	record, err := scanImportRecord(row)
+	if err == sql.ErrNoRows {
+		return nil, nil, fmt.Errorf("import %d not found", importID)
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := database.Query(`
+		SELECT COALESCE(conversation_uuid, ''), error_message
+		FROM import_errors
+		WHERE import_id = ?
+		ORDER BY id
+	`, importID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query import errors: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close rows: %v\n", err)
+		}
+	}()
+
+	var importErrors []models.ImportError
+	for rows.Next() {
+		var ie models.ImportError
+		if err := rows.Scan(&ie.ConversationUUID, &ie.Message); err != nil {
+			return nil, nil, err
+		}
+		importErrors = append(importErrors, ie)
+	}
+
+	return record, importErrors, rows.Err()
+}
+
+// UndoImport removes the conversations and messages introduced by an import.
+// Conversations that also received messages from other imports are kept, with
+// only this import's messages removed. The history entry itself is deleted so
+// the file can be imported again.
+func UndoImport(database *db.DB, importID int64) (*UndoStats, error) {
+	stats := &UndoStats{}
+
+	tx, err := database.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			fmt.Fprintf(os.Stderr, "Warning: failed to rollback transaction: %v\n", err)
+		}
+	}()
+
+	var exists int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM import_history WHERE id = ?", importID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if exists == 0 {
+		return nil, fmt.Errorf("import %d not found", importID)
+	}
+
+	// Remember which conversations are touched so their counts can be refreshed
+	affected, err := queryIDs(tx, `
+		SELECT DISTINCT conversation_id FROM messages WHERE import_id = ?
+		UNION
+		SELECT id FROM conversations WHERE import_id = ?
+	`, importID, importID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find affected conversations: %w", err)
+	}
+
+	// Messages from other imports may reply to messages we're about to remove
+	if _, err := tx.Exec(`
+		UPDATE messages SET parent_id = NULL
+		WHERE parent_id IN (SELECT id FROM messages WHERE import_id = ?)
+		AND (import_id IS NULL OR import_id != ?)
+	`, importID, importID); err != nil {
+		return nil, fmt.Errorf("failed to detach dependent messages: %w", err)
+	}
+
+	result, err := tx.Exec("DELETE FROM messages WHERE import_id = ?", importID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove messages: %w", err)
+	}
+	removed, _ := result.RowsAffected()
+	stats.MessagesRemoved = int(removed)
+
+	// Conversations created by this import with nothing else left in them
+	result, err = tx.Exec(`
+		DELETE FROM conversations
+		WHERE import_id = ?
+		AND NOT EXISTS (SELECT 1 FROM messages m WHERE m.conversation_id = conversations.id)
+	`, importID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove conversations: %w", err)
+	}
+	removed, _ = result.RowsAffected()
+	stats.ConversationsRemoved = int(removed)
+
+	// Surviving conversations are handed to the earliest import still contributing to them
+	if _, err := tx.Exec(`
+		UPDATE conversations
+		SET import_id = (SELECT MIN(m.import_id) FROM messages m WHERE m.conversation_id = conversations.id)
+		WHERE import_id = ?
+	`, importID); err != nil {
+		return nil, fmt.Errorf("failed to reassign conversations: %w", err)
+	}
+
+	for _, convID := range affected {
+		result, err := tx.Exec(`
+			UPDATE conversations
+			SET message_count = (SELECT COUNT(*) FROM messages WHERE conversation_id = ?)
+			WHERE id = ?
+		`, convID, convID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update conversation %d: %w", convID, err)
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			stats.ConversationsUpdated++
+		}
+
+		if _, err := tx.Exec(db.RefreshConversationStatsSQL, convID); err != nil {
+			return nil, fmt.Errorf("failed to update conversation %d statistics: %w", convID, err)
+		}
+
+		// Drop branches that no longer hold any messages
+		if _, err := tx.Exec(`
+			DELETE FROM branches
+			WHERE conversation_id = ? AND name != 'main'
+			AND NOT EXISTS (SELECT 1 FROM messages m WHERE m.branch_id = branches.id)
+		`, convID); err != nil {
+			return nil, fmt.Errorf("failed to clean up branches: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM import_history WHERE id = ?", importID); err != nil {
+		return nil, fmt.Errorf("failed to remove import record: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+
+	return stats, nil
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanImportRecord(row rowScanner) (*models.ImportRecord, error) {
+	var r models.ImportRecord
+	var durationMs int64
+	err := row.Scan(
+		&r.ID,
+		&r.FilePath,
+		&r.FileHash,
+		&r.ImportedAt,
+		&r.ConversationsCount,
+		&r.MessagesCount,
+		&r.BranchesDetected,
+		&r.ErrorsCount,
+		&durationMs,
+		&r.Status,
+		&r.ErrorMessage,
+	)
+	if err != nil {
+		return nil, err
+	}
+	r.Duration = time.Duration(durationMs) * time.Millisecond
+	return &r, nil
+}
+
+func queryIDs(tx *sql.Tx, query string, args ...interface{}) ([]int64, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close rows: %v\n", err)
+		}
+	}()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
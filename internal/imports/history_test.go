@@ -0,0 +1,139 @@
+package imports
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+func writeExport(t *testing.T, dir, name string, conversations []models.ClaudeConversation) string {
+	t.Helper()
+
+	data, err := json.Marshal(conversations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestImportHistoryAndUndo(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	database, err := db.New(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := database.Close(); err != nil {
+			t.Errorf("failed to close database: %v", err)
+		}
+	}()
+
+	parent := "msg-2"
+	first := writeExport(t, tmpDir, "first.json", []models.ClaudeConversation{
+		{
+			UUID: "conv-1", Name: "Python Development",
+			CreatedAt: "2024-01-01T10:00:00Z", UpdatedAt: "2024-01-01T10:05:00Z",
+			ChatMessages: []models.ClaudeChatMessage{
+				{UUID: "msg-1", Sender: "human", Text: "How do I use Python for machine learning?", CreatedAt: "2024-01-01T10:00:00Z"},
+				{UUID: "msg-2", Sender: "assistant", Text: "Python is great for data science with pandas and numpy", CreatedAt: "2024-01-01T10:01:00Z"},
+			},
+		},
+		{
+			UUID: "conv-2", Name: "Test Project Alpha",
+			CreatedAt: "2024-01-02T10:00:00Z", UpdatedAt: "2024-01-02T10:00:00Z",
+			ChatMessages: []models.ClaudeChatMessage{
+				{UUID: "msg-3", Sender: "human", Text: "Tell me about the test project", CreatedAt: "2024-01-02T10:00:00Z"},
+			},
+		},
+	})
+	second := writeExport(t, tmpDir, "second.json", []models.ClaudeConversation{
+		{
+			UUID: "conv-1", Name: "Python Development",
+			CreatedAt: "2024-01-01T10:00:00Z", UpdatedAt: "2024-01-03T10:00:00Z",
+			ChatMessages: []models.ClaudeChatMessage{
+				{UUID: "msg-1", Sender: "human", Text: "How do I use Python for machine learning?", CreatedAt: "2024-01-01T10:00:00Z"},
+				{UUID: "msg-2", Sender: "assistant", Text: "Python is great for data science with pandas and numpy", CreatedAt: "2024-01-01T10:01:00Z"},
+				{UUID: "msg-4", Sender: "human", Text: "What about Django?", CreatedAt: "2024-01-03T10:00:00Z", ParentID: &parent},
+			},
+		},
+	})
+
+	importer := NewImporter(database, 100, false)
+	firstStats, err := importer.Import(first)
+	if err != nil {
+		t.Fatalf("first import failed: %v", err)
+	}
+	secondStats, err := importer.Import(second)
+	if err != nil {
+		t.Fatalf("second import failed: %v", err)
+	}
+
+	records, err := ListImports(database, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 import records, got %d", len(records))
+	}
+	if records[0].ID != secondStats.ImportID || records[0].MessagesCount != 1 || records[0].Status != "success" {
+		t.Errorf("unexpected latest import record: %+v", records[0])
+	}
+
+	record, importErrors, err := GetImport(database, firstStats.ImportID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.ConversationsCount != 2 || record.MessagesCount != 3 || len(importErrors) != 0 {
+		t.Errorf("unexpected first import record: %+v (errors: %v)", record, importErrors)
+	}
+
+	// Undoing the first import removes conv-2 entirely but keeps conv-1 for msg-4
+	undo, err := UndoImport(database, firstStats.ImportID)
+	if err != nil {
+		t.Fatalf("undo failed: %v", err)
+	}
+	if undo.MessagesRemoved != 3 || undo.ConversationsRemoved != 1 {
+		t.Errorf("unexpected undo stats: %+v", undo)
+	}
+
+	var convCount, msgCount, messageCount int
+	if err := database.QueryRow("SELECT COUNT(*) FROM conversations").Scan(&convCount); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.QueryRow("SELECT COUNT(*) FROM messages").Scan(&msgCount); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.QueryRow("SELECT message_count FROM conversations WHERE uuid = 'conv-1'").Scan(&messageCount); err != nil {
+		t.Fatal(err)
+	}
+	if convCount != 1 || msgCount != 1 || messageCount != 1 {
+		t.Errorf("expected 1 conversation with 1 message, got %d conversations, %d messages, message_count %d", convCount, msgCount, messageCount)
+	}
+
+	// Derived metrics follow the remaining messages
+	var humanCount, tokenCount int
+	if err := database.QueryRow("SELECT human_message_count, token_count FROM conversations WHERE uuid = 'conv-1'").Scan(&humanCount, &tokenCount); err != nil {
+		t.Fatal(err)
+	}
+	if humanCount != 1 || tokenCount != 5 {
+		t.Errorf("expected 1 human message and 5 tokens, got %d and %d", humanCount, tokenCount)
+	}
+
+	if _, _, err := GetImport(database, firstStats.ImportID); err == nil {
+		t.Error("expected undone import to be removed from history")
+	}
+
+	// The undone file can be imported again
+	if _, err := importer.Import(first); err != nil {
+		t.Errorf("re-import after undo failed: %v", err)
+	}
+}
@@ -4,20 +4,31 @@ import (
 	"crypto/sha256"
 	"database/sql"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/neilberkman/shannon/internal/artifacts"
+	"github.com/neilberkman/shannon/internal/crash"
 	"github.com/neilberkman/shannon/internal/db"
 	"github.com/neilberkman/shannon/internal/models"
 )
 
 // Importer handles importing Claude export files into the database
 type Importer struct {
-	db        *db.DB
-	batchSize int
-	verbose   bool
+	db             *db.DB
+	batchSize      int
+	verbose        bool
+	restoreDeleted bool
+	strict         bool
+	allowUnknown   bool
+	recovering     bool
+	filter         *Filter
+	extractor      *artifacts.Extractor
 }
 
 // NewImporter creates a new importer
@@ -26,21 +37,54 @@ func NewImporter(database *db.DB, batchSize int, verbose bool) *Importer {
 		db:        database,
 		batchSize: batchSize,
 		verbose:   verbose,
+		extractor: artifacts.NewExtractor(),
 	}
 }
 
+// SetRestoreDeleted controls whether conversations deleted locally are
+// imported again. By default they are skipped.
+func (i *Importer) SetRestoreDeleted(restore bool) {
+	i.restoreDeleted = restore
+}
+
+// SetStrict makes an import fail at the first conversation that is
+// malformed or can't be imported. By default such conversations are skipped
+// and reported in ImportStats.Errors while the rest are imported.
+func (i *Importer) SetStrict(strict bool) {
+	i.strict = strict
+}
+
+// SetAllowUnknownFields lets an import go ahead when the export has fields
+// shannon doesn't know, dropping their data. By default the import fails with
+// an UnknownFieldsError listing them.
+func (i *Importer) SetAllowUnknownFields(allow bool) {
+	i.allowUnknown = allow
+}
+
+// SetRecover makes an import of an export whose JSON breaks off, like a
+// truncated download, keep the conversations before the break instead of
+// failing. The break is recorded as an error, so the import is partial.
+func (i *Importer) SetRecover(recovering bool) {
+	i.recovering = recovering
+}
+
+// SetFilter limits an import to the conversations f matches; the rest are
+// counted in ImportStats.ConversationsFiltered and left out. A nil filter
+// imports everything.
+func (i *Importer) SetFilter(f *Filter) {
+	i.filter = f
+}
+
 // Import imports a Claude export file
 func (i *Importer) Import(filePath string) (*models.ImportStats, error) {
-	stats := &models.ImportStats{}
-	startTime := time.Now()
-
+	crash.Note("import %s", filePath)
 	// Check if file has already been imported
-	hash, err := i.fileHash(filePath)
+	hash, err := FileHash(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to hash file: %w", err)
 	}
 
-	if imported, err := i.isFileImported(hash); err != nil {
+	if imported, err := i.IsImported(hash); err != nil {
 		return nil, err
 	} else if imported {
 		return nil, fmt.Errorf("file already imported (hash: %s)", hash)
@@ -51,6 +95,8 @@ func (i *Importer) Import(filePath string) (*models.ImportStats, error) {
 	if err != nil {
 		return nil, err
 	}
+	parser.SetStrict(i.strict)
+	parser.SetRecover(i.recovering)
 	defer func() {
 		if err := parser.Close(); err != nil {
 			// Log error but don't fail the import
@@ -58,6 +104,68 @@ func (i *Importer) Import(filePath string) (*models.ImportStats, error) {
 		}
 	}()
 
+	return i.run(filePath, hash, func(tx *importTx, stats *models.ImportStats) error {
+		// Use streaming parse for large files, and for compressed files
+		// whose decompressed size isn't known
+		fileInfo, _ := os.Stat(filePath)
+		var err error
+		if fileInfo.Size() > 100*1024*1024 || parser.Compressed() { // 100MB
+			err = i.streamImport(tx, parser, stats)
+		} else {
+			err = i.batchImport(tx, parser, stats)
+		}
+		if err != nil {
+			return err
+		}
+		stats.ExportVariants = parser.Variants()
+		if !i.allowUnknown {
+			return parser.UnknownFields()
+		}
+		return nil
+	})
+}
+
+// ImportConversations imports conversations that were already decoded, such
+// as the contents of a sync bundle. source and hash identify the input in the
+// import history, so the same input is never imported twice.
+func (i *Importer) ImportConversations(source, hash string, conversations []models.ClaudeConversation) (*models.ImportStats, error) {
+	if imported, err := i.IsImported(hash); err != nil {
+		return nil, err
+	} else if imported {
+		return nil, fmt.Errorf("file already imported (hash: %s)", hash)
+	}
+
+	if i.strict {
+		if err := ValidateExport(&models.ClaudeExport{Conversations: conversations}); err != nil {
+			return nil, fmt.Errorf("invalid export: %w", err)
+		}
+	} else if len(conversations) == 0 {
+		return nil, fmt.Errorf("invalid export: no conversations found in export")
+	}
+
+	return i.run(source, hash, func(tx *importTx, stats *models.ImportStats) error {
+		for index := range conversations {
+			conv := &conversations[index]
+			if err := ValidateConversation(conv); err != nil {
+				convErr := &ConversationError{Index: index, UUID: conv.UUID, Err: err}
+				if err := i.recordError(tx, stats, conv.UUID, convErr, convErr.Error()); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := i.importOne(tx, conv, stats); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// run executes an import inside a transaction and records it in the import history
+func (i *Importer) run(source, hash string, importFn func(tx *importTx, stats *models.ImportStats) error) (*models.ImportStats, error) {
+	stats := &models.ImportStats{}
+	startTime := time.Now()
+
 	// Start transaction
 	tx, err := i.db.Begin()
 	if err != nil {
@@ -72,67 +180,183 @@ func (i *Importer) Import(filePath string) (*models.ImportStats, error) {
 		}
 	}()
 
-	// Use streaming parse for large files
-	fileInfo, _ := os.Stat(filePath)
-	if fileInfo.Size() > 100*1024*1024 { // 100MB
-		err = i.streamImport(tx, parser, stats)
-	} else {
-		err = i.batchImport(tx, parser, stats)
+	// Create the history entry up front so imported rows can reference it
+	result, err := tx.Exec(`
+		INSERT INTO import_history (file_path, file_hash, status)
+		VALUES (?, ?, 'partial')
+	`, source, hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record import: %w", err)
+	}
+	stats.ImportID, err = result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get import ID: %w", err)
 	}
 
+	itx, err := newImportTx(tx, i.batchSize)
 	if err != nil {
-		_ = i.recordImport(filePath, hash, stats, "failed", err.Error())
+		return nil, err
+	}
+
+	if err := importFn(itx, stats); err != nil {
+		stats.Duration = time.Since(startTime)
+		_ = tx.Rollback() // release the connection before recording the failure
+		i.recordFailedImport(source, hash, stats, err)
 		return stats, err
 	}
 
+	// Finalize the history entry
+	stats.Duration = time.Since(startTime)
+	status := "success"
+	if len(stats.Errors) > 0 {
+		status = "partial"
+	}
+	_, err = tx.Exec(`
+		UPDATE import_history
+		SET conversations_count = ?, messages_count = ?, branches_detected = ?,
+			errors_count = ?, duration_ms = ?, status = ?
+		WHERE id = ?
+	`, stats.ConversationsImported, stats.MessagesImported, stats.BranchesDetected,
+		len(stats.Errors), stats.Duration.Milliseconds(), status, stats.ImportID)
+	if err != nil {
+		_ = tx.Rollback()
+		i.recordFailedImport(source, hash, stats, err)
+		return stats, fmt.Errorf("failed to record import: %w", err)
+	}
+
 	// Commit transaction
 	if err := tx.Commit(); err != nil {
-		_ = i.recordImport(filePath, hash, stats, "failed", err.Error())
+		i.recordFailedImport(source, hash, stats, err)
 		return stats, fmt.Errorf("failed to commit: %w", err)
 	}
 
-	stats.Duration = time.Since(startTime)
-	_ = i.recordImport(filePath, hash, stats, "success", "")
-
 	return stats, nil
 }
 
-func (i *Importer) batchImport(tx *sql.Tx, parser *Parser, stats *models.ImportStats) error {
+func (i *Importer) batchImport(tx *importTx, parser *Parser, stats *models.ImportStats) error {
 	export, err := parser.Parse()
 	if err != nil {
 		return fmt.Errorf("failed to parse export: %w", err)
 	}
 
-	if err := ValidateExport(export); err != nil {
-		return fmt.Errorf("invalid export: %w", err)
+	if err := i.recordParseErrors(tx, parser, stats); err != nil {
+		return err
+	}
+	if len(export.Conversations) == 0 {
+		if corruption := parser.Corruption(); corruption != nil {
+			return fmt.Errorf("invalid export: no conversations could be recovered before %w", corruption)
+		}
+		if skipped := len(parser.Errors()); skipped > 0 {
+			return fmt.Errorf("invalid export: none of its %d conversations could be read (first error: %v)", skipped, parser.Errors()[0])
+		}
+		return fmt.Errorf("invalid export: no conversations found in export")
 	}
 
 	// Import conversations
 	for _, conv := range export.Conversations {
-		if err := i.importConversation(tx, &conv, stats); err != nil {
-			stats.Errors = append(stats.Errors, fmt.Errorf("conversation %s: %w", conv.UUID, err))
-			if i.verbose {
-				fmt.Printf("Error importing conversation %s: %v\n", conv.UUID, err)
-			}
+		if err := i.importOne(tx, &conv, stats); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
-func (i *Importer) streamImport(tx *sql.Tx, parser *Parser, stats *models.ImportStats) error {
-	return parser.StreamParse(func(conv *models.ClaudeConversation) error {
-		if err := i.importConversation(tx, conv, stats); err != nil {
-			stats.Errors = append(stats.Errors, fmt.Errorf("conversation %s: %w", conv.UUID, err))
+func (i *Importer) streamImport(tx *importTx, parser *Parser, stats *models.ImportStats) error {
+	if err := parser.StreamParse(func(conv *models.ClaudeConversation) error {
+		return i.importOne(tx, conv, stats)
+	}); err != nil {
+		return err
+	}
+	return i.recordParseErrors(tx, parser, stats)
+}
+
+// importOne imports a conversation, recording a failure instead of
+// returning it unless the import is strict
+func (i *Importer) importOne(tx *importTx, conv *models.ClaudeConversation, stats *models.ImportStats) error {
+	if err := i.importConversation(tx, conv, stats); err != nil {
+		if i.strict {
+			return fmt.Errorf("conversation %s: %w", conv.UUID, err)
+		}
+		return i.recordConversationError(tx, stats, conv.UUID, err)
+	}
+	return nil
+}
+
+// recordParseErrors records the malformed conversations the parser skipped,
+// and where the export broke off if it was recovered
+func (i *Importer) recordParseErrors(tx *importTx, parser *Parser, stats *models.ImportStats) error {
+	for _, convErr := range parser.Errors() {
+		if err := i.recordError(tx, stats, convErr.UUID, convErr, convErr.Error()); err != nil {
+			return err
+		}
+	}
+	if corruption := parser.Corruption(); corruption != nil {
+		message := fmt.Sprintf("export broken off after %d conversations: %v; skipped %d bytes, about %d conversations",
+			corruption.Index, corruption, corruption.Skipped, corruption.Conversations)
+		return i.recordError(tx, stats, corruption.UUID, corruption, message)
+	}
+	return nil
+}
+
+// recordConversationError notes a conversation that failed to import, both in
+// the returned stats and in the import_errors table
+func (i *Importer) recordConversationError(tx *importTx, stats *models.ImportStats, convUUID string, convErr error) error {
+	return i.recordError(tx, stats, convUUID, fmt.Errorf("conversation %s: %w", convUUID, convErr), convErr.Error())
+}
+
+// recordError notes a conversation error in the returned stats and saves
+// message, which needn't repeat the conversation UUID, to import_errors
+func (i *Importer) recordError(tx *importTx, stats *models.ImportStats, convUUID string, convErr error, message string) error {
+	stats.Errors = append(stats.Errors, convErr)
+	if i.verbose {
+		fmt.Printf("Error importing %v\n", convErr)
+	}
+
+	var uuid interface{}
+	if convUUID != "" {
+		uuid = convUUID
+	}
+	_, err := tx.Exec(`
+		INSERT INTO import_errors (import_id, conversation_uuid, error_message)
+		VALUES (?, ?, ?)
+	`, stats.ImportID, uuid, message)
+	if err != nil {
+		return fmt.Errorf("failed to record import error: %w", err)
+	}
+	return nil
+}
+
+func (i *Importer) importConversation(tx *importTx, conv *models.ClaudeConversation, stats *models.ImportStats) error {
+	if i.filter != nil && !i.filter.Match(conv) {
+		stats.ConversationsFiltered++
+		if i.verbose {
+			fmt.Printf("Leaving out conversation %s, which doesn't match the filters\n", conv.UUID)
+		}
+		return nil
+	}
+
+	// Conversations deleted locally stay deleted unless asked otherwise
+	if deleted, err := i.isDeleted(tx, conv.UUID); err != nil {
+		return err
+	} else if deleted {
+		if !i.restoreDeleted {
+			stats.ConversationsSkipped++
 			if i.verbose {
-				fmt.Printf("Error importing conversation %s: %v\n", conv.UUID, err)
+				fmt.Printf("Skipping deleted conversation %s\n", conv.UUID)
 			}
+			return nil
 		}
-		return nil
-	})
-}
+		if _, err := tx.Exec("DELETE FROM deleted_conversations WHERE uuid = ?", conv.UUID); err != nil {
+			return fmt.Errorf("failed to restore deleted conversation: %w", err)
+		}
+		// Take it out of the trash if it hasn't been purged yet
+		if _, err := tx.Exec("UPDATE conversations SET deleted_at = NULL WHERE uuid = ? AND deleted_at IS NOT NULL", conv.UUID); err != nil {
+			return fmt.Errorf("failed to restore trashed conversation: %w", err)
+		}
+		stats.ConversationsRestored++
+	}
 
-func (i *Importer) importConversation(tx *sql.Tx, conv *models.ClaudeConversation, stats *models.ImportStats) error {
 	// Parse timestamps
 	createdAt, err := ParseTime(conv.CreatedAt)
 	if err != nil {
@@ -144,21 +368,16 @@ func (i *Importer) importConversation(tx *sql.Tx, conv *models.ClaudeConversatio
 		return fmt.Errorf("invalid updated_at: %w", err)
 	}
 
-	// Check if conversation already exists and get existing message UUIDs
-	existingMessages, err := i.getExistingMessageUUIDs(tx, conv.UUID)
-	if err != nil {
-		return fmt.Errorf("failed to get existing messages: %w", err)
-	}
-
 	// Check if conversation exists
 	var convID int64
-	err = tx.QueryRow("SELECT id FROM conversations WHERE uuid = ?", conv.UUID).Scan(&convID)
+	isNew := false
+	err = tx.queryRow("SELECT id FROM conversations WHERE uuid = ?", conv.UUID).Scan(&convID)
 	if err == sql.ErrNoRows {
 		// Insert new conversation
-		result, err := tx.Exec(`
-			INSERT INTO conversations (uuid, name, created_at, updated_at, message_count)
-			VALUES (?, ?, ?, ?, ?)
-		`, conv.UUID, conv.Name, createdAt, updatedAt, len(conv.ChatMessages))
+		result, err := tx.exec(`
+			INSERT INTO conversations (uuid, name, created_at, updated_at, message_count, import_id, starred)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, conv.UUID, conv.Name, createdAt, updatedAt, len(conv.ChatMessages), stats.ImportID, conv.Starred != nil && *conv.Starred)
 
 		if err != nil {
 			return fmt.Errorf("failed to insert conversation: %w", err)
@@ -168,47 +387,142 @@ func (i *Importer) importConversation(tx *sql.Tx, conv *models.ClaudeConversatio
 		if err != nil {
 			return fmt.Errorf("failed to get conversation ID: %w", err)
 		}
+		isNew = true
 		stats.ConversationsImported++
 	} else if err != nil {
 		return fmt.Errorf("failed to check existing conversation: %w", err)
 	} else {
-		// Update existing conversation
-		_, err = tx.Exec(`
+		// Update existing conversation; the export's messages may have
+		// been deleted or split off locally, so they're counted afterwards.
+		// A conversation renamed locally keeps its name, and the export's
+		// goes to original_name. Its star follows claude.ai's when the export
+		// records it.
+		_, err = tx.exec(`
 			UPDATE conversations 
-			SET name = ?, updated_at = ?, message_count = ?
+			SET name = CASE WHEN original_name IS NULL THEN ? ELSE name END,
+			    original_name = CASE WHEN original_name IS NULL THEN NULL ELSE ? END,
+			    updated_at = ?,
+			    starred = COALESCE(?, starred)
 			WHERE id = ?
-		`, conv.Name, updatedAt, len(conv.ChatMessages), convID)
+		`, conv.Name, conv.Name, updatedAt, conv.Starred, convID)
 
 		if err != nil {
 			return fmt.Errorf("failed to update conversation: %w", err)
 		}
 	}
 
-	// Get or create main branch
-	mainBranchID, err := i.getOrCreateMainBranch(tx, convID)
+	// A new conversation has no messages yet, so skip looking them up
+	var mainBranchID int64
+	existingMessages := make(map[string]struct{})
+	if isNew {
+		mainBranchID, err = i.createBranch(tx, convID, "main", nil)
+	} else {
+		if existingMessages, err = i.getExistingMessageUUIDs(tx, conv.UUID); err != nil {
+			return fmt.Errorf("failed to get existing messages: %w", err)
+		}
+		mainBranchID, err = i.getOrCreateMainBranch(tx, convID)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to get or create main branch: %w", err)
 	}
 
 	// Import only new messages using tree diff approach
-	newMessagesCount, branchesDetected, err := i.importNewMessages(tx, convID, mainBranchID, conv.ChatMessages, existingMessages, stats)
+	newMessagesCount, branchesDetected, err := i.importNewMessages(tx, convID, mainBranchID, !isNew, conv, existingMessages, stats)
 	if err != nil {
 		return fmt.Errorf("failed to import messages: %w", err)
 	}
 
 	stats.MessagesImported += newMessagesCount
 	stats.BranchesDetected += branchesDetected
+	noteLargest(stats, conv, newMessagesCount)
+
+	// Messages added to an existing conversation are numbered by their place
+	// in this export, which needn't agree with the earlier one
+	if !isNew && newMessagesCount > 0 {
+		moved, err := db.RepairOrder(tx, convID)
+		if err != nil {
+			return fmt.Errorf("failed to repair message order: %w", err)
+		}
+		if moved > 0 {
+			stats.ConversationsReordered++
+		}
+	}
+
+	if !isNew {
+		if _, err := tx.exec(`
+			UPDATE conversations
+			SET message_count = (SELECT COUNT(*) FROM messages WHERE conversation_id = ?)
+			WHERE id = ?
+		`, convID, convID); err != nil {
+			return fmt.Errorf("failed to update message count: %w", err)
+		}
+	}
+
+	// Keep the derived sorting metrics in step with the messages
+	if _, err := tx.exec(db.RefreshConversationStatsSQL, convID); err != nil {
+		return fmt.Errorf("failed to update conversation statistics: %w", err)
+	}
 
 	return nil
 }
 
-// getExistingMessageUUIDs returns a map of existing message UUIDs for a conversation
-func (i *Importer) getExistingMessageUUIDs(tx *sql.Tx, convUUID string) (map[string]struct{}, error) {
+// maxLargestConversations is how many conversations ImportStats keeps in
+// LargestConversations
+const maxLargestConversations = 5
+
+// noteLargest keeps a conversation in LargestConversations if it's among
+// those with the most messages imported
+func noteLargest(stats *models.ImportStats, conv *models.ClaudeConversation, messages int) {
+	if messages == 0 {
+		return
+	}
+	largest := append(stats.LargestConversations, models.ImportedConversation{UUID: conv.UUID, Name: conv.Name, Messages: messages})
+	sort.SliceStable(largest, func(a, b int) bool { return largest[a].Messages > largest[b].Messages })
+	if len(largest) > maxLargestConversations {
+		largest = largest[:maxLargestConversations]
+	}
+	stats.LargestConversations = largest
+}
+
+// countBlock adds an imported code block or artifact to the import's totals
+func countBlock(stats *models.ImportStats, block *artifacts.CodeBlock) {
+	if block.Kind == artifacts.KindArtifact {
+		stats.ArtifactsFound++
+	} else {
+		stats.CodeBlocksFound++
+	}
+	if stats.Languages == nil {
+		stats.Languages = make(map[string]int)
+	}
+	stats.Languages[block.Language]++
+}
+
+// isDeleted reports whether a conversation was deleted locally
+func (i *Importer) isDeleted(tx *importTx, convUUID string) (bool, error) {
+	var n int
+	if err := tx.queryRow("SELECT COUNT(*) FROM deleted_conversations WHERE uuid = ?", convUUID).Scan(&n); err != nil {
+		return false, fmt.Errorf("failed to check deleted conversations: %w", err)
+	}
+	return n > 0, nil
+}
+
+// getExistingMessageUUIDs returns a map of existing message UUIDs for a conversation.
+// Messages deleted with `shannon cleanup` count as existing so re-imports don't restore them,
+// as do messages moved to conversations split off it with `shannon split`.
+func (i *Importer) getExistingMessageUUIDs(tx *importTx, convUUID string) (map[string]struct{}, error) {
 	query := `
+		WITH RECURSIVE parts(id) AS (
+			SELECT id FROM conversations WHERE uuid = ?
+			UNION
+			SELECT s.conversation_id FROM conversation_splits s JOIN parts p ON s.source_id = p.id
+		)
 		SELECT m.uuid 
 		FROM messages m
-		JOIN conversations c ON m.conversation_id = c.id
-		WHERE c.uuid = ?
+		WHERE m.conversation_id IN (SELECT id FROM parts)
+		UNION
+		SELECT e.message_uuid
+		FROM message_edits e
+		WHERE e.conversation_id IN (SELECT id FROM parts) AND e.action = 'delete'
 	`
 
 	rows, err := tx.Query(query, convUUID)
@@ -234,10 +548,10 @@ func (i *Importer) getExistingMessageUUIDs(tx *sql.Tx, convUUID string) (map[str
 }
 
 // getOrCreateMainBranch gets existing main branch or creates it
-func (i *Importer) getOrCreateMainBranch(tx *sql.Tx, convID int64) (int64, error) {
+func (i *Importer) getOrCreateMainBranch(tx *importTx, convID int64) (int64, error) {
 	// Try to get existing main branch
 	var branchID int64
-	err := tx.QueryRow(`
+	err := tx.queryRow(`
 		SELECT id FROM branches WHERE conversation_id = ? AND name = 'main'
 	`, convID).Scan(&branchID)
 
@@ -251,27 +565,56 @@ func (i *Importer) getOrCreateMainBranch(tx *sql.Tx, convID int64) (int64, error
 	return branchID, nil
 }
 
-// importNewMessages imports only new messages, detecting branches based on parent relationships
-func (i *Importer) importNewMessages(tx *sql.Tx, convID, mainBranchID int64, messages []models.ClaudeChatMessage, existingMessages map[string]struct{}, stats *models.ImportStats) (int, int, error) {
+// importNewMessages imports only new messages, detecting branches based on parent relationships.
+// Parents and branches are resolved in memory, then the messages and their code
+// blocks are written in multi-row batches. hasExisting says whether the
+// conversation already has messages to link to.
+func (i *Importer) importNewMessages(tx *importTx, convID, mainBranchID int64, hasExisting bool, conv *models.ClaudeConversation, existingMessages map[string]struct{}, stats *models.ImportStats) (int, int, error) {
 	messageIDMap := make(map[string]int64)
-	newMessagesCount := 0
+	// Parents with a child on the main branch; another child starts a branch
+	mainChildren := make(map[int64]bool)
 	branchesDetected := 0
 
 	// Load existing message ID mappings
-	if err := i.loadExistingMessageIDs(tx, convID, messageIDMap); err != nil {
-		return 0, 0, err
+	if hasExisting {
+		if err := i.loadExistingMessageIDs(tx, convID, mainBranchID, messageIDMap, mainChildren); err != nil {
+			return 0, 0, err
+		}
 	}
 
-	for idx, msg := range messages {
-		// Skip if message already exists
+	var messageRows, codeBlockRows [][]interface{}
+	// New messages, to extract code blocks and artifacts from all at once
+	var added []*models.Message
+	for idx, msg := range conv.ChatMessages {
+		model := messageModel(conv, &msg)
+		feedback, hasFeedback := messageFeedback(msg.Feedback)
+
+		// Skip if message already exists, filling in its model if it was
+		// imported before models were kept. Stars and feedback follow
+		// claude.ai's, as far as the export records them.
 		if _, exists := existingMessages[msg.UUID]; exists {
+			if model != "" {
+				if _, err := tx.exec("UPDATE messages SET model = ? WHERE uuid = ? AND model = ''", model, msg.UUID); err != nil {
+					return 0, 0, fmt.Errorf("failed to update message model: %w", err)
+				}
+			}
+			if msg.Starred != nil || hasFeedback {
+				var feedbackArg interface{}
+				if hasFeedback {
+					feedbackArg = feedback
+				}
+				if _, err := tx.exec("UPDATE messages SET starred = COALESCE(?, starred), feedback = COALESCE(?, feedback) WHERE uuid = ?",
+					msg.Starred, feedbackArg, msg.UUID); err != nil {
+					return 0, 0, fmt.Errorf("failed to update message star: %w", err)
+				}
+			}
 			continue
 		}
 
 		// This is a new message
 		msgCreatedAt, err := ParseTime(msg.CreatedAt)
 		if err != nil {
-			return newMessagesCount, branchesDetected, fmt.Errorf("invalid message created_at: %w", err)
+			return 0, 0, fmt.Errorf("invalid message created_at: %w", err)
 		}
 
 		// Get message text
@@ -293,43 +636,103 @@ func (i *Importer) importNewMessages(tx *sql.Tx, convID, mainBranchID int64, mes
 			if pid, ok := messageIDMap[*msg.ParentID]; ok {
 				parentID = &pid
 
-				// Check if parent is in main branch - if not, this might be a new branch
-				if isNewBranch, err := i.detectNewBranch(tx, pid, mainBranchID); err != nil {
-					return newMessagesCount, branchesDetected, err
-				} else if isNewBranch {
-					// Create new branch
+				// If the parent already has a child in the main branch, this is a new branch
+				if mainChildren[pid] {
 					branchName := fmt.Sprintf("branch-%d", time.Now().Unix())
 					branchID, err = i.createBranch(tx, convID, branchName, &mainBranchID)
 					if err != nil {
-						return newMessagesCount, branchesDetected, err
+						return 0, 0, err
 					}
 					branchesDetected++
+				} else {
+					mainChildren[pid] = true
 				}
 			}
 		}
 
-		// Insert message
-		result, err := tx.Exec(`
-			INSERT INTO messages (uuid, conversation_id, sender, text, created_at, parent_id, branch_id, sequence)
-			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
-		`, msg.UUID, convID, msg.Sender, text, msgCreatedAt, parentID, branchID, idx)
+		msgID := tx.newMessageID()
+		messageIDMap[msg.UUID] = msgID
+		messageRows = append(messageRows, []interface{}{
+			msgID, msg.UUID, convID, msg.Sender, db.StoredText(text, tx.compress), msgCreatedAt, parentID, branchID, idx, stats.ImportID, model,
+			msg.Starred != nil && *msg.Starred, feedback,
+		})
 
-		if err != nil {
-			return newMessagesCount, branchesDetected, fmt.Errorf("failed to insert message: %w", err)
+		added = append(added, &models.Message{ID: msgID, Sender: msg.Sender, Text: text})
+	}
+
+	// Index code blocks and artifacts for grep-code and the TUI, extracting
+	// them in parallel for conversations with many messages
+	for j, blocks := range i.extractor.ExtractAllCodeBlocks(added) {
+		for _, block := range blocks {
+			codeBlockRows = append(codeBlockRows, []interface{}{
+				added[j].ID, convID, block.Kind, block.Language, block.Title,
+				block.Identifier, block.Type, block.StartLine, block.Content,
+			})
+			countBlock(stats, block)
 		}
+	}
 
-		msgID, _ := result.LastInsertId()
-		messageIDMap[msg.UUID] = msgID
-		newMessagesCount++
+	if err := tx.insertRows("messages", messageColumns, messageRows); err != nil {
+		return 0, 0, fmt.Errorf("failed to insert messages: %w", err)
+	}
+	if err := tx.insertRows("code_blocks", codeBlockColumns, codeBlockRows); err != nil {
+		return 0, 0, fmt.Errorf("failed to index code blocks: %w", err)
+	}
+
+	return len(messageRows), branchesDetected, nil
+}
+
+// messageModel returns the model that wrote a message: its own if the
+// export records one, otherwise the conversation's. Human messages have none.
+func messageModel(conv *models.ClaudeConversation, msg *models.ClaudeChatMessage) string {
+	if msg.Sender != "assistant" {
+		return ""
+	}
+	if msg.Model != "" {
+		return msg.Model
 	}
+	return conv.Model
+}
 
-	return newMessagesCount, branchesDetected, nil
+// messageFeedback reads the feedback given to a message in claude.ai, which
+// exports record as a string or as an object with a type, into
+// models.FeedbackUp or models.FeedbackDown. It returns false if the export
+// doesn't record feedback, and an empty string if it records none was given.
+func messageFeedback(raw json.RawMessage) (string, bool) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", len(raw) > 0
+	}
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		var object struct {
+			Type   string `json:"type"`
+			Rating string `json:"rating"`
+		}
+		if err := json.Unmarshal(raw, &object); err != nil {
+			return "", false
+		}
+		value = object.Type + object.Rating
+	}
+
+	value = strings.ToLower(value)
+	switch {
+	case value == "":
+		return "", true
+	case strings.Contains(value, "down"), strings.Contains(value, "negative"), strings.Contains(value, "dislike"),
+		strings.Contains(value, "bad"):
+		return models.FeedbackDown, true
+	case strings.Contains(value, "up"), strings.Contains(value, "positive"), strings.Contains(value, "like"),
+		strings.Contains(value, "good"):
+		return models.FeedbackUp, true
+	}
+	return "", false
 }
 
-// loadExistingMessageIDs loads UUID to ID mappings for existing messages
-func (i *Importer) loadExistingMessageIDs(tx *sql.Tx, convID int64, messageIDMap map[string]int64) error {
+// loadExistingMessageIDs loads UUID to ID mappings for existing messages,
+// and notes which of them already have a child in the main branch
+func (i *Importer) loadExistingMessageIDs(tx *importTx, convID, mainBranchID int64, messageIDMap map[string]int64, mainChildren map[int64]bool) error {
 	rows, err := tx.Query(`
-		SELECT id, uuid FROM messages WHERE conversation_id = ?
+		SELECT id, uuid, parent_id, branch_id FROM messages WHERE conversation_id = ?
 	`, convID)
 	if err != nil {
 		return err
@@ -343,34 +746,21 @@ func (i *Importer) loadExistingMessageIDs(tx *sql.Tx, convID int64, messageIDMap
 	for rows.Next() {
 		var id int64
 		var uuid string
-		if err := rows.Scan(&id, &uuid); err != nil {
+		var parentID, branchID sql.NullInt64
+		if err := rows.Scan(&id, &uuid, &parentID, &branchID); err != nil {
 			return err
 		}
 		messageIDMap[uuid] = id
+		if parentID.Valid && branchID.Valid && branchID.Int64 == mainBranchID {
+			mainChildren[parentID.Int64] = true
+		}
 	}
 
 	return rows.Err()
 }
 
-// detectNewBranch determines if a new message creates a branch
-func (i *Importer) detectNewBranch(tx *sql.Tx, parentID, mainBranchID int64) (bool, error) {
-	// Check if parent already has children in main branch
-	var childCount int
-	err := tx.QueryRow(`
-		SELECT COUNT(*) FROM messages 
-		WHERE parent_id = ? AND branch_id = ?
-	`, parentID, mainBranchID).Scan(&childCount)
-
-	if err != nil {
-		return false, err
-	}
-
-	// If parent already has children, this creates a new branch
-	return childCount > 0, nil
-}
-
-func (i *Importer) createBranch(tx *sql.Tx, convID int64, name string, parentBranchID *int64) (int64, error) {
-	result, err := tx.Exec(`
+func (i *Importer) createBranch(tx *importTx, convID int64, name string, parentBranchID *int64) (int64, error) {
+	result, err := tx.exec(`
 		INSERT INTO branches (conversation_id, name, parent_branch_id)
 		VALUES (?, ?, ?)
 	`, convID, name, parentBranchID)
@@ -382,7 +772,9 @@ func (i *Importer) createBranch(tx *sql.Tx, convID int64, name string, parentBra
 	return result.LastInsertId()
 }
 
-func (i *Importer) fileHash(filePath string) (string, error) {
+// FileHash returns the SHA-256 of a file, which identifies it in the import
+// history
+func FileHash(filePath string) (string, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return "", err
@@ -401,16 +793,26 @@ func (i *Importer) fileHash(filePath string) (string, error) {
 	return hex.EncodeToString(hasher.Sum(nil)), nil
 }
 
-func (i *Importer) isFileImported(hash string) (bool, error) {
+// IsImported reports whether the file with this hash was imported before,
+// fully or in part
+func (i *Importer) IsImported(hash string) (bool, error) {
 	var count int
-	err := i.db.QueryRow("SELECT COUNT(*) FROM import_history WHERE file_hash = ?", hash).Scan(&count)
+	err := i.db.QueryRow("SELECT COUNT(*) FROM import_history WHERE file_hash = ? AND status != 'failed'", hash).Scan(&count)
 	return count > 0, err
 }
 
-func (i *Importer) recordImport(filePath, hash string, stats *models.ImportStats, status, errorMsg string) error {
-	_, err := i.db.Exec(`
-		INSERT INTO import_history (file_path, file_hash, conversations_count, messages_count, status, error_message)
-		VALUES (?, ?, ?, ?, ?, ?)
-	`, filePath, hash, stats.ConversationsImported, stats.MessagesImported, status, errorMsg)
-	return err
+// recordFailedImport records a failed import outside of the (rolled back) import transaction
+func (i *Importer) recordFailedImport(filePath, hash string, stats *models.ImportStats, importErr error) {
+	// Nothing from the import transaction survives, so don't report partial counts
+	result, err := i.db.Exec(`
+		INSERT INTO import_history (file_path, file_hash, conversations_count, messages_count, duration_ms, status, error_message)
+		VALUES (?, ?, 0, 0, ?, 'failed', ?)
+	`, filePath, hash, stats.Duration.Milliseconds(), importErr.Error())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record failed import: %v\n", err)
+		return
+	}
+	if id, err := result.LastInsertId(); err == nil {
+		stats.ImportID = id
+	}
 }
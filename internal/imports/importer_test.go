@@ -0,0 +1,375 @@
+package imports
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+func TestImportBatches(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	database, err := db.New(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := database.Close(); err != nil {
+			t.Errorf("failed to close database: %v", err)
+		}
+	}()
+
+	parent := func(uuid string) *string { return &uuid }
+	first := writeExport(t, tmpDir, "first.json", []models.ClaudeConversation{
+		{
+			UUID: "conv-1", Name: "Go Development",
+			CreatedAt: "2024-01-01T10:00:00Z", UpdatedAt: "2024-01-01T10:05:00Z",
+			ChatMessages: []models.ClaudeChatMessage{
+				{UUID: "msg-1", Sender: "human", Text: "How do I read a file in Go?", CreatedAt: "2024-01-01T10:00:00Z"},
+				{UUID: "msg-2", Sender: "assistant", Text: "Use os.ReadFile:\n```go\ndata, err := os.ReadFile(name)\n```", CreatedAt: "2024-01-01T10:01:00Z", ParentID: parent("msg-1")},
+				{UUID: "msg-3", Sender: "human", Text: "And write one?", CreatedAt: "2024-01-01T10:02:00Z", ParentID: parent("msg-2")},
+				{UUID: "msg-4", Sender: "assistant", Text: "Use os.WriteFile", CreatedAt: "2024-01-01T10:03:00Z", ParentID: parent("msg-3")},
+				// A retried answer branches off msg-3
+				{UUID: "msg-5", Sender: "assistant", Text: "Try os.WriteFile with 0644", CreatedAt: "2024-01-01T10:04:00Z", ParentID: parent("msg-3")},
+			},
+		},
+		{
+			UUID: "conv-2", Name: "Shell",
+			CreatedAt: "2024-01-02T10:00:00Z", UpdatedAt: "2024-01-02T10:00:00Z",
+			ChatMessages: []models.ClaudeChatMessage{
+				{UUID: "msg-6", Sender: "human", Text: "List files", CreatedAt: "2024-01-02T10:00:00Z"},
+				{UUID: "msg-7", Sender: "assistant", Text: "```bash\nls -la\n```", CreatedAt: "2024-01-02T10:01:00Z", ParentID: parent("msg-6")},
+			},
+		},
+	})
+	second := writeExport(t, tmpDir, "second.json", []models.ClaudeConversation{
+		{
+			UUID: "conv-1", Name: "Go Development",
+			CreatedAt: "2024-01-01T10:00:00Z", UpdatedAt: "2024-01-03T10:00:00Z",
+			ChatMessages: []models.ClaudeChatMessage{
+				{UUID: "msg-1", Sender: "human", Text: "How do I read a file in Go?", CreatedAt: "2024-01-01T10:00:00Z"},
+				{UUID: "msg-8", Sender: "human", Text: "How do I read a directory?", CreatedAt: "2024-01-03T10:00:00Z", ParentID: parent("msg-4")},
+				// An edited question branches off msg-1, which already has a child
+				{UUID: "msg-9", Sender: "human", Text: "How do I read a file in Rust?", CreatedAt: "2024-01-03T10:01:00Z", ParentID: parent("msg-1")},
+			},
+		},
+	})
+
+	// A batch size of 2 splits every conversation over several INSERTs
+	importer := NewImporter(database, 2, false)
+	stats, err := importer.Import(first)
+	if err != nil {
+		t.Fatalf("first import failed: %v", err)
+	}
+	if stats.MessagesImported != 7 || stats.BranchesDetected != 1 {
+		t.Errorf("first import: got %d messages and %d branches, want 7 and 1", stats.MessagesImported, stats.BranchesDetected)
+	}
+	if stats.CodeBlocksFound != 2 || stats.ArtifactsFound != 0 || stats.Languages["go"] != 1 || stats.Languages["bash"] != 1 {
+		t.Errorf("first import: got %d code blocks, %d artifacts and languages %v, want 2, 0 and go and bash",
+			stats.CodeBlocksFound, stats.ArtifactsFound, stats.Languages)
+	}
+	if largest := stats.LargestConversations; len(largest) != 2 || largest[0].UUID != "conv-1" || largest[0].Messages != 5 || largest[1].Messages != 2 {
+		t.Errorf("first import: got largest conversations %+v, want conv-1 with 5 messages, then conv-2 with 2", largest)
+	}
+
+	// IDs continue after deleted messages instead of reusing theirs
+	var lastID int64
+	if err := database.QueryRow("SELECT MAX(id) FROM messages").Scan(&lastID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.Exec("DELETE FROM messages WHERE uuid = 'msg-7'"); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err = importer.Import(second)
+	if err != nil {
+		t.Fatalf("second import failed: %v", err)
+	}
+	if stats.MessagesImported != 2 || stats.BranchesDetected != 1 || stats.ConversationsReordered != 1 {
+		t.Errorf("second import: got %d messages, %d branches and %d reordered conversations, want 2, 1 and 1",
+			stats.MessagesImported, stats.BranchesDetected, stats.ConversationsReordered)
+	}
+
+	rows, err := database.Query(`
+		SELECT m.id, m.uuid, COALESCE(p.uuid, ''), b.name = 'main', m.sequence
+		FROM messages m
+		LEFT JOIN messages p ON p.id = m.parent_id
+		JOIN branches b ON b.id = m.branch_id
+		ORDER BY m.uuid
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type message struct {
+		parent   string
+		main     bool
+		sequence int
+	}
+	got := make(map[string]message)
+	for rows.Next() {
+		var id int64
+		var uuid string
+		var msg message
+		if err := rows.Scan(&id, &uuid, &msg.parent, &msg.main, &msg.sequence); err != nil {
+			t.Fatal(err)
+		}
+		if (uuid == "msg-8" || uuid == "msg-9") && id <= lastID {
+			t.Errorf("%s reused ID %d", uuid, id)
+		}
+		got[uuid] = msg
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]message{
+		"msg-1": {"", true, 0},
+		"msg-2": {"msg-1", true, 1},
+		"msg-3": {"msg-2", true, 2},
+		"msg-4": {"msg-3", true, 3},
+		"msg-5": {"msg-3", false, 4},
+		"msg-6": {"", true, 0},
+		// Numbered after the first export's messages, not by their place in
+		// the second export
+		"msg-8": {"msg-4", true, 5},
+		"msg-9": {"msg-1", false, 6},
+	}
+	if len(got) != len(want) {
+		t.Errorf("got %d messages, want %d", len(got), len(want))
+	}
+	for uuid, w := range want {
+		if got[uuid] != w {
+			t.Errorf("%s: got %+v, want %+v", uuid, got[uuid], w)
+		}
+	}
+
+	// Code blocks and the full-text index cover the batched messages
+	var blocks int
+	if err := database.QueryRow("SELECT COUNT(*) FROM code_blocks cb JOIN messages m ON m.id = cb.message_id WHERE m.uuid = 'msg-2' AND cb.language = 'go'").Scan(&blocks); err != nil {
+		t.Fatal(err)
+	}
+	if blocks != 1 {
+		t.Errorf("got %d code blocks for msg-2, want 1", blocks)
+	}
+	var matches int
+	if err := database.QueryRow("SELECT COUNT(*) FROM messages_fts WHERE messages_fts MATCH 'directory OR rust'").Scan(&matches); err != nil {
+		t.Fatal(err)
+	}
+	if matches != 2 {
+		t.Errorf("got %d full-text matches, want 2", matches)
+	}
+
+	// The derived conversation stats count the batched messages
+	var humanMessages int
+	if err := database.QueryRow("SELECT human_message_count FROM conversations WHERE uuid = 'conv-1'").Scan(&humanMessages); err != nil {
+		t.Fatal(err)
+	}
+	if humanMessages != 4 {
+		t.Errorf("got %d human messages in conv-1, want 4", humanMessages)
+	}
+}
+
+func TestImportModels(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	database, err := db.New(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := database.Close(); err != nil {
+			t.Errorf("failed to close database: %v", err)
+		}
+	}()
+
+	messages := []models.ClaudeChatMessage{
+		{UUID: "msg-1", Sender: "human", Text: "Hi", CreatedAt: "2024-01-01T10:00:00Z"},
+		{UUID: "msg-2", Sender: "assistant", Text: "Hello", CreatedAt: "2024-01-01T10:01:00Z"},
+		{UUID: "msg-3", Sender: "human", Text: "Again", CreatedAt: "2024-01-01T10:02:00Z"},
+		{UUID: "msg-4", Sender: "assistant", Text: "Hello again", CreatedAt: "2024-01-01T10:03:00Z", Model: "claude-3-5-sonnet-20241022"},
+	}
+	conv := models.ClaudeConversation{
+		UUID: "conv-1", Name: "Models",
+		CreatedAt: "2024-01-01T10:00:00Z", UpdatedAt: "2024-01-01T10:03:00Z",
+		ChatMessages: messages[:2],
+	}
+
+	// An export without models, then one that records them, as when the
+	// same conversation is exported again later
+	importer := NewImporter(database, 0, false)
+	if _, err := importer.Import(writeExport(t, tmpDir, "first.json", []models.ClaudeConversation{conv})); err != nil {
+		t.Fatalf("first import failed: %v", err)
+	}
+	conv.Model = "claude-3-opus-20240229"
+	conv.ChatMessages = messages
+	if _, err := importer.Import(writeExport(t, tmpDir, "second.json", []models.ClaudeConversation{conv})); err != nil {
+		t.Fatalf("second import failed: %v", err)
+	}
+
+	// Assistant messages take the conversation's model unless they have
+	// their own, and the message imported without one gets it
+	want := map[string]string{"msg-1": "", "msg-2": "claude-3-opus-20240229", "msg-3": "", "msg-4": "claude-3-5-sonnet-20241022"}
+	rows, err := database.Query("SELECT uuid, model FROM messages")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = rows.Close() }()
+	got := make(map[string]string)
+	for rows.Next() {
+		var uuid, model string
+		if err := rows.Scan(&uuid, &model); err != nil {
+			t.Fatal(err)
+		}
+		got[uuid] = model
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected models %v, got %v", want, got)
+	}
+}
+
+func TestImportStarsAndFeedback(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	database, err := db.New(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := database.Close(); err != nil {
+			t.Errorf("failed to close database: %v", err)
+		}
+	}()
+
+	starred, unstarred := true, false
+	conv := models.ClaudeConversation{
+		UUID: "conv-1", Name: "Curated",
+		CreatedAt: "2024-01-01T10:00:00Z", UpdatedAt: "2024-01-01T10:03:00Z",
+		Starred: &starred,
+		ChatMessages: []models.ClaudeChatMessage{
+			{UUID: "msg-1", Sender: "human", Text: "Hi", CreatedAt: "2024-01-01T10:00:00Z"},
+			{UUID: "msg-2", Sender: "assistant", Text: "Hello", CreatedAt: "2024-01-01T10:01:00Z",
+				Starred: &starred, Feedback: json.RawMessage(`{"type": "upvote"}`)},
+			{UUID: "msg-3", Sender: "assistant", Text: "Hello again", CreatedAt: "2024-01-01T10:02:00Z",
+				Feedback: json.RawMessage(`"thumbs_down"`)},
+		},
+	}
+
+	importer := NewImporter(database, 0, false)
+	if _, err := importer.Import(writeExport(t, tmpDir, "first.json", []models.ClaudeConversation{conv})); err != nil {
+		t.Fatalf("first import failed: %v", err)
+	}
+
+	// Unstarred in claude.ai later, with the feedback left out of the export
+	conv.Starred = nil
+	conv.ChatMessages[1].Starred = &unstarred
+	conv.ChatMessages[1].Feedback = nil
+	if _, err := importer.Import(writeExport(t, tmpDir, "second.json", []models.ClaudeConversation{conv})); err != nil {
+		t.Fatalf("second import failed: %v", err)
+	}
+
+	var convStarred bool
+	if err := database.QueryRow("SELECT starred FROM conversations WHERE uuid = 'conv-1'").Scan(&convStarred); err != nil {
+		t.Fatal(err)
+	}
+	if !convStarred {
+		t.Error("expected the conversation to stay starred when the export doesn't say")
+	}
+
+	type marks struct {
+		starred  bool
+		feedback string
+	}
+	want := map[string]marks{"msg-1": {}, "msg-2": {false, models.FeedbackUp}, "msg-3": {false, models.FeedbackDown}}
+	rows, err := database.Query("SELECT uuid, starred, feedback FROM messages")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = rows.Close() }()
+	got := make(map[string]marks)
+	for rows.Next() {
+		var uuid string
+		var m marks
+		if err := rows.Scan(&uuid, &m.starred, &m.feedback); err != nil {
+			t.Fatal(err)
+		}
+		got[uuid] = m
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestImportFilter(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	database, err := db.New(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := database.Close(); err != nil {
+			t.Errorf("failed to close database: %v", err)
+		}
+	}()
+
+	conversation := func(uuid, name, updatedAt string, texts ...string) models.ClaudeConversation {
+		conv := models.ClaudeConversation{UUID: uuid, Name: name, CreatedAt: "2023-06-01T10:00:00Z", UpdatedAt: updatedAt}
+		for n, text := range texts {
+			msg := models.ClaudeChatMessage{UUID: fmt.Sprintf("%s-%d", uuid, n), Sender: "human", Text: text, CreatedAt: "2023-06-01T10:00:00Z"}
+			if n == 1 {
+				// The text of newer exports is in the content blocks
+				msg.Text = ""
+				msg.Content = []models.ClaudeMessageContent{{Type: "text", Text: text}}
+			}
+			conv.ChatMessages = append(conv.ChatMessages, msg)
+		}
+		return conv
+	}
+	path := writeExport(t, tmpDir, "export.json", []models.ClaudeConversation{
+		conversation("in-name", "Kubernetes ingress", "2024-03-01T10:00:00Z", "a", "b", "c"),
+		conversation("in-content", "Deploying", "2024-03-01T10:00:00Z", "a", "Set up a KUBERNETES cluster", "c"),
+		conversation("too-short", "Kubernetes pods", "2024-03-01T10:00:00Z", "a", "b"),
+		conversation("too-old", "Kubernetes in 2023", "2023-12-31T23:59:59Z", "a", "b", "c"),
+		conversation("too-new", "Kubernetes in 2025", "2025-01-01T00:00:00Z", "a", "b", "c"),
+		conversation("unrelated", "Sourdough", "2024-03-01T10:00:00Z", "a", "b", "c"),
+	})
+
+	importer := NewImporter(database, 0, false)
+	importer.SetFilter(&Filter{
+		Matching:    "kubernetes",
+		After:       time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Before:      time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		MinMessages: 3,
+	})
+	stats, err := importer.Import(path)
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if stats.ConversationsImported != 2 || stats.ConversationsFiltered != 4 || stats.MessagesImported != 6 {
+		t.Errorf("got %d conversations and %d messages imported and %d filtered, want 2, 6 and 4",
+			stats.ConversationsImported, stats.MessagesImported, stats.ConversationsFiltered)
+	}
+
+	rows, err := database.Query("SELECT uuid FROM conversations ORDER BY uuid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = rows.Close() }()
+	var got []string
+	for rows.Next() {
+		var uuid string
+		if err := rows.Scan(&uuid); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, uuid)
+	}
+	if want := []string{"in-content", "in-name"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v imported, got %v", want, got)
+	}
+}
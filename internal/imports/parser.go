@@ -1,9 +1,11 @@
 package imports
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
-	"os"
+	"io"
+	"regexp"
 	"time"
 
 	"github.com/neilberkman/shannon/internal/models"
@@ -17,28 +19,118 @@ const (
 
 // Parser handles parsing Claude export files
 type Parser struct {
-	file *os.File
+	path   string
+	reader *exportReader
+	strict bool
+	errors []*ConversationError
+	schema *schemaSniffer
+
+	recovering bool
+	corruption *CorruptionError
+}
+
+// ConversationError describes a conversation in an export that couldn't be
+// parsed or is missing required fields
+type ConversationError struct {
+	Index int    // position of the conversation in the export, from 0
+	UUID  string // empty if it couldn't be read
+	Err   error
+}
+
+func (e *ConversationError) Error() string {
+	if e.UUID != "" {
+		return fmt.Sprintf("conversation %d (%s): %v", e.Index, e.UUID, e.Err)
+	}
+	return fmt.Sprintf("conversation %d: %v", e.Index, e.Err)
 }
 
-// NewParser creates a new parser for the given file
+func (e *ConversationError) Unwrap() error {
+	return e.Err
+}
+
+// CorruptionError describes where the JSON of an export breaks off, as in a
+// truncated download
+type CorruptionError struct {
+	Index  int   // conversations read before it, whether they could be imported or not
+	Offset int64 // byte offset in the decompressed export where the broken part starts
+	Err    error
+
+	// When recovering, what was skipped from Offset on
+	UUID          string // of the conversation cut off, if it can be found
+	Skipped       int64  // bytes
+	Conversations int    // conversations started in those bytes, as far as can be told
+}
+
+func (e *CorruptionError) Error() string {
+	return fmt.Sprintf("%v (at byte %d)", e.Err, e.Offset)
+}
+
+func (e *CorruptionError) Unwrap() error {
+	return e.Err
+}
+
+// NewParser creates a new parser for the given file. Compressed files are
+// decompressed transparently (see OpenExport).
 func NewParser(filePath string) (*Parser, error) {
-	file, err := os.Open(filePath)
+	reader, err := openExport(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open file: %w", err)
+		return nil, err
 	}
 
-	return &Parser{file: file}, nil
+	return &Parser{path: filePath, reader: reader, schema: newSchemaSniffer()}, nil
+}
+
+// SetStrict makes parsing stop at the first malformed conversation. By
+// default malformed conversations are skipped and reported by Errors.
+func (p *Parser) SetStrict(strict bool) {
+	p.strict = strict
+}
+
+// SetRecover makes parsing stop without an error where the JSON of the export
+// breaks off, keeping the conversations before it, and note the corruption
+// for Corruption. By default broken JSON fails the parse.
+func (p *Parser) SetRecover(recovering bool) {
+	p.recovering = recovering
+}
+
+// Corruption returns where the export broke off when recovering, or nil if
+// it was read to the end
+func (p *Parser) Corruption() *CorruptionError {
+	return p.corruption
+}
+
+// Errors returns the malformed conversations skipped so far
+func (p *Parser) Errors() []*ConversationError {
+	return p.errors
+}
+
+// Variants returns the older or newer variants of the export format that
+// were adapted while parsing
+func (p *Parser) Variants() []string {
+	return p.schema.Variants()
+}
+
+// UnknownFields returns an *UnknownFieldsError listing the fields parsed so
+// far that shannon doesn't know, or nil if there were none
+func (p *Parser) UnknownFields() error {
+	return p.schema.Err()
 }
 
 // Close closes the underlying file
 func (p *Parser) Close() error {
-	return p.file.Close()
+	return p.reader.Close()
+}
+
+// Compressed reports whether the export file is compressed. The size of the
+// decompressed data isn't known without reading it all.
+func (p *Parser) Compressed() bool {
+	return p.reader.compression != CompressionNone
 }
 
 // Parse parses the export file and returns the data
 func (p *Parser) Parse() (*models.ClaudeExport, error) {
 	// Get file size for progress tracking
-	stat, err := p.file.Stat()
+	stat, err := p.reader.file.Stat()
 	if err != nil {
 		return nil, fmt.Errorf("failed to stat file: %w", err)
 	}
@@ -49,15 +141,13 @@ func (p *Parser) Parse() (*models.ClaudeExport, error) {
 		return nil, fmt.Errorf("file too large (%d bytes), streaming parser not yet implemented", stat.Size())
 	}
 
-	// Read and decode JSON array
-	decoder := json.NewDecoder(p.file)
-	var conversations []models.ClaudeConversation
-	if err := decoder.Decode(&conversations); err != nil {
-		return nil, fmt.Errorf("failed to decode JSON: %w", err)
-	}
-
-	export := &models.ClaudeExport{
-		Conversations: conversations,
+	export := &models.ClaudeExport{}
+	err = p.decodeConversations(func(conv *models.ClaudeConversation) error {
+		export.Conversations = append(export.Conversations, *conv)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return export, nil
@@ -66,12 +156,33 @@ func (p *Parser) Parse() (*models.ClaudeExport, error) {
 // StreamParse parses the export file in a streaming fashion for large files
 // This is more memory efficient for large exports
 func (p *Parser) StreamParse(callback func(*models.ClaudeConversation) error) error {
-	// Seek to beginning
-	if _, err := p.file.Seek(0, 0); err != nil {
-		return fmt.Errorf("failed to seek: %w", err)
+	// Start from the beginning; compressed data can't seek, so reopen
+	if err := p.reader.Close(); err != nil {
+		return fmt.Errorf("failed to rewind: %w", err)
+	}
+	reader, err := openExport(p.path)
+	if err != nil {
+		return err
 	}
+	p.reader = reader
+	p.errors = nil
+	p.corruption = nil
+	p.schema = newSchemaSniffer()
 
-	decoder := json.NewDecoder(p.file)
+	return p.decodeConversations(func(conv *models.ClaudeConversation) error {
+		if err := callback(conv); err != nil {
+			return fmt.Errorf("callback error: %w", err)
+		}
+		return nil
+	})
+}
+
+// decodeConversations reads the export's array of conversations one at a
+// time. A conversation that doesn't decode or lacks required fields is
+// skipped and recorded, unless the parser is strict. Broken JSON syntax
+// can't be skipped and stops parsing, with an error unless recovering.
+func (p *Parser) decodeConversations(fn func(*models.ClaudeConversation) error) error {
+	decoder := json.NewDecoder(p.reader)
 
 	// Read opening bracket for array
 	token, err := decoder.Token()
@@ -79,31 +190,165 @@ func (p *Parser) StreamParse(callback func(*models.ClaudeConversation) error) er
 		return fmt.Errorf("failed to read opening token: %w", err)
 	}
 
-	// Verify it's an array
-	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+	// Some exports wrap the array in an object
+	wrapped := token == json.Delim('{')
+	if wrapped {
+		found, err := p.skipToConversations(decoder)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("expected an array of conversations, or an object with a conversations field")
+		}
+		p.schema.variants[variantWrapped] = true
+	} else if delim, ok := token.(json.Delim); !ok || delim != '[' {
 		return fmt.Errorf("expected array, got %v", token)
 	}
 
 	// Read conversations one by one
-	for decoder.More() {
+	index := 0
+	for ; decoder.More(); index++ {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return p.corrupted(decoder, index, fmt.Errorf("failed to decode conversation %d: %w", index, err))
+		}
+
 		var conv models.ClaudeConversation
-		if err := decoder.Decode(&conv); err != nil {
-			return fmt.Errorf("failed to decode conversation: %w", err)
+		err := json.Unmarshal(p.schema.adapt(raw), &conv)
+		if err == nil {
+			err = ValidateConversation(&conv)
+		}
+		if err != nil {
+			convErr := &ConversationError{Index: index, UUID: conversationUUID(raw), Err: err}
+			if p.strict {
+				return convErr
+			}
+			p.errors = append(p.errors, convErr)
+			continue
 		}
 
-		if err := callback(&conv); err != nil {
-			return fmt.Errorf("callback error: %w", err)
+		if err := fn(&conv); err != nil {
+			return err
 		}
 	}
 
 	// Read closing bracket
 	if _, err := decoder.Token(); err != nil {
-		return fmt.Errorf("failed to read closing token: %w", err)
+		return p.corrupted(decoder, index, fmt.Errorf("failed to read closing token: %w", err))
+	}
+	if wrapped {
+		if _, err := p.skipToConversations(decoder); err != nil {
+			return p.corrupted(decoder, index, err)
+		}
+	}
+
+	return nil
+}
+
+// corrupted handles the JSON of the export breaking off after index
+// conversations. Unless recovering, that's an error. When recovering,
+// parsing ends there as if the export did, and the rest is read to report
+// what was skipped.
+func (p *Parser) corrupted(decoder *json.Decoder, index int, err error) error {
+	corruption := &CorruptionError{Index: index, Offset: decoder.InputOffset(), Err: err}
+	if !p.recovering {
+		return corruption
 	}
 
+	// The decoder has buffered the broken part; the rest is still unread.
+	// A read error just ends what's counted.
+	rest := &skippedCounter{}
+	_, _ = io.Copy(rest, io.MultiReader(decoder.Buffered(), p.reader))
+	corruption.Skipped = rest.bytes
+	corruption.Conversations = rest.conversations
+	if start := bytes.TrimLeft(rest.head, " \t\r\n,"); len(start) > 0 && start[0] == '{' {
+		// A conversation cut off before its messages still counts
+		corruption.Conversations = max(corruption.Conversations, 1)
+		if m := uuidField.FindSubmatch(start); m != nil {
+			corruption.UUID = string(m[1])
+		}
+	}
+	p.corruption = corruption
 	return nil
 }
 
+// uuidField finds the first UUID in the skipped part of an export, which is
+// that of the conversation cut off since conversations start with it
+var uuidField = regexp.MustCompile(`^\{\s*"uuid"\s*:\s*"([^"]+)"`)
+
+// chatMessagesField appears once in every conversation
+var chatMessagesField = []byte(`"chat_messages"`)
+
+// skippedHead is how much of the skipped part is kept to look for a UUID
+const skippedHead = 4096
+
+// skippedCounter counts the bytes of the skipped part of an export and the
+// conversations started in it, keeping its start
+type skippedCounter struct {
+	bytes         int64
+	conversations int
+	head          []byte
+	tail          []byte // the end of the last write, for fields cut between writes
+}
+
+func (c *skippedCounter) Write(b []byte) (int, error) {
+	if len(c.head) < skippedHead {
+		c.head = append(c.head, b[:min(len(b), skippedHead-len(c.head))]...)
+	}
+	c.bytes += int64(len(b))
+
+	joined := append(c.tail, b...)
+	c.conversations += bytes.Count(joined, chatMessagesField)
+	// Keep what could start a field the next write finishes, but not a
+	// whole one already counted
+	keep := min(len(joined), len(chatMessagesField)-1)
+	c.tail = append([]byte(nil), joined[len(joined)-keep:]...)
+	return len(b), nil
+}
+
+// skipToConversations reads the fields of an export wrapped in an object up
+// to the start of its conversations array, noting the other fields as
+// unknown. Past the array, it reads the rest of the object. It returns false
+// if the object ended without a conversations array.
+func (p *Parser) skipToConversations(decoder *json.Decoder) (bool, error) {
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return false, fmt.Errorf("failed to read export: %w", err)
+		}
+		key, _ := token.(string)
+		if key == "conversations" {
+			if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+				return false, fmt.Errorf("expected an array of conversations, got %v", token)
+			}
+			return true, nil
+		}
+		var skipped json.RawMessage
+		if err := decoder.Decode(&skipped); err != nil {
+			return false, fmt.Errorf("failed to read export field %s: %w", key, err)
+		}
+		p.schema.noteUnknown(levelExport+"."+key, "")
+	}
+
+	// The end of the object
+	if _, err := decoder.Token(); err != nil {
+		return false, fmt.Errorf("failed to read closing token: %w", err)
+	}
+	return false, nil
+}
+
+// conversationUUID returns the UUID of a conversation that failed to decode,
+// if it can be found
+func conversationUUID(raw json.RawMessage) string {
+	var conv struct {
+		UUID string `json:"uuid"`
+	}
+	if err := json.Unmarshal(raw, &conv); err != nil {
+		return ""
+	}
+	return conv.UUID
+}
+
 // ParseTime parses Claude's timestamp format
 func ParseTime(timestamp string) (time.Time, error) {
 	// Claude uses ISO 8601 format: "2023-12-06T19:45:30.123456+00:00"
@@ -117,22 +362,32 @@ func ValidateExport(export *models.ClaudeExport) error {
 	}
 
 	// Check for required fields
-	for i, conv := range export.Conversations {
-		if conv.UUID == "" {
-			return fmt.Errorf("conversation %d missing UUID", i)
-		}
-		if conv.CreatedAt == "" {
-			return fmt.Errorf("conversation %d missing created_at", i)
+	for i := range export.Conversations {
+		if err := ValidateConversation(&export.Conversations[i]); err != nil {
+			return &ConversationError{Index: i, UUID: export.Conversations[i].UUID, Err: err}
 		}
+	}
 
-		// Validate messages
-		for j, msg := range conv.ChatMessages {
-			if msg.UUID == "" {
-				return fmt.Errorf("message %d in conversation %d missing UUID", j, i)
-			}
-			if msg.Sender != senderHuman && msg.Sender != senderAssistant {
-				return fmt.Errorf("message %d in conversation %d has invalid sender: %s", j, i, msg.Sender)
-			}
+	return nil
+}
+
+// ValidateConversation checks that a conversation has the fields needed to
+// import it
+func ValidateConversation(conv *models.ClaudeConversation) error {
+	if conv.UUID == "" {
+		return fmt.Errorf("missing UUID")
+	}
+	if conv.CreatedAt == "" {
+		return fmt.Errorf("missing created_at")
+	}
+
+	// Validate messages
+	for j, msg := range conv.ChatMessages {
+		if msg.UUID == "" {
+			return fmt.Errorf("message %d missing UUID", j)
+		}
+		if msg.Sender != senderHuman && msg.Sender != senderAssistant {
+			return fmt.Errorf("message %d has invalid sender: %s", j, msg.Sender)
 		}
 	}
 
@@ -0,0 +1,169 @@
+package imports
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+// malformedExport has one good conversation between one whose name isn't a
+// string and one without a UUID
+const malformedExport = `[
+	{"uuid": "conv-1", "name": 42, "created_at": "2024-01-01T10:00:00Z", "updated_at": "2024-01-01T10:00:00Z", "chat_messages": []},
+	{"uuid": "conv-2", "name": "Good", "created_at": "2024-01-02T10:00:00Z", "updated_at": "2024-01-02T10:00:00Z",
+	 "chat_messages": [{"uuid": "msg-1", "sender": "human", "text": "hello", "created_at": "2024-01-02T10:00:00Z"}]},
+	{"name": "No UUID", "created_at": "2024-01-03T10:00:00Z", "updated_at": "2024-01-03T10:00:00Z", "chat_messages": []}
+]`
+
+func TestParserSkipsMalformedConversations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "conversations.json")
+	if err := os.WriteFile(path, []byte(malformedExport), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	parser, err := NewParser(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := parser.Close(); err != nil {
+			t.Errorf("failed to close parser: %v", err)
+		}
+	}()
+
+	export, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("expected lenient parse to succeed, got %v", err)
+	}
+	if len(export.Conversations) != 1 || export.Conversations[0].UUID != "conv-2" {
+		t.Errorf("expected only the good conversation, got %+v", export.Conversations)
+	}
+
+	errs := parser.Errors()
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 conversation errors, got %v", errs)
+	}
+	if errs[0].Index != 0 || errs[0].UUID != "conv-1" {
+		t.Errorf("expected the UUID of the undecodable conversation, got %+v", errs[0])
+	}
+	if errs[1].Index != 2 || errs[1].UUID != "" {
+		t.Errorf("unexpected error for the conversation without UUID: %+v", errs[1])
+	}
+
+	// Streaming skips the same conversations
+	var streamed []string
+	if err := parser.StreamParse(func(conv *models.ClaudeConversation) error {
+		streamed = append(streamed, conv.UUID)
+		return nil
+	}); err != nil {
+		t.Fatalf("expected lenient stream parse to succeed, got %v", err)
+	}
+	if len(streamed) != 1 || len(parser.Errors()) != 2 {
+		t.Errorf("expected 1 conversation and 2 errors when streaming, got %v and %v", streamed, parser.Errors())
+	}
+}
+
+func TestImportMalformedConversations(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "conversations.json")
+	if err := os.WriteFile(path, []byte(malformedExport), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	database, err := db.New(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := database.Close(); err != nil {
+			t.Errorf("failed to close database: %v", err)
+		}
+	}()
+
+	strict := NewImporter(database, 100, false)
+	strict.SetStrict(true)
+	_, err = strict.Import(path)
+	var convErr *ConversationError
+	if !errors.As(err, &convErr) || convErr.UUID != "conv-1" {
+		t.Fatalf("expected strict import to fail on the first conversation, got %v", err)
+	}
+
+	stats, err := NewImporter(database, 100, false).Import(path)
+	if err != nil {
+		t.Fatalf("lenient import failed: %v", err)
+	}
+	if stats.ConversationsImported != 1 || len(stats.Errors) != 2 {
+		t.Errorf("expected 1 conversation imported and 2 errors, got %+v", stats)
+	}
+
+	_, importErrors, err := GetImport(database, stats.ImportID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(importErrors) != 2 || importErrors[0].ConversationUUID != "conv-1" {
+		t.Errorf("expected errors to be recorded in the import history, got %+v", importErrors)
+	}
+}
+
+// truncatedExport is cut off in the middle of its third conversation
+const truncatedExport = `[
+	{"uuid": "conv-1", "name": "First", "created_at": "2024-01-01T10:00:00Z", "updated_at": "2024-01-01T10:00:00Z",
+	 "chat_messages": [{"uuid": "msg-1", "sender": "human", "text": "hello", "created_at": "2024-01-01T10:00:00Z"}]},
+	{"uuid": "conv-2", "name": "Second", "created_at": "2024-01-02T10:00:00Z", "updated_at": "2024-01-02T10:00:00Z",
+	 "chat_messages": [{"uuid": "msg-2", "sender": "human", "text": "hi", "created_at": "2024-01-02T10:00:00Z"}]},
+	{"uuid": "conv-3", "name": "Third", "created_at": "2024-01-03T10:00:00Z", "updated_at": "2024-01-03T10:00:00Z",
+	 "chat_messages": [{"uuid": "msg-3", "sender": "human", "text": "cut o`
+
+func TestImportRecoversTruncatedExport(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "conversations.json")
+	if err := os.WriteFile(path, []byte(truncatedExport), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	database, err := db.New(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := database.Close(); err != nil {
+			t.Errorf("failed to close database: %v", err)
+		}
+	}()
+
+	// Without recovering, the break fails the import
+	_, err = NewImporter(database, 100, false).Import(path)
+	var corruption *CorruptionError
+	if !errors.As(err, &corruption) || corruption.Index != 2 {
+		t.Fatalf("expected the import to fail at the third conversation, got %v", err)
+	}
+
+	importer := NewImporter(database, 100, false)
+	importer.SetRecover(true)
+	stats, err := importer.Import(path)
+	if err != nil {
+		t.Fatalf("recovering import failed: %v", err)
+	}
+	if stats.ConversationsImported != 2 || len(stats.Errors) != 1 {
+		t.Fatalf("expected the 2 complete conversations and the break as an error, got %+v", stats)
+	}
+	if !errors.As(stats.Errors[0], &corruption) {
+		t.Fatalf("expected the break to be reported, got %v", stats.Errors[0])
+	}
+	if corruption.UUID != "conv-3" || corruption.Conversations != 1 ||
+		corruption.Skipped != int64(len(truncatedExport))-corruption.Offset {
+		t.Errorf("unexpected report of what was skipped: %+v", corruption)
+	}
+
+	record, importErrors, err := GetImport(database, stats.ImportID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Status != "partial" || len(importErrors) != 1 || importErrors[0].ConversationUUID != "conv-3" {
+		t.Errorf("expected a partial import with the break recorded, got %+v and %+v", record, importErrors)
+	}
+}
@@ -0,0 +1,250 @@
+package imports
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Claude's export format changes now and then. Each conversation is checked
+// against the fields shannon knows before it is decoded: known variants of
+// the format are adapted to the current one, and any other field is recorded
+// so the import can fail instead of silently losing what the field held.
+
+// knownFields are the fields shannon reads, or knowingly ignores, at each
+// level of an export
+var knownFields = map[string]map[string]bool{
+	levelConversation: set("uuid", "name", "summary", "created_at", "updated_at", "account", "chat_messages",
+		"is_starred", "project_uuid", "current_leaf_message_uuid", "model", "settings"),
+	levelMessage: set("uuid", "text", "content", "sender", "created_at", "updated_at", "attachments", "files",
+		"files_v2", "parent_message_uuid", "index", "truncated", "stop_reason", "model", "starred", "feedback"),
+	levelTextBlock: set("type", "text", "start_timestamp", "stop_timestamp", "citations", "flags"),
+}
+
+// knownBlockTypes are the types of message content blocks. Only text is
+// imported; the rest are tool calls, reasoning and attachments shown apart
+// from the message in claude.ai.
+var knownBlockTypes = set("text", "thinking", "redacted_thinking", "tool_use", "tool_result", "image",
+	"document", "voice_note", "token_budget")
+
+const (
+	levelExport       = "export"
+	levelConversation = "conversation"
+	levelMessage      = "message"
+	levelTextBlock    = "content"
+)
+
+// fieldRename is a field an older or newer export names differently
+type fieldRename struct {
+	level, from, to string
+	variant         string // described in the import summary
+}
+
+var fieldRenames = []fieldRename{
+	{levelConversation, "title", "name", "conversation title instead of name"},
+	{levelConversation, "messages", "chat_messages", "messages instead of chat_messages"},
+	{levelMessage, "role", "sender", "message role instead of sender"},
+	{levelMessage, "parent_uuid", "parent_message_uuid", "parent_uuid instead of parent_message_uuid"},
+	{levelMessage, "is_starred", "starred", "message is_starred instead of starred"},
+	{levelMessage, "favorited", "starred", "message favorited instead of starred"},
+}
+
+// Variants of the export format that aren't field renames
+const (
+	variantWrapped       = "conversations wrapped in an object"
+	variantUserSender    = "user instead of human as the sender"
+	variantStringContent = "message content as a string"
+)
+
+// UnknownField is a field of an export that shannon doesn't know
+type UnknownField struct {
+	Path      string // where the field is, like message.reactions or content type audio
+	Count     int    // how many times it appears
+	FirstUUID string // the conversation it first appears in, if known
+}
+
+// UnknownFieldsError is returned by imports of exports with fields shannon
+// doesn't know, whose data would otherwise be lost
+type UnknownFieldsError struct {
+	Fields []UnknownField
+}
+
+func (e *UnknownFieldsError) Error() string {
+	var b strings.Builder
+	b.WriteString("the export has fields this version of shannon doesn't know, so their data wouldn't be imported:")
+	for _, f := range e.Fields {
+		fmt.Fprintf(&b, "\n  %s (%d)", f.Path, f.Count)
+		if f.FirstUUID != "" {
+			fmt.Fprintf(&b, ", first in conversation %s", f.FirstUUID)
+		}
+	}
+	b.WriteString("\nUpgrade shannon if a newer version reads them, or import anyway with --allow-unknown-fields")
+	return b.String()
+}
+
+// schemaSniffer adapts conversations to the current export format and
+// records what it had to adapt and what it didn't recognize
+type schemaSniffer struct {
+	variants map[string]bool
+	unknown  map[string]*UnknownField
+	order    []string // unknown field paths in the order they were seen
+}
+
+func newSchemaSniffer() *schemaSniffer {
+	return &schemaSniffer{variants: make(map[string]bool), unknown: make(map[string]*UnknownField)}
+}
+
+// Variants returns the known variants of the format that were adapted
+func (s *schemaSniffer) Variants() []string {
+	variants := make([]string, 0, len(s.variants))
+	for v := range s.variants {
+		variants = append(variants, v)
+	}
+	sort.Strings(variants)
+	return variants
+}
+
+// Err returns an UnknownFieldsError if any unknown fields were seen
+func (s *schemaSniffer) Err() error {
+	if len(s.order) == 0 {
+		return nil
+	}
+	fields := make([]UnknownField, 0, len(s.order))
+	for _, path := range s.order {
+		fields = append(fields, *s.unknown[path])
+	}
+	return &UnknownFieldsError{Fields: fields}
+}
+
+func (s *schemaSniffer) noteUnknown(path, convUUID string) {
+	if f, ok := s.unknown[path]; ok {
+		f.Count++
+		return
+	}
+	s.unknown[path] = &UnknownField{Path: path, Count: 1, FirstUUID: convUUID}
+	s.order = append(s.order, path)
+}
+
+// adapt rewrites a conversation in a known variant of the format into the
+// current one, noting fields it doesn't know. A conversation that isn't a
+// JSON object is returned as it was, for decoding to reject.
+func (s *schemaSniffer) adapt(raw json.RawMessage) json.RawMessage {
+	var conv map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &conv); err != nil {
+		return raw
+	}
+	var convUUID string
+	_ = json.Unmarshal(conv["uuid"], &convUUID)
+
+	changed := s.rename(levelConversation, conv)
+	s.checkFields(levelConversation, conv, convUUID)
+
+	var messages []map[string]json.RawMessage
+	if err := json.Unmarshal(conv["chat_messages"], &messages); err == nil {
+		messagesChanged := false
+		for _, msg := range messages {
+			if s.adaptMessage(msg, convUUID) {
+				messagesChanged = true
+			}
+		}
+		if messagesChanged {
+			conv["chat_messages"], _ = json.Marshal(messages)
+			changed = true
+		}
+	}
+
+	if !changed {
+		return raw
+	}
+	adapted, err := json.Marshal(conv)
+	if err != nil {
+		return raw
+	}
+	return adapted
+}
+
+// adaptMessage adapts a message in place, returning true if it changed
+func (s *schemaSniffer) adaptMessage(msg map[string]json.RawMessage, convUUID string) bool {
+	changed := s.rename(levelMessage, msg)
+	s.checkFields(levelMessage, msg, convUUID)
+
+	if string(msg["sender"]) == `"user"` {
+		msg["sender"] = json.RawMessage(`"human"`)
+		s.variants[variantUserSender] = true
+		changed = true
+	}
+
+	content, ok := msg["content"]
+	if !ok {
+		return changed
+	}
+	var text string
+	if err := json.Unmarshal(content, &text); err == nil {
+		block, _ := json.Marshal([]map[string]string{{"type": "text", "text": text}})
+		msg["content"] = block
+		s.variants[variantStringContent] = true
+		return true
+	}
+
+	var blocks []map[string]json.RawMessage
+	if err := json.Unmarshal(content, &blocks); err != nil {
+		return changed
+	}
+	for _, block := range blocks {
+		var blockType string
+		_ = json.Unmarshal(block["type"], &blockType)
+		switch {
+		case blockType == "text":
+			s.checkFields(levelTextBlock, block, convUUID)
+		case !knownBlockTypes[blockType]:
+			s.noteUnknown(fmt.Sprintf("content type %q", blockType), convUUID)
+		}
+	}
+	return changed
+}
+
+// rename applies the field renames for a level, returning true if any applied
+func (s *schemaSniffer) rename(level string, fields map[string]json.RawMessage) bool {
+	changed := false
+	for _, r := range fieldRenames {
+		if r.level != level {
+			continue
+		}
+		value, ok := fields[r.from]
+		if !ok {
+			continue
+		}
+		if _, exists := fields[r.to]; exists {
+			continue // both present; the old name is left to be reported
+		}
+		fields[r.to] = value
+		delete(fields, r.from)
+		s.variants[r.variant] = true
+		changed = true
+	}
+	return changed
+}
+
+// checkFields notes the fields at a level that shannon doesn't know
+func (s *schemaSniffer) checkFields(level string, fields map[string]json.RawMessage, convUUID string) {
+	known := knownFields[level]
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		if !known[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s.noteUnknown(level+"."+name, convUUID)
+	}
+}
+
+func set(values ...string) map[string]bool {
+	m := make(map[string]bool, len(values))
+	for _, v := range values {
+		m[v] = true
+	}
+	return m
+}
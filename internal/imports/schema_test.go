@@ -0,0 +1,140 @@
+package imports
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+// variantExport wraps its conversations in an object and uses older names
+// for the conversation title, messages, sender and parent link
+const variantExport = `{"version": 2, "conversations": [
+	{"uuid": "conv-1", "title": "Renamed", "created_at": "2024-01-01T10:00:00Z", "updated_at": "2024-01-01T10:00:00Z",
+	 "messages": [
+		{"uuid": "msg-1", "role": "user", "content": "hello", "created_at": "2024-01-01T10:00:00Z"},
+		{"uuid": "msg-2", "sender": "assistant", "parent_uuid": "msg-1", "created_at": "2024-01-01T10:01:00Z",
+		 "content": [{"type": "thinking", "thinking": "hmm"}, {"type": "text", "text": "hi there"}]}
+	 ]}
+]}`
+
+// unknownExport has a message field and a content type shannon doesn't know
+const unknownExport = `[
+	{"uuid": "conv-1", "name": "Unknown", "created_at": "2024-01-01T10:00:00Z", "updated_at": "2024-01-01T10:00:00Z",
+	 "chat_messages": [
+		{"uuid": "msg-1", "sender": "human", "text": "hello", "reactions": ["+1"], "created_at": "2024-01-01T10:00:00Z"},
+		{"uuid": "msg-2", "sender": "assistant", "reactions": [], "created_at": "2024-01-01T10:01:00Z",
+		 "content": [{"type": "audio", "data": "..."}]}
+	 ]}
+]`
+
+func writeRawExport(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "conversations.json")
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParserAdaptsVariants(t *testing.T) {
+	parser, err := NewParser(writeRawExport(t, variantExport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = parser.Close() }()
+
+	var convs []*models.ClaudeConversation
+	if err := parser.StreamParse(func(conv *models.ClaudeConversation) error {
+		convs = append(convs, conv)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(convs) != 1 || convs[0].Name != "Renamed" || len(convs[0].ChatMessages) != 2 {
+		t.Fatalf("expected the renamed conversation with 2 messages, got %+v", convs)
+	}
+
+	first, second := convs[0].ChatMessages[0], convs[0].ChatMessages[1]
+	if first.Sender != "human" || len(first.Content) != 1 || first.Content[0].Text != "hello" {
+		t.Errorf("expected the user message as human with a text block, got %+v", first)
+	}
+	if second.ParentID == nil || *second.ParentID != "msg-1" {
+		t.Errorf("expected the parent link to be kept, got %v", second.ParentID)
+	}
+
+	if err := parser.UnknownFields(); err == nil || !strings.Contains(err.Error(), "export.version") {
+		t.Errorf("expected the wrapper's other field to be reported, got %v", err)
+	}
+	expected := []string{
+		variantWrapped, "conversation title instead of name", variantStringContent,
+		"message role instead of sender", "messages instead of chat_messages",
+		"parent_uuid instead of parent_message_uuid", variantUserSender,
+	}
+	sort.Strings(expected)
+	if variants := parser.Variants(); strings.Join(variants, "|") != strings.Join(expected, "|") {
+		t.Errorf("expected variants %v, got %v", expected, variants)
+	}
+}
+
+func TestParserReportsUnknownFields(t *testing.T) {
+	parser, err := NewParser(writeRawExport(t, unknownExport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = parser.Close() }()
+
+	if _, err := parser.Parse(); err != nil {
+		t.Fatal(err)
+	}
+	var unknown *UnknownFieldsError
+	if !errors.As(parser.UnknownFields(), &unknown) {
+		t.Fatalf("expected an UnknownFieldsError, got %v", parser.UnknownFields())
+	}
+	if len(unknown.Fields) != 2 {
+		t.Fatalf("expected 2 unknown fields, got %+v", unknown.Fields)
+	}
+	reactions, audio := unknown.Fields[0], unknown.Fields[1]
+	if reactions.Path != "message.reactions" || reactions.Count != 2 || reactions.FirstUUID != "conv-1" {
+		t.Errorf("unexpected report of the message field: %+v", reactions)
+	}
+	if audio.Path != `content type "audio"` || audio.Count != 1 {
+		t.Errorf("unexpected report of the content type: %+v", audio)
+	}
+}
+
+func TestImportUnknownFields(t *testing.T) {
+	path := writeRawExport(t, unknownExport)
+	database, err := db.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = database.Close() }()
+
+	var unknown *UnknownFieldsError
+	if _, err := NewImporter(database, 100, false).Import(path); !errors.As(err, &unknown) {
+		t.Fatalf("expected the import to fail on unknown fields, got %v", err)
+	}
+	var count int
+	if err := database.QueryRow("SELECT COUNT(*) FROM conversations").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("expected the failed import to be rolled back, got %d conversations", count)
+	}
+
+	importer := NewImporter(database, 100, false)
+	importer.SetAllowUnknownFields(true)
+	stats, err := importer.Import(path)
+	if err != nil {
+		t.Fatalf("expected the import to be allowed, got %v", err)
+	}
+	if stats.ConversationsImported != 1 || stats.MessagesImported != 2 {
+		t.Errorf("expected 1 conversation and 2 messages, got %+v", stats)
+	}
+}
@@ -0,0 +1,265 @@
+package imports
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+// sessionNameLength is the longest name, in characters, taken from the first
+// message of a session without a summary
+const sessionNameLength = 80
+
+// IsSessionLog reports whether path names a Claude Code session log, a JSONL
+// file under ~/.claude/projects with a line for each event of a session
+func IsSessionLog(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".jsonl")
+}
+
+// SessionLog reads a Claude Code session log as it grows. Claude Code
+// appends a line for each message as the session goes, so each Read only
+// reads the lines added since the last one; a line still being written is
+// left for the next.
+type SessionLog struct {
+	path   string
+	offset int64     // bytes read so far, up to the end of the last whole line
+	hash   hash.Hash // of the bytes read so far
+
+	sessionID string
+	summary   string
+	model     string
+	messages  []models.ClaudeChatMessage
+	kept      map[string]bool   // lines kept as messages, by UUID
+	parents   map[string]string // the line each line follows, by UUID
+}
+
+// sessionLine is a line of a session log. Only user and assistant lines
+// carry messages; the others, such as tool results, attachments and
+// summaries, are there for Claude Code.
+type sessionLine struct {
+	Type        string  `json:"type"`
+	UUID        string  `json:"uuid"`
+	ParentUUID  *string `json:"parentUuid"`
+	SessionID   string  `json:"sessionId"`
+	Timestamp   string  `json:"timestamp"`
+	IsSidechain bool    `json:"isSidechain"` // a subagent's work, not the conversation
+	IsMeta      bool    `json:"isMeta"`      // added by Claude Code, not typed
+	Summary     string  `json:"summary"`
+	Message     struct {
+		Role    string          `json:"role"`
+		Model   string          `json:"model"`
+		Content json.RawMessage `json:"content"` // a string, or a list of blocks
+	} `json:"message"`
+}
+
+// NewSessionLog creates a reader for the session log at path, which reads it
+// from the start
+func NewSessionLog(path string) *SessionLog {
+	l := &SessionLog{path: path}
+	l.reset()
+	return l
+}
+
+// reset forgets what was read, to read the log again from the start
+func (l *SessionLog) reset() {
+	l.offset = 0
+	l.hash = sha256.New()
+	l.sessionID, l.summary, l.model = "", "", ""
+	l.messages = nil
+	l.kept = make(map[string]bool)
+	l.parents = make(map[string]string)
+}
+
+// Read reads the lines written since the last read, and returns how many
+// messages they added. A log that got shorter was rewritten, so it's read
+// again from the start.
+func (l *SessionLog) Read() (int, error) {
+	f, err := os.Open(l.path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open session log: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read session log: %w", err)
+	}
+	if info.Size() < l.offset {
+		l.reset()
+	}
+	if _, err := f.Seek(l.offset, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("failed to read session log: %w", err)
+	}
+
+	added := 0
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			// The rest is a line being written, or nothing
+			return added, nil
+		}
+		if err != nil {
+			return added, fmt.Errorf("failed to read session log: %w", err)
+		}
+		l.offset += int64(len(line))
+		l.hash.Write(line)
+		if l.add(bytes.TrimSpace(line)) {
+			added++
+		}
+	}
+}
+
+// add takes in a line of the log, reporting whether it was a message.
+// Lines that aren't JSON are skipped, as Claude Code would.
+func (l *SessionLog) add(data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+	var line sessionLine
+	if err := json.Unmarshal(data, &line); err != nil {
+		return false
+	}
+	if line.Type == "summary" {
+		l.summary = line.Summary
+		return false
+	}
+	if line.UUID == "" {
+		return false
+	}
+	if line.ParentUUID != nil {
+		l.parents[line.UUID] = *line.ParentUUID
+	}
+	if l.sessionID == "" {
+		l.sessionID = line.SessionID
+	}
+
+	var sender string
+	switch line.Type {
+	case "user":
+		sender = senderHuman
+	case "assistant":
+		sender = senderAssistant
+	default:
+		return false
+	}
+	text := sessionText(line.Message.Content)
+	if text == "" || line.IsSidechain || line.IsMeta {
+		return false
+	}
+
+	msg := models.ClaudeChatMessage{
+		UUID:      line.UUID,
+		Sender:    sender,
+		Text:      text,
+		CreatedAt: line.Timestamp,
+		ParentID:  l.parentMessage(line.UUID),
+	}
+	if sender == senderAssistant {
+		msg.Model = line.Message.Model
+		if msg.Model != "" {
+			l.model = msg.Model
+		}
+	}
+	l.kept[line.UUID] = true
+	l.messages = append(l.messages, msg)
+	return true
+}
+
+// parentMessage returns the message a line follows: its parent, or the
+// nearest line before it kept as a message, going past tool calls and the
+// other lines that aren't. The seen set guards against parents that loop.
+func (l *SessionLog) parentMessage(uuid string) *string {
+	seen := map[string]bool{uuid: true}
+	for parent, ok := l.parents[uuid]; ok && !seen[parent]; parent, ok = l.parents[parent] {
+		if l.kept[parent] {
+			return &parent
+		}
+		seen[parent] = true
+	}
+	return nil
+}
+
+// sessionText is the text of a message's content: the content itself when
+// it's a string, otherwise its text blocks. Tool calls, tool results and
+// thinking are left out.
+func sessionText(content json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(content, &text); err == nil {
+		return strings.TrimSpace(text)
+	}
+
+	var blocks []models.ClaudeMessageContent
+	if err := json.Unmarshal(content, &blocks); err != nil {
+		return ""
+	}
+	var parts []string
+	for _, block := range blocks {
+		if block.Type == "text" && strings.TrimSpace(block.Text) != "" {
+			parts = append(parts, strings.TrimSpace(block.Text))
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// Conversation returns the session read so far as a conversation, named by
+// Claude Code's summary of it or else its first message, or nil if no
+// messages have been read
+func (l *SessionLog) Conversation() *models.ClaudeConversation {
+	if len(l.messages) == 0 {
+		return nil
+	}
+
+	name := l.summary
+	if name == "" {
+		for _, msg := range l.messages {
+			if msg.Sender == senderHuman {
+				name, _, _ = strings.Cut(msg.Text, "\n")
+				break
+			}
+		}
+		if runes := []rune(name); len(runes) > sessionNameLength {
+			name = string(runes[:sessionNameLength-1]) + "…"
+		}
+	}
+
+	uuid := l.sessionID
+	if uuid == "" {
+		// Logs name themselves after the session anyway
+		uuid = strings.TrimSuffix(filepath.Base(l.path), filepath.Ext(l.path))
+	}
+	return &models.ClaudeConversation{
+		UUID:         uuid,
+		Name:         name,
+		CreatedAt:    l.messages[0].CreatedAt,
+		UpdatedAt:    l.messages[len(l.messages)-1].CreatedAt,
+		Model:        l.model,
+		ChatMessages: append([]models.ClaudeChatMessage(nil), l.messages...),
+	}
+}
+
+// Hash identifies what was read so far in the import history, so the log
+// can be imported again each time it grows
+func (l *SessionLog) Hash() string {
+	return hex.EncodeToString(l.hash.Sum(nil))
+}
+
+// Import imports the session read so far, adding the messages read since
+// it was last imported to its conversation
+func (l *SessionLog) Import(importer *Importer) (*models.ImportStats, error) {
+	conv := l.Conversation()
+	if conv == nil {
+		return nil, fmt.Errorf("no messages in %s", l.path)
+	}
+	return importer.ImportConversations(l.path, l.Hash(), []models.ClaudeConversation{*conv})
+}
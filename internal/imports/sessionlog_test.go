@@ -0,0 +1,144 @@
+package imports
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/neilberkman/shannon/internal/db"
+)
+
+// sessionLines is the start of a Claude Code session: a question, a tool
+// call and its result, the answer, and a subagent's work on the side
+var sessionLines = []string{
+	`{"type":"queue-operation","operation":"enqueue","timestamp":"2025-06-25T09:00:00.000Z","sessionId":"sess-1"}`,
+	`{"parentUuid":null,"isSidechain":false,"type":"user","message":{"role":"user","content":"Why is the build failing?\nIt worked yesterday."},"uuid":"u1","timestamp":"2025-06-25T09:00:01.000Z","sessionId":"sess-1"}`,
+	`{"parentUuid":"u1","isSidechain":false,"type":"attachment","uuid":"x1","timestamp":"2025-06-25T09:00:01.500Z","sessionId":"sess-1"}`,
+	`{"parentUuid":"x1","isSidechain":false,"type":"assistant","message":{"role":"assistant","model":"claude-sonnet-4","content":[{"type":"thinking","thinking":"Look at the log"},{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"go build ./..."}}]},"uuid":"a1","timestamp":"2025-06-25T09:00:02.000Z","sessionId":"sess-1"}`,
+	`{"parentUuid":"a1","isSidechain":false,"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"undefined: foo"}]},"uuid":"r1","timestamp":"2025-06-25T09:00:03.000Z","sessionId":"sess-1"}`,
+	`{"parentUuid":"s0","isSidechain":true,"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"Subagent notes"}]},"uuid":"s1","timestamp":"2025-06-25T09:00:03.500Z","sessionId":"sess-1"}`,
+	`not json`,
+	`{"parentUuid":"r1","isSidechain":false,"type":"assistant","message":{"role":"assistant","model":"claude-sonnet-4","content":[{"type":"text","text":"foo was renamed."},{"type":"text","text":"Use bar instead."}]},"uuid":"a2","timestamp":"2025-06-25T09:00:04.000Z","sessionId":"sess-1"}`,
+}
+
+func writeSessionLog(t *testing.T, path string, content string, appendTo bool) {
+	t.Helper()
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if appendTo {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	f, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSessionLog(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "sess-1.jsonl")
+
+	// The last line is still being written
+	next := `{"parentUuid":"a2","isSidechain":false,"type":"user","message":{"role":"user","content":[{"type":"text","text":"Thanks!"}]},"uuid":"u2","timestamp":"2025-06-25T09:01:00.000Z","sessionId":"sess-1"}`
+	writeSessionLog(t, path, strings.Join(sessionLines, "\n")+"\n"+next[:40], false)
+
+	log := NewSessionLog(path)
+	added, err := log.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != 2 {
+		t.Fatalf("expected the question and the answer, got %d messages", added)
+	}
+
+	conv := log.Conversation()
+	if conv.UUID != "sess-1" || conv.Name != "Why is the build failing?" || conv.Model != "claude-sonnet-4" {
+		t.Errorf("unexpected conversation: %+v", conv)
+	}
+	if conv.CreatedAt != "2025-06-25T09:00:01.000Z" || conv.UpdatedAt != "2025-06-25T09:00:04.000Z" {
+		t.Errorf("unexpected times %s to %s", conv.CreatedAt, conv.UpdatedAt)
+	}
+	answer := conv.ChatMessages[1]
+	if answer.Sender != "assistant" || answer.Text != "foo was renamed.\n\nUse bar instead." {
+		t.Errorf("unexpected answer: %+v", answer)
+	}
+	// The answer follows the question, past the tool call and its result
+	if answer.ParentID == nil || *answer.ParentID != "u1" {
+		t.Errorf("expected the answer to reply to u1, got %v", answer.ParentID)
+	}
+
+	database, err := db.New(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := database.Close(); err != nil {
+			t.Errorf("failed to close database: %v", err)
+		}
+	}()
+	importer := NewImporter(database, 100, false)
+	stats, err := log.Import(importer)
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if stats.ConversationsImported != 1 || stats.MessagesImported != 2 {
+		t.Errorf("unexpected import stats: %+v", stats)
+	}
+
+	// Nothing new: the same lines can't be imported twice
+	if added, err = log.Read(); err != nil || added != 0 {
+		t.Fatalf("expected nothing new, got %d (%v)", added, err)
+	}
+	if _, err := log.Import(importer); err == nil {
+		t.Error("expected importing the same lines again to fail")
+	}
+
+	// The line is finished and a summary written: only they are read
+	writeSessionLog(t, path, next[40:]+"\n"+`{"type":"summary","summary":"Fixing the build","leafUuid":"u2"}`+"\n", true)
+	if added, err = log.Read(); err != nil || added != 1 {
+		t.Fatalf("expected one new message, got %d (%v)", added, err)
+	}
+	if stats, err = log.Import(importer); err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if stats.ConversationsImported != 0 || stats.MessagesImported != 1 {
+		t.Errorf("expected one message added to the conversation, got %+v", stats)
+	}
+	var name string
+	var messages int
+	if err := database.QueryRow("SELECT name, message_count FROM conversations WHERE uuid = 'sess-1'").Scan(&name, &messages); err != nil {
+		t.Fatal(err)
+	}
+	if name != "Fixing the build" || messages != 3 {
+		t.Errorf("expected the summary as name and 3 messages, got %q and %d", name, messages)
+	}
+
+	// A log written again from the start is read again
+	writeSessionLog(t, path, sessionLines[1]+"\n", false)
+	if added, err = log.Read(); err != nil || added != 1 {
+		t.Fatalf("expected the rewritten log to be read again, got %d (%v)", added, err)
+	}
+	if len(log.Conversation().ChatMessages) != 1 {
+		t.Errorf("expected only the rewritten log's message, got %d", len(log.Conversation().ChatMessages))
+	}
+}
+
+func TestIsSessionLog(t *testing.T) {
+	for path, want := range map[string]bool{
+		"~/.claude/projects/-home-me/1f0c.jsonl": true,
+		"SESSION.JSONL":                          true,
+		"conversations.json":                     false,
+		"conversations.json.gz":                  false,
+	} {
+		if got := IsSessionLog(path); got != want {
+			t.Errorf("IsSessionLog(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package imports
+
+import (
+	"os"
+	"time"
+)
+
+// FileState is the size and modification time of a file at one look, to tell
+// when a file being written, such as an export being downloaded, has stopped
+// changing
+type FileState struct {
+	Size    int64
+	ModTime time.Time
+}
+
+// StatFile looks at the file at path
+func StatFile(path string) (FileState, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return FileState{}, err
+	}
+	return FileState{Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+// Same reports whether two looks found the file the same
+func (s FileState) Same(other FileState) bool {
+	return s.Size == other.Size && s.ModTime.Equal(other.ModTime)
+}
+
+// Settled reports whether the file can be read whole: it's the same as on
+// the look before, prev, and hasn't been written to for stableFor
+func (s FileState) Settled(prev FileState, stableFor time.Duration, now time.Time) bool {
+	return s.Same(prev) && now.Sub(s.ModTime) >= stableFor
+}
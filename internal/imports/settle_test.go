@@ -0,0 +1,29 @@
+package imports
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFileStateSettled(t *testing.T) {
+	now := time.Date(2025, 6, 25, 9, 0, 0, 0, time.UTC)
+	written := FileState{Size: 100, ModTime: now.Add(-time.Minute)}
+
+	tests := []struct {
+		name string
+		prev FileState
+		cur  FileState
+		want bool
+	}{
+		{"unchanged and quiet", written, written, true},
+		{"first look", FileState{}, written, false},
+		{"grew", FileState{Size: 50, ModTime: written.ModTime}, written, false},
+		{"touched", FileState{Size: 100, ModTime: now.Add(-2 * time.Minute)}, written, false},
+		{"written just now", FileState{Size: 100, ModTime: now}, FileState{Size: 100, ModTime: now}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.cur.Settled(tt.prev, 30*time.Second, now); got != tt.want {
+			t.Errorf("%s: Settled = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
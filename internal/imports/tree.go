@@ -0,0 +1,272 @@
+package imports
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"unicode"
+
+	"github.com/neilberkman/shannon/internal/crash"
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+// DefaultTreePattern matches the files of a directory tree import
+const DefaultTreePattern = "*.json"
+
+// FileError is a file of a directory tree, or a conversation in one, that
+// couldn't be imported
+type FileError struct {
+	Path string // relative to the tree
+	Err  error
+}
+
+func (e *FileError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// TreeFiles lists the files under root whose names match pattern, relative
+// to root and sorted. Compressed files match by their name without the
+// compression extension, so "*.json" finds chat.json.gz.
+func TreeFiles(root, pattern string) ([]string, error) {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		matched, _ := filepath.Match(pattern, d.Name())
+		if !matched {
+			matched, _ = filepath.Match(pattern, TrimCompressionExt(d.Name()))
+		}
+		if matched {
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", root, err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// TreeHash identifies the files of a tree and their contents in the import
+// history, so the same tree isn't imported twice
+func TreeHash(root string, files []string) (string, error) {
+	hasher := sha256.New()
+	for _, rel := range files {
+		hash, err := FileHash(filepath.Join(root, rel))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(hasher, "%s\x00%s\n", filepath.ToSlash(rel), hash)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// ImportTree imports the files under root matching pattern, as saved by
+// exporters that write one JSON file per conversation, in one transaction
+// recorded as a single import. Each file may hold a single conversation or a
+// whole export. Files that aren't either are reported in ImportStats.Errors
+// and skipped, unless the import is strict.
+func (i *Importer) ImportTree(root, pattern string) (*models.ImportStats, error) {
+	crash.Note("import tree %s (%s)", root, pattern)
+	files, err := TreeFiles(root, pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files matching %s under %s", pattern, root)
+	}
+
+	hash, err := TreeHash(root, files)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash files: %w", err)
+	}
+	if imported, err := i.IsImported(hash); err != nil {
+		return nil, err
+	} else if imported {
+		return nil, fmt.Errorf("directory already imported with the same files (hash: %s)", hash)
+	}
+
+	return i.run(root, hash, func(tx *importTx, stats *models.ImportStats) error {
+		// Single conversations share a schema sniffer; exports have their own
+		singles := newSchemaSniffer()
+		variants := make(map[string]bool)
+		var unknown error
+
+		for _, rel := range files {
+			stats.Files++
+			parser, err := i.importTreeFile(tx, filepath.Join(root, rel), rel, singles, stats)
+			if err != nil {
+				return err
+			}
+			if parser == nil {
+				continue
+			}
+			for _, variant := range parser.Variants() {
+				variants[variant] = true
+			}
+			if unknown == nil {
+				unknown = parser.UnknownFields()
+			}
+		}
+
+		for _, variant := range singles.Variants() {
+			variants[variant] = true
+		}
+		for variant := range variants {
+			stats.ExportVariants = append(stats.ExportVariants, variant)
+		}
+		sort.Strings(stats.ExportVariants)
+		if i.allowUnknown {
+			return nil
+		}
+		if unknown == nil {
+			unknown = singles.Err()
+		}
+		return unknown
+	})
+}
+
+// importTreeFile imports a file of a tree. It returns the parser of a file
+// holding a whole export, for its variants and unknown fields.
+func (i *Importer) importTreeFile(tx *importTx, path, rel string, singles *schemaSniffer, stats *models.ImportStats) (*Parser, error) {
+	skip := func(err error) (*Parser, error) {
+		if i.strict {
+			return nil, &FileError{Path: rel, Err: err}
+		}
+		stats.FilesSkipped++
+		return nil, i.recordError(tx, stats, "", &FileError{Path: rel, Err: err}, rel+": "+err.Error())
+	}
+
+	raw, export, err := readTreeFile(path)
+	if err != nil {
+		return skip(err)
+	}
+
+	if export {
+		parser, err := NewParser(path)
+		if err != nil {
+			return skip(err)
+		}
+		defer func() { _ = parser.Close() }()
+		parser.SetStrict(i.strict)
+		parser.SetRecover(i.recovering)
+		parseErr := parser.StreamParse(func(conv *models.ClaudeConversation) error {
+			return i.importOne(tx, conv, stats)
+		})
+		if err := i.recordTreeParseErrors(tx, rel, parser, stats); err != nil {
+			return nil, err
+		}
+		if parseErr != nil {
+			// The conversations read before the export broke off are kept
+			return skip(parseErr)
+		}
+		return parser, nil
+	}
+
+	var conv models.ClaudeConversation
+	err = json.Unmarshal(singles.adapt(raw), &conv)
+	if err == nil {
+		err = ValidateConversation(&conv)
+	}
+	if err != nil {
+		convErr := &ConversationError{Index: 0, UUID: conversationUUID(raw), Err: err}
+		if i.strict {
+			return nil, &FileError{Path: rel, Err: convErr}
+		}
+		stats.FilesSkipped++
+		return nil, i.recordError(tx, stats, convErr.UUID, &FileError{Path: rel, Err: convErr}, rel+": "+convErr.Error())
+	}
+	return nil, i.importOne(tx, &conv, stats)
+}
+
+// recordTreeParseErrors records the malformed conversations skipped in an
+// export of a tree, and where it broke off if it was recovered
+func (i *Importer) recordTreeParseErrors(tx *importTx, rel string, parser *Parser, stats *models.ImportStats) error {
+	for _, convErr := range parser.Errors() {
+		if err := i.recordError(tx, stats, convErr.UUID, &FileError{Path: rel, Err: convErr}, rel+": "+convErr.Error()); err != nil {
+			return err
+		}
+	}
+	if corruption := parser.Corruption(); corruption != nil {
+		message := fmt.Sprintf("%s: export broken off after %d conversations: %v; skipped %d bytes, about %d conversations",
+			rel, corruption.Index, corruption, corruption.Skipped, corruption.Conversations)
+		return i.recordError(tx, stats, corruption.UUID, &FileError{Path: rel, Err: corruption}, message)
+	}
+	return nil
+}
+
+// readTreeFile reads a file of a tree. A file holding a single conversation,
+// a JSON object with its messages, is returned whole; for a whole export, an
+// array of conversations or an object wrapping one, export is true and it is
+// left for a Parser to read.
+func readTreeFile(path string) (raw json.RawMessage, export bool, err error) {
+	reader, err := openExport(path)
+	if err != nil {
+		return nil, false, err
+	}
+	defer func() { _ = reader.Close() }()
+
+	buffered := bufio.NewReader(reader)
+	for {
+		r, _, err := buffered.ReadRune()
+		if err == io.EOF {
+			return nil, false, fmt.Errorf("file is empty")
+		} else if err != nil {
+			return nil, false, fmt.Errorf("failed to read file: %w", err)
+		}
+		if r == '\uFEFF' || unicode.IsSpace(r) {
+			continue
+		}
+		if r == '[' {
+			return nil, true, nil
+		}
+		if r != '{' {
+			return nil, false, fmt.Errorf("not JSON, or not a conversation or an export")
+		}
+		if err := buffered.UnreadRune(); err != nil {
+			return nil, false, fmt.Errorf("failed to read file: %w", err)
+		}
+		break
+	}
+
+	data, err := io.ReadAll(buffered)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read file: %w", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, false, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if _, ok := fields["conversations"]; ok {
+		return nil, true, nil
+	}
+	_, hasMessages := fields["chat_messages"]
+	_, hasRenamed := fields["messages"]
+	if !hasMessages && !hasRenamed {
+		return nil, false, fmt.Errorf("not a conversation or an export: an object without chat_messages or conversations")
+	}
+	return data, false, nil
+}
@@ -0,0 +1,120 @@
+package imports
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+func writeTreeFile(t *testing.T, root, rel, content string) {
+	t.Helper()
+	path := filepath.Join(root, rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestImportTree(t *testing.T) {
+	tmpDir := t.TempDir()
+	database, err := db.New(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := database.Close(); err != nil {
+			t.Errorf("failed to close database: %v", err)
+		}
+	}()
+
+	root := filepath.Join(tmpDir, "backup")
+	single, err := json.Marshal(models.ClaudeConversation{
+		UUID: "conv-1", Name: "Python Development",
+		CreatedAt: "2024-01-01T10:00:00Z", UpdatedAt: "2024-01-01T10:05:00Z",
+		ChatMessages: []models.ClaudeChatMessage{
+			{UUID: "msg-1", Sender: "human", Text: "How do I use Python for machine learning?", CreatedAt: "2024-01-01T10:00:00Z"},
+			{UUID: "msg-2", Sender: "assistant", Text: "Python is great for data science", CreatedAt: "2024-01-01T10:01:00Z"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTreeFile(t, root, "2024/01/python.json", string(single))
+	// A single conversation in an older variant of the format
+	writeTreeFile(t, root, "2024/02/renamed.json", `{"uuid": "conv-2", "title": "Renamed", "created_at": "2024-02-01T10:00:00Z", "updated_at": "2024-02-01T10:00:00Z",
+		"messages": [{"uuid": "msg-3", "sender": "human", "text": "hello", "created_at": "2024-02-01T10:00:00Z"}]}`)
+	// A whole export among them
+	writeExport(t, root, "export.json", []models.ClaudeConversation{
+		{
+			UUID: "conv-3", Name: "Test Project Alpha",
+			CreatedAt: "2024-03-01T10:00:00Z", UpdatedAt: "2024-03-01T10:00:00Z",
+			ChatMessages: []models.ClaudeChatMessage{
+				{UUID: "msg-4", Sender: "human", Text: "Tell me about the test project", CreatedAt: "2024-03-01T10:00:00Z"},
+			},
+		},
+	})
+	writeTreeFile(t, root, "settings.json", `{"theme": "dark"}`)
+	writeTreeFile(t, root, "notes.txt", `not matched`)
+
+	files, err := TreeFiles(root, DefaultTreePattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		filepath.Join("2024", "01", "python.json"),
+		filepath.Join("2024", "02", "renamed.json"),
+		"export.json",
+		"settings.json",
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("TreeFiles = %v, want %v", files, want)
+	}
+
+	importer := NewImporter(database, 100, false)
+	stats, err := importer.ImportTree(root, DefaultTreePattern)
+	if err != nil {
+		t.Fatalf("tree import failed: %v", err)
+	}
+	if stats.Files != 4 || stats.FilesSkipped != 1 {
+		t.Errorf("read %d files and skipped %d, want 4 and 1", stats.Files, stats.FilesSkipped)
+	}
+	if stats.ConversationsImported != 3 || stats.MessagesImported != 4 {
+		t.Errorf("imported %d conversations and %d messages, want 3 and 4", stats.ConversationsImported, stats.MessagesImported)
+	}
+	var fileErr *FileError
+	if len(stats.Errors) != 1 || !errors.As(stats.Errors[0], &fileErr) || fileErr.Path != "settings.json" {
+		t.Errorf("expected settings.json to be skipped, got errors %v", stats.Errors)
+	}
+	if len(stats.ExportVariants) == 0 || stats.ExportVariants[0] != "conversation title instead of name" {
+		t.Errorf("unexpected variants %v", stats.ExportVariants)
+	}
+
+	records, err := ListImports(database, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].FilePath != root || records[0].ConversationsCount != 3 {
+		t.Errorf("expected the tree to be recorded as one import, got %+v", records)
+	}
+
+	if _, err := importer.ImportTree(root, DefaultTreePattern); err == nil {
+		t.Error("importing the same tree again should fail")
+	}
+
+	// A strict import fails on the file that isn't a conversation
+	importer.SetStrict(true)
+	if _, err := importer.ImportTree(root, "settings.json"); !errors.As(err, &fileErr) {
+		t.Errorf("strict import returned %v, want a FileError", err)
+	}
+	if _, err := importer.ImportTree(root, "*.yaml"); err == nil {
+		t.Error("importing a tree with no matching files should fail")
+	}
+}
@@ -0,0 +1,381 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/db"
+)
+
+// Job statuses. A job that fails is retried until it has been attempted
+// MaxAttempts times, then left failed until retried by hand.
+const (
+	StatusPending = "pending"
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+)
+
+// MaxAttempts is how many times a job is run before it's left failed
+const MaxAttempts = 3
+
+// RunningTimeout is how long a job can run before it's taken to belong to a
+// worker that was killed, and queued again
+const RunningTimeout = 10 * time.Minute
+
+// Enricher computes one kind of enrichment of a conversation. Its result is
+// stored as JSON, replacing the one from the last run.
+type Enricher struct {
+	Kind        string
+	Description string
+	Run         func(ctx context.Context, database *db.DB, conversationID int64) (interface{}, error)
+}
+
+// Enrichers are the kinds of job the worker can run
+var Enrichers = []*Enricher{secretsEnricher}
+
+// Lookup returns the enricher of a kind
+func Lookup(kind string) (*Enricher, error) {
+	for _, e := range Enrichers {
+		if e.Kind == kind {
+			return e, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown kind of job %q (known: %s)", kind, kinds())
+}
+
+func kinds() string {
+	names := make([]string, len(Enrichers))
+	for i, e := range Enrichers {
+		names[i] = e.Kind
+	}
+	return strings.Join(names, ", ")
+}
+
+// Job is an enrichment of a conversation, queued or run
+type Job struct {
+	ID             int64      `json:"id"`
+	Kind           string     `json:"kind"`
+	ConversationID int64      `json:"conversation_id"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	Error          string     `json:"error,omitempty"`
+	QueuedAt       time.Time  `json:"queued_at"`
+	StartedAt      *time.Time `json:"started_at,omitempty"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty"`
+}
+
+// Progress counts the jobs of a kind by status
+type Progress struct {
+	Kind    string `json:"kind"`
+	Pending int    `json:"pending"`
+	Running int    `json:"running"`
+	Done    int    `json:"done"`
+	Failed  int    `json:"failed"`
+}
+
+// Total is the number of jobs of the kind
+func (p Progress) Total() int {
+	return p.Pending + p.Running + p.Done + p.Failed
+}
+
+// Enqueue queues a kind of job for conversations, returning how many were
+// queued. Jobs already queued or done are queued again, except those running.
+func Enqueue(database *db.DB, kind string, conversationIDs []int64) (int, error) {
+	if _, err := Lookup(kind); err != nil {
+		return 0, err
+	}
+	tx, err := database.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollback(tx)
+
+	queued := 0
+	for _, id := range conversationIDs {
+		result, err := tx.Exec(`
+			INSERT INTO jobs (kind, conversation_id) VALUES (?, ?)
+			ON CONFLICT (kind, conversation_id) DO UPDATE
+			SET status = 'pending', attempts = 0, error = '', queued_at = CURRENT_TIMESTAMP,
+			    started_at = NULL, finished_at = NULL
+			WHERE status != 'running'
+		`, kind, id)
+		if err != nil {
+			return 0, fmt.Errorf("failed to queue %s for conversation %d: %w", kind, id, err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("failed to queue %s for conversation %d: %w", kind, id, err)
+		}
+		queued += int(n)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit: %w", err)
+	}
+	return queued, nil
+}
+
+// Stale returns the conversations outside the trash that have no result of
+// a kind, or one from before messages were added or removed, and no job of
+// that kind waiting or running
+func Stale(database *db.DB, kind string) ([]int64, error) {
+	rows, err := database.Query(`
+		SELECT c.id FROM conversations c
+		LEFT JOIN enrichments e ON e.conversation_id = c.id AND e.kind = ?1
+		LEFT JOIN jobs j ON j.conversation_id = c.id AND j.kind = ?1
+		WHERE c.deleted_at IS NULL
+		  AND (e.conversation_id IS NULL OR e.message_count != c.message_count)
+		  AND (j.status IS NULL OR j.status NOT IN ('pending', 'running'))
+		ORDER BY c.id
+	`, kind)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find conversations to enrich: %w", err)
+	}
+	defer closeRows(rows)
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Retry queues the failed jobs of a kind again, or of every kind if kind is
+// empty, returning how many there were
+func Retry(database *db.DB, kind string) (int64, error) {
+	result, err := database.Exec(`
+		UPDATE jobs SET status = 'pending', attempts = 0, error = '', queued_at = CURRENT_TIMESTAMP,
+		                started_at = NULL, finished_at = NULL
+		WHERE status = 'failed' AND (?1 = '' OR kind = ?1)
+	`, kind)
+	if err != nil {
+		return 0, fmt.Errorf("failed to retry jobs: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// Claim marks the oldest pending job as running and returns it, or nil if
+// none is waiting. Jobs of conversations in the trash wait until they're
+// restored, and jobs that have run longer than RunningTimeout are queued
+// again first.
+func Claim(database *db.DB) (*Job, error) {
+	timedOut := time.Now().Add(-RunningTimeout).UTC().Format(timeLayout)
+	if _, err := database.Exec(`
+		UPDATE jobs SET status = 'pending' WHERE status = 'running' AND started_at <= ?
+	`, timedOut); err != nil {
+		return nil, fmt.Errorf("failed to requeue stuck jobs: %w", err)
+	}
+
+	var job Job
+	err := database.QueryRow(`
+		UPDATE jobs SET status = 'running', attempts = attempts + 1, started_at = CURRENT_TIMESTAMP
+		WHERE id = (
+			SELECT j.id FROM jobs j JOIN conversations c ON c.id = j.conversation_id
+			WHERE j.status = 'pending' AND c.deleted_at IS NULL
+			ORDER BY j.queued_at, j.id
+			LIMIT 1
+		) AND status = 'pending'
+		RETURNING id, kind, conversation_id, status, attempts, error, queued_at, started_at
+	`).Scan(&job.ID, &job.Kind, &job.ConversationID, &job.Status, &job.Attempts, &job.Error, &job.QueuedAt, &job.StartedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim a job: %w", err)
+	}
+	return &job, nil
+}
+
+// Finish records how a claimed job went: its result is stored if it
+// succeeded, and if it failed it goes to the back of the queue unless it
+// has been tried MaxAttempts times
+func Finish(database *db.DB, job *Job, result interface{}, runErr error) error {
+	tx, err := database.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollback(tx)
+
+	if runErr != nil {
+		job.Status = StatusPending
+		if job.Attempts >= MaxAttempts {
+			job.Status = StatusFailed
+		}
+		job.Error = runErr.Error()
+		if _, err := tx.Exec(`
+			UPDATE jobs SET status = ?, error = ?, queued_at = CURRENT_TIMESTAMP, finished_at = CURRENT_TIMESTAMP
+			WHERE id = ?
+		`, job.Status, job.Error, job.ID); err != nil {
+			return fmt.Errorf("failed to record the failure of job %d: %w", job.ID, err)
+		}
+	} else {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return fmt.Errorf("failed to encode the result of job %d: %w", job.ID, err)
+		}
+		if _, err := tx.Exec(`
+			INSERT OR REPLACE INTO enrichments (conversation_id, kind, result, message_count)
+			SELECT id, ?, ?, message_count FROM conversations WHERE id = ?
+		`, job.Kind, string(data), job.ConversationID); err != nil {
+			return fmt.Errorf("failed to store the result of job %d: %w", job.ID, err)
+		}
+		job.Status, job.Error = StatusDone, ""
+		if _, err := tx.Exec(`
+			UPDATE jobs SET status = 'done', error = '', finished_at = CURRENT_TIMESTAMP WHERE id = ?
+		`, job.ID); err != nil {
+			return fmt.Errorf("failed to record job %d as done: %w", job.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+	return nil
+}
+
+// Release puts a claimed job back in the queue without counting the
+// attempt, for a worker that stops before finishing it
+func Release(database *db.DB, job *Job) error {
+	if _, err := database.Exec(`
+		UPDATE jobs SET status = 'pending', attempts = MAX(attempts - 1, 0), started_at = NULL WHERE id = ?
+	`, job.ID); err != nil {
+		return fmt.Errorf("failed to release job %d: %w", job.ID, err)
+	}
+	return nil
+}
+
+// ListProgress counts the jobs of every kind that has any by status, leaving
+// out those of conversations in the trash
+func ListProgress(database *db.DB) ([]Progress, error) {
+	rows, err := database.Query(`
+		SELECT j.kind, j.status, COUNT(*) FROM jobs j
+		JOIN conversations c ON c.id = j.conversation_id
+		WHERE c.deleted_at IS NULL
+		GROUP BY j.kind, j.status
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count jobs: %w", err)
+	}
+	defer closeRows(rows)
+
+	byKind := make(map[string]*Progress)
+	for rows.Next() {
+		var kind, status string
+		var count int
+		if err := rows.Scan(&kind, &status, &count); err != nil {
+			return nil, err
+		}
+		p, ok := byKind[kind]
+		if !ok {
+			p = &Progress{Kind: kind}
+			byKind[kind] = p
+		}
+		switch status {
+		case StatusPending:
+			p.Pending = count
+		case StatusRunning:
+			p.Running = count
+		case StatusDone:
+			p.Done = count
+		case StatusFailed:
+			p.Failed = count
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	progress := make([]Progress, 0, len(byKind))
+	for _, p := range byKind {
+		progress = append(progress, *p)
+	}
+	sort.Slice(progress, func(i, j int) bool { return progress[i].Kind < progress[j].Kind })
+	return progress, nil
+}
+
+// ListJobs returns the jobs with a status, oldest first, or every job if
+// status is empty
+func ListJobs(database *db.DB, status string) ([]*Job, error) {
+	rows, err := database.Query(`
+		SELECT id, kind, conversation_id, status, attempts, error, queued_at, started_at, finished_at
+		FROM jobs
+		WHERE ?1 = '' OR status = ?1
+		ORDER BY queued_at, id
+	`, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+	defer closeRows(rows)
+
+	var jobs []*Job
+	for rows.Next() {
+		var job Job
+		if err := rows.Scan(&job.ID, &job.Kind, &job.ConversationID, &job.Status, &job.Attempts, &job.Error,
+			&job.QueuedAt, &job.StartedAt, &job.FinishedAt); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, &job)
+	}
+	return jobs, rows.Err()
+}
+
+// Result is the stored result of an enrichment of a conversation
+type Result struct {
+	ConversationID   int64           `json:"conversation_id"`
+	ConversationName string          `json:"conversation_name"`
+	Result           json.RawMessage `json:"result"`
+	UpdatedAt        time.Time       `json:"updated_at"`
+}
+
+// Results returns the stored results of a kind of enrichment for the
+// conversations outside the trash
+func Results(database *db.DB, kind string) ([]*Result, error) {
+	rows, err := database.Query(`
+		SELECT e.conversation_id, c.name, e.result, e.updated_at
+		FROM enrichments e JOIN conversations c ON c.id = e.conversation_id
+		WHERE e.kind = ? AND c.deleted_at IS NULL
+		ORDER BY e.conversation_id
+	`, kind)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s results: %w", kind, err)
+	}
+	defer closeRows(rows)
+
+	var results []*Result
+	for rows.Next() {
+		var r Result
+		var data string
+		if err := rows.Scan(&r.ConversationID, &r.ConversationName, &data, &r.UpdatedAt); err != nil {
+			return nil, err
+		}
+		r.Result = json.RawMessage(data)
+		results = append(results, &r)
+	}
+	return results, rows.Err()
+}
+
+// timeLayout is how SQLite's CURRENT_TIMESTAMP writes times
+const timeLayout = "2006-01-02 15:04:05"
+
+func rollback(tx *sql.Tx) {
+	if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+		fmt.Fprintf(os.Stderr, "Warning: failed to rollback transaction: %v\n", err)
+	}
+}
+
+func closeRows(rows *sql.Rows) {
+	if err := rows.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to close rows: %v\n", err)
+	}
+}
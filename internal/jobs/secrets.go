@@ -0,0 +1,112 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/neilberkman/shannon/internal/db"
+)
+
+// secretRule is a kind of credential that shouldn't have been pasted into a
+// conversation
+type secretRule struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+var secretRules = []secretRule{
+	{"private key", regexp.MustCompile(`-----BEGIN (?:RSA |EC |DSA |OPENSSH |PGP )?PRIVATE KEY( BLOCK)?-----`)},
+	{"AWS access key", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"GitHub token", regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`)},
+	{"Anthropic API key", regexp.MustCompile(`\bsk-ant-[A-Za-z0-9_-]{20,}`)},
+	{"OpenAI API key", regexp.MustCompile(`\bsk-(?:proj-)?[A-Za-z0-9]{20,}\b`)},
+	{"Slack token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+	{"assigned secret", regexp.MustCompile(`(?i)\b(?:api[_-]?key|secret|password|passwd|token)\s*[:=]\s*["'][^"'\s]{8,}["']`)},
+}
+
+// SecretFinding is a likely secret in a message. Only enough of it is kept
+// to recognize it.
+type SecretFinding struct {
+	MessageUUID string `json:"message_uuid"`
+	Line        int    `json:"line"`
+	Rule        string `json:"rule"`
+	Masked      string `json:"masked"`
+}
+
+var secretsEnricher = &Enricher{
+	Kind:        "secrets",
+	Description: "find API keys, tokens, passwords and private keys pasted into messages",
+	Run:         scanSecrets,
+}
+
+// scanSecrets finds likely secrets in the messages of a conversation, on
+// every branch
+func scanSecrets(ctx context.Context, database *db.DB, conversationID int64) (interface{}, error) {
+	rows, err := database.QueryContext(ctx, `
+		SELECT uuid, message_text(text) FROM messages WHERE conversation_id = ? ORDER BY sequence, id
+	`, conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load messages: %w", err)
+	}
+	defer closeRows(rows)
+
+	findings := []SecretFinding{}
+	for rows.Next() {
+		var uuid, text string
+		if err := rows.Scan(&uuid, &text); err != nil {
+			return nil, err
+		}
+		for _, f := range FindSecrets(text) {
+			f.MessageUUID = uuid
+			findings = append(findings, f)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return findings, nil
+}
+
+// FindSecrets returns the likely secrets in text, by line. A match of more
+// than one rule is reported once, for the first.
+func FindSecrets(text string) []SecretFinding {
+	var findings []SecretFinding
+	for i, line := range strings.Split(text, "\n") {
+		var taken [][]int
+		for _, rule := range secretRules {
+			for _, loc := range rule.pattern.FindAllStringIndex(line, -1) {
+				if overlaps(taken, loc) {
+					continue
+				}
+				taken = append(taken, loc)
+				findings = append(findings, SecretFinding{Line: i + 1, Rule: rule.name, Masked: mask(line[loc[0]:loc[1]])})
+			}
+		}
+	}
+	return findings
+}
+
+func overlaps(taken [][]int, loc []int) bool {
+	for _, t := range taken {
+		if loc[0] < t[1] && t[0] < loc[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// mask keeps the start of a secret, enough to tell which it is, and hides
+// the rest
+func mask(secret string) string {
+	const shown = 8
+	if strings.HasPrefix(secret, "-----") {
+		return secret
+	}
+	runes := []rune(secret)
+	if len(runes) <= shown {
+		return strings.Repeat("*", len(runes))
+	}
+	return string(runes[:shown]) + strings.Repeat("*", min(len(runes)-shown, 12))
+}
@@ -0,0 +1,89 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/db"
+)
+
+// Worker runs queued jobs one at a time, no faster than its rate, so
+// enrichments share the database with interactive commands instead of
+// holding it up
+type Worker struct {
+	DB *db.DB
+
+	// Interval is the least time between the starts of two jobs
+	Interval time.Duration
+
+	// Poll is how often an empty queue is checked for new jobs. Zero stops
+	// the worker once the queue is empty.
+	Poll time.Duration
+
+	// Done is called after each job with the job as it was left and the
+	// error it failed with, if any
+	Done func(job *Job, err error)
+}
+
+// Run runs jobs until ctx is canceled, or the queue is empty if w.Poll is
+// zero, returning how many it ran. A job interrupted by ctx is put back in
+// the queue.
+func (w *Worker) Run(ctx context.Context) (int, error) {
+	ran := 0
+	var last time.Time
+	for {
+		if wait := w.Interval - time.Since(last); wait > 0 {
+			if !sleep(ctx, wait) {
+				return ran, nil
+			}
+		}
+		if ctx.Err() != nil {
+			return ran, nil
+		}
+
+		job, err := Claim(w.DB)
+		if err != nil {
+			return ran, err
+		}
+		if job == nil {
+			if w.Poll <= 0 || !sleep(ctx, w.Poll) {
+				return ran, nil
+			}
+			continue
+		}
+
+		last = time.Now()
+		result, runErr := w.run(ctx, job)
+		if ctx.Err() != nil {
+			return ran, Release(w.DB, job)
+		}
+		if err := Finish(w.DB, job, result, runErr); err != nil {
+			return ran, err
+		}
+		ran++
+		if w.Done != nil {
+			w.Done(job, runErr)
+		}
+	}
+}
+
+// run runs a job with the enricher of its kind
+func (w *Worker) run(ctx context.Context, job *Job) (interface{}, error) {
+	enricher, err := Lookup(job.Kind)
+	if err != nil {
+		return nil, err
+	}
+	return enricher.Run(ctx, w.DB, job.ConversationID)
+}
+
+// sleep waits for d, returning false if ctx is canceled first
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -13,6 +14,21 @@ type Conversation struct {
 	UpdatedAt    time.Time `db:"updated_at"`
 	MessageCount int       `db:"message_count"`
 	ImportedAt   time.Time `db:"imported_at"`
+
+	// Derived metrics maintained at import time
+	TokenCount        int   `db:"token_count"`
+	ArtifactCount     int   `db:"artifact_count"`
+	HumanMessageCount int   `db:"human_message_count"`
+	DurationSeconds   int64 `db:"duration_seconds"` // from the first message to the last
+	ActiveDays        int   `db:"active_days"`      // days, in UTC, with messages
+
+	Starred bool `db:"starred" json:",omitempty"` // starred in claude.ai, if the export said
+}
+
+// Duration is how long the conversation ran, from its first message to its
+// last
+func (c *Conversation) Duration() time.Duration {
+	return time.Duration(c.DurationSeconds) * time.Second
 }
 
 // Message represents a single message in a conversation
@@ -23,11 +39,22 @@ type Message struct {
 	Sender         string    `db:"sender"` // "human" or "assistant"
 	Text           string    `db:"text"`
 	CreatedAt      time.Time `db:"created_at"`
-	ParentID       *int64    `db:"parent_id"` // For branching support
-	BranchID       int64     `db:"branch_id"` // To group messages in same branch
-	Sequence       int       `db:"sequence"`  // Order within branch
+	ParentID       *int64    `db:"parent_id"`    // For branching support
+	BranchID       int64     `db:"branch_id"`    // To group messages in same branch
+	Sequence       int       `db:"sequence"`     // Order within branch
+	Rating         string    `json:",omitempty"` // "useful", "obsolete" or "wrong" if rated with `shannon rate`
+	RatingNote     string    `json:",omitempty"` // note saved with the rating
+	Model          string    `json:",omitempty"` // model that wrote an assistant message, if the export said
+	Starred        bool      `json:",omitempty"` // starred in claude.ai, if the export said
+	Feedback       string    `json:",omitempty"` // FeedbackUp or FeedbackDown if given in claude.ai and the export said
 }
 
+// Feedback given to a message in claude.ai
+const (
+	FeedbackUp   = "up"
+	FeedbackDown = "down"
+)
+
 // Branch represents a conversation branch
 type Branch struct {
 	ID             int64     `db:"id"`
@@ -48,16 +75,91 @@ type SearchResult struct {
 	Text             string
 	Snippet          string // Highlighted snippet
 	CreatedAt        time.Time
-	Rank             float64 // Relevance score
+	Rank             float64  // Relevance score
+	Branch           string   // Branch the message is on, empty for the main branch
+	Queries          []string `json:",omitempty"` // with several queries, the ones that found the message
+	Private          bool     `json:",omitempty"` // the message is in a private conversation
 }
 
 // ImportStats tracks import statistics
 type ImportStats struct {
-	ConversationsImported int
-	MessagesImported      int
-	BranchesDetected      int
-	Duration              time.Duration
-	Errors                []error
+	ImportID               int64 // Row in import_history recording this import
+	ConversationsImported  int
+	MessagesImported       int
+	BranchesDetected       int
+	ConversationsSkipped   int // deleted locally and left deleted
+	ConversationsRestored  int // deleted locally and brought back
+	ConversationsFiltered  int // left out by the import's filters
+	ConversationsReordered int // messages renumbered to match their parent links and times
+	ArtifactsFound         int
+	CodeBlocksFound        int
+	Languages              map[string]int         // code blocks and artifacts by language, "" when unlabeled
+	LargestConversations   []ImportedConversation // most messages imported first
+	ExportVariants         []string               // older or newer forms of the export format that were adapted
+	Files                  int                    // files read by a directory tree import
+	FilesSkipped           int                    // files of a tree that were neither a conversation nor an export
+	Duration               time.Duration
+	Errors                 []error
+}
+
+// ImportedConversation is a conversation an import added messages to
+type ImportedConversation struct {
+	UUID     string
+	Name     string
+	Messages int // messages imported
+}
+
+// ImportRecord is a persisted entry from the import history
+type ImportRecord struct {
+	ID                 int64         `json:"id"`
+	FilePath           string        `json:"file_path"`
+	FileHash           string        `json:"file_hash"`
+	ImportedAt         time.Time     `json:"imported_at"`
+	ConversationsCount int           `json:"conversations_count"`
+	MessagesCount      int           `json:"messages_count"`
+	BranchesDetected   int           `json:"branches_detected"`
+	ErrorsCount        int           `json:"errors_count"`
+	Duration           time.Duration `json:"duration_ns"`
+	Status             string        `json:"status"` // "success", "partial", or "failed"
+	ErrorMessage       string        `json:"error_message,omitempty"`
+}
+
+// ImportError records a conversation that failed to import
+type ImportError struct {
+	ConversationUUID string `json:"conversation_uuid"`
+	Message          string `json:"message"`
+}
+
+// MessageEdit records a message that was deleted or truncated locally, keeping
+// enough of the original row to undo the change
+type MessageEdit struct {
+	ID             int64     `json:"id"`
+	Action         string    `json:"action"` // "delete" or "truncate"
+	MessageID      int64     `json:"message_id"`
+	ConversationID int64     `json:"conversation_id"`
+	Sender         string    `json:"sender"`
+	OriginalSize   int       `json:"original_size"` // bytes of the original text
+	EditedAt       time.Time `json:"edited_at"`
+}
+
+// DeletedConversation is the tombstone of a conversation deleted locally. It
+// keeps the conversation from coming back when an export is imported again.
+type DeletedConversation struct {
+	UUID         string    `json:"uuid"`
+	Name         string    `json:"name"`
+	MessageCount int       `json:"message_count"`
+	DeletedAt    time.Time `json:"deleted_at"`
+}
+
+// TrashedConversation is a conversation in the trash. It stays out of search,
+// lists and stats until restored, and is purged for good once it has been
+// there longer than the retention period.
+type TrashedConversation struct {
+	ID           int64     `json:"id"`
+	UUID         string    `json:"uuid"`
+	Name         string    `json:"name"`
+	MessageCount int       `json:"message_count"`
+	DeletedAt    time.Time `json:"deleted_at"`
 }
 
 // ClaudeExport represents the structure of Claude's JSON export
@@ -71,6 +173,8 @@ type ClaudeConversation struct {
 	Name         string              `json:"name"`
 	CreatedAt    string              `json:"created_at"`
 	UpdatedAt    string              `json:"updated_at"`
+	Model        string              `json:"model,omitempty"` // model the conversation used, in exports that say
+	Starred      *bool               `json:"is_starred,omitempty"`
 	ChatMessages []ClaudeChatMessage `json:"chat_messages"`
 }
 
@@ -82,6 +186,9 @@ type ClaudeChatMessage struct {
 	Content   []ClaudeMessageContent `json:"content"`
 	CreatedAt string                 `json:"created_at"`
 	ParentID  *string                `json:"parent_message_uuid,omitempty"`
+	Model     string                 `json:"model,omitempty"`    // model that wrote the message, in exports that say
+	Starred   *bool                  `json:"starred,omitempty"`  // in exports that say
+	Feedback  json.RawMessage        `json:"feedback,omitempty"` // thumbs up or down, as a string or an object with a type
 }
 
 // ClaudeMessageContent represents the content structure
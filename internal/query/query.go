@@ -0,0 +1,203 @@
+// Package query reads the filters that can be written into a search query,
+// such as from:h, a:2w, is:starred and duration:>2h, so the search command and the TUI's query bar
+// understand the same queries.
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/dates"
+	"github.com/neilberkman/shannon/internal/models"
+	"github.com/neilberkman/shannon/internal/search"
+)
+
+// senders maps what from: accepts to the sender it stands for
+var senders = map[string]string{
+	"h": "human", "human": "human", "me": "human",
+	"a": "assistant", "assistant": "assistant", "claude": "assistant",
+}
+
+// Query is a search query split into the words to search for and the
+// filters written into it
+type Query struct {
+	Text       string // the query without its filters
+	Sender     string // from:, or empty for both
+	Model      string // model:, part of a model name such as opus, or empty for all
+	Starred    bool   // is:starred, for messages starred in claude.ai or in a conversation starred there
+	Feedback   string // is:upvoted or is:downvoted, as models.FeedbackUp or FeedbackDown
+	After      *time.Time
+	Before     *time.Time
+	Duration   *search.Bound // duration:, in seconds, as in duration:>2h
+	ActiveDays *search.Bound // days:, the days a conversation has messages on, as in days:>7
+}
+
+// Parse splits the filters out of a query. Dates are read like the --after
+// and --before flags, so a:2w means the last two weeks and b:2024-06-01 up
+// to June. A filter inside a quoted phrase is searched for as written, and a
+// value with spaces can be quoted: since:"1 Jun 2024". duration: and days:
+// compare with >, >=, <, <= or =, a bare value meaning at least.
+func Parse(s string, now time.Time) (*Query, error) {
+	q := &Query{}
+	var words []string
+	for _, word := range fields(s) {
+		key, value, ok := strings.Cut(word, ":")
+		if !ok || value == "" || strings.HasPrefix(word, `"`) {
+			words = append(words, word)
+			continue
+		}
+		value = strings.Trim(value, `"`)
+
+		switch strings.ToLower(key) {
+		case "from":
+			sender, ok := senders[strings.ToLower(value)]
+			if !ok {
+				return nil, fmt.Errorf("invalid filter %s: use from:h for your messages or from:a for Claude's", word)
+			}
+			q.Sender = sender
+		case "model":
+			q.Model = value
+		case "is":
+			switch strings.ToLower(value) {
+			case "starred", "star", "favorite":
+				q.Starred = true
+			case "upvoted", "liked":
+				q.Feedback = models.FeedbackUp
+			case "downvoted", "disliked":
+				q.Feedback = models.FeedbackDown
+			default:
+				return nil, fmt.Errorf("invalid filter %s: use is:starred, is:upvoted or is:downvoted", word)
+			}
+		case "a", "after", "since":
+			t, err := dates.Parse(value, now)
+			if err != nil {
+				return nil, fmt.Errorf("invalid filter %s: %w", word, err)
+			}
+			q.After = &t
+		case "b", "before", "until":
+			t, err := dates.Parse(value, now)
+			if err != nil {
+				return nil, fmt.Errorf("invalid filter %s: %w", word, err)
+			}
+			q.Before = &t
+		case "duration":
+			bound, err := DurationBound(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid filter %s: %w", word, err)
+			}
+			q.Duration = bound
+		case "days":
+			bound, err := DaysBound(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid filter %s: %w", word, err)
+			}
+			q.ActiveDays = bound
+		default:
+			words = append(words, word)
+		}
+	}
+	q.Text = strings.Join(words, " ")
+	return q, nil
+}
+
+// Apply sets the query's text and filters on search options, keeping the
+// filters the options already have where the query has none
+func (q *Query) Apply(opts *search.SearchOptions) {
+	opts.Query = q.Text
+	if q.Sender != "" {
+		opts.Sender = q.Sender
+	}
+	if q.Model != "" {
+		opts.Model = q.Model
+	}
+	if q.Starred {
+		opts.Starred = true
+	}
+	if q.Feedback != "" {
+		opts.Feedback = q.Feedback
+	}
+	if q.After != nil {
+		opts.StartDate = q.After
+	}
+	if q.Before != nil {
+		opts.EndDate = q.Before
+	}
+	if q.Duration != nil {
+		opts.Duration = q.Duration
+	}
+	if q.ActiveDays != nil {
+		opts.ActiveDays = q.ActiveDays
+	}
+}
+
+// HasFilters reports whether the query had any filters
+func (q *Query) HasFilters() bool {
+	return q.Sender != "" || q.Model != "" || q.Starred || q.Feedback != "" || q.After != nil || q.Before != nil ||
+		q.Duration != nil || q.ActiveDays != nil
+}
+
+// DurationBound reads a comparison with a length of time such as >2h, in
+// seconds
+func DurationBound(s string) (*search.Bound, error) {
+	return ParseBound(s, func(s string) (int64, error) {
+		d, err := dates.ParseDuration(s)
+		return int64(d / time.Second), err
+	})
+}
+
+// DaysBound reads a comparison with a number of days such as >7
+func DaysBound(s string) (*search.Bound, error) {
+	return ParseBound(s, func(s string) (int64, error) {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("%q is not a number of days", s)
+		}
+		return n, nil
+	})
+}
+
+// ParseBound reads a comparison such as >2h, <=7 or 3d, reading the value
+// after the operator with parse. A bare value means at least that much.
+func ParseBound(s string, parse func(string) (int64, error)) (*search.Bound, error) {
+	s = strings.TrimSpace(s)
+	op := ">="
+	for _, candidate := range search.BoundOps {
+		if strings.HasPrefix(s, candidate) {
+			op = candidate
+			s = strings.TrimSpace(s[len(candidate):])
+			break
+		}
+	}
+	value, err := parse(s)
+	if err != nil {
+		return nil, err
+	}
+	return &search.Bound{Op: op, Value: value}, nil
+}
+
+// fields splits s at spaces outside double quotes
+func fields(s string) []string {
+	var words []string
+	var word strings.Builder
+	quoted := false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			word.WriteRune(r)
+		case !quoted && (r == ' ' || r == '\t' || r == '\n'):
+			if word.Len() > 0 {
+				words = append(words, word.String())
+				word.Reset()
+			}
+		default:
+			word.WriteRune(r)
+		}
+	}
+	if word.Len() > 0 {
+		words = append(words, word.String())
+	}
+	return words
+}
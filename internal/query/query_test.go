@@ -0,0 +1,107 @@
+package query
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/search"
+)
+
+func TestParse(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	day := func(y int, m time.Month, d int) *time.Time {
+		t := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+		return &t
+	}
+	twoWeeks := now.AddDate(0, 0, -14)
+
+	tests := []struct {
+		in       string
+		text     string
+		sender   string
+		model    string
+		starred  bool
+		feedback string
+		after    *time.Time
+		before   *time.Time
+	}{
+		{in: "bug from:h a:2w", text: "bug", sender: "human", after: &twoWeeks},
+		{in: "from:Claude since:2024-01-01 until:2024-03-01 deploy", text: "deploy", sender: "assistant",
+			after: day(2024, 1, 1), before: day(2024, 3, 1)},
+		{in: "model:opus refactor from:a", text: "refactor", sender: "assistant", model: "opus"},
+		{in: `b:"1 Jun 2024" error`, text: "error", before: day(2024, 6, 1)},
+		{in: "is:starred regex is:Upvoted", text: "regex", starred: true, feedback: "up"},
+		{in: `"from:h a:2w" phrase`, text: `"from:h a:2w" phrase`},
+		{in: "code: handler rating:useful https://example.com", text: "code: handler rating:useful https://example.com"},
+		{in: "  spaced   out  ", text: "spaced out"},
+	}
+	for _, tt := range tests {
+		q, err := Parse(tt.in, now)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.in, err)
+			continue
+		}
+		if q.Text != tt.text || q.Sender != tt.sender || q.Model != tt.model || q.Starred != tt.starred || q.Feedback != tt.feedback ||
+			!sameTime(q.After, tt.after) || !sameTime(q.Before, tt.before) {
+			t.Errorf("Parse(%q) = %+v, want text %q, sender %q, model %q, after %v, before %v", tt.in, q, tt.text, tt.sender, tt.model, tt.after, tt.before)
+		}
+	}
+
+	for _, in := range []string{"bug from:me2", "bug a:someday", "bug is:pinned", "duration:>soon", "days:>=a week", "days:>-1"} {
+		if _, err := Parse(in, now); err == nil || !strings.Contains(err.Error(), "invalid filter") {
+			t.Errorf("Parse(%q): expected an invalid filter error, got %v", in, err)
+		}
+	}
+}
+
+func TestParseBounds(t *testing.T) {
+	tests := []struct {
+		in       string
+		duration *search.Bound
+		days     *search.Bound
+	}{
+		{in: "duration:>2h", duration: &search.Bound{Op: ">", Value: 7200}},
+		{in: "days:>7 migration", days: &search.Bound{Op: ">", Value: 7}},
+		{in: "duration:<=30m days:1", duration: &search.Bound{Op: "<=", Value: 1800}, days: &search.Bound{Op: ">=", Value: 1}},
+		{in: "days:=1", days: &search.Bound{Op: "=", Value: 1}},
+	}
+	for _, tt := range tests {
+		q, err := Parse(tt.in, time.Now())
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.in, err)
+			continue
+		}
+		if !sameBound(q.Duration, tt.duration) || !sameBound(q.ActiveDays, tt.days) || !q.HasFilters() {
+			t.Errorf("Parse(%q) = duration %+v, days %+v, want %+v and %+v", tt.in, q.Duration, q.ActiveDays, tt.duration, tt.days)
+		}
+	}
+}
+
+func sameBound(a, b *search.Bound) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func TestApply(t *testing.T) {
+	flag := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	opts := search.SearchOptions{Query: "ignored", Sender: "assistant", StartDate: &flag}
+
+	q, err := Parse("bug from:h", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.Apply(&opts)
+	if opts.Query != "bug" || opts.Sender != "human" || opts.StartDate != &flag || opts.EndDate != nil {
+		t.Errorf("expected the query's filters to override the options and keep the rest, got %+v", opts)
+	}
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
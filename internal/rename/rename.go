@@ -0,0 +1,244 @@
+package rename
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/db"
+)
+
+// Data is what a rename template sees of a conversation
+type Data struct {
+	ID                    int64
+	UUID                  string
+	Name                  string
+	CreatedAt             time.Time
+	UpdatedAt             time.Time
+	MessageCount          int
+	FirstHumanMessage     string
+	FirstAssistantMessage string
+}
+
+// Rename is a change of a conversation's name
+type Rename struct {
+	ConversationID int64  `json:"conversation_id"`
+	OldName        string `json:"old_name"`
+	NewName        string `json:"new_name"`
+}
+
+// funcs are the functions rename templates can use besides the built-in ones
+var funcs = template.FuncMap{
+	// firstWords keeps the first n words of s
+	"firstWords": func(s string, n int) string {
+		words := strings.Fields(s)
+		if len(words) > n {
+			words = words[:n]
+		}
+		return strings.Join(words, " ")
+	},
+	// firstLine keeps the first line of s that isn't blank
+	"firstLine": func(s string) string {
+		for _, line := range strings.Split(s, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				return line
+			}
+		}
+		return ""
+	},
+	// truncate shortens s to n characters, ending it with "…" if anything
+	// was cut
+	"truncate": func(s string, n int) string {
+		runes := []rune(s)
+		if len(runes) <= n {
+			return s
+		}
+		if n < 1 {
+			return ""
+		}
+		return strings.TrimSpace(string(runes[:n-1])) + "…"
+	},
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"trim":  strings.TrimSpace,
+}
+
+// ParseTemplate parses a Go template for conversation names, such as
+// `Chat {{.CreatedAt.Format "2006-01-02"}} – {{firstWords .FirstHumanMessage 6}}`.
+// Besides the fields of Data it can use firstWords, firstLine, truncate,
+// lower, upper and trim.
+func ParseTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("name").Funcs(funcs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// Matching returns the conversations outside the trash whose name contains
+// text, ignoring case, oldest first
+func Matching(database *db.DB, text string) ([]int64, error) {
+	rows, err := database.Query(`
+		SELECT id FROM conversations
+		WHERE name LIKE ? AND deleted_at IS NULL
+		ORDER BY created_at, id
+	`, "%"+text+"%")
+	if err != nil {
+		return nil, fmt.Errorf("failed to find conversations: %w", err)
+	}
+	defer closeRows(rows)
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Plan works out the new name tmpl gives each conversation, leaving out
+// those it doesn't change. Names are trimmed and made one line; a template
+// that gives a conversation an empty name is an error.
+func Plan(database *db.DB, ids []int64, tmpl *template.Template) ([]Rename, error) {
+	var renames []Rename
+	for _, id := range ids {
+		data, err := load(database, id)
+		if err != nil {
+			return nil, err
+		}
+
+		var b bytes.Buffer
+		if err := tmpl.Execute(&b, data); err != nil {
+			return nil, fmt.Errorf("failed to name conversation %d: %w", id, err)
+		}
+		name := strings.Join(strings.Fields(b.String()), " ")
+		if name == "" {
+			return nil, fmt.Errorf("the template gives conversation %d an empty name", id)
+		}
+		if name != data.Name {
+			renames = append(renames, Rename{ConversationID: id, OldName: data.Name, NewName: name})
+		}
+	}
+	return renames, nil
+}
+
+// Apply renames the conversations in one transaction. The name each had
+// from the export is kept, so imports don't undo the rename and Restore can.
+func Apply(database *db.DB, renames []Rename) error {
+	tx, err := database.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollback(tx)
+
+	for _, r := range renames {
+		if _, err := tx.Exec(`
+			UPDATE conversations
+			SET original_name = COALESCE(original_name, name), name = ?
+			WHERE id = ?
+		`, r.NewName, r.ConversationID); err != nil {
+			return fmt.Errorf("failed to rename conversation %d: %w", r.ConversationID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+	return nil
+}
+
+// Restore gives renamed conversations back the names they have in the
+// export, returning the renames it made. Conversations that weren't renamed
+// are left alone.
+func Restore(database *db.DB, ids []int64) ([]Rename, error) {
+	tx, err := database.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollback(tx)
+
+	var renames []Rename
+	for _, id := range ids {
+		r := Rename{ConversationID: id}
+		var original sql.NullString
+		err := tx.QueryRow("SELECT name, original_name FROM conversations WHERE id = ?", id).Scan(&r.OldName, &original)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("conversation %d not found", id)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load conversation %d: %w", id, err)
+		}
+		if !original.Valid {
+			continue
+		}
+
+		r.NewName = original.String
+		if _, err := tx.Exec("UPDATE conversations SET name = original_name, original_name = NULL WHERE id = ?", id); err != nil {
+			return nil, fmt.Errorf("failed to restore the name of conversation %d: %w", id, err)
+		}
+		renames = append(renames, r)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+	return renames, nil
+}
+
+// load reads what a template sees of a conversation
+func load(database *db.DB, id int64) (*Data, error) {
+	data := &Data{ID: id}
+	err := database.QueryRow(`
+		SELECT uuid, name, created_at, updated_at, message_count
+		FROM conversations WHERE id = ?
+	`, id).Scan(&data.UUID, &data.Name, &data.CreatedAt, &data.UpdatedAt, &data.MessageCount)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("conversation %d not found", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load conversation %d: %w", id, err)
+	}
+
+	first := func(sender string) (string, error) {
+		var text string
+		err := database.QueryRow(`
+			SELECT message_text(text) FROM messages
+			WHERE conversation_id = ? AND sender = ? AND TRIM(message_text(text)) != ''
+			ORDER BY created_at, id
+			LIMIT 1
+		`, id, sender).Scan(&text)
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to load the first %s message of conversation %d: %w", sender, id, err)
+		}
+		return text, nil
+	}
+	if data.FirstHumanMessage, err = first("human"); err != nil {
+		return nil, err
+	}
+	if data.FirstAssistantMessage, err = first("assistant"); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func rollback(tx *sql.Tx) {
+	if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+		fmt.Fprintf(os.Stderr, "Warning: failed to rollback transaction: %v\n", err)
+	}
+}
+
+func closeRows(rows *sql.Rows) {
+	if err := rows.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to close rows: %v\n", err)
+	}
+}
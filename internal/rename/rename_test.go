@@ -0,0 +1,130 @@
+package rename
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/imports"
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+// importExport imports conversations as a new export file
+func importExport(t *testing.T, database *db.DB, dir, name string, conversations []models.ClaudeConversation) {
+	t.Helper()
+	data, err := json.Marshal(conversations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := imports.NewImporter(database, 100, false).Import(path); err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+}
+
+func untitled(uuid, created, question string) models.ClaudeConversation {
+	return models.ClaudeConversation{
+		UUID: uuid, Name: "Untitled", CreatedAt: created, UpdatedAt: created,
+		ChatMessages: []models.ClaudeChatMessage{
+			{UUID: uuid + "-1", Sender: "human", Text: question, CreatedAt: created},
+			{UUID: uuid + "-2", Sender: "assistant", Text: "Sure.", CreatedAt: created},
+		},
+	}
+}
+
+func names(t *testing.T, database *db.DB) map[int64]string {
+	t.Helper()
+	rows, err := database.Query("SELECT id, name FROM conversations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = rows.Close() }()
+	result := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			t.Fatal(err)
+		}
+		result[id] = name
+	}
+	return result
+}
+
+func TestRename(t *testing.T) {
+	dir := t.TempDir()
+	database, err := db.New(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = database.Close() }()
+
+	conversations := []models.ClaudeConversation{
+		untitled("conv-1", "2024-05-01T10:00:00Z", "How do I parse\nJSON in Go without a struct?"),
+		untitled("conv-2", "2024-05-02T10:00:00Z", "Explain   Kubernetes pods"),
+		{UUID: "conv-3", Name: "Named already", CreatedAt: "2024-05-03T10:00:00Z", UpdatedAt: "2024-05-03T10:00:00Z"},
+	}
+	importExport(t, database, dir, "first.json", conversations)
+
+	ids, err := Matching(database, "untitled")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 {
+		t.Fatalf("expected the 2 untitled conversations, got %v", ids)
+	}
+
+	tmpl, err := ParseTemplate(`Chat {{.CreatedAt.Format "2006-01-02"}} – {{firstWords .FirstHumanMessage 6}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	renames, err := Plan(database, ids, tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"Chat 2024-05-01 – How do I parse JSON in", "Chat 2024-05-02 – Explain Kubernetes pods"}
+	if len(renames) != 2 || renames[0].NewName != expected[0] || renames[1].NewName != expected[1] {
+		t.Fatalf("expected %q, got %+v", expected, renames)
+	}
+	if names(t, database)[ids[0]] != "Untitled" {
+		t.Error("expected planning not to rename anything")
+	}
+
+	if err := Apply(database, renames); err != nil {
+		t.Fatal(err)
+	}
+	if again, err := Plan(database, ids, tmpl); err != nil || len(again) != 0 {
+		t.Errorf("expected nothing left to rename, got %+v, %v", again, err)
+	}
+
+	// Importing the conversations again keeps the new names
+	conversations[0].UpdatedAt = "2024-06-01T10:00:00Z"
+	importExport(t, database, dir, "second.json", conversations)
+	if got := names(t, database)[ids[0]]; got != expected[0] {
+		t.Errorf("expected the rename to survive a re-import, got %q", got)
+	}
+
+	restored, err := Restore(database, ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(restored) != 2 || names(t, database)[ids[0]] != "Untitled" {
+		t.Errorf("expected the export's names back, got %+v", restored)
+	}
+
+	empty, err := ParseTemplate(`{{firstWords .FirstAssistantMessage 0}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Plan(database, ids, empty); err == nil {
+		t.Error("expected an error for an empty name")
+	}
+	if _, err := ParseTemplate(`{{.Name`); err == nil {
+		t.Error("expected an error for an invalid template")
+	}
+}
@@ -0,0 +1,112 @@
+package rendering
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// MatchStyle is how a Highlighter marks matches, reversing the terminal's own
+// colors so it works with any theme
+var MatchStyle = lipgloss.NewStyle().Reverse(true).Bold(true)
+
+// Highlighter marks every occurrence of some search terms in text, ignoring
+// case. The words of a term match across any run of whitespace, so a phrase
+// broken over two lines is still found.
+type Highlighter struct {
+	pattern *regexp.Regexp
+	Style   lipgloss.Style
+}
+
+// NewHighlighter creates a highlighter for terms, or returns nil if none of
+// them has any words. A nil Highlighter matches nothing.
+func NewHighlighter(terms ...string) *Highlighter {
+	var alternatives []string
+	for _, term := range terms {
+		words := strings.Fields(term)
+		if len(words) == 0 {
+			continue
+		}
+		for i, word := range words {
+			words[i] = regexp.QuoteMeta(word)
+		}
+		alternatives = append(alternatives, strings.Join(words, `\s+`))
+	}
+	if len(alternatives) == 0 {
+		return nil
+	}
+
+	// Prefer the longest term where several match at the same place
+	sort.SliceStable(alternatives, func(i, j int) bool { return len(alternatives[i]) > len(alternatives[j]) })
+	return &Highlighter{
+		pattern: regexp.MustCompile(`(?i)` + strings.Join(alternatives, "|")),
+		Style:   MatchStyle,
+	}
+}
+
+// Match reports whether text contains any of the terms
+func (h *Highlighter) Match(text string) bool {
+	return h != nil && h.pattern.MatchString(text)
+}
+
+// Index returns the start and end of the first match in text, or nil
+func (h *Highlighter) Index(text string) []int {
+	if h == nil {
+		return nil
+	}
+	return h.pattern.FindStringIndex(text)
+}
+
+// Highlight returns text with every match styled. A match spanning lines is
+// styled line by line, so each line can be indented or wrapped on its own.
+// Text is returned unchanged when output is plain.
+func (h *Highlighter) Highlight(text string) string {
+	if h == nil || Plain() {
+		return text
+	}
+	return h.mark(text, func(s string) string { return h.Style.Render(s) })
+}
+
+// Wrap returns text with every match put between open and close, such as
+// "**" and "**" for Markdown, line by line like Highlight. Unlike Highlight
+// it doesn't depend on the terminal, so it suits files.
+func (h *Highlighter) Wrap(text, open, close string) string {
+	if h == nil {
+		return text
+	}
+	return h.mark(text, func(s string) string { return open + s + close })
+}
+
+// mark returns text with the words of every match passed through style
+func (h *Highlighter) mark(text string, style func(string) string) string {
+	matches := h.pattern.FindAllStringIndex(text, -1)
+	if len(matches) == 0 {
+		return text
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, match := range matches {
+		sb.WriteString(text[last:match[0]])
+		for i, line := range strings.Split(text[match[0]:match[1]], "\n") {
+			if i > 0 {
+				sb.WriteString("\n")
+			}
+			// Only the words are styled, not the whitespace around them
+			core := strings.TrimSpace(line)
+			if core == "" {
+				sb.WriteString(line)
+				continue
+			}
+			start := strings.Index(line, core)
+			sb.WriteString(line[:start])
+			sb.WriteString(style(core))
+			sb.WriteString(line[start+len(core):])
+		}
+		last = match[1]
+	}
+	sb.WriteString(text[last:])
+	return sb.String()
+}
@@ -0,0 +1,53 @@
+package rendering
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestHighlighter(t *testing.T) {
+	if h := NewHighlighter("", "  "); h != nil {
+		t.Errorf("expected no highlighter without terms, got %+v", h)
+	}
+	var none *Highlighter
+	if none.Match("anything") || none.Highlight("anything") != "anything" {
+		t.Error("a nil highlighter should match nothing")
+	}
+
+	h := NewHighlighter("error handling", "go")
+	h.Style = lipgloss.NewStyle().Transform(func(s string) string { return "[" + s + "]" })
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"no match", "nothing here", "nothing here"},
+		{"every occurrence, ignoring case", "Go and go", "[Go] and [go]"},
+		{"longest term first", "Error handling in Go", "[Error handling] in [Go]"},
+		{"phrase across lines", "    better error\n    handling", "    better [error]\n    [handling]"},
+		{"regexp characters are literal", "go.mod", "[go].mod"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := h.Highlight(tt.text); got != tt.want {
+				t.Errorf("Highlight(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+			if got, want := h.Match(tt.text), tt.want != tt.text; got != want {
+				t.Errorf("Match(%q) = %v, want %v", tt.text, got, want)
+			}
+		})
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	if got := h.Highlight("Go"); got != "Go" {
+		t.Errorf("expected no highlighting with NO_COLOR, got %q", got)
+	}
+	if got, want := h.Wrap("Error\n  handling in Go", "**", "**"), "**Error**\n  **handling** in **Go**"; got != want {
+		t.Errorf("expected Wrap to mark matches whatever the colors, got %q, want %q", got, want)
+	}
+	if !h.Match("Go") {
+		t.Error("matching shouldn't depend on color")
+	}
+}
@@ -0,0 +1,401 @@
+package rendering
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// mathRegex finds math in message text: $$...$$ and \[...\] display math,
+// and \(...\) and $...$ inline math. Inline $...$ follows Pandoc: no space
+// just inside the dollars.
+var mathRegex = regexp.MustCompile(`(?s)\$\$(.+?)\$\$|\\\[(.+?)\\\]|\\\((.+?)\\\)|\$([^\s$](?:[^$\n]*?[^\s$\\])?)\$`)
+
+// codeRegex finds fenced code blocks and inline code, which are never math
+var codeRegex = regexp.MustCompile("(?s)```.*?(?:```|$)|`[^`\n]+`")
+
+// HasMath reports whether text contains LaTeX math outside code
+func HasMath(text string) bool {
+	found := false
+	eachMath(text, func(string, bool) string {
+		found = true
+		return ""
+	})
+	return found
+}
+
+// RenderMath replaces the LaTeX math in text with Unicode approximations for
+// the terminal, so $\alpha^2 \leq \frac{1}{2}$ reads α² ≤ 1/2. Display math
+// goes on its own line. Code is left alone.
+func RenderMath(text string) string {
+	return eachMath(text, func(expr string, display bool) string {
+		rendered := strings.TrimSpace(latexToUnicode(expr))
+		if display {
+			return "\n    " + strings.ReplaceAll(rendered, "\n", "\n    ") + "\n"
+		}
+		return rendered
+	})
+}
+
+// eachMath calls fn for the math outside code in text, replacing the math,
+// delimiters included, with what fn returns
+func eachMath(text string, fn func(expr string, display bool) string) string {
+	if !strings.ContainsAny(text, `$\`) {
+		return text
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, code := range codeRegex.FindAllStringIndex(text, -1) {
+		sb.WriteString(replaceMath(text[last:code[0]], fn))
+		sb.WriteString(text[code[0]:code[1]])
+		last = code[1]
+	}
+	sb.WriteString(replaceMath(text[last:], fn))
+	return sb.String()
+}
+
+// replaceMath replaces the math in text that has no code in it
+func replaceMath(text string, fn func(expr string, display bool) string) string {
+	var sb strings.Builder
+	last := 0
+	for _, m := range mathRegex.FindAllStringSubmatchIndex(text, -1) {
+		var expr string
+		display := false
+		switch {
+		case m[2] >= 0:
+			expr, display = text[m[2]:m[3]], true
+		case m[4] >= 0:
+			expr, display = text[m[4]:m[5]], true
+		case m[6] >= 0:
+			expr = text[m[6]:m[7]]
+		default:
+			expr = text[m[8]:m[9]]
+			// Prices and shell variables aren't math: $5 and $10, $HOME/$PATH
+			if !looksLikeMath(expr) || (m[1] < len(text) && isDigit(text[m[1]])) {
+				continue
+			}
+		}
+		sb.WriteString(text[last:m[0]])
+		sb.WriteString(fn(expr, display))
+		last = m[1]
+	}
+	sb.WriteString(text[last:])
+	return sb.String()
+}
+
+// looksLikeMath tells inline $...$ math from other text between dollar signs
+func looksLikeMath(expr string) bool {
+	if strings.ContainsAny(expr, `\^_=`) {
+		return true
+	}
+	// A lone variable, as in "for each $x$"
+	runes := []rune(expr)
+	return len(runes) == 1 && unicode.IsLetter(runes[0])
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+// latexToUnicode converts a LaTeX math expression to its closest plain
+// Unicode rendering
+func latexToUnicode(expr string) string {
+	p := &latexParser{src: []rune(expr)}
+	return p.parse(false)
+}
+
+// latexParser converts LaTeX math one token at a time
+type latexParser struct {
+	src []rune
+	pos int
+}
+
+// parse converts up to the end of the expression, or of the current group
+// if inGroup is set
+func (p *latexParser) parse(inGroup bool) string {
+	var sb strings.Builder
+	for p.pos < len(p.src) {
+		r := p.src[p.pos]
+		switch r {
+		case '}':
+			p.pos++
+			if inGroup {
+				return sb.String()
+			}
+		case '{':
+			p.pos++
+			sb.WriteString(p.parse(true))
+		case '\\':
+			sb.WriteString(p.command())
+		case '^':
+			p.pos++
+			sb.WriteString(script(p.argument(), superscripts, "^"))
+		case '_':
+			p.pos++
+			sb.WriteString(script(p.argument(), subscripts, "_"))
+		case '~':
+			p.pos++
+			sb.WriteRune(' ')
+		case '&':
+			// Alignment points in aligned equations
+			p.pos++
+		default:
+			p.pos++
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// argument converts the argument of a command or script: a group, a
+// command or a single character
+func (p *latexParser) argument() string {
+	for p.pos < len(p.src) && p.src[p.pos] == ' ' {
+		p.pos++
+	}
+	if p.pos >= len(p.src) {
+		return ""
+	}
+	switch p.src[p.pos] {
+	case '{':
+		p.pos++
+		return p.parse(true)
+	case '\\':
+		return p.command()
+	}
+	p.pos++
+	return string(p.src[p.pos-1])
+}
+
+// rawArgument returns the text of a group argument unconverted, for \text
+// and environment names
+func (p *latexParser) rawArgument() string {
+	for p.pos < len(p.src) && p.src[p.pos] == ' ' {
+		p.pos++
+	}
+	if p.pos >= len(p.src) || p.src[p.pos] != '{' {
+		return p.argument()
+	}
+	start := p.pos + 1
+	depth := 0
+	for ; p.pos < len(p.src); p.pos++ {
+		switch p.src[p.pos] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				p.pos++
+				return string(p.src[start : p.pos-1])
+			}
+		}
+	}
+	return string(p.src[start:])
+}
+
+// command converts the command at the current position, a backslash
+// followed by a name or a single symbol
+func (p *latexParser) command() string {
+	p.pos++ // the backslash
+	if p.pos >= len(p.src) {
+		return `\`
+	}
+	start := p.pos
+	for p.pos < len(p.src) && unicode.IsLetter(p.src[p.pos]) && p.src[p.pos] < unicode.MaxASCII {
+		p.pos++
+	}
+	if p.pos == start {
+		// A symbol such as \{ or \, or a \\ line break
+		p.pos++
+		switch s := p.src[start]; s {
+		case '\\':
+			return "\n"
+		case ',', ':', ';', ' ':
+			return " "
+		case '!':
+			return ""
+		default:
+			return string(s)
+		}
+	}
+	name := string(p.src[start:p.pos])
+
+	if symbol, ok := latexSymbols[name]; ok {
+		return symbol
+	}
+	switch name {
+	case "frac", "dfrac", "tfrac":
+		num, den := p.argument(), p.argument()
+		return parenthesize(num) + "/" + parenthesize(den)
+	case "sqrt":
+		index := ""
+		if p.pos < len(p.src) && p.src[p.pos] == '[' {
+			end := p.pos
+			for end < len(p.src) && p.src[end] != ']' {
+				end++
+			}
+			index = string(p.src[p.pos+1 : min(end, len(p.src))])
+			p.pos = min(end+1, len(p.src))
+		}
+		root := "√"
+		switch index {
+		case "":
+		case "3":
+			root = "∛"
+		case "4":
+			root = "∜"
+		default:
+			root = script(index, superscripts, "^") + "√"
+		}
+		return root + parenthesize(p.argument())
+	case "text", "textrm", "textit", "textbf", "mbox", "operatorname":
+		return p.rawArgument()
+	case "mathrm", "mathbf", "mathit", "mathsf", "mathtt", "mathcal", "boldsymbol", "bm":
+		return p.argument()
+	case "mathbb":
+		return doubleStruck(p.argument())
+	case "overline", "bar":
+		return combine(p.argument(), '̅')
+	case "hat":
+		return combine(p.argument(), '̂')
+	case "vec":
+		return combine(p.argument(), '⃗')
+	case "dot":
+		return combine(p.argument(), '̇')
+	case "tilde":
+		return combine(p.argument(), '̃')
+	case "begin", "end":
+		// Environment names such as aligned or pmatrix
+		p.rawArgument()
+		return ""
+	case "left", "right", "big", "Big", "bigg", "Bigg", "bigl", "bigr", "Bigl", "Bigr", "displaystyle", "limits", "nolimits":
+		// Sizing: keep the delimiter that follows, except the invisible one
+		if p.pos < len(p.src) && p.src[p.pos] == '.' {
+			p.pos++
+		}
+		return ""
+	case "quad", "qquad":
+		return "  "
+	}
+	return `\` + name
+}
+
+// parenthesize wraps a fraction's numerator or denominator in parentheses
+// when it's more than one term
+func parenthesize(s string) string {
+	s = strings.TrimSpace(s)
+	if len([]rune(s)) <= 1 || !strings.ContainsAny(s, " +-−*/·×±=") {
+		return s
+	}
+	return "(" + s + ")"
+}
+
+// script renders a superscript or subscript in Unicode if every character
+// has a form for it, or else as ^(...) or _(...)
+func script(s string, forms map[rune]rune, marker string) string {
+	s = strings.TrimSpace(s)
+	var sb strings.Builder
+	for _, r := range s {
+		form, ok := forms[r]
+		if !ok {
+			if len([]rune(s)) == 1 {
+				return marker + s
+			}
+			return marker + "(" + s + ")"
+		}
+		sb.WriteRune(form)
+	}
+	return sb.String()
+}
+
+// combine puts an accent over every character of s
+func combine(s string, accent rune) string {
+	var sb strings.Builder
+	for _, r := range s {
+		sb.WriteRune(r)
+		sb.WriteRune(accent)
+	}
+	return sb.String()
+}
+
+// doubleStruck renders blackboard bold letters such as ℝ and ℕ
+func doubleStruck(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		switch {
+		case r == 'C', r == 'H', r == 'N', r == 'P', r == 'Q', r == 'R', r == 'Z':
+			sb.WriteRune(map[rune]rune{'C': 'ℂ', 'H': 'ℍ', 'N': 'ℕ', 'P': 'ℙ', 'Q': 'ℚ', 'R': 'ℝ', 'Z': 'ℤ'}[r])
+		case r >= 'A' && r <= 'Z':
+			sb.WriteRune('𝔸' + r - 'A')
+		case r >= 'a' && r <= 'z':
+			sb.WriteRune('𝕒' + r - 'a')
+		case r >= '0' && r <= '9':
+			sb.WriteRune('𝟘' + r - '0')
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+var superscripts = map[rune]rune{
+	'0': '⁰', '1': '¹', '2': '²', '3': '³', '4': '⁴', '5': '⁵', '6': '⁶', '7': '⁷', '8': '⁸', '9': '⁹',
+	'+': '⁺', '-': '⁻', '−': '⁻', '=': '⁼', '(': '⁽', ')': '⁾', '′': '′', '*': '*', '∗': '*',
+	'a': 'ᵃ', 'b': 'ᵇ', 'c': 'ᶜ', 'd': 'ᵈ', 'e': 'ᵉ', 'f': 'ᶠ', 'g': 'ᵍ', 'h': 'ʰ', 'i': 'ⁱ',
+	'j': 'ʲ', 'k': 'ᵏ', 'l': 'ˡ', 'm': 'ᵐ', 'n': 'ⁿ', 'o': 'ᵒ', 'p': 'ᵖ', 'r': 'ʳ', 's': 'ˢ',
+	't': 'ᵗ', 'u': 'ᵘ', 'v': 'ᵛ', 'w': 'ʷ', 'x': 'ˣ', 'y': 'ʸ', 'z': 'ᶻ', 'T': 'ᵀ',
+}
+
+var subscripts = map[rune]rune{
+	'0': '₀', '1': '₁', '2': '₂', '3': '₃', '4': '₄', '5': '₅', '6': '₆', '7': '₇', '8': '₈', '9': '₉',
+	'+': '₊', '-': '₋', '−': '₋', '=': '₌', '(': '₍', ')': '₎',
+	'a': 'ₐ', 'e': 'ₑ', 'h': 'ₕ', 'i': 'ᵢ', 'j': 'ⱼ', 'k': 'ₖ', 'l': 'ₗ', 'm': 'ₘ', 'n': 'ₙ',
+	'o': 'ₒ', 'p': 'ₚ', 'r': 'ᵣ', 's': 'ₛ', 't': 'ₜ', 'u': 'ᵤ', 'v': 'ᵥ', 'x': 'ₓ',
+}
+
+// latexSymbols maps LaTeX commands to the characters they stand for
+var latexSymbols = map[string]string{
+	// Greek letters
+	"alpha": "α", "beta": "β", "gamma": "γ", "delta": "δ", "epsilon": "ε", "varepsilon": "ε",
+	"zeta": "ζ", "eta": "η", "theta": "θ", "vartheta": "ϑ", "iota": "ι", "kappa": "κ",
+	"lambda": "λ", "mu": "μ", "nu": "ν", "xi": "ξ", "pi": "π", "varpi": "ϖ", "rho": "ρ",
+	"sigma": "σ", "varsigma": "ς", "tau": "τ", "upsilon": "υ", "phi": "φ", "varphi": "φ",
+	"chi": "χ", "psi": "ψ", "omega": "ω",
+	"Gamma": "Γ", "Delta": "Δ", "Theta": "Θ", "Lambda": "Λ", "Xi": "Ξ", "Pi": "Π",
+	"Sigma": "Σ", "Upsilon": "Υ", "Phi": "Φ", "Psi": "Ψ", "Omega": "Ω",
+
+	// Operators and relations
+	"times": "×", "cdot": "·", "div": "÷", "pm": "±", "mp": "∓", "ast": "∗", "circ": "∘",
+	"leq": "≤", "le": "≤", "geq": "≥", "ge": "≥", "neq": "≠", "ne": "≠", "approx": "≈",
+	"equiv": "≡", "sim": "∼", "simeq": "≃", "cong": "≅", "propto": "∝", "ll": "≪", "gg": "≫",
+	"in": "∈", "notin": "∉", "ni": "∋", "subset": "⊂", "subseteq": "⊆", "supset": "⊃",
+	"supseteq": "⊇", "cup": "∪", "cap": "∩", "setminus": "∖", "emptyset": "∅", "varnothing": "∅",
+	"wedge": "∧", "land": "∧", "vee": "∨", "lor": "∨", "neg": "¬", "lnot": "¬",
+	"oplus": "⊕", "otimes": "⊗", "perp": "⊥", "parallel": "∥", "mid": "∣",
+	"forall": "∀", "exists": "∃", "nexists": "∄",
+
+	// Big operators
+	"sum": "∑", "prod": "∏", "coprod": "∐", "int": "∫", "iint": "∬", "iiint": "∭",
+	"oint": "∮", "bigcup": "⋃", "bigcap": "⋂",
+
+	// Arrows
+	"to": "→", "rightarrow": "→", "leftarrow": "←", "gets": "←", "leftrightarrow": "↔",
+	"Rightarrow": "⇒", "implies": "⇒", "Leftarrow": "⇐", "Leftrightarrow": "⇔", "iff": "⇔",
+	"mapsto": "↦", "uparrow": "↑", "downarrow": "↓", "longrightarrow": "⟶",
+
+	// Other symbols
+	"infty": "∞", "partial": "∂", "nabla": "∇", "prime": "′", "degree": "°",
+	"ldots": "…", "dots": "…", "cdots": "⋯", "vdots": "⋮", "ddots": "⋱",
+	"hbar": "ℏ", "ell": "ℓ", "Re": "ℜ", "Im": "ℑ", "aleph": "ℵ",
+	"langle": "⟨", "rangle": "⟩", "lceil": "⌈", "rceil": "⌉", "lfloor": "⌊", "rfloor": "⌋",
+	"vert": "|", "Vert": "‖", "lvert": "|", "rvert": "|", "lVert": "‖", "rVert": "‖",
+	"angle": "∠", "triangle": "△", "square": "□", "checkmark": "✓",
+
+	// Functions, written upright
+	"sin": "sin", "cos": "cos", "tan": "tan", "cot": "cot", "sec": "sec", "csc": "csc",
+	"arcsin": "arcsin", "arccos": "arccos", "arctan": "arctan", "sinh": "sinh", "cosh": "cosh",
+	"tanh": "tanh", "log": "log", "ln": "ln", "exp": "exp", "lim": "lim", "max": "max",
+	"min": "min", "sup": "sup", "inf": "inf", "det": "det", "gcd": "gcd", "arg": "arg",
+	"deg": "deg", "dim": "dim", "ker": "ker", "Pr": "Pr",
+}
@@ -0,0 +1,43 @@
+package rendering
+
+import "testing"
+
+func TestRenderMath(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"greek and relations", `so $\alpha \leq \beta$ holds`, "so α ≤ β holds"},
+		{"superscripts and subscripts", `$x^2 + y_{i} = z^{n+1}$`, "x² + yᵢ = zⁿ⁺¹"},
+		{"scripts without unicode forms", `$e^{\pi q}$`, "e^(π q)"},
+		{"fractions", `$\frac{1}{2}$ and $\frac{a+b}{c}$`, "1/2 and (a+b)/c"},
+		{"roots", `\(\sqrt{x} + \sqrt[3]{8}\)`, "√x + ∛8"},
+		{"text and blackboard bold", `$f: \mathbb{R} \to \mathbb{R}, \text{for all } x$`, "f: ℝ → ℝ, for all  x"},
+		{"sums", `$\sum_{i=1}^{n} i$`, "∑ᵢ₌₁ⁿ i"},
+		{"sizing is dropped", `$\left( \frac{x}{y} \right)$`, "( x/y )"},
+		{"display math on its own line", "Energy:\n$$E = mc^2$$\ndone", "Energy:\n\n    E = mc²\n\ndone"},
+		{"bracket display math", `\[\int_0^\infty f\,dx\]`, "\n    ∫₀^∞ f dx\n"},
+		{"unknown commands are kept", `$\foo{x}$`, `\foox`},
+		{"prices aren't math", "costs $5 and $10 today", "costs $5 and $10 today"},
+		{"shell variables aren't math", "echo $HOME/$PATH", "echo $HOME/$PATH"},
+		{"inline code is left alone", "run `echo $x^2$` then $x^2$", "run `echo $x^2$` then x²"},
+		{"code blocks are left alone", "```\n$$a^2$$\n```\n$a^2$", "```\n$$a^2$$\n```\na²"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RenderMath(tt.text); got != tt.want {
+				t.Errorf("RenderMath(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasMath(t *testing.T) {
+	if !HasMath(`the area is $\pi r^2$`) || !HasMath("$$x$$") {
+		t.Error("expected math to be found")
+	}
+	if HasMath("costs $5 and $10") || HasMath("`$x^2$`") || HasMath("no math here") {
+		t.Error("expected no math outside math delimiters or in code")
+	}
+}
@@ -24,7 +24,7 @@ func GetSharedRenderer() *MarkdownRenderer {
 	sharedRendererOnce.Do(func() {
 		// Create renderer with a fixed dark theme - no auto detection
 		r, err := glamour.NewTermRenderer(
-			glamour.WithStandardStyle("dark"),
+			glamour.WithStandardStyle(glamourStyle()),
 			glamour.WithWordWrap(76), // Fixed width for consistency
 		)
 		if err != nil {
@@ -44,6 +44,14 @@ func GetSharedRenderer() *MarkdownRenderer {
 	return sharedRenderer
 }
 
+// glamourStyle is the dark theme, or glamour's unstyled one for plain output
+func glamourStyle() string {
+	if Plain() {
+		return "notty"
+	}
+	return "dark"
+}
+
 // NewMarkdownRenderer creates a new markdown renderer with specified width
 func NewMarkdownRenderer(width int) (*MarkdownRenderer, error) {
 	// Store original width for the struct
@@ -57,7 +65,7 @@ func NewMarkdownRenderer(width int) (*MarkdownRenderer, error) {
 
 	// Create renderer with specified width
 	r, err := glamour.NewTermRenderer(
-		glamour.WithStandardStyle("dark"),
+		glamour.WithStandardStyle(glamourStyle()),
 		glamour.WithWordWrap(glamourWidth),
 	)
 	if err != nil {
@@ -142,7 +150,10 @@ func (mr *MarkdownRenderer) renderSnippet(text string, sender string) (string, e
 
 // renderFullMessage handles full message rendering with complete markdown support
 func (mr *MarkdownRenderer) renderFullMessage(text string, sender string) (string, error) {
-	// First enhance text with hyperlinks if supported
+	// Show LaTeX math as Unicode before markdown can eat its backslashes
+	text = RenderMath(text)
+
+	// Then enhance text with hyperlinks if supported
 	if IsHyperlinksSupported() {
 		text = EnhanceTextWithLinks(text)
 	}
@@ -339,7 +350,7 @@ func RenderConversationWithMarkdown(messages []MessageForRendering, width int) (
 			senderStyle = senderStyle.Foreground(lipgloss.Color("#7D56F4"))
 		}
 
-		result.WriteString(senderStyle.Render(strings.ToUpper(msg.Sender)))
+		result.WriteString(senderStyle.Render(FormatSender(msg.Sender)))
 		result.WriteString("\n\n")
 
 		// Render message content
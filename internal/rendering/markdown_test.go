@@ -328,7 +328,7 @@ func TestRenderConversationWithMarkdown(t *testing.T) {
 			}
 			// Check that all senders are present in output
 			for _, msg := range tt.messages {
-				if !strings.Contains(strings.ToUpper(result), strings.ToUpper(msg.Sender)) {
+				if !strings.Contains(result, FormatSender(msg.Sender)) {
 					t.Errorf("RenderConversationWithMarkdown() missing sender %s in output", msg.Sender)
 				}
 			}
@@ -532,8 +532,8 @@ func TestRenderConversationWithMarkdownAndHyperlinks(t *testing.T) {
 	// Should contain hyperlinks for URLs
 	expectedSubstrings := []string{
 		"\x1b]8;;https://github.com/example/repo\x1b\\https://github.com/example/repo\x1b]8;;\x1b\\",
-		"HUMAN",
-		"ASSISTANT",
+		"You",
+		"Claude",
 	}
 
 	for _, expected := range expectedSubstrings {
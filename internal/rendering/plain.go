@@ -0,0 +1,33 @@
+package rendering
+
+import (
+	"os"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// plain is set by SetPlain
+var plain bool
+
+// SetPlain turns on plain output for the rest of the run, for clean logs and
+// scripts: no colors or text styles, no hyperlinks and no emoji
+func SetPlain() {
+	plain = true
+	lipgloss.SetColorProfile(termenv.Ascii)
+}
+
+// Plain reports whether output is plain: after SetPlain, or when the
+// NO_COLOR environment variable is set to anything but an empty string
+// (https://no-color.org)
+func Plain() bool {
+	return plain || os.Getenv("NO_COLOR") != ""
+}
+
+// Symbol returns emoji, or its plain text replacement when output is plain
+func Symbol(emoji, text string) string {
+	if Plain() {
+		return text
+	}
+	return emoji
+}
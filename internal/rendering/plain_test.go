@@ -0,0 +1,41 @@
+package rendering
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestPlain(t *testing.T) {
+	t.Setenv("TERM_PROGRAM", "ghostty")
+	t.Setenv("NO_COLOR", "")
+	if Plain() || Symbol("✓", "ok") != "✓" || !IsHyperlinksSupported() {
+		t.Fatal("expected styled output by default")
+	}
+
+	// An empty NO_COLOR doesn't count, any other value does
+	t.Setenv("NO_COLOR", "1")
+	if !Plain() || Symbol("✓", "ok") != "ok" {
+		t.Error("expected plain output with NO_COLOR")
+	}
+	if IsHyperlinksSupported() || IsGraphicsSupported() {
+		t.Error("expected no hyperlinks or graphics in plain output")
+	}
+	if got := MakeHyperlink("docs", "https://example.com"); got != "docs" {
+		t.Errorf("expected plain link text, got %q", got)
+	}
+
+	t.Setenv("NO_COLOR", "")
+	profile := lipgloss.ColorProfile()
+	t.Cleanup(func() {
+		plain = false
+		lipgloss.SetColorProfile(profile)
+	})
+	SetPlain()
+	if !Plain() {
+		t.Error("expected plain output after SetPlain")
+	}
+	if got := lipgloss.NewStyle().Bold(true).Render("text"); got != "text" {
+		t.Errorf("expected unstyled text, got %q", got)
+	}
+}
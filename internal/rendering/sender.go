@@ -1,9 +1,26 @@
 package rendering
 
+// Sender labels shown in views and exports, set from ui.sender_labels
+var (
+	HumanLabel     = "You"
+	AssistantLabel = "Claude"
+)
+
+// SetSenderLabels changes the sender labels, keeping the current one for
+// any left empty
+func SetSenderLabels(human, assistant string) {
+	if human != "" {
+		HumanLabel = human
+	}
+	if assistant != "" {
+		AssistantLabel = assistant
+	}
+}
+
 // FormatSender returns a user-friendly display name for message senders
 func FormatSender(sender string) string {
 	if sender == "human" {
-		return "You"
+		return HumanLabel
 	}
-	return "Claude"
+	return AssistantLabel
 }
@@ -70,13 +70,22 @@ func DetectTerminalCapabilities() *TerminalCapabilities {
 }
 
 // IsHyperlinksSupported returns true if the terminal supports OSC 8 hyperlinks
+// and output isn't plain
 func IsHyperlinksSupported() bool {
-	return DetectTerminalCapabilities().SupportsHyperlinks
+	return !Plain() && DetectTerminalCapabilities().SupportsHyperlinks
 }
 
 // IsGraphicsSupported returns true if the terminal supports graphics protocols
+// and output isn't plain
 func IsGraphicsSupported() bool {
-	return DetectTerminalCapabilities().SupportsGraphics
+	return !Plain() && DetectTerminalCapabilities().SupportsGraphics
+}
+
+// IsKittyGraphicsSupported returns true if the terminal supports the Kitty
+// graphics protocol. iTerm2 has its own image protocol instead.
+func IsKittyGraphicsSupported() bool {
+	caps := DetectTerminalCapabilities()
+	return !Plain() && caps.SupportsGraphics && caps.TerminalType != "iTerm.app"
 }
 
 // GetTerminalInfo returns human-readable terminal information
@@ -0,0 +1,106 @@
+package rendering
+
+import (
+	"strings"
+	"time"
+)
+
+// sparkLevels are the bars of a sparkline, lowest first
+var sparkLevels = []rune("▁▂▃▄▅▆▇█")
+
+// timelineDate is the format of the dates either side of a timeline's
+// sparkline
+const timelineDate = "2006-01-02"
+
+// Timeline is the number of messages on each day of a conversation, for
+// showing how a conversation that went on for weeks was spread out. When
+// there are more days than columns, each column covers several days.
+type Timeline struct {
+	Start       time.Time // midnight at the start of the first day
+	DaysPerCell int
+	Counts      []int // messages in each column
+	first, last int64 // day numbers of the first and last days
+}
+
+// NewTimeline counts messages sent at times by day, fitting the timeline
+// with its dates in width columns. It returns nil unless the messages span
+// at least two days, since a single day has no timeline to show.
+func NewTimeline(times []time.Time, width int) *Timeline {
+	if len(times) == 0 {
+		return nil
+	}
+	first, last := dayNumber(times[0]), dayNumber(times[0])
+	start := times[0]
+	for _, t := range times[1:] {
+		day := dayNumber(t)
+		if day < first {
+			first, start = day, t
+		}
+		last = max(last, day)
+	}
+	days := int(last-first) + 1
+	if days < 2 {
+		return nil
+	}
+
+	cells := max(width-2*len(timelineDate+" "), len(sparkLevels))
+	perCell := (days + cells - 1) / cells
+	tl := &Timeline{
+		Start:       time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location()),
+		DaysPerCell: perCell,
+		Counts:      make([]int, (days+perCell-1)/perCell),
+		first:       first,
+		last:        last,
+	}
+	for _, t := range times {
+		tl.Counts[tl.Cell(t)]++
+	}
+	return tl
+}
+
+// dayNumber numbers the calendar day of t, in t's time zone, so that
+// consecutive days have consecutive numbers
+func dayNumber(t time.Time) int64 {
+	return time.Date(t.Year(), t.Month(), t.Day(), 12, 0, 0, 0, time.UTC).Unix() / (24 * 60 * 60)
+}
+
+// Cell returns the column covering time t
+func (tl *Timeline) Cell(t time.Time) int {
+	cell := int(dayNumber(t)-tl.first) / tl.DaysPerCell
+	return min(max(cell, 0), len(tl.Counts)-1)
+}
+
+// CellStart returns midnight at the start of the first day of column i
+func (tl *Timeline) CellStart(i int) time.Time {
+	return tl.Start.AddDate(0, 0, i*tl.DaysPerCell)
+}
+
+// End returns midnight at the start of the last day with messages
+func (tl *Timeline) End() time.Time {
+	return tl.Start.AddDate(0, 0, int(tl.last-tl.first))
+}
+
+// Sparkline draws the timeline as bars as high as each column's share of
+// the busiest column, leaving columns without messages blank
+func (tl *Timeline) Sparkline() string {
+	busiest := 0
+	for _, count := range tl.Counts {
+		busiest = max(busiest, count)
+	}
+
+	var b strings.Builder
+	for _, count := range tl.Counts {
+		if count == 0 {
+			b.WriteByte(' ')
+			continue
+		}
+		level := (count*len(sparkLevels)+busiest-1)/busiest - 1
+		b.WriteRune(sparkLevels[level])
+	}
+	return b.String()
+}
+
+// String draws the sparkline between the first and last days
+func (tl *Timeline) String() string {
+	return tl.Start.Format(timelineDate) + " " + tl.Sparkline() + " " + tl.End().Format(timelineDate)
+}
@@ -0,0 +1,44 @@
+package rendering
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeline(t *testing.T) {
+	day := func(d, hour int) time.Time {
+		return time.Date(2025, 3, d, hour, 0, 0, 0, time.UTC)
+	}
+	times := []time.Time{day(1, 9), day(1, 10), day(1, 23), day(1, 23), day(3, 0), day(4, 12)}
+
+	tl := NewTimeline(times, 60)
+	if tl == nil {
+		t.Fatal("expected a timeline for messages on several days")
+	}
+	if tl.DaysPerCell != 1 || len(tl.Counts) != 4 {
+		t.Fatalf("expected a column for each of 4 days, got %d of %d days", len(tl.Counts), tl.DaysPerCell)
+	}
+	if got := tl.Sparkline(); got != "█ ▂▂" {
+		t.Errorf("expected a bar for each day with messages, got %q", got)
+	}
+	if got := tl.String(); got != "2025-03-01 █ ▂▂ 2025-03-04" {
+		t.Errorf("unexpected ribbon %q", got)
+	}
+	if tl.Cell(day(3, 5)) != 2 || !tl.CellStart(2).Equal(day(3, 0)) {
+		t.Errorf("expected the third day in the third column, got %d from %s", tl.Cell(day(3, 5)), tl.CellStart(2))
+	}
+
+	// Days are grouped when there are more than fit
+	long := []time.Time{day(1, 0), day(31, 0)}
+	tl = NewTimeline(long, 30)
+	if tl.DaysPerCell != 4 || len(tl.Counts) != 8 || tl.Counts[0] != 1 || tl.Counts[7] != 1 {
+		t.Errorf("expected 31 days in 8 columns of 4, got %+v", tl)
+	}
+	if !tl.End().Equal(day(31, 0)) {
+		t.Errorf("expected the timeline to end on the last day, got %s", tl.End())
+	}
+
+	if NewTimeline([]time.Time{day(1, 0), day(1, 23)}, 60) != nil {
+		t.Error("expected no timeline for messages on one day")
+	}
+}
@@ -0,0 +1,168 @@
+package rendering
+
+import (
+	"strings"
+
+	"github.com/rivo/uniseg"
+	"golang.org/x/text/unicode/bidi"
+)
+
+// rlm is the right-to-left mark, which starts the continuation rows of a
+// right-to-left paragraph so terminals that lay out each row on its own keep
+// it right-to-left
+const rlm = "\u200f"
+
+// StringWidth returns the number of terminal columns s takes up, counting
+// wide characters such as CJK as two and an emoji sequence as one character
+func StringWidth(s string) int {
+	return uniseg.StringWidth(s)
+}
+
+// Wrap word wraps text to width terminal columns, keeping its line breaks.
+// Rows are broken where Unicode allows a line break, so words in any script
+// stay whole, and never inside a character such as a combined emoji. Spaces
+// at the ends of wrapped rows are dropped.
+func Wrap(text string, width int) string {
+	if width <= 0 {
+		return text
+	}
+
+	lines := strings.Split(text, "\n")
+	var result []string
+	for _, line := range lines {
+		rows := WrapLine(line, width)
+		for i, row := range rows {
+			if i < len(rows)-1 {
+				row = strings.TrimRight(row, " ")
+			}
+			result = append(result, row)
+		}
+	}
+	return strings.Join(result, "\n")
+}
+
+// WrapLine splits a line into rows of at most width terminal columns,
+// breaking after the last place Unicode allows a line break that fits, or
+// between characters when a word is wider than a row. Nothing is dropped, so
+// the rows of a left-to-right line join back into it; continuation rows of a
+// right-to-left line are marked as right-to-left.
+func WrapLine(line string, width int) []string {
+	if width <= 0 || StringWidth(line) <= width {
+		return []string{line}
+	}
+
+	var rows []string
+	var row, word strings.Builder
+	rowWidth := 0
+
+	// placeWord adds the word to the row, starting a new row if it doesn't
+	// fit. Spaces at the end of the word may hang past the edge.
+	placeWord := func() {
+		w := word.String()
+		word.Reset()
+		if rowWidth > 0 && rowWidth+StringWidth(strings.TrimRight(w, " ")) > width {
+			rows = append(rows, row.String())
+			row.Reset()
+			rowWidth = 0
+		}
+		for i, piece := range splitWide(w, width) {
+			if i > 0 {
+				rows = append(rows, row.String())
+				row.Reset()
+				rowWidth = 0
+			}
+			row.WriteString(piece)
+			rowWidth += StringWidth(piece)
+		}
+	}
+
+	state := -1
+	rest := line
+	for rest != "" {
+		var cluster string
+		var boundaries int
+		cluster, rest, boundaries, state = uniseg.StepString(rest, state)
+		word.WriteString(cluster)
+		if boundaries&uniseg.MaskLine != uniseg.LineDontBreak || rest == "" {
+			placeWord()
+		}
+	}
+	if row.Len() > 0 {
+		rows = append(rows, row.String())
+	}
+
+	if isRightToLeft(line) {
+		for i := 1; i < len(rows); i++ {
+			if !isRightToLeft(rows[i]) {
+				rows[i] = rlm + rows[i]
+			}
+		}
+	}
+	return rows
+}
+
+// splitWide breaks a word wider than a row into pieces that fit. Words that
+// fit are returned whole.
+func splitWide(word string, width int) []string {
+	if StringWidth(strings.TrimRight(word, " ")) <= width {
+		return []string{word}
+	}
+
+	var pieces []string
+	var piece strings.Builder
+	pieceWidth := 0
+	state := -1
+	rest := word
+	for rest != "" {
+		var cluster string
+		var clusterWidth int
+		cluster, rest, clusterWidth, state = uniseg.FirstGraphemeClusterInString(rest, state)
+		if pieceWidth > 0 && pieceWidth+clusterWidth > width && cluster != " " {
+			pieces = append(pieces, piece.String())
+			piece.Reset()
+			pieceWidth = 0
+		}
+		piece.WriteString(cluster)
+		pieceWidth += clusterWidth
+	}
+	return append(pieces, piece.String())
+}
+
+// isRightToLeft reports whether the first strongly directional character of
+// s is right-to-left, which sets the direction of the paragraph it starts
+func isRightToLeft(s string) bool {
+	for _, r := range s {
+		props, _ := bidi.LookupRune(r)
+		switch props.Class() {
+		case bidi.R, bidi.AL:
+			return true
+		case bidi.L:
+			return false
+		}
+	}
+	return false
+}
+
+// TruncateWidth shortens s to at most width terminal columns, ending it with
+// "..." if anything was cut. Characters are never split.
+func TruncateWidth(s string, width int) string {
+	if StringWidth(s) <= width {
+		return s
+	}
+	limit := width - 3
+	var b strings.Builder
+	used := 0
+	state := -1
+	rest := s
+	for rest != "" {
+		var cluster string
+		var clusterWidth int
+		cluster, rest, clusterWidth, state = uniseg.FirstGraphemeClusterInString(rest, state)
+		if used+clusterWidth > limit {
+			break
+		}
+		b.WriteString(cluster)
+		used += clusterWidth
+	}
+	return b.String() + "..."
+}
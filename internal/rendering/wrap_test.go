@@ -0,0 +1,61 @@
+package rendering
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWrapLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		width    int
+		expected []string
+	}{
+		{"fits", "short line", 20, []string{"short line"}},
+		{"at spaces", "the quick brown fox", 10, []string{"the quick ", "brown fox"}},
+		{"long word", "abcdefghijkl", 5, []string{"abcde", "fghij", "kl"}},
+		{"wide characters", "日本語のテキスト", 6, []string{"日本語", "のテキ", "スト"}},
+		// A family emoji is one character two columns wide
+		{"emoji sequence", "hi 👨‍👩‍👧 there", 4, []string{"hi ", "👨‍👩‍👧 ", "ther", "e"}},
+		{"combining marks", "café café", 5, []string{"café ", "café"}},
+		{"hebrew", "שלום עולם יפה", 9, []string{"שלום עולם ", "יפה"}},
+		// The continuation of a right-to-left paragraph starting with a
+		// left-to-right word keeps the paragraph's direction
+		{"rtl paragraph", "مرحبا بالعالم Go rocks", 14, []string{"مرحبا بالعالم ", rlm + "Go rocks"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows := WrapLine(tt.line, tt.width)
+			if strings.Join(rows, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("expected %q, got %q", tt.expected, rows)
+			}
+			for _, row := range rows {
+				if w := StringWidth(strings.TrimRight(row, " ")); w > tt.width {
+					t.Errorf("row %q is %d columns wide", row, w)
+				}
+			}
+		})
+	}
+}
+
+func TestWrap(t *testing.T) {
+	text := "first paragraph wraps here\n\nsecond"
+	expected := "first\nparagraph\nwraps here\n\nsecond"
+	if got := Wrap(text, 10); got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+	if got := Wrap(text, 0); got != text {
+		t.Errorf("expected no wrapping without a width, got %q", got)
+	}
+}
+
+func TestTruncateWidth(t *testing.T) {
+	if got := TruncateWidth("日本語のテキスト", 9); got != "日本語..." {
+		t.Errorf("expected the cut to fall between characters, got %q", got)
+	}
+	if got := TruncateWidth("short", 9); got != "short" {
+		t.Errorf("expected short text untouched, got %q", got)
+	}
+}
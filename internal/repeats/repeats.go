@@ -0,0 +1,164 @@
+// Package repeats finds content repeated within a conversation, like pasted
+// input the assistant quotes back, so long sessions can be shown without it
+package repeats
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+// DefaultMinLines is the shortest repeat, in lines, worth collapsing when no
+// other size is given
+const DefaultMinLines = 5
+
+// shingleLines is how many lines in a row must match an earlier message for
+// them to count as repeated. Single lines like "}" or "return nil" repeat all
+// the time; runs of them rarely do by chance.
+const shingleLines = 3
+
+// Result is what collapsing the repeats of a conversation did
+type Result struct {
+	Blocks int // repeated blocks collapsed
+	Lines  int // lines they took up
+}
+
+// Placeholder is what a repeated block of n lines is collapsed into
+func Placeholder(n int) string {
+	return fmt.Sprintf("[repeated content, %d lines]", n)
+}
+
+// Collapse finds blocks of at least minLines lines that repeat content from
+// earlier messages of the conversation, like a pasted log the assistant
+// quotes back, and replaces each with a placeholder. Lines are compared
+// ignoring case, spacing and quote markers, so a block quoted with "> " or
+// reindented still counts. The first occurrence is always kept. Code fences
+// and artifact tags are never collapsed, so what's left still renders.
+//
+// The messages returned are copies where something was collapsed and the
+// originals otherwise.
+func Collapse(messages []*models.Message, minLines int) ([]*models.Message, Result) {
+	if minLines <= 0 {
+		minLines = DefaultMinLines
+	}
+	size := min(shingleLines, minLines)
+
+	var result Result
+	seen := make(map[string]bool)
+	collapsed := make([]*models.Message, len(messages))
+	for i, msg := range messages {
+		lines := strings.Split(msg.Text, "\n")
+		shingles := lineShingles(lines, size)
+
+		repeated := make([]bool, len(lines))
+		for _, shingle := range shingles {
+			if seen[shingle.key] {
+				for _, line := range shingle.lines {
+					repeated[line] = true
+				}
+			}
+		}
+		// Only now, so repeats within a message are left alone
+		for _, shingle := range shingles {
+			seen[shingle.key] = true
+		}
+
+		text, blocks, n := collapseRuns(lines, repeated, minLines)
+		if blocks == 0 {
+			collapsed[i] = msg
+			continue
+		}
+		cp := *msg
+		cp.Text = text
+		collapsed[i] = &cp
+		result.Blocks += blocks
+		result.Lines += n
+	}
+	return collapsed, result
+}
+
+// shingle is a run of consecutive content lines and the key they match by
+type shingle struct {
+	key   string
+	lines []int
+}
+
+// lineShingles returns every run of size content lines of a message, skipping
+// blank lines and not reaching across fences or artifact tags
+func lineShingles(lines []string, size int) []shingle {
+	var shingles []shingle
+	var window []int // indexes of the content lines since the last boundary
+	var keys []string
+	for i, line := range lines {
+		if isBoundary(line) {
+			window, keys = nil, nil
+			continue
+		}
+		key := normalize(line)
+		if key == "" {
+			continue
+		}
+		window = append(window, i)
+		keys = append(keys, key)
+		if len(window) >= size {
+			shingles = append(shingles, shingle{
+				key:   strings.Join(keys[len(keys)-size:], "\n"),
+				lines: window[len(window)-size:],
+			})
+		}
+	}
+	return shingles
+}
+
+// collapseRuns replaces each run of repeated lines, with any blank lines
+// between them, that has at least minLines repeated lines with a placeholder.
+// It returns the new text, and how many runs and lines it collapsed.
+func collapseRuns(lines []string, repeated []bool, minLines int) (string, int, int) {
+	var out []string
+	blocks, collapsedLines := 0, 0
+	for i := 0; i < len(lines); {
+		if !repeated[i] {
+			out = append(out, lines[i])
+			i++
+			continue
+		}
+		// Extend the run over repeated and blank lines, ending it on the
+		// last repeated one
+		end, count := i, 0
+		for j := i; j < len(lines) && (repeated[j] || normalize(lines[j]) == "" && !isBoundary(lines[j])); j++ {
+			if repeated[j] {
+				end = j
+				count++
+			}
+		}
+		if count < minLines {
+			out = append(out, lines[i:end+1]...)
+		} else {
+			n := end - i + 1
+			out = append(out, Placeholder(n))
+			blocks++
+			collapsedLines += n
+		}
+		i = end + 1
+	}
+	return strings.Join(out, "\n"), blocks, collapsedLines
+}
+
+// isBoundary reports whether a line must stay for the message to render: a
+// code fence or an artifact tag
+func isBoundary(line string) bool {
+	line = strings.TrimSpace(line)
+	return strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~") ||
+		strings.HasPrefix(line, "<antArtifact") || strings.HasPrefix(line, "</antArtifact")
+}
+
+// normalize returns the form of a line repeats are compared in: without quote
+// markers, in lower case and with runs of spaces made one
+func normalize(line string) string {
+	line = strings.TrimSpace(line)
+	for strings.HasPrefix(line, ">") {
+		line = strings.TrimSpace(line[1:])
+	}
+	return strings.ToLower(strings.Join(strings.Fields(line), " "))
+}
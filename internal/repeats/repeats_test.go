@@ -0,0 +1,64 @@
+package repeats
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+func TestCollapse(t *testing.T) {
+	pasted := []string{
+		"panic: runtime error: index out of range [3] with length 3",
+		"",
+		"goroutine 1 [running]:",
+		"main.parse(...)",
+		"        /src/main.go:42",
+		"main.main()",
+		"        /src/main.go:17 +0x1d",
+	}
+	quoted := make([]string, len(pasted))
+	for i, line := range pasted {
+		quoted[i] = "> " + strings.ToUpper(line)
+	}
+
+	messages := []*models.Message{
+		{ID: 1, Sender: "human", Text: "Why does this crash?\n\n" + strings.Join(pasted, "\n")},
+		{ID: 2, Sender: "assistant", Text: "Looking at the trace:\n\n" + strings.Join(quoted, "\n") +
+			"\n\nThe slice has three elements.\n```go\nmain.main()\n```"},
+		{ID: 3, Sender: "human", Text: "Thanks"},
+	}
+
+	collapsed, result := Collapse(messages, 5)
+	if collapsed[0] != messages[0] || collapsed[2] != messages[2] {
+		t.Error("expected messages without repeats to be left as they are")
+	}
+	want := "Looking at the trace:\n\n[repeated content, 7 lines]\n\nThe slice has three elements.\n```go\nmain.main()\n```"
+	if collapsed[1].Text != want {
+		t.Errorf("unexpected collapsed text:\n%s", collapsed[1].Text)
+	}
+	if messages[1].Text == want {
+		t.Error("expected the original message to be unchanged")
+	}
+	if result.Blocks != 1 || result.Lines != 7 {
+		t.Errorf("expected 1 block of 7 lines, got %+v", result)
+	}
+
+	// Repeats shorter than minLines stay
+	if _, result := Collapse(messages, 8); result.Blocks != 0 {
+		t.Errorf("expected nothing collapsed with 8 lines, got %+v", result)
+	}
+}
+
+func TestCollapseKeepsFences(t *testing.T) {
+	code := "```\nline one\nline two\nline three\nline four\n```"
+	messages := []*models.Message{
+		{ID: 1, Text: code},
+		{ID: 2, Text: "Again:\n" + code},
+	}
+	collapsed, _ := Collapse(messages, 3)
+	want := "Again:\n```\n[repeated content, 4 lines]\n```"
+	if collapsed[1].Text != want {
+		t.Errorf("expected the fences to stay, got:\n%s", collapsed[1].Text)
+	}
+}
@@ -0,0 +1,53 @@
+// Package schema publishes JSON Schemas for the JSON output of shannon
+// commands, so scripts can validate what they consume and detect breaking
+// changes through the schema_version field every payload carries.
+package schema
+
+import (
+	"embed"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Version is the compatibility version of the JSON outputs, written to each
+// payload as schema_version. It changes only when a field is removed,
+// renamed or changes type; new fields don't change it.
+const Version = 1
+
+// Field is the key every JSON output carries the version under
+const Field = "schema_version"
+
+//go:embed *.schema.json
+var files embed.FS
+
+// Names lists the commands with a published schema
+func Names() []string {
+	entries, _ := files.ReadDir(".")
+	var names []string
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), ".schema.json"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get returns the schema for a command's JSON output
+func Get(name string) ([]byte, error) {
+	data, err := files.ReadFile(name + ".schema.json")
+	if err != nil {
+		return nil, fmt.Errorf("no schema for %q (available: %s)", name, strings.Join(Names(), ", "))
+	}
+	return data, nil
+}
+
+// Write writes the schema for a command's JSON output to w
+func Write(w io.Writer, name string) error {
+	data, err := Get(name)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
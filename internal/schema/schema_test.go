@@ -0,0 +1,96 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+type document struct {
+	Properties map[string]struct {
+		Const *int `json:"const"`
+	} `json:"properties"`
+	Defs map[string]struct {
+		Required   []string                   `json:"required"`
+		Properties map[string]json.RawMessage `json:"properties"`
+	} `json:"$defs"`
+}
+
+func load(t *testing.T, name string) document {
+	t.Helper()
+	data, err := Get(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("%s schema is not valid JSON: %v", name, err)
+	}
+	return doc
+}
+
+// jsonKeys returns the keys v marshals to
+func jsonKeys(t *testing.T, v interface{}) []string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestSchemasDeclareVersion(t *testing.T) {
+	if want := []string{"export", "list", "search", "stats"}; !reflect.DeepEqual(Names(), want) {
+		t.Errorf("expected schemas %v, got %v", want, Names())
+	}
+	for _, name := range Names() {
+		version := load(t, name).Properties[Field].Const
+		if version == nil || *version != Version {
+			t.Errorf("%s schema should require %s %d", name, Field, Version)
+		}
+	}
+
+	if _, err := Get("missing"); err == nil {
+		t.Error("expected an error for an unknown schema")
+	}
+}
+
+// Search results and messages are encoded straight from the models, so a
+// renamed field would silently change the output
+func TestSchemasMatchModels(t *testing.T) {
+	tests := []struct {
+		schema string
+		def    string
+		value  interface{}
+	}{
+		{"search", "result", models.SearchResult{}},
+		{"export", "message", models.Message{}},
+	}
+	for _, tt := range tests {
+		def := load(t, tt.schema).Defs[tt.def]
+		keys := jsonKeys(t, tt.value)
+
+		required := append([]string(nil), def.Required...)
+		sort.Strings(required)
+		if !reflect.DeepEqual(keys, required) {
+			t.Errorf("%s schema %s requires %v, but the output has %v", tt.schema, tt.def, required, keys)
+		}
+		for _, key := range keys {
+			if _, ok := def.Properties[key]; !ok {
+				t.Errorf("%s schema %s doesn't describe %s", tt.schema, tt.def, key)
+			}
+		}
+	}
+}
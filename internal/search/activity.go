@@ -0,0 +1,53 @@
+package search
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// MessageActivity counts the messages written on each day from from up to
+// but excluding to, keyed by date (2006-01-02) in from's time zone. An empty
+// sender counts both senders.
+func (e *Engine) MessageActivity(from, to time.Time, sender string) (map[string]int, error) {
+	// Count by UTC hour in SQL so days can be cut in any time zone
+	query := `
+		SELECT substr(created_at, 1, 13) AS hour, COUNT(*)
+		FROM messages
+		WHERE created_at >= ? AND created_at < ? AND ` + notTrashed
+	args := []interface{}{
+		from.UTC().Format("2006-01-02 15:04:05"),
+		to.UTC().Format("2006-01-02 15:04:05"),
+	}
+	if sender != "" {
+		query += " AND sender = ?"
+		args = append(args, sender)
+	}
+	query += " GROUP BY hour"
+
+	rows, err := e.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count messages: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close rows: %v\n", err)
+		}
+	}()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var hour string
+		var count int
+		if err := rows.Scan(&hour, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan message count: %w", err)
+		}
+		t, err := time.ParseInLocation("2006-01-02 15", hour, time.UTC)
+		if err != nil {
+			continue
+		}
+		counts[t.In(from.Location()).Format("2006-01-02")] += count
+	}
+
+	return counts, rows.Err()
+}
@@ -0,0 +1,292 @@
+package search
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// slugWords is the number of title words kept in a slug
+const slugWords = 5
+
+// Slug derives a conversation's slug from its title and the month it was
+// created, such as "python-pandas-cleanup-2024-05"
+func Slug(name string, created time.Time) string {
+	var words []string
+	var word strings.Builder
+	for _, r := range strings.ToLower(name) + " " {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word.WriteRune(r)
+		case r == '\'' || r == '’':
+			// Keep contractions in one word
+		case word.Len() > 0:
+			words = append(words, word.String())
+			word.Reset()
+		}
+	}
+	if len(words) > slugWords {
+		words = words[:slugWords]
+	}
+	if len(words) == 0 {
+		words = []string{"conversation"}
+	}
+	return strings.Join(words, "-") + "-" + created.UTC().Format("2006-01")
+}
+
+// ValidateAlias checks that alias can name a conversation: letters, digits,
+// dashes, dots and underscores, and not only digits, which would read as an ID
+func ValidateAlias(alias string) error {
+	if alias == "" {
+		return fmt.Errorf("alias is empty")
+	}
+	digits := true
+	for _, r := range alias {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '.' && r != '_' {
+			return fmt.Errorf("invalid alias %q: use letters, digits, '-', '.' and '_'", alias)
+		}
+		digits = digits && unicode.IsDigit(r)
+	}
+	if digits {
+		return fmt.Errorf("invalid alias %q: it would be read as a conversation ID", alias)
+	}
+	return nil
+}
+
+// EnsureSlugs gives the conversations without a slug one. A slug already
+// taken by another conversation or an alias gets a numeric suffix; since
+// conversations are named in ID order, the same names get the same slugs.
+func (e *Engine) EnsureSlugs() error {
+	var count int
+	if err := e.db.QueryRow("SELECT COUNT(*) FROM conversations WHERE slug IS NULL").Scan(&count); err != nil {
+		return fmt.Errorf("failed to count conversations without slugs: %w", err)
+	}
+	if count == 0 {
+		return nil
+	}
+
+	taken := make(map[string]bool)
+	if err := e.collect(`
+		SELECT slug FROM conversations WHERE slug IS NOT NULL
+		UNION SELECT alias FROM conversation_aliases
+	`, func(rows *sql.Rows) error {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		taken[name] = true
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to load slugs: %w", err)
+	}
+
+	type pending struct {
+		id      int64
+		name    string
+		created time.Time
+	}
+	var conversations []pending
+	if err := e.collect("SELECT id, name, created_at FROM conversations WHERE slug IS NULL ORDER BY id", func(rows *sql.Rows) error {
+		var c pending
+		if err := rows.Scan(&c.id, &c.name, &c.created); err != nil {
+			return err
+		}
+		conversations = append(conversations, c)
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to load conversations: %w", err)
+	}
+
+	tx, err := e.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, c := range conversations {
+		base := Slug(c.name, c.created)
+		slug := base
+		for n := 2; taken[slug]; n++ {
+			slug = fmt.Sprintf("%s-%d", base, n)
+		}
+		taken[slug] = true
+		if _, err := tx.Exec("UPDATE conversations SET slug = ? WHERE id = ?", slug, c.id); err != nil {
+			return fmt.Errorf("failed to save slug: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to save slugs: %w", err)
+	}
+	return nil
+}
+
+// ResolveConversation returns the ID of the conversation ref names: an ID, a
+// slug or an alias
+func (e *Engine) ResolveConversation(ref string) (int64, error) {
+	if id, err := strconv.ParseInt(ref, 10, 64); err == nil {
+		return id, nil
+	}
+
+	lookup := func() (int64, error) {
+		var id int64
+		err := e.db.QueryRow(`
+			SELECT conversation_id FROM conversation_aliases WHERE alias = ?
+			UNION ALL
+			SELECT id FROM conversations WHERE slug = ?
+			LIMIT 1
+		`, ref, ref).Scan(&id)
+		return id, err
+	}
+
+	id, err := lookup()
+	if err == sql.ErrNoRows {
+		// Conversations imported since slugs were last generated have none yet
+		if err := e.EnsureSlugs(); err != nil {
+			return 0, err
+		}
+		id, err = lookup()
+	}
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("no conversation with ID, slug or alias %q", ref)
+	} else if err != nil {
+		return 0, fmt.Errorf("failed to look up conversation %q: %w", ref, err)
+	}
+	return id, nil
+}
+
+// SetAlias names a conversation with alias, in addition to its ID and slug
+func (e *Engine) SetAlias(conversationID int64, alias string) error {
+	if err := ValidateAlias(alias); err != nil {
+		return err
+	}
+	// Generate pending slugs first so none of them collides with the alias
+	if err := e.EnsureSlugs(); err != nil {
+		return err
+	}
+
+	var exists int
+	if err := e.db.QueryRow("SELECT COUNT(*) FROM conversations WHERE id = ?", conversationID).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to look up conversation: %w", err)
+	}
+	if exists == 0 {
+		return fmt.Errorf("conversation %d not found", conversationID)
+	}
+
+	var owner int64
+	err := e.db.QueryRow(`
+		SELECT conversation_id FROM conversation_aliases WHERE alias = ?
+		UNION ALL
+		SELECT id FROM conversations WHERE slug = ?
+		LIMIT 1
+	`, alias, alias).Scan(&owner)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return fmt.Errorf("failed to check alias: %w", err)
+	case owner == conversationID:
+		return nil
+	default:
+		return fmt.Errorf("%q already names conversation %d", alias, owner)
+	}
+
+	if _, err := e.db.Exec("INSERT INTO conversation_aliases (alias, conversation_id) VALUES (?, ?)", alias, conversationID); err != nil {
+		return fmt.Errorf("failed to save alias: %w", err)
+	}
+	return nil
+}
+
+// RemoveAlias removes an alias
+func (e *Engine) RemoveAlias(alias string) error {
+	result, err := e.db.Exec("DELETE FROM conversation_aliases WHERE alias = ?", alias)
+	if err != nil {
+		return fmt.Errorf("failed to remove alias: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("no alias %q", alias)
+	}
+	return nil
+}
+
+// ConversationNames are the names a conversation can be referred to by
+type ConversationNames struct {
+	ID      int64
+	Title   string
+	Slug    string
+	Aliases []string
+}
+
+// GetConversationNames returns the slug and aliases of every conversation,
+// or only of those with an alias, in ID order
+func (e *Engine) GetConversationNames(onlyAliased bool) ([]*ConversationNames, error) {
+	if err := e.EnsureSlugs(); err != nil {
+		return nil, err
+	}
+
+	where := ""
+	if onlyAliased {
+		where = "WHERE a.alias IS NOT NULL"
+	}
+	var names []*ConversationNames
+	err := e.collect(`
+		SELECT c.id, c.name, c.slug, a.alias
+		FROM conversations c
+		LEFT JOIN conversation_aliases a ON a.conversation_id = c.id
+		`+where+`
+		ORDER BY c.id, a.alias
+	`, func(rows *sql.Rows) error {
+		var n ConversationNames
+		var alias sql.NullString
+		if err := rows.Scan(&n.ID, &n.Title, &n.Slug, &alias); err != nil {
+			return err
+		}
+		if len(names) == 0 || names[len(names)-1].ID != n.ID {
+			names = append(names, &n)
+		}
+		if alias.Valid {
+			last := names[len(names)-1]
+			last.Aliases = append(last.Aliases, alias.String)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load conversation names: %w", err)
+	}
+	return names, nil
+}
+
+// GetSlug returns the slug of a conversation
+func (e *Engine) GetSlug(conversationID int64) (string, error) {
+	if err := e.EnsureSlugs(); err != nil {
+		return "", err
+	}
+	var slug string
+	if err := e.db.QueryRow("SELECT slug FROM conversations WHERE id = ?", conversationID).Scan(&slug); err != nil {
+		return "", fmt.Errorf("failed to get slug: %w", err)
+	}
+	return slug, nil
+}
+
+// collect runs query with args and passes each row to scan
+func (e *Engine) collect(query string, scan func(rows *sql.Rows) error, args ...interface{}) error {
+	rows, err := e.db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close rows: %v\n", err)
+		}
+	}()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
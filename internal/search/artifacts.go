@@ -16,12 +16,30 @@ type ArtifactSearchResult struct {
 	Snippet      string
 }
 
+// SetCodeBlockArtifacts makes GetConversationArtifacts and SearchArtifacts
+// also return fenced code blocks of at least minLines lines as artifacts;
+// 0 leaves them out
+func (e *Engine) SetCodeBlockArtifacts(minLines int) {
+	e.codeBlockLines = minLines
+}
+
+// extractor returns an artifact extractor that takes code blocks for
+// artifacts if the engine is set to
+func (e *Engine) extractor() *artifacts.Extractor {
+	extractor := artifacts.NewExtractor()
+	extractor.CodeBlockLines = e.codeBlockLines
+	return extractor
+}
+
 // SearchArtifacts searches for artifacts containing the query
 func (e *Engine) SearchArtifacts(opts SearchOptions) ([]*ArtifactSearchResult, error) {
 	// First, find messages that might contain artifacts
-	// We'll search for messages containing "antArtifact" tag
+	// We'll search for messages containing "antArtifact" tag, or any message
+	// matching the query when code blocks count as artifacts
 	artifactOpts := opts
-	if artifactOpts.Query != "" {
+	if e.codeBlockLines > 0 && artifactOpts.Query != "" {
+		artifactOpts.Sender = "assistant"
+	} else if artifactOpts.Query != "" {
 		// Combine artifact tag search with user query
 		artifactOpts.Query = fmt.Sprintf(`antArtifact AND (%s)`, opts.Query)
 	} else {
@@ -35,7 +53,7 @@ func (e *Engine) SearchArtifacts(opts SearchOptions) ([]*ArtifactSearchResult, e
 	}
 
 	// Extract artifacts from found messages
-	extractor := artifacts.NewExtractor()
+	extractor := e.extractor()
 	var results []*ArtifactSearchResult
 
 	for _, sr := range searchResults {
@@ -83,7 +101,7 @@ func (e *Engine) GetConversationArtifacts(conversationID int64) ([]*artifacts.Ar
 		return nil, fmt.Errorf("failed to get conversation: %w", err)
 	}
 
-	extractor := artifacts.NewExtractor()
+	extractor := e.extractor()
 	var allArtifacts []*artifacts.Artifact
 
 	for _, msg := range messages {
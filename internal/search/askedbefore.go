@@ -0,0 +1,213 @@
+package search
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// PriorQuestion is an earlier human message asking much the same thing as a
+// new question, with the start of the answer it got
+type PriorQuestion struct {
+	ConversationID   int64
+	ConversationName string
+	MessageID        int64
+	Question         string
+	Answer           string // the reply to the question, or empty if there was none
+	AskedAt          time.Time
+	Similarity       float64 // from 0 to 1
+}
+
+// MinQuestionSimilarity is the similarity below which AskedBefore leaves
+// earlier questions out
+const MinQuestionSimilarity = 0.3
+
+// questionCandidates is how many full-text matches AskedBefore scores
+const questionCandidates = 200
+
+// questionWords are how many words of an earlier message count as its
+// question, so a short question before a long paste isn't drowned out by it
+const questionWords = 60
+
+// questionStopwords are the words of a question that say how it's asked
+// rather than what about
+var questionStopwords = map[string]bool{}
+
+func init() {
+	for _, word := range strings.Fields(`
+		a an and any are as at be best but by can could do does for from get go
+		have how i i'd i'm if in into is it its me my need of on one or should so
+		some than that the their then there these this to up us use using via want
+		was way we what when where which while who why will with would you your
+		tell show explain help please possible right correct good better`) {
+		questionStopwords[word] = true
+	}
+}
+
+// AskedBefore finds earlier human messages asking much the same as question,
+// the most similar in each conversation, most similar first. Candidates come
+// from the stemmed full-text index, matching any of the question's words;
+// they are scored by how many of its words they share, both as a share of
+// the question and of the start of the earlier message.
+func (e *Engine) AskedBefore(ctx context.Context, question string, limit int) ([]*PriorQuestion, error) {
+	terms := e.questionTerms(question)
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("the question has no words to look for")
+	}
+
+	// Terms are lowercase letters and digits, which FTS5 takes as they are
+	alternatives := make([]string, len(terms))
+	for i, term := range terms {
+		alternatives[i] = e.dictionary.expand(term)
+	}
+	rows, err := e.db.QueryContext(ctx, `
+		SELECT m.id, m.conversation_id, c.name, message_text(m.text), m.created_at
+		FROM messages_fts
+		JOIN messages m ON messages_fts.rowid = m.id
+		JOIN conversations c ON m.conversation_id = c.id
+		WHERE messages_fts MATCH ? AND m.sender = 'human'
+		ORDER BY rank
+		LIMIT ?
+	`, strings.Join(alternatives, " OR "), questionCandidates)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, queryError(question, err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close rows: %v\n", err)
+		}
+	}()
+
+	wanted := stems(terms)
+	best := make(map[int64]*PriorQuestion)
+	for rows.Next() {
+		var q PriorQuestion
+		if err := rows.Scan(&q.MessageID, &q.ConversationID, &q.ConversationName, &q.Question, &q.AskedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan question: %w", err)
+		}
+		q.Similarity = questionSimilarity(wanted, stems(e.questionTerms(q.Question)))
+		if q.Similarity < MinQuestionSimilarity {
+			continue
+		}
+		if prior, ok := best[q.ConversationID]; !ok || q.Similarity > prior.Similarity {
+			best[q.ConversationID] = &q
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	found := make([]*PriorQuestion, 0, len(best))
+	for _, q := range best {
+		found = append(found, q)
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].Similarity != found[j].Similarity {
+			return found[i].Similarity > found[j].Similarity
+		}
+		return found[i].AskedAt.After(found[j].AskedAt)
+	})
+	if limit > 0 && len(found) > limit {
+		found = found[:limit]
+	}
+
+	for _, q := range found {
+		if q.Answer, err = e.answerTo(q); err != nil {
+			return nil, err
+		}
+	}
+	return found, nil
+}
+
+// answerTo returns the reply to a question: the assistant message answering
+// it on its branch, or for exports without parent links the next one after it
+func (e *Engine) answerTo(q *PriorQuestion) (string, error) {
+	var answer string
+	err := e.db.QueryRow(`
+		SELECT message_text(text) FROM messages
+		WHERE conversation_id = ?1 AND sender = 'assistant'
+			AND (parent_id = ?2 OR (parent_id IS NULL AND id > ?2))
+		ORDER BY parent_id IS NULL, id
+		LIMIT 1
+	`, q.ConversationID, q.MessageID).Scan(&answer)
+	if err != nil && err != sql.ErrNoRows {
+		return "", fmt.Errorf("failed to get answer: %w", err)
+	}
+	return answer, nil
+}
+
+// questionTerms returns the distinct words of the start of a question that
+// say what it's about, lowercased
+func (e *Engine) questionTerms(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) > questionWords {
+		words = words[:questionWords]
+	}
+	var terms []string
+	seen := make(map[string]bool)
+	for _, word := range words {
+		if len(word) < 2 || questionStopwords[word] || seen[word] {
+			continue
+		}
+		if e.dictionary != nil && e.dictionary.stopwords[word] {
+			continue
+		}
+		seen[word] = true
+		terms = append(terms, word)
+	}
+	return terms
+}
+
+// questionSimilarity scores how alike two questions' stemmed words are: the
+// share of the new question's words the earlier one has, tempered by the
+// share of the earlier one's words that are in the new question
+func questionSimilarity(wanted, have map[string]bool) float64 {
+	if len(wanted) == 0 || len(have) == 0 {
+		return 0
+	}
+	shared := 0
+	for stem := range wanted {
+		if have[stem] {
+			shared++
+		}
+	}
+	coverage := float64(shared) / float64(len(wanted))
+	precision := float64(shared) / float64(len(have))
+	return coverage * math.Sqrt(precision)
+}
+
+// stems returns the set of stems of words
+func stems(words []string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, word := range words {
+		set[stem(word)] = true
+	}
+	return set
+}
+
+// stem strips common English suffixes, so "profiling" and "profile" or
+// "goroutines" and "goroutine" compare equal. It is much cruder than the
+// Porter stemmer of the full-text index, which only ranks candidates.
+func stem(word string) string {
+	for _, suffix := range []string{"ing", "ies", "es", "ed", "ly", "er", "s", "e"} {
+		if strings.HasSuffix(word, suffix) && len(word)-len(suffix) >= 3 {
+			word = strings.TrimSuffix(word, suffix)
+			if suffix == "ies" {
+				word += "y"
+			}
+			break
+		}
+	}
+	return strings.TrimSuffix(word, "e")
+}
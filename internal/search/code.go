@@ -0,0 +1,410 @@
+package search
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/neilberkman/shannon/internal/artifacts"
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+// codeIndexVersion is bumped whenever code block extraction changes in a way
+// that requires existing databases to be re-indexed
+const codeIndexVersion = "2"
+
+// CodeSearchOptions contains parameters for searching inside code blocks
+type CodeSearchOptions struct {
+	Query      string
+	Languages  []string // canonical language names; empty for all
+	Kinds      []string // artifacts.KindCodeBlock and/or artifacts.KindArtifact; empty for both
+	IgnoreCase bool
+	Limit      int
+}
+
+// CodeMatch is a single matching line inside a code block or artifact
+type CodeMatch struct {
+	ConversationID   int64  `json:"conversation_id"`
+	ConversationName string `json:"conversation_name"`
+	MessageID        int64  `json:"message_id"`
+	Line             int    `json:"line"`
+	Text             string `json:"text"`
+	Kind             string `json:"kind"`
+	Language         string `json:"language,omitempty"`
+	Title            string `json:"title,omitempty"`
+}
+
+// SearchCode finds lines containing the query inside fenced code blocks and
+// artifacts. Candidate blocks are narrowed to those containing the query
+// anywhere before individual lines are matched; the code FTS table can't do
+// this, as a query can start or end in the middle of one of its tokens.
+func (e *Engine) SearchCode(opts CodeSearchOptions) ([]*CodeMatch, error) {
+	if strings.TrimSpace(opts.Query) == "" {
+		return nil, fmt.Errorf("empty code search query")
+	}
+
+	query := `
+		SELECT cb.conversation_id, c.name, cb.message_id, cb.kind,
+		       COALESCE(cb.language, ''), COALESCE(cb.title, ''), cb.start_line, cb.content
+		FROM code_blocks cb
+		JOIN conversations c ON cb.conversation_id = c.id
+	`
+	conditions := []string{"c.deleted_at IS NULL", "NOT c.private"}
+	var args []interface{}
+
+	if condition, arg, ok := codeContains(opts); ok {
+		conditions = append(conditions, condition)
+		args = append(args, arg)
+	}
+
+	if len(opts.Languages) > 0 {
+		conditions = append(conditions, "cb.language IN ("+placeholders(len(opts.Languages))+")")
+		for _, lang := range opts.Languages {
+			args = append(args, lang)
+		}
+	}
+
+	if len(opts.Kinds) > 0 {
+		conditions = append(conditions, "cb.kind IN ("+placeholders(len(opts.Kinds))+")")
+		for _, kind := range opts.Kinds {
+			args = append(args, kind)
+		}
+	}
+
+	query += " WHERE " + strings.Join(conditions, " AND ")
+	query += " ORDER BY cb.conversation_id, cb.message_id, cb.start_line"
+
+	rows, err := e.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("code search query failed: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close rows: %v\n", err)
+		}
+	}()
+
+	needle := opts.Query
+	if opts.IgnoreCase {
+		needle = strings.ToLower(needle)
+	}
+
+	var matches []*CodeMatch
+	for rows.Next() {
+		var base CodeMatch
+		var startLine int
+		var content string
+		if err := rows.Scan(&base.ConversationID, &base.ConversationName, &base.MessageID,
+			&base.Kind, &base.Language, &base.Title, &startLine, &content); err != nil {
+			return nil, fmt.Errorf("failed to scan code block: %w", err)
+		}
+
+		for i, line := range strings.Split(content, "\n") {
+			haystack := line
+			if opts.IgnoreCase {
+				haystack = strings.ToLower(line)
+			}
+			if !strings.Contains(haystack, needle) {
+				continue
+			}
+
+			match := base
+			match.Line = startLine + i
+			match.Text = line
+			matches = append(matches, &match)
+
+			if opts.Limit > 0 && len(matches) >= opts.Limit {
+				return matches, nil
+			}
+		}
+	}
+
+	return matches, rows.Err()
+}
+
+// EnsureCodeIndex builds the code block index for databases that were
+// populated before it existed. It returns true if a rebuild was needed.
+func (e *Engine) EnsureCodeIndex() (bool, error) {
+	current, err := e.codeIndexCurrent()
+	if err != nil || current {
+		return false, err
+	}
+
+	if _, err := e.RebuildCodeIndex(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// codeIndexCurrent reports whether the code block index was built by this
+// version of the extractor
+func (e *Engine) codeIndexCurrent() (bool, error) {
+	var version string
+	err := e.db.QueryRow("SELECT value FROM metadata WHERE key = 'code_index_version'").Scan(&version)
+	if err != nil && err != sql.ErrNoRows {
+		return false, fmt.Errorf("failed to read code index version: %w", err)
+	}
+	return version == codeIndexVersion, nil
+}
+
+// ConversationArtifacts reads the artifacts of a conversation from the code
+// block index, keyed by message ID. It returns false if the index is out of
+// date, in which case callers should extract the artifacts themselves.
+func (e *Engine) ConversationArtifacts(conversationID int64) (map[int64][]*artifacts.Artifact, bool, error) {
+	current, err := e.codeIndexCurrent()
+	if err != nil || !current {
+		return nil, false, err
+	}
+
+	rows, err := e.db.Query(`
+		SELECT message_id, COALESCE(identifier, ''), COALESCE(artifact_type, ''),
+		       COALESCE(language, ''), COALESCE(title, ''), content
+		FROM code_blocks
+		WHERE conversation_id = ? AND kind = ?
+		ORDER BY message_id, start_line
+	`, conversationID, artifacts.KindArtifact)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read artifacts: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close rows: %v\n", err)
+		}
+	}()
+
+	found := make(map[int64][]*artifacts.Artifact)
+	for rows.Next() {
+		a := &artifacts.Artifact{ConversationID: conversationID, Source: artifacts.KindArtifact}
+		if err := rows.Scan(&a.MessageID, &a.ID, &a.Type, &a.Language, &a.Title, &a.Content); err != nil {
+			return nil, false, fmt.Errorf("failed to scan artifact: %w", err)
+		}
+		// The index guesses a language for every artifact so it can be
+		// searched by one; only code artifacts carry their own
+		if a.Type != artifacts.TypeCode {
+			a.Language = ""
+		}
+		found[a.MessageID] = append(found[a.MessageID], a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+	return found, true, nil
+}
+
+// ArtifactFilter narrows the artifacts read by AllArtifacts. Empty fields
+// don't filter.
+type ArtifactFilter struct {
+	Type     string    // part of the artifact type, such as svg or react
+	Language string    // language of code artifacts
+	After    time.Time // only artifacts in messages sent from this time on
+	Before   time.Time // only artifacts in messages sent before this time
+	Source   string    // artifacts.KindArtifact or artifacts.KindCodeBlock for only those
+	// CodeBlockLines also reads fenced code blocks of at least this many
+	// lines as artifacts; 0 leaves them out
+	CodeBlockLines int
+}
+
+// matches reports whether an artifact is of the filter's type, language and
+// source
+func (f ArtifactFilter) matches(a *artifacts.Artifact) bool {
+	if f.Type != "" && !strings.Contains(strings.ToLower(a.Type), strings.ToLower(f.Type)) {
+		return false
+	}
+	if f.Language != "" && (a.Type != artifacts.TypeCode || !strings.EqualFold(a.Language, f.Language)) {
+		return false
+	}
+	return f.Source == "" || a.Source == f.Source
+}
+
+// ArchivedArtifact is an artifact with the conversation and message it's in
+type ArchivedArtifact struct {
+	*artifacts.Artifact
+	ConversationUUID string
+	ConversationName string
+	MessageUUID      string
+	CreatedAt        time.Time
+}
+
+// AllArtifacts reads the artifacts of every conversation outside the trash
+// from the code block index, oldest first. The index must be up to date
+// (see EnsureCodeIndex).
+func (e *Engine) AllArtifacts(filter ArtifactFilter) ([]*ArchivedArtifact, error) {
+	current, err := e.codeIndexCurrent()
+	if err != nil {
+		return nil, err
+	}
+	if !current {
+		return nil, fmt.Errorf("the code block index is out of date; run 'shannon db reindex'")
+	}
+
+	conditions := []string{"c.deleted_at IS NULL"}
+	var args []interface{}
+	if filter.CodeBlockLines > 0 {
+		// Counting newlines in SQL keeps short code blocks from being read
+		conditions = append(conditions, `(cb.kind = ? OR (cb.kind = ? AND m.sender = 'assistant'
+			AND LENGTH(RTRIM(cb.content, char(10))) - LENGTH(REPLACE(RTRIM(cb.content, char(10)), char(10), '')) + 1 >= ?))`)
+		args = append(args, artifacts.KindArtifact, artifacts.KindCodeBlock, filter.CodeBlockLines)
+	} else {
+		conditions = append(conditions, "cb.kind = ?")
+		args = append(args, artifacts.KindArtifact)
+	}
+	if !filter.After.IsZero() {
+		conditions = append(conditions, "m.created_at >= ?")
+		args = append(args, filter.After.UTC().Format("2006-01-02 15:04:05"))
+	}
+	if !filter.Before.IsZero() {
+		conditions = append(conditions, "m.created_at < ?")
+		args = append(args, filter.Before.UTC().Format("2006-01-02 15:04:05"))
+	}
+
+	rows, err := e.db.Query(`
+		SELECT cb.conversation_id, c.uuid, c.name, cb.message_id, m.uuid, m.created_at,
+		       cb.kind, cb.start_line, COALESCE(cb.identifier, ''), COALESCE(cb.artifact_type, ''),
+		       COALESCE(cb.language, ''), COALESCE(cb.title, ''), cb.content
+		FROM code_blocks cb
+		JOIN conversations c ON cb.conversation_id = c.id
+		JOIN messages m ON cb.message_id = m.id
+		WHERE `+strings.Join(conditions, " AND ")+`
+		ORDER BY m.created_at, cb.message_id, cb.start_line
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artifacts: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close rows: %v\n", err)
+		}
+	}()
+
+	var found []*ArchivedArtifact
+	for rows.Next() {
+		a := &ArchivedArtifact{}
+		var block artifacts.CodeBlock
+		var msg models.Message
+		if err := rows.Scan(&msg.ConversationID, &a.ConversationUUID, &a.ConversationName, &msg.ID, &a.MessageUUID,
+			&a.CreatedAt, &block.Kind, &block.StartLine, &block.Identifier, &block.Type,
+			&block.Language, &block.Title, &block.Content); err != nil {
+			return nil, fmt.Errorf("failed to scan artifact: %w", err)
+		}
+		if block.Kind == artifacts.KindCodeBlock {
+			a.Artifact = artifacts.CodeBlockArtifact(&msg, &block)
+		} else {
+			a.Artifact = &artifacts.Artifact{
+				ID: block.Identifier, Type: block.Type, Language: block.Language, Title: block.Title, Content: block.Content,
+				MessageID: msg.ID, ConversationID: msg.ConversationID, Source: artifacts.KindArtifact,
+			}
+			// As in ConversationArtifacts, only code artifacts carry a language
+			if a.Type != artifacts.TypeCode {
+				a.Language = ""
+			}
+		}
+		if filter.matches(a.Artifact) {
+			found = append(found, a)
+		}
+	}
+	return found, rows.Err()
+}
+
+// RebuildCodeIndex re-extracts code blocks and artifacts from every message,
+// in parallel, and returns the number of blocks indexed
+func (e *Engine) RebuildCodeIndex() (int, error) {
+	tx, err := e.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			fmt.Fprintf(os.Stderr, "Warning: failed to rollback transaction: %v\n", err)
+		}
+	}()
+
+	if _, err := tx.Exec("DELETE FROM code_blocks"); err != nil {
+		return 0, fmt.Errorf("failed to clear code index: %w", err)
+	}
+
+	extractor := artifacts.NewExtractor()
+	var lastID int64
+	for {
+		batch, err := loadMessageBatch(tx, lastID, 500)
+		if err != nil {
+			return 0, fmt.Errorf("failed to load messages: %w", err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		for i, blocks := range extractor.ExtractAllCodeBlocks(batch) {
+			if err := artifacts.InsertCodeBlocks(tx, batch[i], blocks); err != nil {
+				return 0, fmt.Errorf("failed to index message %d: %w", batch[i].ID, err)
+			}
+		}
+		lastID = batch[len(batch)-1].ID
+	}
+
+	if _, err := tx.Exec("INSERT OR REPLACE INTO metadata (key, value) VALUES ('code_index_version', ?)", codeIndexVersion); err != nil {
+		return 0, fmt.Errorf("failed to record code index version: %w", err)
+	}
+
+	var count int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM code_blocks").Scan(&count); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit: %w", err)
+	}
+
+	return count, nil
+}
+
+// loadMessageBatch reads up to limit messages with an ID greater than afterID
+func loadMessageBatch(tx *sql.Tx, afterID int64, limit int) ([]*models.Message, error) {
+	rows, err := tx.Query(`
+		SELECT id, conversation_id, sender, message_text(text)
+		FROM messages
+		WHERE id > ?
+		ORDER BY id
+		LIMIT ?
+	`, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close rows: %v\n", err)
+		}
+	}()
+
+	var messages []*models.Message
+	for rows.Next() {
+		var msg models.Message
+		if err := rows.Scan(&msg.ID, &msg.ConversationID, &msg.Sender, &msg.Text); err != nil {
+			return nil, err
+		}
+		messages = append(messages, &msg)
+	}
+	return messages, rows.Err()
+}
+
+// codeContains returns the condition keeping the code blocks that contain
+// the query, to match their lines in Go. SQLite's lower() only folds ASCII,
+// so a case-insensitive query with other letters isn't narrowed, as that
+// could drop blocks strings.ToLower would match.
+func codeContains(opts CodeSearchOptions) (string, string, bool) {
+	if !opts.IgnoreCase {
+		return "instr(cb.content, ?) > 0", opts.Query, true
+	}
+	for _, r := range opts.Query {
+		if r >= utf8.RuneSelf {
+			return "", "", false
+		}
+	}
+	return "instr(lower(cb.content), ?) > 0", strings.ToLower(opts.Query), true
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
@@ -0,0 +1,175 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/artifacts"
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+func TestSearchCode(t *testing.T) {
+	engine, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// Mention http.Client in prose and in a fenced block of a new message
+	text := "The http.Client type handles requests:\n```go\nclient := &http.Client{}\nresp, err := client.Get(url)\n```"
+	if _, err := engine.db.Exec(`
+		INSERT INTO messages (uuid, conversation_id, sender, text, created_at, branch_id, sequence)
+		SELECT 'msg-code', id, 'assistant', ?, ?, (SELECT id FROM branches WHERE conversation_id = conversations.id), 10
+		FROM conversations WHERE uuid = 'conv-1'
+	`, text, time.Now().Format("2006-01-02 15:04:05")); err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := engine.RebuildCodeIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatalf("expected 1 indexed code block, got %d", count)
+	}
+
+	matches, err := engine.SearchCode(CodeSearchOptions{Query: "http.Client"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 {
+		t.Fatalf("expected only the code line to match, got %d: %+v", len(matches), matches)
+	}
+	if matches[0].Line != 3 || matches[0].Language != "go" || matches[0].Text != "client := &http.Client{}" {
+		t.Errorf("unexpected match: %+v", matches[0])
+	}
+
+	tests := []struct {
+		name     string
+		opts     CodeSearchOptions
+		expected int
+	}{
+		{"case sensitive", CodeSearchOptions{Query: "HTTP.CLIENT"}, 0},
+		{"ignore case", CodeSearchOptions{Query: "HTTP.CLIENT", IgnoreCase: true}, 1},
+		{"prefix of identifier", CodeSearchOptions{Query: "client.Ge"}, 1},
+		{"middle of identifier", CodeSearchOptions{Query: "lient.Ge"}, 1},
+		{"suffix of identifier", CodeSearchOptions{Query: "Client{"}, 1},
+		{"ignore case in middle of identifier", CodeSearchOptions{Query: "LIENT.GET", IgnoreCase: true}, 1},
+		{"ignore case with non-ASCII", CodeSearchOptions{Query: "ÜRL", IgnoreCase: true}, 0},
+		{"language filter", CodeSearchOptions{Query: "client", Languages: []string{"python"}}, 0},
+		{"kind filter", CodeSearchOptions{Query: "client", Kinds: []string{artifacts.KindArtifact}}, 0},
+		{"punctuation only", CodeSearchOptions{Query: ":="}, 2},
+		{"limit", CodeSearchOptions{Query: "client", Limit: 1}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, err := engine.SearchCode(tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(matches) != tt.expected {
+				t.Errorf("expected %d matches, got %d: %+v", tt.expected, len(matches), matches)
+			}
+		})
+	}
+}
+
+func TestConversationArtifacts(t *testing.T) {
+	engine, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	text := "Here you go:\n" +
+		"<antArtifact identifier=\"fetch\" type=\"application/vnd.ant.code\" language=\"golang\" title=\"Fetch\">\n" +
+		"resp, err := http.Get(url)\n" +
+		"</antArtifact>\n" +
+		"<antArtifact identifier=\"notes\" type=\"text/markdown\" title=\"Notes\">\n" +
+		"# Notes\n" +
+		"</antArtifact>\n" +
+		"```\n" +
+		"def main():\n" +
+		"    return 1\n" +
+		"```"
+	var convID int64
+	if err := engine.db.QueryRow("SELECT id FROM conversations WHERE uuid = 'conv-1'").Scan(&convID); err != nil {
+		t.Fatal(err)
+	}
+	result, err := engine.db.Exec(`
+		INSERT INTO messages (uuid, conversation_id, sender, text, created_at, branch_id, sequence)
+		SELECT 'msg-artifact', ?, 'assistant', ?, ?, (SELECT id FROM branches WHERE conversation_id = ?), 10
+	`, convID, text, time.Now().Format("2006-01-02 15:04:05"), convID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgID, err := result.LastInsertId()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An index built by an older version can't be trusted
+	if _, err := engine.db.Exec("UPDATE metadata SET value = '1' WHERE key = 'code_index_version'"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := engine.ConversationArtifacts(convID); err != nil || ok {
+		t.Fatalf("expected a stale index to be reported, got ok=%v err=%v", ok, err)
+	}
+
+	if _, err := engine.RebuildCodeIndex(); err != nil {
+		t.Fatal(err)
+	}
+	found, ok, err := engine.ConversationArtifacts(convID)
+	if err != nil || !ok {
+		t.Fatalf("expected indexed artifacts, got ok=%v err=%v", ok, err)
+	}
+
+	// The same artifacts the extractor finds in the message
+	expected, err := artifacts.NewExtractor().ExtractFromMessage(&models.Message{
+		ID: msgID, ConversationID: convID, Sender: "assistant", Text: text,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := found[msgID]
+	if len(found) != 1 || len(got) != len(expected) {
+		t.Fatalf("expected %d artifacts for message %d, got %+v", len(expected), msgID, found)
+	}
+	for i, a := range got {
+		want := *expected[i]
+		want.Language = artifacts.NormalizeLanguage(want.Language)
+		if *a != want {
+			t.Errorf("artifact %d: expected %+v, got %+v", i, want, *a)
+		}
+	}
+
+	// Across the archive, filtered by type, language and date, with large
+	// enough code blocks taken for artifacts in the language they look like
+	codeBlock := fmt.Sprintf("codeblock-%d-9", msgID)
+	for _, tt := range []struct {
+		filter   ArtifactFilter
+		expected []string
+	}{
+		{ArtifactFilter{}, []string{"fetch", "notes"}},
+		{ArtifactFilter{Type: "markdown"}, []string{"notes"}},
+		{ArtifactFilter{Language: "Go"}, []string{"fetch"}},
+		{ArtifactFilter{Type: "svg"}, nil},
+		{ArtifactFilter{Before: time.Now().Add(-time.Hour)}, nil},
+		{ArtifactFilter{CodeBlockLines: 2}, []string{"fetch", "notes", codeBlock}},
+		{ArtifactFilter{CodeBlockLines: 3}, []string{"fetch", "notes"}},
+		{ArtifactFilter{CodeBlockLines: 2, Language: "python"}, []string{codeBlock}},
+		{ArtifactFilter{CodeBlockLines: 2, Source: artifacts.KindArtifact}, []string{"fetch", "notes"}},
+	} {
+		all, err := engine.AllArtifacts(tt.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, a := range all {
+			if a.MessageUUID != "msg-artifact" || a.ConversationUUID != "conv-1" {
+				t.Errorf("expected the artifact's message and conversation, got %+v", a)
+			}
+			ids = append(ids, a.ID)
+		}
+		if strings.Join(ids, ",") != strings.Join(tt.expected, ",") {
+			t.Errorf("%+v: expected %v, got %v", tt.filter, tt.expected, ids)
+		}
+	}
+}
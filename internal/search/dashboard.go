@@ -0,0 +1,107 @@
+package search
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/neilberkman/shannon/internal/artifacts"
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+// MonthActivity is how many conversations were started in a month and how
+// many messages they have
+type MonthActivity struct {
+	Month         string // 2006-01, in UTC
+	Conversations int
+	Messages      int
+}
+
+// ActivityByMonth counts the conversations started in each month outside
+// the trash, newest month first
+func (e *Engine) ActivityByMonth() ([]*MonthActivity, error) {
+	var months []*MonthActivity
+	err := e.collect(`
+		SELECT substr(created_at, 1, 7) AS month, COUNT(*), COALESCE(SUM(message_count), 0)
+		FROM conversations
+		WHERE deleted_at IS NULL
+		GROUP BY month
+		ORDER BY month DESC
+	`, func(rows *sql.Rows) error {
+		var m MonthActivity
+		if err := rows.Scan(&m.Month, &m.Conversations, &m.Messages); err != nil {
+			return err
+		}
+		months = append(months, &m)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count conversations by month: %w", err)
+	}
+	return months, nil
+}
+
+// ConversationsInMonth returns the conversations started in a month, as
+// ActivityByMonth names it, oldest first
+func (e *Engine) ConversationsInMonth(month string) ([]*models.Conversation, error) {
+	var conversations []*models.Conversation
+	err := e.collect(`
+		SELECT id, uuid, name, created_at, updated_at, message_count, imported_at,
+		       token_count, artifact_count, human_message_count, duration_seconds, active_days
+		FROM conversations
+		WHERE substr(created_at, 1, 7) = ? AND deleted_at IS NULL
+		ORDER BY created_at, id
+	`, func(rows *sql.Rows) error {
+		var c models.Conversation
+		if err := rows.Scan(&c.ID, &c.UUID, &c.Name, &c.CreatedAt, &c.UpdatedAt, &c.MessageCount, &c.ImportedAt,
+			&c.TokenCount, &c.ArtifactCount, &c.HumanMessageCount, &c.DurationSeconds, &c.ActiveDays); err != nil {
+			return err
+		}
+		conversations = append(conversations, &c)
+		return nil
+	}, month)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query conversations: %w", err)
+	}
+	return conversations, nil
+}
+
+// LanguageCount is how many code artifacts are in a language
+type LanguageCount struct {
+	Language  string
+	Artifacts int
+}
+
+// ArtifactLanguages counts the code artifacts outside the trash by language,
+// most common first, as AllArtifacts filters them by language. Fenced code
+// blocks aren't counted. The code block index must be up to date (see
+// EnsureCodeIndex).
+func (e *Engine) ArtifactLanguages() ([]*LanguageCount, error) {
+	current, err := e.codeIndexCurrent()
+	if err != nil {
+		return nil, err
+	}
+	if !current {
+		return nil, fmt.Errorf("the code block index is out of date; run 'shannon db reindex'")
+	}
+
+	var languages []*LanguageCount
+	err = e.collect(`
+		SELECT lower(cb.language) AS lang, COUNT(*)
+		FROM code_blocks cb
+		JOIN conversations c ON cb.conversation_id = c.id
+		WHERE cb.kind = ? AND cb.artifact_type = ? AND COALESCE(cb.language, '') != '' AND c.deleted_at IS NULL
+		GROUP BY lang
+		ORDER BY COUNT(*) DESC, lang
+	`, func(rows *sql.Rows) error {
+		var l LanguageCount
+		if err := rows.Scan(&l.Language, &l.Artifacts); err != nil {
+			return err
+		}
+		languages = append(languages, &l)
+		return nil
+	}, artifacts.KindArtifact, artifacts.TypeCode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count artifacts by language: %w", err)
+	}
+	return languages, nil
+}
@@ -0,0 +1,111 @@
+package search
+
+import (
+	"regexp"
+	"strings"
+)
+
+// barewordRegex matches terms FTS5 accepts without quotes
+var barewordRegex = regexp.MustCompile(`^[\p{L}\p{N}_]+$`)
+
+// Dictionary holds the user's stopwords and synonym groups, applied to plain
+// search terms before they reach FTS5. Quoted phrases and prefix queries are
+// left as written.
+type Dictionary struct {
+	stopwords map[string]bool
+	synonyms  map[string][]string // lowercased term to every term of its group
+}
+
+// NewDictionary builds a dictionary from stopwords to drop from multi-word
+// queries and groups of interchangeable terms, such as [js javascript], each
+// of which matches all of its group. A term in several groups matches all
+// of them.
+func NewDictionary(stopwords []string, synonyms [][]string) *Dictionary {
+	d := &Dictionary{
+		stopwords: make(map[string]bool),
+		synonyms:  make(map[string][]string),
+	}
+	for _, word := range stopwords {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+			d.stopwords[word] = true
+		}
+	}
+	for _, group := range synonyms {
+		var terms []string
+		for _, term := range group {
+			if term = strings.TrimSpace(term); term != "" {
+				terms = append(terms, term)
+			}
+		}
+		if len(terms) < 2 {
+			continue
+		}
+		for _, term := range terms {
+			key := strings.ToLower(term)
+			for _, synonym := range terms {
+				if !containsFold(d.synonyms[key], synonym) {
+					d.synonyms[key] = append(d.synonyms[key], synonym)
+				}
+			}
+		}
+	}
+	return d
+}
+
+// SetDictionary makes searches apply a dictionary; nil removes it
+func (e *Engine) SetDictionary(d *Dictionary) {
+	e.dictionary = d
+}
+
+// removeStopwords drops stopwords from the words of a query, unless that
+// would leave nothing to search for
+func (d *Dictionary) removeStopwords(words []string) []string {
+	if d == nil || len(d.stopwords) == 0 {
+		return words
+	}
+	var kept []string
+	for _, word := range words {
+		if !d.stopwords[strings.ToLower(word)] {
+			kept = append(kept, word)
+		}
+	}
+	if len(kept) == 0 {
+		return words
+	}
+	return kept
+}
+
+// expand turns a term with synonyms into an OR group of them, quoting those
+// FTS5 would otherwise misread, and returns other terms unchanged
+func (d *Dictionary) expand(term string) string {
+	if d == nil {
+		return term
+	}
+	group, ok := d.synonyms[strings.ToLower(term)]
+	if !ok {
+		return term
+	}
+	alternatives := make([]string, len(group))
+	for i, synonym := range group {
+		if barewordRegex.MatchString(synonym) && !isOperator(synonym) {
+			alternatives[i] = synonym
+		} else {
+			alternatives[i] = escapeFTSQuery(synonym)
+		}
+	}
+	return "(" + strings.Join(alternatives, " OR ") + ")"
+}
+
+// isOperator reports whether a word is an FTS5 boolean operator
+func isOperator(word string) bool {
+	return word == "AND" || word == "OR" || word == "NOT"
+}
+
+func containsFold(terms []string, term string) bool {
+	for _, t := range terms {
+		if strings.EqualFold(t, term) {
+			return true
+		}
+	}
+	return false
+}
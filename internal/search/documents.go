@@ -0,0 +1,47 @@
+package search
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+// EachConversationText calls fn with every conversation and the text of its
+// human messages, which say what the conversation is about more concisely
+// than the answers. Conversations are visited oldest first, one at a time, so
+// the whole history is never held in memory.
+func (e *Engine) EachConversationText(fn func(conv *models.Conversation, text string) error) error {
+	rows, err := e.db.Query(`
+		SELECT c.id, c.uuid, c.name, c.created_at, c.updated_at, c.message_count, c.imported_at,
+		       c.token_count, c.artifact_count, c.human_message_count, c.duration_seconds, c.active_days,
+		       COALESCE(group_concat(message_text(m.text), char(10)), '')
+		FROM conversations c
+		LEFT JOIN messages m ON m.conversation_id = c.id AND m.sender = 'human'
+		WHERE c.deleted_at IS NULL
+		GROUP BY c.id
+		ORDER BY c.created_at, c.id
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to query conversations: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close rows: %v\n", err)
+		}
+	}()
+
+	for rows.Next() {
+		var c models.Conversation
+		var text string
+		err := rows.Scan(&c.ID, &c.UUID, &c.Name, &c.CreatedAt, &c.UpdatedAt, &c.MessageCount, &c.ImportedAt,
+			&c.TokenCount, &c.ArtifactCount, &c.HumanMessageCount, &c.DurationSeconds, &c.ActiveDays, &text)
+		if err != nil {
+			return fmt.Errorf("failed to scan conversation: %w", err)
+		}
+		if err := fn(&c, text); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,152 @@
+package search
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ratingPrefix filters a query to messages with a rating, as in rating:useful
+const ratingPrefix = "rating:"
+
+// indexPrefixes force an index when a query starts with them
+var indexPrefixes = map[string]string{
+	"code:": IndexCode,
+	"text:": IndexText,
+}
+
+// withIndexPrefix strips a code: or text: prefix from the query and selects
+// the index it names. A prefix overrides the Index option. rating: filters
+// anywhere in the query are stripped too and set the Rating option.
+func withIndexPrefix(opts SearchOptions) SearchOptions {
+	opts = withRatingFilter(opts)
+	query := strings.TrimSpace(opts.Query)
+	for prefix, index := range indexPrefixes {
+		if len(query) >= len(prefix) && strings.EqualFold(query[:len(prefix)], prefix) {
+			opts.Query = strings.TrimSpace(query[len(prefix):])
+			opts.Index = index
+			opts.indexPrefix = prefix
+			break
+		}
+	}
+	return opts
+}
+
+// withRatingFilter moves rating:VALUE words from the query to the Rating
+// option
+func withRatingFilter(opts SearchOptions) SearchOptions {
+	words := strings.Fields(opts.Query)
+	kept := words[:0]
+	found := false
+	for _, word := range words {
+		if len(word) > len(ratingPrefix) && strings.EqualFold(word[:len(ratingPrefix)], ratingPrefix) {
+			opts.Rating = strings.ToLower(word[len(ratingPrefix):])
+			found = true
+			continue
+		}
+		kept = append(kept, word)
+	}
+	if found {
+		opts.Query = strings.Join(kept, " ")
+	}
+	return opts
+}
+
+// checkFilters reports filters in the options or the query that can't match
+// anything, rather than silently finding nothing
+func checkFilters(opts SearchOptions) error {
+	if opts.Distinct != "" && opts.Distinct != DistinctConversation {
+		return fmt.Errorf("invalid distinct %q (must be %s)", opts.Distinct, DistinctConversation)
+	}
+	for _, bound := range []*Bound{opts.Duration, opts.ActiveDays} {
+		if bound != nil && !validBoundOp(bound.Op) {
+			return fmt.Errorf("invalid comparison %q (must be one of %s)", bound.Op, strings.Join(BoundOps, " "))
+		}
+	}
+	if opts = withIndexPrefix(opts); opts.Rating != "" {
+		return ValidateRating(opts.Rating)
+	}
+	return nil
+}
+
+func validBoundOp(op string) bool {
+	for _, valid := range BoundOps {
+		if op == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// chooseIndex picks the index to search and explains why
+func chooseIndex(opts SearchOptions) (string, string) {
+	switch {
+	case opts.indexPrefix != "":
+		return opts.Index, fmt.Sprintf("query prefix %q", opts.indexPrefix)
+	case opts.Index == IndexText || opts.Index == IndexCode:
+		return opts.Index, "requested explicitly"
+	}
+	if reason := codeQueryReason(opts.Query); reason != "" {
+		return IndexCode, "query looks like code: " + reason
+	}
+	return IndexText, "query doesn't look like code"
+}
+
+// Explanation describes how a search runs, for finding out why it returned
+// nothing
+type Explanation struct {
+	Query       string        `json:"query"`
+	FTSQuery    string        `json:"fts_query"`
+	Index       string        `json:"index"`
+	Table       string        `json:"table"`
+	IndexReason string        `json:"index_reason"`
+	Filters     []string      `json:"filters"`
+	SQL         string        `json:"sql"`
+	Args        []interface{} `json:"args"`
+}
+
+// paramRegex matches the numbered parameters of the search SQL
+var paramRegex = regexp.MustCompile(`[$?](\d+)`)
+
+// Explain returns how a search with opts is parsed and run, without running it
+func (e *Engine) Explain(opts SearchOptions) *Explanation {
+	original := opts.Query
+	opts = withIndexPrefix(opts)
+	index, reason := chooseIndex(opts)
+	query, args := e.buildSearchQuery(opts)
+	conditions, _ := e.buildFilters(opts)
+
+	// Show the filters with their values in place of the parameters
+	filters := make([]string, len(conditions))
+	for i, condition := range conditions {
+		filters[i] = paramRegex.ReplaceAllStringFunc(condition, func(param string) string {
+			var n int
+			if _, err := fmt.Sscanf(param[1:], "%d", &n); err != nil || n < 1 || n > len(args) {
+				return param
+			}
+			return fmt.Sprintf("%#v", args[n-1])
+		})
+	}
+
+	return &Explanation{
+		Query:       original,
+		FTSQuery:    e.processFTSQuery(opts.Query),
+		Index:       index,
+		Table:       indexTables[index],
+		IndexReason: reason,
+		Filters:     filters,
+		SQL:         tidySQL(query),
+		Args:        args,
+	}
+}
+
+// tidySQL strips the indentation and blank lines of the generated SQL
+func tidySQL(query string) string {
+	var lines []string
+	for _, line := range strings.Split(query, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
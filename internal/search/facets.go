@@ -0,0 +1,157 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/neilberkman/shannon/internal/artifacts"
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+// FacetCount is the number of matching messages sharing a value
+type FacetCount struct {
+	ID    int64  `json:"id,omitempty"` // conversation ID for conversation facets
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// Facets summarizes how the full set of matches for a search is distributed,
+// regardless of limit and offset
+type Facets struct {
+	Total          int          `json:"total"`
+	BySender       []FacetCount `json:"by_sender"`
+	ByConversation []FacetCount `json:"by_conversation"`
+	ByMonth        []FacetCount `json:"by_month"`
+	ByArtifactType []FacetCount `json:"by_artifact_type"`
+}
+
+// Facets counts the messages matching a search by sender, conversation and
+// month, and the artifacts they contain by type
+func (e *Engine) Facets(opts SearchOptions) (*Facets, error) {
+	return e.FacetsContext(context.Background(), opts)
+}
+
+// FacetsContext counts the matches for a search, stopping early if ctx is
+// canceled
+func (e *Engine) FacetsContext(ctx context.Context, opts SearchOptions) (*Facets, error) {
+	if err := checkFilters(opts); err != nil {
+		return nil, err
+	}
+	opts = withIndexPrefix(opts)
+	ftsTable := e.ftsTable(opts)
+
+	// Only messages that can hold artifacts need their text loaded
+	query := fmt.Sprintf(`
+		SELECT
+			c.id,
+			c.name,
+			m.sender,
+			m.created_at,
+			CASE WHEN instr(message_text(m.text), '<antArtifact') > 0 THEN message_text(m.text) ELSE '' END
+		FROM %s
+		JOIN messages m ON %s.rowid = m.id
+		JOIN conversations c ON m.conversation_id = c.id
+		WHERE %s MATCH ?1
+	`, ftsTable, ftsTable, ftsTable)
+
+	conditions, args := e.buildFilters(opts)
+	if len(conditions) > 0 {
+		query += " AND " + strings.Join(conditions, " AND ")
+	}
+
+	rows, err := e.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("facet query failed: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close rows: %v\n", err)
+		}
+	}()
+
+	senders := make(map[string]int)
+	conversations := make(map[int64]*FacetCount)
+	months := make(map[string]int)
+	artifactTypes := make(map[string]int)
+	extractor := artifacts.NewExtractor()
+
+	facets := &Facets{}
+	for rows.Next() {
+		var msg models.Message
+		var name string
+		if err := rows.Scan(&msg.ConversationID, &name, &msg.Sender, &msg.CreatedAt, &msg.Text); err != nil {
+			return nil, fmt.Errorf("failed to scan facet row: %w", err)
+		}
+
+		facets.Total++
+		senders[msg.Sender]++
+		months[msg.CreatedAt.Format("2006-01")]++
+
+		conv, ok := conversations[msg.ConversationID]
+		if !ok {
+			conv = &FacetCount{ID: msg.ConversationID, Value: name}
+			conversations[msg.ConversationID] = conv
+		}
+		conv.Count++
+
+		if msg.Text != "" {
+			found, err := extractor.ExtractFromMessage(&msg)
+			if err != nil {
+				continue // Skip messages that fail extraction
+			}
+			for _, artifact := range found {
+				artifactTypes[artifact.GetTypeName()]++
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating facet rows: %w", err)
+	}
+
+	facets.BySender = sortedCounts(senders)
+	facets.ByArtifactType = sortedCounts(artifactTypes)
+
+	facets.ByConversation = make([]FacetCount, 0, len(conversations))
+	for _, conv := range conversations {
+		facets.ByConversation = append(facets.ByConversation, *conv)
+	}
+	sort.Slice(facets.ByConversation, func(i, j int) bool {
+		a, b := facets.ByConversation[i], facets.ByConversation[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.ID < b.ID
+	})
+
+	// Months read best in calendar order
+	facets.ByMonth = sortedCounts(months)
+	sort.Slice(facets.ByMonth, func(i, j int) bool {
+		return facets.ByMonth[i].Value < facets.ByMonth[j].Value
+	})
+
+	return facets, nil
+}
+
+// sortedCounts turns counts into facet entries, largest first
+func sortedCounts(counts map[string]int) []FacetCount {
+	result := make([]FacetCount, 0, len(counts))
+	for value, count := range counts {
+		result = append(result, FacetCount{Value: value, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Value < result[j].Value
+	})
+	return result
+}
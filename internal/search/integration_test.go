@@ -1,12 +1,18 @@
 package search
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"os"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/models"
 )
 
 func setupTestDB(t *testing.T) (*Engine, func()) {
@@ -239,6 +245,227 @@ func TestSearchWithSenderFilter(t *testing.T) {
 	}
 }
 
+func TestSearchWithModelFilter(t *testing.T) {
+	engine, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if _, err := engine.db.Exec("UPDATE messages SET model = 'claude-3-opus-20240229' WHERE uuid = 'msg-2'"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := engine.db.Exec("UPDATE messages SET model = 'claude-3-5-sonnet-20241022' WHERE uuid = 'msg-5'"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Part of the name is enough, in any case
+	for model, want := range map[string]int{"Opus": 1, "claude-3": 2, "haiku": 0} {
+		results, err := engine.Search(SearchOptions{Query: "python OR project", Model: model, Limit: 100})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != want {
+			t.Errorf("model %q: expected %d results, got %d", model, want, len(results))
+		}
+	}
+
+	stats, err := engine.GetStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ModelUsage{
+		{Model: "claude-3-5-sonnet-20241022", Messages: 1, Conversations: 1},
+		{Model: "claude-3-opus-20240229", Messages: 1, Conversations: 1},
+	}
+	if got := stats["messages_by_model"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected messages by model %v, got %v", want, got)
+	}
+
+	_, messages, err := engine.GetConversation(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if messages[0].Model != "" || messages[1].Model != "claude-3-opus-20240229" {
+		t.Errorf("expected the answer's model to be loaded, got %q and %q", messages[0].Model, messages[1].Model)
+	}
+}
+
+func TestPrivateConversations(t *testing.T) {
+	engine, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// Conversation 2 is the one about the test project with Alice
+	changed, err := engine.SetPrivate(2, true)
+	if err != nil || !changed {
+		t.Fatalf("expected conversation 2 to be made private, got %v, %v", changed, err)
+	}
+	if changed, _ := engine.SetPrivate(2, true); changed {
+		t.Error("expected making a private conversation private to change nothing")
+	}
+	if _, err := engine.SetPrivate(99, true); err == nil {
+		t.Error("expected an error for a missing conversation")
+	}
+
+	results, err := engine.Search(SearchOptions{Query: "alice", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 0 {
+		t.Errorf("expected private messages to be left out of search, got %d results", len(results))
+	}
+
+	// Included, they come after the indexed matches, marked and highlighted
+	results, err = engine.Search(SearchOptions{Query: "project OR python", IncludePrivate: true, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var private []*models.SearchResult
+	for i, r := range results {
+		if r.Private {
+			private = append(private, r)
+		} else if len(private) > 0 {
+			t.Errorf("expected result %d from the index before the private ones", i)
+		}
+	}
+	if len(private) != 0 {
+		t.Errorf("expected private matches to need every term of the query, got %d", len(private))
+	}
+	results, err = engine.Search(SearchOptions{Query: "test project", IncludePrivate: true, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || !results[0].Private || results[0].ConversationID != 2 {
+		t.Fatalf("expected both private messages about the test project, got %+v", results)
+	}
+	if !strings.Contains(results[0].Snippet, "<mark>test</mark> <mark>project</mark>") {
+		t.Errorf("expected the terms to be marked in the snippet, got %q", results[0].Snippet)
+	}
+
+	convs, err := engine.GetPrivateConversations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(convs) != 1 || convs[0].ID != 2 {
+		t.Errorf("expected conversation 2 to be listed as private, got %+v", convs)
+	}
+
+	// Made public again, its messages are back in the index
+	if _, err := engine.SetPrivate(2, false); err != nil {
+		t.Fatal(err)
+	}
+	results, err = engine.Search(SearchOptions{Query: "alice", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Private {
+		t.Errorf("expected the public conversation's messages to be found again, got %+v", results)
+	}
+}
+
+func TestSearchStarred(t *testing.T) {
+	engine, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if _, err := engine.db.Exec("UPDATE messages SET starred = 1 WHERE uuid = 'msg-2'"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := engine.db.Exec("UPDATE messages SET feedback = 'down' WHERE uuid = 'msg-5'"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := engine.db.Exec("UPDATE conversations SET starred = 1 WHERE uuid = 'conv-2'"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A starred conversation's messages count as starred
+	results, err := engine.Search(SearchOptions{Query: "python OR project", Starred: true, Limit: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range results {
+		got = append(got, r.MessageUUID)
+	}
+	sort.Strings(got)
+	if want := []string{"msg-2", "msg-4", "msg-5"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected starred matches %v, got %v", want, got)
+	}
+
+	results, err = engine.Search(SearchOptions{Query: "python OR project", Feedback: models.FeedbackDown, Limit: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].MessageUUID != "msg-5" {
+		t.Errorf("expected only msg-5 to have a thumbs down, got %d results", len(results))
+	}
+
+	conv, messages, err := engine.GetConversation(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conv.Starred || messages[0].Starred || !messages[1].Starred {
+		t.Errorf("expected only the answer to be starred, got %v, %v and %v", conv.Starred, messages[0].Starred, messages[1].Starred)
+	}
+}
+
+func TestSearchDurationAndDays(t *testing.T) {
+	engine, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// Conversation 1 runs over three days, conversation 2 for ten minutes
+	for uuid, created := range map[string]string{
+		"msg-1": "2024-03-01 09:00:00 +0000 UTC",
+		"msg-2": "2024-03-01 09:05:00 +0000 UTC",
+		"msg-3": "2024-03-03 17:30:00 +0000 UTC",
+		"msg-4": "2024-03-05 12:00:00 +0000 UTC",
+		"msg-5": "2024-03-05 12:10:00 +0000 UTC",
+	} {
+		if _, err := engine.db.Exec("UPDATE messages SET created_at = ? WHERE uuid = ?", created, uuid); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, id := range []int64{1, 2} {
+		if _, err := engine.db.Exec(db.RefreshConversationStatsSQL, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	conv, _, err := engine.GetConversation(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conv.Duration() != 56*time.Hour+30*time.Minute || conv.ActiveDays != 2 {
+		t.Errorf("conversation 1 ran %v on %d days, want 56h30m on 2", conv.Duration(), conv.ActiveDays)
+	}
+
+	tests := []struct {
+		opts SearchOptions
+		want []string
+	}{
+		{SearchOptions{Duration: &Bound{Op: ">", Value: 2 * 3600}}, []string{"msg-1", "msg-2", "msg-3"}},
+		{SearchOptions{Duration: &Bound{Op: "<=", Value: 600}}, []string{"msg-4", "msg-5"}},
+		{SearchOptions{ActiveDays: &Bound{Op: "=", Value: 1}}, []string{"msg-4", "msg-5"}},
+		{SearchOptions{ActiveDays: &Bound{Op: ">", Value: 7}}, nil},
+	}
+	for _, tt := range tests {
+		tt.opts.Query = "python OR project"
+		tt.opts.Limit = 100
+		results, err := engine.Search(tt.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, r := range results {
+			got = append(got, r.MessageUUID)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("search with duration %+v and days %+v found %v, want %v", tt.opts.Duration, tt.opts.ActiveDays, got, tt.want)
+		}
+	}
+
+	if _, err := engine.Search(SearchOptions{Query: "python", Duration: &Bound{Op: "!=", Value: 1}}); err == nil {
+		t.Error("expected an invalid comparison to fail")
+	}
+}
+
 func TestSearchWithConversationFilter(t *testing.T) {
 	engine, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -342,6 +569,843 @@ func TestSearchSortingAndPagination(t *testing.T) {
 	}
 }
 
+func TestSearchRanking(t *testing.T) {
+	engine, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	opts := SearchOptions{
+		Query:     "python OR alice",
+		SortBy:    "relevance",
+		SortOrder: "desc",
+		Limit:     10,
+	}
+
+	results, err := engine.Search(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 5 {
+		t.Fatalf("expected 5 results, got %d", len(results))
+	}
+	// FTS5 ranks the best match lowest, so the most relevant results come first
+	for i := 1; i < len(results); i++ {
+		if results[i-1].Rank > results[i].Rank {
+			t.Errorf("results not sorted by relevance: %v before %v", results[i-1].Rank, results[i].Rank)
+		}
+	}
+
+	for _, rank := range []string{RankRecency, RankHybrid} {
+		opts.Rank = rank
+		results, err := engine.Search(opts)
+		if err != nil {
+			t.Fatalf("%s ranking failed: %v", rank, err)
+		}
+		if len(results) != 5 {
+			t.Fatalf("expected 5 results with %s ranking, got %d", rank, len(results))
+		}
+		// The conversation updated 2 days ago beats the one updated 10 days ago
+		if results[0].ConversationName != "Test Project Alpha" {
+			t.Errorf("expected the recent conversation first with %s ranking, got %q", rank, results[0].ConversationName)
+		}
+	}
+}
+
+func TestSearchFacets(t *testing.T) {
+	engine, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// Facets cover every match, not just the page of results
+	facets, err := engine.Facets(SearchOptions{Query: "python OR alice", Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if facets.Total != 5 {
+		t.Fatalf("expected 5 matches, got %d", facets.Total)
+	}
+	if len(facets.BySender) != 2 || facets.BySender[0] != (FacetCount{Value: "human", Count: 3}) {
+		t.Errorf("unexpected sender facets: %+v", facets.BySender)
+	}
+	if len(facets.ByConversation) != 2 || facets.ByConversation[0].Value != "Python Development" || facets.ByConversation[0].Count != 3 {
+		t.Errorf("unexpected conversation facets: %+v", facets.ByConversation)
+	}
+	months := 0
+	for i, month := range facets.ByMonth {
+		months += month.Count
+		if i > 0 && facets.ByMonth[i-1].Value >= month.Value {
+			t.Errorf("months not in calendar order: %+v", facets.ByMonth)
+		}
+	}
+	if months != 5 {
+		t.Errorf("expected month facets to add up to 5, got %d", months)
+	}
+	if len(facets.ByArtifactType) != 0 {
+		t.Errorf("expected no artifacts, got %+v", facets.ByArtifactType)
+	}
+
+	// Filters narrow the facets like they narrow results
+	facets, err = engine.Facets(SearchOptions{Query: "python OR alice", Sender: "assistant"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if facets.Total != 2 || len(facets.BySender) != 1 || facets.BySender[0].Value != "assistant" {
+		t.Errorf("expected only assistant matches, got %+v", facets)
+	}
+}
+
+func TestSearchConversationMatches(t *testing.T) {
+	engine, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	matches, err := engine.SearchConversationMatches(ctx, SearchOptions{Query: "python OR alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 2 {
+		t.Fatalf("expected 2 conversations, got %d", len(matches))
+	}
+	counts := map[string]int{}
+	for _, match := range matches {
+		counts[match.Conversation.Name] = match.Matches
+		if !strings.Contains(match.Snippet, "<mark>") {
+			t.Errorf("expected a highlighted snippet for %q, got %q", match.Conversation.Name, match.Snippet)
+		}
+	}
+	if counts["Python Development"] != 3 || counts["Test Project Alpha"] != 2 {
+		t.Errorf("unexpected match counts: %v", counts)
+	}
+
+	// Recency puts the conversation updated 2 days ago first
+	matches, err = engine.SearchConversationMatches(ctx, SearchOptions{Query: "python OR alice", Rank: RankRecency, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 || matches[0].Conversation.Name != "Test Project Alpha" {
+		t.Errorf("expected only Test Project Alpha with recency ranking and a limit of 1, got %+v", matches)
+	}
+
+	// Filters narrow the matches counted
+	matches, err = engine.SearchConversationMatches(ctx, SearchOptions{Query: "python", Sender: "human", Rank: RankHybrid})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 || matches[0].Matches != 2 {
+		t.Errorf("expected 2 human matches in one conversation, got %+v", matches)
+	}
+}
+
 func timePtr(t time.Time) *time.Time {
 	return &t
 }
+
+func TestSearchIndexOverride(t *testing.T) {
+	engine, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if _, err := engine.DB().Exec(`
+		INSERT INTO messages (uuid, conversation_id, sender, text, created_at, branch_id, sequence)
+		SELECT 'msg-6', id, 'human', 'Meet me at the café', ?, 1, 5 FROM conversations WHERE uuid = 'conv-2'
+	`, time.Now().Format("2006-01-02 15:04:05")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		index string
+		want  int
+	}{
+		{"develop", IndexAuto, 1}, // stemmed: matches "development"
+		{"develop", IndexCode, 0},
+		{"cafe", IndexAuto, 1}, // accents folded
+		{"cafe", IndexCode, 0},
+		{"café", IndexCode, 1},
+		{"learning", IndexCode, 2},
+	}
+	for _, tt := range tests {
+		results, err := engine.Search(SearchOptions{Query: tt.query, Index: tt.index, Limit: 10})
+		if err != nil {
+			t.Fatalf("search %q in %q index failed: %v", tt.query, tt.index, err)
+		}
+		if len(results) != tt.want {
+			t.Errorf("search %q in %q index: expected %d results, got %d", tt.query, tt.index, tt.want, len(results))
+		}
+	}
+}
+
+func TestRandomConversations(t *testing.T) {
+	engine, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	tests := []struct {
+		name   string
+		filter ConversationFilter
+		want   []string
+	}{
+		{"no filter", ConversationFilter{}, []string{"Python Development", "Test Project Alpha"}},
+		{"since", ConversationFilter{Since: timePtr(time.Now().AddDate(0, 0, -7))}, []string{"Test Project Alpha"}},
+		{"before", ConversationFilter{Before: timePtr(time.Now().AddDate(0, 0, -7))}, []string{"Python Development"}},
+		{"min messages", ConversationFilter{MinMessages: 3}, []string{"Python Development"}},
+		{"nothing matches", ConversationFilter{Since: timePtr(time.Now().AddDate(0, 0, -7)), MinMessages: 3}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conversations, err := engine.RandomConversations(tt.filter, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, conv := range conversations {
+				names = append(names, conv.Name)
+			}
+			sort.Strings(names)
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, names)
+			}
+		})
+	}
+}
+
+func TestOnThisDay(t *testing.T) {
+	engine, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	day := time.Date(2025, 3, 15, 18, 0, 0, 0, time.UTC)
+	for name, created := range map[string]time.Time{
+		"A year ago":       time.Date(2024, 3, 15, 9, 0, 0, 0, time.UTC),
+		"Two years ago":    time.Date(2023, 3, 15, 23, 0, 0, 0, time.UTC),
+		"A month ago":      time.Date(2025, 2, 15, 9, 0, 0, 0, time.UTC),
+		"A day off":        time.Date(2024, 3, 14, 9, 0, 0, 0, time.UTC),
+		"Earlier that day": time.Date(2025, 3, 15, 8, 0, 0, 0, time.UTC),
+	} {
+		if _, err := engine.DB().Exec(`
+			INSERT INTO conversations (uuid, name, created_at, updated_at, message_count)
+			VALUES (?, ?, ?, ?, 1)
+		`, name, name, created, created); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names := func(conversations []*models.Conversation) []string {
+		var names []string
+		for _, conv := range conversations {
+			names = append(names, conv.Name)
+		}
+		return names
+	}
+
+	yearly, err := engine.OnThisDay(day, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"A year ago", "Two years ago"}; !reflect.DeepEqual(names(yearly), want) {
+		t.Errorf("expected %v, got %v", want, names(yearly))
+	}
+
+	monthly, err := engine.OnThisDay(day, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"A month ago", "A year ago", "Two years ago"}; !reflect.DeepEqual(names(monthly), want) {
+		t.Errorf("expected %v, got %v", want, names(monthly))
+	}
+
+	// 23:00 UTC is the next day in Tokyo
+	tokyo := time.FixedZone("JST", 9*60*60)
+	shifted, err := engine.OnThisDay(time.Date(2025, 3, 16, 12, 0, 0, 0, tokyo), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Two years ago"}; !reflect.DeepEqual(names(shifted), want) {
+		t.Errorf("expected %v, got %v", want, names(shifted))
+	}
+}
+
+func TestMessageActivity(t *testing.T) {
+	engine, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if _, err := engine.DB().Exec(`DELETE FROM messages`); err != nil {
+		t.Fatal(err)
+	}
+	for i, msg := range []struct {
+		sender  string
+		created string
+	}{
+		{"human", "2024-03-01 09:00:00"},
+		{"assistant", "2024-03-01 09:01:00"},
+		{"human", "2024-03-01 23:30:00"}, // March 2 in Tokyo
+		{"human", "2024-03-05 12:00:00"},
+		{"human", "2025-01-01 12:00:00"},
+	} {
+		if _, err := engine.DB().Exec(`
+			INSERT INTO messages (uuid, conversation_id, sender, text, created_at, branch_id, sequence)
+			SELECT ?, id, ?, 'hello', ?, 1, ? FROM conversations WHERE uuid = 'conv-1'
+		`, fmt.Sprintf("activity-%d", i), msg.sender, msg.created, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(1, 0, 0)
+	counts, err := engine.MessageActivity(from, to, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]int{"2024-03-01": 3, "2024-03-05": 1}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("expected %v, got %v", want, counts)
+	}
+
+	counts, err = engine.MessageActivity(from, to, "assistant")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]int{"2024-03-01": 1}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("expected %v for the assistant, got %v", want, counts)
+	}
+
+	tokyo := time.FixedZone("JST", 9*60*60)
+	counts, err = engine.MessageActivity(time.Date(2024, 1, 1, 0, 0, 0, 0, tokyo), time.Date(2025, 1, 1, 0, 0, 0, 0, tokyo), "human")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]int{"2024-03-01": 1, "2024-03-02": 1, "2024-03-05": 1}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("expected %v in Tokyo, got %v", want, counts)
+	}
+}
+
+func TestSlugsAndAliases(t *testing.T) {
+	engine, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	created := time.Date(2024, 5, 10, 9, 0, 0, 0, time.UTC)
+	var ids []int64
+	for _, name := range []string{"Python: pandas cleanup", "Python: pandas cleanup", "Don't panic!"} {
+		result, err := engine.DB().Exec(`
+			INSERT INTO conversations (uuid, name, created_at, updated_at, message_count)
+			VALUES (?, ?, ?, ?, 0)
+		`, fmt.Sprintf("slug-%d", len(ids)), name, created, created)
+		if err != nil {
+			t.Fatal(err)
+		}
+		id, _ := result.LastInsertId()
+		ids = append(ids, id)
+	}
+
+	for ref, want := range map[string]int64{
+		"python-pandas-cleanup-2024-05":   ids[0],
+		"python-pandas-cleanup-2024-05-2": ids[1],
+		"dont-panic-2024-05":              ids[2],
+		fmt.Sprint(ids[1]):                ids[1],
+	} {
+		got, err := engine.ResolveConversation(ref)
+		if err != nil || got != want {
+			t.Errorf("ResolveConversation(%q) = %d, %v; want %d", ref, got, err, want)
+		}
+	}
+
+	if err := engine.SetAlias(ids[2], "towel"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := engine.ResolveConversation("towel"); err != nil || got != ids[2] {
+		t.Errorf("ResolveConversation(towel) = %d, %v; want %d", got, err, ids[2])
+	}
+	if err := engine.SetAlias(ids[0], "towel"); err == nil {
+		t.Error("an alias naming another conversation was accepted")
+	}
+	if err := engine.SetAlias(ids[0], "dont-panic-2024-05"); err == nil {
+		t.Error("an alias equal to another conversation's slug was accepted")
+	}
+	if err := engine.SetAlias(ids[0], "2024"); err == nil {
+		t.Error("an alias of digits was accepted")
+	}
+
+	// A conversation imported later whose slug is taken by an alias gets another one
+	if err := engine.SetAlias(ids[2], "towel-2024-05"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := engine.DB().Exec(`
+		INSERT INTO conversations (uuid, name, created_at, updated_at, message_count)
+		VALUES ('slug-3', 'Towel', ?, ?, 0)
+	`, created, created); err != nil {
+		t.Fatal(err)
+	}
+	names, err := engine.GetConversationNames(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last := names[len(names)-1]; last.Title != "Towel" || last.Slug != "towel-2024-05-2" {
+		t.Errorf("got %+v, want the new conversation slugged towel-2024-05-2", names[len(names)-1])
+	}
+
+	aliased, err := engine.GetConversationNames(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(aliased) != 1 || !reflect.DeepEqual(aliased[0].Aliases, []string{"towel", "towel-2024-05"}) {
+		t.Errorf("got %+v, want one conversation with two aliases", aliased)
+	}
+
+	if err := engine.RemoveAlias("towel"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := engine.ResolveConversation("towel"); err == nil {
+		t.Error("a removed alias still resolves")
+	}
+}
+
+func TestUsage(t *testing.T) {
+	engine, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// Python Development is viewed three times on one day, Test Project
+	// Alpha viewed and exported on two
+	now := time.Now().UTC()
+	for _, access := range []struct {
+		convID int64
+		action string
+		at     time.Time
+	}{
+		{1, AccessView, now.AddDate(0, 0, -1)},
+		{1, AccessView, now.AddDate(0, 0, -1)},
+		{1, AccessView, now.AddDate(0, 0, -1)},
+		{2, AccessView, now.AddDate(0, 0, -40)},
+		{2, AccessExport, now.AddDate(0, 0, -3)},
+	} {
+		if _, err := engine.DB().Exec(`INSERT INTO access_log (action, conversation_id, accessed_at) VALUES (?, ?, ?)`,
+			access.action, access.convID, access.at.Format(accessTimeLayout)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, query := range []string{"python", "Python ", "alice", "  "} {
+		if err := engine.LogSearch(query, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	usage, err := engine.GetUsage(time.Time{}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(usage.Conversations) != 2 {
+		t.Fatalf("expected 2 conversations, got %d", len(usage.Conversations))
+	}
+	if got := usage.Conversations[0]; got.Name != "Test Project Alpha" || got.Days != 2 || got.Views != 1 || got.Exports != 1 {
+		t.Errorf("expected Test Project Alpha revisited on 2 days first, got %+v", got)
+	}
+	if got := usage.Conversations[1]; got.Name != "Python Development" || got.Days != 1 || got.Views != 3 {
+		t.Errorf("expected Python Development viewed 3 times on 1 day second, got %+v", got)
+	}
+	if len(usage.Searches) != 2 || usage.Searches[0].Count != 2 || !strings.EqualFold(usage.Searches[0].Query, "python") {
+		t.Errorf("expected python searched twice ignoring case, then alice, got %+v", usage.Searches)
+	}
+
+	// Since drops the view 40 days ago, leaving Python Development ahead
+	usage, err = engine.GetUsage(now.AddDate(0, 0, -30), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(usage.Conversations) != 1 || usage.Conversations[0].Name != "Python Development" {
+		t.Errorf("expected only Python Development in the last 30 days with a limit of 1, got %+v", usage.Conversations)
+	}
+	if len(usage.Searches) != 1 {
+		t.Errorf("expected a limit of 1 search, got %d", len(usage.Searches))
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	engine, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	for _, took := range []time.Duration{3 * time.Millisecond, 40 * time.Millisecond, 2 * time.Second, 0} {
+		if err := engine.LogSearch("python", took); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := engine.RateMessage(2, RatingUseful, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := engine.GetMetrics()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Conversations != 2 || m.Messages["human"] != 3 || m.Messages["assistant"] != 2 {
+		t.Errorf("expected 2 conversations with 3 human and 2 assistant messages, got %d and %v", m.Conversations, m.Messages)
+	}
+	if m.Ratings[RatingUseful] != 1 || m.Ratings[RatingWrong] != 0 || m.DatabaseBytes == 0 {
+		t.Errorf("expected one useful rating and a database size, got %v and %d bytes", m.Ratings, m.DatabaseBytes)
+	}
+	if m.Accesses[AccessSearch] != 4 {
+		t.Errorf("expected 4 searches logged, got %d", m.Accesses[AccessSearch])
+	}
+
+	// The untimed search is left out of the histogram
+	h := m.SearchLatency
+	if h.Count != 3 || h.Sum < 2.04 || h.Sum > 2.05 {
+		t.Errorf("expected 3 timed searches taking 2.043s, got %d taking %gs", h.Count, h.Sum)
+	}
+	want := map[float64]int{0.005: 1, 0.025: 1, 0.05: 2, 1: 2, 2.5: 3, 5: 3}
+	for i, bound := range h.Buckets {
+		if n, ok := want[bound]; ok && h.Counts[i] != n {
+			t.Errorf("expected %d searches up to %gs, got %d", n, bound, h.Counts[i])
+		}
+	}
+}
+
+func TestRatings(t *testing.T) {
+	engine, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// msg-2 and msg-5 are the answers, IDs 2 and 5
+	if err := engine.RateMessage(2, RatingUseful, "  worked for me "); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.RateMessage(5, RatingObsolete, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.RateMessage(1, RatingUseful, ""); err == nil {
+		t.Error("expected questions not to be rateable")
+	}
+	if err := engine.RateMessage(2, "great", ""); err == nil {
+		t.Error("expected an unknown rating to be refused")
+	}
+
+	_, messages, err := engine.GetConversation(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if messages[1].Rating != RatingUseful || messages[1].RatingNote != "worked for me" || messages[0].Rating != "" {
+		t.Errorf("expected the answer to carry its rating and note, got %+v", messages[1])
+	}
+
+	// Rating filters work in the query and as an option
+	for _, opts := range []SearchOptions{
+		{Query: "rating:useful python", Limit: 10},
+		{Query: "python RATING:Useful", Limit: 10},
+		{Query: "python", Rating: RatingUseful, Limit: 10},
+	} {
+		results, err := engine.Search(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 1 || results[0].MessageUUID != "msg-2" {
+			t.Errorf("expected only the useful answer for %+v, got %d results", opts, len(results))
+		}
+	}
+	if _, err := engine.Search(SearchOptions{Query: "python rating:usefull"}); err == nil {
+		t.Error("expected an unknown rating in the query to be an error")
+	}
+	if terms := QueryTerms("rating:useful python"); len(terms) != 1 || terms[0] != "python" {
+		t.Errorf("expected the rating filter not to be a term, got %v", terms)
+	}
+
+	rated, err := engine.GetRatedMessages(RatingObsolete)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rated) != 1 || rated[0].ConversationName != "Test Project Alpha" {
+		t.Errorf("expected the obsolete answer, got %+v", rated)
+	}
+
+	if cleared, err := engine.ClearRating(2); err != nil || !cleared {
+		t.Fatalf("expected the rating to be cleared, got %v, %v", cleared, err)
+	}
+	if cleared, _ := engine.ClearRating(2); cleared {
+		t.Error("expected nothing to clear the second time")
+	}
+	if rated, _ := engine.GetRatedMessages(""); len(rated) != 1 {
+		t.Errorf("expected 1 rated answer left, got %d", len(rated))
+	}
+
+	msg, err := engine.ResolveMessage(1, "2")
+	if err != nil || msg.UUID != "msg-2" {
+		t.Errorf("expected message 2 to be msg-2, got %+v, %v", msg, err)
+	}
+	if _, err := engine.ResolveMessage(1, "msg-4"); err == nil {
+		t.Error("expected a message of another conversation not to resolve")
+	}
+}
+
+func TestClaudeURL(t *testing.T) {
+	engine, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// A part split off conv-1, and a part split off that part
+	for _, uuid := range []string{"conv-1-part-a", "conv-1-part-b"} {
+		if _, err := engine.db.Exec(`
+			INSERT INTO conversations (uuid, name, created_at, updated_at, message_count)
+			VALUES (?, 'Part', ?, ?, 0)
+		`, uuid, time.Now(), time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := engine.db.Exec(`
+		INSERT INTO conversation_splits (conversation_id, source_id)
+		SELECT part.id, source.id FROM conversations part, conversations source
+		WHERE (part.uuid = 'conv-1-part-a' AND source.uuid = 'conv-1')
+		   OR (part.uuid = 'conv-1-part-b' AND source.uuid = 'conv-1-part-a')
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, uuid := range []string{"conv-1", "conv-1-part-a", "conv-1-part-b"} {
+		var id int64
+		if err := engine.db.QueryRow("SELECT id FROM conversations WHERE uuid = ?", uuid).Scan(&id); err != nil {
+			t.Fatal(err)
+		}
+		url, err := engine.ClaudeURL(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if url != "https://claude.ai/chat/conv-1" {
+			t.Errorf("expected %s to link to conv-1, got %s", uuid, url)
+		}
+	}
+
+	if _, err := engine.ClaudeURL(9999); err == nil {
+		t.Error("expected an error for a missing conversation")
+	}
+}
+
+func TestSearchBranches(t *testing.T) {
+	engine, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// msg-2 answers msg-1 and msg-3 follows it on main; a regenerated answer
+	// to msg-1 starts a branch, with a reply of its own
+	created := time.Now().AddDate(0, 0, -20).Format("2006-01-02 15:04:05")
+	for _, stmt := range []string{
+		`UPDATE messages SET parent_id = 1 WHERE uuid = 'msg-2'`,
+		`UPDATE messages SET parent_id = 2 WHERE uuid = 'msg-3'`,
+		`INSERT INTO branches (id, conversation_id, name, parent_branch_id) VALUES (10, 1, 'regen-1', 1)`,
+		`INSERT INTO messages (id, uuid, conversation_id, sender, text, created_at, parent_id, branch_id, sequence)
+		 VALUES (20, 'msg-b1', 1, 'assistant', 'R is better for statistics', '` + created + `', 1, 10, 5)`,
+		`INSERT INTO messages (id, uuid, conversation_id, sender, text, created_at, parent_id, branch_id, sequence)
+		 VALUES (21, 'msg-b2', 1, 'human', 'Which statistics packages?', '` + created + `', 20, 10, 6)`,
+	} {
+		if _, err := engine.db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := engine.Search(SearchOptions{Query: "statistics", SortBy: "date", SortOrder: "asc", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Branch != "regen-1" {
+		t.Fatalf("expected 2 results on regen-1, got %+v", results)
+	}
+	results, err = engine.Search(SearchOptions{Query: "Django", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Branch != "" {
+		t.Errorf("expected a result on the main branch, got %+v", results)
+	}
+
+	_, main, err := engine.GetConversation(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(main) != 3 {
+		t.Errorf("expected the main branch to have 3 messages, got %d", len(main))
+	}
+
+	id, err := engine.FindMessage(1, "msg-b")
+	if err == nil {
+		t.Errorf("expected the start of two UUIDs to be ambiguous, got %d", id)
+	}
+	if id, err = engine.FindMessage(1, "msg-b1"); err != nil || id != 20 {
+		t.Fatalf("expected msg-b1 to be message 20, got %d, %v", id, err)
+	}
+	if branch, err := engine.BranchName(id); err != nil || branch != "regen-1" {
+		t.Errorf("expected branch regen-1, got %q, %v", branch, err)
+	}
+	if branch, err := engine.BranchName(2); err != nil || branch != "" {
+		t.Errorf("expected no name for the main branch, got %q, %v", branch, err)
+	}
+
+	_, thread, err := engine.GetThread(1, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var uuids []string
+	for _, msg := range thread {
+		uuids = append(uuids, msg.UUID)
+	}
+	if got := strings.Join(uuids, " "); got != "msg-1 msg-b1 msg-b2" {
+		t.Errorf("expected the thread through the branch, got %s", got)
+	}
+	if _, _, err := engine.GetThread(1, 4); err == nil {
+		t.Error("expected an error for a message of another conversation")
+	}
+}
+
+func TestSearchDistinctConversation(t *testing.T) {
+	engine, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	all, err := engine.Search(SearchOptions{Query: "python OR alice", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 5 {
+		t.Fatalf("expected every matching message, got %d", len(all))
+	}
+
+	for _, rank := range RankModes {
+		results, err := engine.Search(SearchOptions{Query: "python OR alice", Rank: rank, Distinct: DistinctConversation, Limit: 10})
+		if err != nil {
+			t.Fatalf("%s: %v", rank, err)
+		}
+		if len(results) != 2 || results[0].ConversationID == results[1].ConversationID {
+			t.Errorf("%s: expected one result from each conversation, got %+v", rank, results)
+		}
+	}
+
+	// The best match by the sort order is kept, and pages count conversations
+	results, err := engine.Search(SearchOptions{Query: "python OR alice", SortBy: "date", SortOrder: "asc", Distinct: DistinctConversation, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].MessageUUID != "msg-1" {
+		t.Errorf("expected the first message of the oldest conversation, got %+v", results)
+	}
+	results, err = engine.Search(SearchOptions{Query: "python OR alice", SortBy: "date", SortOrder: "asc", Distinct: DistinctConversation, Limit: 1, Offset: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].MessageUUID != "msg-4" {
+		t.Errorf("expected the first message of the second conversation, got %+v", results)
+	}
+
+	if _, err := engine.Search(SearchOptions{Query: "python", Distinct: "message"}); err == nil {
+		t.Error("expected an unknown distinct mode to be refused")
+	}
+}
+
+func TestAskedBefore(t *testing.T) {
+	engine, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	found, err := engine.AskedBefore(context.Background(), "how can I do machine learning in python", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 {
+		t.Fatalf("expected 1 earlier question, got %d", len(found))
+	}
+	q := found[0]
+	if q.Question != "How do I use Python for machine learning?" {
+		t.Errorf("question = %q", q.Question)
+	}
+	if !strings.HasPrefix(q.Answer, "Python is great for machine learning") {
+		t.Errorf("answer = %q, want the reply after the question", q.Answer)
+	}
+	if q.Similarity < 0.99 {
+		t.Errorf("similarity = %.2f, want 1 for the same words", q.Similarity)
+	}
+
+	// A question sharing one word of several isn't similar enough
+	found, err = engine.AskedBefore(context.Background(), "python packaging with poetry and wheels", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 0 {
+		t.Errorf("expected no earlier questions, got %q", found[0].Question)
+	}
+
+	if _, err := engine.AskedBefore(context.Background(), "how do I?", 5); err == nil {
+		t.Error("expected an error for a question without content words")
+	}
+}
+
+func TestStem(t *testing.T) {
+	for _, pair := range [][2]string{
+		{"profiling", "profile"},
+		{"goroutines", "goroutine"},
+		{"queries", "query"},
+		{"created", "create"},
+	} {
+		if stem(pair[0]) != stem(pair[1]) {
+			t.Errorf("stem(%q) = %q, stem(%q) = %q, want the same", pair[0], stem(pair[0]), pair[1], stem(pair[1]))
+		}
+	}
+}
+
+func TestLineage(t *testing.T) {
+	engine, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// A follow-up to the Python conversation (1), started after both
+	database := engine.DB()
+	res, err := database.Exec(`INSERT INTO conversations (uuid, name, created_at, updated_at, message_count) VALUES (?, ?, ?, ?, ?)`,
+		"conv-3", "Python machine learning, part 2", time.Now(), time.Now(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conv3, _ := res.LastInsertId()
+	res, err = database.Exec(`INSERT INTO branches (conversation_id, name) VALUES (?, 'main')`, conv3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	branch, _ := res.LastInsertId()
+	if _, err := database.Exec(`INSERT INTO messages (uuid, conversation_id, sender, text, created_at, branch_id, sequence) VALUES (?, ?, ?, ?, ?, ?, 0)`,
+		"msg-6", conv3, "human", "Carrying on with machine learning in Python: which scikit-learn model?", time.Now().Format("2006-01-02 15:04:05"), branch); err != nil {
+		t.Fatal(err)
+	}
+
+	candidates, err := engine.SuggestContinued(context.Background(), conv3, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candidates) == 0 || candidates[0].ConversationID != 1 {
+		t.Fatalf("expected conversation 1 to be suggested first, got %+v", candidates)
+	}
+
+	if err := engine.Continue(conv3, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.Continue(2, conv3); err != nil {
+		t.Fatal(err)
+	}
+	lineage, err := engine.GetLineage(conv3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lineage.Continues == nil || lineage.Continues.ID != 1 || lineage.ContinuedIn == nil || lineage.ContinuedIn.ID != 2 {
+		t.Errorf("lineage = %+v, want continuing 1 and continued in 2", lineage)
+	}
+	chain, err := engine.Chain(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(chain) != fmt.Sprint([]int64{1, conv3, 2}) {
+		t.Errorf("chain = %v, want [1 %d 2]", chain, conv3)
+	}
+
+	// Links can't loop or branch
+	if err := engine.Continue(1, 2); err == nil {
+		t.Error("expected an error linking the chain into a loop")
+	}
+	if err := engine.Continue(conv3, 2); err == nil {
+		t.Error("expected an error linking a conversation that already continues another")
+	}
+	candidates, err = engine.SuggestContinued(context.Background(), conv3, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range candidates {
+		if c.ConversationID == 1 {
+			t.Error("expected a conversation already continued not to be suggested")
+		}
+	}
+
+	previous, err := engine.Unlink(conv3)
+	if err != nil || previous != 1 {
+		t.Fatalf("Unlink = %d, %v, want 1", previous, err)
+	}
+	if chain, _ := engine.Chain(2); len(chain) != 2 {
+		t.Errorf("chain after unlinking = %v, want [%d 2]", chain, conv3)
+	}
+}
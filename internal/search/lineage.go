@@ -0,0 +1,324 @@
+package search
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// LinkedConversation is a conversation at the other end of a continuation link
+type LinkedConversation struct {
+	ID   int64
+	Name string
+}
+
+// Lineage is where a conversation sits in a chain of follow-ups: the
+// conversation it carries on from and the one carrying it on, either of
+// which may be nil
+type Lineage struct {
+	Continues   *LinkedConversation
+	ContinuedIn *LinkedConversation
+}
+
+// Empty reports whether the conversation isn't linked to any other
+func (l *Lineage) Empty() bool {
+	return l.Continues == nil && l.ContinuedIn == nil
+}
+
+// ContinuationCandidate is an earlier conversation that a conversation may
+// carry on from
+type ContinuationCandidate struct {
+	ConversationID   int64
+	ConversationName string
+	UpdatedAt        time.Time
+	Similarity       float64 // from 0 to 1
+}
+
+// MinContinuationSimilarity is the similarity below which SuggestContinued
+// leaves earlier conversations out
+const MinContinuationSimilarity = 0.2
+
+// GetLineage returns the conversations a conversation continues and is
+// continued in
+func (e *Engine) GetLineage(conversationID int64) (*Lineage, error) {
+	lineage := &Lineage{}
+	err := e.collect(`
+		SELECT 'continues', c.id, c.name
+		FROM conversation_continuations l
+		JOIN conversations c ON c.id = l.previous_id
+		WHERE l.conversation_id = ?1
+		UNION ALL
+		SELECT 'continued', c.id, c.name
+		FROM conversation_continuations l
+		JOIN conversations c ON c.id = l.conversation_id
+		WHERE l.previous_id = ?1
+	`, func(rows *sql.Rows) error {
+		var direction string
+		var linked LinkedConversation
+		if err := rows.Scan(&direction, &linked.ID, &linked.Name); err != nil {
+			return err
+		}
+		if direction == "continues" {
+			lineage.Continues = &linked
+		} else {
+			lineage.ContinuedIn = &linked
+		}
+		return nil
+	}, conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load conversation links: %w", err)
+	}
+	return lineage, nil
+}
+
+// Continue links a conversation as carrying on from an earlier one. A
+// conversation continues at most one other and is continued in at most one,
+// and a chain can't loop back on itself.
+func (e *Engine) Continue(conversationID, previousID int64) error {
+	if conversationID == previousID {
+		return fmt.Errorf("a conversation can't continue itself")
+	}
+	for _, id := range []int64{conversationID, previousID} {
+		var exists int
+		if err := e.db.QueryRow("SELECT COUNT(*) FROM conversations WHERE id = ?", id).Scan(&exists); err != nil {
+			return fmt.Errorf("failed to look up conversation: %w", err)
+		}
+		if exists == 0 {
+			return fmt.Errorf("conversation %d not found", id)
+		}
+	}
+
+	lineage, err := e.GetLineage(conversationID)
+	if err != nil {
+		return err
+	}
+	switch {
+	case lineage.Continues != nil && lineage.Continues.ID == previousID:
+		return nil
+	case lineage.Continues != nil:
+		return fmt.Errorf("conversation %d already continues %d; unlink it first", conversationID, lineage.Continues.ID)
+	}
+	previous, err := e.GetLineage(previousID)
+	if err != nil {
+		return err
+	}
+	if previous.ContinuedIn != nil {
+		return fmt.Errorf("conversation %d is already continued in %d; unlink that first", previousID, previous.ContinuedIn.ID)
+	}
+
+	chain, err := e.Chain(conversationID)
+	if err != nil {
+		return err
+	}
+	for _, id := range chain {
+		if id == previousID {
+			return fmt.Errorf("conversation %d already follows on from %d", previousID, conversationID)
+		}
+	}
+
+	if _, err := e.db.Exec("INSERT INTO conversation_continuations (conversation_id, previous_id) VALUES (?, ?)", conversationID, previousID); err != nil {
+		return fmt.Errorf("failed to save link: %w", err)
+	}
+	return nil
+}
+
+// Unlink removes the link from a conversation to the one it continues,
+// returning that conversation's ID, or 0 if it wasn't linked
+func (e *Engine) Unlink(conversationID int64) (int64, error) {
+	var previousID int64
+	err := e.db.QueryRow(`
+		DELETE FROM conversation_continuations WHERE conversation_id = ?
+		RETURNING previous_id
+	`, conversationID).Scan(&previousID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("failed to remove link: %w", err)
+	}
+	return previousID, nil
+}
+
+// Chain returns the IDs of the conversations in the chain of follow-ups a
+// conversation is part of, from the first to the last. A conversation that
+// isn't linked is a chain of one.
+func (e *Engine) Chain(conversationID int64) ([]int64, error) {
+	var chain []int64
+	err := e.collect(`
+		WITH RECURSIVE
+			earlier(id) AS (
+				SELECT ?1
+				UNION
+				SELECT l.previous_id
+				FROM conversation_continuations l
+				JOIN earlier e ON l.conversation_id = e.id
+			),
+			later(id) AS (
+				SELECT ?1
+				UNION
+				SELECT l.conversation_id
+				FROM conversation_continuations l
+				JOIN later e ON l.previous_id = e.id
+			)
+		SELECT id FROM earlier
+		UNION
+		SELECT id FROM later
+		ORDER BY 1
+	`, func(rows *sql.Rows) error {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		chain = append(chain, id)
+		return nil
+	}, conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to follow conversation links: %w", err)
+	}
+	return e.orderChain(chain)
+}
+
+// orderChain puts the conversations of a chain in the order they follow on
+// from each other
+func (e *Engine) orderChain(ids []int64) ([]int64, error) {
+	if len(ids) < 2 {
+		return ids, nil
+	}
+	next := make(map[int64]int64)
+	hasPrevious := make(map[int64]bool)
+	in := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		in[id] = true
+	}
+	err := e.collect("SELECT conversation_id, previous_id FROM conversation_continuations", func(rows *sql.Rows) error {
+		var id, previousID int64
+		if err := rows.Scan(&id, &previousID); err != nil {
+			return err
+		}
+		if in[id] && in[previousID] {
+			next[previousID] = id
+			hasPrevious[id] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load conversation links: %w", err)
+	}
+
+	var first int64
+	for _, id := range ids {
+		if !hasPrevious[id] {
+			first = id
+			break
+		}
+	}
+	ordered := make([]int64, 0, len(ids))
+	for id, ok := first, true; ok && len(ordered) < len(ids); id, ok = next[id] {
+		ordered = append(ordered, id)
+	}
+	return ordered, nil
+}
+
+// SuggestContinued finds earlier conversations that a conversation may carry
+// on from, most likely first. Candidates are those started before it and not
+// already continued, with messages matching the words of its title and
+// opening message in the stemmed full-text index. They are scored by how
+// alike the titles are and how many of those words their matching messages
+// share.
+func (e *Engine) SuggestContinued(ctx context.Context, conversationID int64, limit int) ([]*ContinuationCandidate, error) {
+	var name, opening string
+	err := e.db.QueryRow(`
+		SELECT c.name, COALESCE((
+			SELECT message_text(m.text) FROM messages m
+			WHERE m.conversation_id = c.id AND m.sender = 'human'
+			ORDER BY m.created_at, m.id
+			LIMIT 1
+		), '')
+		FROM conversations c
+		WHERE c.id = ?
+	`, conversationID).Scan(&name, &opening)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("conversation %d not found", conversationID)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to load conversation: %w", err)
+	}
+
+	titleStems := stems(e.questionTerms(name))
+	terms := e.questionTerms(name + " " + opening)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	wanted := stems(terms)
+
+	alternatives := make([]string, len(terms))
+	for i, term := range terms {
+		alternatives[i] = e.dictionary.expand(term)
+	}
+	rows, err := e.db.QueryContext(ctx, `
+		SELECT m.conversation_id, c.name, c.updated_at, message_text(m.text)
+		FROM messages_fts
+		JOIN messages m ON messages_fts.rowid = m.id
+		JOIN conversations c ON m.conversation_id = c.id
+		WHERE messages_fts MATCH ?1 AND c.id != ?2
+			AND c.created_at < (SELECT created_at FROM conversations WHERE id = ?2)
+			AND c.id NOT IN (SELECT previous_id FROM conversation_continuations)
+		ORDER BY rank
+		LIMIT ?3
+	`, strings.Join(alternatives, " OR "), conversationID, questionCandidates)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, queryError(name, err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close rows: %v\n", err)
+		}
+	}()
+
+	candidates := make(map[int64]*ContinuationCandidate)
+	shared := make(map[int64]map[string]bool)
+	for rows.Next() {
+		var c ContinuationCandidate
+		var text string
+		if err := rows.Scan(&c.ConversationID, &c.ConversationName, &c.UpdatedAt, &text); err != nil {
+			return nil, fmt.Errorf("failed to scan conversation: %w", err)
+		}
+		if _, ok := candidates[c.ConversationID]; !ok {
+			candidates[c.ConversationID] = &c
+			shared[c.ConversationID] = make(map[string]bool)
+		}
+		for stem := range stems(e.questionTerms(text)) {
+			if wanted[stem] {
+				shared[c.ConversationID][stem] = true
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	found := make([]*ContinuationCandidate, 0, len(candidates))
+	for id, c := range candidates {
+		content := float64(len(shared[id])) / float64(len(wanted))
+		title := questionSimilarity(titleStems, stems(e.questionTerms(c.ConversationName)))
+		c.Similarity = (content + title) / 2
+		if c.Similarity >= MinContinuationSimilarity {
+			found = append(found, c)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].Similarity != found[j].Similarity {
+			return found[i].Similarity > found[j].Similarity
+		}
+		return found[i].UpdatedAt.After(found[j].UpdatedAt)
+	})
+	if limit > 0 && len(found) > limit {
+		found = found[:limit]
+	}
+	return found, nil
+}
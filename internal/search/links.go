@@ -0,0 +1,38 @@
+package search
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// ClaudeURL returns the claude.ai link to a conversation. Conversations split
+// off another locally don't exist on claude.ai, so they link to the one they
+// were split from; claude.ai has no links to single messages.
+func (e *Engine) ClaudeURL(conversationID int64) (string, error) {
+	var uuid string
+	err := e.db.QueryRow(`
+		WITH RECURSIVE origin(id, depth) AS (
+			SELECT ?, 0
+			UNION ALL
+			SELECT s.source_id, o.depth + 1
+			FROM conversation_splits s
+			JOIN origin o ON s.conversation_id = o.id
+		)
+		SELECT c.uuid
+		FROM origin
+		JOIN conversations c ON c.id = origin.id
+		ORDER BY origin.depth DESC
+		LIMIT 1
+	`, conversationID).Scan(&uuid)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("conversation %d not found", conversationID)
+	} else if err != nil {
+		return "", fmt.Errorf("failed to look up conversation %d: %w", conversationID, err)
+	}
+	return ConversationURL(uuid), nil
+}
+
+// ConversationURL is the claude.ai link to the conversation with uuid
+func ConversationURL(uuid string) string {
+	return "https://claude.ai/chat/" + uuid
+}
@@ -0,0 +1,142 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+// ConversationMatch is a conversation with messages matching a search
+type ConversationMatch struct {
+	Conversation *models.Conversation
+	Matches      int    // number of matching messages
+	Snippet      string // snippet of the best match, with <mark> tags
+}
+
+// SearchConversationMatches groups the matches for a search by conversation,
+// best conversation first, counting the matches of each in SQL instead of
+// loading them. The rank mode orders conversations by their best match, the
+// most recently updated first, or the hybrid score of their best match.
+// Limit and offset apply to conversations; SortBy and SortOrder are ignored.
+func (e *Engine) SearchConversationMatches(ctx context.Context, opts SearchOptions) ([]*ConversationMatch, error) {
+	if err := checkFilters(opts); err != nil {
+		return nil, err
+	}
+	opts = withIndexPrefix(opts)
+	ftsTable := e.ftsTable(opts)
+
+	// SQLite takes the bare m.id column from the row with the best rank
+	query := fmt.Sprintf(`
+		SELECT
+			c.id, c.uuid, c.name, c.created_at, c.updated_at, c.message_count, c.imported_at,
+			c.token_count, c.artifact_count, c.human_message_count, c.duration_seconds, c.active_days,
+			COUNT(*) AS matches,
+			MIN(rank) AS best,
+			m.id
+		FROM %s
+		JOIN messages m ON %s.rowid = m.id
+		JOIN conversations c ON m.conversation_id = c.id
+		WHERE %s MATCH ?1
+	`, ftsTable, ftsTable, ftsTable)
+
+	conditions, args := e.buildFilters(opts)
+	if len(conditions) > 0 {
+		query += " AND " + strings.Join(conditions, " AND ")
+	}
+	query += " GROUP BY c.id"
+
+	switch opts.Rank {
+	case RankRecency:
+		query += " ORDER BY c.updated_at DESC, best"
+	case RankHybrid:
+		query += " ORDER BY " + hybridScoreOf("best", "matches") + " DESC"
+	default: // relevance
+		query += " ORDER BY best"
+	}
+
+	if opts.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", opts.Limit)
+		if opts.Offset > 0 {
+			query += fmt.Sprintf(" OFFSET %d", opts.Offset)
+		}
+	}
+
+	rows, err := e.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, queryError(opts.Query, err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close rows: %v\n", err)
+		}
+	}()
+
+	var matches []*ConversationMatch
+	byMessage := make(map[int64]*ConversationMatch)
+	for rows.Next() {
+		var c models.Conversation
+		var match ConversationMatch
+		var best float64
+		var messageID int64
+		err := rows.Scan(&c.ID, &c.UUID, &c.Name, &c.CreatedAt, &c.UpdatedAt, &c.MessageCount, &c.ImportedAt,
+			&c.TokenCount, &c.ArtifactCount, &c.HumanMessageCount, &c.DurationSeconds, &c.ActiveDays,
+			&match.Matches, &best, &messageID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan conversation match: %w", err)
+		}
+		match.Conversation = &c
+		matches = append(matches, &match)
+		byMessage[messageID] = &match
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating conversation matches: %w", err)
+	}
+
+	// FTS5 can't build snippets in an aggregate query, so fetch those of the
+	// best matches separately
+	if len(byMessage) > 0 {
+		ids := make([]string, 0, len(byMessage))
+		for id := range byMessage {
+			ids = append(ids, strconv.FormatInt(id, 10))
+		}
+		snippets, err := e.db.QueryContext(ctx, fmt.Sprintf(`
+			SELECT rowid, snippet(%s, 0, '<mark>', '</mark>', '...', 16)
+			FROM %s
+			WHERE %s MATCH ? AND rowid IN (%s)
+		`, ftsTable, ftsTable, ftsTable, strings.Join(ids, ",")), args[0])
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, fmt.Errorf("failed to load snippets: %w", err)
+		}
+		defer func() {
+			if err := snippets.Close(); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to close rows: %v\n", err)
+			}
+		}()
+		for snippets.Next() {
+			var id int64
+			var snippet string
+			if err := snippets.Scan(&id, &snippet); err != nil {
+				return nil, fmt.Errorf("failed to scan snippet: %w", err)
+			}
+			byMessage[id].Snippet = snippet
+		}
+		if err := snippets.Err(); err != nil {
+			return nil, fmt.Errorf("error iterating snippets: %w", err)
+		}
+	}
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return matches, nil
+}
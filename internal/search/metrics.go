@@ -0,0 +1,142 @@
+package search
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SearchLatencyBuckets are the upper bounds, in seconds, of the search
+// latency histogram
+var SearchLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+// Histogram counts observations into buckets by upper bound
+type Histogram struct {
+	Buckets []float64 // upper bounds
+	Counts  []int     // cumulative: observations up to each bound
+	Count   int
+	Sum     float64
+}
+
+// Metrics are the archive totals and usage counters graphed from
+// `shannon stats --openmetrics`
+type Metrics struct {
+	Conversations int
+	Messages      map[string]int // by sender
+	Branches      int
+	CodeBlocks    map[string]int // by kind: codeblock or artifact
+	Ratings       map[string]int // by rating
+	DatabaseBytes int64
+
+	Imports               map[string]int // by status: success, partial or failed
+	ImportedConversations int
+	ImportedMessages      int
+	LastImport            time.Time // zero if nothing was imported
+
+	Accesses      map[string]int // views, exports and searches from the access log
+	SearchLatency Histogram      // in seconds, of the searches that were timed
+}
+
+// GetMetrics collects the archive totals, import counters and search latency
+func (e *Engine) GetMetrics() (*Metrics, error) {
+	m := &Metrics{
+		Messages:   map[string]int{"human": 0, "assistant": 0},
+		CodeBlocks: map[string]int{"codeblock": 0, "artifact": 0},
+		Ratings:    make(map[string]int),
+		Imports:    map[string]int{"success": 0, "partial": 0, "failed": 0},
+		Accesses:   map[string]int{AccessView: 0, AccessExport: 0, AccessSearch: 0},
+	}
+	for _, r := range Ratings {
+		m.Ratings[r] = 0
+	}
+
+	if err := e.db.QueryRow("SELECT COUNT(*) FROM conversations WHERE deleted_at IS NULL").Scan(&m.Conversations); err != nil {
+		return nil, fmt.Errorf("failed to count conversations: %w", err)
+	}
+	if err := e.db.QueryRow("SELECT COUNT(*) FROM branches WHERE " + notTrashed).Scan(&m.Branches); err != nil {
+		return nil, fmt.Errorf("failed to count branches: %w", err)
+	}
+	if err := e.db.QueryRow("SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size()").Scan(&m.DatabaseBytes); err != nil {
+		return nil, fmt.Errorf("failed to get database size: %w", err)
+	}
+
+	counts := []struct {
+		query  string
+		counts map[string]int
+	}{
+		{"SELECT sender, COUNT(*) FROM messages WHERE " + notTrashed + " GROUP BY sender", m.Messages},
+		{"SELECT kind, COUNT(*) FROM code_blocks WHERE " + notTrashed + " GROUP BY kind", m.CodeBlocks},
+		{"SELECT r.rating, COUNT(*) FROM message_ratings r JOIN messages m ON m.id = r.message_id WHERE m." + notTrashed + " GROUP BY r.rating", m.Ratings},
+		{"SELECT status, COUNT(*) FROM import_history GROUP BY status", m.Imports},
+		{"SELECT action, COUNT(*) FROM access_log GROUP BY action", m.Accesses},
+	}
+	for _, c := range counts {
+		if err := e.collect(c.query, func(rows *sql.Rows) error {
+			var key string
+			var n int
+			if err := rows.Scan(&key, &n); err != nil {
+				return err
+			}
+			c.counts[key] = n
+			return nil
+		}); err != nil {
+			return nil, fmt.Errorf("failed to collect metrics: %w", err)
+		}
+	}
+
+	var lastImport sql.NullString
+	if err := e.db.QueryRow(`
+		SELECT COALESCE(SUM(conversations_count), 0), COALESCE(SUM(messages_count), 0), MAX(imported_at)
+		FROM import_history
+		WHERE status != 'failed'
+	`).Scan(&m.ImportedConversations, &m.ImportedMessages, &lastImport); err != nil {
+		return nil, fmt.Errorf("failed to sum imports: %w", err)
+	}
+	if lastImport.Valid {
+		m.LastImport = parseTimestamp(lastImport.String)
+	}
+
+	latency, err := e.searchLatency()
+	if err != nil {
+		return nil, err
+	}
+	m.SearchLatency = *latency
+
+	return m, nil
+}
+
+// searchLatency buckets the durations of the logged searches
+func (e *Engine) searchLatency() (*Histogram, error) {
+	h := &Histogram{Buckets: SearchLatencyBuckets, Counts: make([]int, len(SearchLatencyBuckets))}
+
+	columns := make([]string, len(h.Buckets))
+	for i, bound := range h.Buckets {
+		columns[i] = fmt.Sprintf("COALESCE(SUM(duration_ms <= %g), 0)", bound*1000)
+	}
+	dest := []interface{}{&h.Count, &h.Sum}
+	for i := range h.Counts {
+		dest = append(dest, &h.Counts[i])
+	}
+
+	query := fmt.Sprintf(`
+		SELECT COUNT(*), COALESCE(SUM(duration_ms), 0) / 1000.0, %s
+		FROM access_log
+		WHERE action = 'search' AND duration_ms IS NOT NULL
+	`, strings.Join(columns, ", "))
+	if err := e.db.QueryRow(query).Scan(dest...); err != nil {
+		return nil, fmt.Errorf("failed to measure search latency: %w", err)
+	}
+	return h, nil
+}
+
+// parseTimestamp reads a timestamp as stored by the importer or by SQLite's
+// CURRENT_TIMESTAMP, returning the zero time if it can't
+func parseTimestamp(s string) time.Time {
+	for _, layout := range []string{"2006-01-02 15:04:05.999999999 -0700 MST", accessTimeLayout, time.RFC3339} {
+		if t, err := time.ParseInLocation(layout, s, time.UTC); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,197 @@
+package search
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+// SetPrivate makes a conversation private or public again. The messages of
+// private conversations are taken out of the full-text indexes, so searches
+// only find them when asked to include private conversations. It reports
+// whether the conversation changed.
+func (e *Engine) SetPrivate(conversationID int64, private bool) (bool, error) {
+	var current bool
+	err := e.db.QueryRow("SELECT private FROM conversations WHERE id = ?", conversationID).Scan(&current)
+	if err == sql.ErrNoRows {
+		return false, fmt.Errorf("conversation %d not found", conversationID)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to look up conversation: %w", err)
+	}
+	if current == private {
+		return false, nil
+	}
+
+	// Triggers move the messages out of or back into the indexes
+	if _, err := e.db.Exec("UPDATE conversations SET private = ? WHERE id = ?", private, conversationID); err != nil {
+		return false, fmt.Errorf("failed to update conversation: %w", err)
+	}
+	return true, nil
+}
+
+// PrivateConversation is a conversation kept out of search
+type PrivateConversation struct {
+	ID        int64
+	Name      string
+	UpdatedAt time.Time
+	Messages  int
+}
+
+// GetPrivateConversations returns the private conversations, most recently
+// updated first, leaving out those in the trash
+func (e *Engine) GetPrivateConversations() ([]*PrivateConversation, error) {
+	var convs []*PrivateConversation
+	err := e.collect(`
+		SELECT id, name, updated_at, message_count
+		FROM conversations
+		WHERE private AND deleted_at IS NULL
+		ORDER BY updated_at DESC, id
+	`, func(rows *sql.Rows) error {
+		var c PrivateConversation
+		if err := rows.Scan(&c.ID, &c.Name, &c.UpdatedAt, &c.Messages); err != nil {
+			return err
+		}
+		convs = append(convs, &c)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load private conversations: %w", err)
+	}
+	return convs, nil
+}
+
+// privateSnippetRunes is about how much text a snippet of a private match
+// shows, like the 32 tokens of FTS snippets
+const privateSnippetRunes = 200
+
+// searchPrivate finds the messages of private conversations matching a
+// search. They aren't indexed, so they're scanned for every word and phrase
+// of the query, ignoring its operators. Matches are ordered by date, since
+// there's no relevance score without the index.
+func (e *Engine) searchPrivate(ctx context.Context, opts SearchOptions) ([]*models.SearchResult, error) {
+	terms := QueryTerms(opts.Query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	conditions, args := e.buildFilters(opts)
+	// buildFilters numbers its arguments after the FTS query, which must be
+	// referenced first for them to bind in order, though nothing matches it
+	// here
+	conditions = append([]string{"?1 IS NOT NULL", "c.private", "c.deleted_at IS NULL"}, conditions...)
+	for _, term := range terms {
+		args = append(args, term)
+		conditions = append(conditions, fmt.Sprintf("instr(lower(message_text(m.text)), lower($%d)) > 0", len(args)))
+	}
+
+	direction := " DESC"
+	if opts.SortBy == "date" && opts.SortOrder == "asc" {
+		direction = " ASC"
+	}
+	query := `
+		SELECT c.id, c.uuid, c.name, m.id, m.uuid, m.sender, message_text(m.text), m.created_at,
+		       CASE WHEN b.name IS NULL OR b.name = 'main' THEN '' ELSE b.name END
+		FROM messages m
+		JOIN conversations c ON m.conversation_id = c.id
+		LEFT JOIN branches b ON b.id = m.branch_id
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY m.created_at` + direction
+	if opts.Limit > 0 && opts.Distinct == "" {
+		query += fmt.Sprintf(" LIMIT %d", opts.Limit)
+	}
+
+	rows, err := e.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("private search failed: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close rows: %v\n", err)
+		}
+	}()
+
+	highlight := termPattern(terms)
+	var results []*models.SearchResult
+	seenConversations := make(map[int64]bool)
+	for rows.Next() {
+		var r models.SearchResult
+		if err := rows.Scan(&r.ConversationID, &r.ConversationUUID, &r.ConversationName,
+			&r.MessageID, &r.MessageUUID, &r.Sender, &r.Text, &r.CreatedAt, &r.Branch); err != nil {
+			return nil, fmt.Errorf("failed to scan result: %w", err)
+		}
+		if opts.Distinct == DistinctConversation {
+			if seenConversations[r.ConversationID] {
+				continue
+			}
+			seenConversations[r.ConversationID] = true
+		}
+		r.Snippet = privateSnippet(r.Text, highlight)
+		r.Private = true
+		results = append(results, &r)
+		if opts.Limit > 0 && len(results) == opts.Limit {
+			break
+		}
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return results, rows.Err()
+}
+
+// termPattern matches any of the terms, ignoring case
+func termPattern(terms []string) *regexp.Regexp {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = regexp.QuoteMeta(term)
+	}
+	return regexp.MustCompile("(?i)" + strings.Join(quoted, "|"))
+}
+
+// privateSnippet cuts a snippet around the first match in text and marks
+// the matches with <mark> tags, as FTS snippets are
+func privateSnippet(text string, highlight *regexp.Regexp) string {
+	text = strings.Join(strings.Fields(text), " ")
+	loc := highlight.FindStringIndex(text)
+	if loc == nil {
+		return ""
+	}
+
+	start, end := 0, len(text)
+	if before := utf8.RuneCountInString(text[:loc[0]]); before > privateSnippetRunes/4 {
+		start = runeOffset(text, before-privateSnippetRunes/4)
+	}
+	if utf8.RuneCountInString(text[start:]) > privateSnippetRunes {
+		end = start + runeOffset(text[start:], privateSnippetRunes)
+	}
+
+	snippet := highlight.ReplaceAllString(text[start:end], "<mark>$0</mark>")
+	if start > 0 {
+		snippet = "..." + snippet
+	}
+	if end < len(text) {
+		snippet += "..."
+	}
+	return snippet
+}
+
+// runeOffset returns the byte offset of the nth rune of s
+func runeOffset(s string, n int) int {
+	for i := range s {
+		if n == 0 {
+			return i
+		}
+		n--
+	}
+	return len(s)
+}
@@ -1,6 +1,8 @@
 package search
 
 import (
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -114,6 +116,36 @@ func TestProcessFTSQuery(t *testing.T) {
 	}
 }
 
+func TestProcessFTSQueryDictionary(t *testing.T) {
+	engine := &Engine{}
+	engine.SetDictionary(NewDictionary(
+		[]string{"the", " How ", "to"},
+		[][]string{{"js", "JavaScript"}, {"k8s", "kubernetes"}, {"ml", "machine learning"}, {"c++"}, {"nope", "NOT"}},
+	))
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"js", "(js OR JavaScript)"},
+		{"JS", "(js OR JavaScript)"},
+		{"how to deploy k8s", "deploy AND (k8s OR kubernetes)"},
+		{"the to", "the AND to"}, // only stopwords, kept
+		{"ml models", `(ml OR "machine learning") AND models`},
+		{"js OR python", "(js OR JavaScript) OR python"},
+		{"nope", `(nope OR "NOT")`}, // operators are quoted
+		{`"js tips"`, `"js tips"`},
+		{"js*", "js*"},
+		{"c++", "c++"}, // a group of one is ignored
+	}
+
+	for _, tt := range tests {
+		if result := engine.processFTSQuery(tt.input); result != tt.expected {
+			t.Errorf("processFTSQuery(%q) = %q, want %q", tt.input, result, tt.expected)
+		}
+	}
+}
+
 func TestEscapeFTSQuery(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -151,3 +183,62 @@ func TestEscapeFTSQuery(t *testing.T) {
 		})
 	}
 }
+
+func TestExplain(t *testing.T) {
+	engine := &Engine{}
+
+	tests := []struct {
+		name   string
+		opts   SearchOptions
+		query  string
+		index  string
+		reason string
+	}{
+		{"prose", SearchOptions{Query: "machine learning"}, "machine AND learning", IndexText, "query doesn't look like code"},
+		{"camelCase", SearchOptions{Query: "getUserName"}, "getUserName", IndexCode, "query looks like code: camelCase"},
+		{"code prefix", SearchOptions{Query: "CODE: machine learning"}, "machine AND learning", IndexCode, `query prefix "code:"`},
+		{"text prefix overrides option", SearchOptions{Query: "text:getUserName", Index: IndexCode}, "getUserName", IndexText, `query prefix "text:"`},
+		{"option", SearchOptions{Query: "getUserName", Index: IndexText}, "getUserName", IndexText, "requested explicitly"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := engine.Explain(tt.opts)
+			if e.FTSQuery != tt.query || e.Index != tt.index || !strings.HasPrefix(e.IndexReason, tt.reason) {
+				t.Errorf("unexpected explanation %+v", e)
+			}
+			if !strings.Contains(e.SQL, indexTables[tt.index]+" MATCH ?1") || e.Args[0] != tt.query {
+				t.Errorf("expected the SQL to search %s for %q, got %s %v", indexTables[tt.index], tt.query, e.SQL, e.Args)
+			}
+		})
+	}
+
+	e := engine.Explain(SearchOptions{Query: "python", Sender: "human"})
+	if len(e.Filters) != 1 || e.Filters[0] != `m.sender = "human"` {
+		t.Errorf("expected the sender filter with its value, got %v", e.Filters)
+	}
+}
+
+func TestQueryTerms(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", nil},
+		{"python", []string{"python"}},
+		{"machine learning", []string{"machine", "learning"}},
+		{`"error handling" AND go*`, []string{"error handling", "go"}},
+		{"(python OR golang) NOT java", []string{"python", "golang", "java"}},
+		{"NEAR(build deploy)", []string{"build", "deploy"}},
+		{"code: Python python", []string{"Python"}},
+		{`"unterminated phrase`, []string{"unterminated phrase"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := QueryTerms(tt.query); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("QueryTerms(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package search
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+// Ratings a message can be given with `shannon rate`
+const (
+	RatingUseful   = "useful"
+	RatingObsolete = "obsolete"
+	RatingWrong    = "wrong"
+)
+
+// Ratings lists the ratings in the order of the TUI's number keys
+var Ratings = []string{RatingUseful, RatingObsolete, RatingWrong}
+
+// RatedMessage is a rated message with where it was written
+type RatedMessage struct {
+	MessageID        int64     `json:"message_id"`
+	ConversationID   int64     `json:"conversation_id"`
+	ConversationName string    `json:"conversation_name"`
+	Rating           string    `json:"rating"`
+	Note             string    `json:"note,omitempty"`
+	RatedAt          time.Time `json:"rated_at"`
+	Preview          string    `json:"preview"`
+}
+
+// ValidateRating checks that rating is one of Ratings
+func ValidateRating(rating string) error {
+	for _, r := range Ratings {
+		if rating == r {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown rating %q (use %s)", rating, strings.Join(Ratings, ", "))
+}
+
+// RateMessage rates an assistant message, with an optional note, replacing
+// any rating it had
+func (e *Engine) RateMessage(messageID int64, rating, note string) error {
+	if err := ValidateRating(rating); err != nil {
+		return err
+	}
+
+	var sender string
+	err := e.db.QueryRow("SELECT sender FROM messages WHERE id = ?", messageID).Scan(&sender)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("message %d not found", messageID)
+	} else if err != nil {
+		return fmt.Errorf("failed to look up message %d: %w", messageID, err)
+	}
+	if sender != "assistant" {
+		return fmt.Errorf("only assistant answers can be rated")
+	}
+
+	if _, err := e.db.Exec(`
+		INSERT OR REPLACE INTO message_ratings (message_id, rating, note, rated_at)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+	`, messageID, rating, strings.TrimSpace(note)); err != nil {
+		return fmt.Errorf("failed to save rating: %w", err)
+	}
+	return nil
+}
+
+// ClearRating removes the rating of a message, reporting whether it had one
+func (e *Engine) ClearRating(messageID int64) (bool, error) {
+	result, err := e.db.Exec("DELETE FROM message_ratings WHERE message_id = ?", messageID)
+	if err != nil {
+		return false, fmt.Errorf("failed to clear rating: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+// GetRatedMessages returns the rated messages, most recently rated first,
+// optionally only those with one rating
+func (e *Engine) GetRatedMessages(rating string) ([]*RatedMessage, error) {
+	query := `
+		SELECT m.id, m.conversation_id, c.name, r.rating, r.note, r.rated_at, message_text(m.text)
+		FROM message_ratings r
+		JOIN messages m ON r.message_id = m.id
+		JOIN conversations c ON m.conversation_id = c.id
+		WHERE c.deleted_at IS NULL
+	`
+	var args []interface{}
+	if rating != "" {
+		if err := ValidateRating(rating); err != nil {
+			return nil, err
+		}
+		query += " AND r.rating = ?"
+		args = append(args, rating)
+	}
+	query += " ORDER BY r.rated_at DESC, m.id"
+
+	var rated []*RatedMessage
+	if err := e.collect(query, func(rows *sql.Rows) error {
+		var r RatedMessage
+		var text string
+		if err := rows.Scan(&r.MessageID, &r.ConversationID, &r.ConversationName, &r.Rating, &r.Note, &r.RatedAt, &text); err != nil {
+			return err
+		}
+		r.Preview = firstLine(text, 60)
+		rated = append(rated, &r)
+		return nil
+	}, args...); err != nil {
+		return nil, fmt.Errorf("failed to load ratings: %w", err)
+	}
+	return rated, nil
+}
+
+// ResolveMessage returns the message of a conversation ref names: its number
+// as `shannon view` shows it, counting from 1, or its UUID
+func (e *Engine) ResolveMessage(conversationID int64, ref string) (*models.Message, error) {
+	_, messages, err := e.GetConversation(conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation: %w", err)
+	}
+
+	if n, err := strconv.Atoi(ref); err == nil {
+		if n < 1 || n > len(messages) {
+			return nil, fmt.Errorf("conversation %d has no message %d (it has %d)", conversationID, n, len(messages))
+		}
+		return messages[n-1], nil
+	}
+	for _, msg := range messages {
+		if msg.UUID == ref {
+			return msg, nil
+		}
+	}
+	return nil, fmt.Errorf("message %s is not in conversation %d", ref, conversationID)
+}
+
+// firstLine returns the first non-empty line of text shortened to n runes
+func firstLine(text string, n int) string {
+	line := strings.TrimSpace(text)
+	if idx := strings.IndexByte(line, '\n'); idx >= 0 {
+		line = strings.TrimSpace(line[:idx])
+	}
+	if runes := []rune(line); len(runes) > n {
+		line = string(runes[:n-3]) + "..."
+	}
+	return line
+}
@@ -0,0 +1,126 @@
+package search
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+// ConversationFilter narrows the conversations picked for rediscovery
+type ConversationFilter struct {
+	Since       *time.Time // created at or after
+	Before      *time.Time // created before
+	MinMessages int
+}
+
+// where builds the WHERE clause and arguments for the filter
+func (f ConversationFilter) where() (string, []interface{}) {
+	conditions := []string{"deleted_at IS NULL"}
+	var args []interface{}
+
+	if f.Since != nil {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, f.Since.UTC().Format("2006-01-02 15:04:05"))
+	}
+	if f.Before != nil {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, f.Before.UTC().Format("2006-01-02 15:04:05"))
+	}
+	if f.MinMessages > 0 {
+		conditions = append(conditions, "message_count >= ?")
+		args = append(args, f.MinMessages)
+	}
+
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// RandomConversations returns up to limit conversations matching the filter,
+// in random order
+func (e *Engine) RandomConversations(filter ConversationFilter, limit int) ([]*models.Conversation, error) {
+	where, args := filter.where()
+	rows, err := e.db.Query(`
+		SELECT id, uuid, name, created_at, updated_at, message_count, imported_at,
+		       token_count, artifact_count, human_message_count, duration_seconds, active_days
+		FROM conversations
+		`+where+`
+		ORDER BY RANDOM()
+		LIMIT ?
+	`, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query conversations: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close rows: %v\n", err)
+		}
+	}()
+
+	var conversations []*models.Conversation
+	for rows.Next() {
+		var c models.Conversation
+		err := rows.Scan(&c.ID, &c.UUID, &c.Name, &c.CreatedAt, &c.UpdatedAt, &c.MessageCount, &c.ImportedAt,
+			&c.TokenCount, &c.ArtifactCount, &c.HumanMessageCount, &c.DurationSeconds, &c.ActiveDays)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan conversation: %w", err)
+		}
+		conversations = append(conversations, &c)
+	}
+
+	return conversations, rows.Err()
+}
+
+// OnThisDay returns the conversations created on the calendar date of day in
+// earlier years, or in earlier months if monthly is set, newest first. Dates
+// are compared in day's time zone.
+func (e *Engine) OnThisDay(day time.Time, monthly bool) ([]*models.Conversation, error) {
+	// Narrow down in SQL on the stored date, allowing a day either side for
+	// time zones, then compare exactly below
+	column, layout := "substr(created_at, 6, 5)", "01-02"
+	if monthly {
+		column, layout = "substr(created_at, 9, 2)", "02"
+	}
+	var candidates []interface{}
+	for offset := -1; offset <= 1; offset++ {
+		candidates = append(candidates, day.AddDate(0, 0, offset).Format(layout))
+	}
+
+	rows, err := e.db.Query(`
+		SELECT id, uuid, name, created_at, updated_at, message_count, imported_at,
+		       token_count, artifact_count, human_message_count, duration_seconds, active_days
+		FROM conversations
+		WHERE `+column+` IN (?, ?, ?) AND deleted_at IS NULL
+		ORDER BY created_at DESC
+	`, candidates...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query conversations: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close rows: %v\n", err)
+		}
+	}()
+
+	year, month, date := day.Date()
+	startOfDay := time.Date(year, month, date, 0, 0, 0, 0, day.Location())
+
+	var conversations []*models.Conversation
+	for rows.Next() {
+		var c models.Conversation
+		err := rows.Scan(&c.ID, &c.UUID, &c.Name, &c.CreatedAt, &c.UpdatedAt, &c.MessageCount, &c.ImportedAt,
+			&c.TokenCount, &c.ArtifactCount, &c.HumanMessageCount, &c.DurationSeconds, &c.ActiveDays)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan conversation: %w", err)
+		}
+
+		created := c.CreatedAt.In(day.Location())
+		if created.Day() != date || (!monthly && created.Month() != month) || !created.Before(startOfDay) {
+			continue
+		}
+		conversations = append(conversations, &c)
+	}
+
+	return conversations, rows.Err()
+}
@@ -1,6 +1,7 @@
 package search
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"os"
@@ -8,13 +9,18 @@ import (
 	"strings"
 	"time"
 
+	"github.com/neilberkman/shannon/internal/crash"
 	"github.com/neilberkman/shannon/internal/db"
 	"github.com/neilberkman/shannon/internal/models"
 )
 
 // Engine handles search operations
 type Engine struct {
-	db *db.DB
+	db         *db.DB
+	dictionary *Dictionary
+	// codeBlockLines is the size from which GetConversationArtifacts and
+	// SearchArtifacts take fenced code blocks for artifacts, or 0 for never
+	codeBlockLines int
 }
 
 // NewEngine creates a new search engine
@@ -27,6 +33,11 @@ func (e *Engine) DB() *db.DB {
 	return e.db
 }
 
+// notTrashed keeps the rows of conversations in the trash out of a query on
+// a table with a conversation_id column. Full-text matches need no filter:
+// trashed messages aren't in the indexes.
+const notTrashed = "conversation_id NOT IN (SELECT id FROM conversations WHERE deleted_at IS NOT NULL)"
+
 // SearchOptions contains search parameters
 type SearchOptions struct {
 	Query          string
@@ -38,24 +49,131 @@ type SearchOptions struct {
 	Offset         int
 	SortBy         string // "relevance" or "date"
 	SortOrder      string // "asc" or "desc"
+	Rank           string // how relevance is scored: RankRelevance (default), RankRecency or RankHybrid
+	Index          string // which FTS index to search: IndexAuto (default), IndexText or IndexCode
+	Rating         string // only messages rated this with `shannon rate`, or empty for all
+	Model          string // only messages from models whose name contains this, like "opus", or empty for all
+	Starred        bool   // only messages starred in claude.ai, or in conversations starred there
+	Feedback       string // only messages given this feedback in claude.ai, models.FeedbackUp or models.FeedbackDown
+	Distinct       string // DistinctConversation for only the best match of each conversation, or empty for all
+	IncludePrivate bool   // also scan private conversations, listing their matches after the others
+	Duration       *Bound // only conversations that ran this long from first message to last, in seconds
+	ActiveDays     *Bound // only conversations with messages on this many days
+
+	indexPrefix string // the code: or text: prefix stripped from Query, if any
+}
+
+// Bound compares a conversation metric with a value, as duration:>2h does
+type Bound struct {
+	Op    string // one of BoundOps
+	Value int64
+}
+
+// BoundOps are the comparisons a Bound can make, longest first so that >=
+// is read before >
+var BoundOps = []string{">=", "<=", ">", "<", "="}
+
+// condition is the SQL comparing column with the bound's value in param
+func (b *Bound) condition(column string, param int) string {
+	return fmt.Sprintf("%s %s $%d", column, b.Op, param)
+}
+
+// Matches reports whether value is within the bound, for stores that filter
+// outside SQL
+func (b *Bound) Matches(value int64) bool {
+	switch b.Op {
+	case ">=":
+		return value >= b.Value
+	case "<=":
+		return value <= b.Value
+	case ">":
+		return value > b.Value
+	case "<":
+		return value < b.Value
+	}
+	return value == b.Value
+}
+
+// DistinctConversation limits results to the best match in each
+// conversation, by the sort order in effect, so pages count conversations
+const DistinctConversation = "conversation"
+
+// Full-text indexes a query can search
+const (
+	// IndexAuto searches IndexCode for queries that look like code and
+	// IndexText otherwise
+	IndexAuto = ""
+	// IndexText is stemmed and folds diacritics, so "running" finds "runs"
+	// and "cafe" finds "café"
+	IndexText = "text"
+	// IndexCode matches words exactly as written, keeping diacritics
+	IndexCode = "code"
+)
+
+// indexTables maps the indexes to their FTS tables
+var indexTables = map[string]string{
+	IndexText: "messages_fts",
+	IndexCode: "messages_fts_code",
+}
+
+// Ranking modes for relevance sorting
+const (
+	// RankRelevance orders by BM25 score alone
+	RankRelevance = "relevance"
+	// RankRecency puts the most recently updated conversations first and
+	// orders by BM25 score within each
+	RankRecency = "recency"
+	// RankHybrid combines BM25 score with a recency decay and how densely
+	// the conversation matches
+	RankHybrid = "hybrid"
+)
+
+// RankModes lists the accepted ranking modes
+var RankModes = []string{RankRelevance, RankRecency, RankHybrid}
+
+// recencyHalfLifeDays is the conversation age at which the hybrid ranking
+// halves a match's score
+const recencyHalfLifeDays = 30
+
+// relevanceScore is the BM25 score of a match, larger is better. FTS5's rank
+// is negative with the best match lowest.
+const relevanceScore = "(-rank)"
+
+// hybridScore scales the BM25 score down with the age of the conversation
+// and up with the share of its messages that match
+var hybridScore = hybridScoreOf("rank", "cm.matches")
+
+// hybridScoreOf builds the hybrid score from the columns holding the FTS5
+// rank and the conversation's match count. Dates are stored with a zone
+// suffix julianday can't parse, so only the date and time are used.
+func hybridScoreOf(rank, matches string) string {
+	return fmt.Sprintf(`(-%s)
+	* (1.0 + %s * 1.0 / MAX(c.message_count, 1))
+	/ (1.0 + MAX(COALESCE(julianday('now') - julianday(substr(c.updated_at, 1, 19)), 0), 0) / %d.0)`,
+		rank, matches, recencyHalfLifeDays)
 }
 
 // Search performs a full-text search
 func (e *Engine) Search(opts SearchOptions) ([]*models.SearchResult, error) {
+	return e.SearchContext(context.Background(), opts)
+}
+
+// SearchContext performs a full-text search that stops early if ctx is canceled
+func (e *Engine) SearchContext(ctx context.Context, opts SearchOptions) ([]*models.SearchResult, error) {
+	crash.Note("search %q", opts.Query)
+	if err := checkFilters(opts); err != nil {
+		return nil, err
+	}
+
 	// Build the query
 	query, args := e.buildSearchQuery(opts)
 
-	rows, err := e.db.Query(query, args...)
+	rows, err := e.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		// Provide more helpful error messages
-		errStr := err.Error()
-		if strings.Contains(errStr, "syntax error") {
-			return nil, fmt.Errorf("invalid search syntax: %s", opts.Query)
-		}
-		if strings.Contains(errStr, "unknown special query") {
-			return nil, fmt.Errorf("invalid wildcard usage in: %s (hint: wildcards must not be quoted)", opts.Query)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
 		}
-		return nil, fmt.Errorf("search query failed: %w", err)
+		return nil, queryError(opts.Query, err)
 	}
 	defer func() {
 		if err := rows.Close(); err != nil {
@@ -63,7 +181,10 @@ func (e *Engine) Search(opts SearchOptions) ([]*models.SearchResult, error) {
 		}
 	}()
 
+	// A message matches at most once, however many of the query's terms or
+	// indexes it matches
 	var results []*models.SearchResult
+	seen := make(map[int64]bool)
 	for rows.Next() {
 		var r models.SearchResult
 		err := rows.Scan(
@@ -77,30 +198,82 @@ func (e *Engine) Search(opts SearchOptions) ([]*models.SearchResult, error) {
 			&r.Snippet,
 			&r.CreatedAt,
 			&r.Rank,
+			&r.Branch,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan result: %w", err)
 		}
+		if seen[r.MessageID] {
+			continue
+		}
+		seen[r.MessageID] = true
 		results = append(results, &r)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	// Private conversations aren't indexed; their matches come after the
+	// others, up to a page of them, on the first page only
+	if opts.IncludePrivate && opts.Offset == 0 {
+		private, err := e.searchPrivate(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, private...)
+	}
+	return results, nil
+}
 
-	return results, rows.Err()
+// queryError turns a failed search query into a more helpful error
+func queryError(query string, err error) error {
+	errStr := err.Error()
+	if strings.Contains(errStr, "syntax error") {
+		return fmt.Errorf("invalid search syntax: %s", query)
+	}
+	if strings.Contains(errStr, "unknown special query") {
+		return fmt.Errorf("invalid wildcard usage in: %s (hint: wildcards must not be quoted)", query)
+	}
+	return fmt.Errorf("search query failed: %w", err)
 }
 
 func (e *Engine) buildSearchQuery(opts SearchOptions) (string, []interface{}) {
-	var conditions []string
-	var args []interface{}
-	argIndex := 1
-
-	// Determine which FTS table to use based on query characteristics
-	useCodeTable := e.isCodeQuery(opts.Query)
-	ftsTable := "messages_fts"
-	if useCodeTable {
-		ftsTable = "messages_fts_code"
+	opts = withIndexPrefix(opts)
+	ftsTable := e.ftsTable(opts)
+
+	// Hybrid ranking needs to know how many messages of each conversation
+	// match. The FTS query is parameter 1 in both places.
+	hybrid := opts.SortBy != "date" && opts.Rank == RankHybrid
+	var withClause, joinClause string
+	if hybrid {
+		withClause = fmt.Sprintf(`
+		WITH conversation_matches AS (
+			SELECT conversation_id, COUNT(*) AS matches
+			FROM messages
+			WHERE id IN (SELECT rowid FROM %s WHERE %s MATCH ?1)
+			GROUP BY conversation_id
+		)`, ftsTable, ftsTable)
+		joinClause = "JOIN conversation_matches cm ON cm.conversation_id = m.conversation_id"
 	}
 
 	// Base query with dynamic FTS table selection
-	baseQuery := fmt.Sprintf(`
+	from := fmt.Sprintf(`
+		FROM %s
+		JOIN messages m ON %s.rowid = m.id
+		JOIN conversations c ON m.conversation_id = c.id
+		LEFT JOIN branches b ON b.id = m.branch_id
+		%s
+		WHERE %s MATCH ?1
+	`, ftsTable, ftsTable, joinClause, ftsTable)
+	conditions, args := e.buildFilters(opts)
+	if len(conditions) > 0 {
+		from += " AND " + strings.Join(conditions, " AND ")
+	}
+
+	query := withClause + fmt.Sprintf(`
 		SELECT 
 			c.id,
 			c.uuid,
@@ -108,15 +281,67 @@ func (e *Engine) buildSearchQuery(opts SearchOptions) (string, []interface{}) {
 			m.id,
 			m.uuid,
 			m.sender,
-			m.text,
+			message_text(m.text),
 			snippet(%s, 0, '<mark>', '</mark>', '...', 32) as snippet,
 			m.created_at,
-			rank
-		FROM %s
-		JOIN messages m ON %s.rowid = m.id
-		JOIN conversations c ON m.conversation_id = c.id
-		WHERE %s MATCH ?
-	`, ftsTable, ftsTable, ftsTable, ftsTable)
+			rank,
+			CASE WHEN b.name IS NULL OR b.name = 'main' THEN '' ELSE b.name END`, ftsTable) + from
+
+	// Add sorting
+	direction := " DESC"
+	if opts.SortOrder == "asc" {
+		direction = " ASC"
+	}
+	var orderBy string
+	switch {
+	case opts.SortBy == "date":
+		orderBy = " ORDER BY m.created_at" + direction
+	case opts.Rank == RankRecency:
+		orderBy = " ORDER BY c.updated_at" + direction + ", " + relevanceScore + direction
+	case opts.Rank == RankHybrid:
+		orderBy = " ORDER BY " + hybridScore + direction
+	default: // relevance
+		orderBy = " ORDER BY " + relevanceScore + direction
+	}
+
+	// Only the best match of each conversation is kept by numbering each
+	// conversation's matches in sort order. snippet() can't be used in a
+	// query with a window function, so the numbering is done apart.
+	if opts.Distinct == DistinctConversation {
+		query += `
+		AND m.id IN (
+			SELECT id FROM (
+				SELECT m.id, ROW_NUMBER() OVER (PARTITION BY m.conversation_id` + orderBy + `) AS hit` + from + `
+			) WHERE hit = 1
+		)`
+	}
+	query += orderBy
+
+	// Add pagination
+	if opts.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", opts.Limit)
+		if opts.Offset > 0 {
+			query += fmt.Sprintf(" OFFSET %d", opts.Offset)
+		}
+	}
+
+	return query, args
+}
+
+// ftsTable picks the FTS table to search: the one the options or a query
+// prefix ask for, or else based on query characteristics
+func (e *Engine) ftsTable(opts SearchOptions) string {
+	index, _ := chooseIndex(opts)
+	return indexTables[index]
+}
+
+// buildFilters returns the conditions narrowing a match to the option's
+// filters, joined with m (messages), and the query arguments. The first
+// argument is always the FTS query, referenced as ?1.
+func (e *Engine) buildFilters(opts SearchOptions) ([]string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	argIndex := 1
 
 	// Process search query for FTS5
 	ftsQuery := e.processFTSQuery(opts.Query)
@@ -136,46 +361,52 @@ func (e *Engine) buildSearchQuery(opts SearchOptions) (string, []interface{}) {
 		argIndex++
 	}
 
-	if opts.StartDate != nil {
-		conditions = append(conditions, fmt.Sprintf("m.created_at >= $%d", argIndex))
-		args = append(args, opts.StartDate.Format("2006-01-02 15:04:05"))
+	if opts.Rating != "" {
+		conditions = append(conditions, fmt.Sprintf("m.id IN (SELECT message_id FROM message_ratings WHERE rating = $%d)", argIndex))
+		args = append(args, opts.Rating)
 		argIndex++
 	}
 
-	if opts.EndDate != nil {
-		conditions = append(conditions, fmt.Sprintf("m.created_at <= $%d", argIndex))
-		args = append(args, opts.EndDate.Format("2006-01-02 15:04:05"))
+	if opts.Model != "" {
+		conditions = append(conditions, fmt.Sprintf("instr(lower(m.model), lower($%d)) > 0", argIndex))
+		args = append(args, opts.Model)
+		argIndex++
 	}
 
-	// Build final query
-	query := baseQuery
-	if len(conditions) > 0 {
-		query += " AND " + strings.Join(conditions, " AND ")
+	if opts.Starred {
+		conditions = append(conditions, "(m.starred OR m.conversation_id IN (SELECT id FROM conversations WHERE starred))")
 	}
 
-	// Add sorting
-	switch opts.SortBy {
-	case "date":
-		query += " ORDER BY m.created_at"
-	default: // relevance
-		query += " ORDER BY rank"
+	if opts.Feedback != "" {
+		conditions = append(conditions, fmt.Sprintf("m.feedback = $%d", argIndex))
+		args = append(args, opts.Feedback)
+		argIndex++
 	}
 
-	if opts.SortOrder == "asc" {
-		query += " ASC"
-	} else {
-		query += " DESC"
+	if opts.Duration != nil {
+		conditions = append(conditions, "m.conversation_id IN (SELECT id FROM conversations WHERE "+opts.Duration.condition("duration_seconds", argIndex)+")")
+		args = append(args, opts.Duration.Value)
+		argIndex++
 	}
 
-	// Add pagination
-	if opts.Limit > 0 {
-		query += fmt.Sprintf(" LIMIT %d", opts.Limit)
-		if opts.Offset > 0 {
-			query += fmt.Sprintf(" OFFSET %d", opts.Offset)
-		}
+	if opts.ActiveDays != nil {
+		conditions = append(conditions, "m.conversation_id IN (SELECT id FROM conversations WHERE "+opts.ActiveDays.condition("active_days", argIndex)+")")
+		args = append(args, opts.ActiveDays.Value)
+		argIndex++
 	}
 
-	return query, args
+	if opts.StartDate != nil {
+		conditions = append(conditions, fmt.Sprintf("m.created_at >= $%d", argIndex))
+		args = append(args, opts.StartDate.UTC().Format("2006-01-02 15:04:05"))
+		argIndex++
+	}
+
+	if opts.EndDate != nil {
+		conditions = append(conditions, fmt.Sprintf("m.created_at < $%d", argIndex))
+		args = append(args, opts.EndDate.UTC().Format("2006-01-02 15:04:05"))
+	}
+
+	return conditions, args
 }
 
 // processFTSQuery converts user query to FTS5 syntax
@@ -189,6 +420,7 @@ func (e *Engine) processFTSQuery(userQuery string) string {
 	}
 
 	// If query already contains FTS5 operators or quotes, validate and return
+	// it as written
 	if strings.ContainsAny(query, `"*`) {
 		// Basic validation - ensure quotes are balanced
 		quoteCount := strings.Count(query, `"`)
@@ -206,18 +438,29 @@ func (e *Engine) processFTSQuery(userQuery string) string {
 		query = strings.ReplaceAll(query, " and ", " AND ")
 		query = strings.ReplaceAll(query, " or ", " OR ")
 		query = strings.ReplaceAll(query, " not ", " NOT ")
-		return query
+
+		// Expand the terms between the operators
+		words := strings.Fields(query)
+		for i, word := range words {
+			if !isOperator(word) {
+				words[i] = e.dictionary.expand(word)
+			}
+		}
+		return strings.Join(words, " ")
 	}
 
 	// For multi-word queries without explicit operators, treat as implicit AND
 	// This is more intuitive behavior - searching "machine learning" finds documents with both words
 	if strings.Contains(query, " ") {
-		// Split on spaces and join with AND
-		words := strings.Fields(query)
+		// Split on spaces, drop stopwords and join with AND
+		words := e.dictionary.removeStopwords(strings.Fields(query))
+		for i, word := range words {
+			words[i] = e.dictionary.expand(word)
+		}
 		return strings.Join(words, " AND ")
 	}
 
-	return query
+	return e.dictionary.expand(query)
 }
 
 // escapeFTSQuery escapes special characters for FTS5
@@ -227,70 +470,82 @@ func escapeFTSQuery(query string) string {
 	return `"` + strings.ReplaceAll(query, `"`, `""`) + `"`
 }
 
+// codePatterns are patterns that indicate code-related searches, with a
+// description of what they match
+var codePatterns = []struct {
+	pattern     *regexp.Regexp
+	description string
+}{
+	{regexp.MustCompile(`[a-z][A-Z]`), "camelCase"},
+	{regexp.MustCompile(`[A-Z][a-z]+[A-Z]`), "PascalCase"},
+	{regexp.MustCompile(`\w+_\w+`), "snake_case"},
+	{regexp.MustCompile(`\w+\.\w+`), "method call or file name"},
+	{regexp.MustCompile(`\w+::\w+`), "namespace::function"},
+	{regexp.MustCompile(`\w+\(\)`), "function()"},
+	{regexp.MustCompile(`\w+\[\]`), "array[]"},
+	{regexp.MustCompile(`[{}()\[\]<>]`), "brackets or braces"},
+	{regexp.MustCompile(`[=!<>]=?`), "operators"},
+	{regexp.MustCompile(`\+\+|--|&&|\|\||->|=>`), "compound operators"},
+	{regexp.MustCompile(`\b(def|function|class|import|export|const|let|var|if|else|for|while|return|async|await|interface|type|struct|enum)\b`), "keywords"},
+	{regexp.MustCompile(`\b[A-Z_][A-Z0-9_]{2,}\b`), "CONSTANTS"},
+	{regexp.MustCompile(`#\w+`), "#hashtag or CSS selector"},
+	{regexp.MustCompile(`\$\w+`), "$variables"},
+	{regexp.MustCompile(`@\w+`), "@decorators"},
+	{regexp.MustCompile(`\\\w+`), `\commands`},
+	{regexp.MustCompile(`\b\w+\.(js|ts|py|go|rs|cpp|c|h|java|kt|swift|rb|php|cs|scala|clj|hs|ml|elm|dart|vue|jsx|tsx|css|scss|sass|less|html|xml|json|yaml|yml|toml|ini|cfg|conf|sh|bash|zsh|fish|ps1|bat|cmd|sql|md|rst|tex|r|m|pl|lua|vim|emacs)\b`), "file extensions"},
+}
+
+// technicalTerms commonly appear in code discussions
+var technicalTerms = []string{
+	"api", "json", "xml", "http", "https", "url", "uri", "sql", "database", "db",
+	"frontend", "backend", "fullstack", "devops", "ci", "cd", "git", "github", "gitlab",
+	"docker", "kubernetes", "aws", "azure", "gcp", "serverless", "microservice",
+	"framework", "library", "package", "dependency", "npm", "pip", "cargo", "maven",
+	"compiler", "interpreter", "runtime", "virtual", "container", "deployment",
+	"authentication", "authorization", "oauth", "jwt", "token", "session", "cookie",
+	"cache", "redis", "mongodb", "postgresql", "mysql", "sqlite", "nosql",
+	"async", "sync", "promise", "callback", "event", "listener", "handler",
+	"component", "module", "service", "controller", "model", "view", "template",
+	"regex", "regexp", "pattern", "match", "parse", "serialize", "deserialize",
+	"algorithm", "optimization", "performance", "benchmark", "profiling", "debug",
+	"test", "unit", "integration", "e2e", "mock", "stub", "fixture", "spec",
+	"build", "compile", "transpile", "bundle", "minify", "lint", "format",
+	"version", "release", "deploy", "staging", "production", "environment",
+}
+
 // isCodeQuery determines if a query should use the code-specific FTS table
 func (e *Engine) isCodeQuery(query string) bool {
-	// Patterns that indicate code-related searches
-	codePatterns := []*regexp.Regexp{
-		regexp.MustCompile(`[a-z][A-Z]`),            // camelCase
-		regexp.MustCompile(`[A-Z][a-z]+[A-Z]`),      // PascalCase
-		regexp.MustCompile(`\w+_\w+`),               // snake_case
-		regexp.MustCompile(`\w+\.\w+`),              // method.calls or file.ext
-		regexp.MustCompile(`\w+::\w+`),              // namespace::function
-		regexp.MustCompile(`\w+\(\)`),               // function()
-		regexp.MustCompile(`\w+\[\]`),               // array[]
-		regexp.MustCompile(`[{}()\[\]<>]`),          // brackets/braces
-		regexp.MustCompile(`[=!<>]=?`),              // operators
-		regexp.MustCompile(`\+\+|--|&&|\|\||->|=>`), // compound operators
-		regexp.MustCompile(`\b(def|function|class|import|export|const|let|var|if|else|for|while|return|async|await|interface|type|struct|enum)\b`), // keywords
-		regexp.MustCompile(`\b[A-Z_][A-Z0-9_]{2,}\b`), // CONSTANTS
-		regexp.MustCompile(`#\w+`),                    // #hashtags or CSS/preprocessor
-		regexp.MustCompile(`\$\w+`),                   // $variables
-		regexp.MustCompile(`@\w+`),                    // @decorators
-		regexp.MustCompile(`\\\w+`),                   // \commands
-		regexp.MustCompile(`\b\w+\.(js|ts|py|go|rs|cpp|c|h|java|kt|swift|rb|php|cs|scala|clj|hs|ml|elm|dart|vue|jsx|tsx|css|scss|sass|less|html|xml|json|yaml|yml|toml|ini|cfg|conf|sh|bash|zsh|fish|ps1|bat|cmd|sql|md|rst|tex|r|m|pl|lua|vim|emacs)\b`), // file extensions
-	}
+	return codeQueryReason(query) != ""
+}
 
+// codeQueryReason explains why a query looks like code, or returns "" if it
+// doesn't
+func codeQueryReason(query string) string {
 	// Check if query matches any code patterns
-	for _, pattern := range codePatterns {
-		if pattern.MatchString(query) {
-			return true
+	for _, p := range codePatterns {
+		if match := p.pattern.FindString(query); match != "" {
+			return fmt.Sprintf("%s (%q)", p.description, match)
 		}
 	}
 
 	// Check for technical terms that commonly appear in code discussions
-	technicalTerms := []string{
-		"api", "json", "xml", "http", "https", "url", "uri", "sql", "database", "db",
-		"frontend", "backend", "fullstack", "devops", "ci", "cd", "git", "github", "gitlab",
-		"docker", "kubernetes", "aws", "azure", "gcp", "serverless", "microservice",
-		"framework", "library", "package", "dependency", "npm", "pip", "cargo", "maven",
-		"compiler", "interpreter", "runtime", "virtual", "container", "deployment",
-		"authentication", "authorization", "oauth", "jwt", "token", "session", "cookie",
-		"cache", "redis", "mongodb", "postgresql", "mysql", "sqlite", "nosql",
-		"async", "sync", "promise", "callback", "event", "listener", "handler",
-		"component", "module", "service", "controller", "model", "view", "template",
-		"regex", "regexp", "pattern", "match", "parse", "serialize", "deserialize",
-		"algorithm", "optimization", "performance", "benchmark", "profiling", "debug",
-		"test", "unit", "integration", "e2e", "mock", "stub", "fixture", "spec",
-		"build", "compile", "transpile", "bundle", "minify", "lint", "format",
-		"version", "release", "deploy", "staging", "production", "environment",
-	}
-
 	queryLower := strings.ToLower(query)
 	for _, term := range technicalTerms {
 		if strings.Contains(queryLower, term) {
-			return true
+			return fmt.Sprintf("technical term (%q)", term)
 		}
 	}
 
-	return false
+	return ""
 }
 
 // SearchConversations searches conversation titles
 func (e *Engine) SearchConversations(query string, limit int) ([]*models.Conversation, error) {
 	sqlQuery := `
-		SELECT id, uuid, name, created_at, updated_at, message_count, imported_at
+		SELECT id, uuid, name, created_at, updated_at, message_count, imported_at,
+		       token_count, artifact_count, human_message_count, duration_seconds, active_days
 		FROM conversations
-		WHERE name LIKE ?
+		WHERE name LIKE ? AND deleted_at IS NULL
 		ORDER BY updated_at DESC
 		LIMIT ?
 	`
@@ -308,7 +563,8 @@ func (e *Engine) SearchConversations(query string, limit int) ([]*models.Convers
 	var conversations []*models.Conversation
 	for rows.Next() {
 		var c models.Conversation
-		err := rows.Scan(&c.ID, &c.UUID, &c.Name, &c.CreatedAt, &c.UpdatedAt, &c.MessageCount, &c.ImportedAt)
+		err := rows.Scan(&c.ID, &c.UUID, &c.Name, &c.CreatedAt, &c.UpdatedAt, &c.MessageCount, &c.ImportedAt,
+			&c.TokenCount, &c.ArtifactCount, &c.HumanMessageCount, &c.DurationSeconds, &c.ActiveDays)
 		if err != nil {
 			return nil, err
 		}
@@ -322,11 +578,14 @@ func (e *Engine) SearchConversations(query string, limit int) ([]*models.Convers
 func (e *Engine) GetConversation(conversationID int64) (*models.Conversation, []*models.Message, error) {
 	// Get conversation
 	var conv models.Conversation
+	var deletedAt sql.NullTime
 	err := e.db.QueryRow(`
-		SELECT id, uuid, name, created_at, updated_at, message_count, imported_at
+		SELECT id, uuid, name, created_at, updated_at, message_count, imported_at,
+		       token_count, artifact_count, human_message_count, duration_seconds, active_days, starred, deleted_at
 		FROM conversations
 		WHERE id = ?
-	`, conversationID).Scan(&conv.ID, &conv.UUID, &conv.Name, &conv.CreatedAt, &conv.UpdatedAt, &conv.MessageCount, &conv.ImportedAt)
+	`, conversationID).Scan(&conv.ID, &conv.UUID, &conv.Name, &conv.CreatedAt, &conv.UpdatedAt, &conv.MessageCount, &conv.ImportedAt,
+		&conv.TokenCount, &conv.ArtifactCount, &conv.HumanMessageCount, &conv.DurationSeconds, &conv.ActiveDays, &conv.Starred, &deletedAt)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -334,12 +593,17 @@ func (e *Engine) GetConversation(conversationID int64) (*models.Conversation, []
 		}
 		return nil, nil, err
 	}
+	if deletedAt.Valid {
+		return nil, nil, fmt.Errorf("conversation %d is in the trash; restore it with 'shannon trash restore %d'", conversationID, conversationID)
+	}
 
 	// Get messages from main branch only (for consistent conversation view)
 	rows, err := e.db.Query(`
-		SELECT m.id, m.uuid, m.conversation_id, m.sender, m.text, m.created_at, m.parent_id, m.branch_id, m.sequence
+		SELECT m.id, m.uuid, m.conversation_id, m.sender, message_text(m.text), m.created_at, m.parent_id, m.branch_id, m.sequence,
+		       m.model, m.starred, m.feedback, COALESCE(r.rating, ''), COALESCE(r.note, '')
 		FROM messages m
 		JOIN branches b ON m.branch_id = b.id
+		LEFT JOIN message_ratings r ON r.message_id = m.id
 		WHERE m.conversation_id = ? AND b.name = 'main'
 		ORDER BY m.sequence ASC, m.created_at ASC
 	`, conversationID)
@@ -356,7 +620,8 @@ func (e *Engine) GetConversation(conversationID int64) (*models.Conversation, []
 	var messages []*models.Message
 	for rows.Next() {
 		var m models.Message
-		err := rows.Scan(&m.ID, &m.UUID, &m.ConversationID, &m.Sender, &m.Text, &m.CreatedAt, &m.ParentID, &m.BranchID, &m.Sequence)
+		err := rows.Scan(&m.ID, &m.UUID, &m.ConversationID, &m.Sender, &m.Text, &m.CreatedAt, &m.ParentID, &m.BranchID, &m.Sequence,
+			&m.Model, &m.Starred, &m.Feedback, &m.Rating, &m.RatingNote)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -366,13 +631,184 @@ func (e *Engine) GetConversation(conversationID int64) (*models.Conversation, []
 	return &conv, messages, rows.Err()
 }
 
+// GetAllMessages returns the messages of every branch of a conversation in
+// the order they were written
+func (e *Engine) GetAllMessages(conversationID int64) ([]*models.Message, error) {
+	rows, err := e.db.Query(`
+		SELECT m.id, m.uuid, m.conversation_id, m.sender, message_text(m.text), m.created_at, m.parent_id, m.branch_id, m.sequence,
+		       m.model, m.starred, m.feedback, COALESCE(r.rating, ''), COALESCE(r.note, '')
+		FROM messages m
+		LEFT JOIN message_ratings r ON r.message_id = m.id
+		WHERE m.conversation_id = ?
+		ORDER BY m.created_at ASC, m.id ASC
+	`, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close rows: %v\n", err)
+		}
+	}()
+
+	var messages []*models.Message
+	for rows.Next() {
+		var m models.Message
+		err := rows.Scan(&m.ID, &m.UUID, &m.ConversationID, &m.Sender, &m.Text, &m.CreatedAt, &m.ParentID, &m.BranchID, &m.Sequence,
+			&m.Model, &m.Starred, &m.Feedback, &m.Rating, &m.RatingNote)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, &m)
+	}
+
+	return messages, rows.Err()
+}
+
+// GetThread retrieves a conversation with the messages along the path
+// through one of its messages: those it replies to, back to the first, then
+// the replies after it, taking the earliest reply at each step. This shows a
+// message on a branch other than main in context, since GetConversation only
+// has the main branch.
+func (e *Engine) GetThread(conversationID, messageID int64) (*models.Conversation, []*models.Message, error) {
+	conv, _, err := e.GetConversation(conversationID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := e.db.Query(`
+		SELECT m.id, m.uuid, m.conversation_id, m.sender, message_text(m.text), m.created_at, m.parent_id, m.branch_id, m.sequence,
+		       m.model, m.starred, m.feedback, COALESCE(r.rating, ''), COALESCE(r.note, '')
+		FROM messages m
+		LEFT JOIN message_ratings r ON r.message_id = m.id
+		WHERE m.conversation_id = ?
+		ORDER BY m.sequence ASC, m.created_at ASC
+	`, conversationID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close rows: %v\n", err)
+		}
+	}()
+
+	byID := make(map[int64]*models.Message)
+	replies := make(map[int64][]*models.Message)
+	for rows.Next() {
+		var m models.Message
+		err := rows.Scan(&m.ID, &m.UUID, &m.ConversationID, &m.Sender, &m.Text, &m.CreatedAt, &m.ParentID, &m.BranchID, &m.Sequence,
+			&m.Model, &m.Starred, &m.Feedback, &m.Rating, &m.RatingNote)
+		if err != nil {
+			return nil, nil, err
+		}
+		byID[m.ID] = &m
+		if m.ParentID != nil {
+			replies[*m.ParentID] = append(replies[*m.ParentID], &m)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	target, ok := byID[messageID]
+	if !ok {
+		return nil, nil, fmt.Errorf("message %d is not in conversation %d", messageID, conversationID)
+	}
+
+	// Walk back to the first message, then forward through the replies. The
+	// seen set guards against parent links that loop.
+	seen := map[int64]bool{target.ID: true}
+	thread := []*models.Message{target}
+	for m := target; m.ParentID != nil; {
+		parent, ok := byID[*m.ParentID]
+		if !ok || seen[parent.ID] {
+			break
+		}
+		seen[parent.ID] = true
+		thread = append(thread, parent)
+		m = parent
+	}
+	for i, j := 0, len(thread)-1; i < j; i, j = i+1, j-1 {
+		thread[i], thread[j] = thread[j], thread[i]
+	}
+	for m := target; len(replies[m.ID]) > 0; {
+		next := replies[m.ID][0]
+		if seen[next.ID] {
+			break
+		}
+		seen[next.ID] = true
+		thread = append(thread, next)
+		m = next
+	}
+
+	return conv, thread, nil
+}
+
+// BranchName returns the name of the branch a message is on, or "" for the
+// main branch
+func (e *Engine) BranchName(messageID int64) (string, error) {
+	var name sql.NullString
+	err := e.db.QueryRow(`
+		SELECT b.name FROM messages m LEFT JOIN branches b ON b.id = m.branch_id WHERE m.id = ?
+	`, messageID).Scan(&name)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("message %d not found", messageID)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to look up the branch of message %d: %w", messageID, err)
+	}
+	if name.String == "main" {
+		return "", nil
+	}
+	return name.String, nil
+}
+
+// FindMessage returns the ID of the message of a conversation with a UUID,
+// or the start of one as search results show, on any branch
+func (e *Engine) FindMessage(conversationID int64, uuid string) (int64, error) {
+	rows, err := e.db.Query(`
+		SELECT id FROM messages
+		WHERE conversation_id = ? AND uuid LIKE ? || '%'
+		LIMIT 2
+	`, conversationID, uuid)
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up message %s: %w", uuid, err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close rows: %v\n", err)
+		}
+	}()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	switch len(ids) {
+	case 0:
+		return 0, fmt.Errorf("message %s is not in conversation %d", uuid, conversationID)
+	case 1:
+		return ids[0], nil
+	default:
+		return 0, fmt.Errorf("more than one message of conversation %d starts with %s", conversationID, uuid)
+	}
+}
+
 // GetStats returns database statistics
 func (e *Engine) GetStats() (map[string]interface{}, error) {
 	stats := make(map[string]interface{})
 
 	// Total conversations
 	var totalConversations int
-	err := e.db.QueryRow("SELECT COUNT(*) FROM conversations").Scan(&totalConversations)
+	err := e.db.QueryRow("SELECT COUNT(*) FROM conversations WHERE deleted_at IS NULL").Scan(&totalConversations)
 	if err != nil {
 		return nil, err
 	}
@@ -380,7 +816,7 @@ func (e *Engine) GetStats() (map[string]interface{}, error) {
 
 	// Total messages
 	var totalMessages int
-	err = e.db.QueryRow("SELECT COUNT(*) FROM messages").Scan(&totalMessages)
+	err = e.db.QueryRow("SELECT COUNT(*) FROM messages WHERE " + notTrashed).Scan(&totalMessages)
 	if err != nil {
 		return nil, err
 	}
@@ -388,11 +824,11 @@ func (e *Engine) GetStats() (map[string]interface{}, error) {
 
 	// Messages by sender
 	var humanCount, assistantCount int
-	err = e.db.QueryRow("SELECT COUNT(*) FROM messages WHERE sender = 'human'").Scan(&humanCount)
+	err = e.db.QueryRow("SELECT COUNT(*) FROM messages WHERE sender = 'human' AND " + notTrashed).Scan(&humanCount)
 	if err != nil {
 		return nil, err
 	}
-	err = e.db.QueryRow("SELECT COUNT(*) FROM messages WHERE sender = 'assistant'").Scan(&assistantCount)
+	err = e.db.QueryRow("SELECT COUNT(*) FROM messages WHERE sender = 'assistant' AND " + notTrashed).Scan(&assistantCount)
 	if err != nil {
 		return nil, err
 	}
@@ -404,7 +840,7 @@ func (e *Engine) GetStats() (map[string]interface{}, error) {
 
 	// Date range
 	var oldestStr, newestStr sql.NullString
-	err = e.db.QueryRow("SELECT MIN(created_at), MAX(created_at) FROM messages").Scan(&oldestStr, &newestStr)
+	err = e.db.QueryRow("SELECT MIN(created_at), MAX(created_at) FROM messages WHERE "+notTrashed).Scan(&oldestStr, &newestStr)
 	if err != nil && err != sql.ErrNoRows {
 		return nil, err
 	}
@@ -439,15 +875,100 @@ func (e *Engine) GetStats() (map[string]interface{}, error) {
 		}
 	}
 
+	byModel, err := e.modelUsage()
+	if err != nil {
+		return nil, err
+	}
+	stats["messages_by_model"] = byModel
+
 	return stats, nil
 }
 
+// ModelUsage is how much a model was used, counting the assistant messages it
+// wrote and the conversations they're in
+type ModelUsage struct {
+	Model         string `json:"model"`
+	Messages      int    `json:"messages"`
+	Conversations int    `json:"conversations"`
+}
+
+// modelUsage counts the assistant messages of each model, most used first.
+// Messages from exports that don't record the model aren't counted.
+func (e *Engine) modelUsage() ([]ModelUsage, error) {
+	rows, err := e.db.Query(`
+		SELECT model, COUNT(*), COUNT(DISTINCT conversation_id)
+		FROM messages
+		WHERE sender = 'assistant' AND model != '' AND ` + notTrashed + `
+		GROUP BY model
+		ORDER BY COUNT(*) DESC, model
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count messages by model: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close rows: %v\n", err)
+		}
+	}()
+
+	usage := []ModelUsage{}
+	for rows.Next() {
+		var u ModelUsage
+		if err := rows.Scan(&u.Model, &u.Messages, &u.Conversations); err != nil {
+			return nil, err
+		}
+		usage = append(usage, u)
+	}
+	return usage, rows.Err()
+}
+
+// Orders for ListConversations. Each puts the largest first, then the most
+// recently updated.
+const (
+	ListByUpdated       = "updated"
+	ListByMessages      = "messages"
+	ListByTokens        = "tokens"
+	ListByArtifacts     = "artifacts"
+	ListByHumanMessages = "human_messages"
+)
+
+// listOrders maps the ListConversations orders to the columns they sort by
+var listOrders = map[string]string{
+	ListByUpdated:       "",
+	ListByMessages:      "message_count DESC, ",
+	ListByTokens:        "token_count DESC, ",
+	ListByArtifacts:     "artifact_count DESC, ",
+	ListByHumanMessages: "human_message_count DESC, ",
+}
+
 // GetAllConversations retrieves all conversations with pagination
 func (e *Engine) GetAllConversations(limit, offset int) ([]*models.Conversation, error) {
+	return e.ListConversations(ListByUpdated, limit, offset)
+}
+
+// CountConversations returns how many conversations there are outside the
+// trash
+func (e *Engine) CountConversations() (int, error) {
+	var count int
+	if err := e.db.QueryRow("SELECT COUNT(*) FROM conversations WHERE deleted_at IS NULL").Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count conversations: %w", err)
+	}
+	return count, nil
+}
+
+// ListConversations retrieves a page of the conversations outside the trash
+// in one of the ListBy orders. A negative limit retrieves the rest.
+func (e *Engine) ListConversations(order string, limit, offset int) ([]*models.Conversation, error) {
+	columns, ok := listOrders[order]
+	if !ok {
+		return nil, fmt.Errorf("unknown conversation order %q", order)
+	}
 	rows, err := e.db.Query(`
-		SELECT id, uuid, name, created_at, updated_at, message_count, imported_at
+		SELECT id, uuid, name, created_at, updated_at, message_count, imported_at,
+		       token_count, artifact_count, human_message_count, duration_seconds, active_days
 		FROM conversations
-		ORDER BY updated_at DESC
+		WHERE deleted_at IS NULL
+		ORDER BY `+columns+`updated_at DESC, id DESC
 		LIMIT ? OFFSET ?
 	`, limit, offset)
 	if err != nil {
@@ -470,6 +991,11 @@ func (e *Engine) GetAllConversations(limit, offset int) ([]*models.Conversation,
 			&conv.UpdatedAt,
 			&conv.MessageCount,
 			&conv.ImportedAt,
+			&conv.TokenCount,
+			&conv.ArtifactCount,
+			&conv.HumanMessageCount,
+			&conv.DurationSeconds,
+			&conv.ActiveDays,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan conversation: %w", err)
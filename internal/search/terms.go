@@ -0,0 +1,42 @@
+package search
+
+import "strings"
+
+// QueryTerms returns the words and quoted phrases of a search query, without
+// its operators, grouping or wildcards, for highlighting what it matched
+func QueryTerms(query string) []string {
+	query = withIndexPrefix(SearchOptions{Query: query}).Query
+
+	var terms []string
+	add := func(term string) {
+		term = strings.Trim(strings.TrimSpace(term), "*")
+		if term == "" || isOperator(term) || term == "NEAR" || containsFold(terms, term) {
+			return
+		}
+		terms = append(terms, term)
+	}
+
+	var word strings.Builder
+	flush := func() {
+		add(word.String())
+		word.Reset()
+	}
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; c {
+		case '"':
+			flush()
+			end := strings.IndexByte(query[i+1:], '"')
+			if end < 0 {
+				end = len(query) - i - 1
+			}
+			add(query[i+1 : i+1+end])
+			i += end + 1
+		case ' ', '\t', '\n', '(', ')':
+			flush()
+		default:
+			word.WriteByte(c)
+		}
+	}
+	flush()
+	return terms
+}
@@ -0,0 +1,121 @@
+package search
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Actions recorded in the access log
+const (
+	AccessView   = "view"
+	AccessExport = "export"
+	AccessSearch = "search"
+)
+
+// accessTimeLayout is how SQLite's CURRENT_TIMESTAMP writes access times, in UTC
+const accessTimeLayout = "2006-01-02 15:04:05"
+
+// LogAccess records that a conversation was viewed or exported
+func (e *Engine) LogAccess(conversationID int64, action string) error {
+	if _, err := e.db.Exec("INSERT INTO access_log (action, conversation_id) VALUES (?, ?)", action, conversationID); err != nil {
+		return fmt.Errorf("failed to log access: %w", err)
+	}
+	return nil
+}
+
+// LogSearch records a search query and how long it took, if known (zero
+// when it isn't)
+func (e *Engine) LogSearch(query string, took time.Duration) error {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil
+	}
+	var duration interface{}
+	if took > 0 {
+		duration = float64(took) / float64(time.Millisecond)
+	}
+	if _, err := e.db.Exec("INSERT INTO access_log (action, query, duration_ms) VALUES (?, ?, ?)", AccessSearch, query, duration); err != nil {
+		return fmt.Errorf("failed to log search: %w", err)
+	}
+	return nil
+}
+
+// ConversationUsage is how often a conversation was opened
+type ConversationUsage struct {
+	ID         int64
+	Name       string
+	Views      int
+	Exports    int
+	Days       int // distinct days the conversation was viewed or exported
+	LastAccess time.Time
+}
+
+// SearchUsage is how often a query was searched, ignoring case
+type SearchUsage struct {
+	Query        string
+	Count        int
+	LastSearched time.Time
+}
+
+// Usage summarizes the access log
+type Usage struct {
+	Conversations []*ConversationUsage
+	Searches      []*SearchUsage
+}
+
+// GetUsage returns the most revisited conversations, those opened on the
+// most days first, and the most repeated searches since the given time (the
+// zero time for all of them), up to limit of each
+func (e *Engine) GetUsage(since time.Time, limit int) (*Usage, error) {
+	after := since.UTC().Format(accessTimeLayout)
+	usage := &Usage{}
+
+	err := e.collect(`
+		SELECT c.id, c.name,
+		       SUM(a.action = 'view'), SUM(a.action = 'export'),
+		       COUNT(DISTINCT date(a.accessed_at)), MAX(a.accessed_at)
+		FROM access_log a
+		JOIN conversations c ON c.id = a.conversation_id
+		WHERE a.accessed_at >= ? AND c.deleted_at IS NULL
+		GROUP BY c.id
+		ORDER BY 5 DESC, COUNT(*) DESC, 6 DESC
+		LIMIT ?
+	`, func(rows *sql.Rows) error {
+		var u ConversationUsage
+		var last string
+		if err := rows.Scan(&u.ID, &u.Name, &u.Views, &u.Exports, &u.Days, &last); err != nil {
+			return err
+		}
+		u.LastAccess, _ = time.ParseInLocation(accessTimeLayout, last, time.UTC)
+		usage.Conversations = append(usage.Conversations, &u)
+		return nil
+	}, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load conversation usage: %w", err)
+	}
+
+	err = e.collect(`
+		SELECT MIN(query), COUNT(*), MAX(accessed_at)
+		FROM access_log
+		WHERE action = 'search' AND accessed_at >= ?
+		GROUP BY lower(query)
+		ORDER BY 2 DESC, 3 DESC
+		LIMIT ?
+	`, func(rows *sql.Rows) error {
+		var u SearchUsage
+		var last string
+		if err := rows.Scan(&u.Query, &u.Count, &last); err != nil {
+			return err
+		}
+		u.LastSearched, _ = time.ParseInLocation(accessTimeLayout, last, time.UTC)
+		usage.Searches = append(usage.Searches, &u)
+		return nil
+	}, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load search usage: %w", err)
+	}
+
+	return usage, nil
+}
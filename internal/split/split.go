@@ -0,0 +1,331 @@
+package split
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/db"
+)
+
+// Part is a conversation made from a segment of another
+type Part struct {
+	ID       int64  `json:"id"`
+	UUID     string `json:"uuid"`
+	Name     string `json:"name"`
+	Messages int    `json:"messages"`
+}
+
+// Links are the conversations a conversation was split from and into
+type Links struct {
+	Source *Part  `json:"source,omitempty"` // nil unless split off another conversation
+	Parts  []Part `json:"parts,omitempty"`
+}
+
+// splitMessage is what moving a message to another conversation needs
+type splitMessage struct {
+	id        int64
+	uuid      string
+	branchID  int64
+	createdAt time.Time
+}
+
+// branch is a branches row of the conversation being split
+type branch struct {
+	name     sql.NullString
+	parentID sql.NullInt64
+}
+
+// Split cuts a conversation before each of the messages at, given by UUID,
+// and moves every segment after the first into a new conversation of its
+// own that links back to the original. Messages keep their IDs, so search
+// results and exports follow them; replies whose parent ends up in another
+// segment start a thread of their own. Returns the new conversations in
+// order.
+func Split(database *db.DB, conversationID int64, at []string) ([]Part, error) {
+	if len(at) == 0 {
+		return nil, fmt.Errorf("no messages to split at")
+	}
+
+	tx, err := database.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer rollback(tx)
+
+	var convUUID, name string
+	err = tx.QueryRow("SELECT uuid, name FROM conversations WHERE id = ?", conversationID).Scan(&convUUID, &name)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("conversation %d not found", conversationID)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to load conversation %d: %w", conversationID, err)
+	}
+
+	messages, err := loadMessages(tx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	cuts, err := cutPoints(messages, at, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	branches, err := loadBranches(tx, conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parts are numbered after those split off the conversation before
+	var existing int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM conversation_splits WHERE source_id = ?", conversationID).Scan(&existing); err != nil {
+		return nil, fmt.Errorf("failed to count earlier splits: %w", err)
+	}
+
+	var parts []Part
+	for i, start := range cuts {
+		end := len(messages)
+		if i+1 < len(cuts) {
+			end = cuts[i+1]
+		}
+		part, err := movePart(tx, conversationID, convUUID, fmt.Sprintf("%s (part %d)", name, existing+i+2), messages[start:end], branches)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, *part)
+	}
+
+	if err := refreshConversation(tx, conversationID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+	return parts, nil
+}
+
+// GetLinks returns the conversation a conversation was split from and the
+// ones split off it
+func GetLinks(database *db.DB, conversationID int64) (*Links, error) {
+	links := &Links{}
+
+	var source Part
+	err := database.QueryRow(`
+		SELECT c.id, c.uuid, c.name, c.message_count
+		FROM conversation_splits s
+		JOIN conversations c ON c.id = s.source_id
+		WHERE s.conversation_id = ?
+	`, conversationID).Scan(&source.ID, &source.UUID, &source.Name, &source.Messages)
+	if err == nil {
+		links.Source = &source
+	} else if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to load split source: %w", err)
+	}
+
+	rows, err := database.Query(`
+		SELECT c.id, c.uuid, c.name, c.message_count
+		FROM conversation_splits s
+		JOIN conversations c ON c.id = s.conversation_id
+		WHERE s.source_id = ?
+		ORDER BY c.id
+	`, conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load split parts: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close rows: %v\n", err)
+		}
+	}()
+
+	for rows.Next() {
+		var part Part
+		if err := rows.Scan(&part.ID, &part.UUID, &part.Name, &part.Messages); err != nil {
+			return nil, fmt.Errorf("failed to scan split part: %w", err)
+		}
+		links.Parts = append(links.Parts, part)
+	}
+	return links, rows.Err()
+}
+
+// cutPoints returns the indexes in messages of the messages to split at, in
+// order. The first message can't start a new part, since the original
+// conversation would be left empty.
+func cutPoints(messages []splitMessage, at []string, conversationID int64) ([]int, error) {
+	index := make(map[string]int, len(messages))
+	for i, m := range messages {
+		index[m.uuid] = i
+	}
+
+	seen := make(map[int]bool)
+	var cuts []int
+	for _, uuid := range at {
+		i, ok := index[uuid]
+		if !ok {
+			return nil, fmt.Errorf("message %s is not in conversation %d", uuid, conversationID)
+		}
+		if i == 0 {
+			return nil, fmt.Errorf("message %s is the first of the conversation; split at a later one", uuid)
+		}
+		if seen[i] {
+			continue
+		}
+		seen[i] = true
+		cuts = append(cuts, i)
+	}
+	sort.Ints(cuts)
+	return cuts, nil
+}
+
+// movePart creates a conversation for a segment of messages and moves them
+// into it, copying the branches they were on
+func movePart(tx *sql.Tx, sourceID int64, sourceUUID, name string, messages []splitMessage, branches map[int64]branch) (*Part, error) {
+	first, last := messages[0], messages[len(messages)-1]
+	part := &Part{
+		UUID:     fmt.Sprintf("%s-part-%s", sourceUUID, first.uuid),
+		Name:     name,
+		Messages: len(messages),
+	}
+
+	// Parts of a private conversation stay private, as their messages stay
+	// out of the indexes
+	result, err := tx.Exec(`
+		INSERT INTO conversations (uuid, name, created_at, updated_at, message_count, private)
+		VALUES (?, ?, ?, ?, ?, (SELECT private FROM conversations WHERE id = ?))
+	`, part.UUID, part.Name, first.createdAt, last.createdAt, part.Messages, sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create conversation %q: %w", name, err)
+	}
+	if part.ID, err = result.LastInsertId(); err != nil {
+		return nil, fmt.Errorf("failed to get conversation ID: %w", err)
+	}
+
+	mainID, err := createBranch(tx, part.ID, "main", nil)
+	if err != nil {
+		return nil, err
+	}
+	copied := make(map[int64]int64)
+	for sequence, m := range messages {
+		branchID, ok := copied[m.branchID]
+		if !ok {
+			if b := branches[m.branchID]; !b.parentID.Valid {
+				branchID = mainID
+			} else if branchID, err = createBranch(tx, part.ID, b.name.String, &mainID); err != nil {
+				return nil, err
+			}
+			copied[m.branchID] = branchID
+		}
+
+		if _, err := tx.Exec(`
+			UPDATE messages SET conversation_id = ?, branch_id = ?, sequence = ? WHERE id = ?
+		`, part.ID, branchID, sequence, m.id); err != nil {
+			return nil, fmt.Errorf("failed to move message %s: %w", m.uuid, err)
+		}
+		if _, err := tx.Exec("UPDATE code_blocks SET conversation_id = ? WHERE message_id = ?", part.ID, m.id); err != nil {
+			return nil, fmt.Errorf("failed to move code blocks of message %s: %w", m.uuid, err)
+		}
+	}
+
+	if _, err := tx.Exec("INSERT INTO conversation_splits (conversation_id, source_id) VALUES (?, ?)", part.ID, sourceID); err != nil {
+		return nil, fmt.Errorf("failed to record split: %w", err)
+	}
+	if err := refreshConversation(tx, part.ID); err != nil {
+		return nil, err
+	}
+	return part, nil
+}
+
+// loadMessages reads the messages of a conversation in the order they're
+// shown in
+func loadMessages(tx *sql.Tx, conversationID int64) ([]splitMessage, error) {
+	rows, err := tx.Query(`
+		SELECT id, uuid, branch_id, created_at
+		FROM messages
+		WHERE conversation_id = ?
+		ORDER BY sequence, created_at, id
+	`, conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query messages: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close rows: %v\n", err)
+		}
+	}()
+
+	var messages []splitMessage
+	for rows.Next() {
+		var m splitMessage
+		if err := rows.Scan(&m.id, &m.uuid, &m.branchID, &m.createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan message: %w", err)
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
+
+// loadBranches reads the branches of a conversation by ID
+func loadBranches(tx *sql.Tx, conversationID int64) (map[int64]branch, error) {
+	rows, err := tx.Query("SELECT id, name, parent_branch_id FROM branches WHERE conversation_id = ?", conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query branches: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close rows: %v\n", err)
+		}
+	}()
+
+	branches := make(map[int64]branch)
+	for rows.Next() {
+		var id int64
+		var b branch
+		if err := rows.Scan(&id, &b.name, &b.parentID); err != nil {
+			return nil, fmt.Errorf("failed to scan branch: %w", err)
+		}
+		branches[id] = b
+	}
+	return branches, rows.Err()
+}
+
+func createBranch(tx *sql.Tx, conversationID int64, name string, parentBranchID *int64) (int64, error) {
+	result, err := tx.Exec(`
+		INSERT INTO branches (conversation_id, name, parent_branch_id)
+		VALUES (?, ?, ?)
+	`, conversationID, name, parentBranchID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create branch: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+// refreshConversation detaches replies whose parent was moved to another
+// conversation, then recomputes the message count and derived metrics
+func refreshConversation(tx *sql.Tx, conversationID int64) error {
+	if _, err := tx.Exec(`
+		UPDATE messages SET parent_id = NULL
+		WHERE conversation_id = ?
+		  AND parent_id NOT IN (SELECT id FROM messages WHERE conversation_id = ?)
+	`, conversationID, conversationID); err != nil {
+		return fmt.Errorf("failed to detach replies in conversation %d: %w", conversationID, err)
+	}
+	if _, err := tx.Exec(`
+		UPDATE conversations
+		SET message_count = (SELECT COUNT(*) FROM messages WHERE conversation_id = ?)
+		WHERE id = ?
+	`, conversationID, conversationID); err != nil {
+		return fmt.Errorf("failed to update conversation %d: %w", conversationID, err)
+	}
+	if _, err := tx.Exec(db.RefreshConversationStatsSQL, conversationID); err != nil {
+		return fmt.Errorf("failed to update conversation %d statistics: %w", conversationID, err)
+	}
+	return nil
+}
+
+func rollback(tx *sql.Tx) {
+	if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+		fmt.Fprintf(os.Stderr, "Warning: failed to rollback transaction: %v\n", err)
+	}
+}
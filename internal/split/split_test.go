@@ -0,0 +1,181 @@
+package split
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/imports"
+	"github.com/neilberkman/shannon/internal/models"
+)
+
+func setupTestDB(t *testing.T) (*db.DB, string, int64) {
+	t.Helper()
+
+	tmpDir := t.TempDir()
+	database, err := db.New(filepath.Join(tmpDir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := database.Close(); err != nil {
+			t.Errorf("failed to close database: %v", err)
+		}
+	})
+
+	// Two topics, the second with a retried answer on a branch of its own
+	parent := func(uuid string) *string { return &uuid }
+	data, err := json.Marshal([]models.ClaudeConversation{
+		{
+			UUID: "conv-1", Name: "Mixed topics",
+			CreatedAt: "2024-01-01T10:00:00Z", UpdatedAt: "2024-01-01T11:05:00Z",
+			ChatMessages: []models.ClaudeChatMessage{
+				{UUID: "msg-1", Sender: "human", Text: "How do I parse flags?", CreatedAt: "2024-01-01T10:00:00Z"},
+				{UUID: "msg-2", Sender: "assistant", Text: "Use the flag package.", CreatedAt: "2024-01-01T10:01:00Z", ParentID: parent("msg-1")},
+				{UUID: "msg-3", Sender: "human", Text: "Unrelated: how do I bake bread?", CreatedAt: "2024-01-01T11:00:00Z", ParentID: parent("msg-2")},
+				{UUID: "msg-4", Sender: "assistant", Text: "Knead it.\n```text\nflour, water, salt\n```", CreatedAt: "2024-01-01T11:01:00Z", ParentID: parent("msg-3")},
+				{UUID: "msg-5", Sender: "assistant", Text: "Let it rise.", CreatedAt: "2024-01-01T11:02:00Z", ParentID: parent("msg-3")},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(tmpDir, "export.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := imports.NewImporter(database, 100, false).Import(path); err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+
+	var convID int64
+	if err := database.QueryRow("SELECT id FROM conversations WHERE uuid = 'conv-1'").Scan(&convID); err != nil {
+		t.Fatal(err)
+	}
+	return database, path, convID
+}
+
+func count(t *testing.T, database *db.DB, query string, args ...interface{}) int {
+	t.Helper()
+	var n int
+	if err := database.QueryRow(query, args...).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestSplit(t *testing.T) {
+	database, path, convID := setupTestDB(t)
+
+	parts, err := Split(database, convID, []string{"msg-3"})
+	if err != nil {
+		t.Fatalf("split failed: %v", err)
+	}
+	if len(parts) != 1 {
+		t.Fatalf("expected one new conversation, got %+v", parts)
+	}
+	part := parts[0]
+	if part.Name != "Mixed topics (part 2)" || part.Messages != 3 {
+		t.Errorf("unexpected part %+v", part)
+	}
+
+	if n := count(t, database, "SELECT message_count FROM conversations WHERE id = ?", convID); n != 2 {
+		t.Errorf("expected 2 messages left in the original, got %d", n)
+	}
+	if n := count(t, database, "SELECT COUNT(*) FROM messages WHERE conversation_id = ? AND sequence IN (0, 1, 2)", part.ID); n != 3 {
+		t.Errorf("expected the part's messages to be renumbered from 0, got %d", n)
+	}
+	if n := count(t, database, "SELECT COUNT(*) FROM messages WHERE uuid = 'msg-3' AND parent_id IS NULL"); n != 1 {
+		t.Error("expected the first message of the part to lose its parent in the original")
+	}
+	if n := count(t, database, "SELECT COUNT(*) FROM branches WHERE conversation_id = ?", part.ID); n != 2 {
+		t.Errorf("expected the part to have a main branch and the retry's branch, got %d branches", n)
+	}
+	if n := count(t, database, "SELECT COUNT(*) FROM code_blocks WHERE conversation_id = ?", part.ID); n != 1 {
+		t.Errorf("expected the code block to move with its message, got %d", n)
+	}
+	if n := count(t, database, "SELECT human_message_count FROM conversations WHERE id = ?", part.ID); n != 1 {
+		t.Errorf("expected the part's metrics to be computed, got %d human messages", n)
+	}
+
+	links, err := GetLinks(database, part.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if links.Source == nil || links.Source.ID != convID || len(links.Parts) != 0 {
+		t.Errorf("expected the part to link back to the original, got %+v", links)
+	}
+	links, err = GetLinks(database, convID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if links.Source != nil || len(links.Parts) != 1 || links.Parts[0].ID != part.ID {
+		t.Errorf("expected the original to link to the part, got %+v", links)
+	}
+
+	// Splitting the original again numbers the parts on
+	if _, err := Split(database, convID, []string{"msg-2"}); err != nil {
+		t.Fatalf("second split failed: %v", err)
+	}
+	if n := count(t, database, "SELECT COUNT(*) FROM conversations WHERE name = 'Mixed topics (part 3)'"); n != 1 {
+		t.Error("expected the second split to make part 3")
+	}
+
+	// Re-importing the original doesn't bring the moved messages back
+	stats, err := imports.NewImporter(database, 100, false).ImportConversations(path, "rehash", mustRead(t, path))
+	if err != nil {
+		t.Fatalf("re-import failed: %v", err)
+	}
+	if stats.MessagesImported != 0 {
+		t.Errorf("expected no messages re-imported, got %d", stats.MessagesImported)
+	}
+	if n := count(t, database, "SELECT message_count FROM conversations WHERE id = ?", convID); n != 1 {
+		t.Errorf("expected the re-import to keep the original's count at 1, got %d", n)
+	}
+	if n := count(t, database, "SELECT COUNT(*) FROM messages"); n != 5 {
+		t.Errorf("expected 5 messages in all, got %d", n)
+	}
+}
+
+func TestSplitErrors(t *testing.T) {
+	database, _, convID := setupTestDB(t)
+
+	tests := []struct {
+		name string
+		at   []string
+		want string
+	}{
+		{"nothing", nil, "no messages"},
+		{"first message", []string{"msg-1"}, "first of the conversation"},
+		{"unknown message", []string{"msg-9"}, "not in conversation"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Split(database, convID, tt.at)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	if n := count(t, database, "SELECT COUNT(*) FROM conversations"); n != 1 {
+		t.Errorf("expected failed splits to change nothing, got %d conversations", n)
+	}
+}
+
+func mustRead(t *testing.T, path string) []models.ClaudeConversation {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var conversations []models.ClaudeConversation
+	if err := json.Unmarshal(data, &conversations); err != nil {
+		t.Fatal(err)
+	}
+	return conversations
+}
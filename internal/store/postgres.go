@@ -0,0 +1,540 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib" // registers the pgx driver
+	"github.com/neilberkman/shannon/internal/imports"
+	"github.com/neilberkman/shannon/internal/models"
+	"github.com/neilberkman/shannon/internal/search"
+)
+
+// postgresSchema creates the tables of a shared archive. Messages carry
+// their own tsvector, kept by Postgres, for the GIN index full-text searches
+// use. Branches aren't stored: a message's parent is enough to tell them
+// apart when a conversation is read.
+const postgresSchema = `
+CREATE TABLE IF NOT EXISTS conversations (
+	id          BIGSERIAL PRIMARY KEY,
+	uuid        TEXT NOT NULL UNIQUE,
+	name        TEXT NOT NULL DEFAULT '',
+	created_at  TIMESTAMPTZ NOT NULL,
+	updated_at  TIMESTAMPTZ NOT NULL,
+	starred     BOOLEAN NOT NULL DEFAULT FALSE,
+	imported_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS messages (
+	id              BIGSERIAL PRIMARY KEY,
+	uuid            TEXT NOT NULL UNIQUE,
+	conversation_id BIGINT NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+	sender          TEXT NOT NULL,
+	text            TEXT NOT NULL,
+	created_at      TIMESTAMPTZ NOT NULL,
+	parent_id       BIGINT REFERENCES messages(id) ON DELETE SET NULL,
+	model           TEXT NOT NULL DEFAULT '',
+	starred         BOOLEAN NOT NULL DEFAULT FALSE,
+	search          TSVECTOR GENERATED ALWAYS AS (to_tsvector('english', text)) STORED
+);
+
+CREATE INDEX IF NOT EXISTS messages_conversation ON messages (conversation_id, created_at);
+CREATE INDEX IF NOT EXISTS messages_search ON messages USING GIN (search);
+
+CREATE TABLE IF NOT EXISTS import_history (
+	id                  BIGSERIAL PRIMARY KEY,
+	file_path           TEXT NOT NULL,
+	file_hash           TEXT NOT NULL UNIQUE,
+	imported_at         TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	conversations_count INTEGER NOT NULL DEFAULT 0,
+	messages_count      INTEGER NOT NULL DEFAULT 0
+);
+`
+
+// postgresStore is an archive on a Postgres server, shared by the machines
+// pointed at it
+type postgresStore struct {
+	db *sql.DB
+}
+
+// OpenPostgres connects to the Postgres database at url, a postgres:// URL
+// or a libpq connection string, creating the archive's tables if need be
+func OpenPostgres(url string) (Store, error) {
+	conn, err := sql.Open("pgx", url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open postgres database: %w", err)
+	}
+	if err := conn.Ping(); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to connect to postgres database: %w", err)
+	}
+	if _, err := conn.Exec(postgresSchema); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to create postgres schema: %w", err)
+	}
+	return &postgresStore{db: conn}, nil
+}
+
+func (s *postgresStore) IsImported(hash string) (bool, error) {
+	var count int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM import_history WHERE file_hash = $1", hash).Scan(&count)
+	return count > 0, err
+}
+
+// ImportConversations imports in one transaction, as the SQLite importer
+// does. Conversations that aren't valid are skipped and reported in the
+// stats' errors; the import fails if another machine imported the same
+// export meanwhile.
+func (s *postgresStore) ImportConversations(source, hash string, conversations []models.ClaudeConversation) (*models.ImportStats, error) {
+	if imported, err := s.IsImported(hash); err != nil {
+		return nil, err
+	} else if imported {
+		return nil, fmt.Errorf("file already imported (hash: %s)", hash)
+	}
+	if len(conversations) == 0 {
+		return nil, fmt.Errorf("invalid export: no conversations found in export")
+	}
+
+	stats := &models.ImportStats{}
+	startTime := time.Now()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			fmt.Fprintf(os.Stderr, "Warning: failed to rollback transaction: %v\n", err)
+		}
+	}()
+
+	for index := range conversations {
+		conv := &conversations[index]
+		if err := imports.ValidateConversation(conv); err != nil {
+			stats.Errors = append(stats.Errors, &imports.ConversationError{Index: index, UUID: conv.UUID, Err: err})
+			continue
+		}
+		if err := importPostgresConversation(tx, conv, stats); err != nil {
+			return stats, fmt.Errorf("failed to import conversation %s: %w", conv.UUID, err)
+		}
+	}
+
+	stats.Duration = time.Since(startTime)
+	err = tx.QueryRow(`
+		INSERT INTO import_history (file_path, file_hash, conversations_count, messages_count)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, source, hash, stats.ConversationsImported, stats.MessagesImported).Scan(&stats.ImportID)
+	if err != nil {
+		return stats, fmt.Errorf("failed to record import: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return stats, fmt.Errorf("failed to commit: %w", err)
+	}
+	return stats, nil
+}
+
+// importPostgresConversation adds a conversation, or updates the one with
+// its UUID, and inserts the messages that aren't there yet
+func importPostgresConversation(tx *sql.Tx, conv *models.ClaudeConversation, stats *models.ImportStats) error {
+	createdAt, err := imports.ParseTime(conv.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("invalid created_at: %w", err)
+	}
+	updatedAt, err := imports.ParseTime(conv.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("invalid updated_at: %w", err)
+	}
+
+	// xmax is 0 for a row the statement inserted rather than updated
+	var convID int64
+	var inserted bool
+	err = tx.QueryRow(`
+		INSERT INTO conversations (uuid, name, created_at, updated_at, starred)
+		VALUES ($1, $2, $3, $4, COALESCE($5::BOOLEAN, FALSE))
+		ON CONFLICT (uuid) DO UPDATE
+		SET name = EXCLUDED.name,
+		    updated_at = EXCLUDED.updated_at,
+		    starred = COALESCE($5::BOOLEAN, conversations.starred)
+		RETURNING id, (xmax = 0)
+	`, conv.UUID, conv.Name, createdAt, updatedAt, conv.Starred).Scan(&convID, &inserted)
+	if err != nil {
+		return fmt.Errorf("failed to save conversation: %w", err)
+	}
+	if inserted {
+		stats.ConversationsImported++
+	}
+
+	added := 0
+	for _, msg := range conv.ChatMessages {
+		msgCreatedAt, err := imports.ParseTime(msg.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("invalid message created_at: %w", err)
+		}
+
+		text := msg.Text
+		if text == "" {
+			for _, content := range msg.Content {
+				if content.Type == "text" && content.Text != "" {
+					text = content.Text
+					break
+				}
+			}
+		}
+		model := ""
+		if msg.Sender == "assistant" {
+			model = msg.Model
+			if model == "" {
+				model = conv.Model
+			}
+		}
+		var parent *string
+		if msg.ParentID != nil && *msg.ParentID != "" {
+			parent = msg.ParentID
+		}
+
+		result, err := tx.Exec(`
+			INSERT INTO messages (uuid, conversation_id, sender, text, created_at, parent_id, model, starred)
+			VALUES ($1, $2, $3, $4, $5, (SELECT id FROM messages WHERE uuid = $6), $7, COALESCE($8::BOOLEAN, FALSE))
+			ON CONFLICT (uuid) DO NOTHING
+		`, msg.UUID, convID, msg.Sender, text, msgCreatedAt, parent, model, msg.Starred)
+		if err != nil {
+			return fmt.Errorf("failed to insert message: %w", err)
+		}
+		if n, err := result.RowsAffected(); err == nil {
+			added += int(n)
+		}
+	}
+
+	stats.MessagesImported += added
+	return nil
+}
+
+func (s *postgresStore) Search(opts search.SearchOptions) ([]*models.SearchResult, error) {
+	query, args, err := postgresSearchQuery(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("search query failed: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close rows: %v\n", err)
+		}
+	}()
+
+	var results []*models.SearchResult
+	for rows.Next() {
+		var r models.SearchResult
+		err := rows.Scan(&r.ConversationID, &r.ConversationUUID, &r.ConversationName, &r.MessageID, &r.MessageUUID,
+			&r.Sender, &r.Text, &r.Snippet, &r.CreatedAt, &r.Rank)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan result: %w", err)
+		}
+		results = append(results, &r)
+	}
+	return results, rows.Err()
+}
+
+// postgresSearchQuery builds the full-text search for the options. Queries
+// are read by websearch_to_tsquery, so they take quoted phrases, OR and
+// -word as a search engine would; FTS5's syntax, such as NEAR and prefix
+// stars, isn't available. Options only the SQLite store has are an error.
+func postgresSearchQuery(opts search.SearchOptions) (string, []interface{}, error) {
+	unsupported := []struct {
+		option string
+		set    bool
+	}{
+		{"--rank " + opts.Rank, opts.Rank != "" && opts.Rank != search.RankRelevance},
+		{"--no-stem and --fold-diacritics", opts.Index != search.IndexAuto},
+		{"--rating", opts.Rating != ""},
+		{"feedback:", opts.Feedback != ""},
+		{"--distinct", opts.Distinct != ""},
+		{"--include-private", opts.IncludePrivate},
+		{"duration:", opts.Duration != nil},
+		{"days:", opts.ActiveDays != nil},
+	}
+	for _, u := range unsupported {
+		if u.set {
+			return "", nil, fmt.Errorf("%s isn't supported with the postgres store", u.option)
+		}
+	}
+	if strings.TrimSpace(opts.Query) == "" {
+		return "", nil, fmt.Errorf("empty search query")
+	}
+
+	args := []interface{}{opts.Query}
+	var conditions []string
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if opts.ConversationID != nil {
+		add("m.conversation_id = $%d", *opts.ConversationID)
+	}
+	if opts.Sender != "" {
+		add("m.sender = $%d", opts.Sender)
+	}
+	if opts.Model != "" {
+		add("POSITION(LOWER($%d) IN LOWER(m.model)) > 0", opts.Model)
+	}
+	if opts.Starred {
+		conditions = append(conditions, "(m.starred OR c.starred)")
+	}
+	if opts.StartDate != nil {
+		add("m.created_at >= $%d", opts.StartDate.UTC())
+	}
+	if opts.EndDate != nil {
+		add("m.created_at < $%d", opts.EndDate.UTC())
+	}
+
+	query := `
+		SELECT c.id, c.uuid, c.name, m.id, m.uuid, m.sender, m.text,
+		       ts_headline('english', m.text, q, 'StartSel=<mark>, StopSel=</mark>, MaxWords=32, MinWords=16'),
+		       m.created_at, ts_rank(m.search, q)
+		FROM messages m
+		JOIN conversations c ON c.id = m.conversation_id
+		CROSS JOIN websearch_to_tsquery('english', $1) q
+		WHERE m.search @@ q`
+	for _, condition := range conditions {
+		query += "\n\t\tAND " + condition
+	}
+
+	direction := " DESC"
+	if opts.SortOrder == "asc" {
+		direction = " ASC"
+	}
+	if opts.SortBy == "date" {
+		query += "\n\t\tORDER BY m.created_at" + direction + ", m.id" + direction
+	} else {
+		query += "\n\t\tORDER BY ts_rank(m.search, q)" + direction + ", m.created_at DESC"
+	}
+
+	if opts.Limit > 0 {
+		query += fmt.Sprintf("\n\t\tLIMIT %d", opts.Limit)
+		if opts.Offset > 0 {
+			query += fmt.Sprintf(" OFFSET %d", opts.Offset)
+		}
+	}
+	return query, args, nil
+}
+
+// postgresConversationColumns select a conversation with the metrics the
+// SQLite store keeps at import time, from c and its messages m, followed by
+// whether it's starred
+const postgresConversationColumns = `
+	c.id, c.uuid, c.name, c.created_at, c.updated_at, COUNT(m.id), c.imported_at,
+	COUNT(m.id) FILTER (WHERE m.sender = 'human'),
+	COALESCE(EXTRACT(EPOCH FROM MAX(m.created_at) - MIN(m.created_at)), 0)::BIGINT,
+	COUNT(DISTINCT (m.created_at AT TIME ZONE 'UTC')::DATE)`
+
+func scanPostgresConversation(row interface{ Scan(...interface{}) error }) (*models.Conversation, error) {
+	var conv models.Conversation
+	err := row.Scan(&conv.ID, &conv.UUID, &conv.Name, &conv.CreatedAt, &conv.UpdatedAt, &conv.MessageCount, &conv.ImportedAt,
+		&conv.HumanMessageCount, &conv.DurationSeconds, &conv.ActiveDays, &conv.Starred)
+	return &conv, err
+}
+
+func (s *postgresStore) ListConversations(limit int) ([]*models.Conversation, error) {
+	// Starred messages star their conversation, as for list --starred
+	query := `SELECT` + postgresConversationColumns + `, c.starred OR COALESCE(BOOL_OR(m.starred), FALSE)
+		FROM conversations c
+		LEFT JOIN messages m ON m.conversation_id = c.id
+		GROUP BY c.id
+		ORDER BY c.updated_at DESC, c.id DESC`
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query conversations: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close rows: %v\n", err)
+		}
+	}()
+
+	var conversations []*models.Conversation
+	for rows.Next() {
+		conv, err := scanPostgresConversation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan conversation: %w", err)
+		}
+		conversations = append(conversations, conv)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating conversations: %w", err)
+	}
+	return conversations, nil
+}
+
+func (s *postgresStore) GetConversation(conversationID int64) (*models.Conversation, []*models.Message, error) {
+	conv, err := scanPostgresConversation(s.db.QueryRow(`SELECT`+postgresConversationColumns+`, c.starred
+		FROM conversations c
+		LEFT JOIN messages m ON m.conversation_id = c.id
+		WHERE c.id = $1
+		GROUP BY c.id
+	`, conversationID))
+	if err == sql.ErrNoRows {
+		return nil, nil, fmt.Errorf("conversation not found")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	messages, err := s.GetAllMessages(conversationID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return conv, mainBranch(messages), nil
+}
+
+func (s *postgresStore) GetAllMessages(conversationID int64) ([]*models.Message, error) {
+	rows, err := s.db.Query(`
+		SELECT id, uuid, conversation_id, sender, text, created_at, parent_id, model, starred
+		FROM messages
+		WHERE conversation_id = $1
+		ORDER BY created_at ASC, id ASC
+	`, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close rows: %v\n", err)
+		}
+	}()
+
+	var messages []*models.Message
+	for rows.Next() {
+		var m models.Message
+		if err := rows.Scan(&m.ID, &m.UUID, &m.ConversationID, &m.Sender, &m.Text, &m.CreatedAt, &m.ParentID, &m.Model, &m.Starred); err != nil {
+			return nil, err
+		}
+		m.Sequence = len(messages)
+		messages = append(messages, &m)
+	}
+	return messages, rows.Err()
+}
+
+func (s *postgresStore) GetStats() (map[string]interface{}, error) {
+	var conversations, messages, human, assistant int
+	var oldest, newest sql.NullTime
+	err := s.db.QueryRow(`
+		SELECT (SELECT COUNT(*) FROM conversations), COUNT(*),
+		       COUNT(*) FILTER (WHERE sender = 'human'), COUNT(*) FILTER (WHERE sender = 'assistant'),
+		       MIN(created_at), MAX(created_at)
+		FROM messages
+	`).Scan(&conversations, &messages, &human, &assistant, &oldest, &newest)
+	if err != nil {
+		return nil, err
+	}
+	stats := map[string]interface{}{
+		"total_conversations": conversations,
+		"total_messages":      messages,
+		"messages_by_sender":  map[string]int{"human": human, "assistant": assistant},
+	}
+	if oldest.Valid && newest.Valid {
+		stats["date_range"] = map[string]time.Time{"oldest": oldest.Time, "newest": newest.Time}
+	}
+
+	rows, err := s.db.Query(`
+		SELECT model, COUNT(*), COUNT(DISTINCT conversation_id)
+		FROM messages
+		WHERE sender = 'assistant' AND model != ''
+		GROUP BY model
+		ORDER BY COUNT(*) DESC, model
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count messages by model: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close rows: %v\n", err)
+		}
+	}()
+	usage := []search.ModelUsage{}
+	for rows.Next() {
+		var u search.ModelUsage
+		if err := rows.Scan(&u.Model, &u.Messages, &u.Conversations); err != nil {
+			return nil, err
+		}
+		usage = append(usage, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	stats["messages_by_model"] = usage
+	return stats, nil
+}
+
+func (s *postgresStore) MessageActivity(from, to time.Time, sender string) (map[string]int, error) {
+	// Count by UTC hour, as the SQLite store does, so days can be cut in any
+	// time zone
+	query := `
+		SELECT date_trunc('hour', created_at AT TIME ZONE 'UTC'), COUNT(*)
+		FROM messages
+		WHERE created_at >= $1 AND created_at < $2`
+	args := []interface{}{from, to}
+	if sender != "" {
+		query += " AND sender = $3"
+		args = append(args, sender)
+	}
+	query += " GROUP BY 1"
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count messages: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close rows: %v\n", err)
+		}
+	}()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var hour time.Time
+		var count int
+		if err := rows.Scan(&hour, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan message count: %w", err)
+		}
+		// A timestamp without time zone comes back as UTC
+		utc := time.Date(hour.Year(), hour.Month(), hour.Day(), hour.Hour(), 0, 0, 0, time.UTC)
+		counts[utc.In(from.Location()).Format("2006-01-02")] += count
+	}
+	return counts, rows.Err()
+}
+
+// mainBranch picks the main branch out of a conversation's messages, in
+// the order they were written, as the importer does: a message is on it if
+// it has no parent, or if its parent is and has no earlier reply there
+func mainBranch(messages []*models.Message) []*models.Message {
+	onMain := make(map[int64]bool)
+	answered := make(map[int64]bool) // main branch messages with a reply on it
+	var main []*models.Message
+	for _, msg := range messages {
+		if msg.ParentID != nil {
+			if !onMain[*msg.ParentID] || answered[*msg.ParentID] {
+				continue
+			}
+			answered[*msg.ParentID] = true
+		}
+		onMain[msg.ID] = true
+		main = append(main, msg)
+	}
+	return main
+}
+
+func (s *postgresStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,105 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/db"
+	"github.com/neilberkman/shannon/internal/imports"
+	"github.com/neilberkman/shannon/internal/models"
+	"github.com/neilberkman/shannon/internal/search"
+)
+
+// sqliteStore is the local database, through the search engine and the
+// importer the other commands use
+type sqliteStore struct {
+	db       *db.DB
+	engine   *search.Engine
+	importer *imports.Importer
+}
+
+// OpenSQLite opens the local database at path as a store, importing in
+// batches of batchSize
+func OpenSQLite(path string, batchSize int) (Store, error) {
+	database, err := db.New(path)
+	if err != nil {
+		return nil, err
+	}
+	return &sqliteStore{
+		db:       database,
+		engine:   search.NewEngine(database),
+		importer: imports.NewImporter(database, batchSize, false),
+	}, nil
+}
+
+func (s *sqliteStore) ImportConversations(source, hash string, conversations []models.ClaudeConversation) (*models.ImportStats, error) {
+	return s.importer.ImportConversations(source, hash, conversations)
+}
+
+func (s *sqliteStore) IsImported(hash string) (bool, error) {
+	return s.importer.IsImported(hash)
+}
+
+func (s *sqliteStore) Search(opts search.SearchOptions) ([]*models.SearchResult, error) {
+	return s.engine.Search(opts)
+}
+
+func (s *sqliteStore) ListConversations(limit int) ([]*models.Conversation, error) {
+	if limit == 0 {
+		limit = -1
+	}
+	conversations, err := s.engine.ListConversations(search.ListByUpdated, limit, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// Starred messages star their conversation, as for list --starred
+	rows, err := s.db.Query(`
+		SELECT id FROM conversations
+		WHERE deleted_at IS NULL AND (starred OR id IN (SELECT conversation_id FROM messages WHERE starred))
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query starred conversations: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close rows: %v\n", err)
+		}
+	}()
+	starred := make(map[int64]bool)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		starred[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, conv := range conversations {
+		conv.Starred = starred[conv.ID]
+	}
+	return conversations, nil
+}
+
+func (s *sqliteStore) GetConversation(conversationID int64) (*models.Conversation, []*models.Message, error) {
+	return s.engine.GetConversation(conversationID)
+}
+
+func (s *sqliteStore) GetAllMessages(conversationID int64) ([]*models.Message, error) {
+	return s.engine.GetAllMessages(conversationID)
+}
+
+func (s *sqliteStore) GetStats() (map[string]interface{}, error) {
+	return s.engine.GetStats()
+}
+
+func (s *sqliteStore) MessageActivity(from, to time.Time, sender string) (map[string]int, error) {
+	return s.engine.MessageActivity(from, to, sender)
+}
+
+func (s *sqliteStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,157 @@
+// Package store is the archive the commands work on: the local SQLite
+// database, or a Postgres server holding one archive shared by several
+// machines, chosen with database.driver in the config.
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/config"
+	"github.com/neilberkman/shannon/internal/models"
+	"github.com/neilberkman/shannon/internal/search"
+)
+
+// Drivers for database.driver
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// Annotation marks the commands that work on a shared store, in their
+// cobra Annotations; the others need the local database
+const Annotation = "shared-store"
+
+// Store holds the conversations of an archive. Its methods are named after
+// the search engine's and the importer's, which the SQLite store is.
+type Store interface {
+	// ImportConversations imports the conversations of an export, adding
+	// the messages of those already there that are new. source and hash
+	// identify the export in the import history, so it's imported once.
+	ImportConversations(source, hash string, conversations []models.ClaudeConversation) (*models.ImportStats, error)
+	// IsImported reports whether the export with this hash was imported
+	IsImported(hash string) (bool, error)
+	// Search finds the messages matching a full-text query
+	Search(opts search.SearchOptions) ([]*models.SearchResult, error)
+	// ListConversations returns the most recently updated conversations,
+	// or all of them with a limit of 0. A conversation is starred if it or
+	// one of its messages is.
+	ListConversations(limit int) ([]*models.Conversation, error)
+	// GetConversation returns a conversation with its main branch
+	GetConversation(conversationID int64) (*models.Conversation, []*models.Message, error)
+	// GetAllMessages returns the messages of every branch of a
+	// conversation in the order they were written
+	GetAllMessages(conversationID int64) ([]*models.Message, error)
+	// GetStats returns the totals `shannon stats` shows
+	GetStats() (map[string]interface{}, error)
+	// MessageActivity counts the messages written each day from from up to
+	// to, in from's time zone, by one sender or both
+	MessageActivity(from, to time.Time, sender string) (map[string]int, error)
+	Close() error
+}
+
+// Shared reports whether the config names a shared store instead of the
+// local database
+func Shared(cfg *config.Config) bool {
+	return cfg.Database.Driver == DriverPostgres
+}
+
+// Open opens the store the config names
+func Open(cfg *config.Config) (Store, error) {
+	switch cfg.Database.Driver {
+	case DriverSQLite, "":
+		return OpenSQLite(cfg.Database.Path, cfg.Import.BatchSize)
+	case DriverPostgres:
+		if cfg.Database.URL == "" {
+			return nil, fmt.Errorf("database.driver is postgres but database.url isn't set")
+		}
+		return OpenPostgres(cfg.Database.URL)
+	}
+	return nil, fmt.Errorf("invalid database.driver %q (use %s or %s)", cfg.Database.Driver, DriverSQLite, DriverPostgres)
+}
+
+// Copy imports every conversation of one store into another, with all its
+// branches, adding what the other doesn't have yet. source names the store
+// copied from in the other's import history. Copying again after nothing
+// changed is an error, as importing an export twice is.
+func Copy(from, to Store, source string) (*models.ImportStats, error) {
+	listed, err := from.ListConversations(0)
+	if err != nil {
+		return nil, err
+	}
+
+	// The hash covers what's copied, so a store is copied again once it
+	// has changed
+	hasher := sha256.New()
+	conversations := make([]models.ClaudeConversation, 0, len(listed))
+	for _, summary := range listed {
+		conv, _, err := from.GetConversation(summary.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read conversation %d: %w", summary.ID, err)
+		}
+		messages, err := from.GetAllMessages(summary.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read conversation %d: %w", summary.ID, err)
+		}
+		if len(messages) == 0 {
+			continue
+		}
+		claude := ClaudeConversation(conv, messages)
+		fmt.Fprintf(hasher, "%s %s %s %t\n", claude.UUID, claude.Name, claude.UpdatedAt, conv.Starred)
+		for _, msg := range claude.ChatMessages {
+			fmt.Fprintf(hasher, "%s\n", msg.UUID)
+		}
+		conversations = append(conversations, claude)
+	}
+	if len(conversations) == 0 {
+		return nil, fmt.Errorf("no conversations to copy")
+	}
+
+	hash := hex.EncodeToString(hasher.Sum(nil))
+	if imported, err := to.IsImported(hash); err != nil {
+		return nil, err
+	} else if imported {
+		return nil, fmt.Errorf("nothing new to copy from %s", source)
+	}
+	return to.ImportConversations(source, hash, conversations)
+}
+
+// ClaudeConversation turns a stored conversation back into the form of an
+// export, to import it elsewhere
+func ClaudeConversation(conv *models.Conversation, messages []*models.Message) models.ClaudeConversation {
+	uuids := make(map[int64]string, len(messages))
+	for _, msg := range messages {
+		uuids[msg.ID] = msg.UUID
+	}
+
+	claude := models.ClaudeConversation{
+		UUID:      conv.UUID,
+		Name:      conv.Name,
+		CreatedAt: conv.CreatedAt.UTC().Format(time.RFC3339Nano),
+		UpdatedAt: conv.UpdatedAt.UTC().Format(time.RFC3339Nano),
+		Starred:   &conv.Starred,
+	}
+	for _, msg := range messages {
+		m := models.ClaudeChatMessage{
+			UUID:      msg.UUID,
+			Sender:    msg.Sender,
+			Text:      msg.Text,
+			CreatedAt: msg.CreatedAt.UTC().Format(time.RFC3339Nano),
+			Model:     msg.Model,
+			Starred:   &msg.Starred,
+		}
+		if msg.Feedback != "" {
+			m.Feedback, _ = json.Marshal(msg.Feedback)
+		}
+		if msg.ParentID != nil {
+			if parent, ok := uuids[*msg.ParentID]; ok {
+				m.ParentID = &parent
+			}
+		}
+		claude.ChatMessages = append(claude.ChatMessages, m)
+	}
+	return claude
+}
@@ -0,0 +1,296 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neilberkman/shannon/internal/models"
+	"github.com/neilberkman/shannon/internal/search"
+)
+
+// testConversations has a conversation whose second question was edited,
+// starting a branch, and another without branches with a starred message
+func testConversations() []models.ClaudeConversation {
+	parent := func(uuid string) *string { return &uuid }
+	yes := true
+	return []models.ClaudeConversation{
+		{
+			UUID: "conv-1", Name: "Kubernetes", CreatedAt: "2024-01-01T10:00:00Z", UpdatedAt: "2024-01-01T10:05:00Z",
+			ChatMessages: []models.ClaudeChatMessage{
+				{UUID: "m1", Sender: "human", Text: "How do I scale a kubernetes deployment?", CreatedAt: "2024-01-01T10:00:00Z"},
+				{UUID: "m2", Sender: "assistant", Text: "Use kubectl scale.", CreatedAt: "2024-01-01T10:01:00Z", ParentID: parent("m1"), Model: "claude-3-opus"},
+				{UUID: "m3", Sender: "human", Text: "And autoscaling?", CreatedAt: "2024-01-01T10:02:00Z", ParentID: parent("m2")},
+				{UUID: "m4", Sender: "human", Text: "And rolling updates?", CreatedAt: "2024-01-01T10:03:00Z", ParentID: parent("m2")},
+			},
+		},
+		{
+			UUID: "conv-2", Name: "Cooking", CreatedAt: "2024-02-01T10:00:00Z", UpdatedAt: "2024-02-01T10:01:00Z",
+			ChatMessages: []models.ClaudeChatMessage{
+				{UUID: "n1", Sender: "human", Text: "How long do I boil an egg?", CreatedAt: "2024-02-01T10:00:00Z"},
+				{UUID: "n2", Sender: "assistant", Text: "About nine minutes.", CreatedAt: "2024-02-01T10:01:00Z", ParentID: parent("n1"), Starred: &yes},
+			},
+		},
+	}
+}
+
+// testStores opens the stores the contract test runs against: SQLite
+// always, and Postgres when SHANNON_TEST_POSTGRES_URL names an empty
+// database to use
+func testStores(t *testing.T) map[string]Store {
+	stores := make(map[string]Store)
+	sqlite, err := OpenSQLite(filepath.Join(t.TempDir(), "test.db"), 100)
+	if err != nil {
+		t.Fatalf("failed to open sqlite store: %v", err)
+	}
+	stores[DriverSQLite] = sqlite
+
+	if url := os.Getenv("SHANNON_TEST_POSTGRES_URL"); url != "" {
+		postgres, err := OpenPostgres(url)
+		if err != nil {
+			t.Fatalf("failed to open postgres store: %v", err)
+		}
+		stores[DriverPostgres] = postgres
+	}
+	for _, s := range stores {
+		t.Cleanup(func() { _ = s.Close() })
+	}
+	return stores
+}
+
+func TestStoreContract(t *testing.T) {
+	for driver, s := range testStores(t) {
+		t.Run(driver, func(t *testing.T) {
+			stats, err := s.ImportConversations("export.json", "hash-1", testConversations())
+			if err != nil {
+				t.Fatalf("ImportConversations() error = %v", err)
+			}
+			if stats.ConversationsImported != 2 || stats.MessagesImported != 6 {
+				t.Errorf("imported %d conversations and %d messages, want 2 and 6",
+					stats.ConversationsImported, stats.MessagesImported)
+			}
+			if imported, err := s.IsImported("hash-1"); err != nil || !imported {
+				t.Errorf("IsImported() = %v, %v, want true", imported, err)
+			}
+			if _, err := s.ImportConversations("export.json", "hash-1", testConversations()); err == nil {
+				t.Error("importing the same export twice should fail")
+			}
+
+			conversations, err := s.ListConversations(0)
+			if err != nil {
+				t.Fatalf("ListConversations() error = %v", err)
+			}
+			if len(conversations) != 2 || conversations[0].Name != "Cooking" {
+				t.Fatalf("ListConversations() = %d conversations, want Cooking first of 2", len(conversations))
+			}
+			if limited, _ := s.ListConversations(1); len(limited) != 1 {
+				t.Errorf("ListConversations(1) = %d conversations, want 1", len(limited))
+			}
+			if !conversations[0].Starred || conversations[1].Starred {
+				t.Errorf("ListConversations() starred = %t, %t, want Cooking starred by its message",
+					conversations[0].Starred, conversations[1].Starred)
+			}
+			if cooking, _, err := s.GetConversation(conversations[0].ID); err != nil || cooking.Starred {
+				t.Errorf("GetConversation(Cooking) should have the conversation itself unstarred")
+			}
+
+			totals, err := s.GetStats()
+			if err != nil {
+				t.Fatalf("GetStats() error = %v", err)
+			}
+			bySender, _ := totals["messages_by_sender"].(map[string]int)
+			byModel, _ := totals["messages_by_model"].([]search.ModelUsage)
+			if totals["total_conversations"] != 2 || totals["total_messages"] != 6 || bySender["human"] != 4 ||
+				len(byModel) != 1 || byModel[0].Model != "claude-3-opus" {
+				t.Errorf("GetStats() = %v", totals)
+			}
+			if dateRange, ok := totals["date_range"].(map[string]time.Time); !ok || !dateRange["newest"].Equal(time.Date(2024, 2, 1, 10, 1, 0, 0, time.UTC)) {
+				t.Errorf("GetStats() date range = %v, want the newest message at 2024-02-01 10:01", totals["date_range"])
+			}
+			activity, err := s.MessageActivity(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), "human")
+			if err != nil {
+				t.Fatalf("MessageActivity() error = %v", err)
+			}
+			if len(activity) != 1 || activity["2024-01-01"] != 3 {
+				t.Errorf("MessageActivity() = %v, want 3 human messages on 2024-01-01", activity)
+			}
+
+			kubernetes := conversations[1]
+			conv, main, err := s.GetConversation(kubernetes.ID)
+			if err != nil {
+				t.Fatalf("GetConversation() error = %v", err)
+			}
+			if conv.UUID != "conv-1" || conv.MessageCount != 4 {
+				t.Errorf("GetConversation() = %s with %d messages, want conv-1 with 4", conv.UUID, conv.MessageCount)
+			}
+			var uuids []string
+			for _, msg := range main {
+				uuids = append(uuids, msg.UUID)
+			}
+			if got := strings.Join(uuids, " "); got != "m1 m2 m3" {
+				t.Errorf("main branch = %s, want m1 m2 m3", got)
+			}
+			if main[1].Model != "claude-3-opus" {
+				t.Errorf("model = %q, want claude-3-opus", main[1].Model)
+			}
+
+			all, err := s.GetAllMessages(kubernetes.ID)
+			if err != nil {
+				t.Fatalf("GetAllMessages() error = %v", err)
+			}
+			if len(all) != 4 || all[3].UUID != "m4" || all[3].ParentID == nil || *all[3].ParentID != all[1].ID {
+				t.Errorf("GetAllMessages() should have m4 last, replying to m2")
+			}
+
+			results, err := s.Search(search.SearchOptions{Query: "kubernetes", Limit: 10})
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+			if len(results) != 1 || results[0].MessageUUID != "m1" || !strings.Contains(results[0].Snippet, "<mark>") {
+				t.Errorf("Search(kubernetes) = %d results, want m1 with a marked snippet", len(results))
+			}
+			results, err = s.Search(search.SearchOptions{Query: "boil", Sender: "assistant", Limit: 10})
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+			if len(results) != 0 {
+				t.Errorf("Search(boil) from the assistant = %d results, want 0", len(results))
+			}
+		})
+	}
+}
+
+func TestCopy(t *testing.T) {
+	from, err := OpenSQLite(filepath.Join(t.TempDir(), "from.db"), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = from.Close() }()
+	to, err := OpenSQLite(filepath.Join(t.TempDir(), "to.db"), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = to.Close() }()
+
+	if _, err := from.ImportConversations("export.json", "hash-1", testConversations()); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := Copy(from, to, "from.db")
+	if err != nil {
+		t.Fatalf("Copy() error = %v", err)
+	}
+	if stats.ConversationsImported != 2 || stats.MessagesImported != 6 {
+		t.Errorf("copied %d conversations and %d messages, want 2 and 6", stats.ConversationsImported, stats.MessagesImported)
+	}
+	if _, err := Copy(from, to, "from.db"); err == nil {
+		t.Error("copying again with nothing new should fail")
+	}
+
+	conversations, err := to.ListConversations(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, main, err := to.GetConversation(conversations[1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(main) != 3 || main[2].UUID != "m3" {
+		t.Errorf("copied main branch has %d messages, want m1 m2 m3", len(main))
+	}
+
+	// A conversation continued locally is copied again, adding its new
+	// messages
+	parent := "n2"
+	more := []models.ClaudeConversation{testConversations()[1]}
+	more[0].UpdatedAt = "2024-02-01T10:02:00Z"
+	more[0].ChatMessages = append(more[0].ChatMessages, models.ClaudeChatMessage{
+		UUID: "n3", Sender: "human", Text: "Thanks", CreatedAt: "2024-02-01T10:02:00Z", ParentID: &parent,
+	})
+	if _, err := from.ImportConversations("export2.json", "hash-2", more); err != nil {
+		t.Fatal(err)
+	}
+	stats, err = Copy(from, to, "from.db")
+	if err != nil {
+		t.Fatalf("Copy() after a change error = %v", err)
+	}
+	if stats.ConversationsImported != 0 || stats.MessagesImported != 1 {
+		t.Errorf("copied %d conversations and %d messages, want 0 and 1", stats.ConversationsImported, stats.MessagesImported)
+	}
+}
+
+func TestMainBranch(t *testing.T) {
+	id := func(n int64) *int64 { return &n }
+	messages := []*models.Message{
+		{ID: 1},
+		{ID: 2, ParentID: id(1)},
+		{ID: 3, ParentID: id(2)},
+		{ID: 4, ParentID: id(2)}, // an edit of 3
+		{ID: 5, ParentID: id(4)}, // the reply to the edit
+		{ID: 6, ParentID: id(3)},
+	}
+	var ids []int64
+	for _, msg := range mainBranch(messages) {
+		ids = append(ids, msg.ID)
+	}
+	if len(ids) != 4 || ids[0] != 1 || ids[1] != 2 || ids[2] != 3 || ids[3] != 6 {
+		t.Errorf("mainBranch() = %v, want [1 2 3 6]", ids)
+	}
+}
+
+func TestPostgresSearchQuery(t *testing.T) {
+	id := int64(7)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		opts     search.SearchOptions
+		contains []string
+		args     int
+		wantErr  string
+	}{
+		{
+			name:     "relevance",
+			opts:     search.SearchOptions{Query: `"rolling update" -helm`, Limit: 10, Offset: 20},
+			contains: []string{"websearch_to_tsquery('english', $1)", "ts_headline", "ORDER BY ts_rank(m.search, q) DESC", "LIMIT 10 OFFSET 20"},
+			args:     1,
+		},
+		{
+			name: "filters",
+			opts: search.SearchOptions{Query: "go", ConversationID: &id, Sender: "human", Model: "opus", StartDate: &start,
+				Starred: true, SortBy: "date", SortOrder: "asc", Rank: search.RankRelevance},
+			contains: []string{"m.conversation_id = $2", "m.sender = $3", "LOWER($4)", "m.created_at >= $5",
+				"(m.starred OR c.starred)", "ORDER BY m.created_at ASC"},
+			args: 5,
+		},
+		{name: "hybrid rank", opts: search.SearchOptions{Query: "go", Rank: search.RankHybrid}, wantErr: "--rank"},
+		{name: "distinct", opts: search.SearchOptions{Query: "go", Distinct: search.DistinctConversation}, wantErr: "--distinct"},
+		{name: "rating", opts: search.SearchOptions{Query: "go", Rating: "useful"}, wantErr: "--rating"},
+		{name: "empty query", opts: search.SearchOptions{Query: " "}, wantErr: "empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args, err := postgresSearchQuery(tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("postgresSearchQuery() error = %v, want one mentioning %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("postgresSearchQuery() error = %v", err)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(query, want) {
+					t.Errorf("query doesn't contain %q:\n%s", want, query)
+				}
+			}
+			if len(args) != tt.args {
+				t.Errorf("got %d args, want %d", len(args), tt.args)
+			}
+		})
+	}
+}
@@ -1,21 +1,47 @@
 package main
 
 import (
+	"github.com/neilberkman/shannon/cmd/alias"
 	"github.com/neilberkman/shannon/cmd/artifacts"
+	"github.com/neilberkman/shannon/cmd/askedbefore"
+	"github.com/neilberkman/shannon/cmd/cleanup"
+	"github.com/neilberkman/shannon/cmd/cluster"
+	contextcmd "github.com/neilberkman/shannon/cmd/context"
+	dbcmd "github.com/neilberkman/shannon/cmd/db"
+	"github.com/neilberkman/shannon/cmd/demo"
 	"github.com/neilberkman/shannon/cmd/discover"
+	"github.com/neilberkman/shannon/cmd/doctor"
 	"github.com/neilberkman/shannon/cmd/edit"
 	"github.com/neilberkman/shannon/cmd/export"
+	"github.com/neilberkman/shannon/cmd/grepcode"
 	imports "github.com/neilberkman/shannon/cmd/import"
+	importhistory "github.com/neilberkman/shannon/cmd/imports"
+	"github.com/neilberkman/shannon/cmd/index"
+	"github.com/neilberkman/shannon/cmd/jobs"
+	"github.com/neilberkman/shannon/cmd/link"
 	"github.com/neilberkman/shannon/cmd/list"
+	"github.com/neilberkman/shannon/cmd/onthisday"
 	"github.com/neilberkman/shannon/cmd/open"
+	"github.com/neilberkman/shannon/cmd/private"
+	"github.com/neilberkman/shannon/cmd/random"
+	"github.com/neilberkman/shannon/cmd/rate"
 	"github.com/neilberkman/shannon/cmd/recent"
+	"github.com/neilberkman/shannon/cmd/rename"
+	"github.com/neilberkman/shannon/cmd/reprompt"
 	"github.com/neilberkman/shannon/cmd/root"
 	"github.com/neilberkman/shannon/cmd/search"
+	"github.com/neilberkman/shannon/cmd/share"
+	"github.com/neilberkman/shannon/cmd/split"
 	"github.com/neilberkman/shannon/cmd/stats"
+	"github.com/neilberkman/shannon/cmd/sync"
+	"github.com/neilberkman/shannon/cmd/tail"
 	"github.com/neilberkman/shannon/cmd/terminal"
+	"github.com/neilberkman/shannon/cmd/trash"
 	"github.com/neilberkman/shannon/cmd/tui"
 	"github.com/neilberkman/shannon/cmd/view"
+	"github.com/neilberkman/shannon/cmd/worker"
 	"github.com/neilberkman/shannon/cmd/xargs"
+	"github.com/neilberkman/shannon/internal/crash"
 )
 
 // Version information, set during build
@@ -26,6 +52,10 @@ var (
 )
 
 func main() {
+	// Write a crash report of any panic
+	crash.SetVersion(version, commit, date)
+	defer crash.Handle()
+
 	// Set version information
 	root.Version = version
 	root.Commit = commit
@@ -33,19 +63,44 @@ func main() {
 	root.RootCmd.Version = version
 
 	// Add subcommands
+	root.RootCmd.AddCommand(alias.NewCmd())
 	root.RootCmd.AddCommand(artifacts.NewCmd())
+	root.RootCmd.AddCommand(askedbefore.AskedBeforeCmd)
 	root.RootCmd.AddCommand(imports.ImportCmd)
+	root.RootCmd.AddCommand(importhistory.NewCmd())
+	root.RootCmd.AddCommand(cleanup.NewCmd())
+	root.RootCmd.AddCommand(cluster.ClusterCmd)
+	root.RootCmd.AddCommand(contextcmd.ContextCmd)
+	root.RootCmd.AddCommand(dbcmd.NewCmd())
+	root.RootCmd.AddCommand(demo.DemoCmd)
 	root.RootCmd.AddCommand(discover.DiscoverCmd)
+	root.RootCmd.AddCommand(doctor.DoctorCmd)
+	root.RootCmd.AddCommand(link.LinkCmd)
 	root.RootCmd.AddCommand(list.ListCmd)
+	root.RootCmd.AddCommand(onthisday.OnThisDayCmd)
 	root.RootCmd.AddCommand(open.OpenCmd)
+	root.RootCmd.AddCommand(private.NewCmd())
+	root.RootCmd.AddCommand(random.RandomCmd)
+	root.RootCmd.AddCommand(rate.NewCmd())
 	root.RootCmd.AddCommand(recent.RecentCmd)
+	root.RootCmd.AddCommand(rename.NewCmd())
+	root.RootCmd.AddCommand(reprompt.RepromptCmd)
 	root.RootCmd.AddCommand(search.SearchCmd)
+	root.RootCmd.AddCommand(grepcode.GrepCodeCmd)
+	root.RootCmd.AddCommand(share.NewCmd())
+	root.RootCmd.AddCommand(split.SplitCmd)
 	root.RootCmd.AddCommand(view.ViewCmd)
 	root.RootCmd.AddCommand(edit.EditCmd)
 	root.RootCmd.AddCommand(export.ExportCmd)
+	root.RootCmd.AddCommand(index.IndexCmd)
+	root.RootCmd.AddCommand(jobs.NewCmd())
 	root.RootCmd.AddCommand(stats.StatsCmd)
+	root.RootCmd.AddCommand(sync.NewCmd())
+	root.RootCmd.AddCommand(tail.TailCmd)
 	root.RootCmd.AddCommand(terminal.TerminalCmd)
+	root.RootCmd.AddCommand(trash.NewCmd())
 	root.RootCmd.AddCommand(tui.TuiCmd)
+	root.RootCmd.AddCommand(worker.NewCmd())
 	root.RootCmd.AddCommand(xargs.XargsCmd)
 
 	// Execute
@@ -0,0 +1,86 @@
+package platform
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/kballard/go-shellquote"
+)
+
+// Browser is the command URLs are opened with, such as "firefox" or
+// `open -a "Google Chrome"`; empty uses the system's default browser. A {url}
+// in it is replaced with the URL, which is otherwise added to the end.
+var Browser string
+
+// launchGrace is how long OpenURL waits to hear whether the browser command
+// failed. Launchers like open and xdg-open exit once the browser has the URL;
+// a browser started directly keeps running and is assumed to have worked.
+const launchGrace = 2 * time.Second
+
+// OpenURL opens url in the browser, returning an error if the browser command
+// can't be run or exits with an error
+func OpenURL(url string) error {
+	args, err := browserCommand(url, Browser, runtime.GOOS)
+	if err != nil {
+		return err
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to run %s: %w", args[0], err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return fmt.Errorf("%s failed: %s", args[0], msg)
+			}
+			return fmt.Errorf("%s failed: %w", args[0], err)
+		}
+		return nil
+	case <-time.After(launchGrace):
+		return nil
+	}
+}
+
+// browserCommand returns the command line that opens url with browser, or
+// with the default browser of goos if browser is empty
+func browserCommand(url, browser, goos string) ([]string, error) {
+	if strings.TrimSpace(browser) != "" {
+		args, err := shellquote.Split(browser)
+		if err != nil {
+			return nil, fmt.Errorf("invalid browser command %q: %w", browser, err)
+		}
+		replaced := false
+		for i, arg := range args {
+			if strings.Contains(arg, "{url}") {
+				args[i] = strings.ReplaceAll(arg, "{url}", url)
+				replaced = true
+			}
+		}
+		if !replaced {
+			args = append(args, url)
+		}
+		return args, nil
+	}
+
+	switch goos {
+	case "darwin":
+		return []string{"open", url}, nil
+	case "windows":
+		return []string{"rundll32", "url.dll,FileProtocolHandler", url}, nil
+	case "linux", "freebsd", "openbsd", "netbsd":
+		return []string{"xdg-open", url}, nil
+	default:
+		return nil, fmt.Errorf("no default browser on %s; set ui.browser in the config file", goos)
+	}
+}
@@ -0,0 +1,42 @@
+package platform
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBrowserCommand(t *testing.T) {
+	url := "https://claude.ai/chat/abc"
+	tests := []struct {
+		name     string
+		browser  string
+		goos     string
+		expected string
+	}{
+		{"default on macOS", "", "darwin", "open|" + url},
+		{"default on Linux", "", "linux", "xdg-open|" + url},
+		{"default on Windows", "", "windows", "rundll32|url.dll,FileProtocolHandler|" + url},
+		{"URL appended", "firefox --new-tab", "linux", "firefox|--new-tab|" + url},
+		{"quoted application", `open -a "Google Chrome"`, "darwin", "open|-a|Google Chrome|" + url},
+		{"URL placeholder", "browser --url={url} --private", "linux", "browser|--url=" + url + "|--private"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, err := browserCommand(url, tt.browser, tt.goos)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Join(args, "|"); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+
+	if _, err := browserCommand(url, "", "plan9"); err == nil {
+		t.Error("expected an error without a default browser")
+	}
+	if _, err := browserCommand(url, `firefox "unclosed`, "linux"); err == nil {
+		t.Error("expected an error for an unparseable command")
+	}
+}